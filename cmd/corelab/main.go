@@ -0,0 +1,101 @@
+// Command corelab is the core-lab developer CLI.
+//
+//	corelab new service [flags] <name>
+//
+// generates a new service skeleton (see pkg/scaffold) in ./<name>, or in the
+// directory given with -dir.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/milan604/core-lab/pkg/scaffold"
+	"github.com/milan604/core-lab/pkg/version"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) >= 2 && args[0] == "new" && args[1] == "service" {
+		return newService(args[2:], stdout, stderr)
+	}
+	if len(args) == 1 && (args[0] == "version" || args[0] == "-version" || args[0] == "--version") {
+		fmt.Fprintln(stdout, version.Version)
+		return 0
+	}
+	fmt.Fprint(stderr, "usage:\n  corelab new service [flags] <name>\n  corelab version\n")
+	return 2
+}
+
+func newService(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("corelab new service", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	module := fs.String("module", "", "Go module path of the service (default <name>)")
+	dir := fs.String("dir", "", "directory to generate into (default ./<name>)")
+	port := fs.String("port", scaffold.DefaultPort, "HTTP port of the service")
+	permissionService := fs.String("permission-service", "", "service segment of permission codes (default <name> without dashes)")
+	coreLabVersion := fs.String("core-lab-version", defaultCoreLabVersion(), "core-lab version required in go.mod; empty leaves it to go mod tidy")
+	force := fs.Bool("force", false, "overwrite existing files")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: corelab new service [flags] <name>\n\nGenerates a service skeleton wired with core-lab.\n\n")
+		fs.PrintDefaults()
+	}
+	// Accept flags after the name too: corelab new service orders -module ...
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+	name := positional[0]
+	target := *dir
+	if target == "" {
+		target = name
+	}
+
+	files, err := scaffold.NewService(target, scaffold.ServiceOptions{
+		Name:              name,
+		Module:            *module,
+		PermissionService: *permissionService,
+		Port:              *port,
+		CoreLabVersion:    *coreLabVersion,
+		Force:             *force,
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		if errors.Is(err, scaffold.ErrFilesExist) {
+			fmt.Fprintln(stderr, "rerun with -force to overwrite them")
+		}
+		return 1
+	}
+	for _, f := range files {
+		fmt.Fprintf(stdout, "  create %s\n", f.Path)
+	}
+	fmt.Fprintf(stdout, "\nNext steps:\n  cd %s\n  go mod tidy\n  make test\n  make run\n", target)
+	return 0
+}
+
+// defaultCoreLabVersion pins generated services to the release corelab was
+// built from; development builds leave the choice to go mod tidy.
+func defaultCoreLabVersion() string {
+	if strings.HasPrefix(version.Version, "v") {
+		return version.Version
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+// Command migratelint lints a directory of golang-migrate SQL migrations.
+//
+//	migratelint -schemas billing ./migrations
+//
+// It prints one line per issue and exits with status 1 when any error-level
+// issue is found, or 2 when the directory cannot be read.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/milan604/core-lab/pkg/postgres/migrations"
+)
+
+func main() {
+	schemas := flag.String("schemas", "", "comma-separated schemas owned by the service; enables schema ownership checks")
+	allowMissingDown := flag.Bool("allow-missing-down", false, "allow up migrations without a down file")
+	strict := flag.Bool("strict", false, "treat warnings as errors")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: migratelint [flags] [dir]\n\nLints golang-migrate SQL migrations in dir (default \"migrations\").\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dir := "migrations"
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	var opts []migrations.Option
+	if *schemas != "" {
+		opts = append(opts, migrations.WithOwnedSchemas(strings.Split(*schemas, ",")...))
+	}
+	if *allowMissingDown {
+		opts = append(opts, migrations.WithAllowMissingDown())
+	}
+	if *strict {
+		opts = append(opts, migrations.WithWarningsAsErrors())
+	}
+
+	report, err := migrations.Lint(os.DirFS(dir), opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	for _, issue := range report.Issues {
+		fmt.Println(issue)
+	}
+
+	errorCount := len(report.Errors())
+	fmt.Fprintf(os.Stderr, "%d migrations, %d issues (%d errors)\n", report.Migrations, len(report.Issues), errorCount)
+	if errorCount > 0 {
+		os.Exit(1)
+	}
+}
@@ -156,22 +156,11 @@ func main() {
 
 	// Demonstrate an application error
 	engine.GET("/fail", func(c *gin.Context) {
-		ctx := c.Request.Context()
-
-		// Create a span for error demonstration
-		if obs != nil {
-			var span trace.Span
-			ctx, span = obs.StartSpan(ctx, "demo.error")
-			defer span.End()
-		}
-
-		err := apperr.New(apperr.ErrorCodeInternal).WithMessage("demo error")
-
-		// Record error in span and log with context
-		if obs != nil {
-			observability.RecordSpanError(ctx, err)
-		}
-		log.ErrorFCtx(ctx, "demo error occurred error=%v", err)
+		// Measure wraps the block in a span, records its duration and error
+		// metrics, and logs the failure with context
+		err := observability.Measure(c.Request.Context(), "demo.error", func(ctx context.Context) error {
+			return apperr.New(apperr.ErrorCodeInternal).WithMessage("demo error")
+		}, observability.WithMeasureLogger(log))
 
 		response.HandleError(c, err)
 	})
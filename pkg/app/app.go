@@ -11,6 +11,8 @@ package app
 
 import (
 	"context"
+	"errors"
+	"net"
 	"strings"
 	"time"
 
@@ -47,6 +49,12 @@ type Context struct {
 	Validator      *validator.Validator
 	AuditPublisher audit.Publisher
 	Observability  observability.ObservabilityIface
+	// Startup traces the startup phases. OnSetup runs inside its
+	// service.setup phase, so phases it starts, such as db.connect,
+	// migrations or permissions.bootstrap, are nested under it:
+	//
+	//	err := ctx.Startup.Phase("db.connect", func(c context.Context) error { ... })
+	Startup *observability.StartupTrace
 }
 
 // App holds the builder configuration for a service.
@@ -201,8 +209,10 @@ func (a *App) OnShutdown(fn ShutdownFunc) *App {
 func (a *App) Run() {
 	// 1. Logger
 	log := logger.MustNewDefaultLogger()
+	startup := observability.NewStartupTrace(log)
 
 	// 2. Config
+	_, endConfigLoad := startup.Begin("config.load")
 	configOpts := []config.Option{
 		config.WithDefaults(map[string]any{
 			"service_name":    a.serviceName,
@@ -212,17 +222,21 @@ func (a *App) Run() {
 	}
 	configOpts = append(configOpts, a.configOptions...)
 	cfg := config.New(configOpts...)
+	endConfigLoad(nil)
 
 	// 3. Runtime config resolution
 	if a.runtimeConfigEnabled {
+		_, endRuntimeConfig := startup.Begin("config.runtime")
 		if a.runtimeConfigOpts.BootstrapPath == "" {
 			a.runtimeConfigOpts.BootstrapPath = a.configFile
 		}
 		runtimeCtx, runtimeCancel := context.WithTimeout(context.Background(), a.runtimeConfigTimeout)
 		result, err := runtimeconfig.ResolveInto(runtimeCtx, cfg, log, a.runtimeConfigOpts)
 		runtimeCancel()
+		endRuntimeConfig(err)
 		if err != nil {
 			log.ErrorF("failed to load runtime configuration: %v", err)
+			startup.End(err)
 			return
 		}
 		if result.Version != "" {
@@ -234,11 +248,18 @@ func (a *App) Run() {
 
 	// 4. Config validation
 	if a.configValidator != nil {
+		_, endValidate := startup.Begin("config.validate")
 		valid, err := a.configValidator(cfg)
 		if !valid {
+			if err == nil {
+				err = errors.New("invalid configuration")
+			}
+			endValidate(err)
 			log.ErrorF("error validating configurations: %v", err)
+			startup.End(err)
 			return
 		}
+		endValidate(nil)
 	}
 
 	// 5. SigNoz endpoint normalization
@@ -248,9 +269,14 @@ func (a *App) Run() {
 		}
 	}
 
+	// Report later reloads against the resolved config, so values merged
+	// above are not delivered as changes.
+	cfg.NotifyChanges()
+
 	// 6. Observability (SigNoz logger + tracing)
 	var obs observability.ObservabilityIface
 	if a.observabilityEnabled {
+		_, endObservability := startup.Begin("observability.init")
 		if signozLogger, loggerErr := observability.NewLoggerWithSigNoz(cfg, logger.LoggerOptions{
 			Level:        "info",
 			Encoding:     "console",
@@ -260,11 +286,13 @@ func (a *App) Run() {
 			log.WarnF("failed to initialize SigNoz log exporter: %v", loggerErr)
 		} else {
 			log = signozLogger
+			startup.SetLogger(log)
 			log.InfoF("SigNoz log exporter enabled")
 		}
 
 		var obsErr error
 		obs, obsErr = observability.New(log, cfg)
+		endObservability(obsErr)
 		if obsErr != nil {
 			log.WarnF("failed to initialize observability: %v", obsErr)
 		} else {
@@ -275,18 +303,29 @@ func (a *App) Run() {
 					log.WarnF("failed to shutdown observability: %v", shutdownErr)
 				}
 			}()
+			// Fatal logs skip deferred calls, so they run the cleanup as a
+			// logger shutdown hook instead. Each hook is unregistered before
+			// its deferred cleanup runs.
+			defer logger.RegisterShutdownHook(obs.Shutdown)()
+			// Export the phases so far under service.startup.
+			startup.Attach(obs.GetTracer())
 		}
 	}
 
+	defer WatchLogLevel(cfg, log)()
+
 	// 7. Audit publisher
 	var auditPublisher audit.Publisher
 	if a.auditEnabled {
+		_, endAudit := startup.Begin("audit.init")
 		auditPublisher = audit.NewKafkaPublisherFromConfig(log, cfg)
+		endAudit(nil)
 		defer func() {
 			if err := auditPublisher.Close(); err != nil {
 				log.WarnF("failed to close audit publisher: %v", err)
 			}
 		}()
+		defer logger.RegisterShutdownHook(func(context.Context) error { return auditPublisher.Close() })()
 	}
 
 	// 8. Validator
@@ -299,31 +338,45 @@ func (a *App) Run() {
 		Validator:      v,
 		AuditPublisher: auditPublisher,
 		Observability:  obs,
+		Startup:        startup,
 	}
 
 	// 9. Service-specific setup
 	var setupResult *SetupResult
 	if a.setupFn != nil {
-		var err error
-		setupResult, err = a.setupFn(appCtx)
+		err := startup.Phase("service.setup", func(context.Context) error {
+			var err error
+			setupResult, err = a.setupFn(appCtx)
+			return err
+		})
 		if err != nil {
 			log.ErrorF("failed to setup service: %v", err)
+			startup.End(err)
 			return
 		}
 	}
-	defer func() {
+	shutdown := func() {
 		runShutdownHooks(log, appCtx, a.shutdownFns, "app")
 		if setupResult != nil {
 			runShutdownHooks(log, appCtx, setupResult.Shutdown, "setup")
 		}
-	}()
+	}
+	defer shutdown()
+	defer logger.RegisterShutdownHook(func(context.Context) error {
+		shutdown()
+		return nil
+	})()
 
 	// 10. Build engine with standard middleware
+	_, endEngine := startup.Begin("engine.build")
+	rateLimit := BuildRateLimitConfig(cfg)
+	defer WatchRateLimit(cfg, rateLimit)()
 	engineOpts := []server.EngineOption{
 		server.WithLogger(log),
 		server.WithRecovery(true),
 		server.WithPrometheus(true),
-		server.WithRateLimit(BuildRateLimitConfig(cfg)),
+		server.WithRuntimeMetrics(cfg.GetBoolD("RuntimeMetricsEnabled", true)),
+		server.WithRateLimit(rateLimit),
 		server.WithCors(BuildCorsConfig(cfg)),
 		server.WithSecurityHeaders(servermiddleware.DefaultSecurityHeadersConfig()),
 		server.WithValidator(v),
@@ -335,18 +388,17 @@ func (a *App) Run() {
 	if setupResult != nil && len(setupResult.Middleware) > 0 {
 		engineOpts = append(engineOpts, server.WithMiddleware(setupResult.Middleware...))
 	}
-	engineOpts = append(engineOpts, a.engineOptions...)
-	engine := server.NewEngine(engineOpts...)
-
 	// Observability middleware
 	if obs != nil {
 		serviceName := cfg.GetString("service_name")
 		if serviceName == "" {
 			serviceName = a.serviceName
 		}
-		engine.Use(observability.GinMiddleware(serviceName))
+		engineOpts = append(engineOpts, server.WithTracing(observability.GinMiddleware(serviceName)))
 		log.InfoF("observability middleware enabled for service: %s", serviceName)
 	}
+	engineOpts = append(engineOpts, a.engineOptions...)
+	engine := server.NewEngine(engineOpts...)
 
 	// 11. Register routes
 	if a.routesFn != nil {
@@ -357,17 +409,28 @@ func (a *App) Run() {
 	for _, r := range engine.Routes() {
 		log.InfoF("route registered: %s %s", r.Method, r.Path)
 	}
+	endEngine(nil)
 
 	// 12. Post-setup hooks (background workers, etc.)
-	for _, fn := range a.postSetupFns {
-		fn(appCtx)
+	if len(a.postSetupFns) > 0 {
+		_ = startup.Phase("service.post_setup", func(context.Context) error {
+			for _, fn := range a.postSetupFns {
+				fn(appCtx)
+			}
+			return nil
+		})
 	}
 
-	// 13. Start server
+	// 13. Start server; startup ends once the listener is bound.
+	_, endBind := startup.Begin("server.bind")
 	startOpts := []server.StartOption{
 		server.StartWithLogger(log),
 		server.StartWithConfig(cfg),
 		server.StartWithAddr(":" + a.servicePort),
+		server.StartWithOnListen(func(_ net.Addr, err error) {
+			endBind(err)
+			startup.End(err)
+		}),
 	}
 	startOpts = append(startOpts, a.startOptions...)
 
@@ -391,6 +454,17 @@ func runShutdownHooks(log logger.LogManager, ctx Context, hooks []ShutdownFunc,
 // BuildRateLimitConfig creates a RateLimitConfig from the service config.
 // Exported so services can customize or override.
 func BuildRateLimitConfig(cfg *config.Config) *servermiddleware.RateLimitConfig {
+	enabled, rps, burst := rateLimitSettings(cfg)
+
+	cleanup := cfg.GetDurationD("RateLimitCleanupInterval", 5*time.Minute)
+	if cleanup <= 0 {
+		cleanup = 5 * time.Minute
+	}
+
+	return servermiddleware.NewRateLimitConfig(enabled, rps, burst, cleanup)
+}
+
+func rateLimitSettings(cfg *config.Config) (bool, float64, int) {
 	enabled := cfg.GetBoolD("RateLimitEnabled", true)
 
 	rps := cfg.GetIntD("RateLimitRPS", 120)
@@ -402,13 +476,50 @@ func BuildRateLimitConfig(cfg *config.Config) *servermiddleware.RateLimitConfig
 	if burst <= 0 {
 		burst = 240
 	}
+	return enabled, float64(rps), burst
+}
 
-	cleanup := cfg.GetDurationD("RateLimitCleanupInterval", 5*time.Minute)
-	if cleanup <= 0 {
-		cleanup = 5 * time.Minute
+// WatchRateLimit applies changes of RateLimitEnabled, RateLimitRPS and
+// RateLimitBurst to rl when the config reloads, without a restart. It returns
+// a function that stops watching.
+func WatchRateLimit(cfg *config.Config, rl *servermiddleware.RateLimitConfig) (stop func()) {
+	apply := func(config.KeyChange) {
+		rl.Update(rateLimitSettings(cfg))
+	}
+	stops := []func(){
+		cfg.OnKeyChange("RateLimitEnabled", apply),
+		cfg.OnKeyChange("RateLimitRPS", apply),
+		cfg.OnKeyChange("RateLimitBurst", apply),
+	}
+	return func() {
+		for _, stop := range stops {
+			stop()
+		}
 	}
+}
 
-	return servermiddleware.NewRateLimitConfig(enabled, float64(rps), burst, cleanup)
+// LogLevelKey is the config key holding the log level, e.g. "debug".
+const LogLevelKey = "log.level"
+
+// WatchLogLevel sets the level of log from LogLevelKey, now and whenever the
+// config reloads, so a service can switch to debug logging without a restart.
+// Invalid levels are logged and ignored. It returns a function that stops
+// watching.
+func WatchLogLevel(cfg *config.Config, log logger.LogManager) (stop func()) {
+	apply := func(level string) {
+		if level == "" {
+			return
+		}
+		if err := log.SetLogLevel(level); err != nil {
+			log.WarnF("invalid %s %q: %v", LogLevelKey, level, err)
+			return
+		}
+		log.InfoF("log level set to %s", level)
+	}
+	apply(cfg.GetString(LogLevelKey))
+	return cfg.OnKeyChange(LogLevelKey, func(config.KeyChange) {
+		apply(cfg.GetString(LogLevelKey))
+	})
 }
 
 // BuildCorsConfig creates a CorsConfig from the service config.
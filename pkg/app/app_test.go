@@ -4,15 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/gin-gonic/gin"
+	"github.com/milan604/core-lab/pkg/config"
 	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/server"
 )
 
 type testLogger struct {
-	warns []string
+	warns  []string
+	levels []string
 }
 
 func (l *testLogger) Debug(args ...any)                 {}
@@ -24,12 +30,22 @@ func (l *testLogger) InfoF(format string, args ...any)  {}
 func (l *testLogger) ErrorF(format string, args ...any) {}
 func (l *testLogger) DebugFCtx(context.Context, string, ...any) {
 }
-func (l *testLogger) InfoFCtx(context.Context, string, ...any)  {}
-func (l *testLogger) WarnFCtx(context.Context, string, ...any)  {}
-func (l *testLogger) ErrorFCtx(context.Context, string, ...any) {}
-func (l *testLogger) With(keyValues ...any) logger.LogManager   { return l }
-func (l *testLogger) Sync() error                               { return nil }
-func (l *testLogger) SetLogLevel(level string) error            { return nil }
+func (l *testLogger) InfoFCtx(context.Context, string, ...any)   {}
+func (l *testLogger) WarnFCtx(context.Context, string, ...any)   {}
+func (l *testLogger) ErrorFCtx(context.Context, string, ...any)  {}
+func (l *testLogger) DPanic(args ...any)                         {}
+func (l *testLogger) DPanicF(string, ...any)                     {}
+func (l *testLogger) DPanicFCtx(context.Context, string, ...any) {}
+func (l *testLogger) Fatal(args ...any)                          {}
+func (l *testLogger) FatalF(string, ...any)                      {}
+func (l *testLogger) FatalFCtx(context.Context, string, ...any)  {}
+func (l *testLogger) With(keyValues ...any) logger.LogManager    { return l }
+func (l *testLogger) Sync() error                                { return nil }
+
+func (l *testLogger) SetLogLevel(level string) error {
+	l.levels = append(l.levels, level)
+	return nil
+}
 
 func (l *testLogger) WarnF(format string, args ...any) {
 	l.warns = append(l.warns, fmt.Sprintf(format, args...))
@@ -66,3 +82,64 @@ func TestRunShutdownHooksRunsInReverseOrderAndContinuesAfterErrors(t *testing.T)
 		t.Fatalf("unexpected warning %q", got)
 	}
 }
+
+func TestWatchersApplyReloadedSettings(t *testing.T) {
+	cfg := config.New(config.WithDefaults(map[string]any{"log.level": "info", "RateLimitRPS": 10}))
+	log := &testLogger{}
+	stopLog := WatchLogLevel(cfg, log)
+	defer stopLog()
+
+	rl := BuildRateLimitConfig(cfg)
+	stopRateLimit := WatchRateLimit(cfg, rl)
+
+	cfg.Set("log.level", "debug")
+	cfg.Set("RateLimitRPS", 5)
+	cfg.Set("RateLimitEnabled", false)
+	cfg.NotifyChanges()
+
+	if !reflect.DeepEqual(log.levels, []string{"info", "debug"}) {
+		t.Fatalf("levels = %v", log.levels)
+	}
+	if rl.RPS != 5 || rl.Enabled || rl.Burst != 240 {
+		t.Fatalf("rate limit = rps %v burst %d enabled %v", rl.RPS, rl.Burst, rl.Enabled)
+	}
+
+	stopRateLimit()
+	cfg.Set("RateLimitRPS", 50)
+	cfg.NotifyChanges()
+	if rl.RPS != 5 {
+		t.Fatalf("rate limit updated after stop: %v", rl.RPS)
+	}
+}
+
+func TestRateLimitReloadEnablesLimiterDisabledAtStartup(t *testing.T) {
+	cfg := config.New(config.WithDefaults(map[string]any{"RateLimitEnabled": false, "RateLimitRPS": 1, "RateLimitBurst": 1}))
+	rl := BuildRateLimitConfig(cfg)
+	defer WatchRateLimit(cfg, rl)()
+
+	// Wired the way Run builds its engine.
+	engine := server.NewEngine(server.WithLogger(&testLogger{}), server.WithRateLimit(rl))
+	engine.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+	serve := func() int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		engine.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := serve(); code != http.StatusOK {
+			t.Fatalf("request %d with the limiter disabled = %d, want %d", i, code, http.StatusOK)
+		}
+	}
+
+	cfg.Set("RateLimitEnabled", true)
+	cfg.NotifyChanges()
+	if code := serve(); code != http.StatusOK {
+		t.Fatalf("first request after reload = %d, want %d", code, http.StatusOK)
+	}
+	if code := serve(); code != http.StatusTooManyRequests {
+		t.Fatalf("second request after reload = %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
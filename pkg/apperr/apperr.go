@@ -11,6 +11,9 @@ import (
 type Suggestion struct {
 	Field   string `json:"field"`
 	Message string `json:"message"`
+	// key and params translate Message; see AddSuggestionKey.
+	key    string
+	params map[string]any
 }
 
 // AppError is the canonical error shape used across handlers and serialized to clients.
@@ -21,6 +24,9 @@ type AppError struct {
 	HTTPStatus  int          `json:"-"`
 	cause       error        `json:"-"`
 	ec          *ErrorCode   `json:"-"`
+	// messageKey and params translate Message; see Localize.
+	messageKey string
+	params     map[string]any
 }
 
 // New creates a new AppError from an ErrorCode.
@@ -96,6 +102,7 @@ func (a *AppError) WithMessage(msg string) *AppError {
 		return New(ErrorCodeInternal).WithMessage(msg)
 	}
 	a.Message = msg
+	a.messageKey = ""
 	return a
 }
 
@@ -109,7 +116,8 @@ func (a *AppError) WithCode(ec *ErrorCode) *AppError {
 	}
 	a.ec = ec
 	a.Code = ec.Code()
-	a.Message = ec.Message()
+	a.Message = interpolateMessage(ec.Message(), a.params)
+	a.messageKey = ""
 	a.HTTPStatus = ec.HTTPStatus()
 	return a
 }
@@ -0,0 +1,137 @@
+package apperr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// OpenAPIExtension is the OpenAPI vendor extension holding the catalog.
+const OpenAPIExtension = "x-error-codes"
+
+var registry = struct {
+	mu    sync.RWMutex
+	codes map[string]*ErrorCode
+}{codes: map[string]*ErrorCode{}}
+
+// Register adds ec to the catalog exported for client SDKs and returns it, so
+// service codes can be declared and registered at once:
+//
+//	var ErrorCodeOrderLocked = apperr.Register(
+//	  apperr.NewErrorCode("order_locked", "Order {{order_id}} is being updated", 210, http.StatusConflict).
+//	    WithRetryable(true),
+//	)
+//
+// Registering the same code twice with a different definition panics, since
+// clients could not tell the two apart.
+func Register(ec *ErrorCode) *ErrorCode {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if existing, ok := registry.codes[ec.code]; ok && *existing != *ec {
+		panic(fmt.Sprintf("apperr: error code %q registered twice with different definitions", ec.code))
+	}
+	registry.codes[ec.code] = ec
+	return ec
+}
+
+// Lookup returns the registered code named code.
+func Lookup(code string) (*ErrorCode, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	ec, ok := registry.codes[code]
+	return ec, ok
+}
+
+// CatalogEntry describes a registered error code to client SDK generators.
+type CatalogEntry struct {
+	Code       string `json:"code"`
+	HTTPStatus int    `json:"http_status"`
+	Retryable  bool   `json:"retryable"`
+	// Message is the default message template; Placeholders are the names
+	// of its {{placeholders}}, for typed message parameters.
+	Message      string   `json:"message"`
+	Placeholders []string `json:"placeholders,omitempty"`
+	MessageKey   string   `json:"message_key"`
+	Value        int      `json:"value"`
+}
+
+var catalogPlaceholderRe = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_\.]+)\s*\}\}`)
+
+// Catalog returns every registered error code, sorted by code.
+func Catalog() []CatalogEntry {
+	registry.mu.RLock()
+	entries := make([]CatalogEntry, 0, len(registry.codes))
+	for _, ec := range registry.codes {
+		entry := CatalogEntry{
+			Code:       ec.code,
+			HTTPStatus: ec.httpStatus,
+			Retryable:  ec.retryable,
+			Message:    ec.message,
+			MessageKey: ec.MessageKey(),
+			Value:      ec.value,
+		}
+		for _, match := range catalogPlaceholderRe.FindAllStringSubmatch(ec.message, -1) {
+			entry.Placeholders = append(entry.Placeholders, match[1])
+		}
+		entries = append(entries, entry)
+	}
+	registry.mu.RUnlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Code < entries[j].Code })
+	return entries
+}
+
+// WriteCatalogJSON writes the catalog as an indented JSON document,
+// {"x-error-codes": [...]}, for SDK generators that read it directly. Run it
+// from a small command in the service, so its own registered codes are
+// included:
+//
+//	//go:generate go run ./internal/tools/errcatalog -o api/error-codes.json
+func WriteCatalogJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(map[string]any{OpenAPIExtension: Catalog()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// AddToOpenAPI returns the OpenAPI JSON document spec with the catalog
+// added as the top-level x-error-codes extension, and an ErrorCode string
+// schema enumerating the codes in components.schemas, so generated clients
+// get a typed error code. Existing keys of spec are kept.
+func AddToOpenAPI(spec []byte) ([]byte, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("apperr: parse OpenAPI document: %w", err)
+	}
+	if doc == nil {
+		doc = map[string]any{}
+	}
+	catalog := Catalog()
+	doc[OpenAPIExtension] = catalog
+
+	codes := make([]string, len(catalog))
+	for i, entry := range catalog {
+		codes[i] = entry.Code
+	}
+	components, _ := doc["components"].(map[string]any)
+	if components == nil {
+		components = map[string]any{}
+		doc["components"] = components
+	}
+	schemas, _ := components["schemas"].(map[string]any)
+	if schemas == nil {
+		schemas = map[string]any{}
+		components["schemas"] = schemas
+	}
+	schemas["ErrorCode"] = map[string]any{
+		"type":           "string",
+		"description":    "Error code returned in the code field of error responses; see " + OpenAPIExtension + ".",
+		"enum":           codes,
+		OpenAPIExtension: catalog,
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
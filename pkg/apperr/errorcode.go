@@ -2,16 +2,30 @@ package apperr
 
 import "net/http"
 
-// Predefined standard error codes (can be extended)
+// Predefined standard error codes (can be extended). All but ErrorCodeSuccess
+// are registered in the catalog.
 var (
-	ErrorCodeSuccess        = NewErrorCode("success", "OK", 0, http.StatusOK)
-	ErrorCodeInvalidRequest = NewErrorCode("invalid_request", "Invalid request body", 10, http.StatusBadRequest)
-	ErrorCodeInvalidInput   = NewErrorCode("invalid_input", "Invalid input", 20, http.StatusUnprocessableEntity)
-	ErrorCodeValidationFail = NewErrorCode("validation_failed", "Validation failed", 30, http.StatusUnprocessableEntity)
-	ErrorCodeUnauthorized   = NewErrorCode("unauthorized", "Unauthorized", 40, http.StatusUnauthorized)
-	ErrorCodeForbidden      = NewErrorCode("forbidden", "Forbidden", 50, http.StatusForbidden)
-	ErrorCodeNotFound       = NewErrorCode("not_found", "Not found", 60, http.StatusNotFound)
-	ErrorCodeInternal       = NewErrorCode("internal_error", "Internal server error", 100, http.StatusInternalServerError)
+	ErrorCodeSuccess              = NewErrorCode("success", "OK", 0, http.StatusOK)
+	ErrorCodeInvalidRequest       = Register(NewErrorCode("invalid_request", "Invalid request body", 10, http.StatusBadRequest))
+	ErrorCodeInvalidInput         = Register(NewErrorCode("invalid_input", "Invalid input", 20, http.StatusUnprocessableEntity))
+	ErrorCodeUnprocessableEntity  = Register(NewErrorCode("unprocessable_entity", "Unprocessable entity", 25, http.StatusUnprocessableEntity))
+	ErrorCodeValidationFail       = Register(NewErrorCode("validation_failed", "Validation failed", 30, http.StatusUnprocessableEntity))
+	ErrorCodeUnauthorized         = Register(NewErrorCode("unauthorized", "Unauthorized", 40, http.StatusUnauthorized))
+	ErrorCodeForbidden            = Register(NewErrorCode("forbidden", "Forbidden", 50, http.StatusForbidden))
+	ErrorCodeNotFound             = Register(NewErrorCode("not_found", "Not found", 60, http.StatusNotFound))
+	ErrorCodeMethodNotAllowed     = Register(NewErrorCode("method_not_allowed", "Method not allowed", 65, http.StatusMethodNotAllowed))
+	ErrorCodeNotAcceptable        = Register(NewErrorCode("not_acceptable", "Not acceptable", 66, http.StatusNotAcceptable))
+	ErrorCodeUnsupportedMediaType = Register(NewErrorCode("unsupported_media_type", "Unsupported media type", 67, http.StatusUnsupportedMediaType))
+	ErrorCodeConflict             = Register(NewErrorCode("conflict", "Conflict", 70, http.StatusConflict))
+	ErrorCodeAlreadyExists        = Register(NewErrorCode("already_exists", "Already exists", 71, http.StatusConflict))
+	ErrorCodePreconditionFailed   = Register(NewErrorCode("precondition_failed", "Precondition failed", 72, http.StatusPreconditionFailed))
+	ErrorCodeRequestTimeout       = Register(NewErrorCode("request_timeout", "Request timeout", 80, http.StatusRequestTimeout).WithRetryable(true))
+	ErrorCodeTooManyRequests      = Register(NewErrorCode("too_many_requests", "Too many requests", 85, http.StatusTooManyRequests).WithRetryable(true))
+	ErrorCodeInternal             = Register(NewErrorCode("internal_error", "Internal server error", 100, http.StatusInternalServerError))
+	ErrorCodeNotImplemented       = Register(NewErrorCode("not_implemented", "Not implemented", 105, http.StatusNotImplemented))
+	ErrorCodeBadGateway           = Register(NewErrorCode("bad_gateway", "Bad gateway", 110, http.StatusBadGateway).WithRetryable(true))
+	ErrorCodeServiceUnavailable   = Register(NewErrorCode("service_unavailable", "Service unavailable", 120, http.StatusServiceUnavailable).WithRetryable(true))
+	ErrorCodeGatewayTimeout       = Register(NewErrorCode("gateway_timeout", "Gateway timeout", 130, http.StatusGatewayTimeout).WithRetryable(true))
 )
 
 // ErrorCode describes a canonical application error code.
@@ -21,13 +35,39 @@ type ErrorCode struct {
 	message    string
 	value      int
 	httpStatus int
+	retryable  bool
+	messageKey string
 }
 
 func NewErrorCode(code, message string, value, httpStatus int) *ErrorCode {
 	return &ErrorCode{code: code, message: message, value: value, httpStatus: httpStatus}
 }
 
+// WithRetryable marks whether a request failing with the code may succeed
+// when retried unchanged. Set it where the code is declared.
+func (ec *ErrorCode) WithRetryable(retryable bool) *ErrorCode {
+	ec.retryable = retryable
+	return ec
+}
+
+// WithMessageKey sets the i18n key of the code's message, overriding the
+// default "errors.<code>". Set it where the code is declared.
+func (ec *ErrorCode) WithMessageKey(key string) *ErrorCode {
+	ec.messageKey = key
+	return ec
+}
+
 func (ec *ErrorCode) Code() string    { return ec.code }
 func (ec *ErrorCode) Message() string { return ec.message }
 func (ec *ErrorCode) Value() int      { return ec.value }
 func (ec *ErrorCode) HTTPStatus() int { return ec.httpStatus }
+func (ec *ErrorCode) Retryable() bool { return ec.retryable }
+
+// MessageKey returns the i18n key of the code's message: the key set with
+// WithMessageKey, or "errors.<code>".
+func (ec *ErrorCode) MessageKey() string {
+	if ec.messageKey != "" {
+		return ec.messageKey
+	}
+	return "errors." + ec.code
+}
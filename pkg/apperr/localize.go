@@ -0,0 +1,113 @@
+package apperr
+
+import (
+	"fmt"
+)
+
+// Translator translates message keys into one language. *i18n.Localizer
+// implements it, so errors can be localized with i18n.FromContext(ctx).
+type Translator interface {
+	// Translate returns the message of key with data interpolated, and
+	// whether the key was found.
+	Translate(key string, data map[string]any, n ...int) (string, bool)
+}
+
+// WithParams fills the {{placeholders}} of the code's message template with
+// params, e.g. "Order {{order_id}} is being updated", and keeps them for
+// Localize. Placeholders without a param are left as is.
+func (a *AppError) WithParams(params map[string]any) *AppError {
+	if a == nil {
+		a = New(ErrorCodeInternal)
+	}
+	a.params = params
+	if a.ec != nil && a.messageKey == "" && a.Message == a.ec.Message() {
+		a.Message = interpolateMessage(a.ec.Message(), params)
+	}
+	return a
+}
+
+// WithMessageKey sets a message that replaces the code's default together
+// with its i18n key, so Localize can still translate it. Params set with
+// WithParams are interpolated into the translation.
+func (a *AppError) WithMessageKey(key, message string) *AppError {
+	if a == nil {
+		a = New(ErrorCodeInternal)
+	}
+	a.messageKey = key
+	a.Message = message
+	return a
+}
+
+// AddSuggestionKey appends a field suggestion with the i18n key and params
+// Localize translates it with. message is used when no translation exists.
+func (a *AppError) AddSuggestionKey(field, key string, params map[string]any, message string) *AppError {
+	if a == nil {
+		a = New(ErrorCodeInternal)
+	}
+	a.Suggestions = append(a.Suggestions, Suggestion{
+		Field:   field,
+		Message: message,
+		key:     key,
+		params:  params,
+	})
+	return a
+}
+
+// Localize returns a copy of a with its message and suggestions translated
+// by tr. The message is translated when it is the code's default (see
+// ErrorCode.MessageKey) or was set with WithMessageKey; messages set with
+// WithMessage or Newf are kept. Suggestions are translated when added with
+// AddSuggestionKey. Anything tr cannot translate keeps its text.
+func (a *AppError) Localize(tr Translator) *AppError {
+	if a == nil || tr == nil {
+		return a
+	}
+	out := *a
+	if key := a.localizationKey(); key != "" {
+		if msg, ok := tr.Translate(key, a.params); ok {
+			out.Message = msg
+		}
+	}
+	if len(a.Suggestions) > 0 {
+		out.Suggestions = append([]Suggestion(nil), a.Suggestions...)
+		for i, s := range out.Suggestions {
+			if s.key == "" {
+				continue
+			}
+			if msg, ok := tr.Translate(s.key, s.params); ok {
+				out.Suggestions[i].Message = msg
+			}
+		}
+	}
+	return &out
+}
+
+// localizationKey returns the i18n key of the message, or "" when the
+// message was customized without one.
+func (a *AppError) localizationKey() string {
+	if a.messageKey != "" {
+		return a.messageKey
+	}
+	if a.ec == nil {
+		return ""
+	}
+	if a.Message == a.ec.Message() || a.Message == interpolateMessage(a.ec.Message(), a.params) {
+		return a.ec.MessageKey()
+	}
+	return ""
+}
+
+// interpolateMessage replaces the {{name}} placeholders of template with
+// params.
+func interpolateMessage(template string, params map[string]any) string {
+	if len(params) == 0 {
+		return template
+	}
+	return catalogPlaceholderRe.ReplaceAllStringFunc(template, func(match string) string {
+		name := catalogPlaceholderRe.FindStringSubmatch(match)[1]
+		if v, ok := params[name]; ok {
+			return fmt.Sprint(v)
+		}
+		return match
+	})
+}
@@ -0,0 +1,311 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// MetadataChangesKey is the event metadata key holding the []Change captured
+// during a request.
+const MetadataChangesKey = "changes"
+
+// Change operations.
+const (
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+var defaultDiffExclude = []string{"updated_at"}
+
+// FieldChange is the before and after value of one field. A nil side means the
+// field did not exist, as on create or delete.
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Change is the field-level diff of one mutated entity.
+type Change struct {
+	Resource   string `json:"resource"`
+	ResourceID string `json:"resource_id,omitempty"`
+	Operation  string `json:"operation"`
+	// Fields is keyed by the dotted path of each changed field (e.g. "address.city").
+	Fields map[string]FieldChange `json:"fields"`
+}
+
+// DiffOptions configures Diff.
+type DiffOptions struct {
+	// Exclude lists fields left out of the diff entirely, matched by name at any
+	// depth or by dotted path. Defaults to "updated_at".
+	Exclude []string
+	// Redactor masks the values of sensitive fields; the field is still
+	// reported as changed. Defaults to DefaultRedactor().
+	Redactor *Redactor
+}
+
+// Diff compares two snapshots of an entity and returns the changed fields.
+// Structs are compared by their JSON form, so field names follow json tags
+// and nested objects are flattened into dotted paths. Either side may be nil.
+func Diff(before, after any, opts DiffOptions) (map[string]FieldChange, error) {
+	beforeFields, err := snapshot(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := snapshot(after)
+	if err != nil {
+		return nil, err
+	}
+	return diffSnapshots(beforeFields, afterFields, opts), nil
+}
+
+func diffSnapshots(before, after map[string]any, opts DiffOptions) map[string]FieldChange {
+	exclude := opts.Exclude
+	if exclude == nil {
+		exclude = defaultDiffExclude
+	}
+	excluded := NewRedactor(exclude...)
+	redactor := opts.Redactor
+	if redactor == nil {
+		redactor = DefaultRedactor()
+	}
+
+	beforeFlat, afterFlat := map[string]any{}, map[string]any{}
+	flatten("", before, beforeFlat)
+	flatten("", after, afterFlat)
+
+	changes := map[string]FieldChange{}
+	record := func(path string, beforeValue, afterValue any) {
+		if pathMatches(excluded, path) {
+			return
+		}
+		if pathMatches(redactor, path) {
+			changes[path] = FieldChange{Before: maskPresent(beforeValue), After: maskPresent(afterValue)}
+			return
+		}
+		changes[path] = FieldChange{Before: redactor.redactValue(beforeValue), After: redactor.redactValue(afterValue)}
+	}
+	for path, beforeValue := range beforeFlat {
+		afterValue, ok := afterFlat[path]
+		if ok && reflect.DeepEqual(beforeValue, afterValue) {
+			continue
+		}
+		record(path, beforeValue, afterValue)
+	}
+	for path, afterValue := range afterFlat {
+		if _, ok := beforeFlat[path]; !ok {
+			record(path, nil, afterValue)
+		}
+	}
+	return changes
+}
+
+// snapshot converts v to its JSON object form. Numbers are kept as
+// json.Number so large integers compare exactly.
+func snapshot(v any) (map[string]any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return nil, nil
+	}
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("audit: snapshot %T: %w", v, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var fields map[string]any
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("audit: snapshot %T: not an object", v)
+	}
+	return fields, nil
+}
+
+// flatten writes the leaves of fields into out keyed by dotted path. Empty
+// objects and arrays are leaves.
+func flatten(prefix string, fields map[string]any, out map[string]any) {
+	for key, value := range fields {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]any); ok && len(nested) > 0 {
+			flatten(path, nested, out)
+			continue
+		}
+		out[path] = value
+	}
+}
+
+// pathMatches reports whether r matches the whole dotted path or any of its
+// segments.
+func pathMatches(r *Redactor, path string) bool {
+	if r.matches(path) {
+		return true
+	}
+	for _, segment := range strings.Split(path, ".") {
+		if r.matches(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+func maskPresent(value any) any {
+	if value == nil {
+		return nil
+	}
+	return RedactedValue
+}
+
+// ChangeSet collects entity snapshots taken while a request is handled. The
+// audit Middleware installs one in the request context when CaptureChanges is
+// set and attaches the resulting diffs to the event. It is safe for
+// concurrent use.
+type ChangeSet struct {
+	mu      sync.Mutex
+	order   []string
+	entries map[string]*changeEntry
+	err     error
+}
+
+type changeEntry struct {
+	resource   string
+	resourceID string
+	before     map[string]any
+	after      map[string]any
+	hasBefore  bool
+	hasAfter   bool
+}
+
+// NewChangeSet returns an empty ChangeSet.
+func NewChangeSet() *ChangeSet {
+	return &ChangeSet{entries: map[string]*changeEntry{}}
+}
+
+// CaptureBefore snapshots entity before it is mutated. Snapshots are taken
+// immediately, so the caller may go on to modify entity in place. When the
+// same entity is captured several times the first snapshot wins, so the diff
+// spans the whole request.
+func (s *ChangeSet) CaptureBefore(resource, resourceID string, entity any) error {
+	return s.capture(resource, resourceID, entity, false)
+}
+
+// CaptureAfter snapshots entity after it is mutated; pass a nil entity for a
+// delete. Without a prior CaptureBefore the change is recorded as a create.
+func (s *ChangeSet) CaptureAfter(resource, resourceID string, entity any) error {
+	return s.capture(resource, resourceID, entity, true)
+}
+
+func (s *ChangeSet) capture(resource, resourceID string, entity any, after bool) error {
+	if s == nil {
+		return nil
+	}
+	fields, err := snapshot(entity)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		if s.err == nil {
+			s.err = err
+		}
+		return err
+	}
+
+	resource, resourceID = strings.TrimSpace(resource), strings.TrimSpace(resourceID)
+	key := resource + "\x00" + resourceID
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &changeEntry{resource: resource, resourceID: resourceID}
+		s.entries[key] = entry
+		s.order = append(s.order, key)
+	}
+	switch {
+	case after:
+		entry.after, entry.hasAfter = fields, true
+	case !entry.hasBefore:
+		entry.before, entry.hasBefore = fields, true
+	}
+	return nil
+}
+
+// Changes diffs every entity captured after its mutation, in capture order.
+// Entities whose diff is empty are omitted. The error reports the first
+// snapshot that could not be taken.
+func (s *ChangeSet) Changes(opts DiffOptions) ([]Change, error) {
+	if s == nil {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changes []Change
+	for _, key := range s.order {
+		entry := s.entries[key]
+		if !entry.hasAfter {
+			continue
+		}
+		operation := OperationUpdate
+		switch {
+		case entry.before == nil && entry.after == nil:
+			continue
+		case entry.before == nil:
+			operation = OperationCreate
+		case entry.after == nil:
+			operation = OperationDelete
+		}
+		fields := diffSnapshots(entry.before, entry.after, opts)
+		if len(fields) == 0 {
+			continue
+		}
+		changes = append(changes, Change{
+			Resource:   entry.resource,
+			ResourceID: entry.resourceID,
+			Operation:  operation,
+			Fields:     fields,
+		})
+	}
+	return changes, s.err
+}
+
+type changeSetContextKey struct{}
+
+// ContextWithChangeSet returns a copy of ctx carrying set.
+func ContextWithChangeSet(ctx context.Context, set *ChangeSet) context.Context {
+	return context.WithValue(ctx, changeSetContextKey{}, set)
+}
+
+// ChangeSetFromContext returns the ChangeSet installed by the audit
+// Middleware, if any.
+func ChangeSetFromContext(ctx context.Context) (*ChangeSet, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	set, ok := ctx.Value(changeSetContextKey{}).(*ChangeSet)
+	return set, ok && set != nil
+}
+
+// CaptureBefore snapshots entity into the ChangeSet of ctx. It is a no-op
+// outside an audited request.
+//
+//	audit.CaptureBefore(ctx, "plan", plan.ID, plan)
+//	plan.Price = req.Price
+//	// save plan
+//	audit.CaptureAfter(ctx, "plan", plan.ID, plan)
+func CaptureBefore(ctx context.Context, resource, resourceID string, entity any) error {
+	set, _ := ChangeSetFromContext(ctx)
+	return set.CaptureBefore(resource, resourceID, entity)
+}
+
+// CaptureAfter snapshots entity into the ChangeSet of ctx; pass nil for a
+// delete. It is a no-op outside an audited request.
+func CaptureAfter(ctx context.Context, resource, resourceID string, entity any) error {
+	set, _ := ChangeSetFromContext(ctx)
+	return set.CaptureAfter(resource, resourceID, entity)
+}
@@ -0,0 +1,141 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testAddress struct {
+	City string `json:"city"`
+	Zip  string `json:"zip"`
+}
+
+type testAccount struct {
+	ID        string      `json:"id"`
+	Plan      string      `json:"plan"`
+	Seats     int64       `json:"seats"`
+	Password  string      `json:"password"`
+	Address   testAddress `json:"address"`
+	Tags      []string    `json:"tags"`
+	UpdatedAt string      `json:"updated_at"`
+}
+
+func TestDiffFlattensExcludesAndMasks(t *testing.T) {
+	before := testAccount{ID: "a-1", Plan: "free", Seats: 9007199254740993, Password: "old", Address: testAddress{City: "Oslo", Zip: "0150"}, Tags: []string{"a"}, UpdatedAt: "t1"}
+	after := before
+	after.Plan = "pro"
+	after.Password = "new"
+	after.Address.City = "Bergen"
+	after.Tags = []string{"a", "b"}
+	after.UpdatedAt = "t2"
+
+	fields, err := Diff(&before, &after, DiffOptions{})
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if len(fields) != 4 {
+		t.Fatalf("fields = %#v, want plan, password, address.city, tags", fields)
+	}
+	if got := fields["plan"]; got.Before != "free" || got.After != "pro" {
+		t.Fatalf("plan = %#v", got)
+	}
+	if got := fields["address.city"]; got.Before != "Oslo" || got.After != "Bergen" {
+		t.Fatalf("address.city = %#v", got)
+	}
+	if got := fields["password"]; got.Before != RedactedValue || got.After != RedactedValue {
+		t.Fatalf("password = %#v, want masked", got)
+	}
+	if _, ok := fields["seats"]; ok {
+		t.Fatal("unchanged large integer reported as changed")
+	}
+
+	created, err := Diff(nil, &after, DiffOptions{Exclude: []string{"address"}, Redactor: NewRedactor()})
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if _, ok := created["address.zip"]; ok {
+		t.Fatal("excluded object was diffed")
+	}
+	if got := created["password"]; got.Before != nil || got.After != "new" {
+		t.Fatalf("password with empty redactor = %#v", got)
+	}
+	if got := created["updated_at"]; got.After != "t2" {
+		t.Fatalf("updated_at with explicit exclude list = %#v", got)
+	}
+
+	if _, err := Diff("plain", nil, DiffOptions{}); err == nil {
+		t.Fatal("Diff of a non-object succeeded")
+	}
+}
+
+func TestMiddlewareAttachesCapturedChanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	publisher := &capturePublisher{}
+	engine := gin.New()
+	engine.Use(Middleware(MiddlewareConfig{
+		Enabled:        true,
+		Service:        "billing-service",
+		Publisher:      publisher,
+		Methods:        defaultAuditedMethods,
+		CaptureChanges: true,
+	}))
+	engine.PATCH("/accounts/:id", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		account := testAccount{ID: c.Param("id"), Plan: "free", Password: "old"}
+		_ = CaptureBefore(ctx, "account", account.ID, &account)
+		account.Plan = "team"
+		_ = CaptureAfter(ctx, "account", account.ID, &account)
+		account.Plan = "pro"
+		account.Password = "new"
+		_ = CaptureAfter(ctx, "account", account.ID, &account)
+
+		_ = CaptureAfter(ctx, "invoice", "inv-1", map[string]any{"id": "inv-1", "total": 10})
+		_ = CaptureBefore(ctx, "session", "s-1", map[string]any{"id": "s-1"})
+		_ = CaptureAfter(ctx, "session", "s-1", nil)
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodPatch, "/accounts/a-1", nil)
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(publisher.events) != 1 {
+		t.Fatalf("expected 1 audit event, got %d", len(publisher.events))
+	}
+	changes, ok := publisher.events[0].Metadata[MetadataChangesKey].([]Change)
+	if !ok || len(changes) != 3 {
+		t.Fatalf("changes = %#v", publisher.events[0].Metadata[MetadataChangesKey])
+	}
+
+	account := changes[0]
+	if account.Resource != "account" || account.ResourceID != "a-1" || account.Operation != OperationUpdate {
+		t.Fatalf("account change = %+v", account)
+	}
+	if got := account.Fields["plan"]; got.Before != "free" || got.After != "pro" {
+		t.Fatalf("plan = %#v, want free -> pro across captures", got)
+	}
+	if got := account.Fields["password"]; got.After != RedactedValue {
+		t.Fatalf("password = %#v, want masked", got)
+	}
+	if changes[1].Operation != OperationCreate || changes[2].Operation != OperationDelete {
+		t.Fatalf("operations = %s, %s", changes[1].Operation, changes[2].Operation)
+	}
+
+	payload, err := json.Marshal(publisher.events[0])
+	if err != nil {
+		t.Fatalf("marshal event: %v", err)
+	}
+	if !json.Valid(payload) {
+		t.Fatal("event is not valid JSON")
+	}
+}
+
+func TestCaptureOutsideAuditedRequestIsNoop(t *testing.T) {
+	if err := CaptureBefore(httptest.NewRequest(http.MethodGet, "/", nil).Context(), "account", "a-1", testAccount{}); err != nil {
+		t.Fatalf("CaptureBefore: %v", err)
+	}
+}
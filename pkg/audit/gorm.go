@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const changeCapturePluginName = "corelab:audit_change_capture"
+
+// ChangeCapture is a GORM plugin that feeds the ChangeSet of the statement
+// context, so repositories get field-level audit diffs without calling
+// CaptureBefore and CaptureAfter by hand:
+//
+//	db.Use(audit.NewChangeCapture())
+//	db.WithContext(c.Request.Context()).Model(&plan).Updates(changes)
+//
+// It handles creates, updates, and deletes of a single model whose primary
+// key is set, reading the row before and after the statement in the same
+// transaction. The table name is used as the resource. Statements without a
+// ChangeSet in their context, batch operations, and conditional updates
+// without a loaded model are left alone.
+type ChangeCapture struct{}
+
+// NewChangeCapture returns the change capture plugin.
+func NewChangeCapture() *ChangeCapture {
+	return &ChangeCapture{}
+}
+
+// Name implements gorm.Plugin.
+func (*ChangeCapture) Name() string {
+	return changeCapturePluginName
+}
+
+// Initialize implements gorm.Plugin.
+func (p *ChangeCapture) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	if err := callback.Update().Before("gorm:update").Register(changeCapturePluginName+":before_update", p.captureBefore); err != nil {
+		return err
+	}
+	if err := callback.Update().After("gorm:update").Register(changeCapturePluginName+":after_update", p.captureAfter); err != nil {
+		return err
+	}
+	if err := callback.Delete().Before("gorm:delete").Register(changeCapturePluginName+":before_delete", p.captureBefore); err != nil {
+		return err
+	}
+	if err := callback.Delete().After("gorm:delete").Register(changeCapturePluginName+":after_delete", p.captureDeleted); err != nil {
+		return err
+	}
+	return callback.Create().After("gorm:create").Register(changeCapturePluginName+":after_create", p.captureAfter)
+}
+
+func (p *ChangeCapture) captureBefore(db *gorm.DB) {
+	set, field, id, ok := p.target(db)
+	if !ok {
+		return
+	}
+	if row, found := loadRow(db, field, id); found {
+		_ = set.CaptureBefore(db.Statement.Table, fmt.Sprint(id), row)
+	}
+}
+
+func (p *ChangeCapture) captureAfter(db *gorm.DB) {
+	if db.Error != nil || db.Statement.RowsAffected == 0 {
+		return
+	}
+	set, field, id, ok := p.target(db)
+	if !ok {
+		return
+	}
+	if row, found := loadRow(db, field, id); found {
+		_ = set.CaptureAfter(db.Statement.Table, fmt.Sprint(id), row)
+	}
+}
+
+func (p *ChangeCapture) captureDeleted(db *gorm.DB) {
+	if db.Error != nil || db.Statement.RowsAffected == 0 {
+		return
+	}
+	set, _, id, ok := p.target(db)
+	if !ok {
+		return
+	}
+	_ = set.CaptureAfter(db.Statement.Table, fmt.Sprint(id), nil)
+}
+
+// target returns the context ChangeSet and the primary key of the statement
+// model, or false when the statement is not a single-model mutation.
+func (*ChangeCapture) target(db *gorm.DB) (*ChangeSet, *schema.Field, any, bool) {
+	stmt := db.Statement
+	if stmt == nil || db.DryRun || stmt.Schema == nil || stmt.Table == "" {
+		return nil, nil, nil, false
+	}
+	set, ok := ChangeSetFromContext(stmt.Context)
+	if !ok {
+		return nil, nil, nil, false
+	}
+	field := stmt.Schema.PrioritizedPrimaryField
+	if field == nil || !stmt.ReflectValue.IsValid() || stmt.ReflectValue.Kind() != reflect.Struct {
+		return nil, nil, nil, false
+	}
+	id, zero := field.ValueOf(stmt.Context, stmt.ReflectValue)
+	if zero {
+		return nil, nil, nil, false
+	}
+	return set, field, id, true
+}
+
+// loadRow reads the current row by primary key on the statement's
+// connection, so it sees uncommitted writes of the surrounding transaction.
+func loadRow(db *gorm.DB, field *schema.Field, id any) (map[string]any, bool) {
+	row := map[string]any{}
+	err := db.Session(&gorm.Session{NewDB: true}).
+		Table(db.Statement.Table).
+		Where(clause.Eq{Column: clause.Column{Name: field.DBName}, Value: id}).
+		Take(&row).
+		Error
+	return row, err == nil
+}
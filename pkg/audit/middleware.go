@@ -32,6 +32,13 @@ type MiddlewareConfig struct {
 	SkipPathPrefixes []string
 	SkipPathSuffixes []string
 	ShouldAudit      func(*gin.Context) bool
+	// Redactor masks sensitive metadata before publishing. Defaults to DefaultRedactor().
+	Redactor *Redactor
+	// CaptureChanges installs a ChangeSet in the request context and attaches
+	// the captured entity diffs to the event metadata under MetadataChangesKey.
+	CaptureChanges bool
+	// DiffExclude lists fields left out of captured diffs. Defaults to "updated_at".
+	DiffExclude []string
 }
 
 func NewMiddlewareConfig(cfg *config.Config, defaultService string, publisher Publisher, log logger.LogManager) MiddlewareConfig {
@@ -59,6 +66,18 @@ func NewMiddlewareConfig(cfg *config.Config, defaultService string, publisher Pu
 		}
 	}
 
+	var redactKeys []string
+	if cfg != nil {
+		redactKeys = splitCSV(cfg.GetString("AuditRedactKeys"))
+	}
+
+	var diffExclude []string
+	if cfg != nil {
+		if configured := splitCSV(cfg.GetString("AuditDiffExclude")); len(configured) > 0 {
+			diffExclude = append(append([]string{}, defaultDiffExclude...), configured...)
+		}
+	}
+
 	return MiddlewareConfig{
 		Enabled:          cfg == nil || cfg.GetBoolD("AuditEnabled", true),
 		Service:          service,
@@ -67,6 +86,9 @@ func NewMiddlewareConfig(cfg *config.Config, defaultService string, publisher Pu
 		Methods:          methods,
 		SkipPathPrefixes: skipPrefixes,
 		SkipPathSuffixes: skipSuffixes,
+		Redactor:         DefaultRedactor(redactKeys...),
+		CaptureChanges:   cfg == nil || cfg.GetBoolD("AuditCaptureChanges", true),
+		DiffExclude:      diffExclude,
 	}
 }
 
@@ -95,8 +117,18 @@ func Middleware(cfg MiddlewareConfig) gin.HandlerFunc {
 	skipPathSuffixes = append(skipPathSuffixes, cfg.SkipPathSuffixes...)
 	cfg.SkipPathSuffixes = skipPathSuffixes
 
+	redactor := cfg.Redactor
+	if redactor == nil {
+		redactor = DefaultRedactor()
+	}
+
 	return func(c *gin.Context) {
 		start := time.Now()
+		var changes *ChangeSet
+		if cfg.CaptureChanges {
+			changes = NewChangeSet()
+			c.Request = c.Request.WithContext(ContextWithChangeSet(c.Request.Context(), changes))
+		}
 		c.Next()
 
 		if !shouldAudit(c, cfg, allowedMethods) {
@@ -135,6 +167,19 @@ func Middleware(cfg MiddlewareConfig) gin.HandlerFunc {
 			}
 		}
 
+		event = redactor.Redact(event)
+
+		// Changes masks redacted fields itself and stays a []Change.
+		if changes != nil {
+			diffs, err := changes.Changes(DiffOptions{Exclude: cfg.DiffExclude, Redactor: redactor})
+			if err != nil && cfg.Logger != nil {
+				cfg.Logger.WarnFCtx(c.Request.Context(), "failed to capture audit changes for %s: %v", event.Action, err)
+			}
+			if len(diffs) > 0 {
+				event.Metadata[MetadataChangesKey] = diffs
+			}
+		}
+
 		if err := cfg.Publisher.Publish(c.Request.Context(), event); err != nil && cfg.Logger != nil {
 			cfg.Logger.WarnFCtx(c.Request.Context(), "failed to enqueue audit event %s (%s): %v", event.Action, event.Resource, err)
 		}
@@ -0,0 +1,141 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// RedactedValue replaces the value of any metadata field matched by a Redactor.
+const RedactedValue = "***REDACTED***"
+
+var defaultRedactKeys = []string{
+	"password",
+	"passwd",
+	"secret",
+	"client_secret",
+	"token",
+	"access_token",
+	"refresh_token",
+	"id_token",
+	"authorization",
+	"cookie",
+	"set_cookie",
+	"api_key",
+	"apikey",
+	"private_key",
+	"credit_card",
+	"card_number",
+	"cvv",
+	"ssn",
+}
+
+// Redactor masks sensitive fields in audit event metadata before it leaves the
+// process. Keys are matched case-insensitively, with "-" treated as "_", at any
+// depth of nested maps and slices. Structs, typed maps and typed slices such
+// as []map[string]any are matched by their JSON field names, the form sinks
+// publish them in.
+type Redactor struct {
+	keys map[string]struct{}
+}
+
+// NewRedactor creates a redactor matching exactly keys.
+func NewRedactor(keys ...string) *Redactor {
+	r := &Redactor{keys: make(map[string]struct{}, len(keys))}
+	for _, key := range keys {
+		if key = normalizeRedactKey(key); key != "" {
+			r.keys[key] = struct{}{}
+		}
+	}
+	return r
+}
+
+// DefaultRedactor returns a redactor for common credential and PII field names,
+// extended with extra.
+func DefaultRedactor(extra ...string) *Redactor {
+	return NewRedactor(append(append([]string{}, defaultRedactKeys...), extra...)...)
+}
+
+// Redact returns a copy of event with sensitive metadata values replaced by
+// RedactedValue. The original event's metadata is left untouched.
+func (r *Redactor) Redact(event Event) Event {
+	if r == nil || len(r.keys) == 0 || len(event.Metadata) == 0 {
+		return event
+	}
+	event.Metadata = r.redactMap(event.Metadata)
+	return event
+}
+
+func (r *Redactor) redactMap(in map[string]any) map[string]any {
+	out := make(map[string]any, len(in))
+	for key, value := range in {
+		if r.matches(key) {
+			out[key] = RedactedValue
+			continue
+		}
+		out[key] = r.redactValue(value)
+	}
+	return out
+}
+
+func (r *Redactor) redactValue(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		return r.redactMap(typed)
+	case map[string]string:
+		out := make(map[string]any, len(typed))
+		for key, v := range typed {
+			if r.matches(key) {
+				out[key] = RedactedValue
+			} else {
+				out[key] = v
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(typed))
+		for i, item := range typed {
+			out[i] = r.redactValue(item)
+		}
+		return out
+	default:
+		normalized, ok := normalizeJSON(value)
+		if !ok {
+			return value
+		}
+		return r.redactValue(normalized)
+	}
+}
+
+// normalizeJSON converts structs, typed maps and typed slices to the
+// map[string]any and []any their JSON encoding decodes to, so redactValue can
+// walk them; ok is false for scalars. Numbers stay json.Number to keep their
+// precision. Values that do not encode as JSON cannot be inspected and are
+// redacted whole.
+func normalizeJSON(value any) (normalized any, ok bool) {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Pointer:
+	default:
+		return nil, false
+	}
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return RedactedValue, true
+	}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&normalized); err != nil {
+		return RedactedValue, true
+	}
+	return normalized, true
+}
+
+func (r *Redactor) matches(key string) bool {
+	_, ok := r.keys[normalizeRedactKey(key)]
+	return ok
+}
+
+func normalizeRedactKey(key string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "-", "_")
+}
@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedactorMasksNestedSensitiveKeys(t *testing.T) {
+	original := Event{
+		Action: "user.update",
+		Metadata: map[string]any{
+			"Password": "hunter2",
+			"profile": map[string]any{
+				"display_name": "Ada",
+				"api-key":      "k-123",
+			},
+			"headers": map[string]string{"Authorization": "Bearer abc"},
+			"items":   []any{map[string]any{"card_number": "4111"}},
+		},
+	}
+
+	redacted := DefaultRedactor().Redact(original)
+
+	if got := redacted.Metadata["Password"]; got != RedactedValue {
+		t.Fatalf("Password = %#v, want redacted", got)
+	}
+	profile := redacted.Metadata["profile"].(map[string]any)
+	if profile["api-key"] != RedactedValue || profile["display_name"] != "Ada" {
+		t.Fatalf("profile = %#v, want api-key redacted and display_name kept", profile)
+	}
+	if got := redacted.Metadata["headers"].(map[string]any)["Authorization"]; got != RedactedValue {
+		t.Fatalf("Authorization = %#v, want redacted", got)
+	}
+	if got := redacted.Metadata["items"].([]any)[0].(map[string]any)["card_number"]; got != RedactedValue {
+		t.Fatalf("card_number = %#v, want redacted", got)
+	}
+	if original.Metadata["Password"] != "hunter2" {
+		t.Fatal("expected original metadata to be left untouched")
+	}
+}
+
+func TestRedactorMasksTypedMetadata(t *testing.T) {
+	type credentials struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}
+	type status string
+
+	redacted := DefaultRedactor().Redact(Event{
+		Metadata: map[string]any{
+			"accounts": []map[string]any{{"id": "a-1", "token": "t-1"}},
+			"login":    credentials{User: "ada", Password: "hunter2"},
+			"previous": &credentials{User: "ada", Password: "hunter1"},
+			"limits":   map[string]int64{"api_key": 42, "max": 9007199254740993},
+			"status":   status("active"),
+		},
+	})
+
+	account := redacted.Metadata["accounts"].([]any)[0].(map[string]any)
+	if account["token"] != RedactedValue || account["id"] != "a-1" {
+		t.Fatalf("accounts[0] = %#v, want token redacted and id kept", account)
+	}
+	for _, key := range []string{"login", "previous"} {
+		login := redacted.Metadata[key].(map[string]any)
+		if login["password"] != RedactedValue || login["user"] != "ada" {
+			t.Fatalf("%s = %#v, want password redacted and user kept", key, login)
+		}
+	}
+	limits := redacted.Metadata["limits"].(map[string]any)
+	if limits["api_key"] != RedactedValue || limits["max"] != json.Number("9007199254740993") {
+		t.Fatalf("limits = %#v, want api_key redacted and max kept exactly", limits)
+	}
+	if got := redacted.Metadata["status"]; got != status("active") {
+		t.Fatalf("status = %#v, want the scalar kept as is", got)
+	}
+}
+
+func TestMiddlewareRedactsMetadataBeforePublishing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	publisher := &capturePublisher{}
+	engine := gin.New()
+	engine.Use(Middleware(MiddlewareConfig{
+		Enabled:   true,
+		Service:   "identity-service",
+		Publisher: publisher,
+		Redactor:  DefaultRedactor("otp"),
+	}))
+	engine.POST("/sessions", func(c *gin.Context) {
+		AddMetadata(c, "otp", "123456")
+		AddMetadata(c, "token", "jwt")
+		AddMetadata(c, "method", "sms")
+		c.Status(http.StatusCreated)
+	})
+
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/sessions", nil))
+
+	if len(publisher.events) != 1 {
+		t.Fatalf("expected 1 audit event, got %d", len(publisher.events))
+	}
+	metadata := publisher.events[0].Metadata
+	if metadata["otp"] != RedactedValue || metadata["token"] != RedactedValue {
+		t.Fatalf("expected otp and token to be redacted, got %#v", metadata)
+	}
+	if metadata["method"] != "sms" {
+		t.Fatalf("expected method to be kept, got %#v", metadata["method"])
+	}
+}
@@ -0,0 +1,385 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/config"
+	"github.com/milan604/core-lab/pkg/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultAuditTable          = "audit_events"
+	defaultWebhookTimeout      = 5 * time.Second
+	defaultStoreAppendTimeout  = 5 * time.Second
+	webhookSignatureHeader     = "X-Audit-Signature"
+	webhookSignatureHashPrefix = "sha256="
+)
+
+// PostgresStore appends audit events to a Postgres table through GORM.
+// It implements Store, so it can back a StorePublisher or a KafkaConsumer sink.
+type PostgresStore struct {
+	db    *gorm.DB
+	table string
+}
+
+// EventRecord is the row shape written by PostgresStore.
+type EventRecord struct {
+	EventID    string    `gorm:"column:event_id;primaryKey"`
+	Timestamp  time.Time `gorm:"column:timestamp;index"`
+	Service    string    `gorm:"column:service;index"`
+	TenantID   string    `gorm:"column:tenant_id;index"`
+	UserID     string    `gorm:"column:user_id;index"`
+	Action     string    `gorm:"column:action"`
+	Resource   string    `gorm:"column:resource"`
+	ResourceID string    `gorm:"column:resource_id"`
+	RequestID  string    `gorm:"column:request_id"`
+	IPAddress  string    `gorm:"column:ip_address"`
+	Status     string    `gorm:"column:status"`
+	Metadata   string    `gorm:"column:metadata;type:jsonb"`
+}
+
+// NewPostgresStore creates a store writing to table (defaults to "audit_events").
+func NewPostgresStore(db *gorm.DB, table string) (*PostgresStore, error) {
+	if db == nil {
+		return nil, stdErrors.New("audit: gorm db is required")
+	}
+	table = strings.TrimSpace(table)
+	if table == "" {
+		table = defaultAuditTable
+	}
+	return &PostgresStore{db: db, table: table}, nil
+}
+
+// AutoMigrate creates or updates the audit table.
+func (s *PostgresStore) AutoMigrate() error {
+	return s.db.Table(s.table).AutoMigrate(&EventRecord{})
+}
+
+// Append inserts event, ignoring duplicates so redelivered events are replay-safe.
+func (s *PostgresStore) Append(ctx context.Context, event Event) error {
+	metadata := "{}"
+	if len(event.Metadata) > 0 {
+		payload, err := json.Marshal(event.Metadata)
+		if err != nil {
+			return fmt.Errorf("marshal audit metadata: %w", err)
+		}
+		metadata = string(payload)
+	}
+
+	record := EventRecord{
+		EventID:    event.EventID,
+		Timestamp:  event.Timestamp,
+		Service:    event.Service,
+		TenantID:   event.TenantID,
+		UserID:     event.UserID,
+		Action:     event.Action,
+		Resource:   event.Resource,
+		ResourceID: event.ResourceID,
+		RequestID:  event.RequestID,
+		IPAddress:  event.IPAddress,
+		Status:     event.Status,
+		Metadata:   metadata,
+	}
+	return s.db.WithContext(ctx).
+		Table(s.table).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "event_id"}}, DoNothing: true}).
+		Create(&record).
+		Error
+}
+
+// WebhookStoreConfig configures WebhookStore.
+type WebhookStoreConfig struct {
+	URL     string
+	Headers map[string]string
+	// Secret, when set, signs each payload with HMAC-SHA256 in the X-Audit-Signature header.
+	Secret  string
+	Timeout time.Duration
+	Client  *http.Client
+}
+
+// WebhookStore posts each audit event as JSON to an HTTP endpoint.
+type WebhookStore struct {
+	url     string
+	headers map[string]string
+	secret  []byte
+	client  *http.Client
+}
+
+// NewWebhookStore creates a store that delivers events to cfg.URL.
+func NewWebhookStore(cfg WebhookStoreConfig) (*WebhookStore, error) {
+	url := strings.TrimSpace(cfg.URL)
+	if url == "" {
+		return nil, stdErrors.New("audit: webhook url is required")
+	}
+	client := cfg.Client
+	if client == nil {
+		timeout := cfg.Timeout
+		if timeout <= 0 {
+			timeout = defaultWebhookTimeout
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+	return &WebhookStore{
+		url:     url,
+		headers: cfg.Headers,
+		secret:  []byte(cfg.Secret),
+		client:  client,
+	}, nil
+}
+
+// Append posts event to the webhook. Any non-2xx response is returned as an error.
+func (s *WebhookStore) Append(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal audit event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("build audit webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+	if len(s.secret) > 0 {
+		mac := hmac.New(sha256.New, s.secret)
+		mac.Write(payload)
+		req.Header.Set(webhookSignatureHeader, webhookSignatureHashPrefix+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("deliver audit webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("audit webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// LogStore writes audit events to a logger as structured log lines, which is
+// useful for log-stream based pipelines and local development.
+type LogStore struct {
+	log logger.LogManager
+}
+
+// NewLogStore creates a store writing to log.
+func NewLogStore(log logger.LogManager) *LogStore {
+	if log == nil {
+		log = logger.MustNewDefaultLogger()
+	}
+	return &LogStore{log: log}
+}
+
+// Append logs event with its fields attached.
+func (s *LogStore) Append(ctx context.Context, event Event) error {
+	s.log.With(
+		"log_type", "audit",
+		"event_id", event.EventID,
+		"service", event.Service,
+		"tenant_id", event.TenantID,
+		"user_id", event.UserID,
+		"action", event.Action,
+		"resource", event.Resource,
+		"resource_id", event.ResourceID,
+		"request_id", event.RequestID,
+		"ip_address", event.IPAddress,
+		"status", event.Status,
+		"metadata", event.Metadata,
+	).InfoFCtx(ctx, "audit %s %s", event.Action, event.Status)
+	return nil
+}
+
+// MultiStore appends every event to all stores, returning the joined errors.
+func MultiStore(stores ...Store) Store {
+	filtered := make(multiStore, 0, len(stores))
+	for _, store := range stores {
+		if store != nil {
+			filtered = append(filtered, store)
+		}
+	}
+	return filtered
+}
+
+type multiStore []Store
+
+func (m multiStore) Append(ctx context.Context, event Event) error {
+	var errs []error
+	for _, store := range m {
+		if err := store.Append(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return stdErrors.Join(errs...)
+}
+
+// StorePublisherConfig configures NewStorePublisher.
+type StorePublisherConfig struct {
+	QueueSize     int
+	AppendTimeout time.Duration
+}
+
+// ErrClosed is returned by StorePublisher.Publish after Close.
+var ErrClosed = stdErrors.New("audit: publisher is closed")
+
+// StorePublisher adapts a Store into a non-blocking Publisher: events are queued
+// and appended by a background worker so request latency is unaffected.
+type StorePublisher struct {
+	log           logger.LogManager
+	store         Store
+	queue         chan Event
+	appendTimeout time.Duration
+
+	// mu guards closed and keeps Close from closing queue during a send.
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewStorePublisher starts a publisher that delivers events to store.
+func NewStorePublisher(log logger.LogManager, store Store, cfg StorePublisherConfig) Publisher {
+	if store == nil {
+		return NoopPublisher{}
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultAuditQueueSize
+	}
+	if cfg.AppendTimeout <= 0 {
+		cfg.AppendTimeout = defaultStoreAppendTimeout
+	}
+
+	publisher := &StorePublisher{
+		log:           log,
+		store:         store,
+		queue:         make(chan Event, cfg.QueueSize),
+		appendTimeout: cfg.AppendTimeout,
+	}
+	publisher.wg.Add(1)
+	go publisher.run()
+	return publisher
+}
+
+// NewWebhookPublisherFromConfig builds an async webhook publisher from AuditWebhook* keys.
+// It returns a NoopPublisher when AuditWebhookURL is not configured.
+func NewWebhookPublisherFromConfig(log logger.LogManager, cfg *config.Config) Publisher {
+	if cfg == nil || strings.TrimSpace(cfg.GetString("AuditWebhookURL")) == "" {
+		return NoopPublisher{}
+	}
+	store, err := NewWebhookStore(WebhookStoreConfig{
+		URL:     cfg.GetString("AuditWebhookURL"),
+		Secret:  cfg.GetString("AuditWebhookSecret"),
+		Timeout: cfg.GetDurationD("AuditWebhookTimeout", defaultWebhookTimeout),
+	})
+	if err != nil {
+		if log != nil {
+			log.WarnF("audit webhook disabled: %v", err)
+		}
+		return NoopPublisher{}
+	}
+	return NewStorePublisher(log, store, StorePublisherConfig{
+		QueueSize: cfg.GetIntD("AuditQueueSize", defaultAuditQueueSize),
+	})
+}
+
+// Publish enqueues event, returning an error when the queue is full and
+// ErrClosed after Close.
+func (p *StorePublisher) Publish(_ context.Context, event Event) error {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrClosed
+	}
+	select {
+	case p.queue <- event:
+		return nil
+	default:
+		err := fmt.Errorf("audit queue is full")
+		if p.log != nil {
+			p.log.WarnF("dropping audit event %s (%s): %v", event.Action, event.Resource, err)
+		}
+		return err
+	}
+}
+
+// Close drains the queue and stops the worker.
+func (p *StorePublisher) Close() error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+	return nil
+}
+
+func (p *StorePublisher) run() {
+	defer p.wg.Done()
+
+	for event := range p.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), p.appendTimeout)
+		err := p.store.Append(ctx, event)
+		cancel()
+
+		if err != nil && p.log != nil {
+			p.log.ErrorF("failed to deliver audit event %s (%s): %v", event.Action, event.Resource, err)
+		}
+	}
+}
+
+// MultiPublisher fans every event out to all publishers. Publish and Close return
+// the joined errors of the underlying publishers.
+func MultiPublisher(publishers ...Publisher) Publisher {
+	filtered := make(multiPublisher, 0, len(publishers))
+	for _, publisher := range publishers {
+		if publisher != nil {
+			filtered = append(filtered, publisher)
+		}
+	}
+	return filtered
+}
+
+type multiPublisher []Publisher
+
+func (m multiPublisher) Publish(ctx context.Context, event Event) error {
+	var errs []error
+	for _, publisher := range m {
+		if err := publisher.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return stdErrors.Join(errs...)
+}
+
+func (m multiPublisher) Close() error {
+	var errs []error
+	for _, publisher := range m {
+		if err := publisher.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return stdErrors.Join(errs...)
+}
@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type captureStore struct {
+	mu     sync.Mutex
+	events []Event
+	err    error
+}
+
+func (s *captureStore) Append(_ context.Context, event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return s.err
+}
+
+func TestWebhookStorePostsSignedEvent(t *testing.T) {
+	var (
+		body      []byte
+		signature string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Audit-Signature")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	store, err := NewWebhookStore(WebhookStoreConfig{URL: server.URL, Secret: "shh"})
+	if err != nil {
+		t.Fatalf("NewWebhookStore() error = %v", err)
+	}
+	if err := store.Append(context.Background(), Event{EventID: "evt-1", Action: "plan.create"}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	var got Event
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("unmarshal webhook body: %v", err)
+	}
+	if got.EventID != "evt-1" || got.Action != "plan.create" {
+		t.Fatalf("webhook event = %#v", got)
+	}
+
+	mac := hmac.New(sha256.New, []byte("shh"))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Fatalf("signature = %q, want %q", signature, want)
+	}
+}
+
+func TestWebhookStoreReturnsErrorOnNon2xx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	store, err := NewWebhookStore(WebhookStoreConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("NewWebhookStore() error = %v", err)
+	}
+	if err := store.Append(context.Background(), Event{EventID: "evt-1"}); err == nil {
+		t.Fatal("expected error for 500 response")
+	}
+}
+
+func TestStorePublisherDeliversQueuedEventsOnClose(t *testing.T) {
+	store := &captureStore{}
+	publisher := NewStorePublisher(nil, store, StorePublisherConfig{QueueSize: 4})
+
+	for _, id := range []string{"evt-1", "evt-2", "evt-3"} {
+		if err := publisher.Publish(context.Background(), Event{EventID: id}); err != nil {
+			t.Fatalf("Publish(%s) error = %v", id, err)
+		}
+	}
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if len(store.events) != 3 {
+		t.Fatalf("delivered %d events, want 3", len(store.events))
+	}
+}
+
+func TestStorePublisherRejectsPublishAfterClose(t *testing.T) {
+	store := &captureStore{}
+	publisher := NewStorePublisher(nil, store, StorePublisherConfig{QueueSize: 64})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				err := publisher.Publish(context.Background(), Event{EventID: "evt"})
+				if err != nil && !errors.Is(err, ErrClosed) && err.Error() != "audit queue is full" {
+					t.Errorf("Publish() error = %v", err)
+				}
+			}
+		}()
+	}
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	wg.Wait()
+
+	if err := publisher.Publish(context.Background(), Event{EventID: "late"}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Publish() after Close error = %v, want ErrClosed", err)
+	}
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("second Close() error = %v", err)
+	}
+}
+
+func TestMultiStoreAppendsToAllAndJoinsErrors(t *testing.T) {
+	failing := &captureStore{err: errors.New("boom")}
+	healthy := &captureStore{}
+
+	err := MultiStore(failing, nil, healthy).Append(context.Background(), Event{EventID: "evt-1"})
+	if err == nil {
+		t.Fatal("expected joined error from failing store")
+	}
+	if len(failing.events) != 1 || len(healthy.events) != 1 {
+		t.Fatalf("events = (%d, %d), want (1, 1)", len(failing.events), len(healthy.events))
+	}
+}
+
+func TestMultiPublisherFansOut(t *testing.T) {
+	first, second := &capturePublisher{}, &capturePublisher{}
+	publisher := MultiPublisher(first, second)
+
+	if err := publisher.Publish(context.Background(), Event{EventID: "evt-1"}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if len(first.events) != 1 || len(second.events) != 1 {
+		t.Fatalf("events = (%d, %d), want (1, 1)", len(first.events), len(second.events))
+	}
+}
@@ -0,0 +1,360 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	httplib "github.com/milan604/core-lab/pkg/http"
+	"github.com/milan604/core-lab/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// TokenUseAPIKey is the token_use recorded on claims produced by APIKeyAuth.
+const TokenUseAPIKey = "api_key"
+
+// DefaultAPIKeyHeader is the header APIKeyAuth reads keys from by default.
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// ClaimAPIKeyMetadata is the raw claim APIKey.Claims nests key metadata under.
+const ClaimAPIKeyMetadata = "api_key_metadata"
+
+var (
+	// ErrAPIKeyNotFound is returned by a KeyStore when the presented key is unknown.
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrAPIKeyRevoked is returned by a KeyStore when the key has been revoked or has expired.
+	ErrAPIKeyRevoked = errors.New("api key revoked or expired")
+)
+
+// APIKey describes the machine principal a key authenticates as.
+type APIKey struct {
+	ID                 string             `json:"id"`
+	Subject            string             `json:"subject"`
+	TenantID           string             `json:"tenant_id,omitempty"`
+	ServicePermissions map[string][]int64 `json:"service_permissions,omitempty"`
+	ExpiresAt          *time.Time         `json:"expires_at,omitempty"`
+	Metadata           map[string]any     `json:"metadata,omitempty"`
+}
+
+// Claims converts the key into Claims so downstream middleware such as
+// RequirePermission and tenant resolution treat the caller like a token holder.
+// Metadata is nested under ClaimAPIKeyMetadata rather than merged into the top
+// level, so it can never set claims the authorizer trusts such as
+// is_super_admin, org_id or scope.
+func (k APIKey) Claims() Claims {
+	subject := strings.TrimSpace(k.Subject)
+	if subject == "" {
+		subject = strings.TrimSpace(k.ID)
+	}
+
+	raw := make(map[string]any, 5)
+	raw["sub"] = subject
+	raw["token_use"] = TokenUseAPIKey
+	raw["api_key_id"] = k.ID
+	if tenantID := strings.TrimSpace(k.TenantID); tenantID != "" {
+		raw["tenant_id"] = tenantID
+	}
+	if len(k.Metadata) > 0 {
+		metadata := make(map[string]any, len(k.Metadata))
+		for key, value := range k.Metadata {
+			metadata[key] = value
+		}
+		raw[ClaimAPIKeyMetadata] = metadata
+	}
+
+	return Claims{
+		Subject:            subject,
+		TokenUse:           TokenUseAPIKey,
+		ServicePermissions: k.ServicePermissions,
+		Raw:                raw,
+	}
+}
+
+func (k APIKey) expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !k.ExpiresAt.IsZero() && now.After(*k.ExpiresAt)
+}
+
+// KeyStore resolves a presented API key to the principal it authenticates.
+// Implementations return ErrAPIKeyNotFound or ErrAPIKeyRevoked for rejected keys;
+// any other error is treated as the store being unavailable.
+type KeyStore interface {
+	Lookup(ctx context.Context, key string) (APIKey, error)
+}
+
+// HashAPIKey returns the hex-encoded SHA-256 digest stored in place of raw keys.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// StaticKeyStore is an in-memory KeyStore for keys supplied through configuration.
+type StaticKeyStore struct {
+	entries []staticKeyEntry
+}
+
+type staticKeyEntry struct {
+	hash [sha256.Size]byte
+	key  APIKey
+}
+
+// NewStaticKeyStore creates a store from raw key → principal pairs. Keys are
+// kept only as SHA-256 digests and compared in constant time.
+func NewStaticKeyStore(keys map[string]APIKey) *StaticKeyStore {
+	store := &StaticKeyStore{entries: make([]staticKeyEntry, 0, len(keys))}
+	for raw, key := range keys {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		store.entries = append(store.entries, staticKeyEntry{hash: sha256.Sum256([]byte(raw)), key: key})
+	}
+	return store
+}
+
+// Lookup compares key against every configured key without short-circuiting.
+func (s *StaticKeyStore) Lookup(_ context.Context, key string) (APIKey, error) {
+	presented := sha256.Sum256([]byte(key))
+
+	var (
+		match APIKey
+		found bool
+	)
+	for _, entry := range s.entries {
+		if subtle.ConstantTimeCompare(presented[:], entry.hash[:]) == 1 {
+			match = entry.key
+			found = true
+		}
+	}
+	if !found {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	if match.expired(time.Now()) {
+		return APIKey{}, ErrAPIKeyRevoked
+	}
+	return match, nil
+}
+
+// APIKeyRecord is the row shape read by PostgresKeyStore. Raw keys are never
+// stored; KeyHash holds HashAPIKey(key).
+type APIKeyRecord struct {
+	ID                 string     `gorm:"column:id;primaryKey"`
+	KeyHash            string     `gorm:"column:key_hash;uniqueIndex"`
+	Subject            string     `gorm:"column:subject"`
+	TenantID           string     `gorm:"column:tenant_id;index"`
+	ServicePermissions string     `gorm:"column:service_permissions;type:jsonb"`
+	ExpiresAt          *time.Time `gorm:"column:expires_at"`
+	RevokedAt          *time.Time `gorm:"column:revoked_at"`
+	CreatedAt          time.Time  `gorm:"column:created_at"`
+}
+
+// PostgresKeyStore looks up hashed API keys in a database table through GORM.
+type PostgresKeyStore struct {
+	db    *gorm.DB
+	table string
+}
+
+// NewPostgresKeyStore creates a store reading from table (defaults to "api_keys").
+func NewPostgresKeyStore(db *gorm.DB, table string) (*PostgresKeyStore, error) {
+	if db == nil {
+		return nil, errors.New("api key store: gorm db is required")
+	}
+	table = strings.TrimSpace(table)
+	if table == "" {
+		table = "api_keys"
+	}
+	return &PostgresKeyStore{db: db, table: table}, nil
+}
+
+// AutoMigrate creates or updates the API key table.
+func (s *PostgresKeyStore) AutoMigrate() error {
+	return s.db.Table(s.table).AutoMigrate(&APIKeyRecord{})
+}
+
+// Lookup finds the key by its SHA-256 digest.
+func (s *PostgresKeyStore) Lookup(ctx context.Context, key string) (APIKey, error) {
+	hash := HashAPIKey(key)
+
+	var record APIKeyRecord
+	err := s.db.WithContext(ctx).Table(s.table).Where("key_hash = ?", hash).Take(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return APIKey{}, fmt.Errorf("lookup api key: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(record.KeyHash), []byte(hash)) != 1 {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	if record.RevokedAt != nil {
+		return APIKey{}, ErrAPIKeyRevoked
+	}
+
+	apiKey := APIKey{
+		ID:        record.ID,
+		Subject:   record.Subject,
+		TenantID:  record.TenantID,
+		ExpiresAt: record.ExpiresAt,
+	}
+	if strings.TrimSpace(record.ServicePermissions) != "" {
+		if err := json.Unmarshal([]byte(record.ServicePermissions), &apiKey.ServicePermissions); err != nil {
+			return APIKey{}, fmt.Errorf("decode api key permissions: %w", err)
+		}
+	}
+	if apiKey.expired(time.Now()) {
+		return APIKey{}, ErrAPIKeyRevoked
+	}
+	return apiKey, nil
+}
+
+// RemoteKeyStore delegates validation to an HTTP endpoint, typically the control
+// plane. The endpoint receives {"api_key": "..."} and answers 200 with an APIKey
+// document, or 401/403/404 for rejected keys.
+type RemoteKeyStore struct {
+	url    string
+	client *httplib.Client
+}
+
+// NewRemoteKeyStore creates a store validating keys against url. client is
+// usually a service-token client such as httplib.NewClientWithServiceToken.
+func NewRemoteKeyStore(url string, client *httplib.Client) (*RemoteKeyStore, error) {
+	url = strings.TrimSpace(url)
+	if url == "" {
+		return nil, errors.New("api key store: validator url is required")
+	}
+	if client == nil {
+		client = httplib.NewClient()
+	}
+	return &RemoteKeyStore{url: url, client: client}, nil
+}
+
+// Lookup posts key to the validator endpoint.
+func (s *RemoteKeyStore) Lookup(ctx context.Context, key string) (APIKey, error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(map[string]string{"api_key": key}); err != nil {
+		return APIKey{}, fmt.Errorf("encode api key validation request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, &body)
+	if err != nil {
+		return APIKey{}, fmt.Errorf("create api key validation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(ctx, req)
+	if err != nil {
+		return APIKey{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusUnauthorized:
+		return APIKey{}, ErrAPIKeyNotFound
+	case http.StatusForbidden, http.StatusGone:
+		return APIKey{}, ErrAPIKeyRevoked
+	default:
+		return APIKey{}, fmt.Errorf("api key validator returned status %d", resp.StatusCode)
+	}
+
+	var out APIKey
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return APIKey{}, fmt.Errorf("decode api key validation response: %w", err)
+	}
+	if out.expired(time.Now()) {
+		return APIKey{}, ErrAPIKeyRevoked
+	}
+	return out, nil
+}
+
+// APIKeyConfig configures APIKeyAuth.
+type APIKeyConfig struct {
+	Store KeyStore
+	// Header is the request header carrying the key. Defaults to X-API-Key.
+	Header string
+	// QueryParam, when set, also accepts the key from this query parameter.
+	// Prefer headers: query strings routinely end up in access logs.
+	QueryParam string
+	// Optional lets requests without a key continue unauthenticated so a later
+	// middleware (e.g. RequireAuthenticated for JWTs) can handle them.
+	Optional bool
+	Logger   logger.LogManager
+}
+
+// APIKeyAuth authenticates machine callers by API key and injects Claims with
+// token_use "api_key", so RequirePermission evaluates the key's ServicePermissions
+// the same way it evaluates a verified token.
+//
+//	router.Use(auth.APIKeyAuth(auth.APIKeyConfig{Store: store, Optional: true}))
+//	router.Use(authorizer.RequireAuthenticated())
+func APIKeyAuth(cfg APIKeyConfig) gin.HandlerFunc {
+	header := strings.TrimSpace(cfg.Header)
+	if header == "" {
+		header = DefaultAPIKeyHeader
+	}
+	queryParam := strings.TrimSpace(cfg.QueryParam)
+
+	return func(c *gin.Context) {
+		if _, ok := GetClaims(c); ok {
+			c.Next()
+			return
+		}
+
+		log := logger.GetLogger(c)
+		if log == nil {
+			log = cfg.Logger
+		}
+
+		key := strings.TrimSpace(c.GetHeader(header))
+		if key == "" && queryParam != "" {
+			key = strings.TrimSpace(c.Query(queryParam))
+		}
+		if key == "" {
+			if cfg.Optional {
+				c.Next()
+				return
+			}
+			abortAPIKey(c, log, http.StatusUnauthorized, "api_key_required", "api key required", nil)
+			return
+		}
+		if cfg.Store == nil {
+			abortAPIKey(c, log, http.StatusServiceUnavailable, "api_key_store_unavailable", "api key store is not configured", nil)
+			return
+		}
+
+		apiKey, err := cfg.Store.Lookup(c.Request.Context(), key)
+		switch {
+		case errors.Is(err, ErrAPIKeyNotFound), errors.Is(err, ErrAPIKeyRevoked):
+			abortAPIKey(c, log, http.StatusUnauthorized, "invalid_api_key", "api key is invalid", err)
+			return
+		case err != nil:
+			abortAPIKey(c, log, http.StatusServiceUnavailable, "api_key_store_unavailable", "api key validation is unavailable", err)
+			return
+		}
+
+		claims := apiKey.Claims()
+		SetClaims(c, claims)
+		c.Next()
+	}
+}
+
+func abortAPIKey(c *gin.Context, log logger.LogManager, status int, code, message string, err error) {
+	if log != nil {
+		if err != nil {
+			log.WarnFCtx(c.Request.Context(), "API key authentication failed: %s: %v (path=%s)", code, err, c.FullPath())
+		} else {
+			log.WarnFCtx(c.Request.Context(), "API key authentication failed: %s (path=%s)", code, c.FullPath())
+		}
+	}
+	c.AbortWithStatusJSON(status, gin.H{
+		"error":   code,
+		"message": message,
+	})
+}
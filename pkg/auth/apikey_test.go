@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/permissions"
+)
+
+type stubPermissionLookup map[string]permissions.Metadata
+
+func (s stubPermissionLookup) LookupPermission(code string) (permissions.Metadata, bool) {
+	metadata, ok := s[code]
+	return metadata, ok
+}
+
+func TestStaticKeyStoreLookup(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	store := NewStaticKeyStore(map[string]APIKey{
+		"key-live":    {ID: "live", Subject: "reporting-bot"},
+		"key-expired": {ID: "old", ExpiresAt: &expired},
+	})
+
+	key, err := store.Lookup(context.Background(), "key-live")
+	if err != nil || key.ID != "live" {
+		t.Fatalf("Lookup(key-live) = %#v, %v", key, err)
+	}
+	if _, err := store.Lookup(context.Background(), "key-unknown"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("Lookup(key-unknown) error = %v, want ErrAPIKeyNotFound", err)
+	}
+	if _, err := store.Lookup(context.Background(), "key-expired"); !errors.Is(err, ErrAPIKeyRevoked) {
+		t.Fatalf("Lookup(key-expired) error = %v, want ErrAPIKeyRevoked", err)
+	}
+}
+
+func TestAPIKeyAuthInjectsClaimsForRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	_, publicKeyPEM := testKeyPair(t)
+	decisions := &stubPermissionDecisionClient{response: permissionDecisionResponse{Allowed: false}}
+	restore := overridePermissionDecisionClientFactory(func(cfg Config, log logger.LogManager) permissionDecisionClient {
+		return decisions
+	})
+	defer restore()
+	authorizer := testAuthorizer(t, stubConfig{"RSAPublicKey": publicKeyPEM})
+
+	store := NewStaticKeyStore(map[string]APIKey{
+		"key-reader": {ID: "k1", Subject: "reporting-bot", TenantID: "tenant-1", ServicePermissions: map[string][]int64{"ten": {1 << 2}}},
+		"key-none":   {ID: "k2", Subject: "other-bot"},
+	})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(string(CtxMiddlewareServiceKey), stubPermissionLookup{
+			"TEN-TENANTS-LIST": {Service: "ten", BitValue: 2},
+		})
+	})
+	router.GET("/tenants", APIKeyAuth(APIKeyConfig{Store: store}), authorizer.RequirePermission("TEN-TENANTS-LIST"), func(c *gin.Context) {
+		claims, _ := ClaimsFromContext(c.Request.Context())
+		tenantID, _ := TenantIDFromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"subject": claims.Subject, "tenant_id": tenantID})
+	})
+
+	cases := []struct {
+		name string
+		key  string
+		want int
+	}{
+		{name: "granted", key: "key-reader", want: http.StatusOK},
+		{name: "missing permission", key: "key-none", want: http.StatusForbidden},
+		{name: "unknown key", key: "key-bogus", want: http.StatusUnauthorized},
+		{name: "no key", key: "", want: http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/tenants", nil)
+			if tc.key != "" {
+				req.Header.Set(DefaultAPIKeyHeader, tc.key)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tc.want {
+				t.Fatalf("status = %d, want %d; body=%s", recorder.Code, tc.want, recorder.Body.String())
+			}
+			if tc.want == http.StatusOK && recorder.Body.String() != `{"subject":"reporting-bot","tenant_id":"tenant-1"}` {
+				t.Fatalf("body = %s", recorder.Body.String())
+			}
+		})
+	}
+
+	if decisions.callCount != 0 {
+		t.Fatalf("Decide() calls = %d, want 0 for API key callers", decisions.callCount)
+	}
+}
+
+func TestAPIKeyAuthOptionalAndQueryParam(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := NewStaticKeyStore(map[string]APIKey{"key-1": {ID: "k1"}})
+	router := gin.New()
+	router.GET("/feed", APIKeyAuth(APIKeyConfig{Store: store, QueryParam: "api_key", Optional: true}), func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		c.JSON(http.StatusOK, gin.H{"authenticated": ok, "api_key": claims.IsAPIKey()})
+	})
+
+	for path, want := range map[string]string{
+		"/feed":               `{"api_key":false,"authenticated":false}`,
+		"/feed?api_key=key-1": `{"api_key":true,"authenticated":true}`,
+	} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != http.StatusOK || recorder.Body.String() != want {
+			t.Fatalf("%s: status = %d body = %s, want 200 %s", path, recorder.Code, recorder.Body.String(), want)
+		}
+	}
+}
+
+func TestRemoteKeyStoreLookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch body["api_key"] {
+		case "key-good":
+			_ = json.NewEncoder(w).Encode(APIKey{ID: "k1", Subject: "partner"})
+		case "key-revoked":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	store, err := NewRemoteKeyStore(server.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRemoteKeyStore() error = %v", err)
+	}
+
+	key, err := store.Lookup(context.Background(), "key-good")
+	if err != nil || key.Subject != "partner" {
+		t.Fatalf("Lookup(key-good) = %#v, %v", key, err)
+	}
+	if _, err := store.Lookup(context.Background(), "key-revoked"); !errors.Is(err, ErrAPIKeyRevoked) {
+		t.Fatalf("Lookup(key-revoked) error = %v, want ErrAPIKeyRevoked", err)
+	}
+	if _, err := store.Lookup(context.Background(), "key-bogus"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("Lookup(key-bogus) error = %v, want ErrAPIKeyNotFound", err)
+	}
+}
+
+func TestAPIKeyClaimsNestMetadata(t *testing.T) {
+	key := APIKey{
+		ID:      "key-1",
+		Subject: "reporting-bot",
+		Metadata: map[string]any{
+			"is_super_admin":       true,
+			"authentication_means": "mtls",
+			"org_id":               "tenant-b",
+			"scope":                ScopeCrossTenant,
+			"team":                 "finance",
+		},
+	}
+
+	claims := key.Claims()
+	if claims.IsSuperAdmin() {
+		t.Fatal("IsSuperAdmin() = true, want metadata not to grant super admin")
+	}
+	if claims.IsClientCertificate() {
+		t.Fatal("IsClientCertificate() = true, want metadata not to claim mTLS")
+	}
+	if tenantID := claims.TenantID(); tenantID != "" {
+		t.Fatalf("TenantID() = %q, want metadata not to set a tenant", tenantID)
+	}
+	if claims.HasScope(ScopeCrossTenant) {
+		t.Fatal("HasScope(tenant:cross) = true, want metadata not to add scopes")
+	}
+	metadata, ok := claims.Raw[ClaimAPIKeyMetadata].(map[string]any)
+	if !ok || metadata["team"] != "finance" {
+		t.Fatalf("Raw[%q] = %#v, want the key metadata", ClaimAPIKeyMetadata, claims.Raw[ClaimAPIKeyMetadata])
+	}
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rsa"
 	"crypto/x509"
@@ -13,10 +14,12 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/milan604/core-lab/pkg/controlplane"
+	httplib "github.com/milan604/core-lab/pkg/http"
 	"github.com/milan604/core-lab/pkg/logger"
 	"github.com/milan604/core-lab/pkg/permissions"
 )
@@ -27,6 +30,14 @@ type PermissionLookup interface {
 	LookupPermission(code string) (permissions.Metadata, bool)
 }
 
+// PermissionResolver is implemented by lookups that can fetch a code missing
+// from their catalog, e.g. one registered in sentinel after startup. The
+// authorizer calls ResolvePermission instead of LookupPermission when it is
+// available; permissions.Store.Resolve is the usual implementation.
+type PermissionResolver interface {
+	ResolvePermission(ctx context.Context, code string) (permissions.Metadata, bool, error)
+}
+
 // ContextKey is a type for context keys to avoid collisions.
 type ContextKey string
 
@@ -42,7 +53,24 @@ type Authorizer struct {
 	verifier                      *jwtVerifier
 	log                           logger.LogManager
 	bypassServiceTokenPermissions bool
-	permissionDecisions           permissionDecisionClient
+	// bypassCertificateSubjects lists the client certificate subjects the
+	// service bypass applies to; see BypassCertificateSubjects.
+	bypassCertificateSubjects map[string]struct{}
+	permissionDecisions       permissionDecisionClient
+	revocation                RevocationChecker
+	// permissionStore resolves codes when no PermissionLookup is in the
+	// gin context; see NewAuthorizerWithStore.
+	permissionStore     *permissions.Store
+	allowAllPermissions bool
+	// scopePermissions maps OAuth scopes to permission codes for scoped
+	// tokens; see UseScopePermissions.
+	scopePermissions ScopePermissions
+	// breakGlass admits break-glass tokens on the routes it allows; see
+	// UseBreakGlass.
+	breakGlass *BreakGlass
+
+	usageMu          sync.Mutex
+	permissionUsages []PermissionUsage
 }
 
 // Config provides configuration for the authorizer.
@@ -60,17 +88,91 @@ func NewAuthorizer(cfg Config, log logger.LogManager) (*Authorizer, error) {
 	if err != nil {
 		return nil, err
 	}
+	allowAllPermissions, err := parseAllowAllPermissions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	scopePermissions, err := ParseScopePermissions(cfg.GetString("ScopePermissionMapping"))
+	if err != nil {
+		return nil, err
+	}
+	if allowAllPermissions && log != nil {
+		log.Warn("jwt authorizer: AllowAllPermissions is enabled; permission checks are skipped. Never enable this outside local development.")
+	}
 	return &Authorizer{
 		verifier:                      verifier,
 		log:                           log,
 		bypassServiceTokenPermissions: bypassServiceTokenPermissions,
+		bypassCertificateSubjects:     parseBypassCertificateSubjects(cfg),
 		permissionDecisions:           newPermissionDecisionClientFunc(cfg, log),
+		allowAllPermissions:           allowAllPermissions,
+		scopePermissions:              scopePermissions,
 	}, nil
 }
 
+// NewAuthorizerWithStore creates an authorizer that enforces permissions
+// offline: codes are resolved through store and checked against the token's
+// svc_perm bitmasks, without calling the permission decision service or
+// requiring a PermissionLookup in the gin context. Populate store with
+// Store.Replace or Store.Load, e.g. from a static fixture in local development.
+func NewAuthorizerWithStore(cfg Config, log logger.LogManager, store *permissions.Store) (*Authorizer, error) {
+	if store == nil {
+		return nil, errors.New("jwt authorizer: permission store is required")
+	}
+	a, err := NewAuthorizer(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	a.permissionStore = store
+	a.permissionDecisions = nil
+	return a, nil
+}
+
 // RequirePermission creates a middleware that enforces permission checking.
 // It validates that the caller has the required bitmask permission.
 func (a *Authorizer) RequirePermission(code string) gin.HandlerFunc {
+	return a.requirePermissionExpression(PermissionExpression{source: code, root: permissionCode(code)})
+}
+
+// RequireAnyPermission allows the request when the caller holds at least one of
+// codes. It panics when no code is given.
+func (a *Authorizer) RequireAnyPermission(codes ...string) gin.HandlerFunc {
+	return a.requirePermissionExpression(anyPermission(codes))
+}
+
+// RequireAllPermissions allows the request only when the caller holds every
+// one of codes. It panics when no code is given.
+func (a *Authorizer) RequireAllPermissions(codes ...string) gin.HandlerFunc {
+	return a.requirePermissionExpression(allPermissions(codes))
+}
+
+// RequirePermissionExpr enforces a permission expression such as
+// "PMS-ORD-READ && (PMS-ORD-EXPORT || PMS-ADMIN-ALL)" (see
+// ParsePermissionExpression). It panics when expr is invalid, so mistakes
+// surface at route registration.
+func (a *Authorizer) RequirePermissionExpr(expr string) gin.HandlerFunc {
+	return a.requirePermissionExpression(MustParsePermissionExpression(expr))
+}
+
+// permissionCheckError aborts a permission check with a specific response.
+type permissionCheckError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *permissionCheckError) Error() string { return e.code + ": " + e.message }
+
+// requirePermissionExpression builds the permission middlewares. With
+// tenantSources the check is scoped to the tenant they resolve (see
+// RequirePermissionInTenant).
+func (a *Authorizer) requirePermissionExpression(expr PermissionExpression, tenantSources ...TenantSource) gin.HandlerFunc {
+	if expr.root == nil {
+		panic("auth: permission middleware requires at least one permission code")
+	}
+	a.recordPermissionUsage(expr, 2)
+	_, singleCode := expr.root.(permissionCode)
+
 	return func(c *gin.Context) {
 		// Get logger from context if available, otherwise use stored logger
 		log := logger.GetLogger(c)
@@ -78,6 +180,13 @@ func (a *Authorizer) RequirePermission(code string) gin.HandlerFunc {
 			log = a.log
 		}
 
+		if a.breakGlass != nil {
+			if token := strings.TrimSpace(c.GetHeader(DefaultBreakGlassHeader)); token != "" {
+				a.admitBreakGlass(c, token, expr, tenantSources, log)
+				return
+			}
+		}
+
 		claims, ok := GetClaims(c)
 		if !ok {
 			var err error
@@ -89,81 +198,190 @@ func (a *Authorizer) RequirePermission(code string) gin.HandlerFunc {
 			}
 		}
 
-		if a.bypassServiceTokenPermissions && claims.IsServiceToken() {
+		tenantID := ""
+		if len(tenantSources) > 0 {
+			if tenantID = resolveTenantSource(c, tenantSources); tenantID == "" {
+				a.abortWithJSON(c, http.StatusForbidden, "tenant_scope_required", "tenant is required", log)
+				return
+			}
+		}
+
+		if a.allowAllPermissions {
+			SetTenantID(c, tenantID)
 			c.Next()
 			return
 		}
-
-		if !claims.IsServiceToken() && a.permissionDecisions != nil {
-			req, err := buildPermissionDecisionRequest(c, claims, code)
-			if err != nil {
-				log.ErrorFCtx(c.Request.Context(), "Permission decision request build failed (permission=%s): %v", code, err)
-				a.abortWithJSON(c, http.StatusInternalServerError, "authorization_request_invalid", "authorization request could not be constructed", log)
+		if a.bypassesPermissions(claims) {
+			// The bypass covers permissions, not tenants.
+			if tenantID != "" && !claims.serviceCanActInTenant(tenantID) {
+				log.WarnFCtx(c.Request.Context(), "Permission check failed: service token cannot act in tenant (subject=%s tenant=%s)", claims.Subject, tenantID)
+				a.abortWithJSON(c, http.StatusForbidden, "tenant_scope_mismatch", "service token is not allowed in this tenant", log)
 				return
 			}
+			SetTenantID(c, tenantID)
+			c.Next()
+			return
+		}
 
-			decision, err := a.permissionDecisions.Decide(c.Request.Context(), req)
-			if err != nil {
-				log.ErrorFCtx(c.Request.Context(), "Permission decision request failed (permission=%s subject=%s): %v", code, claims.Subject, err)
-				a.abortWithJSON(c, http.StatusServiceUnavailable, "authorization_unavailable", "authorization service is unavailable", log)
-				return
+		var (
+			has          func(code string) (bool, error)
+			unregistered bool
+		)
+		// Scoped tokens from a standard OIDC provider carry no bitmasks; their
+		// scopes are mapped to permission codes instead.
+		// API keys carry their grants as ServicePermissions and have no user
+		// identity for the decision service, so they use the local bitmask check.
+		if a.scopePermissions != nil && claims.IsScopedToken() {
+			has = func(code string) (bool, error) {
+				return a.scopeGrants(claims, code, tenantID), nil
 			}
-
-			if !decision.Allowed {
-				log.WarnFCtx(
-					c.Request.Context(),
-					"Permission decision denied (permission=%s subject=%s reasons=%s)",
-					code,
-					claims.Subject,
-					strings.Join(decision.Reasons, ","),
-				)
-				a.abortWithJSON(c, http.StatusForbidden, "permission_denied", "caller lacks required permission", log)
+		} else if !claims.IsServiceToken() && !claims.IsAPIKey() && a.permissionDecisions != nil {
+			has = func(code string) (bool, error) {
+				return a.decidePermission(c, claims, code, tenantID, log)
+			}
+		} else {
+			lookup, errCode, errMessage := a.permissionLookup(c)
+			if lookup == nil {
+				log.ErrorFCtx(c.Request.Context(), "Permission check failed: %s (permission=%s)", errMessage, expr)
+				a.abortWithJSON(c, http.StatusInternalServerError, errCode, errMessage, log)
 				return
 			}
-
-			c.Next()
-			return
+			has = func(code string) (bool, error) {
+				metadata, ok := resolvePermission(c.Request.Context(), lookup, code, log)
+				if !ok {
+					log.WarnFCtx(c.Request.Context(), "Permission check failed: permission not registered in sentinel (permission=%s)", code)
+					unregistered = true
+					return false, nil
+				}
+				// Check if caller has the required bitmask permission
+				if tenantID != "" {
+					return claims.HasPermissionInTenant(tenantID, metadata.Service, metadata.BitValue), nil
+				}
+				return claims.HasPermission(metadata.Service, metadata.BitValue), nil
+			}
 		}
 
-		// Get permission lookup from context to access permission store
-		// This avoids import cycles by using an interface
-		val, exists := c.Get(string(CtxMiddlewareServiceKey))
-		if !exists {
-			log.ErrorFCtx(c.Request.Context(), "Permission check failed: service not available in context (permission=%s)", code)
-			a.abortWithJSON(c, http.StatusInternalServerError, "service_not_available", "service not available in context", log)
-			return
-		}
-		lookup, ok := val.(PermissionLookup)
-		if !ok {
-			log.ErrorFCtx(c.Request.Context(), "Permission check failed: service does not implement PermissionLookup (permission=%s)", code)
-			a.abortWithJSON(c, http.StatusInternalServerError, "service_invalid", "service does not implement PermissionLookup", log)
-			return
-		}
-		metadata, ok := lookup.LookupPermission(code)
-		if !ok {
-			log.WarnFCtx(c.Request.Context(), "Permission check failed: permission not registered in sentinel (permission=%s)", code)
-			a.abortWithJSON(c, http.StatusForbidden, "permission_not_registered", "permission is not registered in sentinel", log)
+		allowed, err := expr.Evaluate(memoizePermissionCheck(has))
+		if err != nil {
+			var checkErr *permissionCheckError
+			if errors.As(err, &checkErr) {
+				a.abortWithJSON(c, checkErr.status, checkErr.code, checkErr.message, log)
+				return
+			}
+			log.ErrorFCtx(c.Request.Context(), "Permission check failed (permission=%s): %v", expr, err)
+			a.abortWithJSON(c, http.StatusInternalServerError, "authorization_failed", "authorization failed", log)
 			return
 		}
-
-		// Check if caller has the required bitmask permission
-		if !claims.HasPermission(metadata.Service, metadata.BitValue) {
-			log.WarnFCtx(
-				c.Request.Context(),
-				"Permission check failed: caller lacks required permission (permission=%s service=%s bit_value=%d subject=%s)",
-				code,
-				metadata.Service,
-				metadata.BitValue,
-				claims.Subject,
-			)
+		if !allowed {
+			if singleCode && unregistered {
+				a.abortWithJSON(c, http.StatusForbidden, "permission_not_registered", "permission is not registered in sentinel", log)
+				return
+			}
+			log.WarnFCtx(c.Request.Context(), "Permission check failed: caller lacks required permission (permission=%s subject=%s tenant=%s)", expr, claims.Subject, tenantID)
 			a.abortWithJSON(c, http.StatusForbidden, "permission_denied", "caller lacks required permission", log)
 			return
 		}
 
+		SetTenantID(c, tenantID)
 		c.Next()
 	}
 }
 
+// permissionLookup returns the PermissionLookup stored in the gin context,
+// falling back to the authorizer's own store. When neither is available it
+// returns the error code and message to abort with.
+func (a *Authorizer) permissionLookup(c *gin.Context) (PermissionLookup, string, string) {
+	// Get permission lookup from context to access permission store
+	// This avoids import cycles by using an interface
+	val, exists := c.Get(string(CtxMiddlewareServiceKey))
+	if !exists {
+		if a.permissionStore != nil {
+			return storePermissionLookup{a.permissionStore}, "", ""
+		}
+		return nil, "service_not_available", "service not available in context"
+	}
+	lookup, ok := val.(PermissionLookup)
+	if !ok {
+		return nil, "service_invalid", "service does not implement PermissionLookup"
+	}
+	return lookup, "", ""
+}
+
+// storePermissionLookup adapts a permissions.Store to PermissionLookup.
+type storePermissionLookup struct {
+	store *permissions.Store
+}
+
+func (l storePermissionLookup) LookupPermission(code string) (permissions.Metadata, bool) {
+	return l.store.Lookup(code)
+}
+
+func (l storePermissionLookup) ResolvePermission(ctx context.Context, code string) (permissions.Metadata, bool, error) {
+	return l.store.Resolve(ctx, code)
+}
+
+// resolvePermission looks code up, resolving a miss through the lookup when
+// it implements PermissionResolver. A failed resolve is logged and treated as
+// unregistered, so the check fails closed.
+func resolvePermission(ctx context.Context, lookup PermissionLookup, code string, log logger.LogManager) (permissions.Metadata, bool) {
+	resolver, ok := lookup.(PermissionResolver)
+	if !ok {
+		return lookup.LookupPermission(code)
+	}
+	metadata, found, err := resolver.ResolvePermission(ctx, code)
+	if err != nil {
+		log.WarnFCtx(ctx, "Permission resolve failed (permission=%s): %v", code, err)
+		return permissions.Metadata{}, false
+	}
+	return metadata, found
+}
+
+// decidePermission asks the permission decision service whether claims hold
+// code, within tenantID when it is set.
+func (a *Authorizer) decidePermission(c *gin.Context, claims Claims, code, tenantID string, log logger.LogManager) (bool, error) {
+	req, err := buildPermissionDecisionRequest(c, claims, code)
+	if err != nil {
+		log.ErrorFCtx(c.Request.Context(), "Permission decision request build failed (permission=%s): %v", code, err)
+		return false, &permissionCheckError{status: http.StatusInternalServerError, code: "authorization_request_invalid", message: "authorization request could not be constructed"}
+	}
+	if tenantID != "" {
+		req.TenantID = tenantID
+	}
+
+	decision, err := a.permissionDecisions.Decide(c.Request.Context(), req)
+	if err != nil {
+		log.ErrorFCtx(c.Request.Context(), "Permission decision request failed (permission=%s subject=%s): %v", code, claims.Subject, err)
+		return false, &permissionCheckError{status: http.StatusServiceUnavailable, code: "authorization_unavailable", message: "authorization service is unavailable"}
+	}
+
+	if !decision.Allowed {
+		log.WarnFCtx(
+			c.Request.Context(),
+			"Permission decision denied (permission=%s subject=%s reasons=%s)",
+			code,
+			claims.Subject,
+			strings.Join(decision.Reasons, ","),
+		)
+	}
+	return decision.Allowed, nil
+}
+
+// memoizePermissionCheck caches answers so a code repeated in an expression is
+// only checked once per request.
+func memoizePermissionCheck(has func(string) (bool, error)) func(string) (bool, error) {
+	seen := make(map[string]bool)
+	return func(code string) (bool, error) {
+		if ok, cached := seen[code]; cached {
+			return ok, nil
+		}
+		ok, err := has(code)
+		if err == nil {
+			seen[code] = ok
+		}
+		return ok, err
+	}
+}
+
 // RequireAuthenticated verifies the bearer token and stores claims in the request context.
 // It is intended for protected route groups that need claims before downstream middleware.
 func (a *Authorizer) RequireAuthenticated() gin.HandlerFunc {
@@ -232,28 +450,42 @@ func (a *Authorizer) authenticate(c *gin.Context) (Claims, error) {
 		log.ErrorFCtx(c.Request.Context(), "Failed to extract bearer token: %v", err)
 		return Claims{}, err
 	}
+	return a.authenticateToken(c, token, log)
+}
 
+// authenticateToken verifies token, checks revocation, and stores the claims
+// and the token in the request context.
+func (a *Authorizer) authenticateToken(c *gin.Context, token string, log logger.LogManager) (Claims, error) {
 	claims, err := a.verifier.Verify(token)
 	if err != nil {
 		log.ErrorFCtx(c.Request.Context(), "Failed to verify JWT token: %v", err)
 		return Claims{}, err
 	}
 
-	// Store claims in context for later use
-	c.Set(string(CtxAuthClaims), claims)
-	reqCtx := c.Request.Context()
-	if reqCtx == nil {
-		reqCtx = context.Background()
+	if err := a.checkRevocation(c.Request.Context(), claims); err != nil {
+		log.ErrorFCtx(c.Request.Context(), "Token rejected by revocation check (subject=%s): %v", claims.Subject, err)
+		return Claims{}, err
 	}
-	c.Request = c.Request.WithContext(ContextWithClaims(reqCtx, claims))
+
+	// Store claims in context for later use
+	c.Request = c.Request.WithContext(httplib.ContextWithBearerToken(c.Request.Context(), token))
+	SetClaims(c, claims)
 	return claims, nil
 }
 
 // abortAuthError handles authentication/authorization errors.
 func (a *Authorizer) abortAuthError(c *gin.Context, err error, log logger.LogManager) {
 	status := authorizationErrorStatus(err)
+	if status == http.StatusServiceUnavailable {
+		a.abortWithJSON(c, status, "revocation_unavailable", "token revocation status could not be verified", log)
+		return
+	}
 	if status == http.StatusUnauthorized {
 		log.ErrorFCtx(c.Request.Context(), "Authentication error: %v", err)
+		if errors.Is(err, ErrTokenRevoked) {
+			a.abortWithJSON(c, status, "token_revoked", "token has been revoked", log)
+			return
+		}
 		a.abortWithJSON(c, status, "invalid_token", "authentication required", log)
 		return
 	}
@@ -272,30 +504,35 @@ func (a *Authorizer) abortWithJSON(c *gin.Context, status int, code, message str
 	})
 }
 
+// minSharedSecretLength is the minimum JWTSharedSecret size, matching the
+// HS256 output size recommended by RFC 7518 §3.2.
+const minSharedSecretLength = 32
+
 // jwtVerifier handles JWT token verification.
 type jwtVerifier struct {
-	staticKey interface{}
-	remote    *remoteKeyProvider
-	issuer    string
-	audiences []string
+	// keysMu guards staticKey and sharedSecret, which rotateKey replaces.
+	keysMu       sync.RWMutex
+	staticKey    interface{}
+	sharedSecret []byte
+	remote       *remoteKeyProvider
+	issuer       string
+	audiences    []string
 }
 
 // newJWTVerifier creates a new JWT verifier from configuration.
 func newJWTVerifier(cfg Config) (*jwtVerifier, error) {
-	var staticKey interface{}
-
-	pubKey := strings.TrimSpace(cfg.GetString("RSAPublicKey"))
-	if pubKey != "" {
-		parsedKey, err := parsePublicKey(pubKey)
-		if err != nil {
-			return nil, fmt.Errorf("jwt authorizer: parse public key: %w", err)
-		}
-		staticKey = parsedKey
+	staticKey, err := parseStaticKey(cfg.GetString(KeyRSAPublicKey))
+	if err != nil {
+		return nil, err
+	}
+	sharedSecret, err := parseSharedSecret(cfg.GetString(KeyJWTSharedSecret))
+	if err != nil {
+		return nil, err
 	}
 
 	remote := newRemoteKeyProvider(cfg)
-	if staticKey == nil && remote == nil {
-		return nil, fmt.Errorf("jwt authorizer: RSAPublicKey or %s/%s must be configured", controlplane.KeyBaseURL, controlplane.LegacyKeyBaseURL)
+	if staticKey == nil && sharedSecret == nil && remote == nil {
+		return nil, fmt.Errorf("jwt authorizer: RSAPublicKey, JWTSharedSecret, or %s/%s must be configured", controlplane.KeyBaseURL, controlplane.LegacyKeyBaseURL)
 	}
 
 	// Issuer and audience are optional - use empty strings if not configured
@@ -303,13 +540,40 @@ func newJWTVerifier(cfg Config) (*jwtVerifier, error) {
 	aud := controlplane.ResolveTokenAudienceFromStringGetter(cfg)
 
 	return &jwtVerifier{
-		staticKey: staticKey,
-		remote:    remote,
-		issuer:    issuer,
-		audiences: aud,
+		staticKey:    staticKey,
+		sharedSecret: sharedSecret,
+		remote:       remote,
+		issuer:       issuer,
+		audiences:    aud,
 	}, nil
 }
 
+// parseStaticKey parses the RSAPublicKey setting; empty yields no key.
+func parseStaticKey(pubKey string) (interface{}, error) {
+	pubKey = strings.TrimSpace(pubKey)
+	if pubKey == "" {
+		return nil, nil
+	}
+	parsedKey, err := parsePublicKey(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("jwt authorizer: parse public key: %w", err)
+	}
+	return parsedKey, nil
+}
+
+// parseSharedSecret parses the JWTSharedSecret setting; empty yields no
+// secret. It enables HS256/HS384/HS512 tokens for internal tooling.
+// Asymmetric keys stay preferred for anything issued to external callers.
+func parseSharedSecret(secret string) ([]byte, error) {
+	if secret == "" {
+		return nil, nil
+	}
+	if len(secret) < minSharedSecretLength {
+		return nil, fmt.Errorf("jwt authorizer: JWTSharedSecret must be at least %d bytes", minSharedSecretLength)
+	}
+	return []byte(secret), nil
+}
+
 // Verify validates and parses a JWT token string.
 func (v *jwtVerifier) Verify(tokenString string) (Claims, error) {
 	keys, err := v.lookupVerificationKeys(tokenString)
@@ -341,21 +605,31 @@ func (v *jwtVerifier) lookupVerificationKeys(tokenString string) ([]interface{},
 		return nil, fmt.Errorf("failed to parse token header: %w", err)
 	}
 
+	v.keysMu.RLock()
+	staticKey, sharedSecret := v.staticKey, v.sharedSecret
+	v.keysMu.RUnlock()
+
+	alg := tokenAlgorithm(unverifiedToken)
 	keys := make([]interface{}, 0, 4)
-	if v.remote != nil {
+	if sharedSecret != nil && keySupportsAlgorithm(sharedSecret, alg) {
+		// HMAC tokens are only ever checked against the shared secret.
+		return []interface{}{sharedSecret}, nil
+	}
+
+	if v.remote != nil && !strings.HasPrefix(alg, "HS") {
 		remoteKeys, err := v.remote.LookupKeys(unverifiedToken)
-		if err != nil && v.staticKey == nil {
+		if err != nil && staticKey == nil {
 			return nil, fmt.Errorf("failed to resolve jwks verification key: %w", err)
 		}
 		keys = append(keys, remoteKeys...)
 	}
 
-	if v.staticKey != nil {
-		keys = append(keys, v.staticKey)
+	if staticKey != nil && keySupportsAlgorithm(staticKey, alg) {
+		keys = append(keys, staticKey)
 	}
 
 	if len(keys) == 0 {
-		return nil, fmt.Errorf("no verification keys available")
+		return nil, fmt.Errorf("no verification keys available for alg %q", alg)
 	}
 
 	return dedupeVerificationKeys(keys), nil
@@ -402,6 +676,14 @@ func validateTokenMethodWithKey(token *jwt.Token, key interface{}) error {
 		if ecdsaMethod.CurveBits != expectedCurve {
 			return fmt.Errorf("ECDSA curve mismatch: token uses %d bits, key is %d bits", ecdsaMethod.CurveBits, expectedCurve)
 		}
+	case ed25519.PublicKey:
+		if _, ok := token.Method.(*jwt.SigningMethodEd25519); !ok {
+			return fmt.Errorf("unexpected signing method: %v (expected EdDSA)", token.Header["alg"])
+		}
+	case []byte:
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return fmt.Errorf("unexpected signing method: %v (expected HMAC)", token.Header["alg"])
+		}
 	default:
 		return fmt.Errorf("unsupported public key type: %T", key)
 	}
@@ -502,6 +784,7 @@ func mapClaimsToAuthClaims(claims jwt.MapClaims) Claims {
 		RoleID:             strings.TrimSpace(fmt.Sprint(raw["role_id"])),
 		TokenUse:           tokenUse,
 		ServicePermissions: decodeServicePermissionsMultiRange(svcPermRaw),
+		TenantPermissions:  decodeTenantServicePermissions(svcPermRaw),
 		Raw:                raw,
 	}
 }
@@ -532,6 +815,9 @@ func authorizationErrorStatus(err error) int {
 	if err == nil {
 		return http.StatusOK
 	}
+	if errors.Is(err, ErrRevocationUnavailable) {
+		return http.StatusServiceUnavailable
+	}
 	// Most auth errors are unauthorized
 	return http.StatusUnauthorized
 }
@@ -548,10 +834,83 @@ func parseBypassServiceTokenPermissions(cfg Config) (bool, error) {
 	return enabled, nil
 }
 
+// parseBypassCertificateSubjects reads BypassCertificateSubjects, a
+// comma-separated list of client certificate subjects (see
+// ClaimsFromCertificate) that BypassServiceTokenPermissions applies to.
+func parseBypassCertificateSubjects(cfg Config) map[string]struct{} {
+	subjects := make(map[string]struct{})
+	for _, subject := range strings.Split(cfg.GetString("BypassCertificateSubjects"), ",") {
+		if subject = strings.TrimSpace(subject); subject != "" {
+			subjects[subject] = struct{}{}
+		}
+	}
+	return subjects
+}
+
+// bypassesPermissions reports whether claims skip permission checks under
+// BypassServiceTokenPermissions. Client certificate claims only do when
+// their subject is allow-listed, since any certificate the CA issued
+// authenticates.
+func (a *Authorizer) bypassesPermissions(claims Claims) bool {
+	if !a.bypassServiceTokenPermissions || !claims.IsServiceToken() {
+		return false
+	}
+	if claims.IsClientCertificate() {
+		_, ok := a.bypassCertificateSubjects[claims.Subject]
+		return ok
+	}
+	return true
+}
+
+// parseAllowAllPermissions reads AllowAllPermissions. When true every
+// permission middleware lets authenticated callers through; it exists for local
+// development only and defaults to false.
+func parseAllowAllPermissions(cfg Config) (bool, error) {
+	raw := strings.TrimSpace(cfg.GetString("AllowAllPermissions"))
+	if raw == "" {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("jwt authorizer: parse AllowAllPermissions: %w", err)
+	}
+	return enabled, nil
+}
+
 func decodeServicePermissionsMultiRange(raw string) map[string][]int64 {
 	perms := make(map[string][]int64)
+	forEachServicePermission(raw, func(tenantID, serviceKey string, ranges []int64) {
+		if tenantID == "" {
+			perms[serviceKey] = ranges
+		}
+	})
+	return perms
+}
+
+// decodeTenantServicePermissions collects tenant-prefixed svc_perm segments
+// ("<tenant>/<service>:<ranges>"), keyed by tenant then service.
+func decodeTenantServicePermissions(raw string) map[string]map[string][]int64 {
+	var perms map[string]map[string][]int64
+	forEachServicePermission(raw, func(tenantID, serviceKey string, ranges []int64) {
+		if tenantID == "" {
+			return
+		}
+		if perms == nil {
+			perms = make(map[string]map[string][]int64)
+		}
+		if perms[tenantID] == nil {
+			perms[tenantID] = make(map[string][]int64)
+		}
+		perms[tenantID][serviceKey] = ranges
+	})
+	return perms
+}
+
+// forEachServicePermission parses "svc:r0,r1;tenant/svc:r0" entries, calling
+// fn with the (possibly empty) tenant, the lower-cased service and its ranges.
+func forEachServicePermission(raw string, fn func(tenantID, serviceKey string, ranges []int64)) {
 	if strings.TrimSpace(raw) == "" {
-		return perms
+		return
 	}
 	entries := strings.Split(raw, ";")
 	for _, entry := range entries {
@@ -563,7 +922,16 @@ func decodeServicePermissionsMultiRange(raw string) map[string][]int64 {
 		if len(parts) != 2 {
 			continue
 		}
-		serviceKey := strings.ToLower(strings.TrimSpace(parts[0]))
+		tenantID := ""
+		serviceKey := strings.TrimSpace(parts[0])
+		if idx := strings.LastIndex(serviceKey, "/"); idx >= 0 {
+			tenantID = strings.TrimSpace(serviceKey[:idx])
+			serviceKey = strings.TrimSpace(serviceKey[idx+1:])
+			if tenantID == "" {
+				continue
+			}
+		}
+		serviceKey = strings.ToLower(serviceKey)
 		if serviceKey == "" {
 			continue
 		}
@@ -589,10 +957,9 @@ func decodeServicePermissionsMultiRange(raw string) map[string][]int64 {
 		}
 
 		if len(ranges) > 0 {
-			perms[serviceKey] = ranges
+			fn(tenantID, serviceKey, ranges)
 		}
 	}
-	return perms
 }
 
 // parsePublicKey parses a public key from a base64-encoded PEM string.
@@ -636,12 +1003,14 @@ func parsePublicKey(pubKeyBase64 string) (interface{}, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse PKIX public key: %w", err)
 		}
-		// Support both RSA and ECDSA keys
+		// Support RSA, ECDSA, and Ed25519 keys
 		switch k := key.(type) {
 		case *rsa.PublicKey:
 			return k, nil
 		case *ecdsa.PublicKey:
 			return k, nil
+		case ed25519.PublicKey:
+			return k, nil
 		default:
 			return nil, fmt.Errorf("unsupported public key type in PKIX format: %T", key)
 		}
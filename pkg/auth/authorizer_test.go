@@ -6,14 +6,18 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/permissions"
 )
 
 type stubConfig map[string]string
@@ -306,6 +310,195 @@ func TestRequirePermissionParsesHyphenatedActionsForDecisionRequests(t *testing.
 	}
 }
 
+func TestNewAuthorizerWithStoreEnforcesPermissionsOffline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	privateKey, publicKeyPEM := testKeyPair(t)
+	store := permissions.NewStore(nil)
+	store.Replace(map[string]permissions.Metadata{
+		"ORD-ORDERS-LIST":   {Service: "ord", BitValue: 0},
+		"ORD-ORDERS-DELETE": {Service: "ord", BitValue: 1},
+	})
+	authorizer, err := NewAuthorizerWithStore(stubConfig{"RSAPublicKey": publicKeyPEM}, logger.MustNewDefaultLogger(), store)
+	if err != nil {
+		t.Fatalf("NewAuthorizerWithStore() error = %v", err)
+	}
+
+	token := signTestToken(t, privateKey, jwt.MapClaims{
+		"sub":      "user-1",
+		"svc_perm": "ord:" + strconv.FormatInt(1<<0, 36),
+	})
+
+	router := gin.New()
+	router.GET("/orders", authorizer.RequirePermission("ORD-ORDERS-LIST"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.DELETE("/orders", authorizer.RequirePermission("ORD-ORDERS-DELETE"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for method, want := range map[string]int{http.MethodGet: http.StatusNoContent, http.MethodDelete: http.StatusForbidden} {
+		req := httptest.NewRequest(method, "/orders", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if recorder.Code != want {
+			t.Fatalf("%s status = %d, want %d; body=%s", method, recorder.Code, want, recorder.Body.String())
+		}
+	}
+}
+
+func TestAuthorizerEnforcesMergedUpstreamCatalogs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	staticLoader := func(perms map[string]permissions.Metadata) permissions.Loader {
+		return func(context.Context) (map[string]permissions.Metadata, error) { return perms, nil }
+	}
+	store := permissions.NewStore(permissions.MergeLoaders(
+		permissions.ServiceLoader{Service: "ord", Loader: staticLoader(map[string]permissions.Metadata{
+			"ORD-ORDERS-LIST": {BitValue: 0},
+		})},
+		permissions.ServiceLoader{Service: "bil", Loader: staticLoader(map[string]permissions.Metadata{
+			"BIL-INVOICES-LIST": {Service: "bil", BitValue: 0},
+			// Owned by another upstream, so dropped.
+			"ORD-ORDERS-LIST": {Service: "ord", BitValue: 5},
+		})},
+		permissions.ServiceLoader{Service: "inv", Optional: true, Loader: func(context.Context) (map[string]permissions.Metadata, error) {
+			return nil, errors.New("inventory unavailable")
+		}},
+	))
+	if _, err := store.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if meta, ok := store.LookupService("ord", "ORD-ORDERS-LIST"); !ok || meta.Service != "ord" || meta.BitValue != 0 {
+		t.Fatalf("LookupService(ord) = %#v, %v", meta, ok)
+	}
+
+	privateKey, publicKeyPEM := testKeyPair(t)
+	authorizer, err := NewAuthorizerWithStore(stubConfig{"RSAPublicKey": publicKeyPEM}, logger.MustNewDefaultLogger(), store)
+	if err != nil {
+		t.Fatalf("NewAuthorizerWithStore() error = %v", err)
+	}
+	token := signTestToken(t, privateKey, jwt.MapClaims{
+		"sub":      "user-1",
+		"svc_perm": "ord:" + strconv.FormatInt(1<<0, 36),
+	})
+
+	router := gin.New()
+	router.GET("/orders", authorizer.RequirePermission("ORD-ORDERS-LIST"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.GET("/invoices", authorizer.RequirePermission("bil:BIL-INVOICES-LIST"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for path, want := range map[string]int{"/orders": http.StatusNoContent, "/invoices": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if recorder.Code != want {
+			t.Fatalf("%s status = %d, want %d; body=%s", path, recorder.Code, want, recorder.Body.String())
+		}
+	}
+}
+
+func TestAuthorizerResolvesPermissionsRegisteredAfterLoad(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := permissions.NewStore(nil)
+	store.Replace(map[string]permissions.Metadata{"ORD-ORDERS-LIST": {Service: "ord", BitValue: 0}})
+	var mu sync.Mutex
+	calls := map[string]int{}
+	store.SetResolver(func(_ context.Context, code string) (permissions.Metadata, bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[code]++
+		if code == "ORD-ORDERS-EXPORT" {
+			return permissions.Metadata{Service: "ord", BitValue: 1}, true, nil
+		}
+		return permissions.Metadata{}, false, nil
+	})
+
+	privateKey, publicKeyPEM := testKeyPair(t)
+	authorizer, err := NewAuthorizerWithStore(stubConfig{"RSAPublicKey": publicKeyPEM}, logger.MustNewDefaultLogger(), store)
+	if err != nil {
+		t.Fatalf("NewAuthorizerWithStore() error = %v", err)
+	}
+	token := signTestToken(t, privateKey, jwt.MapClaims{
+		"sub":      "user-1",
+		"svc_perm": "ord:" + strconv.FormatInt(1<<1, 36),
+	})
+
+	router := gin.New()
+	router.GET("/export", authorizer.RequirePermission("ORD-ORDERS-EXPORT"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.GET("/purge", authorizer.RequirePermission("ORD-ORDERS-PURGE"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for _, tc := range []struct {
+		path string
+		want int
+	}{
+		{"/export", http.StatusNoContent},
+		{"/export", http.StatusNoContent},
+		{"/purge", http.StatusForbidden},
+		{"/purge", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if recorder.Code != tc.want {
+			t.Fatalf("%s status = %d, want %d; body=%s", tc.path, recorder.Code, tc.want, recorder.Body.String())
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls["ORD-ORDERS-EXPORT"] != 1 {
+		t.Fatalf("resolved code fetched %d times, want 1", calls["ORD-ORDERS-EXPORT"])
+	}
+	if calls["ORD-ORDERS-PURGE"] != 1 {
+		t.Fatalf("unknown code fetched %d times, want 1 (negative cache)", calls["ORD-ORDERS-PURGE"])
+	}
+	if _, ok := store.Lookup("ORD-ORDERS-EXPORT"); !ok {
+		t.Fatal("resolved code was not added to the store")
+	}
+}
+
+func TestAllowAllPermissionsSkipsChecks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	privateKey, publicKeyPEM := testKeyPair(t)
+	authorizer := testAuthorizer(t, stubConfig{
+		"RSAPublicKey":        publicKeyPEM,
+		"AllowAllPermissions": "true",
+	})
+
+	router := gin.New()
+	router.GET("/protected", authorizer.RequirePermission("TEN-TENANTS-LIST"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+signTestToken(t, privateKey, jwt.MapClaims{"sub": "user-1"}))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d; body=%s", recorder.Code, http.StatusNoContent, recorder.Body.String())
+	}
+
+	// Authentication is still required.
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/protected", nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+}
+
 func testAuthorizer(t *testing.T, cfg stubConfig) *Authorizer {
 	t.Helper()
 
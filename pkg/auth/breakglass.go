@@ -0,0 +1,497 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+// TokenUseBreakGlass is the token_use recorded on break-glass tokens and on
+// the claims injected for requests admitted with one.
+const TokenUseBreakGlass = "break_glass"
+
+const (
+	// DefaultBreakGlassHeader is the header break-glass tokens are read from.
+	DefaultBreakGlassHeader = "X-Break-Glass-Token"
+	// DefaultBreakGlassTTL is the lifetime of a break-glass token when none
+	// is requested.
+	DefaultBreakGlassTTL = 15 * time.Minute
+	// DefaultBreakGlassMaxTTL caps requested lifetimes.
+	DefaultBreakGlassMaxTTL = time.Hour
+	// DefaultBreakGlassMinReasonLength is the shortest accepted reason.
+	DefaultBreakGlassMinReasonLength = 10
+
+	defaultBreakGlassIssuer = "core-lab/break-glass"
+)
+
+// Break-glass event types passed to BreakGlassConfig.Audit and Alert.
+const (
+	BreakGlassIssued = "break_glass.issued"
+	BreakGlassUsed   = "break_glass.used"
+	BreakGlassDenied = "break_glass.denied"
+)
+
+// ctxBreakGlass is the gin.Context key the admitted break-glass grant is
+// stored under.
+const ctxBreakGlass ContextKey = "auth_break_glass"
+
+var (
+	// ErrBreakGlassDisabled is returned when break-glass access is not enabled.
+	ErrBreakGlassDisabled = errors.New("break-glass access is disabled")
+	// ErrBreakGlassInvalid is returned for malformed, forged, or revoked
+	// break-glass tokens.
+	ErrBreakGlassInvalid = errors.New("break-glass token is invalid")
+	// ErrBreakGlassExpired is returned once a break-glass token has expired.
+	ErrBreakGlassExpired = errors.New("break-glass token has expired")
+	// ErrBreakGlassReasonRequired is returned by Issue for a missing or too
+	// short reason.
+	ErrBreakGlassReasonRequired = errors.New("break-glass access requires a reason")
+)
+
+// BreakGlassGrant is an emergency grant to bypass permission checks on the
+// configured routes, issued to one operator for a short time with a reason.
+type BreakGlassGrant struct {
+	ID       string `json:"id"`
+	Subject  string `json:"subject"`
+	Reason   string `json:"reason"`
+	TenantID string `json:"tenant_id,omitempty"`
+	// Ticket references the incident the access was granted for.
+	Ticket string `json:"ticket,omitempty"`
+	// IssuedBy is the subject that issued the token when it differs from
+	// Subject; see WithBreakGlassSubject.
+	IssuedBy  string    `json:"issued_by,omitempty"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Claims converts the grant into Claims with token_use "break_glass" and no
+// permissions, so handlers and audit logging see the operator.
+func (g BreakGlassGrant) Claims() Claims {
+	raw := map[string]any{
+		"sub":       g.Subject,
+		"token_use": TokenUseBreakGlass,
+		"jti":       g.ID,
+		"bg_reason": g.Reason,
+	}
+	if g.TenantID != "" {
+		raw["tenant_id"] = g.TenantID
+	}
+	return Claims{
+		Subject:  g.Subject,
+		TokenUse: TokenUseBreakGlass,
+		Raw:      raw,
+	}
+}
+
+// BreakGlassEvent describes an issued, used, or denied break-glass grant.
+type BreakGlassEvent struct {
+	Type  string          `json:"type"`
+	Grant BreakGlassGrant `json:"grant"`
+	// Method, Route and Path describe the request for used and denied
+	// events; Permission is the expression that was bypassed.
+	Method     string    `json:"method,omitempty"`
+	Route      string    `json:"route,omitempty"`
+	Path       string    `json:"path,omitempty"`
+	Permission string    `json:"permission,omitempty"`
+	TenantID   string    `json:"tenant_id,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// BreakGlassConfig configures NewBreakGlass. Break-glass access stays
+// disabled unless Enabled is set, e.g. from a BreakGlassEnabled config key.
+type BreakGlassConfig struct {
+	Enabled bool
+	// Secret signs break-glass tokens (HS256) and must be at least 32 bytes,
+	// distinct from the access token and capability secrets.
+	Secret []byte
+	// Issuer is recorded in and required of every token. Default:
+	// "core-lab/break-glass".
+	Issuer string
+	// Routes are the route templates permission checks may be bypassed on,
+	// optionally prefixed with a method: "POST /admin/tenants/:id/unlock".
+	// A trailing "/*" covers every route below a prefix. At least one is
+	// required.
+	Routes []string
+	// DefaultTTL applies when Issue is not given WithBreakGlassTTL. Default: 15m.
+	DefaultTTL time.Duration
+	// MaxTTL caps requested lifetimes. Default: 1h.
+	MaxTTL time.Duration
+	// MinReasonLength is the shortest reason Issue accepts. Default: 10.
+	MinReasonLength int
+	// Audit records every event and is required. A request is rejected when
+	// its used event cannot be recorded, and a token is not issued when its
+	// issued event cannot.
+	Audit func(ctx context.Context, event BreakGlassEvent) error
+	// Alert notifies on-call staff, e.g. through a pager webhook, and is
+	// required. It runs after Audit for issued and used events; failures are
+	// logged.
+	Alert func(ctx context.Context, event BreakGlassEvent) error
+	// Revocation, when set, rejects tokens whose jti is revoked, to end an
+	// emergency early.
+	Revocation RevocationChecker
+	Logger     logger.LogManager
+}
+
+// BreakGlass issues and admits break-glass tokens.
+type BreakGlass struct {
+	cfg BreakGlassConfig
+	now func() time.Time
+}
+
+// NewBreakGlass validates cfg and fills in defaults. A disabled config needs
+// nothing else; its BreakGlass rejects every token and issues none.
+func NewBreakGlass(cfg BreakGlassConfig) (*BreakGlass, error) {
+	if !cfg.Enabled {
+		return &BreakGlass{cfg: BreakGlassConfig{Logger: cfg.Logger}, now: time.Now}, nil
+	}
+	if len(cfg.Secret) < minSharedSecretLength {
+		return nil, fmt.Errorf("break-glass: secret must be at least %d bytes", minSharedSecretLength)
+	}
+	if cfg.Audit == nil || cfg.Alert == nil {
+		return nil, errors.New("break-glass: Audit and Alert are required")
+	}
+	routes := cfg.Routes[:0:0]
+	for _, route := range cfg.Routes {
+		if route = strings.TrimSpace(route); route != "" {
+			routes = append(routes, route)
+		}
+	}
+	if len(routes) == 0 {
+		return nil, errors.New("break-glass: at least one route is required")
+	}
+	cfg.Routes = routes
+	cfg.Issuer = strings.TrimSpace(cfg.Issuer)
+	if cfg.Issuer == "" {
+		cfg.Issuer = defaultBreakGlassIssuer
+	}
+	if cfg.DefaultTTL <= 0 {
+		cfg.DefaultTTL = DefaultBreakGlassTTL
+	}
+	if cfg.MaxTTL <= 0 {
+		cfg.MaxTTL = DefaultBreakGlassMaxTTL
+	}
+	cfg.DefaultTTL = min(cfg.DefaultTTL, cfg.MaxTTL)
+	if cfg.MinReasonLength <= 0 {
+		cfg.MinReasonLength = DefaultBreakGlassMinReasonLength
+	}
+	return &BreakGlass{cfg: cfg, now: time.Now}, nil
+}
+
+// Enabled reports whether break-glass access is enabled.
+func (b *BreakGlass) Enabled() bool { return b != nil && b.cfg.Enabled }
+
+type breakGlassOptions struct {
+	ttl      time.Duration
+	ticket   string
+	tenantID string
+	subject  string
+}
+
+// BreakGlassOption customizes Issue.
+type BreakGlassOption func(*breakGlassOptions)
+
+// WithBreakGlassTTL sets the token lifetime, capped at MaxTTL.
+func WithBreakGlassTTL(ttl time.Duration) BreakGlassOption {
+	return func(o *breakGlassOptions) { o.ttl = ttl }
+}
+
+// WithBreakGlassTicket records the incident the access is granted for.
+func WithBreakGlassTicket(ticket string) BreakGlassOption {
+	return func(o *breakGlassOptions) { o.ticket = strings.TrimSpace(ticket) }
+}
+
+// WithBreakGlassTenant limits the grant to one tenant: tenant-scoped checks
+// (RequirePermissionInTenant) for other tenants are not bypassed.
+func WithBreakGlassTenant(tenantID string) BreakGlassOption {
+	return func(o *breakGlassOptions) { o.tenantID = strings.TrimSpace(tenantID) }
+}
+
+// WithBreakGlassSubject issues the token to subject, e.g. the on-call
+// engineer an approver grants access to, recording the caller as IssuedBy.
+func WithBreakGlassSubject(subject string) BreakGlassOption {
+	return func(o *breakGlassOptions) { o.subject = strings.TrimSpace(subject) }
+}
+
+// Issue grants the caller break-glass access for reason and returns the
+// signed token. The issued event is audited and alerted before the token is
+// returned. Tokens cannot be derived from capability or break-glass tokens;
+// protect the issuing route with its own permission.
+//
+//	token, grant, err := bg.Issue(ctx, claims, "restore access for locked tenant",
+//	    auth.WithBreakGlassTicket("INC-4711"))
+func (b *BreakGlass) Issue(ctx context.Context, claims Claims, reason string, opts ...BreakGlassOption) (string, BreakGlassGrant, error) {
+	if !b.Enabled() {
+		return "", BreakGlassGrant{}, ErrBreakGlassDisabled
+	}
+	reason = strings.TrimSpace(reason)
+	if len([]rune(reason)) < b.cfg.MinReasonLength {
+		return "", BreakGlassGrant{}, fmt.Errorf("%w of at least %d characters", ErrBreakGlassReasonRequired, b.cfg.MinReasonLength)
+	}
+	switch strings.ToLower(strings.TrimSpace(claims.TokenUse)) {
+	case TokenUseCapability, TokenUseBreakGlass:
+		return "", BreakGlassGrant{}, fmt.Errorf("break-glass: cannot issue from a %s token", claims.TokenUse)
+	}
+	caller := claims.UserID()
+	if caller == "" {
+		return "", BreakGlassGrant{}, errors.New("break-glass: claims have no subject")
+	}
+
+	options := breakGlassOptions{ttl: b.cfg.DefaultTTL}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	if options.ttl <= 0 {
+		options.ttl = b.cfg.DefaultTTL
+	}
+	options.ttl = min(options.ttl, b.cfg.MaxTTL)
+
+	id, err := newCapabilityID()
+	if err != nil {
+		return "", BreakGlassGrant{}, err
+	}
+	now := b.now().Truncate(time.Second)
+	grant := BreakGlassGrant{
+		ID:        id,
+		Subject:   caller,
+		Reason:    reason,
+		TenantID:  options.tenantID,
+		Ticket:    options.ticket,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(options.ttl),
+	}
+	if options.subject != "" && options.subject != caller {
+		grant.Subject, grant.IssuedBy = options.subject, caller
+	}
+
+	mapClaims := jwt.MapClaims{
+		"iss":       b.cfg.Issuer,
+		"sub":       grant.Subject,
+		"jti":       grant.ID,
+		"iat":       grant.IssuedAt.Unix(),
+		"exp":       grant.ExpiresAt.Unix(),
+		"token_use": TokenUseBreakGlass,
+		"bg_reason": grant.Reason,
+	}
+	for key, value := range map[string]string{"tenant_id": grant.TenantID, "bg_ticket": grant.Ticket, "bg_issued_by": grant.IssuedBy} {
+		if value != "" {
+			mapClaims[key] = value
+		}
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, mapClaims).SignedString(b.cfg.Secret)
+	if err != nil {
+		return "", BreakGlassGrant{}, fmt.Errorf("break-glass: sign token: %w", err)
+	}
+
+	event := BreakGlassEvent{Type: BreakGlassIssued, Grant: grant, TenantID: grant.TenantID, Time: b.now()}
+	if err := b.cfg.Audit(ctx, event); err != nil {
+		return "", BreakGlassGrant{}, fmt.Errorf("break-glass: audit: %w", err)
+	}
+	b.alert(ctx, event)
+	return token, grant, nil
+}
+
+// Verify checks the token's signature, expiry and revocation.
+func (b *BreakGlass) Verify(ctx context.Context, token string) (BreakGlassGrant, error) {
+	if !b.Enabled() {
+		return BreakGlassGrant{}, ErrBreakGlassDisabled
+	}
+	parsed, err := jwt.Parse(strings.TrimSpace(token), func(*jwt.Token) (interface{}, error) {
+		return b.cfg.Secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(b.cfg.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(b.now),
+	)
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return BreakGlassGrant{}, ErrBreakGlassExpired
+	case err != nil:
+		return BreakGlassGrant{}, fmt.Errorf("%w: %v", ErrBreakGlassInvalid, err)
+	}
+	mapClaims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return BreakGlassGrant{}, ErrBreakGlassInvalid
+	}
+	grant := breakGlassGrantFromMapClaims(mapClaims)
+	if grant.ID == "" || grant.Subject == "" || grant.Reason == "" || mapClaims["token_use"] != TokenUseBreakGlass {
+		return BreakGlassGrant{}, ErrBreakGlassInvalid
+	}
+
+	if b.cfg.Revocation != nil {
+		revoked, err := b.cfg.Revocation.Revoked(ctx, []string{grant.ID})
+		if err != nil {
+			return BreakGlassGrant{}, fmt.Errorf("%w: %v", ErrRevocationUnavailable, err)
+		}
+		if revoked[grant.ID] {
+			return BreakGlassGrant{}, fmt.Errorf("%w: %v", ErrBreakGlassInvalid, ErrTokenRevoked)
+		}
+	}
+	return grant, nil
+}
+
+func breakGlassGrantFromMapClaims(claims jwt.MapClaims) BreakGlassGrant {
+	str := func(key string) string {
+		value, _ := claims[key].(string)
+		return strings.TrimSpace(value)
+	}
+	grant := BreakGlassGrant{
+		ID:       str("jti"),
+		Subject:  str("sub"),
+		Reason:   str("bg_reason"),
+		TenantID: str("tenant_id"),
+		Ticket:   str("bg_ticket"),
+		IssuedBy: str("bg_issued_by"),
+	}
+	if issuedAt, err := claims.GetIssuedAt(); err == nil && issuedAt != nil {
+		grant.IssuedAt = issuedAt.Time
+	}
+	if expiresAt, err := claims.GetExpirationTime(); err == nil && expiresAt != nil {
+		grant.ExpiresAt = expiresAt.Time
+	}
+	return grant
+}
+
+// AllowsRoute reports whether permission checks on the route template may be
+// bypassed for method.
+func (b *BreakGlass) AllowsRoute(method, route string) bool {
+	if !b.Enabled() || route == "" {
+		return false
+	}
+	for _, pattern := range b.cfg.Routes {
+		if m, p, ok := strings.Cut(pattern, " "); ok {
+			if !strings.EqualFold(m, method) {
+				continue
+			}
+			pattern = strings.TrimSpace(p)
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+			if route == prefix || strings.HasPrefix(route, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if route == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// UseBreakGlass lets requests carrying a break-glass token in the
+// X-Break-Glass-Token header through the authorizer's permission middlewares
+// on the routes bg allows, instead of checking permissions. Each admitted
+// request is audited, failing closed, and alerted; tokens presented on other
+// routes, or while break-glass access is disabled, are rejected and audited
+// as denied. It returns the receiver so calls can be chained.
+func (a *Authorizer) UseBreakGlass(bg *BreakGlass) *Authorizer {
+	a.breakGlass = bg
+	return a
+}
+
+// admitBreakGlass handles a request carrying a break-glass token for a
+// permission middleware: it either continues the chain with the grant's
+// claims or aborts.
+func (a *Authorizer) admitBreakGlass(c *gin.Context, token string, expr PermissionExpression, tenantSources []TenantSource, log logger.LogManager) {
+	b := a.breakGlass
+	ctx := c.Request.Context()
+	event := BreakGlassEvent{
+		Method:     c.Request.Method,
+		Route:      c.FullPath(),
+		Path:       c.Request.URL.Path,
+		Permission: expr.String(),
+	}
+	deny := func(status int, code, message string, err error) {
+		event.Type = BreakGlassDenied
+		event.Time = b.now()
+		if err != nil {
+			event.Error = err.Error()
+		}
+		if b.cfg.Audit != nil {
+			if auditErr := b.cfg.Audit(ctx, event); auditErr != nil {
+				log.ErrorFCtx(ctx, "Break-glass audit failed for denied request: %v", auditErr)
+			}
+		}
+		a.abortWithJSON(c, status, code, message, log)
+	}
+
+	grant, err := b.Verify(ctx, token)
+	event.Grant = grant
+	switch {
+	case errors.Is(err, ErrBreakGlassDisabled):
+		deny(http.StatusForbidden, "break_glass_disabled", "break-glass access is disabled", err)
+		return
+	case errors.Is(err, ErrBreakGlassExpired):
+		deny(http.StatusUnauthorized, "break_glass_expired", "break-glass token has expired", err)
+		return
+	case errors.Is(err, ErrBreakGlassInvalid):
+		deny(http.StatusUnauthorized, "invalid_break_glass", "break-glass token is invalid", err)
+		return
+	case err != nil:
+		deny(http.StatusServiceUnavailable, "break_glass_unavailable", "break-glass validation is unavailable", err)
+		return
+	}
+
+	if !b.AllowsRoute(c.Request.Method, c.FullPath()) {
+		deny(http.StatusForbidden, "break_glass_route_not_allowed", "break-glass access is not allowed on this route", nil)
+		return
+	}
+	tenantID := ""
+	if len(tenantSources) > 0 {
+		if tenantID = resolveTenantSource(c, tenantSources); tenantID == "" {
+			deny(http.StatusForbidden, "tenant_scope_required", "tenant is required", nil)
+			return
+		}
+		if grant.TenantID != "" && tenantID != grant.TenantID {
+			deny(http.StatusForbidden, "break_glass_tenant_mismatch", "break-glass token does not cover this tenant", nil)
+			return
+		}
+	}
+	event.TenantID = tenantID
+
+	event.Type = BreakGlassUsed
+	event.Time = b.now()
+	if err := b.cfg.Audit(ctx, event); err != nil {
+		log.ErrorFCtx(ctx, "Break-glass audit failed, rejecting request (grant=%s subject=%s): %v", grant.ID, grant.Subject, err)
+		a.abortWithJSON(c, http.StatusServiceUnavailable, "break_glass_audit_unavailable", "break-glass access could not be audited", log)
+		return
+	}
+	b.alert(ctx, event)
+	log.WarnFCtx(ctx, "Break-glass access: permission check bypassed (permission=%s subject=%s grant=%s reason=%q route=%s %s)",
+		event.Permission, grant.Subject, grant.ID, grant.Reason, event.Method, event.Route)
+
+	c.Set(string(ctxBreakGlass), grant)
+	SetClaims(c, grant.Claims())
+	SetTenantID(c, tenantID)
+	c.Next()
+}
+
+func (b *BreakGlass) alert(ctx context.Context, event BreakGlassEvent) {
+	if err := b.cfg.Alert(ctx, event); err != nil && b.cfg.Logger != nil {
+		b.cfg.Logger.ErrorFCtx(ctx, "Break-glass alert failed (type=%s grant=%s): %v", event.Type, event.Grant.ID, err)
+	}
+}
+
+// BreakGlassFromContext returns the break-glass grant a request was admitted
+// with.
+func BreakGlassFromContext(c *gin.Context) (BreakGlassGrant, bool) {
+	if c == nil {
+		return BreakGlassGrant{}, false
+	}
+	value, ok := c.Get(string(ctxBreakGlass))
+	if !ok {
+		return BreakGlassGrant{}, false
+	}
+	grant, ok := value.(BreakGlassGrant)
+	return grant, ok
+}
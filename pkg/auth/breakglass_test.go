@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type breakGlassRecorder struct {
+	events   []BreakGlassEvent
+	alerts   []BreakGlassEvent
+	auditErr error
+}
+
+func (r *breakGlassRecorder) audit(_ context.Context, event BreakGlassEvent) error {
+	if r.auditErr != nil {
+		return r.auditErr
+	}
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *breakGlassRecorder) alert(_ context.Context, event BreakGlassEvent) error {
+	r.alerts = append(r.alerts, event)
+	return nil
+}
+
+func testBreakGlass(t *testing.T, recorder *breakGlassRecorder, cfg BreakGlassConfig) *BreakGlass {
+	t.Helper()
+	cfg.Enabled = true
+	cfg.Secret = []byte(strings.Repeat("b", 32))
+	cfg.Audit = recorder.audit
+	cfg.Alert = recorder.alert
+	bg, err := NewBreakGlass(cfg)
+	if err != nil {
+		t.Fatalf("NewBreakGlass: %v", err)
+	}
+	return bg
+}
+
+func TestBreakGlassIssue(t *testing.T) {
+	ctx := context.Background()
+	recorder := &breakGlassRecorder{}
+	bg := testBreakGlass(t, recorder, BreakGlassConfig{Routes: []string{"/admin/*"}, MaxTTL: 30 * time.Minute})
+	approver := Claims{Subject: "lead-1", TokenUse: "access"}
+
+	if _, _, err := bg.Issue(ctx, approver, "fix"); !errors.Is(err, ErrBreakGlassReasonRequired) {
+		t.Fatalf("short reason err = %v, want ErrBreakGlassReasonRequired", err)
+	}
+	if _, _, err := bg.Issue(ctx, Claims{Subject: "user-1", TokenUse: TokenUseCapability}, "restore tenant access"); err == nil {
+		t.Fatal("Issue from a capability succeeded")
+	}
+
+	token, grant, err := bg.Issue(ctx, approver, "restore tenant access",
+		WithBreakGlassTTL(2*time.Hour), WithBreakGlassTicket("INC-4711"), WithBreakGlassSubject("oncall-7"))
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if grant.Subject != "oncall-7" || grant.IssuedBy != "lead-1" || grant.ExpiresAt.Sub(grant.IssuedAt) != 30*time.Minute {
+		t.Fatalf("grant = %+v, want oncall-7 issued by lead-1 for 30m", grant)
+	}
+	if len(recorder.events) != 1 || recorder.events[0].Type != BreakGlassIssued || len(recorder.alerts) != 1 {
+		t.Fatalf("events = %+v alerts = %+v, want one issued event and alert", recorder.events, recorder.alerts)
+	}
+
+	verified, err := bg.Verify(ctx, token)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if verified.Reason != "restore tenant access" || verified.Ticket != "INC-4711" || verified.IssuedBy != "lead-1" {
+		t.Fatalf("verified = %+v", verified)
+	}
+
+	recorder.auditErr = errors.New("audit sink down")
+	if _, _, err := bg.Issue(ctx, approver, "restore tenant access"); err == nil {
+		t.Fatal("Issue succeeded without an audit record")
+	}
+}
+
+func TestBreakGlassDisabledByDefault(t *testing.T) {
+	bg, err := NewBreakGlass(BreakGlassConfig{Routes: []string{"/admin/*"}})
+	if err != nil {
+		t.Fatalf("NewBreakGlass: %v", err)
+	}
+	if bg.Enabled() {
+		t.Fatal("break-glass enabled without Enabled")
+	}
+	if _, _, err := bg.Issue(context.Background(), Claims{Subject: "user-1"}, "restore tenant access"); !errors.Is(err, ErrBreakGlassDisabled) {
+		t.Fatalf("Issue err = %v, want ErrBreakGlassDisabled", err)
+	}
+	if _, err := NewBreakGlass(BreakGlassConfig{Enabled: true, Secret: []byte(strings.Repeat("b", 32)), Routes: []string{"/admin/*"}}); err == nil {
+		t.Fatal("NewBreakGlass accepted an enabled config without Audit and Alert")
+	}
+}
+
+func TestAuthorizerAdmitsBreakGlassOnConfiguredRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	_, publicKeyPEM := testKeyPair(t)
+	recorder := &breakGlassRecorder{}
+	bg := testBreakGlass(t, recorder, BreakGlassConfig{Routes: []string{"POST /admin/tenants/:id/unlock"}})
+	authorizer := testAuthorizer(t, stubConfig{"RSAPublicKey": publicKeyPEM}).UseBreakGlass(bg)
+
+	router := gin.New()
+	handler := func(c *gin.Context) {
+		grant, ok := BreakGlassFromContext(c)
+		claims, _ := GetClaims(c)
+		if !ok || claims.TokenUse != TokenUseBreakGlass || claims.Subject != grant.Subject {
+			t.Errorf("grant = %+v (%v), claims = %+v", grant, ok, claims)
+		}
+		c.Status(http.StatusNoContent)
+	}
+	router.POST("/admin/tenants/:id/unlock", authorizer.RequirePermission("TEN-TENANTS-UNLOCK"), handler)
+	router.DELETE("/admin/tenants/:id", authorizer.RequirePermission("TEN-TENANTS-DELETE"), handler)
+
+	token, _, err := bg.Issue(ctx, Claims{Subject: "oncall-7"}, "customer locked out during incident")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	serve := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(DefaultBreakGlassHeader, token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	recorder.events, recorder.alerts = nil, nil
+
+	if rec := serve(http.MethodPost, "/admin/tenants/t1/unlock", token); rec.Code != http.StatusNoContent {
+		t.Fatalf("allowed route status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	if len(recorder.events) != 1 || recorder.events[0].Type != BreakGlassUsed || recorder.events[0].Permission != "TEN-TENANTS-UNLOCK" || len(recorder.alerts) != 1 {
+		t.Fatalf("events = %+v alerts = %+v, want one used event and alert", recorder.events, recorder.alerts)
+	}
+
+	if rec := serve(http.MethodDelete, "/admin/tenants/t1", token); rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "break_glass_route_not_allowed") {
+		t.Fatalf("other route status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	if last := recorder.events[len(recorder.events)-1]; last.Type != BreakGlassDenied {
+		t.Fatalf("last event = %+v, want a denied event", last)
+	}
+
+	if rec := serve(http.MethodPost, "/admin/tenants/t1/unlock", token+"x"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("forged token status = %d, want 401", rec.Code)
+	}
+
+	recorder.auditErr = errors.New("audit sink down")
+	if rec := serve(http.MethodPost, "/admin/tenants/t1/unlock", token); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unaudited request status = %d, want 503", rec.Code)
+	}
+}
@@ -0,0 +1,496 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/milan604/core-lab/pkg/logger"
+	redis "github.com/redis/go-redis/v9"
+)
+
+// TokenUseCapability is the token_use recorded on capability tokens and on the
+// claims RequireCapability injects.
+const TokenUseCapability = "capability"
+
+const (
+	// DefaultCapabilityHeader is the header RequireCapability reads tokens from.
+	DefaultCapabilityHeader = "X-Capability-Token"
+	// DefaultCapabilityQueryParam is the query parameter RequireCapability falls
+	// back to, so capability URLs work from browsers and webhook senders.
+	DefaultCapabilityQueryParam = "capability"
+	// DefaultCapabilityTTL is the lifetime of a capability when none is requested.
+	DefaultCapabilityTTL = 5 * time.Minute
+	// DefaultCapabilityMaxTTL caps requested lifetimes.
+	DefaultCapabilityMaxTTL = time.Hour
+
+	defaultCapabilityIssuer = "core-lab/capability"
+)
+
+// ctxCapability is the gin.Context key RequireCapability stores the verified
+// capability under.
+const ctxCapability ContextKey = "auth_capability"
+
+var (
+	// ErrCapabilityInvalid is returned for malformed, forged, or revoked capability tokens.
+	ErrCapabilityInvalid = errors.New("capability token is invalid")
+	// ErrCapabilityExpired is returned once a capability token has expired.
+	ErrCapabilityExpired = errors.New("capability token has expired")
+	// ErrCapabilityScope is returned when a valid token does not grant the
+	// requested action on the requested resource.
+	ErrCapabilityScope = errors.New("capability token does not grant this operation")
+	// ErrCapabilityUsed is returned when a one-time capability is presented again.
+	ErrCapabilityUsed = errors.New("capability token has already been used")
+)
+
+// Capability is a narrowly scoped grant derived from a caller's claims: one
+// action on one resource, for a short time. It carries no permissions, so a
+// leaked capability URL exposes a single operation rather than an access token.
+type Capability struct {
+	ID        string `json:"id"`
+	Action    string `json:"action"`
+	Resource  string `json:"resource"`
+	Subject   string `json:"subject"`
+	TenantID  string `json:"tenant_id,omitempty"`
+	ServiceID string `json:"service_id,omitempty"`
+	// ParentID is the jti of the token the capability was derived from, so
+	// revoking that token also revokes its capabilities.
+	ParentID  string    `json:"parent_id,omitempty"`
+	OneTime   bool      `json:"one_time,omitempty"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Claims converts the capability into Claims with token_use "capability" and
+// no permissions. Tenant resolution and audit logging see the original caller;
+// RequirePermission rejects the claims.
+func (c Capability) Claims() Claims {
+	raw := map[string]any{
+		"sub":          c.Subject,
+		"token_use":    TokenUseCapability,
+		"jti":          c.ID,
+		"cap_action":   c.Action,
+		"cap_resource": c.Resource,
+	}
+	if c.TenantID != "" {
+		raw["tenant_id"] = c.TenantID
+	}
+	if c.ServiceID != "" {
+		raw["service_id"] = c.ServiceID
+	}
+	return Claims{
+		Subject:  c.Subject,
+		TokenUse: TokenUseCapability,
+		Raw:      raw,
+	}
+}
+
+// CapabilityReplayGuard records one-time capabilities as they are redeemed.
+type CapabilityReplayGuard interface {
+	// Consume marks id as used until expiresAt and reports whether this was
+	// the first use.
+	Consume(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+}
+
+// CapabilityConfig configures NewCapabilities.
+type CapabilityConfig struct {
+	// Secret signs capability tokens (HS256) and must be at least 32 bytes.
+	// Use a secret distinct from JWTSharedSecret so capabilities can never be
+	// replayed as access tokens.
+	Secret []byte
+	// Issuer is recorded in and required of every token. Default: "core-lab/capability".
+	Issuer string
+	// DefaultTTL applies when Issue is not given WithCapabilityTTL. Default: 5m.
+	DefaultTTL time.Duration
+	// MaxTTL caps requested lifetimes. Default: 1h.
+	MaxTTL time.Duration
+	// ReplayGuard is required to issue one-time capabilities.
+	ReplayGuard CapabilityReplayGuard
+	// Revocation, when set, rejects capabilities whose own jti or parent token
+	// jti is revoked.
+	Revocation RevocationChecker
+	Logger     logger.LogManager
+}
+
+// Capabilities issues and verifies capability tokens.
+type Capabilities struct {
+	cfg CapabilityConfig
+	now func() time.Time
+}
+
+// NewCapabilities validates cfg and fills in defaults.
+func NewCapabilities(cfg CapabilityConfig) (*Capabilities, error) {
+	if len(cfg.Secret) < minSharedSecretLength {
+		return nil, fmt.Errorf("capabilities: secret must be at least %d bytes", minSharedSecretLength)
+	}
+	cfg.Issuer = strings.TrimSpace(cfg.Issuer)
+	if cfg.Issuer == "" {
+		cfg.Issuer = defaultCapabilityIssuer
+	}
+	if cfg.DefaultTTL <= 0 {
+		cfg.DefaultTTL = DefaultCapabilityTTL
+	}
+	if cfg.MaxTTL <= 0 {
+		cfg.MaxTTL = DefaultCapabilityMaxTTL
+	}
+	if cfg.DefaultTTL > cfg.MaxTTL {
+		cfg.DefaultTTL = cfg.MaxTTL
+	}
+	return &Capabilities{cfg: cfg, now: time.Now}, nil
+}
+
+type capabilityOptions struct {
+	ttl     time.Duration
+	oneTime bool
+}
+
+// CapabilityOption customizes Issue.
+type CapabilityOption func(*capabilityOptions)
+
+// WithCapabilityTTL sets the capability lifetime, capped at MaxTTL.
+func WithCapabilityTTL(ttl time.Duration) CapabilityOption {
+	return func(o *capabilityOptions) { o.ttl = ttl }
+}
+
+// WithOneTimeUse makes the capability redeemable once. It requires a ReplayGuard.
+func WithOneTimeUse() CapabilityOption {
+	return func(o *capabilityOptions) { o.oneTime = true }
+}
+
+// Issue derives a capability granting action on resource from the caller's
+// claims and returns the signed token. Capabilities cannot be derived from
+// other capabilities.
+//
+//	token, _, err := caps.Issue(claims, "reports:download", reportID)
+func (c *Capabilities) Issue(claims Claims, action, resource string, opts ...CapabilityOption) (string, Capability, error) {
+	action = strings.TrimSpace(action)
+	resource = strings.TrimSpace(resource)
+	if action == "" || resource == "" {
+		return "", Capability{}, errors.New("capabilities: action and resource are required")
+	}
+	if strings.EqualFold(strings.TrimSpace(claims.TokenUse), TokenUseCapability) {
+		return "", Capability{}, errors.New("capabilities: cannot derive a capability from a capability")
+	}
+	subject := claims.UserID()
+	if subject == "" {
+		return "", Capability{}, errors.New("capabilities: claims have no subject")
+	}
+
+	options := capabilityOptions{ttl: c.cfg.DefaultTTL}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	if options.ttl <= 0 {
+		options.ttl = c.cfg.DefaultTTL
+	}
+	options.ttl = min(options.ttl, c.cfg.MaxTTL)
+	if options.oneTime && c.cfg.ReplayGuard == nil {
+		return "", Capability{}, errors.New("capabilities: one-time capabilities require a ReplayGuard")
+	}
+
+	id, err := newCapabilityID()
+	if err != nil {
+		return "", Capability{}, err
+	}
+	now := c.now().Truncate(time.Second)
+	capability := Capability{
+		ID:        id,
+		Action:    action,
+		Resource:  resource,
+		Subject:   subject,
+		TenantID:  claims.TenantID(),
+		ServiceID: claims.ServiceID(),
+		ParentID:  claims.ClaimString("jti"),
+		OneTime:   options.oneTime,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(options.ttl),
+	}
+
+	mapClaims := jwt.MapClaims{
+		"iss":          c.cfg.Issuer,
+		"sub":          capability.Subject,
+		"jti":          capability.ID,
+		"iat":          capability.IssuedAt.Unix(),
+		"exp":          capability.ExpiresAt.Unix(),
+		"token_use":    TokenUseCapability,
+		"cap_action":   capability.Action,
+		"cap_resource": capability.Resource,
+	}
+	if capability.TenantID != "" {
+		mapClaims["tenant_id"] = capability.TenantID
+	}
+	if capability.ServiceID != "" {
+		mapClaims["service_id"] = capability.ServiceID
+	}
+	if capability.ParentID != "" {
+		mapClaims["parent_jti"] = capability.ParentID
+	}
+	if capability.OneTime {
+		mapClaims["once"] = true
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, mapClaims).SignedString(c.cfg.Secret)
+	if err != nil {
+		return "", Capability{}, fmt.Errorf("capabilities: sign token: %w", err)
+	}
+	return token, capability, nil
+}
+
+// Verify checks the token's signature and expiry and that it grants action on
+// resource. One-time capabilities are consumed by a successful Verify.
+func (c *Capabilities) Verify(ctx context.Context, token, action, resource string) (Capability, error) {
+	parsed, err := jwt.Parse(strings.TrimSpace(token), func(*jwt.Token) (interface{}, error) {
+		return c.cfg.Secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(c.cfg.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(c.now),
+	)
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return Capability{}, ErrCapabilityExpired
+	case err != nil:
+		return Capability{}, fmt.Errorf("%w: %v", ErrCapabilityInvalid, err)
+	}
+	mapClaims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return Capability{}, ErrCapabilityInvalid
+	}
+
+	capability := capabilityFromMapClaims(mapClaims)
+	if capability.ID == "" || capability.Subject == "" || mapClaims["token_use"] != TokenUseCapability {
+		return Capability{}, ErrCapabilityInvalid
+	}
+	if capability.Action != strings.TrimSpace(action) || capability.Resource != strings.TrimSpace(resource) {
+		return Capability{}, ErrCapabilityScope
+	}
+
+	if c.cfg.Revocation != nil {
+		ids := []string{capability.ID}
+		if capability.ParentID != "" {
+			ids = append(ids, capability.ParentID)
+		}
+		revoked, err := c.cfg.Revocation.Revoked(ctx, ids)
+		if err != nil {
+			return Capability{}, fmt.Errorf("%w: %v", ErrRevocationUnavailable, err)
+		}
+		for _, id := range ids {
+			if revoked[id] {
+				return Capability{}, fmt.Errorf("%w: %v", ErrCapabilityInvalid, ErrTokenRevoked)
+			}
+		}
+	}
+
+	if capability.OneTime {
+		if c.cfg.ReplayGuard == nil {
+			return Capability{}, fmt.Errorf("%w: no replay guard for one-time capability", ErrCapabilityInvalid)
+		}
+		first, err := c.cfg.ReplayGuard.Consume(ctx, capability.ID, capability.ExpiresAt)
+		if err != nil {
+			return Capability{}, fmt.Errorf("capabilities: replay guard: %w", err)
+		}
+		if !first {
+			return Capability{}, ErrCapabilityUsed
+		}
+	}
+	return capability, nil
+}
+
+func capabilityFromMapClaims(claims jwt.MapClaims) Capability {
+	str := func(key string) string {
+		value, _ := claims[key].(string)
+		return strings.TrimSpace(value)
+	}
+	capability := Capability{
+		ID:        str("jti"),
+		Action:    str("cap_action"),
+		Resource:  str("cap_resource"),
+		Subject:   str("sub"),
+		TenantID:  str("tenant_id"),
+		ServiceID: str("service_id"),
+		ParentID:  str("parent_jti"),
+	}
+	capability.OneTime, _ = claims["once"].(bool)
+	if issuedAt, err := claims.GetIssuedAt(); err == nil && issuedAt != nil {
+		capability.IssuedAt = issuedAt.Time
+	}
+	if expiresAt, err := claims.GetExpirationTime(); err == nil && expiresAt != nil {
+		capability.ExpiresAt = expiresAt.Time
+	}
+	return capability
+}
+
+// CapabilityURL appends token to rawURL as the capability query parameter,
+// producing a link such as a report download or webhook callback URL.
+func CapabilityURL(rawURL, token string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("capabilities: parse url: %w", err)
+	}
+	query := parsed.Query()
+	query.Set(DefaultCapabilityQueryParam, token)
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
+
+// CapabilityResource resolves the resource a request operates on.
+type CapabilityResource func(c *gin.Context) string
+
+// CapabilityParam resolves the resource from the named route parameter.
+func CapabilityParam(name string) CapabilityResource {
+	return func(c *gin.Context) string { return c.Param(name) }
+}
+
+// RequireCapability admits requests carrying a capability token, from the
+// X-Capability-Token header or the capability query parameter, that grants
+// action on the resource named by the request. The capability's claims replace
+// any others for the rest of the chain; use CapabilityFromContext to read it.
+//
+//	router.GET("/reports/:id/download",
+//	    caps.RequireCapability("reports:download", auth.CapabilityParam("id")),
+//	    downloadReport)
+func (c *Capabilities) RequireCapability(action string, resource CapabilityResource) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		log := logger.GetLogger(ctx)
+		if log == nil {
+			log = c.cfg.Logger
+		}
+
+		token := strings.TrimSpace(ctx.GetHeader(DefaultCapabilityHeader))
+		if token == "" {
+			token = strings.TrimSpace(ctx.Query(DefaultCapabilityQueryParam))
+		}
+		if token == "" {
+			abortCapability(ctx, log, http.StatusUnauthorized, "capability_required", "capability token required", nil)
+			return
+		}
+		target := ""
+		if resource != nil {
+			target = resource(ctx)
+		}
+
+		capability, err := c.Verify(ctx.Request.Context(), token, action, target)
+		switch {
+		case errors.Is(err, ErrCapabilityScope):
+			abortCapability(ctx, log, http.StatusForbidden, "capability_scope_mismatch", "capability token does not grant this operation", err)
+			return
+		case errors.Is(err, ErrCapabilityExpired):
+			abortCapability(ctx, log, http.StatusUnauthorized, "capability_expired", "capability token has expired", err)
+			return
+		case errors.Is(err, ErrCapabilityUsed):
+			abortCapability(ctx, log, http.StatusUnauthorized, "capability_used", "capability token has already been used", err)
+			return
+		case errors.Is(err, ErrCapabilityInvalid):
+			abortCapability(ctx, log, http.StatusUnauthorized, "invalid_capability", "capability token is invalid", err)
+			return
+		case err != nil:
+			abortCapability(ctx, log, http.StatusServiceUnavailable, "capability_unavailable", "capability validation is unavailable", err)
+			return
+		}
+
+		ctx.Set(string(ctxCapability), capability)
+		SetClaims(ctx, capability.Claims())
+		ctx.Next()
+	}
+}
+
+// CapabilityFromContext returns the capability verified by RequireCapability.
+func CapabilityFromContext(c *gin.Context) (Capability, bool) {
+	if c == nil {
+		return Capability{}, false
+	}
+	value, ok := c.Get(string(ctxCapability))
+	if !ok {
+		return Capability{}, false
+	}
+	capability, ok := value.(Capability)
+	return capability, ok
+}
+
+func abortCapability(c *gin.Context, log logger.LogManager, status int, code, message string, err error) {
+	if log != nil {
+		if err != nil {
+			log.WarnFCtx(c.Request.Context(), "Capability authentication failed: %s: %v (path=%s)", code, err, c.FullPath())
+		} else {
+			log.WarnFCtx(c.Request.Context(), "Capability authentication failed: %s (path=%s)", code, c.FullPath())
+		}
+	}
+	c.AbortWithStatusJSON(status, gin.H{
+		"error":   code,
+		"message": message,
+	})
+}
+
+func newCapabilityID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("capabilities: generate id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// MemoryReplayGuard tracks redeemed one-time capabilities in process. It suits
+// single-instance services and tests; use RedisReplayGuard when replicas share traffic.
+type MemoryReplayGuard struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+// NewMemoryReplayGuard creates an empty in-process guard.
+func NewMemoryReplayGuard() *MemoryReplayGuard {
+	return &MemoryReplayGuard{used: make(map[string]time.Time)}
+}
+
+// Consume implements CapabilityReplayGuard.
+func (g *MemoryReplayGuard) Consume(_ context.Context, id string, expiresAt time.Time) (bool, error) {
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for usedID, until := range g.used {
+		if now.After(until) {
+			delete(g.used, usedID)
+		}
+	}
+	if _, ok := g.used[id]; ok {
+		return false, nil
+	}
+	g.used[id] = expiresAt
+	return true, nil
+}
+
+// RedisReplayGuard tracks redeemed one-time capabilities in Redis with SETNX,
+// keyed "<prefix>:<id>" until the capability expires.
+type RedisReplayGuard struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisReplayGuard creates a guard. prefix defaults to "auth:capability".
+func NewRedisReplayGuard(client redis.UniversalClient, prefix string) *RedisReplayGuard {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		prefix = "auth:capability"
+	}
+	return &RedisReplayGuard{client: client, prefix: prefix}
+}
+
+// Consume implements CapabilityReplayGuard.
+func (g *RedisReplayGuard) Consume(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return false, nil
+	}
+	return g.client.SetNX(ctx, g.prefix+":"+id, "1", ttl).Result()
+}
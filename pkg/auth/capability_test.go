@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	redis "github.com/redis/go-redis/v9"
+)
+
+func testCapabilities(t *testing.T, cfg CapabilityConfig) *Capabilities {
+	t.Helper()
+	cfg.Secret = []byte(strings.Repeat("c", 32))
+	caps, err := NewCapabilities(cfg)
+	if err != nil {
+		t.Fatalf("NewCapabilities: %v", err)
+	}
+	return caps
+}
+
+func TestCapabilitiesIssueAndVerify(t *testing.T) {
+	ctx := context.Background()
+	checker := &countingRevocationChecker{revoked: map[string]bool{}}
+	caps := testCapabilities(t, CapabilityConfig{MaxTTL: 10 * time.Minute, Revocation: checker})
+	caller := Claims{Subject: "user-1", TokenUse: "access", ServicePermissions: map[string][]int64{"RPT": {7}}, Raw: map[string]any{
+		"tenant_id": "tenant-a",
+		"jti":       "access-1",
+	}}
+
+	token, issued, err := caps.Issue(caller, "reports:download", "report-9", WithCapabilityTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if got := issued.ExpiresAt.Sub(issued.IssuedAt); got != 10*time.Minute {
+		t.Fatalf("ttl = %v, want capped at 10m", got)
+	}
+
+	verified, err := caps.Verify(ctx, token, "reports:download", "report-9")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if verified.TenantID != "tenant-a" || verified.ParentID != "access-1" || verified.Subject != "user-1" {
+		t.Fatalf("verified = %+v", verified)
+	}
+	claims := verified.Claims()
+	if claims.TokenUse != TokenUseCapability || claims.TenantID() != "tenant-a" || len(claims.ServicePermissions) != 0 {
+		t.Fatalf("claims = %+v", claims)
+	}
+
+	if _, err := caps.Verify(ctx, token, "reports:download", "report-10"); !errors.Is(err, ErrCapabilityScope) {
+		t.Fatalf("other resource err = %v, want ErrCapabilityScope", err)
+	}
+	if _, err := caps.Verify(ctx, token, "reports:delete", "report-9"); !errors.Is(err, ErrCapabilityScope) {
+		t.Fatalf("other action err = %v, want ErrCapabilityScope", err)
+	}
+	if _, err := caps.Verify(ctx, token+"x", "reports:download", "report-9"); !errors.Is(err, ErrCapabilityInvalid) {
+		t.Fatalf("tampered err = %v, want ErrCapabilityInvalid", err)
+	}
+	if _, _, err := caps.Issue(claims, "reports:download", "report-9"); err == nil {
+		t.Fatal("Issue derived a capability from a capability")
+	}
+
+	caps.now = func() time.Time { return time.Now().Add(11 * time.Minute) }
+	if _, err := caps.Verify(ctx, token, "reports:download", "report-9"); !errors.Is(err, ErrCapabilityExpired) {
+		t.Fatalf("expired err = %v, want ErrCapabilityExpired", err)
+	}
+	caps.now = time.Now
+
+	// Revoking the access token revokes the capabilities derived from it.
+	checker.revoked["access-1"] = true
+	if _, err := caps.Verify(ctx, token, "reports:download", "report-9"); !errors.Is(err, ErrCapabilityInvalid) {
+		t.Fatalf("revoked parent err = %v, want ErrCapabilityInvalid", err)
+	}
+}
+
+func TestRequireCapabilityOneTimeUse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := miniredis.RunT(t)
+	guard := NewRedisReplayGuard(redis.NewClient(&redis.Options{Addr: server.Addr()}), "")
+	caps := testCapabilities(t, CapabilityConfig{ReplayGuard: guard})
+
+	router := gin.New()
+	router.GET("/reports/:id/download", caps.RequireCapability("reports:download", CapabilityParam("id")), func(c *gin.Context) {
+		capability, _ := CapabilityFromContext(c)
+		claims, _ := GetClaims(c)
+		c.String(http.StatusOK, capability.Resource+"|"+claims.UserID())
+	})
+
+	token, _, err := caps.Issue(Claims{Subject: "user-1"}, "reports:download", "report-9", WithOneTimeUse())
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	link, err := CapabilityURL("/reports/report-9/download?format=csv", token)
+	if err != nil {
+		t.Fatalf("CapabilityURL: %v", err)
+	}
+
+	do := func(target, header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if header != "" {
+			req.Header.Set(DefaultCapabilityHeader, header)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+	errorCode := func(recorder *httptest.ResponseRecorder) string {
+		var body map[string]string
+		_ = json.Unmarshal(recorder.Body.Bytes(), &body)
+		return body["error"]
+	}
+
+	if recorder := do("/reports/report-9/download", ""); recorder.Code != http.StatusUnauthorized || errorCode(recorder) != "capability_required" {
+		t.Fatalf("missing token = %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := do("/reports/report-8/download", token); recorder.Code != http.StatusForbidden || errorCode(recorder) != "capability_scope_mismatch" {
+		t.Fatalf("wrong resource = %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := do(link, ""); recorder.Code != http.StatusOK || recorder.Body.String() != "report-9|user-1" {
+		t.Fatalf("first use = %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := do(link, ""); recorder.Code != http.StatusUnauthorized || errorCode(recorder) != "capability_used" {
+		t.Fatalf("replay = %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	memory := NewMemoryReplayGuard()
+	expires := time.Now().Add(time.Minute)
+	if first, _ := memory.Consume(context.Background(), "id", expires); !first {
+		t.Fatal("memory guard rejected first use")
+	}
+	if again, _ := memory.Consume(context.Background(), "id", expires); again {
+		t.Fatal("memory guard accepted replay")
+	}
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -12,7 +13,11 @@ type Claims struct {
 	RoleID             string
 	TokenUse           string
 	ServicePermissions map[string][]int64 // Multiple ranges per service: [range0, range1, range2, ...]
-	Raw                map[string]any
+	// TenantPermissions holds grants scoped to a single tenant, keyed by tenant ID
+	// then service. They come from tenant-prefixed svc_perm segments
+	// ("<tenant>/<service>:<ranges>").
+	TenantPermissions map[string]map[string][]int64
+	Raw               map[string]any
 }
 
 // IsServiceToken reports whether the token represents a service credential.
@@ -20,9 +25,41 @@ func (c Claims) IsServiceToken() bool {
 	return strings.EqualFold(strings.TrimSpace(c.TokenUse), "service")
 }
 
-// TenantID returns the tenant_id from the token claims, if present.
+// ScopeCrossTenant is the token scope that lets a service token act in any
+// tenant a request names, e.g. through the X-Tenant-ID header. Service tokens
+// without it only act in the tenants they hold grants in (CanAccessTenant).
+const ScopeCrossTenant = "tenant:cross"
+
+// IsCrossTenant reports whether the claims are a service token carrying
+// ScopeCrossTenant.
+func (c Claims) IsCrossTenant() bool {
+	return c.IsServiceToken() && c.HasScope(ScopeCrossTenant)
+}
+
+// serviceCanActInTenant reports whether a service token may act in tenantID:
+// any tenant with ScopeCrossTenant, otherwise one it holds grants in.
+func (c Claims) serviceCanActInTenant(tenantID string) bool {
+	return c.IsCrossTenant() || c.CanAccessTenant(tenantID)
+}
+
+// IsAPIKey reports whether the claims were produced by APIKeyAuth.
+func (c Claims) IsAPIKey() bool {
+	return strings.EqualFold(strings.TrimSpace(c.TokenUse), TokenUseAPIKey)
+}
+
+// IsClientCertificate reports whether the claims were produced by
+// ClientCertAuth from a verified client certificate.
+func (c Claims) IsClientCertificate() bool {
+	return c.ClaimString("authentication_means") == AuthenticationMeansMTLS
+}
+
+// TenantID returns the tenant_id from the token claims, if present, falling
+// back to org_id for issuers that name tenants organizations.
 func (c Claims) TenantID() string {
-	return c.ClaimString("tenant_id")
+	if tenantID := c.ClaimString("tenant_id"); tenantID != "" {
+		return tenantID
+	}
+	return c.ClaimString("org_id")
 }
 
 // TenantStatus returns the tenant_status from the token claims, if present.
@@ -89,11 +126,61 @@ func (c Claims) ClaimString(key string) string {
 // HasPermission evaluates whether the caller holds the permission for the given service.
 // bitValue is a sequential position (0, 1, 2, 3, ...) that gets mapped to a range and position within that range.
 func (c Claims) HasPermission(service string, bitValue int64) bool {
-	if bitValue < 0 {
+	return hasPermissionBit(c.ServicePermissions[strings.ToLower(strings.TrimSpace(service))], bitValue)
+}
+
+// HasPermissionInTenant evaluates whether the caller holds the permission
+// within tenantID. Grants scoped to tenantID count, as do the unscoped grants
+// when tenantID is the caller's home tenant (TenantID).
+func (c Claims) HasPermissionInTenant(tenantID, service string, bitValue int64) bool {
+	tenantID = strings.TrimSpace(tenantID)
+	if tenantID == "" {
 		return false
 	}
-	ranges := c.ServicePermissions[strings.ToLower(strings.TrimSpace(service))]
-	if len(ranges) == 0 {
+	service = strings.ToLower(strings.TrimSpace(service))
+	if hasPermissionBit(c.TenantPermissions[tenantID][service], bitValue) {
+		return true
+	}
+	return tenantID == c.TenantID() && c.HasPermission(service, bitValue)
+}
+
+// AccessibleTenants lists, sorted, the tenants the caller holds any grant in:
+// the home tenant plus every tenant with scoped permissions.
+func (c Claims) AccessibleTenants() []string {
+	seen := make(map[string]bool, len(c.TenantPermissions)+1)
+	if tenantID := c.TenantID(); tenantID != "" {
+		seen[tenantID] = true
+	}
+	for tenantID := range c.TenantPermissions {
+		seen[tenantID] = true
+	}
+	return sortedKeys(seen)
+}
+
+// CanAccessTenant reports whether tenantID is one of AccessibleTenants.
+func (c Claims) CanAccessTenant(tenantID string) bool {
+	tenantID = strings.TrimSpace(tenantID)
+	if tenantID == "" {
+		return false
+	}
+	_, scoped := c.TenantPermissions[tenantID]
+	return scoped || tenantID == c.TenantID()
+}
+
+// TenantsWithPermission lists, sorted, the tenants in which the caller holds
+// the permission.
+func (c Claims) TenantsWithPermission(service string, bitValue int64) []string {
+	seen := make(map[string]bool)
+	for _, tenantID := range c.AccessibleTenants() {
+		if c.HasPermissionInTenant(tenantID, service, bitValue) {
+			seen[tenantID] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+func hasPermissionBit(ranges []int64, bitValue int64) bool {
+	if bitValue < 0 || len(ranges) == 0 {
 		return false
 	}
 
@@ -109,3 +196,12 @@ func (c Claims) HasPermission(service string, bitValue int64) bool {
 
 	return false
 }
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+// AuthenticationMeansMTLS is the authentication_means ClientCertAuth records
+// on the claims of every certificate it accepts.
+const AuthenticationMeansMTLS = "mtls"
+
+// ClientCertConfig configures ClientCertAuth.
+type ClientCertConfig struct {
+	// Optional lets requests without a verified client certificate continue
+	// unauthenticated so a later middleware (e.g. RequireAuthenticated) can run.
+	Optional bool
+	// ClaimsFromCertificate maps the verified leaf certificate to claims.
+	// Defaults to ClaimsFromCertificate.
+	ClaimsFromCertificate func(*x509.Certificate) (Claims, error)
+	Logger                logger.LogManager
+}
+
+// ClientCertAuth authenticates callers by the client certificate verified during
+// the mTLS handshake (see server.StartWithMTLS) and injects the resulting Claims,
+// for service-to-service calls that cannot use bearer tokens.
+//
+// Only certificates in the connection's verified chains are trusted; the server
+// must be started with a client CA for this middleware to authenticate anyone.
+//
+// Certificate claims are service claims, but they skip permission checks
+// under BypassServiceTokenPermissions only when their subject is listed in
+// the authorizer's BypassCertificateSubjects: a CA typically issues far more
+// certificates than there are fully trusted services.
+func ClientCertAuth(cfg ClientCertConfig) gin.HandlerFunc {
+	mapClaims := cfg.ClaimsFromCertificate
+	if mapClaims == nil {
+		mapClaims = ClaimsFromCertificate
+	}
+
+	return func(c *gin.Context) {
+		if _, ok := GetClaims(c); ok {
+			c.Next()
+			return
+		}
+
+		log := logger.GetLogger(c)
+		if log == nil {
+			log = cfg.Logger
+		}
+
+		cert := verifiedClientCertificate(c.Request)
+		if cert == nil {
+			if cfg.Optional {
+				c.Next()
+				return
+			}
+			abortClientCert(c, log, "client_certificate_required", "verified client certificate required", nil)
+			return
+		}
+
+		claims, err := mapClaims(cert)
+		if err != nil {
+			abortClientCert(c, log, "invalid_client_certificate", "client certificate is not accepted", err)
+			return
+		}
+		// Custom mappings cannot drop the marker the authorizer keys the
+		// bypass allow-list on.
+		if claims.Raw == nil {
+			claims.Raw = make(map[string]any)
+		}
+		claims.Raw["authentication_means"] = AuthenticationMeansMTLS
+
+		SetClaims(c, claims)
+		c.Next()
+	}
+}
+
+// ClaimsFromCertificate is the default certificate → claims mapping. The subject
+// is the first URI SAN (typically a SPIFFE ID), falling back to the first DNS SAN
+// and then the subject common name. Claims are issued as service tokens with
+// service_id set to the common name, or the subject when no CN is present.
+func ClaimsFromCertificate(cert *x509.Certificate) (Claims, error) {
+	subject := certificateSubject(cert)
+	if subject == "" {
+		return Claims{}, errCertificateWithoutIdentity
+	}
+
+	serviceID := strings.TrimSpace(cert.Subject.CommonName)
+	if serviceID == "" {
+		serviceID = subject
+	}
+
+	raw := map[string]any{
+		"sub":                  subject,
+		"token_use":            "service",
+		"service_id":           serviceID,
+		"cert_serial":          cert.SerialNumber.String(),
+		"cert_issuer":          cert.Issuer.String(),
+		"cert_subject":         cert.Subject.String(),
+		"cert_not_after":       cert.NotAfter.UTC(),
+		"authentication_means": AuthenticationMeansMTLS,
+	}
+	if len(cert.DNSNames) > 0 {
+		raw["cert_dns_names"] = append([]string(nil), cert.DNSNames...)
+	}
+	if len(cert.URIs) > 0 {
+		uris := make([]string, 0, len(cert.URIs))
+		for _, uri := range cert.URIs {
+			uris = append(uris, uri.String())
+		}
+		raw["cert_uris"] = uris
+	}
+
+	return Claims{
+		Subject:  subject,
+		TokenUse: "service",
+		Raw:      raw,
+	}, nil
+}
+
+var errCertificateWithoutIdentity = errors.New("client certificate carries no URI SAN, DNS SAN, or common name")
+
+func certificateSubject(cert *x509.Certificate) string {
+	for _, uri := range cert.URIs {
+		if value := strings.TrimSpace(uri.String()); value != "" {
+			return value
+		}
+	}
+	for _, name := range cert.DNSNames {
+		if value := strings.TrimSpace(name); value != "" {
+			return value
+		}
+	}
+	return strings.TrimSpace(cert.Subject.CommonName)
+}
+
+func verifiedClientCertificate(r *http.Request) *x509.Certificate {
+	if r == nil || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+func abortClientCert(c *gin.Context, log logger.LogManager, code, message string, err error) {
+	if log != nil {
+		if err != nil {
+			log.WarnFCtx(c.Request.Context(), "Client certificate authentication failed: %s: %v (path=%s)", code, err, c.FullPath())
+		} else {
+			log.WarnFCtx(c.Request.Context(), "Client certificate authentication failed: %s (path=%s)", code, c.FullPath())
+		}
+	}
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+		"error":   code,
+		"message": message,
+	})
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+func TestClientCertAuthInjectsServiceClaims(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	spiffeID, _ := url.Parse("spiffe://platform.internal/ns/billing/sa/api")
+	cert := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "billing-api"},
+		URIs:         []*url.URL{spiffeID},
+	}
+
+	router := gin.New()
+	router.GET("/internal/sync", ClientCertAuth(ClientCertConfig{}), func(c *gin.Context) {
+		claims, _ := ClaimsFromContext(c.Request.Context())
+		serviceID, _ := ServiceIDFromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"sub": claims.Subject, "service": claims.IsServiceToken(), "service_id": serviceID})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/internal/sync", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	want := `{"service":true,"service_id":"billing-api","sub":"spiffe://platform.internal/ns/billing/sa/api"}`
+	if recorder.Code != http.StatusOK || recorder.Body.String() != want {
+		t.Fatalf("status = %d body = %s, want 200 %s", recorder.Code, recorder.Body.String(), want)
+	}
+}
+
+func TestClientCertAuthRejectsUnverifiedPeer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/internal/sync", ClientCertAuth(ClientCertConfig{}), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	// Presented but unverified certificates must not authenticate.
+	req := httptest.NewRequest(http.MethodGet, "/internal/sync", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "spoofed"},
+	}}}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestClientCertClaimsBypassPermissionsOnlyWhenAllowListed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	lookup := stubPermissionLookup{"ORD-READ": {Service: "ord", BitValue: 0}}
+	certificate := func(cn string) *x509.Certificate {
+		return &x509.Certificate{SerialNumber: big.NewInt(7), Subject: pkix.Name{CommonName: cn}}
+	}
+	authorizer := &Authorizer{
+		log:                           logger.MustNewDefaultLogger(),
+		bypassServiceTokenPermissions: true,
+		bypassCertificateSubjects:     parseBypassCertificateSubjects(stubConfig{"BypassCertificateSubjects": "billing-api, ledger"}),
+	}
+
+	cases := []struct {
+		name   string
+		cert   *x509.Certificate
+		custom func(*x509.Certificate) (Claims, error)
+		want   int
+	}{
+		{"allow-listed subject", certificate("billing-api"), nil, http.StatusNoContent},
+		{"other subject", certificate("reporting"), nil, http.StatusForbidden},
+		{"custom mapping without marker", certificate("reporting"), func(cert *x509.Certificate) (Claims, error) {
+			return Claims{Subject: cert.Subject.CommonName, TokenUse: "service"}, nil
+		}, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/orders", func(c *gin.Context) {
+				c.Set(string(CtxMiddlewareServiceKey), PermissionLookup(lookup))
+			}, ClientCertAuth(ClientCertConfig{ClaimsFromCertificate: tc.custom}), authorizer.RequirePermission("ORD-READ"), func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tc.cert}}}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			if recorder.Code != tc.want {
+				t.Fatalf("status = %d, want %d; body=%s", recorder.Code, tc.want, recorder.Body.String())
+			}
+		})
+	}
+}
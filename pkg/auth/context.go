@@ -2,20 +2,40 @@ package auth
 
 import (
 	"context"
+	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	httplib "github.com/milan604/core-lab/pkg/http"
 	coretenant "github.com/milan604/core-lab/pkg/tenant"
 )
 
-// GetClaims retrieves the verified Claims from the request context.
+// GetClaims retrieves the verified Claims from the request context. It checks
+// the gin.Context keys first and falls back to the request's context.Context, so
+// claims stored by non-Gin middleware are visible too.
 func GetClaims(c *gin.Context) (Claims, bool) {
-	val, exists := c.Get(string(CtxAuthClaims))
-	if !exists {
+	if c == nil {
 		return Claims{}, false
 	}
-	claims, ok := val.(Claims)
-	return claims, ok
+	if val, exists := c.Get(string(CtxAuthClaims)); exists {
+		if claims, ok := val.(Claims); ok {
+			return claims, true
+		}
+	}
+	if c.Request == nil {
+		return Claims{}, false
+	}
+	return ClaimsFromContext(c.Request.Context())
+}
+
+// SetClaims stores verified claims on both the gin.Context and the request's
+// context.Context, so handlers can use GetClaims while service and repository
+// layers that only receive a context.Context can use ClaimsFromContext.
+func SetClaims(c *gin.Context, claims Claims) {
+	c.Set(string(CtxAuthClaims), claims)
+	if c.Request != nil {
+		c.Request = c.Request.WithContext(ContextWithClaims(c.Request.Context(), claims))
+	}
 }
 
 // ClaimsFromContext retrieves verified claims from a standard context.Context.
@@ -187,3 +207,38 @@ func IsSuperAdminFromContext(ctx context.Context) bool {
 	requestContext, ok := coretenant.RequestContextFromContext(ctx)
 	return ok && requestContext.IsSuperAdmin
 }
+
+// Headers set by IdentityHeadersHook on outgoing requests.
+const (
+	HeaderTenantID    = "X-Tenant-ID"
+	HeaderActorUserID = "X-Actor-User-ID"
+	HeaderServiceID   = "X-Service-ID"
+)
+
+// IdentityHeadersHook returns a pkg/http request hook that forwards the caller
+// identity found in the outgoing request's context (see ContextWithClaims) as
+// X-Tenant-ID, X-Actor-User-ID and X-Service-ID headers. Headers already set on
+// the request are left untouched. Build requests with http.NewRequestWithContext
+// so the hook sees the caller's context:
+//
+//	client := httplib.NewClient(httplib.WithRequestHook(auth.IdentityHeadersHook()))
+//
+// The headers are informational; downstream services must still authenticate
+// the bearer token before trusting them.
+func IdentityHeadersHook() httplib.RequestHook {
+	return func(req *http.Request) error {
+		ctx := req.Context()
+		setIfEmpty := func(header, value string, ok bool) {
+			if ok && req.Header.Get(header) == "" {
+				req.Header.Set(header, value)
+			}
+		}
+		tenantID, ok := TenantIDFromContext(ctx)
+		setIfEmpty(HeaderTenantID, tenantID, ok)
+		userID, ok := UserIDFromContext(ctx)
+		setIfEmpty(HeaderActorUserID, userID, ok)
+		serviceID, ok := ServiceIDFromContext(ctx)
+		setIfEmpty(HeaderServiceID, serviceID, ok)
+		return nil
+	}
+}
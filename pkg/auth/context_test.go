@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetClaimsFallsBackToRequestContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	claims := Claims{Subject: "user-1", Raw: map[string]any{"tenant_id": "tenant-1"}}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request = c.Request.WithContext(ContextWithClaims(c.Request.Context(), claims))
+
+	got, ok := GetClaims(c)
+	if !ok || got.Subject != "user-1" || got.TenantID() != "tenant-1" {
+		t.Fatalf("GetClaims() = %+v, %v; want claims from request context", got, ok)
+	}
+}
+
+func TestSetClaimsPopulatesGinAndRequestContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	SetClaims(c, Claims{Subject: "user-1", Raw: map[string]any{"tenant_id": "tenant-1"}})
+
+	if val, ok := c.Get(string(CtxAuthClaims)); !ok || val.(Claims).Subject != "user-1" {
+		t.Fatalf("gin context claims = %v, %v", val, ok)
+	}
+	claims, ok := ClaimsFromContext(c.Request.Context())
+	if !ok || claims.Subject != "user-1" {
+		t.Fatalf("ClaimsFromContext() = %+v, %v", claims, ok)
+	}
+	if tenantID, _ := TenantIDFromContext(c.Request.Context()); tenantID != "tenant-1" {
+		t.Fatalf("TenantIDFromContext() = %q, want tenant-1", tenantID)
+	}
+}
+
+func TestIdentityHeadersHook(t *testing.T) {
+	ctx := ContextWithClaims(context.Background(), Claims{
+		Subject: "user-1",
+		Raw:     map[string]any{"tenant_id": "tenant-1"},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	req.Header.Set(HeaderTenantID, "explicit")
+
+	if err := IdentityHeadersHook()(req); err != nil {
+		t.Fatalf("hook: %v", err)
+	}
+	if got := req.Header.Get(HeaderTenantID); got != "explicit" {
+		t.Fatalf("%s = %q, want existing header kept", HeaderTenantID, got)
+	}
+	if got := req.Header.Get(HeaderActorUserID); got != "user-1" {
+		t.Fatalf("%s = %q, want user-1", HeaderActorUserID, got)
+	}
+	if got := req.Header.Get(HeaderServiceID); got != "" {
+		t.Fatalf("%s = %q, want empty", HeaderServiceID, got)
+	}
+}
@@ -2,10 +2,12 @@ package auth
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"net/http"
@@ -29,18 +31,36 @@ type remoteKeyProvider struct {
 	fallbackJWKSURL string
 	client          *http.Client
 	cacheTTL        time.Duration
+	// resolver, when set, owns JWKS URL resolution (see JWTConfig).
+	resolver jwksURLResolver
 
 	mu            sync.RWMutex
 	cachedJWKSURL string
 	cachedKeys    *cachedKeySet
 }
 
+// jwksURLResolver is implemented by configs that resolve the JWKS URL
+// dynamically, such as JWTConfig.
+type jwksURLResolver interface {
+	resolveJWKSURL(force bool) (string, error)
+}
+
 type cachedKeySet struct {
-	keysByID  map[string]interface{}
-	allKeys   []interface{}
+	keys      []cachedKey
 	expiresAt time.Time
 }
 
+// cachedKey is a parsed JWK with the identifiers used for key selection.
+type cachedKey struct {
+	kid string
+	alg string
+	key interface{}
+}
+
+// errUnsupportedJWK marks keys this package cannot verify with (e.g. "oct" keys
+// or unknown curves). They are skipped instead of invalidating the whole set.
+var errUnsupportedJWK = errors.New("unsupported jwk")
+
 type oidcDiscoveryDocument struct {
 	JWKSURI string `json:"jwks_uri"`
 }
@@ -86,7 +106,7 @@ func newRemoteKeyProvider(cfg Config) *remoteKeyProvider {
 		cacheTTL = defaultJWKSCacheTTL
 	}
 
-	return &remoteKeyProvider{
+	provider := &remoteKeyProvider{
 		discoveryURL:    discoveryURL,
 		explicitJWKSURL: explicitJWKSURL,
 		fallbackJWKSURL: fallbackJWKSURL,
@@ -95,6 +115,10 @@ func newRemoteKeyProvider(cfg Config) *remoteKeyProvider {
 		},
 		cacheTTL: cacheTTL,
 	}
+	if resolver, ok := cfg.(jwksURLResolver); ok {
+		provider.resolver = resolver
+	}
+	return provider
 }
 
 func (p *remoteKeyProvider) LookupKeys(token *jwt.Token) ([]interface{}, error) {
@@ -102,22 +126,24 @@ func (p *remoteKeyProvider) LookupKeys(token *jwt.Token) ([]interface{}, error)
 		return nil, fmt.Errorf("jwks provider is nil")
 	}
 
-	kid := strings.TrimSpace(fmt.Sprint(token.Header["kid"]))
+	kid, _ := token.Header["kid"].(string)
+	kid = strings.TrimSpace(kid)
+	alg := tokenAlgorithm(token)
 	keySet, err := p.loadKeySet(false)
 	if err != nil && keySet == nil {
 		return nil, err
 	}
 
-	keys := keySet.selectKeys(kid)
+	keys := keySet.selectKeys(kid, alg)
 	if len(keys) == 0 && kid != "" {
 		keySet, err = p.loadKeySet(true)
 		if err == nil && keySet != nil {
-			keys = keySet.selectKeys(kid)
+			keys = keySet.selectKeys(kid, alg)
 		}
 	}
 
 	if len(keys) == 0 {
-		return nil, fmt.Errorf("no jwks keys matched kid=%q", kid)
+		return nil, fmt.Errorf("no jwks keys matched kid=%q alg=%q", kid, alg)
 	}
 
 	return keys, nil
@@ -159,6 +185,10 @@ func (p *remoteKeyProvider) resolveJWKSURL(force bool) (string, error) {
 		return "", fmt.Errorf("jwks provider is nil")
 	}
 
+	if p.resolver != nil {
+		return p.resolver.resolveJWKSURL(force)
+	}
+
 	if p.explicitJWKSURL != "" {
 		return p.explicitJWKSURL, nil
 	}
@@ -223,31 +253,33 @@ func (p *remoteKeyProvider) fetchKeySet(jwksURL string) (*cachedKeySet, error) {
 		return nil, fmt.Errorf("decode jwks response: %w", err)
 	}
 
-	keysByID := make(map[string]interface{}, len(payload.Keys))
-	allKeys := make([]interface{}, 0, len(payload.Keys))
+	keys := make([]cachedKey, 0, len(payload.Keys))
 	for _, jwk := range payload.Keys {
 		if use := strings.TrimSpace(jwk.Use); use != "" && !strings.EqualFold(use, "sig") {
 			continue
 		}
 
 		publicKey, err := parseJSONWebKey(jwk)
+		if errors.Is(err, errUnsupportedJWK) {
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
 
-		if kid := strings.TrimSpace(jwk.Kid); kid != "" {
-			keysByID[kid] = publicKey
-		}
-		allKeys = append(allKeys, publicKey)
+		keys = append(keys, cachedKey{
+			kid: strings.TrimSpace(jwk.Kid),
+			alg: strings.TrimSpace(jwk.Alg),
+			key: publicKey,
+		})
 	}
 
-	if len(allKeys) == 0 {
-		return nil, fmt.Errorf("jwks response did not contain any signing keys")
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("jwks response did not contain any supported signing keys")
 	}
 
 	return &cachedKeySet{
-		keysByID:  keysByID,
-		allKeys:   allKeys,
+		keys:      keys,
 		expiresAt: time.Now().Add(p.cacheTTL),
 	}, nil
 }
@@ -270,20 +302,29 @@ func (p *remoteKeyProvider) cachedSnapshot(now time.Time) *cachedKeySet {
 	return p.cachedKeys
 }
 
-func (c *cachedKeySet) selectKeys(kid string) []interface{} {
+// selectKeys returns the keys that can verify a token with the given kid and
+// alg. A kid must match exactly; keys that declare an alg must match it, and
+// every key must be of a type the alg can use.
+func (c *cachedKeySet) selectKeys(kid, alg string) []interface{} {
 	if c == nil {
 		return nil
 	}
 
 	kid = strings.TrimSpace(kid)
-	if kid != "" {
-		if key, ok := c.keysByID[kid]; ok {
-			return []interface{}{key}
+	selected := make([]interface{}, 0, 1)
+	for _, candidate := range c.keys {
+		if kid != "" && candidate.kid != kid {
+			continue
 		}
-		return nil
+		if alg != "" && candidate.alg != "" && !strings.EqualFold(candidate.alg, alg) {
+			continue
+		}
+		if alg != "" && !keySupportsAlgorithm(candidate.key, alg) {
+			continue
+		}
+		selected = append(selected, candidate.key)
 	}
-
-	return append([]interface{}{}, c.allKeys...)
+	return selected
 }
 
 func parseJSONWebKey(jwk jsonWebKey) (interface{}, error) {
@@ -292,8 +333,10 @@ func parseJSONWebKey(jwk jsonWebKey) (interface{}, error) {
 		return parseRSAJSONWebKey(jwk)
 	case "EC":
 		return parseECDSAJSONWebKey(jwk)
+	case "OKP":
+		return parseEd25519JSONWebKey(jwk)
 	default:
-		return nil, fmt.Errorf("unsupported jwk key type %q", jwk.Kty)
+		return nil, fmt.Errorf("%w: key type %q", errUnsupportedJWK, jwk.Kty)
 	}
 }
 
@@ -336,6 +379,9 @@ func parseECDSAJSONWebKey(jwk jsonWebKey) (*ecdsa.PublicKey, error) {
 
 	x := new(big.Int).SetBytes(xBytes)
 	y := new(big.Int).SetBytes(yBytes)
+	if !curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("ecdsa jwk point is not on curve %s", jwk.Crv)
+	}
 
 	return &ecdsa.PublicKey{
 		Curve: curve,
@@ -344,6 +390,22 @@ func parseECDSAJSONWebKey(jwk jsonWebKey) (*ecdsa.PublicKey, error) {
 	}, nil
 }
 
+// parseEd25519JSONWebKey parses an RFC 8037 OKP key. Only Ed25519 can sign JWTs;
+// X25519 and Ed448 keys are reported as unsupported.
+func parseEd25519JSONWebKey(jwk jsonWebKey) (ed25519.PublicKey, error) {
+	if strings.TrimSpace(jwk.Crv) != "Ed25519" {
+		return nil, fmt.Errorf("%w: okp curve %q", errUnsupportedJWK, jwk.Crv)
+	}
+	xBytes, err := decodeBase64URL(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("decode ed25519 public key: %w", err)
+	}
+	if len(xBytes) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 public key length %d", len(xBytes))
+	}
+	return ed25519.PublicKey(xBytes), nil
+}
+
 func parseJWKCurve(curveName string) (elliptic.Curve, error) {
 	switch strings.TrimSpace(curveName) {
 	case "P-256":
@@ -353,7 +415,45 @@ func parseJWKCurve(curveName string) (elliptic.Curve, error) {
 	case "P-521":
 		return elliptic.P521(), nil
 	default:
-		return nil, fmt.Errorf("unsupported jwk curve %q", curveName)
+		return nil, fmt.Errorf("%w: curve %q", errUnsupportedJWK, curveName)
+	}
+}
+
+// tokenAlgorithm returns the token's "alg" header.
+func tokenAlgorithm(token *jwt.Token) string {
+	if token == nil {
+		return ""
+	}
+	if token.Method != nil {
+		return token.Method.Alg()
+	}
+	alg, _ := token.Header["alg"].(string)
+	return strings.TrimSpace(alg)
+}
+
+// keySupportsAlgorithm reports whether key has the type alg verifies with, so
+// for example an HMAC token is never checked against a public key.
+func keySupportsAlgorithm(key interface{}, alg string) bool {
+	alg = strings.ToUpper(strings.TrimSpace(alg))
+	switch typed := key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		switch alg {
+		case "ES256":
+			return typed.Curve == elliptic.P256()
+		case "ES384":
+			return typed.Curve == elliptic.P384()
+		case "ES512":
+			return typed.Curve == elliptic.P521()
+		}
+		return false
+	case ed25519.PublicKey:
+		return alg == "EDDSA"
+	case []byte:
+		return strings.HasPrefix(alg, "HS")
+	default:
+		return false
 	}
 }
 
@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -146,3 +149,116 @@ func responseWithStatus(status int, body string) *http.Response {
 		Body:       io.NopCloser(strings.NewReader(body)),
 	}
 }
+
+func TestAuthorizerVerifiesES256AndEdDSAFromJWKS(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+	}
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() error = %v", err)
+	}
+
+	jwksPayload, _ := json.Marshal(map[string]any{
+		"keys": []map[string]any{
+			{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"}, // unsupported, must be skipped
+			{"kty": "OKP", "kid": "x25519", "crv": "X25519", "x": base64.RawURLEncoding.EncodeToString(make([]byte, 32))},
+			{
+				"kty": "EC", "kid": "ec-1", "alg": "ES256", "crv": "P-256",
+				"x": base64.RawURLEncoding.EncodeToString(ecKey.PublicKey.X.FillBytes(make([]byte, 32))),
+				"y": base64.RawURLEncoding.EncodeToString(ecKey.PublicKey.Y.FillBytes(make([]byte, 32))),
+			},
+			{"kty": "OKP", "kid": "ed-1", "alg": "EdDSA", "crv": "Ed25519", "x": base64.RawURLEncoding.EncodeToString(edPublic)},
+		},
+	})
+
+	authorizer, err := NewAuthorizer(stubConfig{"SentinelJWKSURL": "http://idp.test/jwks.json"}, logger.MustNewDefaultLogger())
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error = %v", err)
+	}
+	authorizer.verifier.remote.client.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return responseWithStatus(http.StatusOK, string(jwksPayload)), nil
+	})
+
+	sign := func(method jwt.SigningMethod, kid string, key any) string {
+		token := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "user-" + kid})
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("SignedString(%s) error = %v", kid, err)
+		}
+		return signed
+	}
+
+	for kid, token := range map[string]string{
+		"ec-1": sign(jwt.SigningMethodES256, "ec-1", ecKey),
+		"ed-1": sign(jwt.SigningMethodEdDSA, "ed-1", edPrivate),
+	} {
+		claims, err := authorizer.verifier.Verify(token)
+		if err != nil {
+			t.Fatalf("Verify(%s) error = %v", kid, err)
+		}
+		if claims.Subject != "user-"+kid {
+			t.Fatalf("subject = %q, want user-%s", claims.Subject, kid)
+		}
+	}
+
+	// A token claiming a different alg than the JWK declares is not matched.
+	mismatched := sign(jwt.SigningMethodES384, "ec-1", mustECKey(t, elliptic.P384()))
+	if _, err := authorizer.verifier.Verify(mismatched); err == nil || !strings.Contains(err.Error(), "no jwks keys matched") {
+		t.Fatalf("Verify(ES384 with ES256 kid) error = %v, want key selection failure", err)
+	}
+}
+
+func TestAuthorizerSharedSecretHS256(t *testing.T) {
+	secret := strings.Repeat("s", 32)
+	privateKey, publicKeyPEM := testKeyPair(t)
+
+	authorizer, err := NewAuthorizer(stubConfig{"JWTSharedSecret": secret, "RSAPublicKey": publicKeyPEM}, logger.MustNewDefaultLogger())
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error = %v", err)
+	}
+
+	hsToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "tooling"}).SignedString([]byte(secret))
+	if claims, err := authorizer.verifier.Verify(hsToken); err != nil || claims.Subject != "tooling" {
+		t.Fatalf("Verify(HS256) claims = %#v err = %v", claims, err)
+	}
+
+	rsToken, _ := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "user"}).SignedString(privateKey)
+	if _, err := authorizer.verifier.Verify(rsToken); err != nil {
+		t.Fatalf("Verify(RS256) error = %v", err)
+	}
+
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "attacker"}).SignedString([]byte("another-secret-that-is-32-bytes!"))
+	if _, err := authorizer.verifier.Verify(forged); err == nil {
+		t.Fatal("Verify() accepted an HS256 token signed with another secret")
+	}
+}
+
+func TestAuthorizerRejectsHS256WithoutSharedSecret(t *testing.T) {
+	_, publicKeyPEM := testKeyPair(t)
+	authorizer, err := NewAuthorizer(stubConfig{"RSAPublicKey": publicKeyPEM}, logger.MustNewDefaultLogger())
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error = %v", err)
+	}
+
+	// Classic alg confusion: HMAC signed with the public key bytes.
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "attacker"}).SignedString([]byte(publicKeyPEM))
+	if _, err := authorizer.verifier.Verify(token); err == nil {
+		t.Fatal("Verify() accepted an HS256 token without a shared secret")
+	}
+
+	if _, err := NewAuthorizer(stubConfig{"JWTSharedSecret": "short"}, logger.MustNewDefaultLogger()); err == nil {
+		t.Fatal("NewAuthorizer() accepted a short JWTSharedSecret")
+	}
+}
+
+func mustECKey(t *testing.T, curve elliptic.Curve) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+	}
+	return key
+}
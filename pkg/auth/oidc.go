@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/milan604/core-lab/pkg/controlplane"
+)
+
+const (
+	defaultOIDCRefreshInterval    = time.Hour
+	defaultOIDCMinRefreshInterval = time.Minute
+	oidcDiscoveryPath             = "/.well-known/openid-configuration"
+)
+
+// OIDCProviderMetadata is the subset of an OpenID Provider's discovery document
+// used by services.
+type OIDCProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	JWKSURI               string `json:"jwks_uri"`
+	AuthorizationEndpoint string `json:"authorization_endpoint,omitempty"`
+	TokenEndpoint         string `json:"token_endpoint,omitempty"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint,omitempty"`
+	EndSessionEndpoint    string `json:"end_session_endpoint,omitempty"`
+}
+
+// OIDCOption customizes NewJWTConfigFromOIDC.
+type OIDCOption func(*JWTConfig)
+
+// WithOIDCHTTPClient sets the client used to fetch the discovery document.
+func WithOIDCHTTPClient(client *http.Client) OIDCOption {
+	return func(c *JWTConfig) {
+		if client != nil {
+			c.client = client
+		}
+	}
+}
+
+// WithOIDCRefreshInterval sets how long a fetched discovery document is reused
+// before it is fetched again. Defaults to one hour.
+func WithOIDCRefreshInterval(interval time.Duration) OIDCOption {
+	return func(c *JWTConfig) {
+		if interval > 0 {
+			c.refreshInterval = interval
+		}
+	}
+}
+
+// WithOIDCAudience sets the audiences tokens must carry, overriding the
+// PlatformTokenAudience value of the base config.
+func WithOIDCAudience(audiences ...string) OIDCOption {
+	return func(c *JWTConfig) {
+		c.audience = strings.Join(audiences, ",")
+	}
+}
+
+// WithOIDCBaseConfig supplies the remaining authorizer settings (audience, JWKS
+// cache TTL, BypassServiceTokenPermissions, RSAPublicKey fallback, ...). Keys
+// resolved from discovery always take precedence.
+func WithOIDCBaseConfig(cfg Config) OIDCOption {
+	return func(c *JWTConfig) {
+		c.base = cfg
+	}
+}
+
+// JWTConfig is an authorizer Config whose issuer and JWKS URL come from an
+// OpenID Provider's discovery document instead of manual configuration.
+//
+//	jwtCfg, err := auth.NewJWTConfigFromOIDC(ctx, "https://login.example.com/realms/acme",
+//		auth.WithOIDCBaseConfig(cfg),
+//	)
+//	authorizer, err := auth.NewAuthorizer(jwtCfg, log)
+//
+// The document is cached and refetched in the background after the refresh
+// interval, and early when a token names a key ID missing from the current
+// JWKS. Requests are served the cached copy while a refresh runs, and a failed
+// refresh keeps serving the last good document.
+type JWTConfig struct {
+	issuerURL       string
+	discoveryURL    string
+	client          *http.Client
+	refreshInterval time.Duration
+	audience        string
+	base            Config
+
+	mu          sync.RWMutex
+	metadata    OIDCProviderMetadata
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	refreshes   singleflight.Group
+}
+
+// NewJWTConfigFromOIDC fetches issuerURL's /.well-known/openid-configuration and
+// returns a JWTConfig for NewAuthorizer. It fails when the document cannot be
+// fetched, lacks a jwks_uri, or names a different issuer.
+func NewJWTConfigFromOIDC(ctx context.Context, issuerURL string, opts ...OIDCOption) (*JWTConfig, error) {
+	issuerURL = strings.TrimRight(strings.TrimSpace(issuerURL), "/")
+	if issuerURL == "" {
+		return nil, fmt.Errorf("oidc: issuer url is required")
+	}
+
+	cfg := &JWTConfig{
+		issuerURL:       issuerURL,
+		discoveryURL:    issuerURL + oidcDiscoveryPath,
+		client:          &http.Client{Timeout: defaultJWKSHTTPTimeout},
+		refreshInterval: defaultOIDCRefreshInterval,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cfg)
+		}
+	}
+
+	if _, err := cfg.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// GetString implements Config, answering issuer and JWKS keys from discovery
+// and delegating everything else to the base config.
+func (c *JWTConfig) GetString(key string) string {
+	switch key {
+	case controlplane.KeyTokenIssuer, controlplane.LegacyKeyTokenIssuer:
+		return c.Metadata().Issuer
+	case controlplane.KeyJWKSURL, controlplane.LegacyKeyJWKSURL:
+		return c.Metadata().JWKSURI
+	case controlplane.KeyOIDCDiscoveryURL, controlplane.LegacyKeyOIDCDiscoveryURL:
+		return c.discoveryURL
+	case controlplane.KeyTokenAudience, controlplane.LegacyKeyTokenAudience:
+		if c.audience != "" {
+			return c.audience
+		}
+	}
+	if c.base == nil {
+		return ""
+	}
+	return c.base.GetString(key)
+}
+
+// Metadata returns the cached discovery document without waiting on the
+// network. When it is older than the refresh interval, one background refresh
+// starts and callers keep getting the cached copy until it lands. Failed
+// refreshes are retried at most once per minute.
+func (c *JWTConfig) Metadata() OIDCProviderMetadata {
+	c.mu.RLock()
+	metadata, fetchedAt, lastAttempt := c.metadata, c.fetchedAt, c.lastAttempt
+	c.mu.RUnlock()
+
+	if time.Since(fetchedAt) >= c.refreshInterval && time.Since(lastAttempt) >= defaultOIDCMinRefreshInterval {
+		c.refreshes.DoChan(c.discoveryURL, c.refreshShared)
+	}
+	return metadata
+}
+
+// refreshShared is Refresh for callers coalesced through c.refreshes; it
+// outlives any one request.
+func (c *JWTConfig) refreshShared() (interface{}, error) {
+	return c.Refresh(context.Background())
+}
+
+// Refresh fetches the discovery document now, replacing the cached copy on success.
+func (c *JWTConfig) Refresh(ctx context.Context) (OIDCProviderMetadata, error) {
+	c.mu.Lock()
+	c.lastAttempt = time.Now()
+	c.mu.Unlock()
+
+	metadata, err := c.fetch(ctx)
+	if err != nil {
+		return OIDCProviderMetadata{}, err
+	}
+
+	c.mu.Lock()
+	c.metadata = metadata
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+	return metadata, nil
+}
+
+// Issuer returns the issuer tokens are validated against.
+func (c *JWTConfig) Issuer() string { return c.Metadata().Issuer }
+
+// JWKSURL returns the provider's jwks_uri.
+func (c *JWTConfig) JWKSURL() string { return c.Metadata().JWKSURI }
+
+// TokenEndpoint returns the provider's token endpoint, if advertised.
+func (c *JWTConfig) TokenEndpoint() string { return c.Metadata().TokenEndpoint }
+
+// EndSessionEndpoint returns the provider's RP-initiated logout endpoint, if advertised.
+func (c *JWTConfig) EndSessionEndpoint() string { return c.Metadata().EndSessionEndpoint }
+
+// resolveJWKSURL lets the JWKS key provider follow jwks_uri changes. A forced
+// lookup (unknown kid) refetches the document at most once per minute so bogus
+// tokens cannot hammer the provider.
+func (c *JWTConfig) resolveJWKSURL(force bool) (string, error) {
+	c.mu.RLock()
+	lastAttempt := c.lastAttempt
+	c.mu.RUnlock()
+
+	if force && time.Since(lastAttempt) >= defaultOIDCMinRefreshInterval {
+		if metadata, err, _ := c.refreshes.Do(c.discoveryURL, c.refreshShared); err == nil {
+			return metadata.(OIDCProviderMetadata).JWKSURI, nil
+		}
+	}
+	if jwksURL := c.Metadata().JWKSURI; jwksURL != "" {
+		return jwksURL, nil
+	}
+	return "", fmt.Errorf("oidc: jwks_uri unavailable")
+}
+
+func (c *JWTConfig) fetch(ctx context.Context) (OIDCProviderMetadata, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.discoveryURL, nil)
+	if err != nil {
+		return OIDCProviderMetadata{}, fmt.Errorf("oidc: build discovery request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return OIDCProviderMetadata{}, fmt.Errorf("oidc: fetch discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return OIDCProviderMetadata{}, fmt.Errorf("oidc: discovery endpoint returned status %d", resp.StatusCode)
+	}
+
+	var metadata OIDCProviderMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return OIDCProviderMetadata{}, fmt.Errorf("oidc: decode discovery document: %w", err)
+	}
+
+	metadata.Issuer = strings.TrimSpace(metadata.Issuer)
+	metadata.JWKSURI = strings.TrimSpace(metadata.JWKSURI)
+	if metadata.JWKSURI == "" {
+		return OIDCProviderMetadata{}, fmt.Errorf("oidc: discovery document has no jwks_uri")
+	}
+	// OpenID Connect Discovery 1.0 §4.3: the issuer must match the URL used
+	// to retrieve the document.
+	if strings.TrimRight(metadata.Issuer, "/") != c.issuerURL {
+		return OIDCProviderMetadata{}, fmt.Errorf("oidc: discovery issuer %q does not match %q", metadata.Issuer, c.issuerURL)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+func newOIDCTestServer(t *testing.T, jwksPayload []byte, issuerOverride string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var discoveryHits atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/realms/acme/.well-known/openid-configuration":
+			discoveryHits.Add(1)
+			issuer := server.URL + "/realms/acme"
+			if issuerOverride != "" {
+				issuer = issuerOverride
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"issuer":               issuer,
+				"jwks_uri":             server.URL + "/realms/acme/certs",
+				"token_endpoint":       server.URL + "/realms/acme/token",
+				"end_session_endpoint": server.URL + "/realms/acme/logout",
+			})
+		case "/realms/acme/certs":
+			_, _ = w.Write(jwksPayload)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &discoveryHits
+}
+
+func TestNewJWTConfigFromOIDCConfiguresAuthorizer(t *testing.T) {
+	privateKey, kid, jwksPayload := testJWKSKey(t)
+	server, _ := newOIDCTestServer(t, jwksPayload, "")
+	issuer := server.URL + "/realms/acme"
+
+	jwtCfg, err := NewJWTConfigFromOIDC(context.Background(), issuer+"/",
+		WithOIDCAudience("orders-api"),
+		WithOIDCBaseConfig(stubConfig{"BypassServiceTokenPermissions": "false"}),
+	)
+	if err != nil {
+		t.Fatalf("NewJWTConfigFromOIDC() error = %v", err)
+	}
+	if got := jwtCfg.TokenEndpoint(); got != issuer+"/token" {
+		t.Fatalf("TokenEndpoint() = %q", got)
+	}
+	if got := jwtCfg.EndSessionEndpoint(); got != issuer+"/logout" {
+		t.Fatalf("EndSessionEndpoint() = %q", got)
+	}
+	if got := jwtCfg.GetString("BypassServiceTokenPermissions"); got != "false" {
+		t.Fatalf("base config passthrough = %q", got)
+	}
+
+	authorizer, err := NewAuthorizer(jwtCfg, logger.MustNewDefaultLogger())
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error = %v", err)
+	}
+
+	token := signTestTokenWithHeader(t, privateKey, kid, jwt.MapClaims{"sub": "user-1", "iss": issuer, "aud": "orders-api"})
+	claims, err := authorizer.verifier.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if claims.Subject != "user-1" {
+		t.Fatalf("subject = %q, want user-1", claims.Subject)
+	}
+
+	wrongAudience := signTestTokenWithHeader(t, privateKey, kid, jwt.MapClaims{"sub": "user-1", "iss": issuer, "aud": "billing-api"})
+	if _, err := authorizer.verifier.Verify(wrongAudience); err == nil {
+		t.Fatal("Verify() accepted a token for another audience")
+	}
+}
+
+func TestNewJWTConfigFromOIDCRejectsIssuerMismatch(t *testing.T) {
+	_, _, jwksPayload := testJWKSKey(t)
+	server, _ := newOIDCTestServer(t, jwksPayload, "https://evil.example.com")
+
+	_, err := NewJWTConfigFromOIDC(context.Background(), server.URL+"/realms/acme")
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("NewJWTConfigFromOIDC() error = %v, want issuer mismatch", err)
+	}
+}
+
+func TestJWTConfigRefreshesDiscoveryDocument(t *testing.T) {
+	_, _, jwksPayload := testJWKSKey(t)
+	server, hits := newOIDCTestServer(t, jwksPayload, "")
+
+	jwtCfg, err := NewJWTConfigFromOIDC(context.Background(), server.URL+"/realms/acme", WithOIDCRefreshInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("NewJWTConfigFromOIDC() error = %v", err)
+	}
+	_ = jwtCfg.Issuer()
+	_ = jwtCfg.JWKSURL()
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("discovery fetched %d times, want cached after first fetch", got)
+	}
+
+	jwtCfg.mu.Lock()
+	jwtCfg.fetchedAt = time.Now().Add(-2 * time.Hour)
+	jwtCfg.lastAttempt = jwtCfg.fetchedAt
+	jwtCfg.mu.Unlock()
+	_ = jwtCfg.JWKSURL()
+	awaitDiscoveryHits(t, hits, 2)
+
+	server.Close()
+	jwtCfg.mu.Lock()
+	jwtCfg.fetchedAt, jwtCfg.lastAttempt = time.Time{}, time.Time{}
+	jwtCfg.mu.Unlock()
+	if got := jwtCfg.JWKSURL(); !strings.HasSuffix(got, "/realms/acme/certs") {
+		t.Fatalf("JWKSURL() = %q, want last good document when refresh fails", got)
+	}
+}
+
+// An expired document is refreshed once in the background while callers keep
+// getting the cached copy.
+func TestJWTConfigRefreshesOffRequestPath(t *testing.T) {
+	var hits atomic.Int32
+	release := make(chan struct{})
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) > 1 {
+			<-release
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":   server.URL,
+			"jwks_uri": server.URL + "/certs-" + strconv.Itoa(int(hits.Load())),
+		})
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+
+	jwtCfg, err := NewJWTConfigFromOIDC(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("NewJWTConfigFromOIDC() error = %v", err)
+	}
+	jwtCfg.mu.Lock()
+	jwtCfg.fetchedAt, jwtCfg.lastAttempt = time.Time{}, time.Time{}
+	jwtCfg.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := jwtCfg.JWKSURL(); got != server.URL+"/certs-1" {
+				t.Errorf("JWKSURL() = %q, want the cached copy during the refresh", got)
+			}
+		}()
+	}
+	wg.Wait()
+
+	close(release)
+	awaitDiscoveryHits(t, &hits, 2)
+	deadline := time.Now().Add(5 * time.Second)
+	for jwtCfg.JWKSURL() != server.URL+"/certs-2" {
+		if time.Now().After(deadline) {
+			t.Fatalf("JWKSURL() = %q, want the refreshed document", jwtCfg.JWKSURL())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("discovery fetched %d times, want one refresh", got)
+	}
+}
+
+func awaitDiscoveryHits(t *testing.T, hits *atomic.Int32, want int32) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for hits.Load() < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("discovery fetched %d times, want %d", hits.Load(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/milan604/core-lab/pkg/permissions"
+)
+
+// PermissionUsage is a permission code referenced by a permission middleware,
+// with the place the middleware was built (usually the route declaration).
+type PermissionUsage struct {
+	Code     string
+	Location string
+}
+
+// recordPermissionUsage remembers the codes expr references for
+// CheckPermissionCatalog. skip is the number of frames between the caller and
+// the public Require* method.
+func (a *Authorizer) recordPermissionUsage(expr PermissionExpression, skip int) {
+	location := "unknown"
+	if _, file, line, ok := runtime.Caller(skip + 1); ok {
+		location = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+
+	a.usageMu.Lock()
+	defer a.usageMu.Unlock()
+	for _, code := range expr.Codes() {
+		a.permissionUsages = append(a.permissionUsages, PermissionUsage{Code: code, Location: location})
+	}
+}
+
+// PermissionUsages returns every permission code referenced by middlewares this
+// authorizer has built, in registration order.
+func (a *Authorizer) PermissionUsages() []PermissionUsage {
+	a.usageMu.Lock()
+	defer a.usageMu.Unlock()
+	return append([]PermissionUsage(nil), a.permissionUsages...)
+}
+
+// CheckPermissionCatalog reports permission codes referenced by the
+// authorizer's middlewares that catalog does not declare. Call it from a test
+// after building the service's engine with the same authorizer, so a route
+// guarded by a typo or an undeclared code fails CI instead of returning
+// permission_not_registered at runtime:
+//
+//	func TestRoutePermissionsAreDeclared(t *testing.T) {
+//		authorizer, _ := auth.NewAuthorizer(cfg, log)
+//		_ = routes.NewEngine(authorizer)
+//		if err := auth.CheckPermissionCatalog(authorizer, permissionsCatalog); err != nil {
+//			t.Fatal(err)
+//		}
+//	}
+//
+// Codes are compared case-insensitively.
+func CheckPermissionCatalog(a *Authorizer, catalog *permissions.Catalog) error {
+	if a == nil {
+		return fmt.Errorf("permission catalog check: authorizer is nil")
+	}
+	if catalog == nil {
+		return fmt.Errorf("permission catalog check: catalog is nil")
+	}
+
+	declared := make(map[string]bool, catalog.Count())
+	for _, code := range catalog.Codes() {
+		declared[strings.ToUpper(code)] = true
+	}
+
+	undeclared := map[string][]string{}
+	for _, usage := range a.PermissionUsages() {
+		if !declared[strings.ToUpper(usage.Code)] {
+			undeclared[usage.Code] = append(undeclared[usage.Code], usage.Location)
+		}
+	}
+	if len(undeclared) == 0 {
+		return nil
+	}
+
+	codes := make([]string, 0, len(undeclared))
+	for code := range undeclared {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	lines := make([]string, len(codes))
+	for i, code := range codes {
+		lines[i] = fmt.Sprintf("%s (used at %s)", code, strings.Join(undeclared[code], ", "))
+	}
+	return fmt.Errorf("permission catalog check: %d undeclared permission code(s): %s", len(codes), strings.Join(lines, "; "))
+}
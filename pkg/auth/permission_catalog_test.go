@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/permissions"
+)
+
+func TestCheckPermissionCatalog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	catalog := permissions.NewCatalog([]permissions.Definition{
+		{Reference: permissions.Reference{Service: "ord", Category: "orders", Action: "list"}, Name: "List orders"},
+		{Reference: permissions.Reference{Service: "ord", Category: "orders", Action: "export"}, Name: "Export orders"},
+	})
+	authorizer := &Authorizer{log: logger.MustNewDefaultLogger()}
+
+	router := gin.New()
+	router.GET("/orders", authorizer.RequirePermission("ORD-ORDERS-LIST"))
+	router.GET("/orders/export", authorizer.RequirePermissionExpr("ORD-ORDERS-LIST && ord-orders-export"))
+	if err := CheckPermissionCatalog(authorizer, catalog); err != nil {
+		t.Fatalf("CheckPermissionCatalog() = %v, want nil", err)
+	}
+
+	router.DELETE("/orders", authorizer.RequireAnyPermission("ORD-ORDERS-DELETE", "ORD-ORDERS-LIST"))
+	err := CheckPermissionCatalog(authorizer, catalog)
+	if err == nil {
+		t.Fatal("expected undeclared code error")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "ORD-ORDERS-DELETE (used at permission_catalog_test.go:") || strings.Contains(msg, "ORD-ORDERS-LIST") {
+		t.Fatalf("error = %q", msg)
+	}
+	if got := len(authorizer.PermissionUsages()); got != 5 {
+		t.Fatalf("PermissionUsages() len = %d, want 5", got)
+	}
+}
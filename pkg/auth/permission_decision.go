@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"strconv"
 	"strings"
 
@@ -15,34 +14,23 @@ import (
 	"github.com/milan604/core-lab/pkg/controlplane"
 	httplib "github.com/milan604/core-lab/pkg/http"
 	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/sentinel"
 )
 
 const permissionDecisionRequestBodyKey = "auth_permission_decision_body"
 
-type permissionDecisionRequest struct {
-	SubjectUserID string            `json:"subject_user_id"`
-	TenantID      string            `json:"tenant_id,omitempty"`
-	Service       string            `json:"service"`
-	Category      string            `json:"category"`
-	Action        string            `json:"action"`
-	ResourceType  string            `json:"resource_type,omitempty"`
-	ResourceID    string            `json:"resource_id,omitempty"`
-	Context       map[string]string `json:"context,omitempty"`
-}
-
-type permissionDecisionResponse struct {
-	Allowed bool     `json:"allowed"`
-	Reasons []string `json:"reasons,omitempty"`
-}
+type (
+	permissionDecisionRequest  = sentinel.DecisionRequest
+	permissionDecisionResponse = sentinel.DecisionResponse
+)
 
 type permissionDecisionClient interface {
 	Decide(ctx context.Context, input permissionDecisionRequest) (permissionDecisionResponse, error)
 }
 
 type httpPermissionDecisionClient struct {
-	baseURL    string
-	httpClient *httplib.Client
-	initErr    error
+	client  *sentinel.Client
+	initErr error
 }
 
 var newPermissionDecisionClientFunc = func(cfg Config, log logger.LogManager) permissionDecisionClient {
@@ -64,25 +52,23 @@ func newPermissionDecisionClient(cfg Config, log logger.LogManager) permissionDe
 		return nil
 	}
 
-	client, err := httplib.NewInternalControlPlaneClientForAudience(log, realCfg, controlplane.ResolveAuthorizationAudience(realCfg))
-	return &httpPermissionDecisionClient{
-		baseURL:    baseURL,
-		httpClient: client,
-		initErr:    err,
+	httpClient, err := httplib.NewInternalControlPlaneClientForAudience(log, realCfg, controlplane.ResolveAuthorizationAudience(realCfg))
+	client := &httpPermissionDecisionClient{initErr: err}
+	if err == nil {
+		// Decisions sit on the request path; fail fast instead of retrying 5xx.
+		client.client = sentinel.New(baseURL, httpClient, sentinel.WithRetry(1, 0))
 	}
+	return client
 }
 
 func (c *httpPermissionDecisionClient) Decide(ctx context.Context, input permissionDecisionRequest) (permissionDecisionResponse, error) {
 	if c == nil {
 		return permissionDecisionResponse{}, fmt.Errorf("permission decision client is nil")
 	}
-	if strings.TrimSpace(c.baseURL) == "" {
-		return permissionDecisionResponse{}, fmt.Errorf("%s or %s not configured", controlplane.KeyBaseURL, controlplane.LegacyKeyBaseURL)
-	}
 	if c.initErr != nil {
 		return permissionDecisionResponse{}, fmt.Errorf("authorization control-plane client initialization failed: %w", c.initErr)
 	}
-	if c.httpClient == nil {
+	if c.client == nil {
 		return permissionDecisionResponse{}, fmt.Errorf("authorization control-plane client is not configured")
 	}
 	if strings.TrimSpace(input.SubjectUserID) == "" {
@@ -92,32 +78,7 @@ func (c *httpPermissionDecisionClient) Decide(ctx context.Context, input permiss
 		return permissionDecisionResponse{}, fmt.Errorf("service, category, and action are required")
 	}
 
-	var body bytes.Buffer
-	if err := json.NewEncoder(&body).Encode(input); err != nil {
-		return permissionDecisionResponse{}, fmt.Errorf("encode permission decision request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, controlplane.API{BaseURL: c.baseURL}.AuthorizationDecisionURL(), &body)
-	if err != nil {
-		return permissionDecisionResponse{}, fmt.Errorf("create permission decision request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(ctx, req)
-	if err != nil {
-		return permissionDecisionResponse{}, err
-	}
-	defer resp.Body.Close()
-
-	var out permissionDecisionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil && err != io.EOF {
-		return permissionDecisionResponse{}, fmt.Errorf("decode permission decision response: %w", err)
-	}
-	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
-		return out, fmt.Errorf("permission decision endpoint returned status %d", resp.StatusCode)
-	}
-
-	return out, nil
+	return c.client.Decide(ctx, input)
 }
 
 func buildPermissionDecisionRequest(c *gin.Context, claims Claims, code string) (permissionDecisionRequest, error) {
@@ -0,0 +1,266 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// PermissionExpression is a compiled boolean expression over permission codes,
+// e.g. "PMS-ORD-READ && (PMS-ORD-EXPORT || PMS-ADMIN-ALL)". Supported operators
+// are && (and), || (or), ! (not) and parentheses; && binds tighter than ||.
+type PermissionExpression struct {
+	source string
+	root   permissionNode
+}
+
+// ParsePermissionExpression compiles expr. Codes may contain letters, digits
+// and any of . _ - : /.
+func ParsePermissionExpression(expr string) (PermissionExpression, error) {
+	p := &permissionParser{tokens: tokenizePermissionExpression(expr)}
+	if len(p.tokens) == 0 {
+		return PermissionExpression{}, fmt.Errorf("permission expression is empty")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return PermissionExpression{}, fmt.Errorf("permission expression %q: %w", expr, err)
+	}
+	if tok, ok := p.peek(); ok {
+		return PermissionExpression{}, fmt.Errorf("permission expression %q: unexpected %q", expr, tok)
+	}
+	return PermissionExpression{source: strings.TrimSpace(expr), root: root}, nil
+}
+
+// MustParsePermissionExpression is like ParsePermissionExpression but panics on
+// an invalid expression. It is meant for route declarations.
+func MustParsePermissionExpression(expr string) PermissionExpression {
+	compiled, err := ParsePermissionExpression(expr)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// String returns the expression source.
+func (e PermissionExpression) String() string { return e.source }
+
+// Codes returns the distinct permission codes referenced by the expression.
+func (e PermissionExpression) Codes() []string {
+	var codes []string
+	seen := map[string]bool{}
+	collectPermissionCodes(e.root, func(code string) {
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	})
+	return codes
+}
+
+// Evaluate reports whether the expression holds, calling has for each code it
+// needs. Evaluation short-circuits, so has is not called for codes that cannot
+// change the outcome. An error from has stops evaluation.
+func (e PermissionExpression) Evaluate(has func(code string) (bool, error)) (bool, error) {
+	if e.root == nil {
+		return false, fmt.Errorf("permission expression is empty")
+	}
+	return e.root.eval(has)
+}
+
+// anyPermission and allPermissions build expressions for RequireAnyPermission
+// and RequireAllPermissions.
+func anyPermission(codes []string) PermissionExpression {
+	return joinPermissionCodes(codes, " || ", func(children []permissionNode) permissionNode { return permissionOr(children) })
+}
+
+func allPermissions(codes []string) PermissionExpression {
+	return joinPermissionCodes(codes, " && ", func(children []permissionNode) permissionNode { return permissionAnd(children) })
+}
+
+func joinPermissionCodes(codes []string, sep string, join func([]permissionNode) permissionNode) PermissionExpression {
+	children := make([]permissionNode, 0, len(codes))
+	names := make([]string, 0, len(codes))
+	for _, code := range codes {
+		if code = strings.TrimSpace(code); code != "" {
+			children = append(children, permissionCode(code))
+			names = append(names, code)
+		}
+	}
+	switch len(children) {
+	case 0:
+		return PermissionExpression{}
+	case 1:
+		return PermissionExpression{source: names[0], root: children[0]}
+	}
+	return PermissionExpression{source: strings.Join(names, sep), root: join(children)}
+}
+
+type permissionNode interface {
+	eval(has func(string) (bool, error)) (bool, error)
+}
+
+type (
+	permissionCode string
+	permissionAnd  []permissionNode
+	permissionOr   []permissionNode
+	permissionNot  struct{ node permissionNode }
+)
+
+func (n permissionCode) eval(has func(string) (bool, error)) (bool, error) {
+	return has(string(n))
+}
+
+func (n permissionAnd) eval(has func(string) (bool, error)) (bool, error) {
+	for _, child := range n {
+		ok, err := child.eval(has)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (n permissionOr) eval(has func(string) (bool, error)) (bool, error) {
+	for _, child := range n {
+		ok, err := child.eval(has)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func (n permissionNot) eval(has func(string) (bool, error)) (bool, error) {
+	ok, err := n.node.eval(has)
+	return !ok && err == nil, err
+}
+
+func collectPermissionCodes(node permissionNode, visit func(string)) {
+	switch n := node.(type) {
+	case permissionCode:
+		visit(string(n))
+	case permissionAnd:
+		for _, child := range n {
+			collectPermissionCodes(child, visit)
+		}
+	case permissionOr:
+		for _, child := range n {
+			collectPermissionCodes(child, visit)
+		}
+	case permissionNot:
+		collectPermissionCodes(n.node, visit)
+	}
+}
+
+type permissionParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *permissionParser) peek() (string, bool) {
+	if p.pos >= len(p.tokens) {
+		return "", false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *permissionParser) parseOr() (permissionNode, error) {
+	return p.parseBinary("||", p.parseAnd, func(children []permissionNode) permissionNode { return permissionOr(children) })
+}
+
+func (p *permissionParser) parseAnd() (permissionNode, error) {
+	return p.parseBinary("&&", p.parseUnary, func(children []permissionNode) permissionNode { return permissionAnd(children) })
+}
+
+func (p *permissionParser) parseBinary(op string, next func() (permissionNode, error), join func([]permissionNode) permissionNode) (permissionNode, error) {
+	first, err := next()
+	if err != nil {
+		return nil, err
+	}
+	children := []permissionNode{first}
+	for {
+		if tok, ok := p.peek(); !ok || tok != op {
+			break
+		}
+		p.pos++
+		child, err := next()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return join(children), nil
+}
+
+func (p *permissionParser) parseUnary() (permissionNode, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+	switch tok {
+	case "!":
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return permissionNot{node: node}, nil
+	case "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing, ok := p.peek(); !ok || closing != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return node, nil
+	case ")", "&&", "||":
+		return nil, fmt.Errorf("unexpected %q", tok)
+	}
+	if !isPermissionCode(tok) {
+		return nil, fmt.Errorf("invalid permission code %q", tok)
+	}
+	return permissionCode(tok), nil
+}
+
+func tokenizePermissionExpression(expr string) []string {
+	var tokens []string
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == '!':
+			tokens = append(tokens, string(r))
+			i++
+		case (r == '&' || r == '|') && i+1 < len(runes) && runes[i+1] == r:
+			tokens = append(tokens, string(runes[i:i+2]))
+			i += 2
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("()!&|", runes[i]) {
+				i++
+			}
+			if i == start {
+				// A lone & or |; keep it so the parser reports it.
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		}
+	}
+	return tokens
+}
+
+func isPermissionCode(tok string) bool {
+	for _, r := range tok {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("._-:/", r) {
+			return false
+		}
+	}
+	return tok != ""
+}
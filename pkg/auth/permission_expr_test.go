@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+func TestParsePermissionExpression(t *testing.T) {
+	held := map[string]bool{"ORD-READ": true, "ADMIN": true}
+	cases := []struct {
+		expr string
+		want bool
+	}{
+		{"ORD-READ", true},
+		{"ORD-EXPORT", false},
+		{"ORD-READ && ORD-EXPORT", false},
+		{"ORD-READ && (ORD-EXPORT || ADMIN)", true},
+		{"ORD-EXPORT || ORD-READ && ADMIN", true},
+		{"!ORD-EXPORT && ORD-READ", true},
+		{"!(ORD-READ || ORD-EXPORT)", false},
+	}
+	for _, tc := range cases {
+		expr, err := ParsePermissionExpression(tc.expr)
+		if err != nil {
+			t.Fatalf("ParsePermissionExpression(%q): %v", tc.expr, err)
+		}
+		got, err := expr.Evaluate(func(code string) (bool, error) { return held[code], nil })
+		if err != nil || got != tc.want {
+			t.Fatalf("Evaluate(%q) = %v, %v; want %v", tc.expr, got, err, tc.want)
+		}
+	}
+
+	for _, invalid := range []string{"", "ORD-READ &&", "(ORD-READ", "ORD-READ) ", "ORD-READ & ADMIN", "ORD-READ ADMIN", "ORD*READ"} {
+		if _, err := ParsePermissionExpression(invalid); err == nil {
+			t.Fatalf("ParsePermissionExpression(%q) succeeded, want error", invalid)
+		}
+	}
+}
+
+func TestPermissionExpressionShortCircuits(t *testing.T) {
+	expr := MustParsePermissionExpression("A || B || A")
+	if codes := expr.Codes(); strings.Join(codes, ",") != "A,B" {
+		t.Fatalf("Codes() = %v, want [A B]", codes)
+	}
+
+	var checked []string
+	ok, err := expr.Evaluate(func(code string) (bool, error) {
+		checked = append(checked, code)
+		return code == "A", nil
+	})
+	if err != nil || !ok || len(checked) != 1 {
+		t.Fatalf("Evaluate() = %v, %v after checking %v; want true after checking only A", ok, err, checked)
+	}
+}
+
+func TestRequirePermissionExpressionMiddlewares(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authorizer := &Authorizer{log: logger.MustNewDefaultLogger()}
+	lookup := stubPermissionLookup{
+		"ORD-READ":   {Service: "ord", BitValue: 0},
+		"ORD-EXPORT": {Service: "ord", BitValue: 1},
+		"ORD-ADMIN":  {Service: "ord", BitValue: 2},
+	}
+	// The caller holds ORD-READ and ORD-ADMIN.
+	claims := Claims{Subject: "user-1", ServicePermissions: map[string][]int64{"ord": {1<<0 | 1<<2}}}
+
+	cases := []struct {
+		name       string
+		middleware gin.HandlerFunc
+		want       int
+	}{
+		{"any held", authorizer.RequireAnyPermission("ORD-EXPORT", "ORD-READ"), http.StatusNoContent},
+		{"any missing", authorizer.RequireAnyPermission("ORD-EXPORT", "ORD-UNKNOWN"), http.StatusForbidden},
+		{"all held", authorizer.RequireAllPermissions("ORD-READ", "ORD-ADMIN"), http.StatusNoContent},
+		{"all missing one", authorizer.RequireAllPermissions("ORD-READ", "ORD-EXPORT"), http.StatusForbidden},
+		{"expression", authorizer.RequirePermissionExpr("ORD-READ && (ORD-EXPORT || ORD-ADMIN)"), http.StatusNoContent},
+		{"expression denied", authorizer.RequirePermissionExpr("ORD-READ && !ORD-ADMIN"), http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/orders", func(c *gin.Context) {
+				SetClaims(c, claims)
+				c.Set(string(CtxMiddlewareServiceKey), PermissionLookup(lookup))
+			}, tc.middleware, func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/orders", nil))
+			if recorder.Code != tc.want {
+				t.Fatalf("status = %d, want %d; body=%s", recorder.Code, tc.want, recorder.Body.String())
+			}
+			if tc.want == http.StatusForbidden && !strings.Contains(recorder.Body.String(), "permission_denied") {
+				t.Fatalf("body = %q, want permission_denied error", recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireAnyPermissionPanicsWithoutCodes(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	(&Authorizer{}).RequireAnyPermission()
+}
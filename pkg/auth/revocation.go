@@ -0,0 +1,348 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	httplib "github.com/milan604/core-lab/pkg/http"
+	redis "github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrTokenRevoked is returned when a verified token's jti is on the denylist.
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrRevocationUnavailable wraps RevocationChecker failures; requests are
+	// rejected with 503 rather than admitting possibly revoked tokens.
+	ErrRevocationUnavailable = errors.New("token revocation check unavailable")
+)
+
+// RevocationChecker reports which token IDs (jti claims) are revoked. It takes
+// a batch so implementations can resolve many IDs in one round trip.
+type RevocationChecker interface {
+	// Revoked returns the subset of ids that are revoked.
+	Revoked(ctx context.Context, ids []string) (map[string]bool, error)
+}
+
+// UseRevocationChecker makes the authorizer reject tokens whose jti is revoked,
+// checked after signature verification. Tokens without a jti are not checked.
+// Wrap remote checkers with NewCachingRevocationChecker to batch lookups and
+// cache results. It returns the receiver so calls can be chained.
+func (a *Authorizer) UseRevocationChecker(checker RevocationChecker) *Authorizer {
+	a.revocation = checker
+	return a
+}
+
+func (a *Authorizer) checkRevocation(ctx context.Context, claims Claims) error {
+	if a.revocation == nil {
+		return nil
+	}
+	jti, _ := claims.Raw["jti"].(string)
+	jti = strings.TrimSpace(jti)
+	if jti == "" {
+		return nil
+	}
+
+	revoked, err := a.revocation.Revoked(ctx, []string{jti})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRevocationUnavailable, err)
+	}
+	if revoked[jti] {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// RedisRevocationStore keeps revoked token IDs in Redis until the token would
+// have expired anyway.
+type RedisRevocationStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisRevocationStore creates a store keyed "<prefix>:<jti>". prefix
+// defaults to "auth:revoked".
+func NewRedisRevocationStore(client redis.UniversalClient, prefix string) *RedisRevocationStore {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		prefix = "auth:revoked"
+	}
+	return &RedisRevocationStore{client: client, prefix: prefix}
+}
+
+// Revoke denylists jti until expiresAt, normally the token's exp claim.
+func (s *RedisRevocationStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	jti = strings.TrimSpace(jti)
+	if jti == "" {
+		return errors.New("revocation store: jti is required")
+	}
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.client.Set(ctx, s.key(jti), "1", ttl).Err()
+}
+
+// Revoked looks all ids up with a single MGET.
+func (s *RedisRevocationStore) Revoked(ctx context.Context, ids []string) (map[string]bool, error) {
+	if len(ids) == 0 {
+		return map[string]bool{}, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.key(id)
+	}
+
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]bool, len(ids))
+	for i, value := range values {
+		if value != nil {
+			out[ids[i]] = true
+		}
+	}
+	return out, nil
+}
+
+func (s *RedisRevocationStore) key(jti string) string {
+	return s.prefix + ":" + jti
+}
+
+// RemoteRevocationChecker asks an HTTP endpoint, typically the control plane,
+// which token IDs are revoked. The endpoint receives {"jtis": [...]} and answers
+// 200 with {"revoked": [...]}.
+type RemoteRevocationChecker struct {
+	url    string
+	client *httplib.Client
+}
+
+// NewRemoteRevocationChecker creates a checker for url. client is usually a
+// service-token client such as httplib.NewClientWithServiceToken.
+func NewRemoteRevocationChecker(url string, client *httplib.Client) (*RemoteRevocationChecker, error) {
+	url = strings.TrimSpace(url)
+	if url == "" {
+		return nil, errors.New("revocation checker: url is required")
+	}
+	if client == nil {
+		client = httplib.NewClient()
+	}
+	return &RemoteRevocationChecker{url: url, client: client}, nil
+}
+
+// Revoked posts ids to the revocation endpoint.
+func (r *RemoteRevocationChecker) Revoked(ctx context.Context, ids []string) (map[string]bool, error) {
+	if len(ids) == 0 {
+		return map[string]bool{}, nil
+	}
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(map[string][]string{"jtis": ids}); err != nil {
+		return nil, fmt.Errorf("encode revocation request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, &body)
+	if err != nil {
+		return nil, fmt.Errorf("create revocation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("revocation endpoint returned status %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		Revoked []string `json:"revoked"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("decode revocation response: %w", err)
+	}
+	out := make(map[string]bool, len(payload.Revoked))
+	for _, id := range payload.Revoked {
+		out[id] = true
+	}
+	return out, nil
+}
+
+// RevocationCacheConfig configures NewCachingRevocationChecker.
+type RevocationCacheConfig struct {
+	// NegativeTTL is how long a "not revoked" answer is trusted, i.e. the longest
+	// a freshly revoked token can still be accepted. Default: 30s.
+	NegativeTTL time.Duration
+	// PositiveTTL is how long a "revoked" answer is cached. Default: 10m.
+	PositiveTTL time.Duration
+	// BatchWindow is how long lookups wait to be coalesced. Default: 2ms.
+	BatchWindow time.Duration
+	// MaxBatch flushes a batch early once it holds this many ids. Default: 100.
+	MaxBatch int
+	// Timeout bounds each batched call to the wrapped checker. Default: 2s.
+	Timeout time.Duration
+}
+
+// CachingRevocationChecker coalesces concurrent lookups into batched calls to
+// another checker and caches the answers locally.
+type CachingRevocationChecker struct {
+	inner RevocationChecker
+	cfg   RevocationCacheConfig
+
+	mu      sync.Mutex
+	cache   map[string]revocationCacheEntry
+	pending *revocationBatch
+	swept   time.Time
+}
+
+type revocationCacheEntry struct {
+	revoked   bool
+	expiresAt time.Time
+}
+
+type revocationBatch struct {
+	ids     map[string]struct{}
+	done    chan struct{}
+	result  map[string]bool
+	err     error
+	flushed bool
+}
+
+// NewCachingRevocationChecker wraps inner with batching and local caching.
+func NewCachingRevocationChecker(inner RevocationChecker, cfg RevocationCacheConfig) *CachingRevocationChecker {
+	if cfg.NegativeTTL <= 0 {
+		cfg.NegativeTTL = 30 * time.Second
+	}
+	if cfg.PositiveTTL <= 0 {
+		cfg.PositiveTTL = 10 * time.Minute
+	}
+	if cfg.BatchWindow <= 0 {
+		cfg.BatchWindow = 2 * time.Millisecond
+	}
+	if cfg.MaxBatch <= 0 {
+		cfg.MaxBatch = 100
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	return &CachingRevocationChecker{
+		inner: inner,
+		cfg:   cfg,
+		cache: make(map[string]revocationCacheEntry),
+	}
+}
+
+// Revoked answers from the cache where possible and batches the remaining ids.
+func (c *CachingRevocationChecker) Revoked(ctx context.Context, ids []string) (map[string]bool, error) {
+	out := make(map[string]bool, len(ids))
+	now := time.Now()
+
+	c.mu.Lock()
+	var missing []string
+	for _, id := range ids {
+		if entry, ok := c.cache[id]; ok && now.Before(entry.expiresAt) {
+			if entry.revoked {
+				out[id] = true
+			}
+			continue
+		}
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		c.mu.Unlock()
+		return out, nil
+	}
+	batch := c.enqueueLocked(missing)
+	c.mu.Unlock()
+
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if batch.err != nil {
+		return nil, batch.err
+	}
+	for _, id := range missing {
+		if batch.result[id] {
+			out[id] = true
+		}
+	}
+	return out, nil
+}
+
+// enqueueLocked adds ids to the pending batch, starting a new one (and its
+// flush timer) when none is open. c.mu must be held.
+func (c *CachingRevocationChecker) enqueueLocked(ids []string) *revocationBatch {
+	batch := c.pending
+	if batch == nil {
+		batch = &revocationBatch{ids: make(map[string]struct{}), done: make(chan struct{})}
+		c.pending = batch
+		time.AfterFunc(c.cfg.BatchWindow, func() { c.flush(batch) })
+	}
+	for _, id := range ids {
+		batch.ids[id] = struct{}{}
+	}
+	if len(batch.ids) >= c.cfg.MaxBatch {
+		go c.flush(batch)
+	}
+	return batch
+}
+
+func (c *CachingRevocationChecker) flush(batch *revocationBatch) {
+	c.mu.Lock()
+	if batch.flushed {
+		c.mu.Unlock()
+		return
+	}
+	batch.flushed = true
+	if c.pending == batch {
+		c.pending = nil
+	}
+	ids := make([]string, 0, len(batch.ids))
+	for id := range batch.ids {
+		ids = append(ids, id)
+	}
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	result, err := c.inner.Revoked(ctx, ids)
+	cancel()
+
+	if err == nil {
+		now := time.Now()
+		c.mu.Lock()
+		for _, id := range ids {
+			ttl := c.cfg.NegativeTTL
+			if result[id] {
+				ttl = c.cfg.PositiveTTL
+			}
+			c.cache[id] = revocationCacheEntry{revoked: result[id], expiresAt: now.Add(ttl)}
+		}
+		c.sweepLocked(now)
+		c.mu.Unlock()
+	}
+
+	batch.result, batch.err = result, err
+	close(batch.done)
+}
+
+// sweepLocked drops expired cache entries at most once per NegativeTTL.
+func (c *CachingRevocationChecker) sweepLocked(now time.Time) {
+	if now.Sub(c.swept) < c.cfg.NegativeTTL {
+		return
+	}
+	c.swept = now
+	for id, entry := range c.cache {
+		if now.After(entry.expiresAt) {
+			delete(c.cache, id)
+		}
+	}
+}
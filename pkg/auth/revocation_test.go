@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	redis "github.com/redis/go-redis/v9"
+)
+
+type countingRevocationChecker struct {
+	revoked map[string]bool
+	err     error
+	calls   atomic.Int32
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (c *countingRevocationChecker) Revoked(_ context.Context, ids []string) (map[string]bool, error) {
+	c.calls.Add(1)
+	c.mu.Lock()
+	c.batches = append(c.batches, append([]string(nil), ids...))
+	c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	out := map[string]bool{}
+	for _, id := range ids {
+		if c.revoked[id] {
+			out[id] = true
+		}
+	}
+	return out, nil
+}
+
+func TestRequireAuthenticatedRejectsRevokedTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	privateKey, publicKeyPEM := testKeyPair(t)
+	checker := &countingRevocationChecker{revoked: map[string]bool{"jti-revoked": true}}
+	authorizer := testAuthorizer(t, stubConfig{"RSAPublicKey": publicKeyPEM}).UseRevocationChecker(checker)
+
+	router := gin.New()
+	router.GET("/me", authorizer.RequireAuthenticated(), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	do := func(claims jwt.MapClaims) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+signTestToken(t, privateKey, claims))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	if recorder := do(jwt.MapClaims{"sub": "user-1", "jti": "jti-ok"}); recorder.Code != http.StatusNoContent {
+		t.Fatalf("valid token status = %d body = %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := do(jwt.MapClaims{"sub": "user-1", "jti": "jti-revoked"}); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked token status = %d, want 401", recorder.Code)
+	}
+	if recorder := do(jwt.MapClaims{"sub": "user-1"}); recorder.Code != http.StatusNoContent {
+		t.Fatalf("token without jti status = %d, want 204", recorder.Code)
+	}
+	if got := checker.calls.Load(); got != 2 {
+		t.Fatalf("checker calls = %d, want 2 (tokens without jti are not checked)", got)
+	}
+
+	checker.err = errors.New("redis down")
+	if recorder := do(jwt.MapClaims{"sub": "user-1", "jti": "jti-ok"}); recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("checker failure status = %d, want 503", recorder.Code)
+	}
+}
+
+func TestCachingRevocationCheckerBatchesAndCaches(t *testing.T) {
+	inner := &countingRevocationChecker{revoked: map[string]bool{"b": true}}
+	checker := NewCachingRevocationChecker(inner, RevocationCacheConfig{BatchWindow: 20 * time.Millisecond})
+
+	var wg sync.WaitGroup
+	results := make([]map[string]bool, 3)
+	for i, id := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			result, err := checker.Revoked(context.Background(), []string{id})
+			if err != nil {
+				t.Errorf("Revoked(%s) error = %v", id, err)
+			}
+			results[i] = result
+		}(i, id)
+	}
+	wg.Wait()
+
+	if got := inner.calls.Load(); got != 1 {
+		t.Fatalf("inner calls = %d, want one batched call", got)
+	}
+	if results[0]["a"] || !results[1]["b"] || results[2]["c"] {
+		t.Fatalf("results = %v", results)
+	}
+
+	if result, _ := checker.Revoked(context.Background(), []string{"a", "b"}); !result["b"] || result["a"] {
+		t.Fatalf("cached result = %v", result)
+	}
+	if got := inner.calls.Load(); got != 1 {
+		t.Fatalf("inner calls = %d, want cached answers", got)
+	}
+}
+
+func TestRedisRevocationStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	store := NewRedisRevocationStore(client, "")
+	ctx := context.Background()
+
+	if err := store.Revoke(ctx, "jti-1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if err := store.Revoke(ctx, "jti-expired", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("Revoke(expired) error = %v", err)
+	}
+
+	revoked, err := store.Revoked(ctx, []string{"jti-1", "jti-2", "jti-expired"})
+	if err != nil {
+		t.Fatalf("Revoked() error = %v", err)
+	}
+	if !revoked["jti-1"] || revoked["jti-2"] || revoked["jti-expired"] {
+		t.Fatalf("revoked = %v, want only jti-1", revoked)
+	}
+
+	server.FastForward(2 * time.Hour)
+	if revoked, _ := store.Revoked(ctx, []string{"jti-1"}); revoked["jti-1"] {
+		t.Fatal("revocation outlived the token expiry")
+	}
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/milan604/core-lab/pkg/config"
+)
+
+// Config keys of the verification keys SubscribeRotation reloads.
+const (
+	KeyRSAPublicKey    = "RSAPublicKey"
+	KeyJWTSharedSecret = "JWTSharedSecret"
+)
+
+// SubscribeRotation reloads the token verification keys whenever
+// RSAPublicKey or JWTSharedSecret is rotated on bus, so a new signing key
+// takes effect without a restart. A rotated value that fails to parse, or
+// that would leave no way to verify tokens, is rejected and the current key
+// stays in use. JWKS keys are refreshed by their own cache. It returns a
+// function that removes the subscriptions.
+func (a *Authorizer) SubscribeRotation(bus *config.RotationBus) (unsubscribe func()) {
+	handler := func(_ context.Context, rotation config.SecretRotation) error {
+		return a.verifier.rotateKey(rotation.Key, rotation.Value)
+	}
+	unsubscribeKey := bus.Subscribe(KeyRSAPublicKey, handler)
+	unsubscribeSecret := bus.Subscribe(KeyJWTSharedSecret, handler)
+	return func() {
+		unsubscribeKey()
+		unsubscribeSecret()
+	}
+}
+
+// rotateKey replaces the static key or shared secret named by key.
+func (v *jwtVerifier) rotateKey(key, value string) error {
+	v.keysMu.Lock()
+	defer v.keysMu.Unlock()
+
+	staticKey, sharedSecret := v.staticKey, v.sharedSecret
+	var err error
+	switch {
+	case strings.EqualFold(strings.TrimSpace(key), KeyRSAPublicKey):
+		staticKey, err = parseStaticKey(value)
+	case strings.EqualFold(strings.TrimSpace(key), KeyJWTSharedSecret):
+		sharedSecret, err = parseSharedSecret(value)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if staticKey == nil && sharedSecret == nil && v.remote == nil {
+		return errors.New("jwt authorizer: rotation would leave no verification key")
+	}
+	v.staticKey, v.sharedSecret = staticKey, sharedSecret
+	return nil
+}
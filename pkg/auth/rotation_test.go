@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/milan604/core-lab/pkg/config"
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+func TestAuthorizerSubscribeRotationReloadsSharedSecret(t *testing.T) {
+	oldSecret := strings.Repeat("o", 32)
+	newSecret := strings.Repeat("n", 32)
+	authorizer, err := NewAuthorizer(stubConfig{"JWTSharedSecret": oldSecret}, logger.MustNewDefaultLogger())
+	if err != nil {
+		t.Fatalf("NewAuthorizer() error = %v", err)
+	}
+	bus := config.NewRotationBus()
+	unsubscribe := authorizer.SubscribeRotation(bus)
+	defer unsubscribe()
+
+	sign := func(secret string) string {
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "tooling"}).SignedString([]byte(secret))
+		return token
+	}
+
+	ctx := context.Background()
+	if err := bus.Publish(ctx, config.SecretRotation{Key: "JWTSharedSecret", Value: "short"}); err == nil {
+		t.Fatal("Publish() accepted a shared secret below the minimum length")
+	}
+	if _, err := authorizer.verifier.Verify(sign(oldSecret)); err != nil {
+		t.Fatalf("Verify(old secret) after rejected rotation error = %v", err)
+	}
+	if err := bus.Publish(ctx, config.SecretRotation{Key: "JWTSharedSecret", Value: ""}); err == nil {
+		t.Fatal("Publish() accepted a rotation leaving no verification key")
+	}
+
+	if err := bus.Publish(ctx, config.SecretRotation{Key: "JWTSharedSecret", Value: newSecret}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if claims, err := authorizer.verifier.Verify(sign(newSecret)); err != nil || claims.Subject != "tooling" {
+		t.Fatalf("Verify(new secret) claims = %#v err = %v", claims, err)
+	}
+	if _, err := authorizer.verifier.Verify(sign(oldSecret)); err == nil {
+		t.Fatal("Verify() accepted a token signed with the rotated-out secret")
+	}
+}
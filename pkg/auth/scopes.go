@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ScopePermissions maps OAuth scopes to the internal permission codes they
+// grant, e.g. {"orders:read": ["PMS-ORD-READ", "PMS-ORD-LIST"]}. With it the
+// permission middlewares accept standards-based tokens (Keycloak or any other
+// OIDC provider) that carry scopes instead of Sentinel svc_perm bitmasks.
+type ScopePermissions map[string][]string
+
+// ParseScopePermissions parses a JSON object of scope to permission codes,
+// the format of the ScopePermissionMapping config key. An empty string yields
+// a nil mapping.
+func ParseScopePermissions(raw string) (ScopePermissions, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var mapping map[string][]string
+	if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+		return nil, fmt.Errorf("jwt authorizer: parse ScopePermissionMapping: %w", err)
+	}
+	return normalizeScopePermissions(mapping), nil
+}
+
+// Permissions lists, sorted, the permission codes granted by scopes.
+func (m ScopePermissions) Permissions(scopes []string) []string {
+	seen := make(map[string]bool)
+	for _, scope := range scopes {
+		for _, code := range m[strings.TrimSpace(scope)] {
+			seen[code] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// Grants reports whether any scope of claims maps to code.
+func (m ScopePermissions) Grants(claims Claims, code string) bool {
+	code = strings.TrimSpace(code)
+	for _, scope := range claims.Scopes() {
+		for _, granted := range m[scope] {
+			if granted == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UseScopePermissions makes the permission middlewares check scoped tokens
+// (see Claims.IsScopedToken) against mapping instead of svc_perm bitmasks or
+// the permission decision service. Scope grants apply in the caller's home
+// tenant only. It replaces any mapping read from the ScopePermissionMapping
+// config key and returns the receiver so calls can be chained.
+func (a *Authorizer) UseScopePermissions(mapping ScopePermissions) *Authorizer {
+	a.scopePermissions = normalizeScopePermissions(mapping)
+	return a
+}
+
+// scopeGrants checks code against the scope mapping for a scoped token.
+func (a *Authorizer) scopeGrants(claims Claims, code, tenantID string) bool {
+	if tenantID != "" && tenantID != claims.TenantID() {
+		return false
+	}
+	return a.scopePermissions.Grants(claims, code)
+}
+
+// Scopes returns the OAuth scopes of the token: the space-delimited "scope"
+// claim (RFC 9068), or "scp" as a string or list as issued by some providers.
+func (c Claims) Scopes() []string {
+	if c.Raw == nil {
+		return nil
+	}
+	if scope, ok := c.Raw["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+	switch scp := c.Raw["scp"].(type) {
+	case string:
+		return strings.Fields(scp)
+	case []any:
+		scopes := make([]string, 0, len(scp))
+		for _, item := range scp {
+			if scope, ok := item.(string); ok && strings.TrimSpace(scope) != "" {
+				scopes = append(scopes, strings.TrimSpace(scope))
+			}
+		}
+		return scopes
+	case []string:
+		return scp
+	}
+	return nil
+}
+
+// HasScope reports whether the token carries scope.
+func (c Claims) HasScope(scope string) bool {
+	scope = strings.TrimSpace(scope)
+	for _, granted := range c.Scopes() {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsScopedToken reports whether the token authorizes through OAuth scopes
+// rather than Sentinel permissions: it carries scopes and no svc_perm claim.
+func (c Claims) IsScopedToken() bool {
+	if _, ok := c.Raw["svc_perm"]; ok {
+		return false
+	}
+	return len(c.Scopes()) > 0
+}
+
+func normalizeScopePermissions(mapping map[string][]string) ScopePermissions {
+	if mapping == nil {
+		return nil
+	}
+	out := make(ScopePermissions, len(mapping))
+	for scope, codes := range mapping {
+		scope = strings.TrimSpace(scope)
+		if scope == "" {
+			continue
+		}
+		seen := make(map[string]bool, len(codes))
+		for _, code := range codes {
+			if code = strings.TrimSpace(code); code != "" {
+				seen[code] = true
+			}
+		}
+		out[scope] = sortedKeys(seen)
+	}
+	return out
+}
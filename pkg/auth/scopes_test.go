@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/permissions"
+)
+
+func TestRequirePermissionMapsScopesToPermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	privateKey, publicKeyPEM := testKeyPair(t)
+	store := permissions.NewStore(nil)
+	store.Replace(map[string]permissions.Metadata{
+		"ORD-ORDERS-LIST":   {Service: "ord", BitValue: 0},
+		"ORD-ORDERS-DELETE": {Service: "ord", BitValue: 1},
+	})
+	authorizer, err := NewAuthorizerWithStore(stubConfig{
+		"RSAPublicKey":           publicKeyPEM,
+		"ScopePermissionMapping": `{"orders:read": ["ORD-ORDERS-LIST"], "orders:admin": ["ORD-ORDERS-LIST", "ORD-ORDERS-DELETE"]}`,
+	}, logger.MustNewDefaultLogger(), store)
+	if err != nil {
+		t.Fatalf("NewAuthorizerWithStore() error = %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/orders", authorizer.RequirePermission("ORD-ORDERS-LIST"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.DELETE("/orders", authorizer.RequirePermission("ORD-ORDERS-DELETE"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.DELETE("/tenants/:tenant_id/orders", authorizer.RequirePermissionInTenant("ORD-ORDERS-DELETE", TenantFromParam("tenant_id")), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	scoped := signTestToken(t, privateKey, jwt.MapClaims{"sub": "kc-user", "tenant_id": "t-1", "scope": "openid orders:read"})
+	admin := signTestToken(t, privateKey, jwt.MapClaims{"sub": "kc-admin", "tenant_id": "t-1", "scp": []any{"orders:admin"}})
+	sentinel := signTestToken(t, privateKey, jwt.MapClaims{"sub": "user-1", "scope": "orders:admin", "svc_perm": "ord:" + strconv.FormatInt(1<<0, 36)})
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"scope grants permission", http.MethodGet, "/orders", scoped, http.StatusNoContent},
+		{"scope lacks permission", http.MethodDelete, "/orders", scoped, http.StatusForbidden},
+		{"scp list grants permission", http.MethodDelete, "/orders", admin, http.StatusNoContent},
+		{"home tenant", http.MethodDelete, "/tenants/t-1/orders", admin, http.StatusNoContent},
+		{"other tenant", http.MethodDelete, "/tenants/t-2/orders", admin, http.StatusForbidden},
+		{"sentinel token ignores scopes", http.MethodDelete, "/orders", sentinel, http.StatusForbidden},
+		{"sentinel token uses bitmask", http.MethodGet, "/orders", sentinel, http.StatusNoContent},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			if recorder.Code != tc.want {
+				t.Fatalf("status = %d, want %d; body=%s", recorder.Code, tc.want, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestScopePermissions(t *testing.T) {
+	if _, err := ParseScopePermissions(`["orders:read"]`); err == nil {
+		t.Fatal("ParseScopePermissions accepted a JSON array")
+	}
+
+	mapping := ScopePermissions{" orders:read ": {"ORD-ORDERS-LIST", " ORD-ORDERS-LIST"}, "orders:write": {"ORD-ORDERS-CREATE"}}
+	authorizer := (&Authorizer{}).UseScopePermissions(mapping)
+	if got := authorizer.scopePermissions.Permissions([]string{"orders:read", "orders:write", "profile"}); len(got) != 2 || got[0] != "ORD-ORDERS-CREATE" || got[1] != "ORD-ORDERS-LIST" {
+		t.Fatalf("Permissions() = %v", got)
+	}
+
+	claims := Claims{Raw: map[string]any{"scope": "orders:read"}}
+	if !claims.IsScopedToken() || !claims.HasScope("orders:read") || claims.HasScope("orders:write") {
+		t.Fatalf("scopes = %v", claims.Scopes())
+	}
+	if !authorizer.scopePermissions.Grants(claims, "ORD-ORDERS-LIST") || authorizer.scopePermissions.Grants(claims, "ORD-ORDERS-CREATE") {
+		t.Fatal("Grants() did not follow the mapping")
+	}
+}
@@ -0,0 +1,442 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/milan604/core-lab/pkg/logger"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// DefaultAccessTokenCookie and DefaultRefreshTokenCookie are the cookie
+	// names CookieTokenStore uses by default.
+	DefaultAccessTokenCookie  = "access_token"
+	DefaultRefreshTokenCookie = "refresh_token"
+
+	defaultSessionRefreshBefore = time.Minute
+	defaultSessionReuseWindow   = 10 * time.Second
+)
+
+// ErrRefreshTokenInvalid is returned by a TokenRefresher when the identity
+// provider rejects the refresh token (expired, revoked, or already rotated).
+// RequireSession then clears the session instead of retrying.
+var ErrRefreshTokenInvalid = errors.New("refresh token is invalid")
+
+// SessionTokens are the tokens a browser session holds.
+type SessionTokens struct {
+	AccessToken  string
+	RefreshToken string
+	// ExpiresAt is when AccessToken expires. When zero, the token's exp claim
+	// is used.
+	ExpiresAt time.Time
+	// RefreshExpiresAt is when RefreshToken expires, if the provider says.
+	RefreshExpiresAt time.Time
+}
+
+// TokenRefresher exchanges a refresh token for a new token pair. Providers
+// that rotate refresh tokens return the new one; otherwise RefreshToken may be
+// left empty and the presented one is kept.
+type TokenRefresher interface {
+	RefreshTokens(ctx context.Context, refreshToken string) (SessionTokens, error)
+}
+
+// TokenRefresherFunc adapts a function to TokenRefresher.
+type TokenRefresherFunc func(ctx context.Context, refreshToken string) (SessionTokens, error)
+
+// RefreshTokens implements TokenRefresher.
+func (f TokenRefresherFunc) RefreshTokens(ctx context.Context, refreshToken string) (SessionTokens, error) {
+	return f(ctx, refreshToken)
+}
+
+// SessionTokenStore loads and saves the tokens of the current browser session.
+type SessionTokenStore interface {
+	// Load returns the session's tokens, or false when there is no session.
+	Load(c *gin.Context) (SessionTokens, bool)
+	// Save stores rotated tokens, e.g. by setting cookies on the response.
+	Save(c *gin.Context, tokens SessionTokens) error
+	// Clear ends the session.
+	Clear(c *gin.Context)
+}
+
+// CookieTokenStore keeps the session's tokens in HttpOnly cookies.
+type CookieTokenStore struct {
+	// AccessCookie and RefreshCookie name the cookies. Defaults:
+	// DefaultAccessTokenCookie and DefaultRefreshTokenCookie.
+	AccessCookie  string
+	RefreshCookie string
+	// Path defaults to "/".
+	Path   string
+	Domain string
+	// Insecure drops the Secure attribute, for local development over HTTP.
+	Insecure bool
+	// SameSite defaults to http.SameSiteLaxMode.
+	SameSite http.SameSite
+}
+
+// Load implements SessionTokenStore.
+func (s CookieTokenStore) Load(c *gin.Context) (SessionTokens, bool) {
+	var tokens SessionTokens
+	if cookie, err := c.Request.Cookie(s.accessCookie()); err == nil {
+		tokens.AccessToken = cookie.Value
+	}
+	if cookie, err := c.Request.Cookie(s.refreshCookie()); err == nil {
+		tokens.RefreshToken = cookie.Value
+	}
+	return tokens, tokens.AccessToken != "" || tokens.RefreshToken != ""
+}
+
+// Save implements SessionTokenStore. The access cookie expires with the
+// access token; the refresh cookie with the refresh token, or at the end of
+// the browser session when its expiry is unknown.
+func (s CookieTokenStore) Save(c *gin.Context, tokens SessionTokens) error {
+	http.SetCookie(c.Writer, s.cookie(s.accessCookie(), tokens.AccessToken, sessionTokenExpiry(tokens)))
+	if tokens.RefreshToken != "" {
+		http.SetCookie(c.Writer, s.cookie(s.refreshCookie(), tokens.RefreshToken, tokens.RefreshExpiresAt))
+	}
+	return nil
+}
+
+// Clear implements SessionTokenStore by expiring both cookies.
+func (s CookieTokenStore) Clear(c *gin.Context) {
+	for _, name := range []string{s.accessCookie(), s.refreshCookie()} {
+		cookie := s.cookie(name, "", time.Time{})
+		cookie.MaxAge = -1
+		http.SetCookie(c.Writer, cookie)
+	}
+}
+
+func (s CookieTokenStore) cookie(name, value string, expires time.Time) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     s.Path,
+		Domain:   s.Domain,
+		Secure:   !s.Insecure,
+		HttpOnly: true,
+		SameSite: s.SameSite,
+	}
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	if !expires.IsZero() {
+		cookie.Expires = expires
+		cookie.MaxAge = int(time.Until(expires).Seconds())
+		if cookie.MaxAge <= 0 {
+			cookie.MaxAge = -1
+		}
+	}
+	return cookie
+}
+
+func (s CookieTokenStore) accessCookie() string {
+	if s.AccessCookie != "" {
+		return s.AccessCookie
+	}
+	return DefaultAccessTokenCookie
+}
+
+func (s CookieTokenStore) refreshCookie() string {
+	if s.RefreshCookie != "" {
+		return s.RefreshCookie
+	}
+	return DefaultRefreshTokenCookie
+}
+
+// SessionConfig configures RequireSession.
+type SessionConfig struct {
+	Refresher TokenRefresher
+	// Store defaults to a CookieTokenStore with default settings.
+	Store SessionTokenStore
+	// RefreshBefore refreshes access tokens this close to expiry. Default: 1m.
+	RefreshBefore time.Duration
+	// ReuseWindow is how long a refresh result is reused for the same
+	// refresh token. Parallel requests an SPA sent with the old cookies then
+	// share one rotation instead of replaying a rotated refresh token, and a
+	// rejected refresh token is not retried against the provider. Default: 10s.
+	ReuseWindow time.Duration
+	// Optional lets requests without a session continue unauthenticated.
+	Optional bool
+	Logger   logger.LogManager
+}
+
+// RequireSession authenticates browser requests from a stored session for
+// backend-for-frontend services. Access tokens close to expiry are refreshed
+// with the stored refresh token, rotated tokens are saved back (as cookies by
+// default), and the verified claims are stored in the request context, so the
+// SPA never handles token lifetimes itself.
+//
+//	router.Use(authorizer.RequireSession(auth.SessionConfig{
+//		Refresher: auth.NewOIDCTokenRefresher(auth.OIDCTokenRefresherConfig{
+//			TokenEndpoint: jwtCfg.TokenEndpoint(),
+//			ClientID:      "web-bff",
+//			ClientSecret:  secret,
+//		}),
+//	}))
+//
+// Refreshes are coalesced per refresh token. When the provider rejects the
+// refresh token the session is cleared and the request fails with 401; when
+// the provider is unreachable the current access token is used until it
+// expires, after which requests fail with 503.
+func (a *Authorizer) RequireSession(cfg SessionConfig) gin.HandlerFunc {
+	if cfg.Store == nil {
+		cfg.Store = CookieTokenStore{}
+	}
+	if cfg.RefreshBefore <= 0 {
+		cfg.RefreshBefore = defaultSessionRefreshBefore
+	}
+	if cfg.ReuseWindow <= 0 {
+		cfg.ReuseWindow = defaultSessionReuseWindow
+	}
+	refreshes := newSessionRefreshes(cfg.Refresher, cfg.ReuseWindow)
+
+	return func(c *gin.Context) {
+		if _, ok := GetClaims(c); ok {
+			c.Next()
+			return
+		}
+
+		log := logger.GetLogger(c)
+		if log == nil {
+			log = cfg.Logger
+		}
+		if log == nil {
+			log = a.log
+		}
+
+		tokens, ok := cfg.Store.Load(c)
+		if !ok {
+			if cfg.Optional {
+				c.Next()
+				return
+			}
+			a.abortWithJSON(c, http.StatusUnauthorized, "session_required", "authentication required", log)
+			return
+		}
+
+		now := time.Now()
+		expiresAt := sessionTokenExpiry(tokens)
+		if tokens.RefreshToken != "" && (tokens.AccessToken == "" || now.Add(cfg.RefreshBefore).After(expiresAt)) {
+			refreshed, err := refreshes.refresh(c.Request.Context(), tokens.RefreshToken)
+			switch {
+			case err == nil:
+				if saveErr := cfg.Store.Save(c, refreshed); saveErr != nil {
+					log.ErrorFCtx(c.Request.Context(), "Failed to save refreshed session tokens: %v", saveErr)
+				}
+				tokens = refreshed
+			case errors.Is(err, ErrRefreshTokenInvalid):
+				log.WarnFCtx(c.Request.Context(), "Session refresh token rejected: %v", err)
+				cfg.Store.Clear(c)
+				a.abortWithJSON(c, http.StatusUnauthorized, "session_expired", "session has expired", log)
+				return
+			case tokens.AccessToken != "" && now.Before(expiresAt):
+				log.WarnFCtx(c.Request.Context(), "Session token refresh failed, using current access token: %v", err)
+			default:
+				log.ErrorFCtx(c.Request.Context(), "Session token refresh failed: %v", err)
+				a.abortWithJSON(c, http.StatusServiceUnavailable, "token_refresh_unavailable", "session could not be refreshed", log)
+				return
+			}
+		}
+		if tokens.AccessToken == "" {
+			cfg.Store.Clear(c)
+			a.abortWithJSON(c, http.StatusUnauthorized, "session_expired", "session has expired", log)
+			return
+		}
+
+		if _, err := a.authenticateToken(c, tokens.AccessToken, log); err != nil {
+			a.abortAuthError(c, err, log)
+			return
+		}
+		c.Next()
+	}
+}
+
+// sessionTokenExpiry returns tokens.ExpiresAt, falling back to the access
+// token's exp claim. Tokens without either are treated as expired.
+func sessionTokenExpiry(tokens SessionTokens) time.Time {
+	if !tokens.ExpiresAt.IsZero() {
+		return tokens.ExpiresAt
+	}
+	if tokens.AccessToken == "" {
+		return time.Time{}
+	}
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokens.AccessToken, claims); err != nil {
+		return time.Time{}
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return time.Time{}
+	}
+	return exp.Time
+}
+
+// sessionRefreshes coalesces refreshes of the same refresh token and reuses
+// their result for the reuse window.
+type sessionRefreshes struct {
+	refresher TokenRefresher
+	window    time.Duration
+	group     singleflight.Group
+
+	mu      sync.Mutex
+	results map[string]sessionRefreshResult
+}
+
+type sessionRefreshResult struct {
+	tokens    SessionTokens
+	err       error
+	expiresAt time.Time
+}
+
+func newSessionRefreshes(refresher TokenRefresher, window time.Duration) *sessionRefreshes {
+	return &sessionRefreshes{refresher: refresher, window: window, results: map[string]sessionRefreshResult{}}
+}
+
+func (r *sessionRefreshes) refresh(ctx context.Context, refreshToken string) (SessionTokens, error) {
+	if r.refresher == nil {
+		return SessionTokens{}, fmt.Errorf("session: no token refresher configured")
+	}
+	sum := sha256.Sum256([]byte(refreshToken))
+	key := hex.EncodeToString(sum[:])
+
+	now := time.Now()
+	r.mu.Lock()
+	if result, ok := r.results[key]; ok && now.Before(result.expiresAt) {
+		r.mu.Unlock()
+		return result.tokens, result.err
+	}
+	r.mu.Unlock()
+
+	value, err, _ := r.group.Do(key, func() (interface{}, error) {
+		// The refresh outlives any one request: other requests wait on it.
+		tokens, err := r.refresher.RefreshTokens(context.WithoutCancel(ctx), refreshToken)
+		if err == nil && tokens.RefreshToken == "" {
+			tokens.RefreshToken = refreshToken
+		}
+		if err == nil || errors.Is(err, ErrRefreshTokenInvalid) {
+			r.remember(key, sessionRefreshResult{tokens: tokens, err: err, expiresAt: time.Now().Add(r.window)})
+		}
+		return tokens, err
+	})
+	tokens, _ := value.(SessionTokens)
+	return tokens, err
+}
+
+func (r *sessionRefreshes) remember(key string, result sessionRefreshResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for k, existing := range r.results {
+		if !now.Before(existing.expiresAt) {
+			delete(r.results, k)
+		}
+	}
+	r.results[key] = result
+}
+
+// OIDCTokenRefresherConfig configures NewOIDCTokenRefresher.
+type OIDCTokenRefresherConfig struct {
+	// TokenEndpoint is the provider's token endpoint, e.g. JWTConfig.TokenEndpoint().
+	TokenEndpoint string
+	ClientID      string
+	// ClientSecret is sent with HTTP basic auth; leave empty for public clients.
+	ClientSecret string
+	// Scopes, when set, are requested on refresh.
+	Scopes     []string
+	HTTPClient *http.Client
+}
+
+// OIDCTokenRefresher refreshes tokens with the OAuth 2.0 refresh_token grant
+// (RFC 6749 §6).
+type OIDCTokenRefresher struct {
+	cfg OIDCTokenRefresherConfig
+}
+
+// NewOIDCTokenRefresher returns a TokenRefresher posting to cfg.TokenEndpoint.
+func NewOIDCTokenRefresher(cfg OIDCTokenRefresherConfig) *OIDCTokenRefresher {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: defaultJWKSHTTPTimeout}
+	}
+	return &OIDCTokenRefresher{cfg: cfg}
+}
+
+// RefreshTokens implements TokenRefresher. invalid_grant responses are
+// reported as ErrRefreshTokenInvalid.
+func (r *OIDCTokenRefresher) RefreshTokens(ctx context.Context, refreshToken string) (SessionTokens, error) {
+	if r.cfg.TokenEndpoint == "" {
+		return SessionTokens{}, fmt.Errorf("oidc: token endpoint is required")
+	}
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	}
+	if r.cfg.ClientSecret == "" {
+		form.Set("client_id", r.cfg.ClientID)
+	}
+	if len(r.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(r.cfg.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("oidc: build refresh request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if r.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(r.cfg.ClientID), url.QueryEscape(r.cfg.ClientSecret))
+	}
+
+	resp, err := r.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("oidc: refresh tokens: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		RefreshToken     string `json:"refresh_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		RefreshExpiresIn int64  `json:"refresh_expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		if body.Error == "invalid_grant" {
+			return SessionTokens{}, fmt.Errorf("%w: %s", ErrRefreshTokenInvalid, body.ErrorDescription)
+		}
+		return SessionTokens{}, fmt.Errorf("oidc: token endpoint returned status %d: %s", resp.StatusCode, body.Error)
+	}
+	if decodeErr != nil {
+		return SessionTokens{}, fmt.Errorf("oidc: decode token response: %w", decodeErr)
+	}
+	if body.AccessToken == "" {
+		return SessionTokens{}, fmt.Errorf("oidc: token response has no access_token")
+	}
+
+	now := time.Now()
+	tokens := SessionTokens{AccessToken: body.AccessToken, RefreshToken: body.RefreshToken}
+	if body.ExpiresIn > 0 {
+		tokens.ExpiresAt = now.Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	if body.RefreshExpiresIn > 0 {
+		tokens.RefreshExpiresAt = now.Add(time.Duration(body.RefreshExpiresIn) * time.Second)
+	}
+	return tokens, nil
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRequireSessionRefreshesNearExpiryTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	privateKey, publicKeyPEM := testKeyPair(t)
+	authorizer := testAuthorizer(t, stubConfig{"RSAPublicKey": publicKeyPEM})
+
+	var calls atomic.Int32
+	refresher := TokenRefresherFunc(func(_ context.Context, refreshToken string) (SessionTokens, error) {
+		calls.Add(1)
+		switch refreshToken {
+		case "refresh-1":
+			return SessionTokens{
+				AccessToken:  signTestToken(t, privateKey, jwt.MapClaims{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()}),
+				RefreshToken: "refresh-2",
+			}, nil
+		case "refresh-down":
+			return SessionTokens{}, errors.New("provider unavailable")
+		default:
+			return SessionTokens{}, ErrRefreshTokenInvalid
+		}
+	})
+
+	router := gin.New()
+	router.GET("/me", authorizer.RequireSession(SessionConfig{Refresher: refresher}), func(c *gin.Context) {
+		claims, _ := GetClaims(c)
+		c.String(http.StatusOK, claims.Subject)
+	})
+
+	do := func(access, refresh string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		if access != "" {
+			req.AddCookie(&http.Cookie{Name: DefaultAccessTokenCookie, Value: access})
+		}
+		if refresh != "" {
+			req.AddCookie(&http.Cookie{Name: DefaultRefreshTokenCookie, Value: refresh})
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+	cookies := func(recorder *httptest.ResponseRecorder) map[string]*http.Cookie {
+		out := map[string]*http.Cookie{}
+		for _, cookie := range recorder.Result().Cookies() {
+			out[cookie.Name] = cookie
+		}
+		return out
+	}
+
+	fresh := signTestToken(t, privateKey, jwt.MapClaims{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()})
+	if recorder := do(fresh, "refresh-1"); recorder.Code != http.StatusOK || len(recorder.Result().Cookies()) != 0 || calls.Load() != 0 {
+		t.Fatalf("fresh token: status = %d cookies = %d refreshes = %d", recorder.Code, len(recorder.Result().Cookies()), calls.Load())
+	}
+
+	nearExpiry := signTestToken(t, privateKey, jwt.MapClaims{"sub": "user-1", "exp": time.Now().Add(30 * time.Second).Unix()})
+	recorder := do(nearExpiry, "refresh-1")
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "user-1" {
+		t.Fatalf("near expiry: status = %d body = %s", recorder.Code, recorder.Body.String())
+	}
+	set := cookies(recorder)
+	if set[DefaultRefreshTokenCookie] == nil || set[DefaultRefreshTokenCookie].Value != "refresh-2" {
+		t.Fatalf("rotated refresh cookie not set: %v", set)
+	}
+	access := set[DefaultAccessTokenCookie]
+	if access == nil || access.Value == nearExpiry || !access.HttpOnly || !access.Secure || access.SameSite != http.SameSiteLaxMode || access.Expires.IsZero() {
+		t.Fatalf("access cookie = %+v", access)
+	}
+
+	// The browser dropped the expired access cookie; a parallel request still
+	// carrying the old refresh token reuses the rotation.
+	if recorder := do("", "refresh-1"); recorder.Code != http.StatusOK || cookies(recorder)[DefaultRefreshTokenCookie].Value != "refresh-2" {
+		t.Fatalf("reused rotation: status = %d", recorder.Code)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("refreshes = %d, want 1", got)
+	}
+
+	recorder = do("", "refresh-revoked")
+	if recorder.Code != http.StatusUnauthorized || !strings.Contains(recorder.Body.String(), "session_expired") {
+		t.Fatalf("rejected refresh: status = %d body = %s", recorder.Code, recorder.Body.String())
+	}
+	if cleared := cookies(recorder)[DefaultRefreshTokenCookie]; cleared == nil || cleared.MaxAge != -1 {
+		t.Fatalf("session not cleared: %+v", cleared)
+	}
+
+	if recorder := do(nearExpiry, "refresh-down"); recorder.Code != http.StatusOK {
+		t.Fatalf("provider down with valid token: status = %d, want 200", recorder.Code)
+	}
+	if recorder := do("", "refresh-down"); recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("provider down without token: status = %d, want 503", recorder.Code)
+	}
+	if recorder := do("", ""); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("no session: status = %d, want 401", recorder.Code)
+	}
+}
+
+func TestRequireSessionCoalescesConcurrentRefreshes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	privateKey, publicKeyPEM := testKeyPair(t)
+	authorizer := testAuthorizer(t, stubConfig{"RSAPublicKey": publicKeyPEM})
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	refresher := TokenRefresherFunc(func(context.Context, string) (SessionTokens, error) {
+		calls.Add(1)
+		<-release
+		return SessionTokens{
+			AccessToken:  signTestToken(t, privateKey, jwt.MapClaims{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()}),
+			RefreshToken: "refresh-2",
+		}, nil
+	})
+	router := gin.New()
+	router.GET("/me", authorizer.RequireSession(SessionConfig{Refresher: refresher}), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	var wg sync.WaitGroup
+	codes := make([]int, 5)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.AddCookie(&http.Cookie{Name: DefaultRefreshTokenCookie, Value: "refresh-1"})
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			codes[i] = recorder.Code
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusNoContent {
+			t.Fatalf("request %d status = %d", i, code)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("refreshes = %d, want 1", got)
+	}
+}
+
+func TestOIDCTokenRefresher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		user, pass, _ := r.BasicAuth()
+		if r.Form.Get("grant_type") != "refresh_token" || user != "web-bff" || pass != "s3cret" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.Form.Get("refresh_token") != "refresh-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"token rotated"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"access-2","refresh_token":"refresh-2","expires_in":300,"refresh_expires_in":1800}`))
+	}))
+	defer server.Close()
+
+	refresher := NewOIDCTokenRefresher(OIDCTokenRefresherConfig{TokenEndpoint: server.URL, ClientID: "web-bff", ClientSecret: "s3cret"})
+	tokens, err := refresher.RefreshTokens(context.Background(), "refresh-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens.AccessToken != "access-2" || tokens.RefreshToken != "refresh-2" ||
+		time.Until(tokens.ExpiresAt) <= 4*time.Minute || time.Until(tokens.RefreshExpiresAt) <= 29*time.Minute {
+		t.Fatalf("tokens = %+v", tokens)
+	}
+
+	if _, err := refresher.RefreshTokens(context.Background(), "refresh-1-reused"); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("invalid_grant error = %v", err)
+	}
+	wrongClient := NewOIDCTokenRefresher(OIDCTokenRefresherConfig{TokenEndpoint: server.URL, ClientID: "web-bff"})
+	if _, err := wrongClient.RefreshTokens(context.Background(), "refresh-1"); err == nil || errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("invalid_client error = %v", err)
+	}
+}
@@ -25,7 +25,8 @@ type TenantAccessConfig struct {
 
 // DefaultTenantAccessConfig preserves the existing permissive route helper behaviour:
 // service tokens are allowed, platform users may pass explicit tenant IDs, and lifecycle
-// checks apply when a tenant-scoped user token is present.
+// checks apply when a tenant-scoped user token is present. Service tokens only
+// name tenants they hold grants in unless they carry ScopeCrossTenant.
 func DefaultTenantAccessConfig() TenantAccessConfig {
 	return TenantAccessConfig{
 		AllowServiceTokens:      true,
@@ -131,6 +132,10 @@ func ResolveTenantUserScope(c *gin.Context, tenantID *string, userID *string, cf
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "tenant_scope_required"})
 			return nil, nil, false
 		}
+		if requestedTenantID != "" && !claims.serviceCanActInTenant(requestedTenantID) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "tenant_scope_mismatch"})
+			return nil, nil, false
+		}
 		if requestedTenantID != "" {
 			SetTenantID(c, requestedTenantID)
 		}
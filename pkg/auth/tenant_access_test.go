@@ -154,3 +154,33 @@ func TestTenantAccessMiddlewareSeedsResolvedUserContext(t *testing.T) {
 		t.Fatalf("status = %d, want %d; body=%s", recorder.Code, http.StatusNoContent, recorder.Body.String())
 	}
 }
+
+func TestResolveTenantScopeLimitsServiceTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name   string
+		claims Claims
+		want   int
+	}{
+		{name: "no grant in the tenant", claims: Claims{TokenUse: "service"}, want: http.StatusForbidden},
+		{name: "own tenant", claims: Claims{TokenUse: "service", Raw: map[string]any{"tenant_id": "tenant-b"}}, want: http.StatusNoContent},
+		{name: "cross-tenant scope", claims: Claims{TokenUse: "service", Raw: map[string]any{"scp": []any{"service", ScopeCrossTenant}}}, want: http.StatusNoContent},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/tenants/:tenant_id", func(c *gin.Context) {
+				SetClaims(c, tc.claims)
+				if _, ok := ResolveTenantScope(c, "", DefaultTenantAccessConfig().WithTenantPathParam("tenant_id")); ok {
+					c.Status(http.StatusNoContent)
+				}
+			})
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/tenants/tenant-b", nil))
+			if recorder.Code != tc.want {
+				t.Fatalf("status = %d, want %d; body=%s", recorder.Code, tc.want, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+func TestTenantPrefixedServicePermissions(t *testing.T) {
+	read, export := strconv.FormatInt(1<<0, 36), strconv.FormatInt(1<<0|1<<1, 36)
+	claims := mapClaimsToAuthClaims(jwt.MapClaims{
+		"sub":      "user-1",
+		"org_id":   "Tenant-A",
+		"svc_perm": "ord:" + read + ";Tenant-B/ord:" + export + ";/ord:" + export,
+	})
+
+	if claims.TenantID() != "Tenant-A" {
+		t.Fatalf("TenantID() = %q, want org_id fallback", claims.TenantID())
+	}
+	if !claims.HasPermission("ord", 0) || claims.HasPermission("ord", 1) {
+		t.Fatalf("unscoped permissions = %v", claims.ServicePermissions)
+	}
+	if !claims.HasPermissionInTenant("Tenant-A", "ORD", 0) || claims.HasPermissionInTenant("Tenant-A", "ord", 1) {
+		t.Fatal("home tenant should use unscoped grants")
+	}
+	if !claims.HasPermissionInTenant("Tenant-B", "ord", 1) {
+		t.Fatal("expected scoped grant in Tenant-B")
+	}
+	if claims.HasPermissionInTenant("Tenant-C", "ord", 0) {
+		t.Fatal("unexpected grant in Tenant-C")
+	}
+	if got := strings.Join(claims.AccessibleTenants(), ","); got != "Tenant-A,Tenant-B" {
+		t.Fatalf("AccessibleTenants() = %q", got)
+	}
+	if got := strings.Join(claims.TenantsWithPermission("ord", 1), ","); got != "Tenant-B" {
+		t.Fatalf("TenantsWithPermission() = %q", got)
+	}
+	if !claims.CanAccessTenant("Tenant-B") || claims.CanAccessTenant("Tenant-C") {
+		t.Fatal("CanAccessTenant mismatch")
+	}
+}
+
+func TestRequirePermissionInTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authorizer := &Authorizer{log: logger.MustNewDefaultLogger()}
+	lookup := stubPermissionLookup{"ORD-ORDERS-LIST": {Service: "ord", BitValue: 1}}
+	claims := Claims{
+		Subject:           "user-1",
+		Raw:               map[string]any{"tenant_id": "tenant-a"},
+		TenantPermissions: map[string]map[string][]int64{"tenant-b": {"ord": {1 << 1}}},
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		SetClaims(c, claims)
+		c.Set(string(CtxMiddlewareServiceKey), PermissionLookup(lookup))
+	})
+	handler := func(c *gin.Context) {
+		tenantID, _ := GetTenantID(c)
+		c.String(http.StatusOK, tenantID)
+	}
+	router.GET("/tenants/:tenant_id/orders", authorizer.RequirePermissionInTenant("ORD-ORDERS-LIST"), handler)
+	router.GET("/orders", authorizer.RequirePermissionInTenant("ORD-ORDERS-LIST"), handler)
+
+	cases := []struct {
+		name, path, header string
+		want               int
+		wantBody           string
+	}{
+		{name: "scoped grant", path: "/tenants/tenant-b/orders", want: http.StatusOK, wantBody: "tenant-b"},
+		{name: "home tenant lacks grant", path: "/tenants/tenant-a/orders", want: http.StatusForbidden, wantBody: "permission_denied"},
+		{name: "header", path: "/orders", header: "tenant-b", want: http.StatusOK, wantBody: "tenant-b"},
+		{name: "missing tenant", path: "/orders", want: http.StatusForbidden, wantBody: "tenant_scope_required"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.header != "" {
+				req.Header.Set(HeaderTenantID, tc.header)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			if recorder.Code != tc.want || !strings.Contains(recorder.Body.String(), tc.wantBody) {
+				t.Fatalf("status = %d body = %q; want %d containing %q", recorder.Code, recorder.Body.String(), tc.want, tc.wantBody)
+			}
+		})
+	}
+}
+
+func TestRequirePermissionInTenantServiceTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authorizer := &Authorizer{log: logger.MustNewDefaultLogger(), bypassServiceTokenPermissions: true}
+	cases := []struct {
+		name   string
+		claims Claims
+		want   int
+		body   string
+	}{
+		{
+			name:   "bypass without a tenant grant",
+			claims: Claims{Subject: "svc-orders", TokenUse: "service"},
+			want:   http.StatusForbidden,
+			body:   "tenant_scope_mismatch",
+		},
+		{
+			name:   "bypass in its own tenant",
+			claims: Claims{Subject: "svc-orders", TokenUse: "service", Raw: map[string]any{"tenant_id": "tenant-b"}},
+			want:   http.StatusOK,
+			body:   "tenant-b",
+		},
+		{
+			name:   "bypass with a scoped grant",
+			claims: Claims{Subject: "svc-orders", TokenUse: "service", TenantPermissions: map[string]map[string][]int64{"tenant-b": {"ord": {1}}}},
+			want:   http.StatusOK,
+			body:   "tenant-b",
+		},
+		{
+			name:   "cross-tenant scope",
+			claims: Claims{Subject: "svc-orders", TokenUse: "service", Raw: map[string]any{"scope": "service " + ScopeCrossTenant}},
+			want:   http.StatusOK,
+			body:   "tenant-b",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) { SetClaims(c, tc.claims) })
+			router.GET("/orders", authorizer.RequirePermissionInTenant("ORD-ORDERS-LIST"), func(c *gin.Context) {
+				tenantID, _ := GetTenantID(c)
+				c.String(http.StatusOK, tenantID)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			req.Header.Set(HeaderTenantID, "tenant-b")
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			if recorder.Code != tc.want || !strings.Contains(recorder.Body.String(), tc.body) {
+				t.Fatalf("status = %d body = %q; want %d containing %q", recorder.Code, recorder.Body.String(), tc.want, tc.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+// Package blob defines a presigned-URL abstraction over object storage and
+// ready-made Gin handlers that let clients upload and download objects directly
+// from the store without proxying bytes through the service.
+//
+// Cloud adapters (S3, GCS, Azure Blob) implement Presigner; LocalStore is a
+// filesystem-backed implementation for development and tests.
+package blob
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when an object does not exist.
+	ErrNotFound = errors.New("blob: object not found")
+	// ErrInvalidSignature is returned for tampered or expired presigned URLs.
+	ErrInvalidSignature = errors.New("blob: invalid or expired signature")
+)
+
+// UploadConstraints are enforced by the store when a presigned upload is used.
+type UploadConstraints struct {
+	// ContentType is the exact Content-Type the client must send.
+	ContentType string
+	// MaxSize is the largest accepted body in bytes; zero means unlimited.
+	MaxSize int64
+	// Metadata is stored with the object, e.g. the owner's tenant and subject.
+	Metadata map[string]string
+}
+
+// PresignedURL is a time-limited URL the client uses directly against the store.
+type PresignedURL struct {
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// Presigner issues presigned URLs for object keys.
+type Presigner interface {
+	// PresignUpload returns a URL accepting an upload of key under constraints.
+	PresignUpload(ctx context.Context, key string, constraints UploadConstraints, ttl time.Duration) (PresignedURL, error)
+	// PresignDownload returns a URL serving key. It returns ErrNotFound when the
+	// store can tell the object is missing.
+	PresignDownload(ctx context.Context, key string, ttl time.Duration) (PresignedURL, error)
+}
@@ -0,0 +1,238 @@
+package blob
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/milan604/core-lab/pkg/apperr"
+	"github.com/milan604/core-lab/pkg/audit"
+	"github.com/milan604/core-lab/pkg/auth"
+	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/response"
+)
+
+// Audit actions emitted by the handlers.
+const (
+	AuditActionUploadURLIssued = "blob.upload_url.issued"
+	AuditActionDownload        = "blob.download"
+	auditResource              = "blob"
+)
+
+// Metadata keys recorded on uploaded objects.
+const (
+	MetadataOwner    = "owner"
+	MetadataTenantID = "tenant_id"
+	MetadataFilename = "filename"
+)
+
+// HandlerConfig configures RegisterRoutes.
+type HandlerConfig struct {
+	Presigner Presigner
+	// Service is reported as the audit event's service name.
+	Service string
+	// KeyPrefix is prepended to every generated key, e.g. "attachments".
+	KeyPrefix string
+	// AllowedContentTypes restricts uploads; empty allows any type.
+	AllowedContentTypes []string
+	// MaxSize caps uploads in bytes. Default: 25 MiB.
+	MaxSize int64
+	// UploadTTL and DownloadTTL bound presigned URL lifetimes. Default: 15m and 5m.
+	UploadTTL   time.Duration
+	DownloadTTL time.Duration
+	// AuthorizeDownload decides whether the caller may read key. Default: the key
+	// must sit under the caller's own tenant/owner prefix (see OwnerPrefix).
+	AuthorizeDownload func(c *gin.Context, key string) bool
+	// Audit receives upload and download events when set.
+	Audit  audit.Publisher
+	Logger logger.LogManager
+}
+
+// UploadRequest is the body of POST /uploads.
+type UploadRequest struct {
+	Filename    string `json:"filename" binding:"required"`
+	ContentType string `json:"content_type" binding:"required"`
+	Size        int64  `json:"size" binding:"required,gt=0"`
+}
+
+// UploadResponse describes an issued upload URL.
+type UploadResponse struct {
+	Key string `json:"key"`
+	PresignedURL
+}
+
+// RegisterRoutes mounts presigned upload and download routes on router. Mount it
+// behind authentication; uploads are bound to the caller's claims.
+//
+//	POST /uploads         {filename, content_type, size} -> 201 {key, url, method, headers, expires_at}
+//	GET  /downloads/*key  -> 302 to a presigned download URL
+func RegisterRoutes(router gin.IRoutes, cfg HandlerConfig) {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 25 << 20
+	}
+	if cfg.UploadTTL <= 0 {
+		cfg.UploadTTL = 15 * time.Minute
+	}
+	if cfg.DownloadTTL <= 0 {
+		cfg.DownloadTTL = 5 * time.Minute
+	}
+	if cfg.AuthorizeDownload == nil {
+		cfg.AuthorizeDownload = ownsKey(cfg.KeyPrefix)
+	}
+
+	router.POST("/uploads", cfg.issueUpload)
+	router.GET("/downloads/*key", cfg.redirectDownload)
+}
+
+func (cfg HandlerConfig) issueUpload(c *gin.Context) {
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		response.HandleError(c, apperr.New(apperr.ErrorCodeUnauthorized))
+		return
+	}
+
+	var req UploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.HandleError(c, apperr.New(apperr.ErrorCodeInvalidRequest).WithMessage(err.Error()))
+		return
+	}
+	contentType := strings.ToLower(strings.TrimSpace(req.ContentType))
+	if !cfg.contentTypeAllowed(contentType) {
+		response.HandleError(c, apperr.New(apperr.ErrorCodeInvalidInput).
+			WithMessage("content type is not allowed").
+			AddSuggestion("content_type", "use one of: "+strings.Join(cfg.AllowedContentTypes, ", ")))
+		return
+	}
+	if req.Size > cfg.MaxSize {
+		response.HandleError(c, apperr.New(apperr.ErrorCodeInvalidInput).
+			WithMessage("file is too large").
+			AddSuggestion("size", "maximum size is "+strconv.FormatInt(cfg.MaxSize, 10)+" bytes"))
+		return
+	}
+
+	filename := path.Base(strings.ReplaceAll(req.Filename, "\\", "/"))
+	key := OwnerPrefix(cfg.KeyPrefix, claims) + uuid.NewString() + strings.ToLower(path.Ext(filename))
+	presigned, err := cfg.Presigner.PresignUpload(c.Request.Context(), key, UploadConstraints{
+		ContentType: contentType,
+		MaxSize:     req.Size,
+		Metadata: map[string]string{
+			MetadataOwner:    claimsOwner(claims),
+			MetadataTenantID: claims.TenantID(),
+			MetadataFilename: filename,
+		},
+	}, cfg.UploadTTL)
+	if err != nil {
+		cfg.logError(c, "Presign upload failed (key=%s): %v", key, err)
+		response.HandleError(c, apperr.New(apperr.ErrorCodeInternal))
+		return
+	}
+
+	cfg.publish(c, AuditActionUploadURLIssued, key, "success", map[string]any{
+		"filename":     filename,
+		"content_type": contentType,
+		"size":         req.Size,
+	})
+	response.JSONSuccess(c, http.StatusCreated, UploadResponse{Key: key, PresignedURL: presigned}, nil)
+}
+
+func (cfg HandlerConfig) redirectDownload(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if key == "" {
+		response.HandleError(c, apperr.New(apperr.ErrorCodeNotFound))
+		return
+	}
+	if !cfg.AuthorizeDownload(c, key) {
+		cfg.publish(c, AuditActionDownload, key, "denied", nil)
+		response.HandleError(c, apperr.New(apperr.ErrorCodeForbidden))
+		return
+	}
+
+	presigned, err := cfg.Presigner.PresignDownload(c.Request.Context(), key, cfg.DownloadTTL)
+	if errors.Is(err, ErrNotFound) {
+		response.HandleError(c, apperr.New(apperr.ErrorCodeNotFound))
+		return
+	}
+	if err != nil {
+		cfg.logError(c, "Presign download failed (key=%s): %v", key, err)
+		response.HandleError(c, apperr.New(apperr.ErrorCodeInternal))
+		return
+	}
+
+	cfg.publish(c, AuditActionDownload, key, "success", nil)
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, presigned.URL)
+}
+
+// OwnerPrefix returns the key prefix uploads by claims are stored under:
+// "<prefix>/<tenant>/<owner>/". Platform-level callers use "_" as tenant.
+func OwnerPrefix(prefix string, claims auth.Claims) string {
+	tenantID := claims.TenantID()
+	if tenantID == "" {
+		tenantID = "_"
+	}
+	parts := []string{tenantID, claimsOwner(claims), ""}
+	if prefix = strings.Trim(prefix, "/"); prefix != "" {
+		parts = append([]string{prefix}, parts...)
+	}
+	return strings.Join(parts, "/")
+}
+
+func ownsKey(prefix string) func(*gin.Context, string) bool {
+	return func(c *gin.Context, key string) bool {
+		claims, ok := auth.GetClaims(c)
+		if !ok || claimsOwner(claims) == "" {
+			return false
+		}
+		return strings.HasPrefix(key, OwnerPrefix(prefix, claims)) && !strings.Contains(key, "..")
+	}
+}
+
+func claimsOwner(claims auth.Claims) string {
+	if claims.IdentityID != "" {
+		return claims.IdentityID
+	}
+	return claims.Subject
+}
+
+func (cfg HandlerConfig) contentTypeAllowed(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+	if len(cfg.AllowedContentTypes) == 0 {
+		return true
+	}
+	for _, allowed := range cfg.AllowedContentTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == contentType || (strings.HasSuffix(allowed, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(allowed, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cfg HandlerConfig) publish(c *gin.Context, action, key, status string, metadata map[string]any) {
+	if cfg.Audit == nil {
+		return
+	}
+	event := audit.NewEvent(c, cfg.Service, action, auditResource, key, status)
+	event.Metadata = metadata
+	if err := cfg.Audit.Publish(c.Request.Context(), event); err != nil {
+		cfg.logError(c, "Publish blob audit event failed (action=%s key=%s): %v", action, key, err)
+	}
+}
+
+func (cfg HandlerConfig) logError(c *gin.Context, format string, args ...any) {
+	log := logger.GetLogger(c)
+	if log == nil {
+		log = cfg.Logger
+	}
+	if log != nil {
+		log.ErrorFCtx(c.Request.Context(), format, args...)
+	}
+}
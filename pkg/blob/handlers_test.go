@@ -0,0 +1,204 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/milan604/core-lab/pkg/audit"
+	"github.com/milan604/core-lab/pkg/auth"
+)
+
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, event audit.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error { return nil }
+
+func newBlobTestServer(t *testing.T, publisher audit.Publisher) (*httptest.Server, *LocalStore) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	store, err := NewLocalStore(t.TempDir(), server.URL+"/blobs", bytes.Repeat([]byte("k"), 32))
+	if err != nil {
+		t.Fatalf("NewLocalStore() error = %v", err)
+	}
+	router.Any("/blobs/*key", gin.WrapH(store))
+
+	api := router.Group("/files", func(c *gin.Context) {
+		if subject := c.GetHeader("X-Test-Subject"); subject != "" {
+			c.Set(string(auth.CtxAuthClaims), auth.Claims{Subject: subject, Raw: map[string]any{"tenant_id": "tenant-1"}})
+		}
+		c.Next()
+	})
+	RegisterRoutes(api, HandlerConfig{
+		Presigner:           store,
+		Service:             "files",
+		KeyPrefix:           "attachments",
+		AllowedContentTypes: []string{"image/*", "application/pdf"},
+		MaxSize:             1024,
+		Audit:               publisher,
+	})
+	return server, store
+}
+
+func requestUpload(t *testing.T, server *httptest.Server, subject string, body UploadRequest) *http.Response {
+	t.Helper()
+	payload, _ := json.Marshal(body)
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/files/uploads", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-Subject", subject)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /uploads error = %v", err)
+	}
+	return resp
+}
+
+func TestUploadAndDownloadThroughPresignedURLs(t *testing.T) {
+	publisher := &recordingPublisher{}
+	server, store := newBlobTestServer(t, publisher)
+
+	resp := requestUpload(t, server, "user-1", UploadRequest{Filename: "../avatar.PNG", ContentType: "image/png", Size: 5})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("POST /uploads status = %d body = %s", resp.StatusCode, body)
+	}
+	var envelope struct {
+		Data UploadResponse `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		t.Fatalf("decode upload response: %v", err)
+	}
+	upload := envelope.Data
+	if !strings.HasPrefix(upload.Key, "attachments/tenant-1/user-1/") || !strings.HasSuffix(upload.Key, ".png") {
+		t.Fatalf("key = %q", upload.Key)
+	}
+
+	put := func(contentType, body string) int {
+		req, _ := http.NewRequest(upload.Method, upload.URL, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("PUT error = %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := put("text/plain", "hello"); status != http.StatusUnsupportedMediaType {
+		t.Fatalf("PUT with wrong content type status = %d", status)
+	}
+	if status := put("image/png", "hello, this is too long"); status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("PUT oversized status = %d", status)
+	}
+	if status := put("image/png", "hello"); status != http.StatusCreated {
+		t.Fatalf("PUT status = %d", status)
+	}
+	if meta, err := store.Metadata(upload.Key); err != nil || meta[MetadataOwner] != "user-1" || meta[MetadataFilename] != "avatar.PNG" {
+		t.Fatalf("Metadata() = %v, %v", meta, err)
+	}
+
+	download := func(subject string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/files/downloads/"+upload.Key, nil)
+		req.Header.Set("X-Test-Subject", subject)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /downloads error = %v", err)
+		}
+		return resp
+	}
+
+	resp = download("user-1")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" || resp.Header.Get("Content-Type") != "image/png" {
+		t.Fatalf("download status = %d body = %q content-type = %q", resp.StatusCode, body, resp.Header.Get("Content-Type"))
+	}
+
+	resp = download("user-2")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("other user's download status = %d, want 403", resp.StatusCode)
+	}
+
+	actions := make([]string, 0, len(publisher.events))
+	for _, event := range publisher.events {
+		actions = append(actions, event.Action+":"+event.Status)
+	}
+	want := []string{"blob.upload_url.issued:success", "blob.download:success", "blob.download:denied"}
+	if strings.Join(actions, ",") != strings.Join(want, ",") {
+		t.Fatalf("audit actions = %v, want %v", actions, want)
+	}
+}
+
+func TestUploadRejectsDisallowedRequests(t *testing.T) {
+	server, _ := newBlobTestServer(t, nil)
+
+	cases := map[string]UploadRequest{
+		"content type": {Filename: "run.sh", ContentType: "application/x-sh", Size: 10},
+		"size":         {Filename: "scan.pdf", ContentType: "application/pdf", Size: 4096},
+	}
+	for name, req := range cases {
+		resp := requestUpload(t, server, "user-1", req)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("%s: status = %d, want 422", name, resp.StatusCode)
+		}
+	}
+
+	resp := requestUpload(t, server, "", UploadRequest{Filename: "a.pdf", ContentType: "application/pdf", Size: 1})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("anonymous upload status = %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestLocalStoreRejectsTamperedURLs(t *testing.T) {
+	_, store := newBlobTestServer(t, nil)
+
+	presigned, err := store.PresignUpload(context.Background(), "attachments/a.txt", UploadConstraints{ContentType: "text/plain", MaxSize: 10}, time.Minute)
+	if err != nil {
+		t.Fatalf("PresignUpload() error = %v", err)
+	}
+	tampered := strings.Replace(presigned.URL, "max_size=10", "max_size=100000", 1)
+	req, _ := http.NewRequest(http.MethodPut, tampered, strings.NewReader("hi"))
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("tampered PUT status = %d, want 403", resp.StatusCode)
+	}
+
+	if _, err := store.PresignUpload(context.Background(), "../escape", UploadConstraints{}, time.Minute); err == nil {
+		t.Fatal("PresignUpload() accepted a key escaping the store")
+	}
+	if _, err := store.PresignDownload(context.Background(), "attachments/missing.txt", time.Minute); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("PresignDownload(missing) error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,293 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const metadataSuffix = ".meta.json"
+
+// LocalStore keeps objects on the local filesystem and serves HMAC-signed URLs
+// through its ServeHTTP handler, mirroring how cloud stores behave. Mount the
+// handler at the path of baseURL:
+//
+//	store, _ := blob.NewLocalStore("./data/blobs", "http://localhost:8080/blobs", secret)
+//	router.Any("/blobs/*key", gin.WrapH(store))
+type LocalStore struct {
+	dir      string
+	baseURL  *url.URL
+	secret   []byte
+	basePath string
+}
+
+// localObjectMetadata is persisted next to each object.
+type localObjectMetadata struct {
+	ContentType string            `json:"content_type"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// NewLocalStore creates a store rooted at dir serving URLs under baseURL. secret
+// signs the URLs and must be at least 32 bytes.
+func NewLocalStore(dir, baseURL string, secret []byte) (*LocalStore, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, errors.New("blob: local store directory is required")
+	}
+	if len(secret) < 32 {
+		return nil, errors.New("blob: local store secret must be at least 32 bytes")
+	}
+	parsed, err := url.Parse(strings.TrimRight(strings.TrimSpace(baseURL), "/"))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("blob: invalid base url %q", baseURL)
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("blob: create local store directory: %w", err)
+	}
+	return &LocalStore{dir: dir, baseURL: parsed, secret: append([]byte(nil), secret...), basePath: parsed.Path}, nil
+}
+
+// PresignUpload implements Presigner.
+func (s *LocalStore) PresignUpload(_ context.Context, key string, constraints UploadConstraints, ttl time.Duration) (PresignedURL, error) {
+	if _, err := s.objectPath(key); err != nil {
+		return PresignedURL{}, err
+	}
+	expiresAt := time.Now().Add(ttl).UTC()
+
+	params := url.Values{}
+	params.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	if constraints.ContentType != "" {
+		params.Set("content_type", constraints.ContentType)
+	}
+	if constraints.MaxSize > 0 {
+		params.Set("max_size", strconv.FormatInt(constraints.MaxSize, 10))
+	}
+	if len(constraints.Metadata) > 0 {
+		encoded, err := json.Marshal(constraints.Metadata)
+		if err != nil {
+			return PresignedURL{}, fmt.Errorf("blob: encode metadata: %w", err)
+		}
+		params.Set("metadata", base64.RawURLEncoding.EncodeToString(encoded))
+	}
+	params.Set("signature", s.sign(http.MethodPut, key, params))
+
+	headers := map[string]string{}
+	if constraints.ContentType != "" {
+		headers["Content-Type"] = constraints.ContentType
+	}
+	return PresignedURL{URL: s.objectURL(key, params), Method: http.MethodPut, Headers: headers, ExpiresAt: expiresAt}, nil
+}
+
+// PresignDownload implements Presigner.
+func (s *LocalStore) PresignDownload(_ context.Context, key string, ttl time.Duration) (PresignedURL, error) {
+	objectPath, err := s.objectPath(key)
+	if err != nil {
+		return PresignedURL{}, err
+	}
+	if _, err := os.Stat(objectPath); errors.Is(err, os.ErrNotExist) {
+		return PresignedURL{}, ErrNotFound
+	} else if err != nil {
+		return PresignedURL{}, err
+	}
+
+	expiresAt := time.Now().Add(ttl).UTC()
+	params := url.Values{}
+	params.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	params.Set("signature", s.sign(http.MethodGet, key, params))
+	return PresignedURL{URL: s.objectURL(key, params), Method: http.MethodGet, ExpiresAt: expiresAt}, nil
+}
+
+// Metadata returns the metadata stored with key at upload time.
+func (s *LocalStore) Metadata(key string) (map[string]string, error) {
+	meta, err := s.readMetadata(key)
+	if err != nil {
+		return nil, err
+	}
+	return meta.Metadata, nil
+}
+
+// ServeHTTP serves presigned PUT and GET requests.
+func (s *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, s.basePath), "/")
+	params := r.URL.Query()
+	if err := s.verify(r.Method, key, params); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		s.serveUpload(w, r, key, params)
+	case http.MethodGet, http.MethodHead:
+		s.serveDownload(w, r, key)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *LocalStore) serveUpload(w http.ResponseWriter, r *http.Request, key string, params url.Values) {
+	contentType := params.Get("content_type")
+	if contentType != "" && r.Header.Get("Content-Type") != contentType {
+		http.Error(w, "content type does not match the presigned upload", http.StatusUnsupportedMediaType)
+		return
+	}
+	maxSize, _ := strconv.ParseInt(params.Get("max_size"), 10, 64)
+	if maxSize > 0 {
+		if r.ContentLength > maxSize {
+			http.Error(w, "object exceeds the presigned size limit", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+	}
+
+	meta := localObjectMetadata{ContentType: contentType}
+	if raw := params.Get("metadata"); raw != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(raw)
+		if err == nil {
+			err = json.Unmarshal(decoded, &meta.Metadata)
+		}
+		if err != nil {
+			http.Error(w, "invalid metadata", http.StatusBadRequest)
+			return
+		}
+	}
+
+	objectPath, _ := s.objectPath(key)
+	if err := s.writeObject(objectPath, r.Body, meta); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "object exceeds the presigned size limit", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to store object", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *LocalStore) serveDownload(w http.ResponseWriter, r *http.Request, key string) {
+	objectPath, _ := s.objectPath(key)
+	file, err := os.Open(objectPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, "failed to read object", http.StatusInternalServerError)
+		return
+	}
+	if meta, err := s.readMetadata(key); err == nil && meta.ContentType != "" {
+		w.Header().Set("Content-Type", meta.ContentType)
+	}
+	http.ServeContent(w, r, path.Base(key), info.ModTime(), file)
+}
+
+func (s *LocalStore) writeObject(objectPath string, body io.Reader, meta localObjectMetadata) error {
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(objectPath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(objectPath+metadataSuffix, encoded, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), objectPath)
+}
+
+func (s *LocalStore) readMetadata(key string) (localObjectMetadata, error) {
+	objectPath, err := s.objectPath(key)
+	if err != nil {
+		return localObjectMetadata{}, err
+	}
+	raw, err := os.ReadFile(objectPath + metadataSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return localObjectMetadata{}, ErrNotFound
+	}
+	if err != nil {
+		return localObjectMetadata{}, err
+	}
+	var meta localObjectMetadata
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return localObjectMetadata{}, err
+	}
+	return meta, nil
+}
+
+// objectPath maps key to a path inside dir, rejecting keys that would escape it.
+func (s *LocalStore) objectPath(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned == "/" || cleaned != "/"+key || strings.HasSuffix(key, metadataSuffix) {
+		return "", fmt.Errorf("blob: invalid object key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(cleaned)), nil
+}
+
+func (s *LocalStore) objectURL(key string, params url.Values) string {
+	u := *s.baseURL
+	u.Path = s.basePath + "/" + key
+	u.RawQuery = params.Encode()
+	return u.String()
+}
+
+// sign computes the URL signature over the method, key, and every parameter.
+func (s *LocalStore) sign(method, key string, params url.Values) string {
+	unsigned := url.Values{}
+	for name, values := range params {
+		if name != "signature" {
+			unsigned[name] = values
+		}
+	}
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(method + "\n" + key + "\n" + unsigned.Encode()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *LocalStore) verify(method, key string, params url.Values) error {
+	signedMethod := method
+	if method == http.MethodHead {
+		signedMethod = http.MethodGet
+	}
+	if _, err := s.objectPath(key); err != nil {
+		return err
+	}
+	expires, err := strconv.ParseInt(params.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(params.Get("signature")), []byte(s.sign(signedMethod, key, params))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
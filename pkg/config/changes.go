@@ -0,0 +1,197 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyChange is the old and new value of one config key after a reload.
+type KeyChange struct {
+	// Key is the lowercased dotted key, e.g. "log.level".
+	Key string
+	// Old is nil when the key was not set before; New is nil when it was
+	// removed.
+	Old, New any
+	// Sensitive reports whether the key is registered as sensitive; String
+	// then masks both values.
+	Sensitive bool
+	ChangedAt time.Time
+}
+
+// String describes the change for logs, masking sensitive values.
+func (c KeyChange) String() string {
+	if c.Sensitive {
+		return fmt.Sprintf("config %q changed", c.Key)
+	}
+	return fmt.Sprintf("config %q changed from %v to %v", c.Key, c.Old, c.New)
+}
+
+// keyWatchers holds the change subscriptions of a Config and the values the
+// next diff is taken against.
+type keyWatchers struct {
+	mu       sync.Mutex
+	baseline map[string]any
+	handlers []*keyHandler
+	channels []*keyChannel
+}
+
+type keyHandler struct {
+	key string
+	fn  func(KeyChange)
+}
+
+type keyChannel struct {
+	ch chan<- KeyChange
+}
+
+// OnKeyChange calls fn for every change of key, or of a key below it: a
+// subscription to "ratelimit" sees "ratelimit.rps" and "ratelimit.burst".
+// Keys match case-insensitively. Handlers run synchronously, in subscription
+// order, from the goroutine that detected the reload; a panic is recovered
+// and logged. It returns a function that removes the subscription.
+//
+//	cfg.OnKeyChange("log.level", func(ch config.KeyChange) {
+//	  _ = log.SetLogLevel(fmt.Sprint(ch.New))
+//	})
+func (c *Config) OnKeyChange(key string, fn func(KeyChange)) (unsubscribe func()) {
+	if fn == nil {
+		return func() {}
+	}
+	h := &keyHandler{key: strings.ToLower(strings.TrimSpace(key)), fn: fn}
+	c.watchers.mu.Lock()
+	c.watchers.handlers = append(c.watchers.handlers, h)
+	c.watchers.mu.Unlock()
+
+	return func() {
+		c.watchers.mu.Lock()
+		defer c.watchers.mu.Unlock()
+		for i, registered := range c.watchers.handlers {
+			if registered == h {
+				c.watchers.handlers = append(c.watchers.handlers[:i:i], c.watchers.handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Subscribe sends every key change to ch. Sends never block a reload: a
+// change that does not fit in ch's buffer is dropped and logged, so size the
+// buffer for the keys a reload may touch. It returns a function that removes
+// the subscription; ch is not closed.
+func (c *Config) Subscribe(ch chan<- KeyChange) (unsubscribe func()) {
+	if ch == nil {
+		return func() {}
+	}
+	sub := &keyChannel{ch: ch}
+	c.watchers.mu.Lock()
+	c.watchers.channels = append(c.watchers.channels, sub)
+	c.watchers.mu.Unlock()
+
+	return func() {
+		c.watchers.mu.Lock()
+		defer c.watchers.mu.Unlock()
+		for i, registered := range c.watchers.channels {
+			if registered == sub {
+				c.watchers.channels = append(c.watchers.channels[:i:i], c.watchers.channels[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// NotifyChanges diffs every key against the values seen at the previous call
+// (or when the config was created) and delivers the changes, sorted by key,
+// to OnKeyChange and Subscribe subscribers. WithWatch calls it after every
+// file reload and RefreshSecrets after secrets change; call it yourself after
+// Set or MergeConfigMap.
+func (c *Config) NotifyChanges() []KeyChange {
+	current := c.flatSettings()
+	now := time.Now()
+
+	c.watchers.mu.Lock()
+	previous := c.watchers.baseline
+	c.watchers.baseline = current
+	handlers := append([]*keyHandler(nil), c.watchers.handlers...)
+	channels := append([]*keyChannel(nil), c.watchers.channels...)
+	c.watchers.mu.Unlock()
+
+	var changes []KeyChange
+	for key, value := range current {
+		if old, ok := previous[key]; !ok || !reflect.DeepEqual(old, value) {
+			changes = append(changes, KeyChange{Key: key, Old: old, New: value})
+		}
+	}
+	for key, old := range previous {
+		if _, ok := current[key]; !ok {
+			changes = append(changes, KeyChange{Key: key, Old: old})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	for i := range changes {
+		changes[i].ChangedAt = now
+		changes[i].Sensitive = c.isSensitive(changes[i].Key)
+	}
+
+	for _, change := range changes {
+		for _, h := range handlers {
+			if h.key == "" || change.Key == h.key || strings.HasPrefix(change.Key, h.key+".") {
+				runKeyHandler(h.fn, change)
+			}
+		}
+		for _, sub := range channels {
+			select {
+			case sub.ch <- change:
+			default:
+				log.Printf("config: change subscriber is full; dropped %v", change)
+			}
+		}
+	}
+	return changes
+}
+
+// recordKeys sets the baseline of the first NotifyChanges.
+func (c *Config) recordKeys() {
+	settings := c.flatSettings()
+	c.watchers.mu.Lock()
+	c.watchers.baseline = settings
+	c.watchers.mu.Unlock()
+}
+
+func (c *Config) flatSettings() map[string]any {
+	keys := c.AllKeys()
+	settings := make(map[string]any, len(keys))
+	for _, key := range keys {
+		settings[key] = c.Get(key)
+	}
+	return settings
+}
+
+// isSensitive reports whether key, or a key above it, is registered as
+// sensitive or matches DefaultSensitivePatterns.
+func (c *Config) isSensitive(key string) bool {
+	patterns := c.sensitivePatterns()
+	for {
+		if matchSensitive(patterns, key) {
+			return true
+		}
+		i := strings.LastIndexByte(key, '.')
+		if i < 0 {
+			return false
+		}
+		key = key[:i]
+	}
+}
+
+func runKeyHandler(fn func(KeyChange), change KeyChange) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("config: change handler for %q panicked: %v", change.Key, r)
+		}
+	}()
+	fn(change)
+}
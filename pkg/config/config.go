@@ -1,11 +1,13 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -20,6 +22,35 @@ type Config struct {
 	// private
 	sensitiveKeys map[string]struct{}
 	onChange      func()
+	watchFile     bool
+
+	// reloadMu is held for writing while a reload, a remote change or a
+	// secret refresh updates the settings, and for reading by Snapshot.
+	reloadMu sync.RWMutex
+
+	// see WithoutDefaultSensitiveKeys
+	noDefaultSensitive bool
+
+	// secret rotation; see WithSecretRotation
+	rotation     *RotationBus
+	secretKeys   []string
+	secretsMu    sync.Mutex
+	secretValues map[string]string
+
+	// secrets providers; see WithSecretsProvider
+	secretSources   []*secretSource
+	resolvedSecrets []*resolvedSecret
+
+	// key change subscriptions; see OnKeyChange
+	watchers keyWatchers
+
+	// remote backends; see WithConsul and WithEtcd
+	remoteSources []*remoteSource
+
+	// file layers and the source of each key; see WithLayeredProfiles and Origin
+	layers    *layeredProfiles
+	originsMu sync.Mutex
+	origins   map[string]keyOrigin
 }
 
 // Option is a functional option for New.
@@ -49,10 +80,29 @@ func New(opts ...Option) *Config {
 		}
 	}
 
-	// try to read config (if file was set via options)
-	if err := cfg.readConfigIfPossible(); err != nil {
+	if cfg.layers != nil {
+		if err := cfg.loadLayers(); err != nil {
+			log.Fatalf("config: loading layered profiles failed: %v", err)
+		}
+	} else if err := cfg.readConfigIfPossible(); err != nil {
+		// try to read config (if file was set via options);
 		// non-fatal; user might only want env/flags/defaults
 		log.Printf("config: read config warning: %v", err)
+	} else {
+		cfg.recordFileOrigins()
+	}
+	if err := cfg.loadRemote(context.Background()); err != nil {
+		log.Fatalf("config: loading remote config failed: %v", err)
+	}
+	if err := cfg.resolveSecrets(context.Background()); err != nil {
+		log.Fatalf("config: resolving secrets failed: %v", err)
+	}
+	cfg.recordSecrets()
+	cfg.recordKeys()
+	if cfg.watchFile {
+		if err := cfg.watchConfigFile(); err != nil {
+			log.Printf("config: watch config file: %v", err)
+		}
 	}
 
 	return cfg
@@ -115,7 +165,7 @@ func WithConfigNamePaths(name string, paths ...string) Option {
 			c.SetConfigName(name)
 		}
 		if len(paths) == 0 {
-			paths = []string{".", "./env", "/etc/core-lab"}
+			paths = defaultConfigPaths
 		}
 		for _, p := range paths {
 			c.AddConfigPath(p)
@@ -216,34 +266,109 @@ func WithDotEnv(path string) Option {
 	}
 }
 
-// WithWatch enables hot-reload. onChange will be called after a successful reload.
+// WithWatch enables hot-reload. After a successful reload the changed keys
+// are delivered to OnKeyChange and Subscribe subscribers, then onChange, which
+// may be nil, is called. WithConsul and WithEtcd documents are merged again
+// over the reloaded file, and WatchRemote calls onChange too.
 func WithWatch(onChange func()) Option {
 	return func(c *Config) error {
-		c.WatchConfig()
+		c.watchFile = true
 		c.onChange = onChange
-		c.OnConfigChange(func(e fsnotify.Event) {
-			log.Printf("config: file changed: %s", e.Name)
-			// merge again (viper handles reload) - call callback
-			if c.onChange != nil {
-				c.onChange()
-			}
-		})
 		return nil
 	}
 }
 
+// watchConfigFile reloads the config file when it is written, created, or
+// replaced through a symlink (a Kubernetes ConfigMap update), as
+// viper.WatchConfig does, but reads it under reloadMu together with the
+// remote documents merged over it.
+func (c *Config) watchConfigFile() error {
+	path := c.ConfigFileUsed()
+	if path == "" {
+		return nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	configFile := filepath.Clean(path)
+	realFile, _ := filepath.EvalSymlinks(path)
+	// the directory is watched to pick up atomic saves and renames
+	if err := watcher.Add(filepath.Dir(configFile)); err != nil {
+		watcher.Close()
+		return err
+	}
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				current, _ := filepath.EvalSymlinks(path)
+				name := filepath.Clean(event.Name)
+				switch {
+				case name == configFile && (event.Has(fsnotify.Write) || event.Has(fsnotify.Create)),
+					current != "" && current != realFile:
+					realFile = current
+					c.reloadFile(event.Name)
+				case name == configFile && event.Has(fsnotify.Remove):
+					return
+				}
+			case err, ok := <-watcher.Errors:
+				if ok {
+					log.Printf("config: watch %s: %v", path, err)
+				}
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// reloadFile re-reads the config file, merges the remote documents over it
+// again, and publishes the changes.
+func (c *Config) reloadFile(name string) {
+	log.Printf("config: file changed: %s", name)
+	c.reloadMu.Lock()
+	if err := c.ReadInConfig(); err != nil {
+		log.Printf("config: read config file: %v", err)
+	}
+	c.recordFileOrigins()
+	c.applyRemote()
+	c.reloadMu.Unlock()
+
+	if err := c.CheckSecrets(context.Background()); err != nil {
+		log.Printf("config: %v", err)
+	}
+	c.NotifyChanges()
+	if c.onChange != nil {
+		c.onChange()
+	}
+}
+
 // WithSensitiveKeys registers keys which should be redacted when printing/logging.
+// Keys are dotted paths and may be patterns: "*" matches within one segment
+// and "**" any number of segments, so "**.password" masks every key named
+// password and "clients.*.secret" the secret of every client. The value of a
+// matched key is masked whole, including nested keys. DefaultSensitivePatterns
+// are always masked unless WithoutDefaultSensitiveKeys is set.
 func WithSensitiveKeys(keys ...string) Option {
 	return func(c *Config) error {
 		for _, k := range keys {
+			if err := validSensitivePattern(k); err != nil {
+				return err
+			}
 			c.sensitiveKeys[k] = struct{}{}
 		}
 		return nil
 	}
 }
 
-// WithRemoteProvider is a hook for remote providers (Consul/etcd/S3).
-// Pass a function that will perform remote load/merge using the provided viper instance.
+// WithRemoteProvider is a hook for remote providers without built-in support
+// (S3, ...); see WithConsul and WithEtcd for those. Pass a function that will
+// perform remote load/merge using the provided viper instance.
 func WithRemoteProvider(loader func(v *viper.Viper) error) Option {
 	return func(c *Config) error {
 		if loader == nil {
@@ -272,7 +397,7 @@ func (c *Config) MergeInFile(path string) error {
 	if err := tmp.ReadInConfig(); err != nil {
 		return err
 	}
-	return c.MergeConfigMap(tmp.AllSettings())
+	return c.mergeSource(path, tmp.AllSettings())
 }
 
 // Save writes current effective configuration as a file of given type.
@@ -330,7 +455,8 @@ func (c *Config) GetDurationD(key string, def time.Duration) time.Duration {
    Validation & Utilities
 ----------------------------*/
 
-// ValidateRequired ensures keys exist and are non-empty.
+// ValidateRequired ensures keys exist and are non-empty. For keys declared in
+// a Schema, Validate also checks types and allowed values.
 func (c *Config) ValidateRequired(keys ...string) error {
 	var missing []string
 	for _, k := range keys {
@@ -345,29 +471,20 @@ func (c *Config) ValidateRequired(keys ...string) error {
 }
 
 // MaskedSettings returns a copy of AllSettings with sensitive keys redacted.
+// Nested keys, including those of maps inside lists, are matched by their
+// dotted path against the registered keys and DefaultSensitivePatterns, e.g.
+// "database.password".
 func (c *Config) MaskedSettings() map[string]interface{} {
-	all := c.AllSettings()
-	redacted := map[string]interface{}{}
-	for k, v := range all {
-		if _, ok := c.sensitiveKeys[k]; ok {
-			redacted[k] = "***REDACTED***"
-		} else {
-			redacted[k] = v
-		}
-	}
-	return redacted
+	return maskSettings(c.AllSettings(), "", c.sensitivePatterns())
 }
 
 // Print prints all settings to stdout with optional masking for sensitive keys.
 func (c *Config) Print(mask bool) {
 	all := c.AllSettings()
+	if mask {
+		all = c.MaskedSettings()
+	}
 	for k, v := range all {
-		if mask {
-			if _, ok := c.sensitiveKeys[k]; ok {
-				fmt.Printf("%s = %s\n", k, "***REDACTED***")
-				continue
-			}
-		}
 		fmt.Printf("%s = %v\n", k, v)
 	}
 }
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// defaultConfigPaths are searched for config files when no paths are given.
+var defaultConfigPaths = []string{".", "./env", "/etc/core-lab"}
+
+type layeredProfiles struct {
+	name     string
+	profiles []string
+	paths    []string
+}
+
+// keyOrigin is the source that last set a key, and the value it set.
+type keyOrigin struct {
+	source string
+	value  any
+}
+
+// WithLayeredProfiles loads one file per profile, named name.profile (or
+// just name for an empty profile) with any supported extension, and merges
+// them in order so each profile overrides the ones before it:
+//
+//	cfg := config.New(
+//	  config.WithLayeredProfiles("config", []string{"base", os.Getenv("APP_ENV"), "local"}),
+//	  config.WithEnv("APP"),
+//	)
+//
+// reads config.base.yaml, then config.dev.yaml, then config.local.yaml.
+// Nested maps are merged key by key. Each profile's file is searched for in
+// paths, defaulting to ".", "./env" and "/etc/core-lab", and the first match
+// wins. Missing files are skipped, so a gitignored local overlay is optional;
+// a file that cannot be parsed fails New. Env vars, flags and Set still take
+// precedence over every layer. Origin reports which file each key came from.
+// Layers replace WithFile and WithConfigNamePaths and are not reloaded by
+// WithWatch.
+func WithLayeredProfiles(name string, profiles []string, paths ...string) Option {
+	return func(c *Config) error {
+		if name == "" {
+			return errors.New("layered profiles: name is required")
+		}
+		if len(profiles) == 0 {
+			return errors.New("layered profiles: at least one profile is required")
+		}
+		if len(paths) == 0 {
+			paths = defaultConfigPaths
+		}
+		c.layers = &layeredProfiles{name: name, profiles: append([]string(nil), profiles...), paths: append([]string(nil), paths...)}
+		return nil
+	}
+}
+
+// loadLayers merges the file of every profile that has one, in order.
+func (c *Config) loadLayers() error {
+	loaded := 0
+	for _, profile := range c.layers.profiles {
+		base := c.layers.name
+		if profile = strings.TrimSpace(profile); profile != "" {
+			base += "." + profile
+		}
+		path, ok := findConfigFile(base, c.layers.paths)
+		if !ok {
+			continue
+		}
+		layer := viper.New()
+		layer.SetConfigFile(path)
+		if err := layer.ReadInConfig(); err != nil {
+			return fmt.Errorf("layer %s: %w", path, err)
+		}
+		if err := c.mergeSource(path, layer.AllSettings()); err != nil {
+			return fmt.Errorf("layer %s: %w", path, err)
+		}
+		loaded++
+	}
+	if loaded == 0 {
+		log.Printf("config: no %s.{%s} file found in %v", c.layers.name, strings.Join(c.layers.profiles, ","), c.layers.paths)
+	}
+	return nil
+}
+
+// findConfigFile returns the first file named base with a supported
+// extension in paths.
+func findConfigFile(base string, paths []string) (string, bool) {
+	for _, dir := range paths {
+		for _, ext := range viper.SupportedExts {
+			path := filepath.Join(dir, base+"."+ext)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Origin reports where the effective value of key came from: the path of
+// the config file or WithLayeredProfiles layer, or the WithConsul or WithEtcd
+// key, that last set it. It returns "" when the value comes from anywhere
+// else (an env var, a flag, Set or a default) and for keys holding a map,
+// whose leaves may each come from a different file.
+//
+//	log.Printf("database.host=%s from %s", cfg.GetString("database.host"), cfg.Origin("database.host"))
+func (c *Config) Origin(key string) string {
+	key = strings.ToLower(key)
+	c.originsMu.Lock()
+	origin, ok := c.origins[key]
+	c.originsMu.Unlock()
+	if !ok || fmt.Sprint(c.Get(key)) != fmt.Sprint(origin.value) {
+		// overridden by a source with higher precedence
+		return ""
+	}
+	return origin.source
+}
+
+// mergeSource merges values over the config and records source as the
+// origin of every key in it.
+func (c *Config) mergeSource(source string, values map[string]any) error {
+	if err := c.MergeConfigMap(values); err != nil {
+		return err
+	}
+	flat := make(map[string]any)
+	flattenSettings("", values, flat)
+	c.originsMu.Lock()
+	defer c.originsMu.Unlock()
+	if c.origins == nil {
+		c.origins = make(map[string]keyOrigin, len(flat))
+	}
+	for key, value := range flat {
+		c.origins[key] = keyOrigin{source: source, value: value}
+	}
+	return nil
+}
+
+// recordFileOrigins resets the recorded origins to the keys of the config
+// file, after it has been read or reloaded.
+func (c *Config) recordFileOrigins() {
+	flat := make(map[string]any)
+	path := c.ConfigFileUsed()
+	if path != "" {
+		file := viper.New()
+		file.SetConfigFile(path)
+		if err := file.ReadInConfig(); err == nil {
+			flattenSettings("", file.AllSettings(), flat)
+		}
+	}
+	c.originsMu.Lock()
+	defer c.originsMu.Unlock()
+	c.origins = make(map[string]keyOrigin, len(flat))
+	for key, value := range flat {
+		c.origins[key] = keyOrigin{source: path, value: value}
+	}
+}
+
+func flattenSettings(prefix string, settings map[string]any, flat map[string]any) {
+	for key, value := range settings {
+		key = prefix + strings.ToLower(key)
+		if nested, ok := value.(map[string]any); ok && len(nested) > 0 {
+			flattenSettings(key+".", nested, flat)
+			continue
+		}
+		flat[key] = value
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DefaultSensitivePatterns are the key patterns masked by MaskedSettings,
+// Print, KeyChange.String and SchemaReport in addition to the registered
+// sensitive keys, unless WithoutDefaultSensitiveKeys is set. They match
+// common secret names at any depth, e.g. "database.password" or
+// "clients.billing.client_secret".
+var DefaultSensitivePatterns = []string{
+	"**.password", "**.*_password", "**.passwd",
+	"**.secret", "**.*_secret", "**.secret_key", "**.secretkey",
+	"**.token", "**.*_token",
+	"**.api_key", "**.apikey", "**.*_api_key",
+	"**.private_key", "**.*_private_key",
+	"**.credentials",
+	"**.dsn",
+}
+
+// WithoutDefaultSensitiveKeys masks only the keys registered as sensitive,
+// not DefaultSensitivePatterns.
+func WithoutDefaultSensitiveKeys() Option {
+	return func(c *Config) error {
+		c.noDefaultSensitive = true
+		return nil
+	}
+}
+
+// validSensitivePattern reports an error for a pattern with a malformed
+// segment, e.g. an unclosed "[".
+func validSensitivePattern(pattern string) error {
+	for _, segment := range strings.Split(pattern, ".") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("sensitive key pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// sensitivePatterns returns the lowercased registered keys and, unless
+// disabled, DefaultSensitivePatterns.
+func (c *Config) sensitivePatterns() [][]string {
+	patterns := make([][]string, 0, len(c.sensitiveKeys)+len(DefaultSensitivePatterns))
+	for k := range c.sensitiveKeys {
+		patterns = append(patterns, strings.Split(strings.ToLower(k), "."))
+	}
+	if !c.noDefaultSensitive {
+		for _, p := range DefaultSensitivePatterns {
+			patterns = append(patterns, strings.Split(strings.ToLower(p), "."))
+		}
+	}
+	return patterns
+}
+
+// matchSensitive reports whether the dotted key path matches one of
+// patterns.
+func matchSensitive(patterns [][]string, key string) bool {
+	segments := strings.Split(key, ".")
+	for _, pattern := range patterns {
+		if matchSegments(pattern, segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches a key's segments against a pattern's: "**" matches
+// any number of segments, including none, and other segments are path.Match
+// globs over one segment.
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// maskSettings returns a copy of settings with the values of sensitive keys
+// replaced, descending into nested maps and into maps inside lists. List
+// elements share the path of their list.
+func maskSettings(settings map[string]interface{}, prefix string, patterns [][]string) map[string]interface{} {
+	masked := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		key := prefix + strings.ToLower(k)
+		if matchSensitive(patterns, key) {
+			masked[k] = redacted
+			continue
+		}
+		masked[k] = maskValue(v, key, patterns)
+	}
+	return masked
+}
+
+func maskValue(v interface{}, key string, patterns [][]string) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		return maskSettings(value, key+".", patterns)
+	case []interface{}:
+		items := make([]interface{}, len(value))
+		for i, item := range value {
+			items[i] = maskValue(item, key, patterns)
+		}
+		return items
+	case []map[string]interface{}:
+		items := make([]map[string]interface{}, len(value))
+		for i, item := range value {
+			items[i] = maskSettings(item, key+".", patterns)
+		}
+		return items
+	}
+	return v
+}
@@ -0,0 +1,367 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultRemoteTimeout bounds each read of a remote config backend.
+const DefaultRemoteTimeout = 10 * time.Second
+
+// RemoteOption configures WithConsul and WithEtcd.
+type RemoteOption func(*remoteSettings)
+
+type remoteSettings struct {
+	tls       *tls.Config
+	token     string
+	username  string
+	password  string
+	format    string
+	cachePath string
+	timeout   time.Duration
+	client    *http.Client
+}
+
+// WithRemoteTLS connects to the backend over HTTPS with cfg, e.g. to trust a
+// private CA or present a client certificate.
+func WithRemoteTLS(cfg *tls.Config) RemoteOption {
+	return func(s *remoteSettings) {
+		s.tls = cfg
+	}
+}
+
+// WithRemoteToken authenticates with a token: a Consul ACL token, or an etcd
+// auth token. Consul defaults to $CONSUL_HTTP_TOKEN.
+func WithRemoteToken(token string) RemoteOption {
+	return func(s *remoteSettings) {
+		s.token = token
+	}
+}
+
+// WithRemoteCredentials authenticates with a username and password: etcd
+// exchanges them for an auth token, Consul sends them as basic auth.
+func WithRemoteCredentials(username, password string) RemoteOption {
+	return func(s *remoteSettings) {
+		s.username, s.password = username, password
+	}
+}
+
+// WithRemoteFormat sets the format of the stored document (yaml, json, toml,
+// ...). Default: the key's extension, or yaml, which also reads JSON.
+func WithRemoteFormat(format string) RemoteOption {
+	return func(s *remoteSettings) {
+		s.format = strings.ToLower(strings.TrimPrefix(format, "."))
+	}
+}
+
+// WithRemoteCache keeps the last document read from the backend at path.
+// When the backend cannot be reached at startup, the config is loaded from
+// that copy and a warning is logged. The first start still requires the
+// backend.
+func WithRemoteCache(path string) RemoteOption {
+	return func(s *remoteSettings) {
+		s.cachePath = path
+	}
+}
+
+// WithRemoteTimeout bounds each read. Default: DefaultRemoteTimeout.
+func WithRemoteTimeout(d time.Duration) RemoteOption {
+	return func(s *remoteSettings) {
+		if d > 0 {
+			s.timeout = d
+		}
+	}
+}
+
+// WithRemoteHTTPClient sets the HTTP client; WithRemoteTLS is ignored. Its
+// timeout must not cut off watches, so leave it zero and use
+// WithRemoteTimeout.
+func WithRemoteHTTPClient(client *http.Client) RemoteOption {
+	return func(s *remoteSettings) {
+		s.client = client
+	}
+}
+
+func newRemoteSettings(opts []RemoteOption) remoteSettings {
+	s := remoteSettings{timeout: DefaultRemoteTimeout}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&s)
+		}
+	}
+	if s.client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if s.tls != nil {
+			transport.TLSClientConfig = s.tls
+		}
+		s.client = &http.Client{Transport: transport}
+	}
+	return s
+}
+
+// remoteBackend reads one document from a key/value store.
+type remoteBackend interface {
+	// String names the backend and key for logs and errors.
+	String() string
+	// get reads the document and the index to watch it from. A missing key
+	// is an error.
+	get(ctx context.Context) (value []byte, index uint64, err error)
+	// wait blocks until the document changes after index, or the backend's
+	// wait time passes, and returns the document at the returned index. The
+	// index is unchanged when nothing changed; value is nil when the key was
+	// deleted.
+	wait(ctx context.Context, index uint64) (value []byte, next uint64, err error)
+}
+
+// remoteSource is a backend loaded into the config.
+type remoteSource struct {
+	backend  remoteBackend
+	settings remoteSettings
+	format   string
+
+	mu     sync.Mutex
+	values map[string]any
+	raw    []byte
+	index  uint64
+}
+
+func remoteOption(backend remoteBackend, key string, settings remoteSettings) Option {
+	return func(c *Config) error {
+		format := settings.format
+		if format == "" {
+			format = strings.ToLower(strings.TrimPrefix(filepath.Ext(key), "."))
+		}
+		if !slices.Contains(viper.SupportedExts, format) {
+			format = "yaml"
+		}
+		c.remoteSources = append(c.remoteSources, &remoteSource{backend: backend, settings: settings, format: format})
+		return nil
+	}
+}
+
+// loadRemote reads every remote source, falling back to its cache, and
+// merges them over the config file in registration order.
+func (c *Config) loadRemote(ctx context.Context) error {
+	var errs []error
+	for _, src := range c.remoteSources {
+		readCtx, cancel := context.WithTimeout(ctx, src.settings.timeout)
+		raw, index, err := src.backend.get(readCtx)
+		cancel()
+		if err == nil {
+			err = src.set(raw, index)
+		}
+		if err != nil {
+			if cacheErr := src.loadCache(); cacheErr != nil {
+				errs = append(errs, fmt.Errorf("config: %s: %w (no cached copy: %v)", src.backend, err, cacheErr))
+				continue
+			}
+			log.Printf("config: %s unreachable, using the cached copy from %s: %v", src.backend, src.settings.cachePath, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	c.applyRemote()
+	return nil
+}
+
+// applyRemote merges the last document of every remote source into the
+// config. A file reload replaces the merged values, so WithWatch calls it
+// again afterwards.
+func (c *Config) applyRemote() {
+	for _, src := range c.remoteSources {
+		src.mu.Lock()
+		values := src.values
+		src.mu.Unlock()
+		if values != nil {
+			if err := c.mergeSource(fmt.Sprint(src.backend), values); err != nil {
+				log.Printf("config: merge %s: %v", src.backend, err)
+			}
+		}
+	}
+}
+
+// WatchRemote reloads the config whenever a WithConsul or WithEtcd document
+// changes, until ctx is done. Consul is watched with blocking queries and
+// etcd with its watch API, so a change applies within moments. Changed keys
+// are merged over the current config; a key removed from the document keeps
+// its last value until restart. Changes are published through CheckSecrets
+// and NotifyChanges, then to the WithWatch callback. While the backend is
+// unreachable the config keeps its last values and the watch retries with
+// backoff.
+func (c *Config) WatchRemote(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, src := range c.remoteSources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.watchRemoteSource(ctx, src)
+		}()
+	}
+	wg.Wait()
+}
+
+func (c *Config) watchRemoteSource(ctx context.Context, src *remoteSource) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		src.mu.Lock()
+		index := src.index
+		src.mu.Unlock()
+
+		raw, next, err := src.backend.wait(ctx, index)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("config: watch %s: %v; retrying in %s", src.backend, err, backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, 30*time.Second)
+			continue
+		}
+		backoff = time.Second
+		if next == index {
+			continue
+		}
+		if raw == nil {
+			log.Printf("config: %s was deleted; keeping its last values", src.backend)
+			src.mu.Lock()
+			src.index = next
+			src.mu.Unlock()
+			continue
+		}
+
+		src.mu.Lock()
+		unchanged := bytes.Equal(raw, src.raw)
+		src.mu.Unlock()
+		if unchanged {
+			src.mu.Lock()
+			src.index = next
+			src.mu.Unlock()
+			continue
+		}
+		if err := src.set(raw, next); err != nil {
+			log.Printf("config: %v; keeping the last values", err)
+			src.mu.Lock()
+			src.index = next
+			src.mu.Unlock()
+			continue
+		}
+		log.Printf("config: %s changed", src.backend)
+		c.reloadMu.Lock()
+		c.applyRemote()
+		c.reloadMu.Unlock()
+		if err := c.CheckSecrets(ctx); err != nil {
+			log.Printf("config: %v", err)
+		}
+		c.NotifyChanges()
+		if c.onChange != nil {
+			c.onChange()
+		}
+	}
+}
+
+// set decodes raw and makes it the source's document, updating the cache.
+func (s *remoteSource) set(raw []byte, index uint64) error {
+	values, err := decodeRemote(raw, s.format)
+	if err != nil {
+		return fmt.Errorf("decode %s: %w", s.backend, err)
+	}
+	s.mu.Lock()
+	s.values, s.raw, s.index = values, raw, index
+	s.mu.Unlock()
+	if s.settings.cachePath != "" {
+		if err := saveRemoteCache(s.settings.cachePath, raw, index); err != nil {
+			log.Printf("config: write cache of %s: %v", s.backend, err)
+		}
+	}
+	return nil
+}
+
+func (s *remoteSource) loadCache() error {
+	if s.settings.cachePath == "" {
+		return errors.New("WithRemoteCache is not set")
+	}
+	cache, err := readRemoteCache(s.settings.cachePath)
+	if err != nil {
+		return err
+	}
+	values, err := decodeRemote(cache.Value, s.format)
+	if err != nil {
+		return fmt.Errorf("decode cache %s: %w", s.settings.cachePath, err)
+	}
+	s.mu.Lock()
+	// watch from the start, so the first wait returns the live document
+	s.values, s.raw, s.index = values, cache.Value, 0
+	s.mu.Unlock()
+	return nil
+}
+
+func decodeRemote(raw []byte, format string) (map[string]any, error) {
+	v := viper.New()
+	v.SetConfigType(format)
+	if err := v.ReadConfig(bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+	return v.AllSettings(), nil
+}
+
+// remoteCache is the on-disk format written by WithRemoteCache.
+type remoteCache struct {
+	SavedAt time.Time `json:"saved_at"`
+	Index   uint64    `json:"index"`
+	Value   []byte    `json:"value"`
+}
+
+func saveRemoteCache(path string, raw []byte, index uint64) error {
+	data, err := json.Marshal(remoteCache{SavedAt: time.Now().UTC(), Index: index, Value: raw})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func readRemoteCache(path string) (remoteCache, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return remoteCache{}, err
+	}
+	var cache remoteCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return remoteCache{}, err
+	}
+	if cache.Value == nil {
+		return remoteCache{}, fmt.Errorf("remote config cache %s is empty", path)
+	}
+	return cache, nil
+}
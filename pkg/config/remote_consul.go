@@ -0,0 +1,138 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// consulWaitTime is how long a blocking query waits for a change.
+const consulWaitTime = 5 * time.Minute
+
+// WithConsul loads a config document from the Consul KV store at key and
+// merges it over the config file, e.g. a YAML file kept at
+// "config/orders.yaml". addr is the agent's HTTP address, defaulting to
+// $CONSUL_HTTP_ADDR; https:// addresses use WithRemoteTLS.
+//
+//	cfg := config.New(
+//	  config.WithFile("config.yaml"),
+//	  config.WithConsul("https://consul:8501", "config/orders.yaml",
+//	    config.WithRemoteToken(token),
+//	    config.WithRemoteCache("/var/cache/orders/config.json"),
+//	  ),
+//	)
+//	go cfg.WatchRemote(ctx)
+//
+// New fails when the key cannot be read and there is no cached copy.
+func WithConsul(addr, key string, opts ...RemoteOption) Option {
+	settings := newRemoteSettings(opts)
+	if addr == "" {
+		addr = os.Getenv("CONSUL_HTTP_ADDR")
+	}
+	if settings.token == "" {
+		settings.token = os.Getenv("CONSUL_HTTP_TOKEN")
+	}
+	if addr != "" && !strings.Contains(addr, "://") {
+		scheme := "http://"
+		if settings.tls != nil {
+			scheme = "https://"
+		}
+		addr = scheme + addr
+	}
+	key = strings.Trim(key, "/")
+	backend := &consulBackend{address: strings.TrimRight(addr, "/"), key: key, settings: settings}
+	configure := remoteOption(backend, key, settings)
+	return func(c *Config) error {
+		if addr == "" {
+			return errors.New("consul: address is required")
+		}
+		if key == "" {
+			return errors.New("consul: key is required")
+		}
+		return configure(c)
+	}
+}
+
+type consulBackend struct {
+	address  string
+	key      string
+	settings remoteSettings
+}
+
+func (b *consulBackend) String() string {
+	return "consul key " + b.key
+}
+
+func (b *consulBackend) get(ctx context.Context) ([]byte, uint64, error) {
+	value, index, found, err := b.read(ctx, url.Values{})
+	if err != nil {
+		return nil, 0, err
+	}
+	if !found {
+		return nil, 0, fmt.Errorf("consul: key %s not found", b.key)
+	}
+	return value, index, nil
+}
+
+// wait issues a blocking query, which Consul answers when the key's index
+// passes index or after consulWaitTime.
+func (b *consulBackend) wait(ctx context.Context, index uint64) ([]byte, uint64, error) {
+	query := url.Values{}
+	query.Set("index", strconv.FormatUint(index, 10))
+	query.Set("wait", consulWaitTime.String())
+	waitCtx, cancel := context.WithTimeout(ctx, consulWaitTime+b.settings.timeout)
+	defer cancel()
+
+	value, next, found, err := b.read(waitCtx, query)
+	if err != nil {
+		return nil, index, err
+	}
+	// Consul may reset the index, e.g. after a snapshot restore; a lower
+	// index means the next query must start over.
+	if next < index {
+		next = 0
+	}
+	if !found {
+		return nil, next, nil
+	}
+	return value, next, nil
+}
+
+func (b *consulBackend) read(ctx context.Context, query url.Values) ([]byte, uint64, bool, error) {
+	query.Set("raw", "")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.address+"/v1/kv/"+b.key+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if b.settings.token != "" {
+		req.Header.Set("X-Consul-Token", b.settings.token)
+	}
+	if b.settings.username != "" {
+		req.SetBasicAuth(b.settings.username, b.settings.password)
+	}
+
+	resp, err := b.settings.client.Do(req)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("consul: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("consul: read response: %w", err)
+	}
+	index, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, index, false, nil
+	case resp.StatusCode >= 300:
+		return nil, 0, false, fmt.Errorf("consul: GET %s: %d: %s", b.key, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, index, true, nil
+}
@@ -0,0 +1,302 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// etcdWaitTime is how long one watch stream stays open before it is
+// reopened from the last revision.
+const etcdWaitTime = 10 * time.Minute
+
+// WithEtcd loads a config document from etcd at key and merges it over the
+// config file. endpoints are the members' client URLs, tried in order, and
+// are reached through etcd's JSON gateway (/v3). Hosts without a scheme use
+// https:// when WithRemoteTLS is set.
+//
+//	cfg := config.New(
+//	  config.WithFile("config.yaml"),
+//	  config.WithEtcd([]string{"https://etcd-0:2379", "https://etcd-1:2379"}, "/config/orders.yaml",
+//	    config.WithRemoteTLS(tlsConfig),
+//	    config.WithRemoteCredentials("orders", password),
+//	    config.WithRemoteCache("/var/cache/orders/config.json"),
+//	  ),
+//	)
+//	go cfg.WatchRemote(ctx)
+//
+// New fails when the key cannot be read and there is no cached copy.
+func WithEtcd(endpoints []string, key string, opts ...RemoteOption) Option {
+	settings := newRemoteSettings(opts)
+	backend := &etcdBackend{key: key, settings: settings, token: settings.token}
+	for _, endpoint := range endpoints {
+		endpoint = strings.TrimRight(strings.TrimSpace(endpoint), "/")
+		if endpoint == "" {
+			continue
+		}
+		if !strings.Contains(endpoint, "://") {
+			scheme := "http://"
+			if settings.tls != nil {
+				scheme = "https://"
+			}
+			endpoint = scheme + endpoint
+		}
+		backend.endpoints = append(backend.endpoints, endpoint)
+	}
+	configure := remoteOption(backend, key, settings)
+	return func(c *Config) error {
+		if len(backend.endpoints) == 0 {
+			return errors.New("etcd: at least one endpoint is required")
+		}
+		if key == "" {
+			return errors.New("etcd: key is required")
+		}
+		return configure(c)
+	}
+}
+
+type etcdBackend struct {
+	endpoints []string
+	key       string
+	settings  remoteSettings
+
+	mu      sync.Mutex
+	current int    // index of the endpoint that last answered
+	token   string // auth token, from WithRemoteToken or authenticate
+}
+
+type etcdKeyValue struct {
+	Key         []byte `json:"key"`
+	Value       []byte `json:"value"`
+	ModRevision int64  `json:"mod_revision,string"`
+}
+
+type etcdHeader struct {
+	Revision int64 `json:"revision,string"`
+}
+
+type etcdRangeResponse struct {
+	Header etcdHeader     `json:"header"`
+	Kvs    []etcdKeyValue `json:"kvs"`
+}
+
+type etcdWatchResponse struct {
+	Result *struct {
+		Header          etcdHeader `json:"header"`
+		Created         bool       `json:"created"`
+		Canceled        bool       `json:"canceled"`
+		CompactRevision int64      `json:"compact_revision,string"`
+		CancelReason    string     `json:"cancel_reason"`
+		Events          []struct {
+			Type string       `json:"type"`
+			Kv   etcdKeyValue `json:"kv"`
+		} `json:"events"`
+	} `json:"result"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (b *etcdBackend) String() string {
+	return "etcd key " + b.key
+}
+
+func (b *etcdBackend) get(ctx context.Context) ([]byte, uint64, error) {
+	var resp etcdRangeResponse
+	if err := b.call(ctx, "/v3/kv/range", map[string]any{"key": []byte(b.key)}, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&resp)
+	}); err != nil {
+		return nil, 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, 0, fmt.Errorf("etcd: key %s not found", b.key)
+	}
+	return resp.Kvs[0].Value, uint64(resp.Header.Revision), nil
+}
+
+// wait opens a watch from the revision after index and returns the first
+// change of the key. A compacted revision falls back to a fresh read.
+func (b *etcdBackend) wait(ctx context.Context, index uint64) ([]byte, uint64, error) {
+	if index == 0 {
+		return b.get(ctx)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, etcdWaitTime)
+	defer cancel()
+
+	var (
+		value []byte
+		next  = index
+		fresh bool
+	)
+	request := map[string]any{"create_request": map[string]any{"key": []byte(b.key), "start_revision": index + 1}}
+	err := b.call(waitCtx, "/v3/watch", request, func(body io.Reader) error {
+		decoder := json.NewDecoder(body)
+		for {
+			var resp etcdWatchResponse
+			if err := decoder.Decode(&resp); err != nil {
+				return err
+			}
+			switch {
+			case resp.Error != nil:
+				return fmt.Errorf("etcd: watch %s: %s", b.key, resp.Error.Message)
+			case resp.Result == nil:
+				continue
+			case resp.Result.Canceled:
+				if resp.Result.CompactRevision > 0 {
+					fresh = true
+					return nil
+				}
+				return fmt.Errorf("etcd: watch %s canceled: %s", b.key, resp.Result.CancelReason)
+			}
+			for _, event := range resp.Result.Events {
+				next = uint64(event.Kv.ModRevision)
+				if event.Type == "DELETE" {
+					value = nil
+				} else {
+					value = event.Kv.Value
+				}
+			}
+			if next != index {
+				return nil
+			}
+		}
+	})
+	switch {
+	case fresh:
+		return b.get(ctx)
+	case err != nil && waitCtx.Err() != nil && ctx.Err() == nil:
+		// the stream stayed open for etcdWaitTime without a change
+		return nil, index, nil
+	case err != nil:
+		return nil, index, err
+	}
+	return value, next, nil
+}
+
+// call posts body to path on the first endpoint that answers and passes the
+// response body to decode. It authenticates first when credentials are set,
+// and once more when the token has expired.
+func (b *etcdBackend) call(ctx context.Context, path string, body any, decode func(io.Reader) error) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	start := b.current
+	b.mu.Unlock()
+
+	var errs []error
+	for i := range b.endpoints {
+		n := (start + i) % len(b.endpoints)
+		err := b.callEndpoint(ctx, b.endpoints[n], path, payload, decode)
+		if err == nil {
+			b.mu.Lock()
+			b.current = n
+			b.mu.Unlock()
+			return nil
+		}
+		var status etcdStatusError
+		if errors.As(err, &status) || ctx.Err() != nil {
+			// the cluster answered; another member would answer the same
+			return err
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+type etcdStatusError struct {
+	path    string
+	status  int
+	message string
+}
+
+func (e etcdStatusError) Error() string {
+	return fmt.Sprintf("etcd: POST %s: %d: %s", e.path, e.status, e.message)
+}
+
+func (b *etcdBackend) callEndpoint(ctx context.Context, endpoint, path string, payload []byte, decode func(io.Reader) error) error {
+	for attempt := 0; ; attempt++ {
+		token, err := b.authToken(ctx, endpoint, attempt > 0)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+
+		resp, err := b.settings.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("etcd: %w", err)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 && b.settings.username != "" {
+			resp.Body.Close()
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			data, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+			resp.Body.Close()
+			return etcdStatusError{path: path, status: resp.StatusCode, message: strings.TrimSpace(string(data))}
+		}
+		err = decode(io.LimitReader(resp.Body, 4<<20))
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("etcd: decode %s: %w", path, err)
+		}
+		return nil
+	}
+}
+
+// authToken returns the token to send, authenticating with the configured
+// credentials when there is none yet or renew is set.
+func (b *etcdBackend) authToken(ctx context.Context, endpoint string, renew bool) (string, error) {
+	b.mu.Lock()
+	token := b.token
+	b.mu.Unlock()
+	if b.settings.username == "" || (token != "" && !renew) {
+		return token, nil
+	}
+
+	payload, err := json.Marshal(map[string]string{"name": b.settings.username, "password": b.settings.password})
+	if err != nil {
+		return "", err
+	}
+	authCtx, cancel := context.WithTimeout(ctx, b.settings.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(authCtx, http.MethodPost, endpoint+"/v3/auth/authenticate", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := b.settings.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("etcd: authenticate: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return "", etcdStatusError{path: "/v3/auth/authenticate", status: resp.StatusCode, message: strings.TrimSpace(string(data))}
+	}
+	var auth struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&auth); err != nil {
+		return "", fmt.Errorf("etcd: decode authenticate: %w", err)
+	}
+	b.mu.Lock()
+	b.token = auth.Token
+	b.mu.Unlock()
+	return auth.Token, nil
+}
@@ -0,0 +1,276 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConsul serves one KV key with blocking queries. failures answers the
+// next requests with 500.
+type fakeConsul struct {
+	t        *testing.T
+	mu       sync.Mutex
+	value    string
+	index    uint64
+	failures int
+	changed  chan struct{}
+}
+
+func newFakeConsul(t *testing.T, value string) (*fakeConsul, *httptest.Server) {
+	f := &fakeConsul{t: t, value: value, index: 10, changed: make(chan struct{})}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeConsul) set(value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.value, f.index = value, f.index+1
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeConsul) fail(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = n
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/kv/config/orders.yaml" || r.Header.Get("X-Consul-Token") != "acl-token" {
+		f.t.Errorf("request %s with token %q", r.URL.Path, r.Header.Get("X-Consul-Token"))
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	if f.failures > 0 {
+		f.failures--
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	changed := f.changed
+	wait, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+	blocking := wait >= f.index
+	f.mu.Unlock()
+
+	if blocking {
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+	_, _ = io.WriteString(w, f.value)
+}
+
+func remoteConfig(t *testing.T, opt Option) *Config {
+	t.Helper()
+	cfg := New(WithDefaults(map[string]interface{}{"log.level": "info"}))
+	if err := opt(cfg); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestConsulLoadsAndWatches(t *testing.T) {
+	consul, server := newFakeConsul(t, "log:\n  level: warn\nworkers: 4\n")
+	cachePath := filepath.Join(t.TempDir(), "config.json")
+	cfg := remoteConfig(t, WithConsul(server.URL, "/config/orders.yaml/",
+		WithRemoteToken("acl-token"), WithRemoteCache(cachePath)))
+	if err := cfg.loadRemote(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	cfg.recordKeys()
+	if cfg.GetString("log.level") != "warn" || cfg.GetInt("workers") != 4 {
+		t.Fatalf("log.level = %q, workers = %d", cfg.GetString("log.level"), cfg.GetInt("workers"))
+	}
+	if cache, err := readRemoteCache(cachePath); err != nil || cache.Index != 10 {
+		t.Fatalf("cache = %+v, %v", cache, err)
+	}
+
+	changes := make(chan KeyChange, 4)
+	cfg.OnKeyChange("log.level", func(change KeyChange) { changes <- change })
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cfg.WatchRemote(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	consul.set("log:\n  level: debug\n")
+	if change := awaitChange(t, changes); change.New != "debug" {
+		t.Fatalf("change = %+v", change)
+	}
+
+	// The watch survives a failing agent and picks up the next change.
+	consul.fail(1)
+	consul.set("log:\n  level: error\n")
+	if change := awaitChange(t, changes); change.New != "error" {
+		t.Fatalf("change after reconnect = %+v", change)
+	}
+}
+
+func awaitChange(t *testing.T, changes <-chan KeyChange) KeyChange {
+	t.Helper()
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change delivered")
+		return KeyChange{}
+	}
+}
+
+func TestRemoteCacheFallback(t *testing.T) {
+	_, server := newFakeConsul(t, "workers: 8\n")
+	cachePath := filepath.Join(t.TempDir(), "config.json")
+	cfg := remoteConfig(t, WithConsul(server.URL, "config/orders.yaml",
+		WithRemoteToken("acl-token"), WithRemoteCache(cachePath)))
+	if err := cfg.loadRemote(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	cached := remoteConfig(t, WithConsul(server.URL, "config/orders.yaml",
+		WithRemoteToken("acl-token"), WithRemoteCache(cachePath), WithRemoteTimeout(time.Second)))
+	if err := cached.loadRemote(context.Background()); err != nil {
+		t.Fatalf("unreachable backend with a cache: %v", err)
+	}
+	if cached.GetInt("workers") != 8 {
+		t.Fatalf("workers = %d, want the cached value", cached.GetInt("workers"))
+	}
+	if index := cached.remoteSources[0].index; index != 0 {
+		t.Fatalf("index = %d, want the watch to start over after a cache load", index)
+	}
+
+	uncached := remoteConfig(t, WithConsul(server.URL, "config/orders.yaml",
+		WithRemoteToken("acl-token"), WithRemoteCache(filepath.Join(t.TempDir(), "missing.json"))))
+	if err := uncached.loadRemote(context.Background()); err == nil {
+		t.Fatal("expected an error without a cached copy")
+	}
+}
+
+// fakeEtcd serves the v3 JSON gateway for one key. A watch from a compacted
+// revision is canceled with compact_revision, as etcd does.
+type fakeEtcd struct {
+	mu        sync.Mutex
+	value     string
+	revision  int64
+	compacted int64
+	token     string
+	ranges    int
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path == "/v3/auth/authenticate" {
+		f.token = fmt.Sprintf("token-%d", f.revision)
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": f.token})
+		return
+	}
+	if r.Header.Get("Authorization") != f.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = io.WriteString(w, `{"error":"invalid auth token"}`)
+		return
+	}
+	var body struct {
+		CreateRequest struct {
+			StartRevision int64 `json:"start_revision"`
+		} `json:"create_request"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	encoded := base64.StdEncoding.EncodeToString([]byte(f.value))
+	switch r.URL.Path {
+	case "/v3/kv/range":
+		f.ranges++
+		fmt.Fprintf(w, `{"header":{"revision":"%d"},"kvs":[{"key":"a2V5","value":"%s","mod_revision":"%d"}]}`, f.revision, encoded, f.revision)
+	case "/v3/watch":
+		fmt.Fprintf(w, `{"result":{"header":{"revision":"%d"},"created":true}}`+"\n", f.revision)
+		if body.CreateRequest.StartRevision <= f.compacted {
+			fmt.Fprintf(w, `{"result":{"header":{"revision":"%d"},"canceled":true,"compact_revision":"%d"}}`+"\n", f.revision, f.compacted)
+			return
+		}
+		fmt.Fprintf(w, `{"result":{"header":{"revision":"%d"},"events":[{"kv":{"key":"a2V5","value":"%s","mod_revision":"%d"}}]}}`+"\n",
+			f.revision, encoded, f.revision)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestEtcdBackend(t *testing.T) {
+	fake := &fakeEtcd{value: "workers: 2\n", revision: 5}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	cfg := remoteConfig(t, WithEtcd([]string{down.URL, server.URL}, "/config/orders.yaml",
+		WithRemoteCredentials("orders", "secret")))
+	if err := cfg.loadRemote(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GetInt("workers") != 2 {
+		t.Fatalf("workers = %d", cfg.GetInt("workers"))
+	}
+	backend := cfg.remoteSources[0].backend.(*etcdBackend)
+	if backend.current != 1 {
+		t.Fatalf("current endpoint = %d, want the reachable one", backend.current)
+	}
+	ctx := context.Background()
+
+	// A change arrives on the watch; an expired token is renewed once.
+	fake.mu.Lock()
+	fake.value, fake.revision, fake.token = "workers: 3\n", 7, "rotated"
+	fake.mu.Unlock()
+	value, next, err := backend.wait(ctx, 5)
+	if err != nil || string(value) != "workers: 3\n" || next != 7 {
+		t.Fatalf("wait = %q, %d, %v", value, next, err)
+	}
+
+	// A watch from a compacted revision falls back to a fresh read.
+	fake.mu.Lock()
+	fake.value, fake.revision, fake.compacted = "workers: 5\n", 12, 10
+	ranges := fake.ranges
+	fake.mu.Unlock()
+	value, next, err = backend.wait(ctx, 7)
+	if err != nil || string(value) != "workers: 5\n" || next != 12 {
+		t.Fatalf("wait after compaction = %q, %d, %v", value, next, err)
+	}
+	if fake.ranges != ranges+1 {
+		t.Fatalf("ranges = %d, want a fresh read after compaction", fake.ranges-ranges)
+	}
+}
+
+func TestRemoteOptionsValidate(t *testing.T) {
+	t.Setenv("CONSUL_HTTP_ADDR", "")
+	for name, opt := range map[string]Option{
+		"consul without address": WithConsul("", "config/orders.yaml"),
+		"consul without key":     WithConsul("http://consul:8500", "/"),
+		"etcd without endpoints": WithEtcd([]string{" "}, "/config/orders.yaml"),
+		"etcd without key":       WithEtcd([]string{"etcd:2379"}, ""),
+	} {
+		if err := opt(&Config{}); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
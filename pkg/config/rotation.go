@@ -0,0 +1,210 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SecretRotation is published on a RotationBus when a watched secret, such as
+// a database password or API key, changes value.
+type SecretRotation struct {
+	// Key is the config key of the secret, e.g. "DBPassword".
+	Key string
+	// Value is the new secret.
+	Value     string
+	RotatedAt time.Time
+}
+
+// String describes the rotation without its value, so it can be logged.
+func (r SecretRotation) String() string {
+	return fmt.Sprintf("secret %q rotated at %s", r.Key, r.RotatedAt.Format(time.RFC3339))
+}
+
+// RotationHandler reacts to a rotated secret, e.g. by reconnecting with it.
+type RotationHandler func(ctx context.Context, rotation SecretRotation) error
+
+// RotationBus delivers SecretRotation events to the components that hold the
+// secrets, so they pick up new credentials without a restart. Keys match
+// case-insensitively, like viper keys. The zero value is not usable; create
+// one with NewRotationBus.
+type RotationBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]*rotationSubscription
+}
+
+type rotationSubscription struct {
+	handler RotationHandler
+}
+
+// NewRotationBus creates an empty RotationBus.
+func NewRotationBus() *RotationBus {
+	return &RotationBus{handlers: map[string][]*rotationSubscription{}}
+}
+
+// Subscribe registers handler for rotations of key. It returns a function
+// that removes the subscription.
+func (b *RotationBus) Subscribe(key string, handler RotationHandler) (unsubscribe func()) {
+	if handler == nil {
+		return func() {}
+	}
+	key = rotationKey(key)
+	sub := &rotationSubscription{handler: handler}
+	b.mu.Lock()
+	b.handlers[key] = append(b.handlers[key], sub)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		subs := b.handlers[key]
+		for i, registered := range subs {
+			if registered == sub {
+				b.handlers[key] = append(subs[:i:i], subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers rotation to the handlers subscribed to its key, in
+// subscription order. Every handler runs even when an earlier one fails or
+// panics; the failures are joined into the returned error.
+func (b *RotationBus) Publish(ctx context.Context, rotation SecretRotation) error {
+	if rotation.RotatedAt.IsZero() {
+		rotation.RotatedAt = time.Now()
+	}
+	b.mu.RLock()
+	subs := append([]*rotationSubscription(nil), b.handlers[rotationKey(rotation.Key)]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, sub := range subs {
+		if err := runRotationHandler(ctx, sub.handler, rotation); err != nil {
+			errs = append(errs, fmt.Errorf("config: rotate %q: %w", rotation.Key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func runRotationHandler(ctx context.Context, handler RotationHandler, rotation SecretRotation) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(ctx, rotation)
+}
+
+func rotationKey(key string) string {
+	return strings.ToLower(strings.TrimSpace(key))
+}
+
+// WithSecretRotation watches keys for changes and publishes a SecretRotation
+// on bus for each one whose value differs after a reload, either from
+// WithWatch or from an explicit CheckSecrets call. The keys are also
+// registered as sensitive.
+func WithSecretRotation(bus *RotationBus, keys ...string) Option {
+	return func(c *Config) error {
+		if bus == nil {
+			return errors.New("secret rotation: bus is required")
+		}
+		c.rotation = bus
+		for _, k := range keys {
+			c.sensitiveKeys[k] = struct{}{}
+			c.secretKeys = append(c.secretKeys, k)
+		}
+		return nil
+	}
+}
+
+// recordSecrets remembers the current values of the watched secrets so later
+// reloads only publish real changes.
+func (c *Config) recordSecrets() {
+	c.secretsMu.Lock()
+	defer c.secretsMu.Unlock()
+	c.secretValues = make(map[string]string, len(c.secretKeys))
+	for _, k := range c.secretKeys {
+		c.secretValues[k] = c.GetString(k)
+	}
+}
+
+// CheckSecrets compares the secrets watched through WithSecretRotation with
+// their last known values and publishes a rotation for each change. WithWatch
+// calls it after every file reload; call it yourself after merging config from
+// elsewhere, e.g. a remote provider.
+func (c *Config) CheckSecrets(ctx context.Context) error {
+	if c.rotation == nil {
+		return nil
+	}
+	c.secretsMu.Lock()
+	var changed []SecretRotation
+	now := time.Now()
+	for _, k := range c.secretKeys {
+		value := c.GetString(k)
+		if value == c.secretValues[k] {
+			continue
+		}
+		c.secretValues[k] = value
+		changed = append(changed, SecretRotation{Key: k, Value: value, RotatedAt: now})
+	}
+	c.secretsMu.Unlock()
+
+	var errs []error
+	for _, rotation := range changed {
+		if err := c.rotation.Publish(ctx, rotation); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WatchSecretFile publishes a SecretRotation for key on bus whenever the
+// content of the file at path changes, checking every interval (10s by
+// default). It suits secrets mounted as files, such as Kubernetes secret
+// volumes, which are swapped in place without a config reload. Surrounding
+// whitespace is trimmed from the value. It returns an error if the file
+// cannot be read initially; otherwise it blocks until ctx is done, skipping
+// reads that fail or come back empty while the file is being replaced.
+func WatchSecretFile(ctx context.Context, bus *RotationBus, key, path string, interval time.Duration) error {
+	if bus == nil {
+		return errors.New("secret rotation: bus is required")
+	}
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("secret rotation: read %s: %w", path, err)
+	}
+	current = bytes.TrimSpace(current)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		next, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		next = bytes.TrimSpace(next)
+		if len(next) == 0 || bytes.Equal(next, current) {
+			continue
+		}
+		current = next
+		rotation := SecretRotation{Key: key, Value: string(next), RotatedAt: time.Now()}
+		if err := bus.Publish(ctx, rotation); err != nil {
+			log.Printf("config: %v", err)
+		}
+	}
+}
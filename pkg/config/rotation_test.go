@@ -0,0 +1,120 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotationBusPublish(t *testing.T) {
+	bus := NewRotationBus()
+	var calls []string
+	bus.Subscribe("DBPassword", func(_ context.Context, r SecretRotation) error {
+		calls = append(calls, "first:"+r.Value)
+		return errors.New("reconnect failed")
+	})
+	bus.Subscribe("dbpassword", func(context.Context, SecretRotation) error {
+		calls = append(calls, "second")
+		panic("nil pool")
+	})
+	unsubscribe := bus.Subscribe(" DBPASSWORD ", func(context.Context, SecretRotation) error {
+		calls = append(calls, "removed")
+		return nil
+	})
+	unsubscribe()
+	unsubscribe()
+
+	err := bus.Publish(context.Background(), SecretRotation{Key: "dbPassword", Value: "v2"})
+	if err == nil || !strings.Contains(err.Error(), "reconnect failed") || !strings.Contains(err.Error(), "panic: nil pool") {
+		t.Fatalf("err = %v, want both failures joined", err)
+	}
+	if strings.Join(calls, ",") != "first:v2,second" {
+		t.Fatalf("calls = %v", calls)
+	}
+	if err := bus.Publish(context.Background(), SecretRotation{Key: "other"}); err != nil {
+		t.Fatalf("publish without subscribers: %v", err)
+	}
+
+	rotation := SecretRotation{Key: "DBPassword", Value: "s3cret", RotatedAt: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)}
+	if s := rotation.String(); strings.Contains(s, "s3cret") {
+		t.Fatalf("String() = %q leaks the value", s)
+	}
+}
+
+func TestCheckSecretsPublishesChanges(t *testing.T) {
+	bus := NewRotationBus()
+	var rotations []SecretRotation
+	bus.Subscribe("DBPassword", func(_ context.Context, r SecretRotation) error {
+		rotations = append(rotations, r)
+		return nil
+	})
+	cfg := New(WithDefaults(map[string]interface{}{"DBPassword": "v1"}), WithSecretRotation(bus, "DBPassword"))
+
+	if err := cfg.CheckSecrets(context.Background()); err != nil || len(rotations) != 0 {
+		t.Fatalf("unchanged secret: err = %v, rotations = %v", err, rotations)
+	}
+	cfg.Set("DBPassword", "v2")
+	if err := cfg.CheckSecrets(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.CheckSecrets(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(rotations) != 1 || rotations[0].Value != "v2" {
+		t.Fatalf("rotations = %v, want one for v2", rotations)
+	}
+	if err := WithSecretRotation(nil)(cfg); err == nil {
+		t.Fatal("expected an error without a bus")
+	}
+}
+
+func TestWatchSecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("v1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	bus := NewRotationBus()
+	rotated := make(chan string, 4)
+	bus.Subscribe("DBPassword", func(_ context.Context, r SecretRotation) error {
+		rotated <- r.Value
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- WatchSecretFile(ctx, bus, "DBPassword", path, 10*time.Millisecond) }()
+
+	// An empty file, as while the secret is being replaced, is skipped.
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("v2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case value := <-rotated:
+		if value != "v2" {
+			t.Fatalf("rotated to %q, want v2", value)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("rotation was not published")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case value := <-rotated:
+		t.Fatalf("unexpected rotation to %q", value)
+	default:
+	}
+
+	if err := WatchSecretFile(context.Background(), bus, "DBPassword", filepath.Join(t.TempDir(), "missing"), 0); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
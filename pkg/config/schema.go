@@ -0,0 +1,405 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultEnvPrefix is the environment prefix of a Schema without EnvPrefix.
+const DefaultEnvPrefix = "APP"
+
+// Field types understood by Config.Validate. Other types are documented but
+// not checked.
+const (
+	TypeString   = "string"
+	TypeInt      = "int"
+	TypeFloat    = "float"
+	TypeBool     = "bool"
+	TypeDuration = "duration"
+	TypeStrings  = "[]string"
+	TypeInts     = "[]int"
+	TypeMap      = "map"
+)
+
+// Field declares one configuration key.
+type Field struct {
+	// Key is the dotted viper key, e.g. "database.max_conns".
+	Key string
+	// Type documents the value type ("string", "int", "bool", "duration",
+	// "[]string", ...). It is inferred from Default when empty.
+	Type string
+	// Default is applied by WithSchema and shown in generated docs.
+	Default any
+	// Description is a one-line explanation for generated docs.
+	Description string
+	// Required keys are checked by Schema.Validate.
+	Required bool
+	// Sensitive keys are masked like WithSensitiveKeys, and their defaults are
+	// left out of generated docs.
+	Sensitive bool
+	// Allowed lists the values the key may take; Config.Validate reports any
+	// other. Values compare by their printed form, so 8080 matches "8080".
+	Allowed []any
+}
+
+// FieldOption configures a key declared with Schema.Key.
+type FieldOption func(*Field)
+
+// Default sets the key's default value.
+func Default(value any) FieldOption {
+	return func(f *Field) {
+		f.Default = value
+	}
+}
+
+// Required makes Config.Validate report the key when it is unset or empty.
+func Required() FieldOption {
+	return func(f *Field) {
+		f.Required = true
+	}
+}
+
+// Sensitive masks the key's value in logs, prints and reports.
+func Sensitive() FieldOption {
+	return func(f *Field) {
+		f.Sensitive = true
+	}
+}
+
+// OneOf restricts the key to values.
+func OneOf(values ...any) FieldOption {
+	return func(f *Field) {
+		f.Allowed = append(f.Allowed, values...)
+	}
+}
+
+// Schema declares the configuration a service reads, so defaults, env
+// bindings, validation and docs come from one list.
+//
+//	var schema = config.Schema{Fields: []config.Field{
+//	  {Key: "service.port", Default: 8080, Description: "HTTP listen port"},
+//	  {Key: "database.dsn", Required: true, Sensitive: true, Description: "Postgres DSN"},
+//	}}
+//
+//	cfg := config.New(config.WithFile("config.yaml"), config.WithSchema(schema))
+//	if err := schema.Validate(cfg); err != nil { ... }
+type Schema struct {
+	// EnvPrefix prefixes env var names. Default: DefaultEnvPrefix.
+	EnvPrefix string
+	// Fields in the order they are documented.
+	Fields []Field
+}
+
+// NewSchema starts a schema whose keys are declared with Key. An empty
+// envPrefix means DefaultEnvPrefix.
+//
+//	var schema = config.NewSchema("ORDERS").
+//	  Key("service.port", config.TypeInt, "HTTP listen port", config.Default(8080)).
+//	  Key("log.level", config.TypeString, "Log level", config.Default("info"), config.OneOf("debug", "info", "warn", "error")).
+//	  Key("database.dsn", config.TypeString, "Postgres DSN", config.Required(), config.Sensitive())
+func NewSchema(envPrefix string) Schema {
+	return Schema{EnvPrefix: envPrefix}
+}
+
+// Key returns a copy of the schema with one more key, of typ (one of the
+// Type constants), described by description.
+func (s Schema) Key(key, typ, description string, opts ...FieldOption) Schema {
+	f := Field{Key: key, Type: typ, Description: description}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&f)
+		}
+	}
+	s.Fields = append(s.Fields[:len(s.Fields):len(s.Fields)], f)
+	return s
+}
+
+// EnvVar returns the environment variable overriding key, following WithEnv:
+// APP_DATABASE_MAX_CONNS for "database.max_conns".
+func (s Schema) EnvVar(key string) string {
+	prefix := s.EnvPrefix
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	name := strings.NewReplacer(".", "_", "-", "_").Replace(key)
+	return strings.ToUpper(prefix + "_" + name)
+}
+
+// Defaults returns the declared defaults, for WithDefaults.
+func (s Schema) Defaults() map[string]interface{} {
+	defaults := map[string]interface{}{}
+	for _, f := range s.Fields {
+		if f.Default != nil {
+			defaults[f.Key] = f.Default
+		}
+	}
+	return defaults
+}
+
+// Validate returns the issues of c.Validate(s) as one error, or nil.
+func (s Schema) Validate(c *Config) error {
+	return c.Validate(s).Err()
+}
+
+// WithSchema applies the schema's defaults, binds every key to its EnvVar and
+// registers sensitive keys.
+func WithSchema(s Schema) Option {
+	return func(c *Config) error {
+		if err := s.check(); err != nil {
+			return err
+		}
+		for _, f := range s.Fields {
+			if f.Default != nil {
+				c.SetDefault(f.Key, f.Default)
+			}
+			if err := c.BindEnv(f.Key, s.EnvVar(f.Key)); err != nil {
+				return fmt.Errorf("bind env for %s: %w", f.Key, err)
+			}
+			if f.Sensitive {
+				c.sensitiveKeys[f.Key] = struct{}{}
+			}
+		}
+		return nil
+	}
+}
+
+// WriteExampleYAML writes a commented example config file: every key nested
+// under its dotted path in declaration order, set to its default, with its
+// description and env var as comments. Sensitive keys are left empty.
+func (s Schema) WriteExampleYAML(w io.Writer) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+	root := &schemaNode{}
+	for i := range s.Fields {
+		root.insert(strings.Split(s.Fields[i].Key, "."), &s.Fields[i])
+	}
+
+	var b strings.Builder
+	b.WriteString("# Example configuration generated from the config schema.\n")
+	b.WriteString("# Every key can also be set through the environment variable noted above it.\n")
+	for _, child := range root.children {
+		b.WriteString("\n")
+		s.writeNode(&b, child, 0)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteEnvTable writes a Markdown table of the environment variables, keys,
+// types, defaults and descriptions of the schema.
+func (s Schema) WriteEnvTable(w io.Writer) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("| Variable | Key | Type | Default | Required | Description |\n")
+	b.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+	for _, f := range s.Fields {
+		def := ""
+		switch {
+		case f.Sensitive && f.Default != nil:
+			def = "(sensitive)"
+		case f.Default != nil:
+			def = "`" + formatDefault(f.Default) + "`"
+		}
+		required := ""
+		if f.Required {
+			required = "yes"
+		}
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s | %s | %s |\n",
+			s.EnvVar(f.Key), f.Key, fieldType(f), def, required, escapeTableCell(f.Description))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// check rejects empty and duplicate keys, and keys that are both a value and a
+// parent of other keys, which cannot be written as YAML.
+func (s Schema) check() error {
+	seen := make(map[string]struct{}, len(s.Fields))
+	for _, f := range s.Fields {
+		if f.Key == "" || strings.Contains(f.Key, "..") || strings.HasPrefix(f.Key, ".") || strings.HasSuffix(f.Key, ".") {
+			return fmt.Errorf("config schema: invalid key %q", f.Key)
+		}
+		if _, dup := seen[f.Key]; dup {
+			return fmt.Errorf("config schema: duplicate key %q", f.Key)
+		}
+		seen[f.Key] = struct{}{}
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i := 1; i < len(keys); i++ {
+		if strings.HasPrefix(keys[i], keys[i-1]+".") {
+			return fmt.Errorf("config schema: key %q is also the parent of %q", keys[i-1], keys[i])
+		}
+	}
+	return nil
+}
+
+type schemaNode struct {
+	name     string
+	field    *Field
+	children []*schemaNode
+}
+
+func (n *schemaNode) insert(path []string, f *Field) {
+	for _, child := range n.children {
+		if child.name == path[0] {
+			if len(path) > 1 {
+				child.insert(path[1:], f)
+			}
+			return
+		}
+	}
+	child := &schemaNode{name: path[0]}
+	n.children = append(n.children, child)
+	if len(path) == 1 {
+		child.field = f
+		return
+	}
+	child.insert(path[1:], f)
+}
+
+func (s Schema) writeNode(b *strings.Builder, n *schemaNode, depth int) {
+	indent := strings.Repeat("  ", depth)
+	if n.field == nil {
+		fmt.Fprintf(b, "%s%s:\n", indent, n.name)
+		for _, child := range n.children {
+			s.writeNode(b, child, depth+1)
+		}
+		return
+	}
+
+	f := n.field
+	if f.Description != "" {
+		fmt.Fprintf(b, "%s# %s\n", indent, f.Description)
+	}
+	var notes []string
+	notes = append(notes, "env: "+s.EnvVar(f.Key))
+	notes = append(notes, "type: "+fieldType(*f))
+	if f.Required {
+		notes = append(notes, "required")
+	}
+	if f.Sensitive {
+		notes = append(notes, "sensitive; prefer the env var")
+	}
+	fmt.Fprintf(b, "%s# %s\n", indent, strings.Join(notes, ", "))
+
+	value := exampleValue(*f)
+	if value == "" {
+		fmt.Fprintf(b, "%s%s:\n", indent, n.name)
+		return
+	}
+	fmt.Fprintf(b, "%s%s: %s\n", indent, n.name, value)
+}
+
+// exampleValue renders the default of f as a YAML scalar or flow sequence.
+// Keys without a default, and sensitive keys, get an empty string, list or map,
+// or no value for other types.
+func exampleValue(f Field) string {
+	if f.Default != nil && !f.Sensitive {
+		return yamlValue(f.Default)
+	}
+	switch fieldType(f) {
+	case "string", "duration":
+		return `""`
+	case "[]string", "[]int":
+		return "[]"
+	case "map":
+		return "{}"
+	default:
+		return ""
+	}
+}
+
+func yamlValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case time.Duration:
+		return strconv.Quote(v.String())
+	case []string:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = strconv.Quote(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case []int:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = strconv.Itoa(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]string:
+		if len(v) == 0 {
+			return "{}"
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, k := range keys {
+			items[i] = strconv.Quote(k) + ": " + strconv.Quote(v[k])
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// formatDefault renders a default for the env table, as it would be written
+// in the environment.
+func formatDefault(v any) string {
+	switch v := v.(type) {
+	case []string:
+		return strings.Join(v, ",")
+	case []int:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = strconv.Itoa(item)
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func fieldType(f Field) string {
+	if f.Type != "" {
+		return f.Type
+	}
+	switch f.Default.(type) {
+	case nil, string:
+		return "string"
+	case bool:
+		return "bool"
+	case int, int32, int64, uint, uint32, uint64:
+		return "int"
+	case float32, float64:
+		return "float"
+	case time.Duration:
+		return "duration"
+	case []string:
+		return "[]string"
+	case []int:
+		return "[]int"
+	case map[string]string, map[string]any:
+		return "map"
+	default:
+		return fmt.Sprintf("%T", f.Default)
+	}
+}
+
+func escapeTableCell(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", `\|`), "\n", " ")
+}
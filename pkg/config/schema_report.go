@@ -0,0 +1,259 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// IssueKind classifies a SchemaIssue.
+type IssueKind string
+
+const (
+	// IssueMissing is a required key that is unset or empty.
+	IssueMissing IssueKind = "missing"
+	// IssueInvalid is a value that cannot be read as the key's type.
+	IssueInvalid IssueKind = "invalid"
+	// IssueNotAllowed is a value outside the key's allowed values.
+	IssueNotAllowed IssueKind = "not_allowed"
+)
+
+// Sources of an EffectiveValue.
+const (
+	SourceEnv      = "env"
+	SourceConfig   = "config"
+	SourceDefault  = "default"
+	SourceOverride = "override"
+	SourceUnset    = "unset"
+)
+
+const redacted = "***REDACTED***"
+
+// SchemaIssue is a key whose value does not satisfy the schema.
+type SchemaIssue struct {
+	Key     string
+	EnvVar  string
+	Kind    IssueKind
+	Message string
+}
+
+// String formats the issue as "key: message (env ENV_VAR)".
+func (i SchemaIssue) String() string {
+	return fmt.Sprintf("%s: %s (env %s)", i.Key, i.Message, i.EnvVar)
+}
+
+// EffectiveValue is the value a declared key resolved to.
+type EffectiveValue struct {
+	Key    string
+	EnvVar string
+	// Value is nil for unset keys, and masked for sensitive ones.
+	Value     any
+	Sensitive bool
+	// Source is where the value came from: SourceEnv, SourceConfig (a file
+	// or remote document), SourceDefault, SourceOverride (a flag or Set) or
+	// SourceUnset.
+	Source string
+}
+
+// SchemaReport is the result of Config.Validate: every declared key's
+// effective value and every issue found, in declaration order.
+type SchemaReport struct {
+	Values []EffectiveValue
+	Issues []SchemaIssue
+}
+
+// OK reports whether there are no issues.
+func (r SchemaReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Err returns the issues joined into one error, or nil.
+func (r SchemaReport) Err() error {
+	var errs []error
+	for _, issue := range r.Issues {
+		errs = append(errs, errors.New(issue.String()))
+	}
+	return errors.Join(errs...)
+}
+
+// WriteTo writes the effective config as a table, sensitive values masked,
+// followed by the issues. Print it at startup so the running configuration
+// is in the logs:
+//
+//	report := cfg.Validate(schema)
+//	report.WriteTo(os.Stderr)
+//	if err := report.Err(); err != nil {
+//	  log.Fatal(err)
+//	}
+func (r SchemaReport) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Effective configuration (%d keys, %d issues):\n", len(r.Values), len(r.Issues))
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  KEY\tVALUE\tSOURCE\tENV")
+	for _, v := range r.Values {
+		value := "-"
+		if v.Value != nil {
+			value = fmt.Sprint(v.Value)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", v.Key, value, v.Source, v.EnvVar)
+	}
+	tw.Flush()
+	if len(r.Issues) > 0 {
+		b.WriteString("Issues:\n")
+		for _, issue := range r.Issues {
+			fmt.Fprintf(&b, "  %s\n", issue)
+		}
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// Validate checks c against every key of s and reports all problems at once:
+// required keys that are unset or empty, values that cannot be read as the
+// key's type, and values outside its allowed values. The report also lists
+// the effective value and source of every key, for a startup printout.
+func (c *Config) Validate(s Schema) SchemaReport {
+	var report SchemaReport
+	for _, f := range s.Fields {
+		env := s.EnvVar(f.Key)
+		sensitive := f.Sensitive || c.isSensitive(strings.ToLower(f.Key))
+		value := c.Get(f.Key)
+		set := c.IsSet(f.Key) && !isEmptyValue(value)
+
+		effective := EffectiveValue{Key: f.Key, EnvVar: env, Sensitive: sensitive, Source: c.valueSource(f, env, value, set)}
+		if set {
+			effective.Value = value
+			if sensitive {
+				effective.Value = redacted
+			}
+		}
+		report.Values = append(report.Values, effective)
+
+		issue := SchemaIssue{Key: f.Key, EnvVar: env}
+		switch {
+		case !set && f.Required:
+			issue.Kind, issue.Message = IssueMissing, "required key is not set"
+		case !set:
+			continue
+		default:
+			typed, err := typedValue(fieldType(f), value)
+			if err != nil {
+				issue.Kind, issue.Message = IssueInvalid, "not a valid "+fieldType(f)
+				if !sensitive {
+					issue.Message = fmt.Sprintf("%v is not a valid %s", value, fieldType(f))
+				}
+				break
+			}
+			if len(f.Allowed) == 0 || allowedValue(typed, f.Allowed) {
+				continue
+			}
+			issue.Kind = IssueNotAllowed
+			issue.Message = "must be one of " + formatAllowed(f.Allowed)
+			if !sensitive {
+				issue.Message = fmt.Sprintf("%v is not allowed; must be one of %s", value, formatAllowed(f.Allowed))
+			}
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+	return report
+}
+
+// valueSource reports where the value of f came from.
+func (c *Config) valueSource(f Field, env string, value any, set bool) string {
+	switch {
+	case !set:
+		return SourceUnset
+	case os.Getenv(env) != "":
+		return SourceEnv
+	case c.InConfig(f.Key):
+		return SourceConfig
+	case f.Default != nil && fmt.Sprint(value) == fmt.Sprint(f.Default):
+		return SourceDefault
+	}
+	return SourceOverride
+}
+
+func isEmptyValue(value any) bool {
+	if value == nil {
+		return true
+	}
+	if s, ok := value.(string); ok {
+		return s == ""
+	}
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return false
+}
+
+// typedValue decodes value as typ, like UnmarshalInto would. Types without a
+// check are returned as is.
+func typedValue(typ string, value any) (any, error) {
+	var target any
+	switch typ {
+	case TypeString:
+		var s string
+		target = &s
+	case TypeInt:
+		var n int64
+		target = &n
+	case TypeFloat:
+		var n float64
+		target = &n
+	case TypeBool:
+		var b bool
+		target = &b
+	case TypeDuration:
+		if s, ok := value.(string); ok {
+			return time.ParseDuration(s)
+		}
+		var d time.Duration
+		target = &d
+	case TypeStrings:
+		var s []string
+		target = &s
+	case TypeInts:
+		var n []int64
+		target = &n
+	case TypeMap:
+		var m map[string]any
+		target = &m
+	default:
+		return value, nil
+	}
+	if err := decodeValue(value, target); err != nil {
+		return nil, err
+	}
+	return reflect.ValueOf(target).Elem().Interface(), nil
+}
+
+func allowedValue(value any, allowed []any) bool {
+	if list := reflect.ValueOf(value); list.Kind() == reflect.Slice {
+		for i := 0; i < list.Len(); i++ {
+			if !allowedValue(list.Index(i).Interface(), allowed) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, a := range allowed {
+		if fmt.Sprint(a) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatAllowed(allowed []any) string {
+	items := make([]string, len(allowed))
+	for i, a := range allowed {
+		items[i] = fmt.Sprint(a)
+	}
+	return strings.Join(items, ", ")
+}
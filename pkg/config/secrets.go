@@ -0,0 +1,370 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// DefaultSecretScheme is the placeholder scheme of a provider registered
+// without WithSecretScheme: secret://path#key.
+const DefaultSecretScheme = "secret"
+
+// DefaultSecretRefreshInterval is how often WatchSecrets re-reads secrets
+// that have no lease.
+const DefaultSecretRefreshInterval = 5 * time.Minute
+
+// Secret is a value read from a secrets store.
+type Secret struct {
+	// Value is the raw secret. A placeholder without #key resolves to it.
+	Value string
+	// Fields are the named values of a structured secret, e.g. a Vault KV
+	// entry. When nil, #key is looked up in Value parsed as a JSON object.
+	Fields map[string]string
+	// LeaseID identifies a lease that SecretRenewer can extend.
+	LeaseID string
+	// LeaseDuration is how long the secret is valid; zero means it does not
+	// expire and is re-read every refresh interval.
+	LeaseDuration time.Duration
+	// Renewable reports whether the lease can be extended.
+	Renewable bool
+}
+
+// SecretsProvider reads secrets referenced by config placeholders.
+type SecretsProvider interface {
+	// GetSecret reads the secret at path, the part of a placeholder between
+	// the scheme and '#'.
+	GetSecret(ctx context.Context, path string) (Secret, error)
+}
+
+// SecretRenewer is implemented by providers whose secrets carry leases. When
+// a renewal fails the secret is read again instead.
+type SecretRenewer interface {
+	RenewSecret(ctx context.Context, secret Secret) (Secret, error)
+}
+
+// SecretsOption configures WithSecretsProvider.
+type SecretsOption func(*secretSource)
+
+// WithSecretScheme sets the placeholder scheme the provider resolves, so
+// several providers can be used side by side, e.g. vault:// and aws-sm://.
+// Default: DefaultSecretScheme.
+func WithSecretScheme(scheme string) SecretsOption {
+	return func(s *secretSource) {
+		if scheme != "" {
+			s.scheme = scheme
+		}
+	}
+}
+
+// WithSecretRefreshInterval sets how often WatchSecrets re-reads secrets
+// without a lease. Default: DefaultSecretRefreshInterval.
+func WithSecretRefreshInterval(d time.Duration) SecretsOption {
+	return func(s *secretSource) {
+		if d > 0 {
+			s.refresh = d
+		}
+	}
+}
+
+// WithSecretsTimeout bounds each read or renewal. Default: 10s.
+func WithSecretsTimeout(d time.Duration) SecretsOption {
+	return func(s *secretSource) {
+		if d > 0 {
+			s.timeout = d
+		}
+	}
+}
+
+type secretSource struct {
+	provider SecretsProvider
+	scheme   string
+	refresh  time.Duration
+	timeout  time.Duration
+}
+
+// WithSecretsProvider resolves config values of the form
+// "<scheme>://path#key" through provider when the config is loaded. Resolved
+// keys are registered as sensitive, and as watched secrets for
+// WithSecretRotation, so a value changed by WatchSecrets is published on the
+// rotation bus.
+//
+//	vault, _ := config.NewVaultProvider(config.VaultConfig{})
+//	cfg := config.New(
+//	  config.WithFile("config.yaml"), // DBPassword: secret://secret/data/orders/db#password
+//	  config.WithSecretsProvider(vault),
+//	)
+//
+// New fails when a placeholder cannot be resolved. Resolved values are set
+// like Set does, as overrides above every other source, and placeholders are
+// only read at load: a reload that edits or removes a placeholder, or an env
+// variable for the key, has no effect until restart. Change a secret in the
+// store instead; WatchSecrets picks it up.
+func WithSecretsProvider(provider SecretsProvider, opts ...SecretsOption) Option {
+	return func(c *Config) error {
+		if provider == nil {
+			return errors.New("secrets: provider is required")
+		}
+		src := &secretSource{provider: provider, scheme: DefaultSecretScheme, refresh: DefaultSecretRefreshInterval, timeout: 10 * time.Second}
+		for _, opt := range opts {
+			if opt != nil {
+				opt(src)
+			}
+		}
+		for _, existing := range c.secretSources {
+			if existing.scheme == src.scheme {
+				return fmt.Errorf("secrets: scheme %q already has a provider", src.scheme)
+			}
+		}
+		c.secretSources = append(c.secretSources, src)
+		return nil
+	}
+}
+
+// resolvedSecret is a secret read for one or more config keys.
+type resolvedSecret struct {
+	source   *secretSource
+	path     string
+	secret   Secret
+	readAt   time.Time
+	bindings []secretBinding
+}
+
+type secretBinding struct {
+	key   string // config key
+	field string // #key in the placeholder, or empty
+}
+
+func (r *resolvedSecret) due() time.Time {
+	if r.secret.LeaseDuration > 0 {
+		return r.readAt.Add(r.secret.LeaseDuration * 2 / 3)
+	}
+	return r.readAt.Add(r.source.refresh)
+}
+
+// resolveSecrets replaces every placeholder with its secret.
+func (c *Config) resolveSecrets(ctx context.Context) error {
+	if len(c.secretSources) == 0 {
+		return nil
+	}
+	resolved := map[string]*resolvedSecret{}
+	var order []*resolvedSecret
+	for _, key := range c.AllKeys() {
+		raw, ok := c.Get(key).(string)
+		if !ok {
+			continue
+		}
+		src, path, field, ok := c.parseSecretRef(raw)
+		if !ok {
+			continue
+		}
+		id := src.scheme + "://" + path
+		r := resolved[id]
+		if r == nil {
+			r = &resolvedSecret{source: src, path: path}
+			resolved[id] = r
+			order = append(order, r)
+		}
+		r.bindings = append(r.bindings, secretBinding{key: key, field: field})
+	}
+
+	var errs []error
+	for _, r := range order {
+		secret, err := r.source.get(ctx, r.path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		r.secret, r.readAt = secret, time.Now()
+		if err := c.applySecret(r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	c.secretsMu.Lock()
+	c.resolvedSecrets = order
+	c.secretsMu.Unlock()
+	for _, r := range order {
+		for _, b := range r.bindings {
+			c.sensitiveKeys[b.key] = struct{}{}
+			c.secretKeys = append(c.secretKeys, b.key)
+		}
+	}
+	return nil
+}
+
+// parseSecretRef splits "<scheme>://path#key" for a registered scheme.
+func (c *Config) parseSecretRef(raw string) (*secretSource, string, string, bool) {
+	for _, src := range c.secretSources {
+		rest, ok := strings.CutPrefix(raw, src.scheme+"://")
+		if !ok {
+			continue
+		}
+		path, field := rest, ""
+		if i := strings.LastIndex(rest, "#"); i >= 0 {
+			path, field = rest[:i], rest[i+1:]
+		}
+		return src, path, field, path != ""
+	}
+	return nil, "", "", false
+}
+
+func (s *secretSource) get(ctx context.Context, path string) (Secret, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	secret, err := s.provider.GetSecret(ctx, path)
+	if err != nil {
+		return Secret{}, fmt.Errorf("secrets: read %s://%s: %w", s.scheme, path, err)
+	}
+	return secret, nil
+}
+
+// applySecret sets the config keys bound to r to its current values, as
+// overrides (see WithSecretsProvider).
+func (c *Config) applySecret(r *resolvedSecret) error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	for _, b := range r.bindings {
+		value, err := r.secret.field(b.field)
+		if err != nil {
+			return fmt.Errorf("secrets: %s://%s#%s for %s: %w", r.source.scheme, r.path, b.field, b.key, err)
+		}
+		c.Set(b.key, value)
+	}
+	return nil
+}
+
+func (s Secret) field(name string) (string, error) {
+	if name == "" {
+		if s.Value == "" && s.Fields != nil {
+			data, err := json.Marshal(s.Fields)
+			return string(data), err
+		}
+		return s.Value, nil
+	}
+	fields := s.Fields
+	if fields == nil {
+		var parsed map[string]any
+		if err := json.Unmarshal([]byte(s.Value), &parsed); err != nil {
+			return "", errors.New("secret is not a JSON object")
+		}
+		fields = make(map[string]string, len(parsed))
+		for k, v := range parsed {
+			if str, ok := v.(string); ok {
+				fields[k] = str
+			} else {
+				fields[k] = fmt.Sprint(v)
+			}
+		}
+	}
+	value, ok := fields[name]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %q", name)
+	}
+	return value, nil
+}
+
+// RefreshSecrets renews or re-reads the resolved secrets that are due: leased
+// secrets after two thirds of their lease, others every refresh interval.
+// Changed values are set on the config and published through CheckSecrets
+// and NotifyChanges. A secret that cannot be refreshed keeps its last value.
+func (c *Config) RefreshSecrets(ctx context.Context) error {
+	now := time.Now()
+	c.secretsMu.Lock()
+	var due []*resolvedSecret
+	for _, r := range c.resolvedSecrets {
+		if !now.Before(r.due()) {
+			due = append(due, r)
+		}
+	}
+	c.secretsMu.Unlock()
+
+	var errs []error
+	for _, r := range due {
+		c.secretsMu.Lock()
+		current := r.secret
+		c.secretsMu.Unlock()
+		secret, err := r.source.refreshSecret(ctx, r.path, current)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c.secretsMu.Lock()
+		r.secret, r.readAt = secret, time.Now()
+		err = c.applySecret(r)
+		c.secretsMu.Unlock()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := c.CheckSecrets(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if len(due) > 0 {
+		c.NotifyChanges()
+	}
+	return errors.Join(errs...)
+}
+
+func (s *secretSource) refreshSecret(ctx context.Context, path string, current Secret) (Secret, error) {
+	if renewer, ok := s.provider.(SecretRenewer); ok && current.Renewable && current.LeaseID != "" {
+		renewCtx, cancel := context.WithTimeout(ctx, s.timeout)
+		renewed, err := renewer.RenewSecret(renewCtx, current)
+		cancel()
+		if err == nil {
+			return renewed, nil
+		}
+		log.Printf("config: secrets: renew lease of %s://%s: %v; reading it again", s.scheme, path, err)
+	}
+	return s.get(ctx, path)
+}
+
+// WatchSecrets calls RefreshSecrets whenever the next resolved secret is due,
+// until ctx is done. Failures are logged and retried at the refresh interval.
+func (c *Config) WatchSecrets(ctx context.Context) {
+	for {
+		wait := c.nextSecretRefresh()
+		if wait < 0 {
+			return
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := c.RefreshSecrets(ctx); err != nil {
+			log.Printf("config: %v", err)
+		}
+	}
+}
+
+// nextSecretRefresh returns the time until the next secret is due, at least a
+// second so a failing provider is not hammered, or -1 without secrets.
+func (c *Config) nextSecretRefresh() time.Duration {
+	c.secretsMu.Lock()
+	defer c.secretsMu.Unlock()
+	if len(c.resolvedSecrets) == 0 {
+		return -1
+	}
+	var next time.Time
+	for _, r := range c.resolvedSecrets {
+		due := r.due()
+		if !due.After(time.Now()) {
+			// failed last time: retry after the refresh interval
+			due = time.Now().Add(min(r.source.refresh, time.Minute))
+		}
+		if next.IsZero() || due.Before(next) {
+			next = due
+		}
+	}
+	return max(time.Until(next), time.Second)
+}
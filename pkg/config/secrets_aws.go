@@ -0,0 +1,143 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/internal/sigv4"
+)
+
+// AWSCredentials sign AWS requests.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// AWSSecretsManagerConfig configures an AWSSecretsManagerProvider.
+type AWSSecretsManagerConfig struct {
+	// Region of the secrets. Default: $AWS_REGION, then $AWS_DEFAULT_REGION.
+	Region string
+	// Credentials returns the credentials for each request, e.g. from an
+	// assumed role. Default: static credentials from $AWS_ACCESS_KEY_ID,
+	// $AWS_SECRET_ACCESS_KEY and $AWS_SESSION_TOKEN.
+	Credentials func(ctx context.Context) (AWSCredentials, error)
+	// Endpoint overrides https://secretsmanager.<region>.amazonaws.com.
+	Endpoint string
+	// HTTPClient defaults to a client with a 10s timeout.
+	HTTPClient *http.Client
+}
+
+// AWSSecretsManagerProvider reads secrets from AWS Secrets Manager with
+// GetSecretValue, signing requests with Signature Version 4. A placeholder
+// path is the secret name or ARN, and #key selects a field of a JSON secret:
+// aws-sm://orders/db#password. Secrets Manager has no leases, so rotated
+// secrets are picked up at the refresh interval.
+type AWSSecretsManagerProvider struct {
+	region      string
+	endpoint    string
+	credentials func(ctx context.Context) (AWSCredentials, error)
+	client      *http.Client
+	now         func() time.Time
+}
+
+// NewAWSSecretsManagerProvider returns an AWSSecretsManagerProvider, filling
+// unset fields from the standard AWS_* environment variables.
+func NewAWSSecretsManagerProvider(cfg AWSSecretsManagerConfig) (*AWSSecretsManagerProvider, error) {
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_REGION")
+	}
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if cfg.Region == "" {
+		return nil, errors.New("secrets: aws region is required")
+	}
+	if cfg.Credentials == nil {
+		creds := AWSCredentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+		if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+			return nil, errors.New("secrets: aws credentials are required")
+		}
+		cfg.Credentials = func(context.Context) (AWSCredentials, error) { return creds, nil }
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://secretsmanager." + cfg.Region + ".amazonaws.com"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &AWSSecretsManagerProvider{
+		region:      cfg.Region,
+		endpoint:    strings.TrimRight(cfg.Endpoint, "/"),
+		credentials: cfg.Credentials,
+		client:      cfg.HTTPClient,
+		now:         time.Now,
+	}, nil
+}
+
+// GetSecret implements SecretsProvider.
+func (p *AWSSecretsManagerProvider) GetSecret(ctx context.Context, path string) (Secret, error) {
+	body, err := json.Marshal(map[string]string{"SecretId": path})
+	if err != nil {
+		return Secret{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return Secret{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+
+	creds, err := p.credentials(ctx)
+	if err != nil {
+		return Secret{}, fmt.Errorf("aws secrets manager: credentials: %w", err)
+	}
+	sigv4.SignRequest(req, sigv4.HashHex(body), sigv4.Credentials(creds), p.region, "secretsmanager", p.now())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Secret{}, fmt.Errorf("aws secrets manager: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Secret{}, fmt.Errorf("aws secrets manager: read response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(data, &apiErr)
+		return Secret{}, fmt.Errorf("aws secrets manager: %d %s: %s", resp.StatusCode, apiErr.Type, apiErr.Message)
+	}
+
+	var out struct {
+		SecretString string `json:"SecretString"`
+		SecretBinary string `json:"SecretBinary"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return Secret{}, fmt.Errorf("aws secrets manager: decode response: %w", err)
+	}
+	if out.SecretString == "" && out.SecretBinary != "" {
+		decoded, err := base64.StdEncoding.DecodeString(out.SecretBinary)
+		if err != nil {
+			return Secret{}, fmt.Errorf("aws secrets manager: decode SecretBinary: %w", err)
+		}
+		return Secret{Value: string(decoded)}, nil
+	}
+	return Secret{Value: out.SecretString}, nil
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAWSSecretsManagerProvider(t *testing.T) {
+	secrets := map[string]string{
+		"orders/db":     `{"SecretString":"{\"password\":\"hunter2\"}"}`,
+		"orders/binary": `{"SecretBinary":"` + base64.StdEncoding.EncodeToString([]byte("raw-bytes")) + `"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20261016/eu-west-1/secretsmanager/aws4_request, ") ||
+			!strings.Contains(auth, "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target,") {
+			t.Errorf("Authorization = %q", auth)
+		}
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" || r.Header.Get("X-Amz-Security-Token") != "session" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		var body struct{ SecretId string }
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+		response, ok := secrets[body.SecretId]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"__type":"ResourceNotFoundException","message":"Secrets Manager can't find the specified secret."}`)
+			return
+		}
+		_, _ = io.WriteString(w, response)
+	}))
+	defer server.Close()
+
+	provider, err := NewAWSSecretsManagerProvider(AWSSecretsManagerConfig{
+		Region:   "eu-west-1",
+		Endpoint: server.URL,
+		Credentials: func(context.Context) (AWSCredentials, error) {
+			return AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", SessionToken: "session"}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.now = func() time.Time { return time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	secret, err := provider.GetSecret(ctx, "orders/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password, err := secret.field("password"); err != nil || password != "hunter2" {
+		t.Fatalf("password = %q, %v", password, err)
+	}
+	if secret, err := provider.GetSecret(ctx, "orders/binary"); err != nil || secret.Value != "raw-bytes" {
+		t.Fatalf("binary secret = %+v, %v", secret, err)
+	}
+	if _, err := provider.GetSecret(ctx, "orders/missing"); err == nil || !strings.Contains(err.Error(), "ResourceNotFoundException") {
+		t.Fatalf("missing secret error = %v", err)
+	}
+}
+
+func TestNewAWSSecretsManagerProviderFromEnv(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "ap-south-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	if _, err := NewAWSSecretsManagerProvider(AWSSecretsManagerConfig{}); err == nil {
+		t.Fatal("expected an error without credentials")
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	provider, err := NewAWSSecretsManagerProvider(AWSSecretsManagerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.region != "ap-south-1" || provider.endpoint != "https://secretsmanager.ap-south-1.amazonaws.com" {
+		t.Fatalf("region = %q, endpoint = %q", provider.region, provider.endpoint)
+	}
+
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	if _, err := NewAWSSecretsManagerProvider(AWSSecretsManagerConfig{}); err == nil {
+		t.Fatal("expected an error without a region")
+	}
+}
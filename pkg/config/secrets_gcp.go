@@ -0,0 +1,178 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// GCPSecretManagerConfig configures a GCPSecretManagerProvider.
+type GCPSecretManagerConfig struct {
+	// Project resolves short secret names. Default: $GOOGLE_CLOUD_PROJECT.
+	Project string
+	// TokenSource returns an OAuth2 access token for each request, e.g. from
+	// golang.org/x/oauth2/google. Default: the service account token of the
+	// GCE/GKE metadata server.
+	TokenSource func(ctx context.Context) (string, error)
+	// Endpoint overrides https://secretmanager.googleapis.com.
+	Endpoint string
+	// HTTPClient defaults to a client with a 10s timeout.
+	HTTPClient *http.Client
+}
+
+// GCPSecretManagerProvider reads secrets from Google Cloud Secret Manager
+// over its REST API. A placeholder path is a version resource name
+// (projects/p/secrets/s/versions/v), a secret resource name (projects/p/secrets/s,
+// read at "latest"), or a secret name in the configured project, optionally
+// with a version: gcp-sm://orders-db/versions/3#password. #key selects a
+// field of a JSON secret. Secret Manager has no leases, so new versions are
+// picked up at the refresh interval.
+type GCPSecretManagerProvider struct {
+	project  string
+	endpoint string
+	token    func(ctx context.Context) (string, error)
+	client   *http.Client
+}
+
+// NewGCPSecretManagerProvider returns a GCPSecretManagerProvider.
+func NewGCPSecretManagerProvider(cfg GCPSecretManagerConfig) (*GCPSecretManagerProvider, error) {
+	if cfg.Project == "" {
+		cfg.Project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://secretmanager.googleapis.com"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.TokenSource == nil {
+		cfg.TokenSource = (&gcpMetadataToken{url: gcpMetadataTokenURL, client: cfg.HTTPClient}).Token
+	}
+	return &GCPSecretManagerProvider{
+		project:  cfg.Project,
+		endpoint: strings.TrimRight(cfg.Endpoint, "/"),
+		token:    cfg.TokenSource,
+		client:   cfg.HTTPClient,
+	}, nil
+}
+
+// GetSecret implements SecretsProvider.
+func (p *GCPSecretManagerProvider) GetSecret(ctx context.Context, path string) (Secret, error) {
+	name, err := p.versionName(path)
+	if err != nil {
+		return Secret{}, err
+	}
+	token, err := p.token(ctx)
+	if err != nil {
+		return Secret{}, fmt.Errorf("gcp secret manager: token: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.endpoint+"/v1/"+name+":access", nil)
+	if err != nil {
+		return Secret{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Secret{}, fmt.Errorf("gcp secret manager: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Secret{}, fmt.Errorf("gcp secret manager: read response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+				Status  string `json:"status"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(data, &apiErr)
+		return Secret{}, fmt.Errorf("gcp secret manager: %d %s: %s", resp.StatusCode, apiErr.Error.Status, apiErr.Error.Message)
+	}
+
+	var out struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return Secret{}, fmt.Errorf("gcp secret manager: decode response: %w", err)
+	}
+	value, err := base64.StdEncoding.DecodeString(out.Payload.Data)
+	if err != nil {
+		return Secret{}, fmt.Errorf("gcp secret manager: decode payload: %w", err)
+	}
+	return Secret{Value: string(value)}, nil
+}
+
+// versionName expands path to projects/<p>/secrets/<s>/versions/<v>.
+func (p *GCPSecretManagerProvider) versionName(path string) (string, error) {
+	name := strings.Trim(path, "/")
+	if !strings.HasPrefix(name, "projects/") {
+		if p.project == "" {
+			return "", fmt.Errorf("gcp secret manager: %s: project is required for short secret names", path)
+		}
+		name = "projects/" + p.project + "/secrets/" + name
+	}
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+	return name, nil
+}
+
+// gcpMetadataToken caches the access token of the metadata server until
+// shortly before it expires.
+type gcpMetadataToken struct {
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (t *gcpMetadataToken) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && time.Now().Before(t.expires) {
+		return t.token, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server: %d", resp.StatusCode)
+	}
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	if out.AccessToken == "" {
+		return "", errors.New("metadata server returned no token")
+	}
+	t.token = out.AccessToken
+	t.expires = time.Now().Add(time.Duration(out.ExpiresIn)*time.Second - time.Minute)
+	return t.token, nil
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGCPSecretManagerVersionName(t *testing.T) {
+	provider := &GCPSecretManagerProvider{project: "orders-prod"}
+	tests := map[string]string{
+		"db":                          "projects/orders-prod/secrets/db/versions/latest",
+		"db/versions/3":               "projects/orders-prod/secrets/db/versions/3",
+		"projects/shared/secrets/tls": "projects/shared/secrets/tls/versions/latest",
+		"/projects/shared/secrets/tls/versions/7": "projects/shared/secrets/tls/versions/7",
+	}
+	for path, want := range tests {
+		if got, err := provider.versionName(path); err != nil || got != want {
+			t.Fatalf("versionName(%q) = %q, %v; want %q", path, got, err, want)
+		}
+	}
+	if _, err := (&GCPSecretManagerProvider{}).versionName("db"); err == nil {
+		t.Fatal("expected an error for a short name without a project")
+	}
+}
+
+func TestGCPSecretManagerProvider(t *testing.T) {
+	tokenCalls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokenCalls++
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = io.WriteString(w, `{"access_token":"ya29.token","expires_in":3600}`)
+		case "/v1/projects/orders-prod/secrets/db/versions/latest:access":
+			if r.Header.Get("Authorization") != "Bearer ya29.token" {
+				t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+			}
+			payload := base64.StdEncoding.EncodeToString([]byte(`{"password":"hunter2"}`))
+			_, _ = io.WriteString(w, `{"name":"projects/orders-prod/secrets/db/versions/4","payload":{"data":"`+payload+`"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"error":{"code":404,"message":"Secret not found","status":"NOT_FOUND"}}`)
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewGCPSecretManagerProvider(GCPSecretManagerConfig{Project: "orders-prod", Endpoint: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.token = (&gcpMetadataToken{url: server.URL + "/token", client: server.Client()}).Token
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		secret, err := provider.GetSecret(ctx, "db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if password, _ := secret.field("password"); password != "hunter2" {
+			t.Fatalf("secret = %+v", secret)
+		}
+	}
+	if tokenCalls != 1 {
+		t.Fatalf("token fetched %d times, want it cached", tokenCalls)
+	}
+	if _, err := provider.GetSecret(ctx, "missing"); err == nil || !strings.Contains(err.Error(), "NOT_FOUND") {
+		t.Fatalf("missing secret error = %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSecrets is a SecretsProvider and SecretRenewer backed by a map.
+type fakeSecrets struct {
+	mu       sync.Mutex
+	secrets  map[string]Secret
+	reads    map[string]int
+	renewals int
+	renewErr error
+}
+
+func newFakeSecrets(secrets map[string]Secret) *fakeSecrets {
+	return &fakeSecrets{secrets: secrets, reads: map[string]int{}}
+}
+
+func (f *fakeSecrets) GetSecret(_ context.Context, path string) (Secret, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reads[path]++
+	secret, ok := f.secrets[path]
+	if !ok {
+		return Secret{}, fmt.Errorf("%s not found", path)
+	}
+	return secret, nil
+}
+
+func (f *fakeSecrets) RenewSecret(_ context.Context, secret Secret) (Secret, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.renewals++
+	if f.renewErr != nil {
+		return Secret{}, f.renewErr
+	}
+	return secret, nil
+}
+
+func (f *fakeSecrets) set(path string, secret Secret) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.secrets[path] = secret
+}
+
+func TestSecretsProviderResolvesPlaceholders(t *testing.T) {
+	vault := newFakeSecrets(map[string]Secret{
+		"secret/data/orders/db": {Fields: map[string]string{"username": "orders", "password": "hunter2"}},
+	})
+	aws := newFakeSecrets(map[string]Secret{
+		"orders/stripe": {Value: `{"api_key":"sk_live_1","retries":3}`},
+		"orders/plain":  {Value: "plain-value"},
+	})
+	cfg := New(
+		WithDefaults(map[string]interface{}{
+			"db.user":        "secret://secret/data/orders/db#username",
+			"db.password":    "secret://secret/data/orders/db#password",
+			"stripe.api_key": "aws-sm://orders/stripe#api_key",
+			"stripe.retries": "aws-sm://orders/stripe#retries",
+			"webhook":        "aws-sm://orders/plain",
+			"region":         "eu-west-1",
+		}),
+		WithSecretsProvider(vault),
+		WithSecretsProvider(aws, WithSecretScheme("aws-sm")),
+	)
+
+	for key, want := range map[string]string{
+		"db.user":        "orders",
+		"db.password":    "hunter2",
+		"stripe.api_key": "sk_live_1",
+		"stripe.retries": "3",
+		"webhook":        "plain-value",
+		"region":         "eu-west-1",
+	} {
+		if got := cfg.GetString(key); got != want {
+			t.Fatalf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if vault.reads["secret/data/orders/db"] != 1 || aws.reads["orders/stripe"] != 1 {
+		t.Fatalf("reads = %v, %v; want each secret read once", vault.reads, aws.reads)
+	}
+	if masked := fmt.Sprint(cfg.MaskedSettings()); strings.Contains(masked, "hunter2") || strings.Contains(masked, "sk_live_1") {
+		t.Fatalf("masked settings leak a resolved secret: %s", masked)
+	}
+}
+
+func TestSecretsProviderErrors(t *testing.T) {
+	provider := newFakeSecrets(map[string]Secret{"app": {Value: "not json"}})
+
+	duplicate := &Config{}
+	if err := WithSecretsProvider(provider)(duplicate); err != nil {
+		t.Fatal(err)
+	}
+	if err := WithSecretsProvider(provider)(duplicate); err == nil {
+		t.Fatal("expected an error for a second provider on the same scheme")
+	}
+	if err := WithSecretsProvider(nil)(&Config{}); err == nil {
+		t.Fatal("expected an error for a nil provider")
+	}
+
+	for name, placeholder := range map[string]string{
+		"missing secret": "secret://other",
+		"not an object":  "secret://app#password",
+	} {
+		cfg := New(WithDefaults(map[string]interface{}{"key": placeholder}))
+		cfg.secretSources = duplicate.secretSources
+		if err := cfg.resolveSecrets(context.Background()); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSecretField(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  Secret
+		field   string
+		want    string
+		wantErr bool
+	}{
+		{"whole value", Secret{Value: "raw"}, "", "raw", false},
+		{"structured fields", Secret{Fields: map[string]string{"user": "u"}}, "user", "u", false},
+		{"whole structured secret", Secret{Fields: map[string]string{"user": "u"}}, "", `{"user":"u"}`, false},
+		{"json string field", Secret{Value: `{"password":"p"}`}, "password", "p", false},
+		{"json number field", Secret{Value: `{"port":5432}`}, "port", "5432", false},
+		{"missing field", Secret{Fields: map[string]string{}}, "user", "", true},
+		{"not json", Secret{Value: "raw"}, "user", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.secret.field(tt.field)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Fatalf("field(%q) = %q, %v; want %q, error %v", tt.field, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRefreshSecretsPublishesRotations(t *testing.T) {
+	provider := newFakeSecrets(map[string]Secret{
+		"database/creds/orders": {Fields: map[string]string{"password": "v1"}, LeaseID: "lease-1", LeaseDuration: time.Hour, Renewable: true},
+		"static":                {Value: "s1"},
+	})
+	bus := NewRotationBus()
+	var rotations []SecretRotation
+	bus.Subscribe("db.password", func(_ context.Context, r SecretRotation) error {
+		rotations = append(rotations, r)
+		return nil
+	})
+	cfg := New(
+		WithDefaults(map[string]interface{}{
+			"db.password": "secret://database/creds/orders#password",
+			"static":      "secret://static",
+		}),
+		WithSecretRotation(bus),
+		WithSecretsProvider(provider, WithSecretRefreshInterval(time.Hour)),
+	)
+
+	// Nothing is due yet.
+	if err := cfg.RefreshSecrets(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if provider.renewals != 0 || len(rotations) != 0 {
+		t.Fatalf("renewals = %d, rotations = %v before anything was due", provider.renewals, rotations)
+	}
+	if wait := cfg.nextSecretRefresh(); wait <= 0 || wait > time.Hour {
+		t.Fatalf("next refresh in %s", wait)
+	}
+
+	// A renewed lease keeps the value.
+	expireSecrets(cfg)
+	if err := cfg.RefreshSecrets(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if provider.renewals != 1 || len(rotations) != 0 {
+		t.Fatalf("renewals = %d, rotations = %v; want a quiet renewal", provider.renewals, rotations)
+	}
+
+	// A failed renewal reads the secret again and publishes the new value.
+	provider.renewErr = errors.New("lease expired")
+	provider.set("database/creds/orders", Secret{Fields: map[string]string{"password": "v2"}, LeaseID: "lease-2", LeaseDuration: time.Hour, Renewable: true})
+	expireSecrets(cfg)
+	if err := cfg.RefreshSecrets(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GetString("db.password") != "v2" {
+		t.Fatalf("db.password = %q, want v2", cfg.GetString("db.password"))
+	}
+	if len(rotations) != 1 || rotations[0].Value != "v2" {
+		t.Fatalf("rotations = %v, want one for v2", rotations)
+	}
+
+	// A secret that cannot be read keeps its last value.
+	delete(provider.secrets, "static")
+	expireSecrets(cfg)
+	if err := cfg.RefreshSecrets(context.Background()); err == nil {
+		t.Fatal("expected the failed read to be reported")
+	}
+	if cfg.GetString("static") != "s1" {
+		t.Fatalf("static = %q, want the last value kept", cfg.GetString("static"))
+	}
+}
+
+func expireSecrets(cfg *Config) {
+	cfg.secretsMu.Lock()
+	defer cfg.secretsMu.Unlock()
+	for _, r := range cfg.resolvedSecrets {
+		r.readAt = time.Now().Add(-2 * time.Hour)
+	}
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// VaultConfig configures a VaultProvider.
+type VaultConfig struct {
+	// Address of the Vault server. Default: $VAULT_ADDR.
+	Address string
+	// Token authenticates requests. Default: $VAULT_TOKEN.
+	Token string
+	// Namespace is sent as X-Vault-Namespace when set. Default: $VAULT_NAMESPACE.
+	Namespace string
+	// HTTPClient defaults to a client with a 10s timeout.
+	HTTPClient *http.Client
+}
+
+// VaultProvider reads secrets from HashiCorp Vault over its HTTP API. A
+// placeholder path is the API path after /v1/: for a KV v2 mount that is
+// "<mount>/data/<path>", e.g. secret://secret/data/orders/db#password, and
+// for dynamic secrets the credentials endpoint, e.g.
+// secret://database/creds/orders#username. Leased secrets are renewed
+// through sys/leases/renew.
+type VaultProvider struct {
+	address   string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+// NewVaultProvider returns a VaultProvider, filling unset fields from the
+// standard VAULT_* environment variables.
+func NewVaultProvider(cfg VaultConfig) (*VaultProvider, error) {
+	if cfg.Address == "" {
+		cfg.Address = os.Getenv("VAULT_ADDR")
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if cfg.Address == "" {
+		return nil, errors.New("secrets: vault address is required")
+	}
+	if cfg.Token == "" {
+		return nil, errors.New("secrets: vault token is required")
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &VaultProvider{
+		address:   strings.TrimRight(cfg.Address, "/"),
+		token:     cfg.Token,
+		namespace: cfg.Namespace,
+		client:    cfg.HTTPClient,
+	}, nil
+}
+
+type vaultResponse struct {
+	LeaseID       string          `json:"lease_id"`
+	LeaseDuration int64           `json:"lease_duration"`
+	Renewable     bool            `json:"renewable"`
+	Data          json.RawMessage `json:"data"`
+	Errors        []string        `json:"errors"`
+}
+
+// GetSecret implements SecretsProvider.
+func (p *VaultProvider) GetSecret(ctx context.Context, path string) (Secret, error) {
+	var resp vaultResponse
+	if err := p.do(ctx, http.MethodGet, "/v1/"+strings.TrimLeft(path, "/"), nil, &resp); err != nil {
+		return Secret{}, err
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		return Secret{}, fmt.Errorf("vault: decode secret: %w", err)
+	}
+	// KV v2 nests the entry under data.data next to data.metadata.
+	if nested, ok := data["data"].(map[string]any); ok {
+		if _, kv2 := data["metadata"]; kv2 {
+			data = nested
+		}
+	}
+	fields := make(map[string]string, len(data))
+	for k, v := range data {
+		if s, ok := v.(string); ok {
+			fields[k] = s
+			continue
+		}
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return Secret{}, fmt.Errorf("vault: encode field %s: %w", k, err)
+		}
+		fields[k] = string(encoded)
+	}
+	return Secret{
+		Fields:        fields,
+		LeaseID:       resp.LeaseID,
+		LeaseDuration: time.Duration(resp.LeaseDuration) * time.Second,
+		Renewable:     resp.Renewable,
+	}, nil
+}
+
+// RenewSecret implements SecretRenewer, extending the lease by its original
+// duration. The secret's values are unchanged.
+func (p *VaultProvider) RenewSecret(ctx context.Context, secret Secret) (Secret, error) {
+	body := map[string]any{"lease_id": secret.LeaseID}
+	if secret.LeaseDuration > 0 {
+		body["increment"] = int64(secret.LeaseDuration / time.Second)
+	}
+	var resp vaultResponse
+	if err := p.do(ctx, http.MethodPut, "/v1/sys/leases/renew", body, &resp); err != nil {
+		return Secret{}, err
+	}
+	secret.LeaseDuration = time.Duration(resp.LeaseDuration) * time.Second
+	secret.Renewable = resp.Renewable
+	if resp.LeaseID != "" {
+		secret.LeaseID = resp.LeaseID
+	}
+	return secret, nil
+}
+
+func (p *VaultProvider) do(ctx context.Context, method, path string, body any, out *vaultResponse) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.address+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	req.Header.Set("X-Vault-Request", "true")
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("vault: read response: %w", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil && resp.StatusCode < 300 {
+		return fmt.Errorf("vault: decode response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		if len(out.Errors) > 0 {
+			return fmt.Errorf("vault: %s %s: %d: %s", method, path, resp.StatusCode, strings.Join(out.Errors, "; "))
+		}
+		return fmt.Errorf("vault: %s %s: %d", method, path, resp.StatusCode)
+	}
+	return nil
+}
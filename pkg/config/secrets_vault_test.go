@@ -0,0 +1,82 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVaultProvider(t *testing.T) {
+	var renewBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" || r.Header.Get("X-Vault-Namespace") != "team-a" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/secret/data/orders/db":
+			_, _ = io.WriteString(w, `{"data":{"data":{"password":"hunter2","port":5432},"metadata":{"version":3}}}`)
+		case "GET /v1/database/creds/orders":
+			_, _ = io.WriteString(w, `{"lease_id":"database/creds/orders/abc","lease_duration":3600,"renewable":true,"data":{"username":"v-orders","password":"p"}}`)
+		case "PUT /v1/sys/leases/renew":
+			data, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(data, &renewBody)
+			_, _ = io.WriteString(w, `{"lease_id":"database/creds/orders/abc","lease_duration":1800,"renewable":true}`)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `{"errors":["permission denied"]}`)
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewVaultProvider(VaultConfig{Address: server.URL + "/", Token: "s.token", Namespace: "team-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	kv, err := provider.GetSecret(ctx, "secret/data/orders/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kv.Fields["password"] != "hunter2" || kv.Fields["port"] != "5432" || kv.LeaseID != "" {
+		t.Fatalf("kv secret = %+v, want the KV v2 entry unwrapped", kv)
+	}
+
+	dynamic, err := provider.GetSecret(ctx, "/database/creds/orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dynamic.Fields["username"] != "v-orders" || dynamic.LeaseDuration != time.Hour || !dynamic.Renewable {
+		t.Fatalf("dynamic secret = %+v", dynamic)
+	}
+	renewed, err := provider.RenewSecret(ctx, dynamic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed.LeaseDuration != 30*time.Minute || renewed.Fields["username"] != "v-orders" {
+		t.Fatalf("renewed = %+v", renewed)
+	}
+	if renewBody["lease_id"] != "database/creds/orders/abc" || renewBody["increment"] != float64(3600) {
+		t.Fatalf("renew body = %v", renewBody)
+	}
+
+	if _, err := provider.GetSecret(ctx, "secret/data/other"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("forbidden error = %v", err)
+	}
+}
+
+func TestNewVaultProviderRequiresAddressAndToken(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "")
+	t.Setenv("VAULT_TOKEN", "")
+	if _, err := NewVaultProvider(VaultConfig{Token: "t"}); err == nil {
+		t.Fatal("expected an error without an address")
+	}
+	if _, err := NewVaultProvider(VaultConfig{Address: "http://vault:8200"}); err == nil {
+		t.Fatal("expected an error without a token")
+	}
+}
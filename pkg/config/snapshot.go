@@ -0,0 +1,219 @@
+package config
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Snapshot is a point-in-time, read-only view of a Config. Take one per request
+// or job so a hot reload in the middle of it cannot make successive reads
+// disagree.
+//
+// Settings known to the config (defaults, files, flags, bound keys) are deep
+// copied when the snapshot is taken, while no reload is applying, so a
+// snapshot never mixes old and new values and later changes to the live
+// maps and slices do not reach it. Keys only reachable through AutomaticEnv
+// are read from the live config on first use and then pinned; they are not
+// changed by file reloads anyway.
+type Snapshot struct {
+	takenAt time.Time
+
+	mu     sync.Mutex
+	values *viper.Viper
+	live   *Config
+	pinned map[string]struct{}
+}
+
+// Snapshot captures the current configuration.
+func (c *Config) Snapshot() *Snapshot {
+	c.reloadMu.RLock()
+	settings := deepCopy(c.AllSettings()).(map[string]any)
+	c.reloadMu.RUnlock()
+
+	values := viper.New()
+	_ = values.MergeConfigMap(settings)
+	return &Snapshot{
+		takenAt: time.Now(),
+		values:  values,
+		live:    c,
+		pinned:  map[string]struct{}{},
+	}
+}
+
+// TakenAt returns when the snapshot was captured.
+func (s *Snapshot) TakenAt() time.Time {
+	return s.takenAt
+}
+
+// read runs get against the snapshot values after pinning key. The caller must
+// not hold s.mu.
+func (s *Snapshot) read(key string, get func(v *viper.Viper) any) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pinned[key]; !ok && !s.values.IsSet(key) {
+		s.pinned[key] = struct{}{}
+		if s.live != nil {
+			s.live.reloadMu.RLock()
+			value := s.live.Get(key)
+			s.live.reloadMu.RUnlock()
+			if value != nil {
+				s.values.Set(key, deepCopy(value))
+			}
+		}
+	}
+	return get(s.values)
+}
+
+// Get returns the value for key, or nil.
+func (s *Snapshot) Get(key string) any {
+	return s.read(key, func(v *viper.Viper) any { return v.Get(key) })
+}
+
+// IsSet reports whether key has a value in the snapshot.
+func (s *Snapshot) IsSet(key string) bool {
+	return s.read(key, func(v *viper.Viper) any { return v.IsSet(key) }).(bool)
+}
+
+// GetString returns the value for key as a string.
+func (s *Snapshot) GetString(key string) string {
+	return s.read(key, func(v *viper.Viper) any { return v.GetString(key) }).(string)
+}
+
+// GetInt returns the value for key as an int.
+func (s *Snapshot) GetInt(key string) int {
+	return s.read(key, func(v *viper.Viper) any { return v.GetInt(key) }).(int)
+}
+
+// GetInt64 returns the value for key as an int64.
+func (s *Snapshot) GetInt64(key string) int64 {
+	return s.read(key, func(v *viper.Viper) any { return v.GetInt64(key) }).(int64)
+}
+
+// GetFloat64 returns the value for key as a float64.
+func (s *Snapshot) GetFloat64(key string) float64 {
+	return s.read(key, func(v *viper.Viper) any { return v.GetFloat64(key) }).(float64)
+}
+
+// GetBool returns the value for key as a bool.
+func (s *Snapshot) GetBool(key string) bool {
+	return s.read(key, func(v *viper.Viper) any { return v.GetBool(key) }).(bool)
+}
+
+// GetDuration returns the value for key as a time.Duration.
+func (s *Snapshot) GetDuration(key string) time.Duration {
+	return s.read(key, func(v *viper.Viper) any { return v.GetDuration(key) }).(time.Duration)
+}
+
+// GetStringSlice returns the value for key as a []string.
+func (s *Snapshot) GetStringSlice(key string) []string {
+	return s.read(key, func(v *viper.Viper) any { return v.GetStringSlice(key) }).([]string)
+}
+
+// GetStringMapString returns the value for key as a map[string]string.
+func (s *Snapshot) GetStringMapString(key string) map[string]string {
+	return s.read(key, func(v *viper.Viper) any { return v.GetStringMapString(key) }).(map[string]string)
+}
+
+// GetStringD returns string or def
+func (s *Snapshot) GetStringD(key, def string) string {
+	if val := s.GetString(key); val != "" {
+		return val
+	}
+	return def
+}
+
+// GetIntD returns int or def
+func (s *Snapshot) GetIntD(key string, def int) int {
+	if s.IsSet(key) {
+		return s.GetInt(key)
+	}
+	return def
+}
+
+// GetBoolD returns bool or def
+func (s *Snapshot) GetBoolD(key string, def bool) bool {
+	if s.IsSet(key) {
+		return s.GetBool(key)
+	}
+	return def
+}
+
+// GetDurationD returns time.Duration or def
+func (s *Snapshot) GetDurationD(key string, def time.Duration) time.Duration {
+	if s.IsSet(key) {
+		return s.GetDuration(key)
+	}
+	return def
+}
+
+// AllSettings returns a copy of the captured settings.
+func (s *Snapshot) AllSettings() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values.AllSettings()
+}
+
+// deepCopy copies the maps and slices in value, so the copy shares no
+// mutable state with it.
+func deepCopy(value any) any {
+	if value == nil {
+		return nil
+	}
+	return copyValue(reflect.ValueOf(value)).Interface()
+}
+
+func copyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(copyValue(v.Elem()))
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), copyValue(iter.Value()))
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(copyValue(v.Index(i)))
+		}
+		return out
+	}
+	return v
+}
+
+type snapshotContextKey struct{}
+
+// ContextWithSnapshot stores s in ctx.
+func ContextWithSnapshot(ctx context.Context, s *Snapshot) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, snapshotContextKey{}, s)
+}
+
+// SnapshotFromContext returns the snapshot stored by ContextWithSnapshot.
+func SnapshotFromContext(ctx context.Context) (*Snapshot, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	s, ok := ctx.Value(snapshotContextKey{}).(*Snapshot)
+	return s, ok && s != nil
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSnapshotIsDeepCopy(t *testing.T) {
+	hosts := []string{"db-0", "db-1"}
+	labels := map[string]string{"team": "orders"}
+	cfg := New(WithDefaults(map[string]interface{}{"log.level": "info"}))
+	cfg.Set("db.hosts", hosts)
+	cfg.Set("labels", labels)
+	cfg.Set("pools", []any{map[string]any{"name": "primary"}})
+
+	snap := cfg.Snapshot()
+	hosts[0] = "changed"
+	labels["team"] = "changed"
+	cfg.Get("pools").([]any)[0].(map[string]any)["name"] = "changed"
+	cfg.Set("log.level", "debug")
+
+	if got := snap.GetStringSlice("db.hosts"); got[0] != "db-0" {
+		t.Fatalf("db.hosts = %v, want the value at snapshot time", got)
+	}
+	if got := snap.GetStringMapString("labels"); got["team"] != "orders" {
+		t.Fatalf("labels = %v", got)
+	}
+	if got := snap.Get("pools").([]any)[0].(map[string]any)["name"]; got != "primary" {
+		t.Fatalf("pools[0].name = %v", got)
+	}
+	if snap.GetString("log.level") != "info" {
+		t.Fatalf("log.level = %q", snap.GetString("log.level"))
+	}
+
+	ctx := ContextWithSnapshot(context.Background(), snap)
+	if got, ok := SnapshotFromContext(ctx); !ok || got != snap {
+		t.Fatal("snapshot not found in context")
+	}
+}
+
+func TestSnapshotWaitsForReload(t *testing.T) {
+	cfg := New(WithDefaults(map[string]interface{}{"workers": 1}))
+	cfg.reloadMu.Lock()
+	taken := make(chan *Snapshot)
+	go func() { taken <- cfg.Snapshot() }()
+	select {
+	case <-taken:
+		t.Fatal("snapshot taken while a reload was applying")
+	case <-time.After(20 * time.Millisecond):
+	}
+	cfg.Set("workers", 2)
+	cfg.reloadMu.Unlock()
+	if snap := <-taken; snap.GetInt("workers") != 2 {
+		t.Fatalf("workers = %d, want the reloaded value", snap.GetInt("workers"))
+	}
+}
+
+// A file reload replaces the merged remote documents until they are merged
+// again; snapshots taken meanwhile must still see the remote values.
+func TestSnapshotConsistentDuringFileReload(t *testing.T) {
+	_, server := newFakeConsul(t, "workers: 8\n")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("workers: 1\nrevision: 0\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	changed := make(chan struct{}, 16)
+	cfg := New(
+		WithFile(path),
+		WithConsul(server.URL, "config/orders.yaml", WithRemoteToken("acl-token")),
+		WithWatch(func() { changed <- struct{}{} }),
+	)
+	if cfg.Snapshot().GetInt("workers") != 8 {
+		t.Fatalf("workers = %d, want the remote value", cfg.GetInt("workers"))
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if workers := cfg.Snapshot().GetInt("workers"); workers != 8 {
+				t.Errorf("snapshot workers = %d during a reload, want 8", workers)
+				return
+			}
+		}
+	}()
+	for i := 1; i <= 3; i++ {
+		if err := os.WriteFile(path, []byte("workers: 1\nrevision: "+strconv.Itoa(i)+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-changed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("file change not reloaded")
+		}
+	}
+	close(stop)
+	<-done
+	// a write may be seen as several events; wait for the last one
+	deadline := time.Now().Add(5 * time.Second)
+	for cfg.Snapshot().GetInt("revision") != 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("revision = %d, want the last write", cfg.Snapshot().GetInt("revision"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+
+	"github.com/milan604/core-lab/pkg/apperr"
+	"github.com/milan604/core-lab/pkg/validator"
+)
+
+// UnmarshalOption configures UnmarshalInto.
+type UnmarshalOption func(*unmarshalOptions)
+
+type unmarshalOptions struct {
+	prefix    string
+	validator *validator.Validator
+}
+
+// UnmarshalAt decodes the subtree under key, e.g. "orders", instead of the
+// whole config.
+func UnmarshalAt(key string) UnmarshalOption {
+	return func(o *unmarshalOptions) { o.prefix = strings.Trim(key, ".") }
+}
+
+// UnmarshalWithValidator validates with v, so custom validations and messages
+// registered on it apply. Default: validator.New().
+func UnmarshalWithValidator(v *validator.Validator) UnmarshalOption {
+	return func(o *unmarshalOptions) { o.validator = v }
+}
+
+// UnmarshalInto decodes the config into a new T, so services read typed
+// fields instead of calling GetString with key literals.
+//
+//	type OrdersConfig struct {
+//	  Port     int           `mapstructure:"port" default:"8080" binding:"min=1,max=65535"`
+//	  Timeout  time.Duration `mapstructure:"timeout" default:"5s"`
+//	  Database struct {
+//	    DSN      string `mapstructure:"dsn" binding:"required"`
+//	    MaxConns int    `mapstructure:"max_conns" default:"20" binding:"gte=1"`
+//	  } `mapstructure:"database"`
+//	}
+//
+//	cfg, err := config.UnmarshalInto[OrdersConfig](c, config.UnmarshalAt("orders"))
+//
+// Each field is read from its dotted key: the mapstructure tag, or the
+// lowercased field name. Nested structs are nested keys; embedded structs
+// tagged `mapstructure:",squash"` share their parent's keys. A key that is not
+// set takes the field's default tag, decoded like a config value (durations
+// as "5s", slices as "a,b"). The result is then checked against its binding
+// tags with pkg/validator, the same rules as request binding.
+//
+// Every undecodable value and failed rule is reported in one
+// validation_failed *apperr.AppError, with a suggestion per config key.
+func UnmarshalInto[T any](c *Config, opts ...UnmarshalOption) (*T, error) {
+	o := unmarshalOptions{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	out := new(T)
+	root := reflect.ValueOf(out).Elem()
+	if root.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: UnmarshalInto needs a struct type, got %s", root.Type())
+	}
+
+	d := typedDecoder{cfg: c, keys: map[string]string{}}
+	d.decodeStruct(root, o.prefix, "")
+
+	vi := o.validator
+	if vi == nil {
+		vi = validator.New()
+	}
+	fieldErrs, err := vi.StructErrors(out)
+	if err != nil {
+		return nil, fmt.Errorf("config: validate %s: %w", root.Type(), err)
+	}
+	for _, fe := range fieldErrs {
+		d.fail(d.keyFor(fe.Field), fe.Message)
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	return out, nil
+}
+
+// typedDecoder fills a struct from config keys, collecting every failure.
+type typedDecoder struct {
+	cfg *Config
+	// keys maps Go field paths ("Database.MaxConns") to config keys, to name
+	// validation failures.
+	keys    map[string]string
+	err     *apperr.AppError
+	invalid []string
+}
+
+func (d *typedDecoder) decodeStruct(v reflect.Value, prefix, goPath string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !(field.Anonymous && field.Type.Kind() == reflect.Struct) {
+			continue
+		}
+		name, squash := fieldKey(field)
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		fieldGoPath := joinPath(goPath, field.Name)
+
+		if squash || (field.Anonymous && name == "" && isNestedStruct(field.Type)) {
+			d.decodeStruct(fv, prefix, fieldGoPath)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		key := joinPath(prefix, name)
+		d.keys[fieldGoPath] = key
+
+		if isNestedStruct(field.Type) {
+			d.decodeStruct(fv, key, fieldGoPath)
+			continue
+		}
+		d.decodeField(fv, field, key)
+	}
+}
+
+func (d *typedDecoder) decodeField(fv reflect.Value, field reflect.StructField, key string) {
+	var input any
+	switch {
+	case d.cfg.IsSet(key):
+		input = d.cfg.Get(key)
+	default:
+		def, ok := field.Tag.Lookup("default")
+		if !ok {
+			return
+		}
+		input = def
+	}
+	if err := decodeValue(input, fv.Addr().Interface()); err != nil {
+		d.fail(key, fmt.Sprintf("cannot use %v as %s", input, field.Type))
+	}
+}
+
+func (d *typedDecoder) fail(key, message string) {
+	if d.err == nil {
+		d.err = apperr.New(apperr.ErrorCodeValidationFail)
+	}
+	d.err.AddSuggestion(key, message)
+	for _, k := range d.invalid {
+		if k == key {
+			return
+		}
+	}
+	d.invalid = append(d.invalid, key)
+	d.err.WithMessage("invalid configuration: " + strings.Join(d.invalid, ", "))
+}
+
+// keyFor maps a validator field path to its config key. Slice elements keep
+// their index: "Hosts[1]" becomes "hosts[1]".
+func (d *typedDecoder) keyFor(goPath string) string {
+	base, index := goPath, ""
+	if i := strings.IndexByte(goPath, '['); i >= 0 && strings.HasSuffix(goPath, "]") {
+		base, index = goPath[:i], goPath[i:]
+	}
+	if key, ok := d.keys[base]; ok {
+		return key + index
+	}
+	return goPath
+}
+
+func decodeValue(input, result any) error {
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+			mapstructure.TextUnmarshallerHookFunc(),
+		),
+		WeaklyTypedInput: true,
+		Result:           result,
+	})
+	if err != nil {
+		return err
+	}
+	return dec.Decode(input)
+}
+
+// fieldKey returns the mapstructure name of field and whether it is squashed.
+func fieldKey(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("mapstructure")
+	name, rest, _ := strings.Cut(tag, ",")
+	return name, strings.Contains(","+rest+",", ",squash,")
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isNestedStruct reports whether t holds nested keys rather than one value.
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == timeType || t == durationType {
+		return false
+	}
+	return !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
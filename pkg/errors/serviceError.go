@@ -3,7 +3,6 @@ package errors
 import (
 	stdErrors "errors"
 	"fmt"
-	"net/http"
 	"time"
 
 	"github.com/milan604/core-lab/pkg/apperr"
@@ -96,7 +95,7 @@ func FromCode(ec *apperr.ErrorCode, opts ...Option) *ServiceError {
 	if ec == nil {
 		ec = apperr.ErrorCodeInternal
 	}
-	return NewServiceError(ec.Code(), ec.Message(), ec.HTTPStatus(), opts...)
+	return NewServiceError(ec.Code(), ec.Message(), ec.HTTPStatus(), append([]Option{WithRetryable(ec.Retryable())}, opts...)...)
 }
 
 // Common helpers.
@@ -125,61 +124,61 @@ func ValidationFailed(msg string, opts ...Option) *ServiceError {
 }
 
 func Conflict(msg string, opts ...Option) *ServiceError {
-	return NewServiceError("conflict", msg, http.StatusConflict, opts...)
+	return FromCode(apperr.ErrorCodeConflict, append([]Option{WithMessage(msg)}, opts...)...)
 }
 
 func AlreadyExists(msg string, opts ...Option) *ServiceError {
-	return NewServiceError("already_exists", msg, http.StatusConflict, opts...)
+	return FromCode(apperr.ErrorCodeAlreadyExists, append([]Option{WithMessage(msg)}, opts...)...)
 }
 
 func TooManyRequests(msg string, opts ...Option) *ServiceError {
-	return NewServiceError("too_many_requests", msg, http.StatusTooManyRequests, opts...)
+	return FromCode(apperr.ErrorCodeTooManyRequests, append([]Option{WithMessage(msg)}, opts...)...)
 }
 
 // Alias for TooManyRequests
 func RateLimited(msg string, opts ...Option) *ServiceError { return TooManyRequests(msg, opts...) }
 
 func ServiceUnavailable(msg string, opts ...Option) *ServiceError {
-	return NewServiceError("service_unavailable", msg, http.StatusServiceUnavailable, opts...)
+	return FromCode(apperr.ErrorCodeServiceUnavailable, append([]Option{WithMessage(msg)}, opts...)...)
 }
 
 func BadGateway(msg string, opts ...Option) *ServiceError {
-	return NewServiceError("bad_gateway", msg, http.StatusBadGateway, opts...)
+	return FromCode(apperr.ErrorCodeBadGateway, append([]Option{WithMessage(msg)}, opts...)...)
 }
 
 func GatewayTimeout(msg string, opts ...Option) *ServiceError {
-	return NewServiceError("gateway_timeout", msg, http.StatusGatewayTimeout, opts...)
+	return FromCode(apperr.ErrorCodeGatewayTimeout, append([]Option{WithMessage(msg)}, opts...)...)
 }
 
 // Alias for GatewayTimeout
 func Timeout(msg string, opts ...Option) *ServiceError { return GatewayTimeout(msg, opts...) }
 
 func RequestTimeout(msg string, opts ...Option) *ServiceError {
-	return NewServiceError("request_timeout", msg, http.StatusRequestTimeout, opts...)
+	return FromCode(apperr.ErrorCodeRequestTimeout, append([]Option{WithMessage(msg)}, opts...)...)
 }
 
 func MethodNotAllowed(msg string, opts ...Option) *ServiceError {
-	return NewServiceError("method_not_allowed", msg, http.StatusMethodNotAllowed, opts...)
+	return FromCode(apperr.ErrorCodeMethodNotAllowed, append([]Option{WithMessage(msg)}, opts...)...)
 }
 
 func NotAcceptable(msg string, opts ...Option) *ServiceError {
-	return NewServiceError("not_acceptable", msg, http.StatusNotAcceptable, opts...)
+	return FromCode(apperr.ErrorCodeNotAcceptable, append([]Option{WithMessage(msg)}, opts...)...)
 }
 
 func UnsupportedMediaType(msg string, opts ...Option) *ServiceError {
-	return NewServiceError("unsupported_media_type", msg, http.StatusUnsupportedMediaType, opts...)
+	return FromCode(apperr.ErrorCodeUnsupportedMediaType, append([]Option{WithMessage(msg)}, opts...)...)
 }
 
 func UnprocessableEntity(msg string, opts ...Option) *ServiceError {
-	return NewServiceError("unprocessable_entity", msg, http.StatusUnprocessableEntity, opts...)
+	return FromCode(apperr.ErrorCodeUnprocessableEntity, append([]Option{WithMessage(msg)}, opts...)...)
 }
 
 func PreconditionFailed(msg string, opts ...Option) *ServiceError {
-	return NewServiceError("precondition_failed", msg, http.StatusPreconditionFailed, opts...)
+	return FromCode(apperr.ErrorCodePreconditionFailed, append([]Option{WithMessage(msg)}, opts...)...)
 }
 
 func NotImplemented(msg string, opts ...Option) *ServiceError {
-	return NewServiceError("not_implemented", msg, http.StatusNotImplemented, opts...)
+	return FromCode(apperr.ErrorCodeNotImplemented, append([]Option{WithMessage(msg)}, opts...)...)
 }
 
 // ParseServiceError extracts a ServiceError from err or creates an Internal one.
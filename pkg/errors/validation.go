@@ -0,0 +1,109 @@
+package errors
+
+import (
+	stdErrors "errors"
+	"fmt"
+
+	"github.com/milan604/core-lab/pkg/apperr"
+)
+
+// ValidationBuilder accumulates field errors across multiple checks and produces a
+// single ValidationFailed ServiceError carrying one suggestion per field error.
+//
+//	err := serr.Validation().
+//		FieldIf(req.Email == "", "email", "email is required").
+//		FieldIf(len(req.Password) < 8, "password", "must be at least 8 characters").
+//		Err()
+type ValidationBuilder struct {
+	message     string
+	suggestions []apperr.Suggestion
+	details     map[string]any
+}
+
+// Validation starts a new validation error builder.
+func Validation() *ValidationBuilder {
+	return &ValidationBuilder{}
+}
+
+// Message overrides the top-level error message (defaults to "Validation failed").
+func (b *ValidationBuilder) Message(msg string) *ValidationBuilder {
+	b.message = msg
+	return b
+}
+
+// Field records an error for field.
+func (b *ValidationBuilder) Field(field, message string) *ValidationBuilder {
+	b.suggestions = append(b.suggestions, apperr.Suggestion{Field: field, Message: message})
+	return b
+}
+
+// Fieldf records a formatted error for field.
+func (b *ValidationBuilder) Fieldf(field, format string, args ...any) *ValidationBuilder {
+	return b.Field(field, fmt.Sprintf(format, args...))
+}
+
+// FieldIf records an error for field only when cond is true.
+func (b *ValidationBuilder) FieldIf(cond bool, field, message string) *ValidationBuilder {
+	if cond {
+		b.Field(field, message)
+	}
+	return b
+}
+
+// Detail attaches a detail key-value to the resulting error.
+func (b *ValidationBuilder) Detail(key string, val any) *ValidationBuilder {
+	if b.details == nil {
+		b.details = make(map[string]any)
+	}
+	b.details[key] = val
+	return b
+}
+
+// Merge folds the field errors of err into the builder. ServiceError and AppError
+// suggestions are copied as-is; any other non-nil error is recorded under field.
+func (b *ValidationBuilder) Merge(field string, err error) *ValidationBuilder {
+	if err == nil {
+		return b
+	}
+	var se *ServiceError
+	if stdErrors.As(err, &se) && len(se.Suggestions) > 0 {
+		b.suggestions = append(b.suggestions, se.Suggestions...)
+		return b
+	}
+	var ae *apperr.AppError
+	if stdErrors.As(err, &ae) && len(ae.Suggestions) > 0 {
+		b.suggestions = append(b.suggestions, ae.Suggestions...)
+		return b
+	}
+	return b.Field(field, err.Error())
+}
+
+// HasErrors reports whether any field error has been recorded.
+func (b *ValidationBuilder) HasErrors() bool { return len(b.suggestions) > 0 }
+
+// Len returns the number of recorded field errors.
+func (b *ValidationBuilder) Len() int { return len(b.suggestions) }
+
+// Build returns the accumulated ValidationFailed ServiceError, or nil when no
+// field error was recorded.
+func (b *ValidationBuilder) Build(opts ...Option) *ServiceError {
+	if !b.HasErrors() {
+		return nil
+	}
+	msg := b.message
+	if msg == "" {
+		msg = apperr.ErrorCodeValidationFail.Message()
+	}
+	se := ValidationFailed(msg, append([]Option{WithDetails(b.details)}, opts...)...)
+	se.Suggestions = append(se.Suggestions, b.suggestions...)
+	return se
+}
+
+// Err is Build returning a plain error, so callers can `return b.Err()` without
+// leaking a typed-nil *ServiceError when there are no field errors.
+func (b *ValidationBuilder) Err(opts ...Option) error {
+	if se := b.Build(opts...); se != nil {
+		return se
+	}
+	return nil
+}
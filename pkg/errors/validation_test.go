@@ -0,0 +1,103 @@
+package errors_test
+
+import (
+	stdErrors "errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/milan604/core-lab/pkg/apperr"
+	serr "github.com/milan604/core-lab/pkg/errors"
+)
+
+func TestValidationBuilder(t *testing.T) {
+	nested := serr.Validation().Field("address.city", "city is required").Build()
+
+	tests := []struct {
+		name        string
+		build       func() *serr.ValidationBuilder
+		opts        []serr.Option
+		wantNil     bool
+		wantMessage string
+		wantStatus  int
+		wantFields  []apperr.Suggestion
+		wantDetails map[string]any
+	}{
+		{
+			name:    "no field errors",
+			build:   func() *serr.ValidationBuilder { return serr.Validation().FieldIf(false, "email", "email is required") },
+			wantNil: true,
+		},
+		{
+			name: "fields in order with the default message",
+			build: func() *serr.ValidationBuilder {
+				return serr.Validation().
+					FieldIf(true, "email", "email is required").
+					FieldIf(false, "name", "name is required").
+					Fieldf("password", "must be at least %d characters", 8)
+			},
+			wantMessage: "Validation failed",
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantFields: []apperr.Suggestion{
+				{Field: "email", Message: "email is required"},
+				{Field: "password", Message: "must be at least 8 characters"},
+			},
+		},
+		{
+			name: "merged errors, message, details and options",
+			build: func() *serr.ValidationBuilder {
+				return serr.Validation().
+					Message("Order is invalid").
+					Merge("address", nested).
+					Merge("quantity", stdErrors.New("must be positive")).
+					Merge("note", nil).
+					Detail("order_id", "o-1")
+			},
+			opts:        []serr.Option{serr.WithStatus(http.StatusBadRequest)},
+			wantMessage: "Order is invalid",
+			wantStatus:  http.StatusBadRequest,
+			wantFields: []apperr.Suggestion{
+				{Field: "address.city", Message: "city is required"},
+				{Field: "quantity", Message: "must be positive"},
+			},
+			wantDetails: map[string]any{"order_id": "o-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.build()
+			se := b.Build(tt.opts...)
+			if tt.wantNil {
+				if se != nil || b.Err() != nil || b.HasErrors() {
+					t.Fatalf("Build() = %v, Err() = %v, want both nil", se, b.Err())
+				}
+				return
+			}
+			if se == nil {
+				t.Fatal("Build() = nil, want a ServiceError")
+			}
+			if se.Code != apperr.ErrorCodeValidationFail.Code() {
+				t.Fatalf("Code = %q, want %q", se.Code, apperr.ErrorCodeValidationFail.Code())
+			}
+			if se.Message != tt.wantMessage {
+				t.Fatalf("Message = %q, want %q", se.Message, tt.wantMessage)
+			}
+			if se.HTTPStatus != tt.wantStatus {
+				t.Fatalf("HTTPStatus = %d, want %d", se.HTTPStatus, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(se.Suggestions, tt.wantFields) {
+				t.Fatalf("Suggestions = %+v, want %+v", se.Suggestions, tt.wantFields)
+			}
+			if b.Len() != len(tt.wantFields) {
+				t.Fatalf("Len() = %d, want %d", b.Len(), len(tt.wantFields))
+			}
+			if len(tt.wantDetails) > 0 && !reflect.DeepEqual(se.Details, tt.wantDetails) {
+				t.Fatalf("Details = %v, want %v", se.Details, tt.wantDetails)
+			}
+			var target *serr.ServiceError
+			if !stdErrors.As(b.Err(tt.opts...), &target) || !target.IsCode(se.Code) {
+				t.Fatalf("Err() = %v, want the built ServiceError", b.Err())
+			}
+		})
+	}
+}
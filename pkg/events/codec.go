@@ -0,0 +1,141 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	coreerrors "github.com/milan604/core-lab/pkg/errors"
+	"github.com/milan604/core-lab/pkg/mq"
+)
+
+// Message headers set by Publish.
+const (
+	HeaderContentType = "content-type"
+	HeaderEventType   = "event-type"
+)
+
+// Codec encodes message values.
+type Codec interface {
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// Built-in codecs.
+var (
+	JSON     Codec = jsonCodec{}
+	Protobuf Codec = protobufCodec{}
+)
+
+// CodecFor returns the built-in codec for a content-type header value.
+func CodecFor(contentType string) (Codec, bool) {
+	contentType, _, _ = strings.Cut(contentType, ";")
+	switch strings.TrimSpace(contentType) {
+	case JSON.ContentType():
+		return JSON, true
+	case Protobuf.ContentType():
+		return Protobuf, true
+	}
+	return nil, false
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string                { return "application/json" }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string { return "application/x-protobuf" }
+
+func (protobufCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf codec: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+// Unmarshal accepts a proto.Message, or a pointer to a nil message pointer,
+// which it allocates; the latter is what Handle passes for T = *pb.Message.
+func (protobufCodec) Unmarshal(data []byte, v any) error {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, m)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() == reflect.Pointer {
+		elem := rv.Elem()
+		if elem.IsNil() {
+			elem.Set(reflect.New(elem.Type().Elem()))
+		}
+		if m, ok := elem.Interface().(proto.Message); ok {
+			return proto.Unmarshal(data, m)
+		}
+	}
+	return fmt.Errorf("protobuf codec: %T is not a proto.Message", v)
+}
+
+// Publish encodes v with codec and publishes it to topic with key, setting the
+// content-type header and injecting the trace and tenant request context of
+// ctx (see mq.InjectHeaders).
+//
+//	err := events.Publish(ctx, mq.NATSPublisher{JS: js}, "orders.created", order.ID, order, events.Protobuf)
+func Publish(ctx context.Context, publisher mq.Publisher, topic, key string, v any, codec Codec) error {
+	value, err := codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode %s message: %w", topic, err)
+	}
+	headers := mq.InjectHeaders(ctx, nil)
+	headers[HeaderContentType] = codec.ContentType()
+	return publisher.Publish(ctx, mq.Message{Topic: topic, Key: []byte(key), Value: value, Headers: headers})
+}
+
+// Handle returns an mq.Handler that decodes each message into a T before
+// calling fn. A message that cannot be decoded fails with a non-retryable
+// bad request ServiceError, so mq.Retry skips it and mq.DeadLetter moves it
+// aside.
+//
+//	handler := mq.Chain(events.Handle(events.JSON, func(ctx context.Context, order Order, msg mq.Message) error {
+//		return svc.Fulfil(ctx, order)
+//	}), mq.Standard(cfg)...)
+func Handle[T any](codec Codec, fn func(ctx context.Context, v T, msg mq.Message) error) mq.Handler {
+	return func(ctx context.Context, msg mq.Message) error {
+		var v T
+		if err := codec.Unmarshal(msg.Value, &v); err != nil {
+			return coreerrors.BadRequest("cannot decode message",
+				coreerrors.WithCause(err),
+				coreerrors.WithDetail("topic", msg.Topic),
+				coreerrors.WithDetail("content_type", codec.ContentType()),
+			)
+		}
+		return fn(ctx, v, msg)
+	}
+}
+
+// EnvelopePublisher publishes envelopes through an mq.Publisher as JSON,
+// keyed by PartitionKey. It satisfies outbox.Publisher.
+type EnvelopePublisher struct {
+	Publisher mq.Publisher
+}
+
+// Publish writes envelope to topic, or DefaultTopic when topic is empty, with
+// the event type in the event-type header.
+func (p EnvelopePublisher) Publish(ctx context.Context, topic string, envelope Envelope) error {
+	if strings.TrimSpace(topic) == "" {
+		topic = DefaultTopic
+	}
+	value, err := envelope.JSON()
+	if err != nil {
+		return fmt.Errorf("encode event %s: %w", envelope.EventID, err)
+	}
+	headers := mq.InjectHeaders(ctx, nil)
+	headers[HeaderContentType] = JSON.ContentType()
+	headers[HeaderEventType] = envelope.EventType
+	return p.Publisher.Publish(ctx, mq.Message{Topic: topic, Key: []byte(envelope.PartitionKey()), Value: value, Headers: headers})
+}
@@ -0,0 +1,123 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	coreerrors "github.com/milan604/core-lab/pkg/errors"
+	"github.com/milan604/core-lab/pkg/mq"
+	coretenant "github.com/milan604/core-lab/pkg/tenant"
+)
+
+type capturePublisher struct {
+	published []mq.Message
+}
+
+func (p *capturePublisher) Publish(_ context.Context, msg mq.Message) error {
+	p.published = append(p.published, msg)
+	return nil
+}
+
+func TestPublishAndHandleRoundTrip(t *testing.T) {
+	type order struct {
+		ID    string `json:"id"`
+		Total int    `json:"total"`
+	}
+	ctx := coretenant.ContextWithRequestContext(context.Background(), coretenant.RequestContext{TenantID: "t-1"})
+
+	tests := []struct {
+		name    string
+		codec   Codec
+		value   any
+		handler func(t *testing.T) mq.Handler
+	}{
+		{
+			name:  "json",
+			codec: JSON,
+			value: order{ID: "o-1", Total: 42},
+			handler: func(t *testing.T) mq.Handler {
+				return Handle(JSON, func(_ context.Context, v order, _ mq.Message) error {
+					if v != (order{ID: "o-1", Total: 42}) {
+						t.Fatalf("decoded %+v", v)
+					}
+					return nil
+				})
+			},
+		},
+		{
+			name:  "protobuf",
+			codec: Protobuf,
+			value: wrapperspb.String("o-1"),
+			handler: func(t *testing.T) mq.Handler {
+				return Handle(Protobuf, func(_ context.Context, v *wrapperspb.StringValue, _ mq.Message) error {
+					if v.GetValue() != "o-1" {
+						t.Fatalf("decoded %v", v)
+					}
+					return nil
+				})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := &capturePublisher{}
+			if err := Publish(ctx, pub, "orders.created", "o-1", tt.value, tt.codec); err != nil {
+				t.Fatal(err)
+			}
+			msg := pub.published[0]
+			if msg.Topic != "orders.created" || string(msg.Key) != "o-1" {
+				t.Fatalf("published %s key %q", msg.Topic, msg.Key)
+			}
+			if got, ok := CodecFor(msg.Headers[HeaderContentType]); !ok || got != tt.codec {
+				t.Fatalf("content-type header = %q", msg.Headers[HeaderContentType])
+			}
+			if requestContext, ok := coretenant.RequestContextFromMetadata(msg.Headers); !ok || requestContext.TenantID != "t-1" {
+				t.Fatalf("request context not injected: %v", msg.Headers)
+			}
+			if err := tt.handler(t)(context.Background(), msg); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestHandleDecodeFailureIsNotRetryable(t *testing.T) {
+	called := false
+	handler := Handle(Protobuf, func(context.Context, *wrapperspb.StringValue, mq.Message) error {
+		called = true
+		return nil
+	})
+
+	err := handler(context.Background(), mq.Message{Topic: "orders.created", Value: []byte{0xff, 0xff}})
+	var se *coreerrors.ServiceError
+	if !errors.As(err, &se) || se.HTTPStatus != http.StatusBadRequest {
+		t.Fatalf("err = %v, want a bad request ServiceError", err)
+	}
+	if called || mq.Retryable(err) {
+		t.Fatalf("called = %v, retryable = %v", called, mq.Retryable(err))
+	}
+}
+
+func TestEnvelopePublisherKeysByPartition(t *testing.T) {
+	envelope, err := NewEnvelope(context.Background(), PublishRequest{ServiceID: "billing", EventType: "invoice.paid", TenantID: "t-9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &capturePublisher{}
+	if err := (EnvelopePublisher{Publisher: pub}).Publish(context.Background(), "", envelope); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := pub.published[0]
+	if msg.Topic != DefaultTopic || string(msg.Key) != "t-9" || msg.Headers[HeaderEventType] != "invoice.paid" {
+		t.Fatalf("published %+v", msg)
+	}
+	parsed, err := ParseEnvelope(msg.Value)
+	if err != nil || parsed.EventID != envelope.EventID {
+		t.Fatalf("ParseEnvelope() = %+v, %v", parsed, err)
+	}
+}
@@ -0,0 +1,95 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const meterName = "github.com/milan604/core-lab/pkg/events/outbox"
+
+// Metric attribute keys recorded by the processor.
+var (
+	AttrOutboxName  = attribute.Key("outbox.name")
+	attrDestination = attribute.Key("messaging.destination.name")
+)
+
+// processorMetrics are the delivery instruments recorded by a Processor.
+// Instruments that fail to register are left nil and skipped.
+type processorMetrics struct {
+	name      attribute.KeyValue
+	meter     metric.Meter
+	published metric.Int64Counter
+	failed    metric.Int64Counter
+	lag       metric.Float64Histogram
+	pending   metric.Int64ObservableGauge
+}
+
+func newProcessorMetrics(name string) *processorMetrics {
+	meter := otel.Meter(meterName)
+	m := &processorMetrics{name: AttrOutboxName.String(name), meter: meter}
+	var err error
+	if m.published, err = meter.Int64Counter("outbox.events.published",
+		metric.WithDescription("Outbox records published"), metric.WithUnit("{event}")); err != nil {
+		otel.Handle(fmt.Errorf("create outbox.events.published: %w", err))
+	}
+	if m.failed, err = meter.Int64Counter("outbox.events.failed",
+		metric.WithDescription("Outbox publish attempts that failed and were rescheduled"), metric.WithUnit("{event}")); err != nil {
+		otel.Handle(fmt.Errorf("create outbox.events.failed: %w", err))
+	}
+	if m.lag, err = meter.Float64Histogram("outbox.event.lag",
+		metric.WithDescription("Time from writing an outbox record to publishing it"), metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900)); err != nil {
+		otel.Handle(fmt.Errorf("create outbox.event.lag: %w", err))
+	}
+	if m.pending, err = meter.Int64ObservableGauge("outbox.events.pending",
+		metric.WithDescription("Undelivered outbox records"), metric.WithUnit("{event}")); err != nil {
+		otel.Handle(fmt.Errorf("create outbox.events.pending: %w", err))
+	}
+	return m
+}
+
+func (m *processorMetrics) recordPublished(ctx context.Context, topic string, createdAt, now time.Time) {
+	attrs := metric.WithAttributes(m.name, attrDestination.String(topic))
+	if m.published != nil {
+		m.published.Add(ctx, 1, attrs)
+	}
+	if m.lag != nil && !createdAt.IsZero() {
+		m.lag.Record(ctx, now.Sub(createdAt).Seconds(), attrs)
+	}
+}
+
+func (m *processorMetrics) recordFailed(ctx context.Context, topic string) {
+	if m.failed != nil {
+		m.failed.Add(ctx, 1, metric.WithAttributes(m.name, attrDestination.String(topic)))
+	}
+}
+
+// observeBacklog reports counter's backlog on every collection until the
+// returned function is called.
+func (m *processorMetrics) observeBacklog(counter BacklogCounter) func() {
+	if m.pending == nil {
+		return func() {}
+	}
+	registration, err := m.meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		pending, err := counter.Backlog(ctx)
+		if err != nil {
+			return err
+		}
+		o.ObserveInt64(m.pending, pending, metric.WithAttributes(m.name))
+		return nil
+	}, m.pending)
+	if err != nil {
+		otel.Handle(fmt.Errorf("observe outbox.events.pending: %w", err))
+		return func() {}
+	}
+	return func() {
+		if err := registration.Unregister(); err != nil {
+			otel.Handle(err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS platform_event_outbox (
+    id             text PRIMARY KEY,
+    seq            bigint GENERATED ALWAYS AS IDENTITY,
+    topic          text NOT NULL,
+    aggregate_key  text NOT NULL,
+    partition_key  text NOT NULL,
+    tenant_id      text,
+    event_type     text NOT NULL,
+    service_id     text NOT NULL,
+    correlation_id text,
+    payload        jsonb NOT NULL,
+    available_at   timestamptz NOT NULL DEFAULT now(),
+    attempt_count  integer NOT NULL DEFAULT 0,
+    claimed_by     text,
+    claimed_until  timestamptz,
+    last_error     text,
+    delivered_at   timestamptz,
+    created_at     timestamptz NOT NULL DEFAULT now(),
+    updated_at     timestamptz NOT NULL DEFAULT now()
+);
+
+-- Claiming walks the undelivered records of each aggregate in seq order.
+CREATE INDEX IF NOT EXISTS platform_event_outbox_pending_idx
+    ON platform_event_outbox (aggregate_key, seq)
+    WHERE delivered_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS platform_event_outbox_delivered_idx
+    ON platform_event_outbox (delivered_at)
+    WHERE delivered_at IS NOT NULL;
@@ -0,0 +1,254 @@
+// Package outboxpg is the Postgres schema and Store for pkg/events/outbox:
+// a migration for the platform_event_outbox table, WriteOutbox to append an
+// event inside the business transaction, and a Store whose claims keep the
+// records of each aggregate in order.
+//
+//	err := db.Migrate(ctx, outboxpg.Migrations(), postgres.MigrateUp())
+//
+//	err = db.WithinTx(ctx, func(tx *gorm.DB) error {
+//		if err := tx.Create(&subscription).Error; err != nil {
+//			return err
+//		}
+//		return outboxpg.WriteOutbox(tx, "", envelope)
+//	})
+//
+//	processor := outbox.NewProcessor(outboxpg.NewStore(db), events.EnvelopePublisher{Publisher: pub}, outbox.ProcessorOptions{})
+//	go processor.Run(ctx)
+package outboxpg
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	coreevents "github.com/milan604/core-lab/pkg/events"
+	"github.com/milan604/core-lab/pkg/events/outbox"
+	"github.com/milan604/core-lab/pkg/postgres"
+)
+
+// TableName is the outbox table created by Migrations.
+const TableName = "platform_event_outbox"
+
+// MigrationsTable records the applied version of Migrations, separately from
+// the application's schema_migrations.
+const MigrationsTable = "platform_event_outbox_schema_migrations"
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations returns the migrations creating TableName, for postgres.DB.Migrate.
+// Their version is tracked in MigrationsTable, so they run alongside the
+// application's own migrations. Services that manage the schema themselves
+// can copy the SQL from this package's migrations directory instead.
+func Migrations() postgres.MigrationSource {
+	return postgres.MigrationsFS(migrationFiles, "migrations").WithMigrationsTable(MigrationsTable)
+}
+
+// WriteOption configures WriteOutbox.
+type WriteOption func(*writeOptions)
+
+type writeOptions struct {
+	aggregateKey string
+	availableAt  time.Time
+}
+
+// WithAggregateKey sets the key that orders delivery (see
+// outbox.Record.AggregateKey). It defaults to AggregateKey(envelope).
+func WithAggregateKey(key string) WriteOption {
+	return func(o *writeOptions) { o.aggregateKey = key }
+}
+
+// WithAvailableAt delays publication until t.
+func WithAvailableAt(t time.Time) WriteOption {
+	return func(o *writeOptions) { o.availableAt = t }
+}
+
+// AggregateKey returns the default ordering key of an envelope:
+// "<resource_type>/<resource_id>" when the event names a resource, and its
+// PartitionKey otherwise.
+func AggregateKey(envelope coreevents.Envelope) string {
+	if resourceID := strings.TrimSpace(envelope.ResourceID); resourceID != "" {
+		return strings.TrimSpace(envelope.ResourceType) + "/" + resourceID
+	}
+	return envelope.PartitionKey()
+}
+
+// WriteOutbox appends envelope to the outbox for topic (events.DefaultTopic
+// when empty). Call it with the transaction of the business write, so the
+// event is stored if and only if the write commits.
+func WriteOutbox(tx *gorm.DB, topic string, envelope coreevents.Envelope, opts ...WriteOption) error {
+	if strings.TrimSpace(envelope.EventID) == "" {
+		return errors.New("outboxpg: envelope event_id is required")
+	}
+	o := writeOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if strings.TrimSpace(topic) == "" {
+		topic = coreevents.DefaultTopic
+	}
+	if o.aggregateKey == "" {
+		o.aggregateKey = AggregateKey(envelope)
+	}
+	payload, err := envelope.JSON()
+	if err != nil {
+		return fmt.Errorf("outboxpg: encode event %s: %w", envelope.EventID, err)
+	}
+	now := time.Now().UTC()
+	if o.availableAt.IsZero() {
+		o.availableAt = now
+	}
+
+	err = tx.Exec(`INSERT INTO `+TableName+` (id, topic, aggregate_key, partition_key, tenant_id, event_type,
+		service_id, correlation_id, payload, available_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''), ?, ?, NULLIF(?, ''), ?::jsonb, ?, ?, ?)`,
+		envelope.EventID, topic, o.aggregateKey, envelope.PartitionKey(), envelope.TenantID, envelope.EventType,
+		envelope.ServiceID, envelope.CorrelationID, string(payload), o.availableAt, now, now,
+	).Error
+	if err != nil {
+		return fmt.Errorf("outboxpg: write event %s: %w", envelope.EventID, err)
+	}
+	return nil
+}
+
+// Store implements outbox.Store, outbox.Releaser and outbox.BacklogCounter on
+// TableName.
+//
+// ClaimBatch only claims a record once every earlier undelivered record of its
+// aggregate is claimed with it, so a record that failed or is held by another
+// processor blocks the rest of its aggregate until it is delivered. Claims
+// are serialized with a transaction-level advisory lock, so several
+// processors can share the table.
+type Store struct {
+	db *postgres.DB
+}
+
+var (
+	_ outbox.Store          = (*Store)(nil)
+	_ outbox.Releaser       = (*Store)(nil)
+	_ outbox.BacklogCounter = (*Store)(nil)
+)
+
+// NewStore creates a Store on the primary of db.
+func NewStore(db *postgres.DB) *Store {
+	return &Store{db: db}
+}
+
+type claimedRow struct {
+	ID           string
+	Seq          int64
+	Topic        string
+	AggregateKey string
+	Payload      []byte
+	AttemptCount int
+	AvailableAt  time.Time
+	ClaimedBy    string
+	ClaimedUntil time.Time
+	CreatedAt    time.Time
+}
+
+// claimSQL picks, per aggregate, the longest run of undelivered records from
+// the oldest that are all available and unclaimed, and leases up to limit of
+// them in seq order.
+const claimSQL = `WITH ready AS (
+	SELECT id, seq, bool_and(available_at <= @now AND (claimed_until IS NULL OR claimed_until <= @now))
+		OVER (PARTITION BY aggregate_key ORDER BY seq) AS claimable
+	FROM ` + TableName + `
+	WHERE delivered_at IS NULL
+), picked AS (
+	SELECT id FROM ready WHERE claimable ORDER BY seq LIMIT @limit
+)
+UPDATE ` + TableName + ` AS o
+SET claimed_by = @consumer, claimed_until = @until, updated_at = @now
+FROM picked
+WHERE o.id = picked.id
+RETURNING o.id, o.seq, o.topic, o.aggregate_key, o.payload, o.attempt_count,
+	o.available_at, o.claimed_by, o.claimed_until, o.created_at`
+
+// ClaimBatch implements outbox.Store.
+func (s *Store) ClaimBatch(ctx context.Context, consumer string, limit int, leaseDuration time.Duration, now time.Time) ([]outbox.Record, error) {
+	var rows []claimedRow
+	err := s.db.Primary(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext(?))`, TableName).Error; err != nil {
+			return err
+		}
+		return tx.Raw(claimSQL, map[string]any{
+			"now":      now,
+			"until":    now.Add(leaseDuration),
+			"limit":    limit,
+			"consumer": consumer,
+		}).Scan(&rows).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("outboxpg: claim batch: %w", err)
+	}
+
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Seq < rows[j].Seq })
+	records := make([]outbox.Record, 0, len(rows))
+	for _, row := range rows {
+		var envelope coreevents.Envelope
+		if err := json.Unmarshal(row.Payload, &envelope); err != nil {
+			return nil, fmt.Errorf("outboxpg: decode record %s: %w", row.ID, err)
+		}
+		records = append(records, outbox.Record{
+			ID:           row.ID,
+			Topic:        row.Topic,
+			Envelope:     envelope,
+			Attempts:     row.AttemptCount,
+			AvailableAt:  row.AvailableAt,
+			ClaimedBy:    row.ClaimedBy,
+			ClaimedUntil: row.ClaimedUntil,
+			CreatedAt:    row.CreatedAt,
+			AggregateKey: row.AggregateKey,
+		})
+	}
+	return records, nil
+}
+
+// MarkDelivered implements outbox.Store.
+func (s *Store) MarkDelivered(ctx context.Context, recordID string, deliveredAt time.Time) error {
+	return s.db.Primary(ctx).Exec(`UPDATE `+TableName+`
+		SET delivered_at = ?, claimed_by = NULL, claimed_until = NULL, last_error = NULL, updated_at = ?
+		WHERE id = ?`, deliveredAt, deliveredAt, recordID).Error
+}
+
+// MarkFailed implements outbox.Store.
+func (s *Store) MarkFailed(ctx context.Context, recordID string, nextAttemptAt time.Time, lastError string, failedAt time.Time) error {
+	return s.db.Primary(ctx).Exec(`UPDATE `+TableName+`
+		SET attempt_count = attempt_count + 1, available_at = ?, last_error = ?,
+			claimed_by = NULL, claimed_until = NULL, updated_at = ?
+		WHERE id = ?`, nextAttemptAt, lastError, failedAt, recordID).Error
+}
+
+// Release implements outbox.Releaser, clearing the lease of the records still
+// claimed by consumer.
+func (s *Store) Release(ctx context.Context, consumer string, recordIDs []string) error {
+	if len(recordIDs) == 0 {
+		return nil
+	}
+	return s.db.Primary(ctx).Exec(`UPDATE `+TableName+`
+		SET claimed_by = NULL, claimed_until = NULL, updated_at = ?
+		WHERE id IN ? AND claimed_by = ? AND delivered_at IS NULL`, time.Now().UTC(), recordIDs, consumer).Error
+}
+
+// Backlog implements outbox.BacklogCounter.
+func (s *Store) Backlog(ctx context.Context) (int64, error) {
+	var pending int64
+	err := s.db.Primary(ctx).Raw(`SELECT count(*) FROM ` + TableName + ` WHERE delivered_at IS NULL`).Scan(&pending).Error
+	return pending, err
+}
+
+// PurgeDelivered deletes records delivered before cutoff and returns how many
+// were deleted. Run it periodically, e.g. from a scheduler job, to keep the
+// table small.
+func (s *Store) PurgeDelivered(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := s.db.Primary(ctx).Exec(`DELETE FROM `+TableName+` WHERE delivered_at < ?`, cutoff)
+	return result.RowsAffected, result.Error
+}
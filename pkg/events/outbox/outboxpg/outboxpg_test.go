@@ -0,0 +1,69 @@
+package outboxpg
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	gormpostgres "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	coreevents "github.com/milan604/core-lab/pkg/events"
+	"github.com/milan604/core-lab/pkg/postgres/migrations"
+)
+
+func TestMigrationsPassLint(t *testing.T) {
+	report, err := migrations.Lint(migrationFiles, migrations.WithDir("migrations"), migrations.WithWarningsAsErrors())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := report.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteOutboxStatement(t *testing.T) {
+	db, err := gorm.Open(gormpostgres.New(gormpostgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stmt *gorm.Statement
+	if err := db.Callback().Raw().After("gorm:raw").Register("capture", func(tx *gorm.DB) { stmt = tx.Statement }); err != nil {
+		t.Fatal(err)
+	}
+	envelope := coreevents.Envelope{
+		EventID:      "evt-1",
+		EventType:    "subscription.created",
+		ServiceID:    "subscription-service",
+		TenantID:     "t-1",
+		ResourceType: "subscription",
+		ResourceID:   "sub-1",
+		OccurredAt:   time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC),
+	}
+
+	if err := WriteOutbox(db, "", envelope); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stmt.SQL.String(), "INSERT INTO platform_event_outbox") {
+		t.Fatalf("SQL = %s", stmt.SQL.String())
+	}
+	wantVars := []any{"evt-1", coreevents.DefaultTopic, "subscription/sub-1", "t-1", "t-1", "subscription.created", "subscription-service", ""}
+	for i, want := range wantVars {
+		if stmt.Vars[i] != want {
+			t.Fatalf("var %d = %v, want %v (vars %v)", i, stmt.Vars[i], want, stmt.Vars)
+		}
+	}
+
+	if err := WriteOutbox(db, "", coreevents.Envelope{}); err == nil {
+		t.Fatal("WriteOutbox accepted an envelope without event_id")
+	}
+}
+
+func TestAggregateKey(t *testing.T) {
+	if got := AggregateKey(coreevents.Envelope{TenantID: "t-1", EventType: "plan.updated"}); got != "t-1" {
+		t.Fatalf("AggregateKey(no resource) = %q", got)
+	}
+	if got := AggregateKey(coreevents.Envelope{TenantID: "t-1", ResourceType: "invoice", ResourceID: "inv-9"}); got != "invoice/inv-9" {
+		t.Fatalf("AggregateKey(resource) = %q", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	coreevents "github.com/milan604/core-lab/pkg/events"
@@ -29,6 +30,10 @@ type Record struct {
 	ClaimedBy    string
 	ClaimedUntil time.Time
 	CreatedAt    time.Time
+	// AggregateKey orders delivery: records sharing a key are published one
+	// at a time in batch order, and a failure holds back the records after
+	// it. Records without a key are independent.
+	AggregateKey string
 }
 
 // Store owns claiming and state transitions for durable outbox records.
@@ -38,6 +43,20 @@ type Store interface {
 	MarkFailed(ctx context.Context, recordID string, nextAttemptAt time.Time, lastError string, failedAt time.Time) error
 }
 
+// Releaser is implemented by stores that can hand claimed records back before
+// their lease expires. The processor releases the records it skipped because
+// an earlier record of the same aggregate failed; with other stores they
+// wait for the lease to expire.
+type Releaser interface {
+	Release(ctx context.Context, consumer string, recordIDs []string) error
+}
+
+// BacklogCounter is implemented by stores that can count undelivered
+// records; Run reports the count as the outbox.events.pending gauge.
+type BacklogCounter interface {
+	Backlog(ctx context.Context) (int64, error)
+}
+
 // Publisher emits a durable outbox record to the shared event transport.
 type Publisher interface {
 	Publish(ctx context.Context, topic string, envelope coreevents.Envelope) error
@@ -53,8 +72,12 @@ type ProcessorOptions struct {
 	MinRetryBackoff time.Duration
 	MaxRetryBackoff time.Duration
 	BatchSize       int
-	Logger          logger.LogManager
-	Clock           Clock
+	// Concurrency is how many aggregates of a batch are published in
+	// parallel (1 by default). Records of one aggregate are always published
+	// in order.
+	Concurrency int
+	Logger      logger.LogManager
+	Clock       Clock
 }
 
 // Processor runs a safe polling loop for durable business-event delivery.
@@ -70,6 +93,8 @@ type Processor struct {
 	minRetry       time.Duration
 	maxRetry       time.Duration
 	batchSize      int
+	concurrency    int
+	metrics        *processorMetrics
 }
 
 func NewProcessor(store Store, publisher Publisher, opts ProcessorOptions) *Processor {
@@ -97,6 +122,9 @@ func NewProcessor(store Store, publisher Publisher, opts ProcessorOptions) *Proc
 	if opts.BatchSize <= 0 {
 		opts.BatchSize = defaultBatchSize
 	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
 	if opts.Clock == nil {
 		opts.Clock = func() time.Time { return time.Now().UTC() }
 	}
@@ -113,6 +141,8 @@ func NewProcessor(store Store, publisher Publisher, opts ProcessorOptions) *Proc
 		minRetry:       opts.MinRetryBackoff,
 		maxRetry:       opts.MaxRetryBackoff,
 		batchSize:      opts.BatchSize,
+		concurrency:    opts.Concurrency,
+		metrics:        newProcessorMetrics(strings.TrimSpace(opts.Name)),
 	}
 }
 
@@ -120,6 +150,10 @@ func (p *Processor) Run(ctx context.Context) {
 	if p == nil || p.store == nil || p.publisher == nil {
 		return
 	}
+	if counter, ok := p.store.(BacklogCounter); ok {
+		unregister := p.metrics.observeBacklog(counter)
+		defer unregister()
+	}
 
 	p.ProcessOnce(ctx)
 
@@ -146,16 +180,83 @@ func (p *Processor) ProcessOnce(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("claim outbox batch: %w", err)
 	}
-	for _, record := range records {
-		p.processRecord(ctx, record)
+	groups := groupByAggregate(records)
+	if p.concurrency == 1 || len(groups) == 1 {
+		for _, group := range groups {
+			p.processGroup(ctx, group)
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, p.concurrency)
+	for _, group := range groups {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			p.processGroup(ctx, group)
+		}()
 	}
+	wg.Wait()
 	return nil
 }
 
-func (p *Processor) processRecord(ctx context.Context, record Record) {
-	if strings.TrimSpace(record.ID) == "" {
+// groupByAggregate splits a batch into runs that must be published in order,
+// keeping the batch order within each. Records without an AggregateKey form
+// groups of one.
+func groupByAggregate(records []Record) [][]Record {
+	var groups [][]Record
+	index := make(map[string]int)
+	for _, record := range records {
+		key := strings.TrimSpace(record.AggregateKey)
+		if key == "" {
+			groups = append(groups, []Record{record})
+			continue
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], record)
+	}
+	return groups
+}
+
+// processGroup publishes the records of one aggregate in order, stopping at
+// the first that is not delivered and releasing the rest.
+func (p *Processor) processGroup(ctx context.Context, group []Record) {
+	for i, record := range group {
+		if p.processRecord(ctx, record) {
+			continue
+		}
+		rest := group[i+1:]
+		if len(rest) == 0 {
+			return
+		}
+		releaser, ok := p.store.(Releaser)
+		if !ok {
+			return
+		}
+		ids := make([]string, 0, len(rest))
+		for _, r := range rest {
+			ids = append(ids, r.ID)
+		}
+		if err := releaser.Release(ctx, p.name, ids); err != nil && p.logger != nil {
+			p.logger.ErrorFCtx(ctx, "outbox processor %s failed to release %d records of %s: %v", p.name, len(ids), record.AggregateKey, err)
+		}
 		return
 	}
+}
+
+// processRecord publishes record and reports whether it was delivered and
+// marked so.
+func (p *Processor) processRecord(ctx context.Context, record Record) bool {
+	if strings.TrimSpace(record.ID) == "" {
+		return false
+	}
 
 	now := p.clock()
 	topic := strings.TrimSpace(record.Topic)
@@ -175,19 +276,24 @@ func (p *Processor) processRecord(ctx context.Context, record Record) {
 		if p.logger != nil {
 			p.logger.ErrorFCtx(ctx, "outbox processor %s failed to publish %s: %v", p.name, record.ID, err)
 		}
-		return
+		p.metrics.recordFailed(ctx, topic)
+		return false
 	}
+	p.metrics.recordPublished(ctx, topic, record.CreatedAt, p.clock())
 
 	if err := p.store.MarkDelivered(ctx, record.ID, now); err != nil {
 		if p.logger != nil {
 			p.logger.ErrorFCtx(ctx, "outbox processor %s failed to mark record %s delivered: %v", p.name, record.ID, err)
 		}
-		return
+		// Stop the aggregate here: the record is published again once its
+		// lease expires, and later records must not overtake it.
+		return false
 	}
 
 	if p.logger != nil {
 		p.logger.DebugFCtx(ctx, "outbox processor %s delivered %s (%s)", p.name, record.ID, record.Envelope.EventType)
 	}
+	return true
 }
 
 func (p *Processor) retryBackoff(attempt int) time.Duration {
@@ -3,9 +3,14 @@ package outbox
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
 	coreevents "github.com/milan604/core-lab/pkg/events"
 )
 
@@ -94,3 +99,128 @@ func TestProcessorMarksFailedOnPublishError(t *testing.T) {
 		t.Fatalf("expected next attempt %s, got %s", expectedNext, store.failedNextTimes[0])
 	}
 }
+
+type orderingStore struct {
+	fakeStore
+	mu       sync.Mutex
+	released []string
+	backlog  int64
+}
+
+func (s *orderingStore) MarkDelivered(ctx context.Context, recordID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fakeStore.MarkDelivered(ctx, recordID, at)
+}
+
+func (s *orderingStore) MarkFailed(ctx context.Context, recordID string, next time.Time, lastError string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fakeStore.MarkFailed(ctx, recordID, next, lastError, at)
+}
+
+func (s *orderingStore) Release(_ context.Context, _ string, recordIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.released = append(s.released, recordIDs...)
+	return nil
+}
+
+func (s *orderingStore) Backlog(context.Context) (int64, error) { return s.backlog, nil }
+
+type recordingPublisher struct {
+	mu        sync.Mutex
+	published []string
+	fail      map[string]bool
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, _ string, envelope coreevents.Envelope) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail[envelope.EventID] {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, envelope.EventID)
+	return nil
+}
+
+func TestProcessorKeepsAggregateOrderAndReleasesAfterFailure(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	previous := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	t.Cleanup(func() { otel.SetMeterProvider(previous) })
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	record := func(id, aggregate string) Record {
+		return Record{ID: id, AggregateKey: aggregate, CreatedAt: now.Add(-3 * time.Second), Envelope: coreevents.Envelope{EventID: id}}
+	}
+	store := &orderingStore{fakeStore: fakeStore{claimed: []Record{
+		record("a1", "order/a"), record("b1", "order/b"), record("a2", "order/a"),
+		record("b2", "order/b"), record("b3", "order/b"), record("c1", ""),
+	}}, backlog: 4}
+	publisher := &recordingPublisher{fail: map[string]bool{"b2": true}}
+
+	processor := NewProcessor(store, publisher, ProcessorOptions{
+		Name:        "orders",
+		Concurrency: 3,
+		Clock:       func() time.Time { return now },
+	})
+	if err := processor.ProcessOnce(context.Background()); err != nil {
+		t.Fatalf("ProcessOnce returned error: %v", err)
+	}
+
+	position := map[string]int{}
+	for i, id := range publisher.published {
+		position[id] = i
+	}
+	_, publishedB3 := position["b3"]
+	if len(publisher.published) != 4 || position["a1"] > position["a2"] || publishedB3 {
+		t.Fatalf("published %v, want a1 before a2 and nothing after b2 failed", publisher.published)
+	}
+	if len(store.failed) != 1 || store.failed[0] != "b2" || len(store.released) != 1 || store.released[0] != "b3" {
+		t.Fatalf("failed = %v, released = %v", store.failed, store.released)
+	}
+
+	// Run reports the store backlog while it is running.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	store.claimed = nil
+	go func() { processor.Run(ctx); close(done) }()
+	var data metricdata.ResourceMetrics
+	deadline := time.Now().Add(time.Second)
+	pending := int64(-1)
+	for pending < 0 && time.Now().Before(deadline) {
+		if err := reader.Collect(context.Background(), &data); err != nil {
+			t.Fatalf("Collect: %v", err)
+		}
+		for _, scope := range data.ScopeMetrics {
+			for _, m := range scope.Metrics {
+				if gauge, ok := m.Data.(metricdata.Gauge[int64]); ok && m.Name == "outbox.events.pending" && len(gauge.DataPoints) == 1 {
+					pending = gauge.DataPoints[0].Value
+				}
+			}
+		}
+	}
+	cancel()
+	<-done
+
+	counts := map[string]int64{}
+	var lagSamples uint64
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch agg := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, point := range agg.DataPoints {
+					counts[m.Name] += point.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, point := range agg.DataPoints {
+					lagSamples += point.Count
+				}
+			}
+		}
+	}
+	if pending != 4 || counts["outbox.events.published"] != 4 || counts["outbox.events.failed"] != 1 || lagSamples != 4 {
+		t.Fatalf("pending = %d, counts = %v, lag samples = %d", pending, counts, lagSamples)
+	}
+}
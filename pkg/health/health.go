@@ -0,0 +1,244 @@
+// Package health aggregates health checks for liveness and readiness probes.
+//
+//	reg := health.NewRegistry()
+//	reg.Register("postgres", health.CheckerFunc(func(ctx context.Context) health.Result {
+//	  if err := db.Ping(ctx); err != nil {
+//	    return health.Down(err.Error())
+//	  }
+//	  return health.Up()
+//	}), health.Critical())
+//	reg.RegisterRoutes(engine) // GET /livez, GET /readyz
+//
+// A check that reports StatusDegraded shows up in the report without failing
+// readiness; a Critical check that reports StatusDown takes the instance out
+// of rotation.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds each check of a Registry created without WithTimeout.
+const DefaultTimeout = 2 * time.Second
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	// StatusUp means the component works normally.
+	StatusUp Status = "up"
+	// StatusDegraded means the component works with reduced capacity or
+	// freshness; it never fails readiness.
+	StatusDegraded Status = "degraded"
+	// StatusDown means the component does not work. It fails readiness when
+	// the check is registered as Critical.
+	StatusDown Status = "down"
+)
+
+// severity orders statuses from best to worst.
+func (s Status) severity() int {
+	switch s {
+	case StatusUp:
+		return 0
+	case StatusDegraded:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Status  Status         `json:"status"`
+	Message string         `json:"message,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+	// Critical reports whether the check can fail readiness. It is set by the
+	// Registry.
+	Critical bool `json:"critical"`
+}
+
+// Up returns an up Result.
+func Up() Result { return Result{Status: StatusUp} }
+
+// Degraded returns a degraded Result with message.
+func Degraded(message string) Result { return Result{Status: StatusDegraded, Message: message} }
+
+// Down returns a down Result with message.
+func Down(message string) Result { return Result{Status: StatusDown, Message: message} }
+
+// WithDetails returns r with details attached.
+func (r Result) WithDetails(details map[string]any) Result {
+	r.Details = details
+	return r
+}
+
+// Checker reports the health of one component.
+type Checker interface {
+	Check(ctx context.Context) Result
+}
+
+// CheckerFunc adapts a function to Checker.
+type CheckerFunc func(ctx context.Context) Result
+
+// Check implements Checker.
+func (f CheckerFunc) Check(ctx context.Context) Result { return f(ctx) }
+
+// Option configures a Registry.
+type Option func(*Registry)
+
+// WithTimeout bounds each check. A check that does not return in time is
+// reported down. Default: DefaultTimeout.
+func WithTimeout(d time.Duration) Option {
+	return func(r *Registry) {
+		if d > 0 {
+			r.timeout = d
+		}
+	}
+}
+
+// CheckOption configures a registered check.
+type CheckOption func(*registration)
+
+// Critical makes the check fail readiness while it reports StatusDown.
+func Critical() CheckOption {
+	return func(r *registration) { r.critical = true }
+}
+
+type registration struct {
+	name     string
+	checker  Checker
+	critical bool
+}
+
+// Registry holds the checks of a service.
+type Registry struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	checks map[string]registration
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry(opts ...Option) *Registry {
+	r := &Registry{timeout: DefaultTimeout, checks: map[string]registration{}}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(r)
+		}
+	}
+	return r
+}
+
+// Register adds checker under name, replacing a check registered under the
+// same name.
+func (r *Registry) Register(name string, checker Checker, opts ...CheckOption) {
+	if name == "" || checker == nil {
+		return
+	}
+	reg := registration{name: name, checker: checker}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&reg)
+		}
+	}
+	r.mu.Lock()
+	r.checks[name] = reg
+	r.mu.Unlock()
+}
+
+// Unregister removes the check registered under name.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.checks, name)
+	r.mu.Unlock()
+}
+
+// Report is the outcome of all checks.
+type Report struct {
+	// Status is the worst status of any check.
+	Status Status `json:"status"`
+	// Ready is false while a Critical check is down.
+	Ready     bool              `json:"ready"`
+	Checks    map[string]Result `json:"checks"`
+	CheckedAt time.Time         `json:"checked_at"`
+}
+
+// Failing returns the names of the critical checks that are down, sorted.
+func (r Report) Failing() []string {
+	var names []string
+	for name, result := range r.Checks {
+		if result.Critical && result.Status == StatusDown {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check runs every check concurrently, each bounded by the registry timeout.
+// A check that panics or times out is reported down.
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := make([]registration, 0, len(r.checks))
+	for _, reg := range r.checks {
+		checks = append(checks, reg)
+	}
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, reg := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.run(ctx, reg)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Ready: true, Checks: make(map[string]Result, len(checks)), CheckedAt: time.Now().UTC()}
+	for i, reg := range checks {
+		result := results[i]
+		report.Checks[reg.name] = result
+		if result.Status.severity() > report.Status.severity() {
+			report.Status = result.Status
+		}
+		if result.Critical && result.Status == StatusDown {
+			report.Ready = false
+		}
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, reg registration) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	done := make(chan Result, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- Down(fmt.Sprintf("check panicked: %v", recovered))
+			}
+		}()
+		done <- reg.checker.Check(ctx)
+	}()
+
+	var result Result
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result = Down(fmt.Sprintf("check did not finish: %v", ctx.Err()))
+	}
+	switch result.Status {
+	case StatusUp, StatusDegraded, StatusDown:
+	default:
+		result.Status = StatusDown
+	}
+	result.Critical = reg.critical
+	return result
+}
@@ -0,0 +1,99 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegistryCriticalDownFailsReadiness(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register("cache", CheckerFunc(func(context.Context) Result { return Down("unreachable") }))
+	reg.Register("queue", CheckerFunc(func(context.Context) Result { return Degraded("lagging") }), Critical())
+
+	report := reg.Check(context.Background())
+	if !report.Ready {
+		t.Fatalf("non-critical down and critical degraded must not fail readiness: %+v", report)
+	}
+	if report.Status != StatusDown {
+		t.Fatalf("expected worst status down, got %s", report.Status)
+	}
+
+	reg.Register("queue", CheckerFunc(func(context.Context) Result { return Down("stalled") }), Critical())
+	report = reg.Check(context.Background())
+	if report.Ready {
+		t.Fatalf("critical down check must fail readiness")
+	}
+	if failing := report.Failing(); len(failing) != 1 || failing[0] != "queue" {
+		t.Fatalf("unexpected failing checks %v", failing)
+	}
+	if !report.Checks["queue"].Critical || report.Checks["cache"].Critical {
+		t.Fatalf("critical flag not reported: %+v", report.Checks)
+	}
+}
+
+func TestRegistryTimeoutAndPanicReportDown(t *testing.T) {
+	reg := NewRegistry(WithTimeout(20 * time.Millisecond))
+	reg.Register("slow", CheckerFunc(func(ctx context.Context) Result {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return Up()
+	}), Critical())
+	reg.Register("broken", CheckerFunc(func(context.Context) Result { panic("boom") }))
+
+	report := reg.Check(context.Background())
+	if report.Checks["slow"].Status != StatusDown || report.Ready {
+		t.Fatalf("timed out critical check must be down: %+v", report.Checks["slow"])
+	}
+	if report.Checks["broken"].Status != StatusDown {
+		t.Fatalf("panicking check must be down: %+v", report.Checks["broken"])
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reg := NewRegistry()
+	healthy := true
+	reg.Register("db", CheckerFunc(func(context.Context) Result {
+		if healthy {
+			return Up()
+		}
+		return Down("connection refused")
+	}), Critical())
+
+	router := gin.New()
+	reg.RegisterRoutes(router)
+
+	get := func(path string) (*httptest.ResponseRecorder, map[string]any) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+		return rec, body
+	}
+
+	if rec, _ := get("/readyz"); rec.Code != http.StatusOK {
+		t.Fatalf("expected ready, got %d %s", rec.Code, rec.Body)
+	}
+
+	healthy = false
+	rec, body := get("/readyz")
+	if rec.Code != http.StatusServiceUnavailable || body["code"] != "not_ready" {
+		t.Fatalf("expected 503 not_ready, got %d %s", rec.Code, rec.Body)
+	}
+	meta, _ := body["meta"].(map[string]any)
+	if _, ok := meta["health"]; !ok {
+		t.Fatalf("expected report in meta.health: %s", rec.Body)
+	}
+
+	if rec, _ := get("/healthz"); rec.Code != http.StatusOK {
+		t.Fatalf("liveness must not depend on checks, got %d", rec.Code)
+	}
+}
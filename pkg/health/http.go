@@ -0,0 +1,47 @@
+package health
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/milan604/core-lab/pkg/apperr"
+	"github.com/milan604/core-lab/pkg/response"
+)
+
+// ErrorCodeNotReady is returned by the readiness route while a critical check
+// is down.
+var ErrorCodeNotReady = apperr.Register(apperr.NewErrorCode("not_ready", "Service not ready", 90, http.StatusServiceUnavailable).WithRetryable(true))
+
+// RegisterRoutes mounts the probe routes onto the provided router:
+//
+//	GET /healthz  liveness: 200 while the process serves requests
+//	GET /readyz   readiness: 200 with the check report, 503 while a critical check is down
+func (r *Registry) RegisterRoutes(router gin.IRoutes) {
+	router.GET("/healthz", r.LiveHandler())
+	router.GET("/readyz", r.ReadyHandler())
+}
+
+// LiveHandler answers liveness probes. It does not run checks, so a failing
+// dependency never restarts the process.
+func (r *Registry) LiveHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response.Success(c, gin.H{"status": StatusUp})
+	}
+}
+
+// ReadyHandler runs the checks and answers with the Report, or with a
+// not_ready error carrying the Report in meta.health.
+func (r *Registry) ReadyHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := r.Check(c.Request.Context())
+		if report.Ready {
+			response.Success(c, report)
+			return
+		}
+		response.JSONErrorWithMeta(c,
+			apperr.New(ErrorCodeNotReady).WithMessage("not ready: "+strings.Join(report.Failing(), ", ")+" down"),
+			map[string]interface{}{"health": report})
+	}
+}
@@ -27,6 +27,16 @@ type Client struct {
 	requestHooks   []RequestHook
 	responseHooks  []ResponseHook
 	circuitBreaker *gobreaker.CircuitBreaker[*http.Response]
+
+	bearerPassThrough   bool
+	passThroughFallback bool
+
+	peerService string
+	tracingOff  bool
+	metrics     *clientMetrics
+	resolver    *CachingResolver
+
+	requestEvents RequestEventFunc
 }
 
 // RequestHook is a function that can modify a request before it's sent.
@@ -165,12 +175,22 @@ func NewClient(opts ...ClientOption) *Client {
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.installResolver()
 
 	return c
 }
 
 // Do executes an HTTP request with automatic token injection and retry logic.
-func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+func (c *Client) Do(ctx context.Context, req *http.Request) (resp *http.Response, err error) {
+	ctx, span := c.startSpan(ctx, req)
+	defer func() { endSpan(span, resp, err) }()
+	if c.metrics != nil {
+		start := time.Now()
+		defer func() { c.metrics.record(ctx, req, c.peerName(req), start, resp, err) }()
+	}
+	ctx, finishEvents := c.startRequestEvents(ctx, req)
+	defer func() { finishEvents(resp, err) }()
+
 	if err := c.prepareRequest(ctx, req); err != nil {
 		return nil, err
 	}
@@ -209,13 +229,21 @@ func (c *Client) applyRequestHooks(req *http.Request) error {
 	return nil
 }
 
-// injectToken injects the authorization token if token cache is available.
+// injectToken injects the forwarded inbound token or, failing that, the service
+// token if token cache is available.
 func (c *Client) injectToken(ctx context.Context, req *http.Request) error {
+	if token, ok := c.passThroughToken(ctx); ok {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+	if c.bearerPassThrough && !c.passThroughFallback {
+		return ErrNoInboundBearerToken
+	}
 	if c.tokenCache == nil {
 		return nil
 	}
 
-	token, err := c.tokenCache.GetToken(ctx)
+	token, err := c.serviceToken(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get token: %w", err)
 	}
@@ -224,6 +252,17 @@ func (c *Client) injectToken(ctx context.Context, req *http.Request) error {
 	return nil
 }
 
+// serviceToken returns the cached service token, reporting a fetch from the
+// token provider as RequestTokenRefreshed.
+func (c *Client) serviceToken(ctx context.Context) (string, error) {
+	start := time.Now()
+	token, fetched, err := c.tokenCache.getToken(ctx)
+	if fetched {
+		emitRequestEvent(ctx, RequestEvent{Kind: RequestTokenRefreshed, Duration: time.Since(start), Err: err})
+	}
+	return token, err
+}
+
 // readRequestBody reads the request body once for retries.
 func (c *Client) readRequestBody(req *http.Request) ([]byte, error) {
 	if req.Body == nil {
@@ -240,18 +279,26 @@ func (c *Client) readRequestBody(req *http.Request) ([]byte, error) {
 	return bodyBytes, nil
 }
 
-// executeWithRetry executes the request with retry logic.
+// executeWithRetry executes the request with retry logic. Retries, 401
+// re-authentication, and circuit breaker fail-fast are recorded as events on
+// the client span. A 401 is re-sent once with a fresh token on top of the
+// retry attempts, so WithRetry(1, ...) turns off retries but not
+// re-authentication.
 func (c *Client) executeWithRetry(ctx context.Context, req *http.Request, bodyBytes []byte) (*http.Response, error) {
 	var lastErr error
+	var reason string
+	sent := 0
+	attempts, reauthed := c.retryMax, false
 
-	for attempt := 0; attempt < c.retryMax; attempt++ {
+	for attempt := 0; attempt < attempts; attempt++ {
 		if attempt > 0 {
-			if err := c.waitForRetry(ctx, attempt); err != nil {
+			if err := c.waitForRetry(ctx, attempt, reason); err != nil {
 				return nil, err
 			}
 		}
 
 		resp, err := c.executeRequest(ctx, req, bodyBytes, attempt)
+		sent++
 		if err != nil {
 			lastErr = err
 			// Don't retry when the circuit breaker is open — fail fast.
@@ -259,11 +306,13 @@ func (c *Client) executeWithRetry(ctx context.Context, req *http.Request, bodyBy
 				if c.logger != nil {
 					c.logger.WarnF("circuit breaker open, failing fast: %v", err)
 				}
+				c.addSpanEvent(ctx, EventCircuitOpen, attrResendCount.Int(attempt))
 				break
 			}
 			if c.logger != nil {
 				c.logger.WarnF("request failed: %v (attempt %d/%d)", err, attempt+1, c.retryMax)
 			}
+			reason = err.Error()
 			continue
 		}
 
@@ -272,24 +321,31 @@ func (c *Client) executeWithRetry(ctx context.Context, req *http.Request, bodyBy
 			return nil, err
 		}
 
-		if c.shouldRetryOn401(resp, attempt) {
+		if !reauthed && c.shouldRetryOn401(ctx, resp) {
+			attempts, reauthed = attempts+1, true
 			resp.Body.Close()
 			c.handle401()
+			c.addSpanEvent(ctx, EventReauth, attrResendCount.Int(attempt))
+			reason = http.StatusText(http.StatusUnauthorized)
 			continue
 		}
 
+		c.recordResends(ctx, attempt)
 		return resp, nil
 	}
 
+	c.recordResends(ctx, sent-1)
 	return nil, fmt.Errorf("request failed after %d attempts: %w", c.retryMax, lastErr)
 }
 
 // waitForRetry waits for the retry delay with exponential backoff.
-func (c *Client) waitForRetry(ctx context.Context, attempt int) error {
+func (c *Client) waitForRetry(ctx context.Context, attempt int, reason string) error {
 	delay := c.retryDelay * time.Duration(1<<uint(attempt-1))
 	if c.logger != nil {
 		c.logger.DebugF("retrying request after %v (attempt %d/%d)", delay, attempt+1, c.retryMax)
 	}
+	c.recordRetry(ctx, attempt, delay, reason)
+	emitRequestEvent(ctx, RequestEvent{Kind: RequestRetryScheduled, Attempt: attempt + 1, Duration: delay, Reason: reason})
 
 	select {
 	case <-ctx.Done():
@@ -301,13 +357,13 @@ func (c *Client) waitForRetry(ctx context.Context, attempt int) error {
 
 // executeRequest executes a single request attempt, optionally through the circuit breaker.
 func (c *Client) executeRequest(ctx context.Context, req *http.Request, bodyBytes []byte, attempt int) (*http.Response, error) {
-	reqClone := req.Clone(ctx)
+	reqClone := req.Clone(withAttemptTrace(ctx, attempt+1))
 	if len(bodyBytes) > 0 {
 		reqClone.Body = io.NopCloser(bytes.NewReader(bodyBytes))
 	}
 
-	if c.tokenCache != nil && attempt > 0 {
-		token, err := c.tokenCache.GetToken(ctx)
+	if _, forwarded := c.passThroughToken(ctx); c.tokenCache != nil && attempt > 0 && !forwarded {
+		token, err := c.serviceToken(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get token for retry: %w", err)
 		}
@@ -350,9 +406,13 @@ func (c *Client) applyResponseHooks(resp *http.Response) error {
 	return nil
 }
 
-// shouldRetryOn401 checks if we should retry on 401.
-func (c *Client) shouldRetryOn401(resp *http.Response, attempt int) bool {
-	return resp.StatusCode == http.StatusUnauthorized && c.tokenCache != nil && attempt < c.retryMax-1
+// shouldRetryOn401 checks if we should retry on 401. Forwarded inbound tokens
+// cannot be refreshed, so they are never retried.
+func (c *Client) shouldRetryOn401(ctx context.Context, resp *http.Response) bool {
+	if _, forwarded := c.passThroughToken(ctx); forwarded {
+		return false
+	}
+	return resp.StatusCode == http.StatusUnauthorized && c.tokenCache != nil
 }
 
 // handle401 handles a 401 response by invalidating the token cache.
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"context"
+	stdhttp "net/http"
+	"testing"
+	"time"
+)
+
+// A 401 is re-sent once with a fresh token even when retries are off, and
+// only once when they are on.
+func TestClientReauthenticatesOnceOutsideRetries(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name     string
+		attempts int
+		statuses []int
+		want     int
+		sent     int
+	}{
+		{name: "retries off", attempts: 1, statuses: []int{401, 200}, want: 200, sent: 2},
+		{name: "retries off, rejected again", attempts: 1, statuses: []int{401, 401}, want: 401, sent: 2},
+		{name: "retries on, rejected again", attempts: 3, statuses: []int{401, 401, 200}, want: 401, sent: 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := 0
+			client := NewClient(
+				WithRetry(tt.attempts, time.Millisecond),
+				WithTokenProvider(NewStaticTokenProvider("service-token"), time.Minute),
+				WithHTTPClient(&stdhttp.Client{
+					Transport: roundTripFunc(func(req *stdhttp.Request) (*stdhttp.Response, error) {
+						sent++
+						return jsonResponse(tt.statuses[sent-1], `{}`), nil
+					}),
+				}),
+			)
+
+			resp, err := client.Get(context.Background(), "https://billing.internal/invoices")
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want || sent != tt.sent {
+				t.Fatalf("status = %d after %d requests, want %d after %d", resp.StatusCode, sent, tt.want, tt.sent)
+			}
+		})
+	}
+}
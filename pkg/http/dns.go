@@ -0,0 +1,450 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/sync/singleflight"
+)
+
+// DNS cache defaults; see DNSCacheConfig.
+const (
+	DefaultDNSMinTTL      = time.Second
+	DefaultDNSMaxTTL      = 5 * time.Minute
+	DefaultDNSFallbackTTL = 30 * time.Second
+	DefaultDNSNegativeTTL = 5 * time.Second
+)
+
+// Metric attribute keys recorded by CachingResolver.
+const (
+	AttrDNSQuestionName = attribute.Key("dns.question.name")
+	AttrDNSCacheResult  = attribute.Key("dns.cache.result")
+	attrErrorType       = attribute.Key("error.type")
+)
+
+// DNSCacheConfig configures a CachingResolver.
+type DNSCacheConfig struct {
+	// MinTTL and MaxTTL clamp the TTL of cached answers (DefaultDNSMinTTL and
+	// DefaultDNSMaxTTL by default).
+	MinTTL time.Duration
+	MaxTTL time.Duration
+	// FallbackTTL is used for answers without a TTL, such as /etc/hosts
+	// entries (DefaultDNSFallbackTTL by default).
+	FallbackTTL time.Duration
+	// NegativeTTL is how long "no such host" answers are cached
+	// (DefaultDNSNegativeTTL by default); a negative value disables negative
+	// caching. Other failures, such as timeouts, are never cached.
+	NegativeTTL time.Duration
+	// Lookup resolves host and returns the answer TTL, or 0 when unknown. The
+	// default queries the system's DNS servers with the pure-Go resolver and
+	// reads the TTLs from the responses.
+	Lookup func(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error)
+}
+
+// CachingResolver caches host lookups for outbound connections, honouring the
+// TTLs in DNS answers, so high-QPS clients stop querying cluster DNS for every
+// new connection. Concurrent lookups of the same host share one query.
+//
+// It records dns.lookup.duration and dns.lookup.errors for the queries it
+// sends, labelled by dns.question.name and error.type, and dns.cache.lookups
+// labelled by dns.cache.result ("hit", "negative_hit" or "miss"), on the
+// global meter provider.
+type CachingResolver struct {
+	cfg   DNSCacheConfig
+	now   func() time.Time
+	group singleflight.Group
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+
+	lookups  metric.Int64Counter
+	duration metric.Float64Histogram
+	errors   metric.Int64Counter
+}
+
+type dnsEntry struct {
+	addrs   []net.IPAddr
+	err     error
+	expires time.Time
+}
+
+// NewCachingResolver creates a CachingResolver. Metrics are optional for
+// resolving: if the global meter rejects an instrument, the error goes to the
+// OpenTelemetry error handler and lookups are still cached and served, just
+// without that metric.
+func NewCachingResolver(cfg DNSCacheConfig) *CachingResolver {
+	if cfg.MinTTL <= 0 {
+		cfg.MinTTL = DefaultDNSMinTTL
+	}
+	if cfg.MaxTTL <= 0 {
+		cfg.MaxTTL = DefaultDNSMaxTTL
+	}
+	if cfg.FallbackTTL <= 0 {
+		cfg.FallbackTTL = DefaultDNSFallbackTTL
+	}
+	if cfg.NegativeTTL == 0 {
+		cfg.NegativeTTL = DefaultDNSNegativeTTL
+	}
+	if cfg.Lookup == nil {
+		cfg.Lookup = systemLookup
+	}
+
+	r := &CachingResolver{cfg: cfg, now: time.Now, entries: make(map[string]dnsEntry)}
+	meter := otel.Meter(tracerName)
+	var err error
+	if r.lookups, err = meter.Int64Counter("dns.cache.lookups",
+		metric.WithDescription("Host lookups served by the DNS cache"), metric.WithUnit("{lookup}")); err != nil {
+		otel.Handle(fmt.Errorf("create dns.cache.lookups: %w", err))
+		r.lookups = nil
+	}
+	if r.duration, err = meter.Float64Histogram("dns.lookup.duration",
+		metric.WithDescription("Duration of DNS queries sent on a cache miss"), metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5)); err != nil {
+		otel.Handle(fmt.Errorf("create dns.lookup.duration: %w", err))
+		r.duration = nil
+	}
+	if r.errors, err = meter.Int64Counter("dns.lookup.errors",
+		metric.WithDescription("Failed DNS queries"), metric.WithUnit("{lookup}")); err != nil {
+		otel.Handle(fmt.Errorf("create dns.lookup.errors: %w", err))
+		r.errors = nil
+	}
+	return r
+}
+
+var (
+	sharedResolverOnce sync.Once
+	sharedResolver     *CachingResolver
+)
+
+// SharedCachingResolver returns a process-wide CachingResolver with the
+// default configuration, so clients calling the same hosts share one cache.
+func SharedCachingResolver() *CachingResolver {
+	sharedResolverOnce.Do(func() { sharedResolver = NewCachingResolver(DNSCacheConfig{}) })
+	return sharedResolver
+}
+
+// WithDNSCache resolves hosts through r when the client dials a new
+// connection. It applies to the client's *http.Transport (a clone of
+// http.DefaultTransport when none is set), including one installed by
+// WithMTLS or WithHTTPClient in any order; custom RoundTrippers are left
+// unchanged.
+//
+//	client := http.NewClient(http.WithDNSCache(http.SharedCachingResolver()), ...)
+func WithDNSCache(r *CachingResolver) ClientOption {
+	return func(c *Client) {
+		c.resolver = r
+	}
+}
+
+// installResolver points the transport's dialer at c.resolver; NewClient runs
+// it after every option so the transport is final.
+func (c *Client) installResolver() {
+	if c.resolver == nil {
+		return
+	}
+	var transport *http.Transport
+	switch t := c.httpClient.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		if c.logger != nil {
+			c.logger.WarnF("DNS cache not installed: transport %T is not an *http.Transport", t)
+		}
+		return
+	}
+	transport.DialContext = c.resolver.DialContext(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second})
+	// Copy the http.Client so a client shared through WithHTTPClient keeps
+	// its own transport.
+	httpClient := *c.httpClient
+	httpClient.Transport = transport
+	c.httpClient = &httpClient
+}
+
+// DialContext returns a dial function for http.Transport.DialContext that
+// resolves the host through r and tries each address in turn with dialer.
+func (r *CachingResolver) DialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, address)
+		}
+		addrs, err := traceDNS(ctx, host, func() ([]net.IPAddr, error) {
+			return r.LookupIPAddr(ctx, host)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var dialErr error
+		for _, addr := range addrs {
+			if !matchesNetwork(network, addr.IP) {
+				continue
+			}
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = err
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if dialErr == nil {
+			dialErr = &net.AddrError{Err: "no suitable address found", Addr: host}
+		}
+		return nil, dialErr
+	}
+}
+
+func matchesNetwork(network string, ip net.IP) bool {
+	switch network {
+	case "tcp4", "udp4":
+		return ip.To4() != nil
+	case "tcp6", "udp6":
+		return ip.To4() == nil
+	}
+	return true
+}
+
+// LookupIPAddr returns the addresses of host from the cache, querying DNS
+// when the cached answer has expired.
+func (r *CachingResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	r.mu.Lock()
+	entry, ok := r.entries[host]
+	r.mu.Unlock()
+	if ok && r.now().Before(entry.expires) {
+		result := "hit"
+		if entry.err != nil {
+			result = "negative_hit"
+		}
+		r.countLookup(ctx, host, result)
+		return entry.addrs, entry.err
+	}
+	r.countLookup(ctx, host, "miss")
+
+	// The query is shared by every caller waiting on host, so it must not
+	// be cancelled with the first caller's context.
+	ch := r.group.DoChan(host, func() (any, error) {
+		return r.resolve(context.WithoutCancel(ctx), host)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]net.IPAddr), nil
+	}
+}
+
+// Flush drops every cached answer.
+func (r *CachingResolver) Flush() {
+	r.mu.Lock()
+	r.entries = make(map[string]dnsEntry)
+	r.mu.Unlock()
+}
+
+func (r *CachingResolver) resolve(ctx context.Context, host string) ([]net.IPAddr, error) {
+	start := r.now()
+	addrs, ttl, err := r.cfg.Lookup(ctx, host)
+	r.recordQuery(ctx, host, start, err)
+
+	var ttlToCache time.Duration
+	switch {
+	case err == nil && len(addrs) > 0:
+		if ttl <= 0 {
+			ttl = r.cfg.FallbackTTL
+		}
+		ttlToCache = min(max(ttl, r.cfg.MinTTL), r.cfg.MaxTTL)
+	case err == nil:
+		err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		fallthrough
+	default:
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound && r.cfg.NegativeTTL > 0 {
+			ttlToCache = r.cfg.NegativeTTL
+		}
+	}
+
+	if ttlToCache > 0 {
+		now := r.now()
+		r.mu.Lock()
+		if len(r.entries) >= 1024 {
+			for h, e := range r.entries {
+				if !now.Before(e.expires) {
+					delete(r.entries, h)
+				}
+			}
+		}
+		r.entries[host] = dnsEntry{addrs: addrs, err: err, expires: now.Add(ttlToCache)}
+		r.mu.Unlock()
+	}
+	return addrs, err
+}
+
+func (r *CachingResolver) countLookup(ctx context.Context, host, result string) {
+	if r.lookups != nil {
+		r.lookups.Add(ctx, 1, metric.WithAttributes(AttrDNSQuestionName.String(host), AttrDNSCacheResult.String(result)))
+	}
+}
+
+func (r *CachingResolver) recordQuery(ctx context.Context, host string, start time.Time, err error) {
+	attrs := []attribute.KeyValue{AttrDNSQuestionName.String(host)}
+	if err != nil {
+		attrs = append(attrs, attrErrorType.String(dnsErrorType(err)))
+		if r.errors != nil {
+			r.errors.Add(ctx, 1, metric.WithAttributes(attrs...))
+		}
+	}
+	if r.duration != nil {
+		r.duration.Record(ctx, r.now().Sub(start).Seconds(), metric.WithAttributes(attrs...))
+	}
+}
+
+// dnsErrorType classifies a lookup error for the error.type attribute.
+func dnsErrorType(err error) string {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		return "host_not_found"
+	case errors.As(err, &dnsErr) && dnsErr.IsTimeout:
+		return "timeout"
+	case errors.As(err, &dnsErr) && dnsErr.IsTemporary:
+		return "temporary"
+	}
+	return "other"
+}
+
+// goResolver is the pure-Go resolver used by systemLookup. Its connections to
+// the DNS servers are wrapped so the TTLs of the answers can be read.
+var goResolver = &net.Resolver{
+	PreferGo: true,
+	Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		if rec, ok := ctx.Value(ttlRecorderKey{}).(*ttlRecorder); ok {
+			return wrapDNSConn(conn, rec), nil
+		}
+		return conn, nil
+	},
+}
+
+func systemLookup(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	rec := &ttlRecorder{}
+	addrs, err := goResolver.LookupIPAddr(context.WithValue(ctx, ttlRecorderKey{}, rec), host)
+	return addrs, rec.TTL(), err
+}
+
+type ttlRecorderKey struct{}
+
+// ttlRecorder keeps the lowest TTL of the address and CNAME records seen in
+// the DNS responses of one lookup.
+type ttlRecorder struct {
+	mu   sync.Mutex
+	ttl  uint32
+	seen bool
+}
+
+// TTL returns the lowest TTL seen, or 0 when no record was seen.
+func (t *ttlRecorder) TTL() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.seen {
+		return 0
+	}
+	return time.Duration(t.ttl) * time.Second
+}
+
+func (t *ttlRecorder) observe(msg []byte) {
+	var p dnsmessage.Parser
+	if _, err := p.Start(msg); err != nil {
+		return
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return
+	}
+	for {
+		h, err := p.AnswerHeader()
+		if err != nil {
+			return
+		}
+		switch h.Type {
+		case dnsmessage.TypeA, dnsmessage.TypeAAAA, dnsmessage.TypeCNAME:
+			t.mu.Lock()
+			if !t.seen || h.TTL < t.ttl {
+				t.ttl, t.seen = h.TTL, true
+			}
+			t.mu.Unlock()
+		}
+		if err := p.SkipAnswer(); err != nil {
+			return
+		}
+	}
+}
+
+// dnsConn passes DNS responses read by the resolver to a ttlRecorder. Over
+// TCP, responses are framed with a two-byte length.
+type dnsConn struct {
+	net.Conn
+	rec    *ttlRecorder
+	stream bool
+	buf    []byte
+}
+
+// dnsPacketConn keeps UDP connections recognisable as net.PacketConn, which
+// the resolver uses to choose between packet and stream framing.
+type dnsPacketConn struct {
+	*dnsConn
+	packet net.PacketConn
+}
+
+func (c dnsPacketConn) ReadFrom(b []byte) (int, net.Addr, error) { return c.packet.ReadFrom(b) }
+func (c dnsPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return c.packet.WriteTo(b, addr)
+}
+
+func wrapDNSConn(conn net.Conn, rec *ttlRecorder) net.Conn {
+	if packet, ok := conn.(net.PacketConn); ok {
+		return dnsPacketConn{dnsConn: &dnsConn{Conn: conn, rec: rec}, packet: packet}
+	}
+	return &dnsConn{Conn: conn, rec: rec, stream: true}
+}
+
+func (c *dnsConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n == 0 {
+		return n, err
+	}
+	if !c.stream {
+		c.rec.observe(b[:n])
+		return n, err
+	}
+	c.buf = append(c.buf, b[:n]...)
+	for len(c.buf) >= 2 {
+		size := int(c.buf[0])<<8 | int(c.buf[1])
+		if len(c.buf) < 2+size {
+			break
+		}
+		c.rec.observe(c.buf[2 : 2+size])
+		c.buf = c.buf[2+size:]
+	}
+	return n, err
+}
@@ -0,0 +1,181 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestCachingResolverHonoursTTLAndCachesNotFound(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	previous := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	t.Cleanup(func() { otel.SetMeterProvider(previous) })
+
+	var queries atomic.Int64
+	r := NewCachingResolver(DNSCacheConfig{
+		MaxTTL: time.Minute,
+		Lookup: func(_ context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+			queries.Add(1)
+			switch host {
+			case "sentinel.platform.svc":
+				return []net.IPAddr{{IP: net.ParseIP("10.0.0.7")}}, 10 * time.Second, nil
+			case "long.platform.svc":
+				return []net.IPAddr{{IP: net.ParseIP("10.0.0.8")}}, time.Hour, nil
+			}
+			return nil, 0, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		},
+	})
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	lookup := func(host string) ([]net.IPAddr, error) {
+		t.Helper()
+		return r.LookupIPAddr(ctx, host)
+	}
+	for range 3 {
+		if addrs, err := lookup("sentinel.platform.svc"); err != nil || addrs[0].IP.String() != "10.0.0.7" {
+			t.Fatalf("LookupIPAddr() = %v, %v", addrs, err)
+		}
+	}
+	if _, err := lookup("missing.platform.svc"); err == nil {
+		t.Fatal("LookupIPAddr(missing) succeeded")
+	}
+	lookup("missing.platform.svc")
+	lookup("long.platform.svc")
+	if got := queries.Load(); got != 3 {
+		t.Fatalf("queries = %d, want 3", got)
+	}
+
+	// The 10s TTL and 5s negative TTL expire; the 1h TTL is capped at MaxTTL.
+	now = now.Add(11 * time.Second)
+	lookup("sentinel.platform.svc")
+	lookup("missing.platform.svc")
+	lookup("long.platform.svc")
+	if got := queries.Load(); got != 5 {
+		t.Fatalf("queries after expiry = %d, want 5", got)
+	}
+	now = now.Add(time.Minute)
+	lookup("long.platform.svc")
+	if got := queries.Load(); got != 6 {
+		t.Fatalf("queries after MaxTTL = %d, want 6", got)
+	}
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &data); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	results := map[string]int64{}
+	var failures int64
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				continue
+			}
+			for _, point := range sum.DataPoints {
+				switch m.Name {
+				case "dns.cache.lookups":
+					result, _ := point.Attributes.Value(AttrDNSCacheResult)
+					results[result.AsString()] += point.Value
+				case "dns.lookup.errors":
+					errorType, _ := point.Attributes.Value(attrErrorType)
+					if errorType.AsString() != "host_not_found" {
+						t.Fatalf("error.type = %q", errorType.AsString())
+					}
+					failures += point.Value
+				}
+			}
+		}
+	}
+	if results["hit"] != 3 || results["negative_hit"] != 1 || results["miss"] != 6 || failures != 2 {
+		t.Fatalf("cache results = %v, failures = %d", results, failures)
+	}
+}
+
+func TestDNSConnRecordsAnswerTTLs(t *testing.T) {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true})
+	builder.EnableCompression()
+	name := dnsmessage.MustNewName("sentinel.platform.svc.cluster.local.")
+	if err := builder.StartQuestions(); err != nil {
+		t.Fatal(err)
+	}
+	builder.Question(dnsmessage.Question{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET})
+	builder.StartAnswers()
+	builder.AResource(dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: 30}, dnsmessage.AResource{A: [4]byte{10, 0, 0, 7}})
+	builder.AResource(dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: 12}, dnsmessage.AResource{A: [4]byte{10, 0, 0, 8}})
+	msg, err := builder.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Over TCP the response is length-prefixed and may arrive in pieces.
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		server.Write(append([]byte{byte(len(msg) >> 8), byte(len(msg))}, msg...))
+		server.Close()
+	}()
+	rec := &ttlRecorder{}
+	conn := wrapDNSConn(client, rec)
+	buf := make([]byte, 7)
+	for {
+		if _, err := conn.Read(buf); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := rec.TTL(); got != 12*time.Second {
+		t.Fatalf("TTL() = %s, want 12s", got)
+	}
+}
+
+func TestClientDialsThroughDNSCache(t *testing.T) {
+	server := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, _ *stdhttp.Request) {
+		w.WriteHeader(stdhttp.StatusNoContent)
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+	_, port, _ := net.SplitHostPort(target.Host)
+
+	var queries atomic.Int64
+	resolver := NewCachingResolver(DNSCacheConfig{
+		Lookup: func(_ context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+			queries.Add(1)
+			if host != "billing.internal" {
+				t.Errorf("lookup of %q", host)
+			}
+			return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, time.Minute, nil
+		},
+	})
+	client := NewClient(WithDNSCache(resolver), WithRetry(1, time.Millisecond))
+
+	for range 2 {
+		resp, err := client.Get(context.Background(), "http://billing.internal:"+port+"/health")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != stdhttp.StatusNoContent {
+			t.Fatalf("status = %d", resp.StatusCode)
+		}
+		// Force a new connection, and so a new lookup, for the next request.
+		client.httpClient.CloseIdleConnections()
+	}
+	if got := queries.Load(); got != 1 {
+		t.Fatalf("queries = %d, want 1", got)
+	}
+}
@@ -0,0 +1,288 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestEventKind names a step in the life of a request made through Do.
+type RequestEventKind string
+
+// Request lifecycle events, in the order they usually occur. The resolve,
+// connect and TLS events only occur when a new connection is dialed.
+const (
+	RequestAttemptStart   RequestEventKind = "attempt_start"
+	RequestResolveStart   RequestEventKind = "resolve_start"
+	RequestResolveDone    RequestEventKind = "resolve_done"
+	RequestConnectStart   RequestEventKind = "connect_start"
+	RequestConnectDone    RequestEventKind = "connect_done"
+	RequestTLSStart       RequestEventKind = "tls_start"
+	RequestTLSDone        RequestEventKind = "tls_done"
+	RequestConnAcquired   RequestEventKind = "conn_acquired"
+	RequestWritten        RequestEventKind = "request_written"
+	RequestFirstByte      RequestEventKind = "first_byte"
+	RequestRetryScheduled RequestEventKind = "retry_scheduled"
+	RequestTokenRefreshed RequestEventKind = "token_refreshed"
+	RequestDone           RequestEventKind = "done"
+)
+
+// RequestEvent is one step in the life of a request. Fields that do not
+// apply to Kind are zero.
+type RequestEvent struct {
+	Kind   RequestEventKind
+	Method string
+	// URL is the target without query string or credentials.
+	URL  string
+	Peer string
+	// Attempt is the attempt the event belongs to, starting at 1. For
+	// RequestRetryScheduled it is the attempt about to be sent.
+	Attempt int
+	At      time.Time
+	// Elapsed is the time since Do was called.
+	Elapsed time.Duration
+	// Duration is the length of the step that just ended: the lookup,
+	// connect, handshake or token fetch for their done events, the server's
+	// time to first byte for RequestFirstByte, and the backoff for
+	// RequestRetryScheduled.
+	Duration time.Duration
+	// Host is the name being resolved; Addr is the resolved addresses, the
+	// address being dialed, or the connection's remote address.
+	Host string
+	Addr string
+	// Reused and IdleTime describe a pooled connection for
+	// RequestConnAcquired.
+	Reused   bool
+	IdleTime time.Duration
+	// TLSVersion and TLSResumed describe the handshake for RequestTLSDone.
+	TLSVersion string
+	TLSResumed bool
+	// Reason is why a retry was scheduled.
+	Reason     string
+	StatusCode int
+	Err        error
+}
+
+// RequestEventFunc receives request lifecycle events. It runs synchronously
+// on the request path, sometimes from transport goroutines, so it must be
+// fast. Events of one request are delivered one at a time; a client-wide
+// function sees concurrent requests and must be safe for concurrent use.
+type RequestEventFunc func(RequestEvent)
+
+type requestEventsKey struct{}
+
+// ContextWithRequestEvents sends the lifecycle events of every request made
+// with ctx to fn, e.g. to trace one slow call against a specific downstream.
+func ContextWithRequestEvents(ctx context.Context, fn RequestEventFunc) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	if existing, ok := ctx.Value(requestEventsKey{}).(RequestEventFunc); ok {
+		fn = chainRequestEvents(existing, fn)
+	}
+	return context.WithValue(ctx, requestEventsKey{}, fn)
+}
+
+// WithRequestEvents sends the lifecycle events of every request made through
+// the client to fn.
+func WithRequestEvents(fn RequestEventFunc) ClientOption {
+	return func(c *Client) {
+		if fn == nil {
+			return
+		}
+		if c.requestEvents != nil {
+			fn = chainRequestEvents(c.requestEvents, fn)
+		}
+		c.requestEvents = fn
+	}
+}
+
+// RequestEventChannel returns a RequestEventFunc that sends events to ch.
+// Sends never block a request: an event that does not fit in ch's buffer is
+// dropped. No events are sent once Do has returned, so ch may be closed then.
+func RequestEventChannel(ch chan<- RequestEvent) RequestEventFunc {
+	return func(event RequestEvent) {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func chainRequestEvents(first, second RequestEventFunc) RequestEventFunc {
+	return func(event RequestEvent) {
+		first(event)
+		second(event)
+	}
+}
+
+// requestEmitter delivers the events of one call to Do.
+type requestEmitter struct {
+	fn     RequestEventFunc
+	method string
+	url    string
+	peer   string
+	start  time.Time
+
+	mu      sync.Mutex
+	stopped bool
+}
+
+type requestEmitterKey struct{}
+
+// startRequestEvents returns ctx carrying an emitter for req when the client
+// or ctx has a RequestEventFunc, and a function that sends RequestDone and
+// stops the emitter.
+func (c *Client) startRequestEvents(ctx context.Context, req *http.Request) (context.Context, func(*http.Response, error)) {
+	fn := c.requestEvents
+	if ctxFn, ok := ctx.Value(requestEventsKey{}).(RequestEventFunc); ok {
+		if fn != nil {
+			fn = chainRequestEvents(fn, ctxFn)
+		} else {
+			fn = ctxFn
+		}
+	}
+	if fn == nil {
+		return ctx, func(*http.Response, error) {}
+	}
+	e := &requestEmitter{fn: fn, method: req.Method, peer: c.peerName(req), start: time.Now()}
+	if req.URL != nil {
+		e.url = redactedURL(req)
+	}
+	return context.WithValue(ctx, requestEmitterKey{}, e), func(resp *http.Response, err error) {
+		event := RequestEvent{Kind: RequestDone, Err: err}
+		if resp != nil {
+			event.StatusCode = resp.StatusCode
+		}
+		e.emit(event)
+		e.mu.Lock()
+		e.stopped = true
+		e.mu.Unlock()
+	}
+}
+
+// emitRequestEvent sends event to the emitter in ctx, if any.
+func emitRequestEvent(ctx context.Context, event RequestEvent) {
+	if e, ok := ctx.Value(requestEmitterKey{}).(*requestEmitter); ok {
+		e.emit(event)
+	}
+}
+
+func (e *requestEmitter) emit(event RequestEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped {
+		return
+	}
+	event.Method, event.URL, event.Peer = e.method, e.url, e.peer
+	event.At = time.Now()
+	event.Elapsed = event.At.Sub(e.start)
+	e.fn(event)
+}
+
+// withAttemptTrace returns ctx with an httptrace.ClientTrace that reports the
+// connection events of one attempt, when ctx carries an emitter.
+func withAttemptTrace(ctx context.Context, attempt int) context.Context {
+	e, ok := ctx.Value(requestEmitterKey{}).(*requestEmitter)
+	if !ok {
+		return ctx
+	}
+	emit := func(event RequestEvent) {
+		event.Attempt = attempt
+		e.emit(event)
+	}
+	emit(RequestEvent{Kind: RequestAttemptStart})
+
+	var (
+		mu         sync.Mutex
+		dnsStart   time.Time
+		tlsStart   time.Time
+		wroteAt    time.Time
+		dialStarts = map[string]time.Time{}
+	)
+	since := func(start *time.Time) time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		if start.IsZero() {
+			return 0
+		}
+		return time.Since(*start)
+	}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			mu.Lock()
+			dnsStart = time.Now()
+			mu.Unlock()
+			emit(RequestEvent{Kind: RequestResolveStart, Host: info.Host})
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			addrs := make([]string, len(info.Addrs))
+			for i, addr := range info.Addrs {
+				addrs[i] = addr.String()
+			}
+			emit(RequestEvent{Kind: RequestResolveDone, Duration: since(&dnsStart), Addr: strings.Join(addrs, ","), Err: info.Err})
+		},
+		ConnectStart: func(network, addr string) {
+			mu.Lock()
+			dialStarts[addr] = time.Now()
+			mu.Unlock()
+			emit(RequestEvent{Kind: RequestConnectStart, Addr: addr})
+		},
+		ConnectDone: func(network, addr string, err error) {
+			mu.Lock()
+			start := dialStarts[addr]
+			mu.Unlock()
+			emit(RequestEvent{Kind: RequestConnectDone, Duration: since(&start), Addr: addr, Err: err})
+		},
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			tlsStart = time.Now()
+			mu.Unlock()
+			emit(RequestEvent{Kind: RequestTLSStart})
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			event := RequestEvent{Kind: RequestTLSDone, Duration: since(&tlsStart), Err: err}
+			if err == nil {
+				event.TLSVersion = tls.VersionName(state.Version)
+				event.TLSResumed = state.DidResume
+			}
+			emit(event)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			event := RequestEvent{Kind: RequestConnAcquired, Reused: info.Reused, IdleTime: info.IdleTime}
+			if info.Conn != nil {
+				event.Addr = info.Conn.RemoteAddr().String()
+			}
+			emit(event)
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			mu.Lock()
+			wroteAt = time.Now()
+			mu.Unlock()
+			emit(RequestEvent{Kind: RequestWritten, Err: info.Err})
+		},
+		GotFirstResponseByte: func() {
+			emit(RequestEvent{Kind: RequestFirstByte, Duration: since(&wroteAt)})
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace)
+}
+
+// traceDNS reports a lookup made by a custom dialer to the request's
+// httptrace hooks, which only fire on their own for the standard resolver.
+func traceDNS(ctx context.Context, host string, lookup func() ([]net.IPAddr, error)) ([]net.IPAddr, error) {
+	trace := httptrace.ContextClientTrace(ctx)
+	if trace != nil && trace.DNSStart != nil {
+		trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+	}
+	addrs, err := lookup()
+	if trace != nil && trace.DNSDone != nil {
+		trace.DNSDone(httptrace.DNSDoneInfo{Addrs: addrs, Err: err})
+	}
+	return addrs, err
+}
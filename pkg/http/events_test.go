@@ -0,0 +1,97 @@
+package http
+
+import (
+	"context"
+	"net"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientEmitsRequestLifecycleEvents(t *testing.T) {
+	calls := 0
+	server := httptest.NewTLSServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, _ *stdhttp.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(stdhttp.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(stdhttp.StatusNoContent)
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+	_, port, _ := net.SplitHostPort(target.Host)
+
+	// example.com is in the test server's certificate; resolve it locally.
+	resolver := NewCachingResolver(DNSCacheConfig{
+		Lookup: func(context.Context, string) ([]net.IPAddr, time.Duration, error) {
+			return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, time.Minute, nil
+		},
+	})
+	var clientWide []RequestEvent
+	client := NewClient(
+		WithHTTPClient(server.Client()),
+		WithDNSCache(resolver),
+		WithRetry(2, time.Millisecond),
+		WithTokenProvider(NewStaticTokenProvider("service-token"), time.Minute),
+		WithPeerService("billing"),
+		WithRequestEvents(func(event RequestEvent) { clientWide = append(clientWide, event) }),
+	)
+
+	events := make(chan RequestEvent, 64)
+	ctx := ContextWithRequestEvents(context.Background(), RequestEventChannel(events))
+	resp, err := client.Get(ctx, "https://example.com:"+port+"/invoices?token=secret")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+	close(events)
+
+	var kinds []string
+	var received []RequestEvent
+	for event := range events {
+		received = append(received, event)
+		kinds = append(kinds, string(event.Kind))
+	}
+	want := []RequestEventKind{
+		RequestTokenRefreshed,
+		RequestAttemptStart, RequestResolveStart, RequestResolveDone, RequestConnectStart, RequestConnectDone,
+		RequestTLSStart, RequestTLSDone, RequestConnAcquired, RequestWritten, RequestFirstByte,
+		RequestRetryScheduled,
+		RequestAttemptStart, RequestTokenRefreshed, RequestConnAcquired, RequestWritten, RequestFirstByte,
+		RequestDone,
+	}
+	if len(received) != len(want) {
+		t.Fatalf("events = %s", strings.Join(kinds, ", "))
+	}
+	for i, event := range received {
+		if event.Kind != want[i] {
+			t.Fatalf("event %d = %s, want %s; events = %s", i, event.Kind, want[i], strings.Join(kinds, ", "))
+		}
+		if event.Peer != "billing" || event.URL != "https://example.com:"+port+"/invoices" {
+			t.Fatalf("event %d target = %q %q", i, event.Peer, event.URL)
+		}
+	}
+	if len(clientWide) != len(received) {
+		t.Fatalf("client-wide events = %d, want %d", len(clientWide), len(received))
+	}
+
+	if resolved := received[3]; resolved.Addr != "127.0.0.1" || resolved.Attempt != 1 {
+		t.Fatalf("resolve_done = %+v", resolved)
+	}
+	if handshake := received[7]; handshake.TLSVersion == "" || handshake.Err != nil {
+		t.Fatalf("tls_done = %+v", handshake)
+	}
+	if retry := received[11]; retry.Attempt != 2 || retry.Reason != "Unauthorized" || retry.Duration != time.Millisecond {
+		t.Fatalf("retry_scheduled = %+v", retry)
+	}
+	if reused := received[14]; !reused.Reused || reused.Attempt != 2 {
+		t.Fatalf("second conn_acquired = %+v", reused)
+	}
+	if done := received[17]; done.StatusCode != stdhttp.StatusNoContent || done.Err != nil || done.Elapsed <= 0 {
+		t.Fatalf("done = %+v", done)
+	}
+}
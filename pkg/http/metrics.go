@@ -0,0 +1,142 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metric attribute keys recorded on outgoing request metrics, alongside
+// peer.service and http.request.method.
+const (
+	AttrClientName  = attribute.Key("http.client.name")
+	AttrURLTemplate = attribute.Key("url.template")
+	AttrStatusClass = attribute.Key("http.response.status_class")
+)
+
+// clientDurationBuckets are the semantic-convention bucket boundaries for
+// http.client.request.duration, in seconds.
+var clientDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+
+type clientMetrics struct {
+	name     string
+	requests metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+// WithMetrics records http.client.requests and http.client.request.duration for
+// every call made through Do, labelled by client name, peer.service, method,
+// templated path, and status class ("2xx" … "5xx", or "error" when no response
+// arrived). Retries count as one call. Instruments come from the global meter
+// provider, which observability.New installs.
+//
+// Paths are templated with ContextWithURLTemplate when the caller sets one, and
+// otherwise by replacing ID-like segments (numbers, UUIDs, long hex or mixed
+// alphanumeric tokens) with "{id}", so raw URLs never become labels.
+func WithMetrics(clientName string) ClientOption {
+	return func(c *Client) {
+		meter := otel.Meter(tracerName)
+		requests, err := meter.Int64Counter("http.client.requests",
+			metric.WithDescription("Outbound HTTP requests"), metric.WithUnit("{request}"))
+		if err != nil {
+			otel.Handle(err)
+			return
+		}
+		duration, err := meter.Float64Histogram("http.client.request.duration",
+			metric.WithDescription("Duration of outbound HTTP requests, including retries"), metric.WithUnit("s"),
+			metric.WithExplicitBucketBoundaries(clientDurationBuckets...))
+		if err != nil {
+			otel.Handle(err)
+			return
+		}
+		c.metrics = &clientMetrics{name: strings.TrimSpace(clientName), requests: requests, duration: duration}
+	}
+}
+
+type urlTemplateKey struct{}
+
+// ContextWithURLTemplate sets the path template recorded for requests made with
+// ctx, e.g. "/invoices/{id}/lines".
+func ContextWithURLTemplate(ctx context.Context, template string) context.Context {
+	return context.WithValue(ctx, urlTemplateKey{}, template)
+}
+
+// record adds one call to the client metrics.
+func (m *clientMetrics) record(ctx context.Context, req *http.Request, peer string, start time.Time, resp *http.Response, err error) {
+	template, _ := ctx.Value(urlTemplateKey{}).(string)
+	if template == "" && req.URL != nil {
+		template = TemplatePath(req.URL.Path)
+	}
+
+	statusClass := "error"
+	if resp != nil && err == nil {
+		statusClass = strconv.Itoa(resp.StatusCode/100) + "xx"
+	}
+
+	attrs := metric.WithAttributes(
+		AttrClientName.String(m.name),
+		AttrPeerService.String(peer),
+		attrRequestMethod.String(req.Method),
+		AttrURLTemplate.String(template),
+		AttrStatusClass.String(statusClass),
+	)
+	m.requests.Add(ctx, 1, attrs)
+	m.duration.Record(ctx, time.Since(start).Seconds(), attrs)
+}
+
+var (
+	uuidSegment = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexSegment  = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+)
+
+// TemplatePath replaces ID-like path segments with "{id}":
+// "/users/42/orders/9f1c2e4a-…" becomes "/users/{id}/orders/{id}". Segments
+// that are numbers, UUIDs, hex strings of 16+ characters, or 20+ character
+// tokens mixing letters and digits (ULIDs, KSUIDs, object IDs) are replaced.
+func TemplatePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if isIDSegment(segment) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func isIDSegment(segment string) bool {
+	if segment == "" {
+		return false
+	}
+	if _, err := strconv.ParseUint(segment, 10, 64); err == nil {
+		return true
+	}
+	if uuidSegment.MatchString(segment) || hexSegment.MatchString(segment) {
+		return true
+	}
+	if len(segment) < 20 {
+		return false
+	}
+	hasDigit, hasLetter := false, false
+	for _, r := range segment {
+		switch {
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			hasLetter = true
+		case r == '-' || r == '_':
+		default:
+			return false
+		}
+	}
+	return hasDigit && hasLetter
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestClientRecordsTemplatedMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	previous := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	t.Cleanup(func() { otel.SetMeterProvider(previous) })
+
+	client := NewClient(
+		WithRetry(1, time.Millisecond),
+		WithPeerService("billing"),
+		WithMetrics("billing-client"),
+		WithHTTPClient(&stdhttp.Client{
+			Transport: roundTripFunc(func(req *stdhttp.Request) (*stdhttp.Response, error) {
+				if req.URL.Path == "/down" {
+					return nil, errors.New("connection refused")
+				}
+				if req.URL.Path == "/invoices/7/lines" {
+					return jsonResponse(stdhttp.StatusNotFound, `{}`), nil
+				}
+				return jsonResponse(stdhttp.StatusOK, `{}`), nil
+			}),
+		}),
+	)
+
+	ctx := context.Background()
+	for _, url := range []string{
+		"https://billing.internal/invoices/42?sig=secret",
+		"https://billing.internal/invoices/9f1c2e4a-1b2c-4d5e-8f90-123456789abc",
+		"https://billing.internal/invoices/7/lines",
+		"https://billing.internal/down",
+	} {
+		if resp, err := client.Get(ctx, url); err == nil {
+			resp.Body.Close()
+		}
+	}
+	resp, err := client.Get(ContextWithURLTemplate(ctx, "/accounts/{account}"), "https://billing.internal/accounts/acme")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &data); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	counts := map[string]int64{}
+	histograms := 0
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch agg := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, point := range agg.DataPoints {
+					name, _ := point.Attributes.Value(AttrClientName)
+					peer, _ := point.Attributes.Value(AttrPeerService)
+					if name.AsString() != "billing-client" || peer.AsString() != "billing" {
+						t.Fatalf("attributes = %v", point.Attributes.ToSlice())
+					}
+					template, _ := point.Attributes.Value(AttrURLTemplate)
+					class, _ := point.Attributes.Value(AttrStatusClass)
+					counts[template.AsString()+" "+class.AsString()] += point.Value
+				}
+			case metricdata.Histogram[float64]:
+				histograms += len(agg.DataPoints)
+			}
+		}
+	}
+
+	want := map[string]int64{
+		"/invoices/{id} 2xx":       2,
+		"/invoices/{id}/lines 4xx": 1,
+		"/down error":              1,
+		"/accounts/{account} 2xx":  1,
+	}
+	if len(counts) != len(want) {
+		t.Fatalf("counts = %v, want %v", counts, want)
+	}
+	for key, value := range want {
+		if counts[key] != value {
+			t.Fatalf("counts = %v, want %v", counts, want)
+		}
+	}
+	if histograms != len(want) {
+		t.Fatalf("duration series = %d, want %d", histograms, len(want))
+	}
+}
+
+func TestTemplatePath(t *testing.T) {
+	tests := map[string]string{
+		"":             "/",
+		"/v1/users/42": "/v1/users/{id}",
+		"/objects/01HZX3J5Q9R7Y6T2W8V4N0M1KC/tags": "/objects/{id}/tags",
+		"/blobs/5f2b1c9e8d7a6b4c":                  "/blobs/{id}",
+		"/reports/monthly-summary":                 "/reports/monthly-summary",
+		"/api/v2/health":                           "/api/v2/health",
+	}
+	for path, want := range tests {
+		if got := TemplatePath(path); got != want {
+			t.Fatalf("TemplatePath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// bearerTokenContextKey stores the inbound caller's bearer token in a context.Context.
+type bearerTokenContextKey struct{}
+
+// ErrNoInboundBearerToken is returned when bearer pass-through is required but the
+// request context carries no inbound token.
+var ErrNoInboundBearerToken = errors.New("no inbound bearer token in context")
+
+// ContextWithBearerToken stores the inbound caller's bearer token so outgoing
+// requests made with WithBearerPassThrough can forward it. The auth middleware
+// calls this after verifying the token.
+func ContextWithBearerToken(ctx context.Context, token string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, bearerTokenContextKey{}, token)
+}
+
+// BearerTokenFromContext returns the inbound caller's bearer token, if any.
+func BearerTokenFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	token, ok := ctx.Value(bearerTokenContextKey{}).(string)
+	return token, ok && token != ""
+}
+
+// WithBearerPassThrough makes the client forward the inbound caller's bearer token
+// (see ContextWithBearerToken) instead of the service token, so proxy-style
+// endpoints preserve end-user identity downstream.
+//
+// When the context carries no inbound token, the client falls back to the
+// configured service token if fallbackToServiceToken is true; otherwise Do fails
+// with ErrNoInboundBearerToken rather than silently elevating to service identity.
+//
+// Forwarded tokens are never refreshed, so a 401 response is returned to the
+// caller as-is instead of being retried.
+func WithBearerPassThrough(fallbackToServiceToken bool) ClientOption {
+	return func(c *Client) {
+		c.bearerPassThrough = true
+		c.passThroughFallback = fallbackToServiceToken
+	}
+}
+
+// passThroughToken returns the inbound bearer token to forward for ctx, if the
+// client is configured for pass-through and one is present.
+func (c *Client) passThroughToken(ctx context.Context) (string, bool) {
+	if !c.bearerPassThrough {
+		return "", false
+	}
+	return BearerTokenFromContext(ctx)
+}
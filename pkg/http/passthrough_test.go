@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"testing"
+	"time"
+)
+
+func TestBearerPassThroughForwardsInboundToken(t *testing.T) {
+	t.Parallel()
+
+	var seen []string
+	client := NewClient(
+		WithRetry(3, time.Millisecond),
+		WithTokenProvider(NewStaticTokenProvider("service-token"), time.Minute),
+		WithBearerPassThrough(false),
+		WithHTTPClient(&stdhttp.Client{
+			Transport: roundTripFunc(func(req *stdhttp.Request) (*stdhttp.Response, error) {
+				seen = append(seen, req.Header.Get("Authorization"))
+				return jsonResponse(stdhttp.StatusOK, `{}`), nil
+			}),
+		}),
+	)
+	ctx := ContextWithBearerToken(context.Background(), "user-token")
+
+	resp, err := client.Get(ctx, "http://downstream.local/orders")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if len(seen) != 1 || seen[0] != "Bearer user-token" {
+		t.Fatalf("Authorization headers = %v, want [Bearer user-token]", seen)
+	}
+}
+
+func TestBearerPassThroughDoesNotRetryForwarded401(t *testing.T) {
+	t.Parallel()
+
+	var seen []string
+	client := NewClient(
+		WithRetry(3, time.Millisecond),
+		WithTokenProvider(NewStaticTokenProvider("service-token"), time.Minute),
+		WithBearerPassThrough(false),
+		WithHTTPClient(&stdhttp.Client{
+			Transport: roundTripFunc(func(req *stdhttp.Request) (*stdhttp.Response, error) {
+				seen = append(seen, req.Header.Get("Authorization"))
+				return jsonResponse(stdhttp.StatusUnauthorized, `{}`), nil
+			}),
+		}),
+	)
+	ctx := ContextWithBearerToken(context.Background(), "user-token")
+
+	resp, err := client.Get(ctx, "http://downstream.local/orders")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != stdhttp.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", resp.StatusCode)
+	}
+	if len(seen) != 1 {
+		t.Fatalf("attempts = %d, want 1", len(seen))
+	}
+}
+
+func TestBearerPassThroughWithoutInboundToken(t *testing.T) {
+	t.Parallel()
+
+	var strictSeen []string
+	strict := NewClient(
+		WithRetry(3, time.Millisecond),
+		WithTokenProvider(NewStaticTokenProvider("service-token"), time.Minute),
+		WithBearerPassThrough(false),
+		WithHTTPClient(&stdhttp.Client{
+			Transport: roundTripFunc(func(req *stdhttp.Request) (*stdhttp.Response, error) {
+				strictSeen = append(strictSeen, req.Header.Get("Authorization"))
+				return jsonResponse(stdhttp.StatusOK, `{}`), nil
+			}),
+		}),
+	)
+	if _, err := strict.Get(context.Background(), "http://downstream.local/orders"); !errors.Is(err, ErrNoInboundBearerToken) {
+		t.Fatalf("Get() error = %v, want ErrNoInboundBearerToken", err)
+	}
+	if len(strictSeen) != 0 {
+		t.Fatalf("strict client sent %d requests, want 0", len(strictSeen))
+	}
+
+	var fallbackSeen []string
+	fallback := NewClient(
+		WithRetry(3, time.Millisecond),
+		WithTokenProvider(NewStaticTokenProvider("service-token"), time.Minute),
+		WithBearerPassThrough(true),
+		WithHTTPClient(&stdhttp.Client{
+			Transport: roundTripFunc(func(req *stdhttp.Request) (*stdhttp.Response, error) {
+				fallbackSeen = append(fallbackSeen, req.Header.Get("Authorization"))
+				return jsonResponse(stdhttp.StatusOK, `{}`), nil
+			}),
+		}),
+	)
+	resp, err := fallback.Get(context.Background(), "http://downstream.local/orders")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+	if len(fallbackSeen) != 1 || fallbackSeen[0] != "Bearer service-token" {
+		t.Fatalf("Authorization headers = %v, want [Bearer service-token]", fallbackSeen)
+	}
+}
@@ -34,6 +34,11 @@ func NewOAuth2ClientCredentialsProvider(tokenURL, clientID, clientSecret, scope
 	}
 }
 
+// RotateCredential implements CredentialRotator by replacing the client secret.
+func (p *OAuth2ClientCredentialsProvider) RotateCredential(secret string) {
+	p.ClientSecret = secret
+}
+
 // FetchToken retrieves a token using OAuth2 client credentials flow.
 func (p *OAuth2ClientCredentialsProvider) FetchToken(ctx context.Context) (string, time.Time, error) {
 	data := url.Values{}
@@ -106,6 +111,11 @@ func NewStaticTokenProvider(token string) *StaticTokenProvider {
 	return &StaticTokenProvider{Token: token}
 }
 
+// RotateCredential implements CredentialRotator by replacing the token.
+func (p *StaticTokenProvider) RotateCredential(secret string) {
+	p.Token = secret
+}
+
 // FetchToken returns the static token with a far-future expiration.
 func (p *StaticTokenProvider) FetchToken(ctx context.Context) (string, time.Time, error) {
 	return p.Token, time.Now().Add(24 * 365 * time.Hour), nil // 1 year from now
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"context"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/config"
+)
+
+// CredentialRotator is implemented by token providers whose credential can be
+// replaced at runtime: ServiceTokenProvider (API key),
+// OAuth2ClientCredentialsProvider (client secret) and StaticTokenProvider
+// (token). TokenCache.Rotate calls it while no token fetch is running.
+type CredentialRotator interface {
+	RotateCredential(secret string)
+}
+
+// Rotate hands secret to the provider when it implements CredentialRotator
+// and drops the cached token, so the next request fetches a token with the
+// new credential.
+func (tc *TokenCache) Rotate(secret string) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if rotator, ok := tc.provider.(CredentialRotator); ok {
+		rotator.RotateCredential(secret)
+	}
+	tc.token = ""
+	tc.expiresAt = time.Time{}
+}
+
+// SubscribeRotation rotates the client's token provider credential whenever
+// key is rotated on bus, e.g. controlplane.KeyServiceAPIKey for clients built
+// by NewClientWithServiceToken. Clients without a token provider ignore
+// rotations. It returns a function that removes the subscription.
+func (c *Client) SubscribeRotation(bus *config.RotationBus, key string) (unsubscribe func()) {
+	return bus.Subscribe(key, func(_ context.Context, rotation config.SecretRotation) error {
+		if c.tokenCache != nil {
+			c.tokenCache.Rotate(rotation.Value)
+		}
+		return nil
+	})
+}
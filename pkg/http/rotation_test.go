@@ -0,0 +1,52 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	stdhttp "net/http"
+	"testing"
+
+	"github.com/milan604/core-lab/pkg/config"
+	"github.com/milan604/core-lab/pkg/controlplane"
+)
+
+func TestClientSubscribeRotationRefetchesTokenWithNewAPIKey(t *testing.T) {
+	t.Parallel()
+
+	var apiKeys []string
+	tokenClient := NewClient(WithHTTPClient(&stdhttp.Client{
+		Transport: roundTripFunc(func(req *stdhttp.Request) (*stdhttp.Response, error) {
+			var body map[string]any
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Fatalf("decode token request: %v", err)
+			}
+			apiKeys = append(apiKeys, body["api_key"].(string))
+			return jsonResponse(stdhttp.StatusOK, `{"access_token":"token-`+body["api_key"].(string)+`","expires_in":3600}`), nil
+		}),
+	}))
+	provider := NewServiceTokenProvider(ServiceTokenProviderConfig{
+		ServiceURL: "http://sentinel.test/sentinel",
+		ServiceID:  "sites",
+		APIKey:     "key-1",
+		HTTPClient: tokenClient,
+	})
+	client := NewClient(WithTokenProvider(provider, 0))
+
+	bus := config.NewRotationBus()
+	unsubscribe := client.SubscribeRotation(bus, controlplane.KeyServiceAPIKey)
+	defer unsubscribe()
+
+	ctx := context.Background()
+	if token, err := client.tokenCache.GetToken(ctx); err != nil || token != "token-key-1" {
+		t.Fatalf("GetToken() = %q, %v", token, err)
+	}
+	if err := bus.Publish(ctx, config.SecretRotation{Key: "platformserviceapikey", Value: "key-2"}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if token, err := client.tokenCache.GetToken(ctx); err != nil || token != "token-key-2" {
+		t.Fatalf("GetToken() after rotation = %q, %v", token, err)
+	}
+	if len(apiKeys) != 2 || apiKeys[0] != "key-1" || apiKeys[1] != "key-2" {
+		t.Fatalf("token requests used api keys %v", apiKeys)
+	}
+}
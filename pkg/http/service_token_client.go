@@ -57,6 +57,11 @@ func NewServiceTokenProvider(cfg ServiceTokenProviderConfig) *ServiceTokenProvid
 	}
 }
 
+// RotateCredential implements CredentialRotator by replacing the API key.
+func (p *ServiceTokenProvider) RotateCredential(secret string) {
+	p.APIKey = secret
+}
+
 // FetchToken retrieves a token from the service token API.
 func (p *ServiceTokenProvider) FetchToken(ctx context.Context) (string, time.Time, error) {
 	if p.ServiceURL == "" {
@@ -138,13 +143,15 @@ func (p *ServiceTokenProvider) parseExpirationTime(expiresAtStr string, expiresI
 // - PlatformServiceID
 // - PlatformServiceAPIKey
 // - PlatformMTLSCertFile / PlatformMTLSKeyFile / PlatformMTLSCAFile
-func NewClientWithServiceToken(log logger.LogManager, cfg *config.Config) (*Client, error) {
-	return NewClientWithServiceTokenForAudience(log, cfg, nil)
+//
+// opts are applied last, e.g. WithRetry(1, 0) for callers that retry themselves.
+func NewClientWithServiceToken(log logger.LogManager, cfg *config.Config, opts ...ClientOption) (*Client, error) {
+	return NewClientWithServiceTokenForAudience(log, cfg, nil, opts...)
 }
 
 // NewClientWithServiceTokenForAudience creates a token-authenticated HTTP client
-// and optionally overrides the requested token audience.
-func NewClientWithServiceTokenForAudience(log logger.LogManager, cfg *config.Config, audience []string) (*Client, error) {
+// and optionally overrides the requested token audience. opts are applied last.
+func NewClientWithServiceTokenForAudience(log logger.LogManager, cfg *config.Config, audience []string, opts ...ClientOption) (*Client, error) {
 	if cfg == nil {
 		return nil, fmt.Errorf("config not configured")
 	}
@@ -161,10 +168,11 @@ func NewClientWithServiceTokenForAudience(log logger.LogManager, cfg *config.Con
 	if err != nil {
 		return nil, err
 	}
+	mtlsOpts = append(mtlsOpts, dnsCacheOptions(cfg)...)
 
 	// Create base HTTP client for calling service token API
 	// This client doesn't need token provider - it's used to get the token
-	baseClient := NewClient(mtlsOpts...)
+	baseClient := NewClient(append(mtlsOpts, WithPeerService(PeerServiceControlPlane))...)
 
 	// Create service token provider
 	tokenProvider := NewServiceTokenProvider(ServiceTokenProviderConfig{
@@ -178,6 +186,7 @@ func NewClientWithServiceTokenForAudience(log logger.LogManager, cfg *config.Con
 
 	clientOpts := append([]ClientOption{}, mtlsOpts...)
 	clientOpts = append(clientOpts, WithTokenProvider(tokenProvider, 1*time.Minute))
+	clientOpts = append(clientOpts, opts...)
 
 	// Create HTTP client with token provider configured and the same trust
 	// configuration used for token retrieval.
@@ -207,8 +216,9 @@ func NewInternalControlPlaneClientForAudience(log logger.LogManager, cfg *config
 	if err != nil {
 		return nil, err
 	}
+	mtlsOpts = append(mtlsOpts, dnsCacheOptions(cfg)...)
 
-	baseClient := NewClient(mtlsOpts...)
+	baseClient := NewClient(append(mtlsOpts, WithPeerService(PeerServiceControlPlane))...)
 	tokenProvider := NewServiceTokenProvider(ServiceTokenProviderConfig{
 		ServiceURL: settings.BaseURL,
 		ServiceID:  settings.ServiceID,
@@ -219,10 +229,21 @@ func NewInternalControlPlaneClientForAudience(log logger.LogManager, cfg *config
 	})
 
 	clientOpts := append([]ClientOption{}, mtlsOpts...)
-	clientOpts = append(clientOpts, WithTokenProvider(tokenProvider, 1*time.Minute))
+	clientOpts = append(clientOpts, WithTokenProvider(tokenProvider, 1*time.Minute), WithPeerService(PeerServiceControlPlane))
 	return NewClient(clientOpts...), nil
 }
 
+// KeyDNSCacheEnabled turns on SharedCachingResolver for the control-plane
+// clients created from config.
+const KeyDNSCacheEnabled = "PlatformHTTPDNSCacheEnabled"
+
+func dnsCacheOptions(cfg *config.Config) []ClientOption {
+	if !cfg.GetBoolD(KeyDNSCacheEnabled, false) {
+		return nil
+	}
+	return []ClientOption{WithDNSCache(SharedCachingResolver())}
+}
+
 func controlPlaneMTLSOptions(log logger.LogManager, mtls controlplane.MTLSConfig) ([]ClientOption, error) {
 	if missing := mtls.MissingRequiredFields(); len(missing) > 0 {
 		return nil, fmt.Errorf("control-plane mTLS configuration missing required keys: %s", strings.Join(missing, ", "))
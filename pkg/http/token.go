@@ -39,13 +39,19 @@ func NewTokenCache(provider TokenProvider, refreshBuffer time.Duration) *TokenCa
 // GetToken retrieves a valid token, fetching a new one if needed.
 // It is thread-safe and handles token expiration automatically.
 func (tc *TokenCache) GetToken(ctx context.Context) (string, error) {
+	token, _, err := tc.getToken(ctx)
+	return token, err
+}
+
+// getToken is GetToken, also reporting whether the provider was called.
+func (tc *TokenCache) getToken(ctx context.Context) (string, bool, error) {
 	tc.mu.RLock()
 	now := time.Now()
 	// Check if we have a valid token that won't expire soon
 	if tc.token != "" && now.Before(tc.expiresAt.Add(-tc.refreshBuffer)) {
 		token := tc.token
 		tc.mu.RUnlock()
-		return token, nil
+		return token, false, nil
 	}
 	tc.mu.RUnlock()
 
@@ -54,25 +60,25 @@ func (tc *TokenCache) GetToken(ctx context.Context) (string, error) {
 }
 
 // refreshToken fetches a new token and updates the cache.
-func (tc *TokenCache) refreshToken(ctx context.Context) (string, error) {
+func (tc *TokenCache) refreshToken(ctx context.Context) (string, bool, error) {
 	tc.mu.Lock()
 	defer tc.mu.Unlock()
 
 	// Double-check: another goroutine might have refreshed it
 	now := time.Now()
 	if tc.token != "" && now.Before(tc.expiresAt.Add(-tc.refreshBuffer)) {
-		return tc.token, nil
+		return tc.token, false, nil
 	}
 
 	// Fetch new token
 	token, expiresAt, err := tc.provider.FetchToken(ctx)
 	if err != nil {
-		return "", err
+		return "", true, err
 	}
 
 	tc.token = token
 	tc.expiresAt = expiresAt
-	return token, nil
+	return token, true, nil
 }
 
 // Invalidate clears the cached token, forcing a refresh on next GetToken call.
@@ -0,0 +1,141 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/milan604/core-lab/pkg/http"
+
+// PeerServiceControlPlane is the peer.service name recorded for calls made by
+// the control-plane clients created in this package.
+const PeerServiceControlPlane = "control-plane"
+
+// Span attribute keys recorded on outgoing request spans.
+const (
+	AttrPeerService    = attribute.Key("peer.service")
+	attrRequestMethod  = attribute.Key("http.request.method")
+	attrServerAddress  = attribute.Key("server.address")
+	attrURLFull        = attribute.Key("url.full")
+	attrResponseStatus = attribute.Key("http.response.status_code")
+	attrResendCount    = attribute.Key("http.request.resend_count")
+	attrRetryDelay     = attribute.Key("http.retry.delay")
+	attrRetryReason    = attribute.Key("http.retry.reason")
+)
+
+// Span events recorded on the client span while a request is retried.
+const (
+	EventRetry       = "http.retry"
+	EventReauth      = "http.reauth"
+	EventCircuitOpen = "http.circuit_open"
+)
+
+// WithPeerService names the service this client talks to. The name is recorded
+// as the peer.service attribute on every outgoing request span, which trace
+// backends use to draw service maps and observability.DependencyInventory uses
+// to list runtime dependencies. Without it the target host is used.
+func WithPeerService(name string) ClientOption {
+	return func(c *Client) {
+		c.peerService = strings.TrimSpace(name)
+	}
+}
+
+// WithTracing toggles the client span around each call. Tracing is on by
+// default and is a no-op until a tracer provider is installed. When off, the
+// caller's trace context is still propagated in the traceparent header.
+func WithTracing(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.tracingOff = !enabled
+	}
+}
+
+// startSpan starts a client span for req and injects the trace context into
+// its headers. With no tracer provider installed the span is a no-op.
+func (c *Client) startSpan(ctx context.Context, req *http.Request) (context.Context, trace.Span) {
+	if c.tracingOff {
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	peer := c.peerName(req)
+
+	attrs := []attribute.KeyValue{attrRequestMethod.String(req.Method)}
+	if peer != "" {
+		attrs = append(attrs, AttrPeerService.String(peer))
+	}
+	if req.URL != nil {
+		attrs = append(attrs, attrServerAddress.String(req.URL.Hostname()), attrURLFull.String(redactedURL(req)))
+	}
+
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return ctx, span
+}
+
+// peerName returns the WithPeerService name, or the target host without one.
+func (c *Client) peerName(req *http.Request) string {
+	if c.peerService == "" && req.URL != nil {
+		return req.URL.Hostname()
+	}
+	return c.peerService
+}
+
+// addSpanEvent adds an event to the client span started by Do.
+func (c *Client) addSpanEvent(ctx context.Context, name string, attrs ...attribute.KeyValue) {
+	if c.tracingOff {
+		return
+	}
+	trace.SpanFromContext(ctx).AddEvent(name, trace.WithAttributes(attrs...))
+}
+
+// recordRetry adds an EventRetry event for the attempt about to be sent.
+func (c *Client) recordRetry(ctx context.Context, attempt int, delay time.Duration, reason string) {
+	c.addSpanEvent(ctx, EventRetry,
+		attrResendCount.Int(attempt),
+		attrRetryDelay.Float64(delay.Seconds()),
+		attrRetryReason.String(reason),
+	)
+}
+
+// recordResends sets http.request.resend_count when the request was sent
+// more than once.
+func (c *Client) recordResends(ctx context.Context, attempt int) {
+	if attempt > 0 && !c.tracingOff {
+		trace.SpanFromContext(ctx).SetAttributes(attrResendCount.Int(attempt))
+	}
+}
+
+// endSpan records the outcome of a request on span and ends it.
+func endSpan(span trace.Span, resp *http.Response, err error) {
+	if resp != nil {
+		span.SetAttributes(attrResponseStatus.Int(resp.StatusCode))
+		if resp.StatusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// redactedURL drops the query string and user info, which commonly carry
+// credentials or presigned signatures.
+func redactedURL(req *http.Request) string {
+	u := *req.URL
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
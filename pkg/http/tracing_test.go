@@ -0,0 +1,172 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func installSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestClientRecordsPeerServiceSpan(t *testing.T) {
+	recorder := installSpanRecorder(t)
+
+	var traceparent string
+	client := NewClient(
+		WithRetry(1, time.Millisecond),
+		WithPeerService("billing"),
+		WithHTTPClient(&stdhttp.Client{
+			Transport: roundTripFunc(func(req *stdhttp.Request) (*stdhttp.Response, error) {
+				traceparent = req.Header.Get("traceparent")
+				return jsonResponse(stdhttp.StatusOK, `{}`), nil
+			}),
+		}),
+	)
+
+	resp, err := client.Get(context.Background(), "https://billing.internal/invoices?sig=secret")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("ended spans = %d, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.SpanKind() != trace.SpanKindClient {
+		t.Fatalf("span kind = %v, want client", span.SpanKind())
+	}
+	attrs := map[string]string{}
+	for _, attr := range span.Attributes() {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	if attrs["peer.service"] != "billing" {
+		t.Fatalf("peer.service = %q, want billing", attrs["peer.service"])
+	}
+	if attrs["url.full"] != "https://billing.internal/invoices" {
+		t.Fatalf("url.full = %q, want query stripped", attrs["url.full"])
+	}
+	if attrs["http.response.status_code"] != "200" {
+		t.Fatalf("http.response.status_code = %q", attrs["http.response.status_code"])
+	}
+	if traceparent == "" {
+		t.Fatal("expected traceparent header to be injected")
+	}
+}
+
+func TestClientRecordsRetryAndReauthEvents(t *testing.T) {
+	recorder := installSpanRecorder(t)
+
+	calls := 0
+	var traceparents []string
+	client := NewClient(
+		WithRetry(3, time.Millisecond),
+		WithTokenProvider(NewStaticTokenProvider("service-token"), time.Minute),
+		WithHTTPClient(&stdhttp.Client{
+			Transport: roundTripFunc(func(req *stdhttp.Request) (*stdhttp.Response, error) {
+				calls++
+				traceparents = append(traceparents, req.Header.Get("traceparent"))
+				switch calls {
+				case 1:
+					return nil, errors.New("connection reset")
+				case 2:
+					return jsonResponse(stdhttp.StatusUnauthorized, `{}`), nil
+				default:
+					return jsonResponse(stdhttp.StatusOK, `{}`), nil
+				}
+			}),
+		}),
+	)
+
+	resp, err := client.Get(context.Background(), "https://billing.internal/invoices")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("ended spans = %d, want 1", len(spans))
+	}
+	var events []string
+	for _, event := range spans[0].Events() {
+		attrs := map[string]string{}
+		for _, attr := range event.Attributes {
+			attrs[string(attr.Key)] = attr.Value.Emit()
+		}
+		events = append(events, event.Name+" "+attrs["http.request.resend_count"]+" "+attrs["http.retry.reason"])
+	}
+	want := []string{
+		"http.retry 1 Get \"https://billing.internal/invoices\": connection reset",
+		"http.reauth 1 ",
+		"http.retry 2 Unauthorized",
+	}
+	if len(events) != len(want) {
+		t.Fatalf("events = %q, want %q", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("event %d = %q, want %q", i, events[i], want[i])
+		}
+	}
+	for _, attr := range spans[0].Attributes() {
+		if attr.Key == "http.request.resend_count" && attr.Value.AsInt64() != 2 {
+			t.Fatalf("resend_count = %d, want 2", attr.Value.AsInt64())
+		}
+	}
+	for i, traceparent := range traceparents {
+		if traceparent == "" || traceparent != traceparents[0] {
+			t.Fatalf("attempt %d traceparent = %q, want %q", i, traceparent, traceparents[0])
+		}
+	}
+}
+
+func TestClientWithTracingDisabledPropagatesCallerContext(t *testing.T) {
+	recorder := installSpanRecorder(t)
+
+	var traceparent string
+	client := NewClient(
+		WithTracing(false),
+		WithHTTPClient(&stdhttp.Client{
+			Transport: roundTripFunc(func(req *stdhttp.Request) (*stdhttp.Response, error) {
+				traceparent = req.Header.Get("traceparent")
+				return jsonResponse(stdhttp.StatusOK, `{}`), nil
+			}),
+		}),
+	)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "handler")
+	resp, err := client.Get(ctx, "https://billing.internal/invoices")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	if spans := recorder.Ended(); len(spans) != 1 || spans[0].Name() != "handler" {
+		t.Fatalf("ended spans = %v, want only the caller's span", spans)
+	}
+	if want := parent.SpanContext().SpanID().String(); len(traceparent) < 52 || traceparent[36:52] != want {
+		t.Fatalf("traceparent = %q, want parent span %s", traceparent, want)
+	}
+}
@@ -0,0 +1,410 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// FormatFunc renders a placeholder such as {{money total "EUR"}} in locale.
+// args are the placeholder's arguments: data values for names, strings for
+// quoted literals. An error leaves the placeholder as written.
+type FormatFunc func(locale string, args ...any) (string, error)
+
+// builtinFormatFuncs are available in every message:
+//
+//	{{number n}}               1,234.5 / 1.234,5
+//	{{money amount "EUR"}}     €1,234.50 / 1.234,50 €
+//	{{date when "long"}}       March 5, 2025 / 5. März 2025 (short, medium, long, full)
+//	{{relative when}}          3 days ago / vor 3 Tagen
+var builtinFormatFuncs = map[string]FormatFunc{
+	"number": func(locale string, args ...any) (string, error) {
+		if len(args) != 1 {
+			return "", errors.New("number takes a value")
+		}
+		if _, ok := toFloat64(args[0]); !ok {
+			return "", fmt.Errorf("number: %T is not numeric", args[0])
+		}
+		return FormatNumber(locale, args[0]), nil
+	},
+	"money": func(locale string, args ...any) (string, error) {
+		if len(args) != 2 {
+			return "", errors.New("money takes an amount and a currency code")
+		}
+		if _, ok := toFloat64(args[0]); !ok {
+			return "", fmt.Errorf("money: %T is not numeric", args[0])
+		}
+		code, _ := args[1].(string)
+		if _, err := currency.ParseISO(code); err != nil {
+			return "", fmt.Errorf("money: %w", err)
+		}
+		return FormatCurrency(locale, args[0], code), nil
+	},
+	"date": func(locale string, args ...any) (string, error) {
+		if len(args) < 1 || len(args) > 2 {
+			return "", errors.New("date takes a time and an optional style")
+		}
+		t, err := toTime(args[0])
+		if err != nil {
+			return "", fmt.Errorf("date: %w", err)
+		}
+		style := DateMedium
+		if len(args) == 2 {
+			name, _ := args[1].(string)
+			if style, err = ParseDateStyle(name); err != nil {
+				return "", fmt.Errorf("date: %w", err)
+			}
+		}
+		return FormatDate(locale, t, style), nil
+	},
+	"relative": func(locale string, args ...any) (string, error) {
+		if len(args) != 1 {
+			return "", errors.New("relative takes a time")
+		}
+		t, err := toTime(args[0])
+		if err != nil {
+			return "", fmt.Errorf("relative: %w", err)
+		}
+		return FormatRelativeTime(locale, t, time.Now()), nil
+	},
+}
+
+// WithFormatFunc makes fn available to messages as {{name arg...}}, next to
+// the built-in number, money, date and relative functions; a built-in of the
+// same name is replaced.
+func WithFormatFunc(name string, fn FormatFunc) Option {
+	return func(t *Translator) error {
+		if t.formatFuncs == nil {
+			t.formatFuncs = make(map[string]FormatFunc, len(builtinFormatFuncs)+1)
+			for k, v := range builtinFormatFuncs {
+				t.formatFuncs[k] = v
+			}
+		}
+		t.formatFuncs[name] = fn
+		return nil
+	}
+}
+
+// DateStyle selects how much of a date FormatDate spells out.
+type DateStyle int
+
+const (
+	DateShort  DateStyle = iota // 3/5/25
+	DateMedium                  // Mar 5, 2025
+	DateLong                    // March 5, 2025
+	DateFull                    // Wednesday, March 5, 2025
+)
+
+var dateStyleNames = map[string]DateStyle{
+	"short":  DateShort,
+	"medium": DateMedium,
+	"long":   DateLong,
+	"full":   DateFull,
+}
+
+// ParseDateStyle returns the DateStyle named short, medium, long or full.
+func ParseDateStyle(name string) (DateStyle, error) {
+	if style, ok := dateStyleNames[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return style, nil
+	}
+	return DateMedium, fmt.Errorf("unknown date style %q", name)
+}
+
+// FormatNumber formats v, any integer or float type or a decimal string,
+// with the digits, grouping and decimal separator of locale: 1,234.5 in en,
+// 1.234,5 in de, 12,34,567 in hi. Up to three fraction digits are shown
+// unless opts say otherwise, e.g. number.Scale(2). Non-numeric values are
+// printed as with fmt.Sprint.
+func FormatNumber(locale string, v any, opts ...number.Option) string {
+	f, ok := toFloat64(v)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	return printerFor(locale).Sprint(number.Decimal(f, opts...))
+}
+
+// FormatCurrency formats amount in the ISO 4217 currency code with the
+// symbol, fraction digits and symbol placement of locale. 1234.5 EUR is
+// €1,234.50 in en and 1.234,50 € in de; 1234.5 JPY is ￥1,234 in ja, with
+// the fullwidth yen sign and the amount rounded half to even to the
+// currency's digits. An unknown code is printed after the number.
+func FormatCurrency(locale string, amount any, code string) string {
+	f, ok := toFloat64(amount)
+	if !ok {
+		return fmt.Sprint(amount) + " " + code
+	}
+	p := printerFor(locale)
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		return p.Sprint(number.Decimal(f)) + " " + code
+	}
+	scale, _ := currency.Standard.Rounding(unit)
+	signed := p.Sprint(number.Decimal(f, number.Scale(scale)))
+	abs := p.Sprint(number.Decimal(math.Abs(f), number.Scale(scale)))
+	sign := strings.TrimSuffix(signed, abs)
+	symbol := p.Sprint(currency.Symbol(unit))
+
+	pattern := currencyPatternFor(locale)
+	if pattern == currencyPrefix {
+		// CLDR currency spacing: a symbol ending in a letter, such as
+		// "EUR", is kept apart from the digits
+		if r, _ := utf8.DecodeLastRuneInString(symbol); unicode.IsLetter(r) {
+			pattern = currencyPrefixSpace
+		}
+	}
+	switch pattern {
+	case currencySuffix:
+		return signed + "\u00a0" + symbol
+	case currencyPrefixSpace:
+		return sign + symbol + "\u00a0" + abs
+	default:
+		return sign + symbol + abs
+	}
+}
+
+// FormatDate formats t, in its own location, in the date pattern of locale
+// for style: 3/5/25, Mar 5, 2025, March 5, 2025 or Wednesday, March 5, 2025
+// in en; 05.03.25, 05.03.2025, 5. März 2025 or Mittwoch, 5. März 2025 in de.
+// Locales without built-in patterns use English.
+func FormatDate(locale string, t time.Time, style DateStyle) string {
+	symbols := dateSymbolsFor(locale)
+	if style < DateShort || style > DateFull {
+		style = DateMedium
+	}
+	return formatDatePattern(symbols.patterns[style], t, symbols)
+}
+
+// formatDatePattern expands the CLDR date fields y, yy, M, MM, MMM, MMMM, d,
+// dd and EEEE of pattern; text in single quotes is copied as is.
+func formatDatePattern(pattern string, t time.Time, symbols *dateSymbols) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		if c == '\'' {
+			end := strings.IndexByte(pattern[i+1:], '\'')
+			if end < 0 {
+				b.WriteString(pattern[i+1:])
+				break
+			}
+			b.WriteString(pattern[i+1 : i+1+end])
+			i += end + 2
+			continue
+		}
+		if c != 'y' && c != 'M' && c != 'd' && c != 'E' {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		n := 1
+		for i+n < len(pattern) && pattern[i+n] == c {
+			n++
+		}
+		i += n
+		switch {
+		case c == 'y' && n == 2:
+			fmt.Fprintf(&b, "%02d", t.Year()%100)
+		case c == 'y':
+			b.WriteString(strconv.Itoa(t.Year()))
+		case c == 'M' && n >= 4:
+			b.WriteString(symbols.months[t.Month()-1])
+		case c == 'M' && n == 3:
+			b.WriteString(symbols.shortMonths[t.Month()-1])
+		case c == 'M' && n == 2:
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case c == 'M':
+			b.WriteString(strconv.Itoa(int(t.Month())))
+		case c == 'd' && n == 2:
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case c == 'd':
+			b.WriteString(strconv.Itoa(t.Day()))
+		case c == 'E':
+			b.WriteString(symbols.weekdays[t.Weekday()])
+		}
+	}
+	return b.String()
+}
+
+// FormatRelativeTime describes t relative to now in locale, in the largest
+// unit that fits, rounded: "in 3 days" or "3 days ago" in en, "in 3 Tagen"
+// or "vor 3 Tagen" in de. Differences under a second read as "now".
+// Locales without built-in phrases get English ones, with English digits.
+func FormatRelativeTime(locale string, t, now time.Time) string {
+	phrases, locale := relativePhrasesFor(locale)
+	d := t.Sub(now)
+	if d > -time.Second && d < time.Second {
+		return phrases.now
+	}
+	unit, n := relativeUnit(d.Abs())
+	forms := phrases.units[unit]
+	name, ok := forms[PluralCategoryFor(locale, n)]
+	if !ok {
+		name = forms[PluralOther]
+	}
+	template := phrases.future
+	if d < 0 {
+		template = phrases.past
+	}
+	r := strings.NewReplacer("{n}", FormatNumber(locale, n), "{unit}", name)
+	return r.Replace(template)
+}
+
+// FormatNumber formats v in the bound locale; see the package FormatNumber.
+func (l *Localizer) FormatNumber(v any, opts ...number.Option) string {
+	return FormatNumber(l.Locale(), v, opts...)
+}
+
+// FormatCurrency formats amount in the bound locale; see the package
+// FormatCurrency.
+func (l *Localizer) FormatCurrency(amount any, code string) string {
+	return FormatCurrency(l.Locale(), amount, code)
+}
+
+// FormatDate formats t in the bound locale; see the package FormatDate.
+func (l *Localizer) FormatDate(t time.Time, style DateStyle) string {
+	return FormatDate(l.Locale(), t, style)
+}
+
+// FormatRelativeTime describes t relative to the current time in the bound
+// locale; see the package FormatRelativeTime.
+func (l *Localizer) FormatRelativeTime(t time.Time) string {
+	return FormatRelativeTime(l.Locale(), t, time.Now())
+}
+
+// relativeUnit picks the unit for d and the rounded count of it.
+func relativeUnit(d time.Duration) (relativeTimeUnit, int64) {
+	seconds := int64(math.Round(d.Seconds()))
+	if seconds < 60 {
+		return relativeSecond, max(seconds, 1)
+	}
+	minutes := int64(math.Round(d.Minutes()))
+	if minutes < 60 {
+		return relativeMinute, minutes
+	}
+	hours := int64(math.Round(d.Hours()))
+	if hours < 24 {
+		return relativeHour, hours
+	}
+	days := int64(math.Round(d.Hours() / 24))
+	switch {
+	case days < 7:
+		return relativeDay, days
+	case days < 30:
+		return relativeWeek, int64(math.Round(float64(days) / 7))
+	case days < 365:
+		return relativeMonth, max(int64(math.Round(float64(days)/30.44)), 1)
+	default:
+		return relativeYear, int64(math.Round(float64(days) / 365.25))
+	}
+}
+
+func printerFor(locale string) *message.Printer {
+	tag, err := language.Parse(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	if err != nil {
+		tag = language.English
+	}
+	return message.NewPrinter(tag)
+}
+
+// lookupLocale returns the entry of table for locale or its language.
+func lookupLocale[V any](table map[string]V, locale string) (V, bool) {
+	locale = normalizePluralLocale(locale)
+	if v, ok := table[locale]; ok {
+		return v, true
+	}
+	lang, _, _ := strings.Cut(locale, "-")
+	v, ok := table[lang]
+	return v, ok
+}
+
+func toFloat64(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	case fmt.Stringer:
+		// decimal types such as shopspring's Decimal
+		f, err := strconv.ParseFloat(n.String(), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toTime accepts a time.Time, a *time.Time or an RFC 3339 string, the form
+// times take in data decoded from JSON.
+func toTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		if t != nil {
+			return *t, nil
+		}
+	case string:
+		return time.Parse(time.RFC3339, t)
+	}
+	return time.Time{}, fmt.Errorf("%T is not a time", v)
+}
+
+// funcPlaceholderArgRe splits the arguments of a function placeholder.
+var funcPlaceholderArgRe = regexp.MustCompile(`"[^"]*"|[a-zA-Z0-9_\.]+`)
+
+// callFormatFunc renders a {{name args}} placeholder, reporting false when
+// name is no function or an argument is missing from data.
+func callFormatFunc(funcs map[string]FormatFunc, locale, name, rawArgs string, data map[string]any) (string, bool) {
+	fn, ok := funcs[name]
+	if !ok {
+		return "", false
+	}
+	var args []any
+	for _, tok := range funcPlaceholderArgRe.FindAllString(rawArgs, -1) {
+		if strings.HasPrefix(tok, `"`) {
+			args = append(args, strings.Trim(tok, `"`))
+			continue
+		}
+		v, ok := dig(data, tok)
+		if !ok {
+			return "", false
+		}
+		args = append(args, v)
+	}
+	out, err := fn(locale, args...)
+	if err != nil {
+		return "", false
+	}
+	return out, true
+}
@@ -0,0 +1,209 @@
+package i18n
+
+// Date, currency and relative time data from CLDR 44 for the locales with
+// built-in formats. Lookups try the full locale, then its language.
+
+type currencyPattern int
+
+const (
+	currencyPrefix      currencyPattern = iota // ¤1,234.50
+	currencyPrefixSpace                        // ¤ 1.234,50
+	currencySuffix                             // 1.234,50 ¤
+)
+
+var currencyPatterns = map[string]currencyPattern{
+	"ar": currencySuffix, "bg": currencySuffix, "cs": currencySuffix, "da": currencySuffix,
+	"de": currencySuffix, "el": currencySuffix, "es": currencySuffix, "et": currencySuffix,
+	"fi": currencySuffix, "fr": currencySuffix, "he": currencySuffix, "hr": currencySuffix,
+	"hu": currencySuffix, "it": currencySuffix, "lt": currencySuffix, "lv": currencySuffix,
+	"nb": currencySuffix, "no": currencySuffix, "pl": currencySuffix, "pt-pt": currencySuffix,
+	"ro": currencySuffix, "ru": currencySuffix, "sk": currencySuffix, "sl": currencySuffix,
+	"sv": currencySuffix, "uk": currencySuffix, "vi": currencySuffix,
+	"de-at": currencyPrefixSpace, "de-ch": currencyPrefixSpace, "it-ch": currencyPrefixSpace,
+	"nl": currencyPrefixSpace, "pt": currencyPrefixSpace,
+	"es-419": currencyPrefix, "es-mx": currencyPrefix, "es-us": currencyPrefix,
+}
+
+func currencyPatternFor(locale string) currencyPattern {
+	pattern, _ := lookupLocale(currencyPatterns, locale)
+	return pattern
+}
+
+// dateSymbols holds the patterns of a locale's date styles, indexed by
+// DateStyle, and the names they use.
+type dateSymbols struct {
+	patterns    [4]string
+	months      [12]string
+	shortMonths [12]string
+	// weekdays start on Sunday, like time.Weekday.
+	weekdays [7]string
+}
+
+var (
+	enMonths   = [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+	enShort    = [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+	enWeekdays = [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+	cjkMonths  = [12]string{"1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"}
+)
+
+var dateSymbolsByLocale = map[string]*dateSymbols{
+	"en": {
+		patterns:    [4]string{"M/d/yy", "MMM d, y", "MMMM d, y", "EEEE, MMMM d, y"},
+		months:      enMonths,
+		shortMonths: enShort,
+		weekdays:    enWeekdays,
+	},
+	"en-gb": {
+		patterns:    [4]string{"dd/MM/y", "d MMM y", "d MMMM y", "EEEE d MMMM y"},
+		months:      enMonths,
+		shortMonths: enShort,
+		weekdays:    enWeekdays,
+	},
+	"de": {
+		patterns:    [4]string{"dd.MM.yy", "dd.MM.y", "d. MMMM y", "EEEE, d. MMMM y"},
+		months:      [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		shortMonths: [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		weekdays:    [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+	},
+	"fr": {
+		patterns:    [4]string{"dd/MM/y", "d MMM y", "d MMMM y", "EEEE d MMMM y"},
+		months:      [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		shortMonths: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		weekdays:    [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+	},
+	"es": {
+		patterns:    [4]string{"d/M/yy", "d MMM y", "d 'de' MMMM 'de' y", "EEEE, d 'de' MMMM 'de' y"},
+		months:      [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		shortMonths: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		weekdays:    [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+	},
+	"it": {
+		patterns:    [4]string{"dd/MM/yy", "d MMM y", "d MMMM y", "EEEE d MMMM y"},
+		months:      [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		shortMonths: [12]string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+		weekdays:    [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+	},
+	"pt": {
+		patterns:    [4]string{"dd/MM/y", "d 'de' MMM 'de' y", "d 'de' MMMM 'de' y", "EEEE, d 'de' MMMM 'de' y"},
+		months:      [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		shortMonths: [12]string{"jan.", "fev.", "mar.", "abr.", "mai.", "jun.", "jul.", "ago.", "set.", "out.", "nov.", "dez."},
+		weekdays:    [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+	},
+	"nl": {
+		patterns:    [4]string{"dd-MM-y", "d MMM y", "d MMMM y", "EEEE d MMMM y"},
+		months:      [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		shortMonths: [12]string{"jan", "feb", "mrt", "apr", "mei", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+		weekdays:    [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+	},
+	"ru": {
+		patterns: [4]string{"dd.MM.y", "d MMM y 'г'.", "d MMMM y 'г'.", "EEEE, d MMMM y 'г'."},
+		// genitive forms, as used after the day
+		months:      [12]string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"},
+		shortMonths: [12]string{"янв.", "февр.", "мар.", "апр.", "мая", "июн.", "июл.", "авг.", "сент.", "окт.", "нояб.", "дек."},
+		weekdays:    [7]string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"},
+	},
+	"ja": {
+		patterns:    [4]string{"y/MM/dd", "y/MM/dd", "y年M月d日", "y年M月d日EEEE"},
+		months:      cjkMonths,
+		shortMonths: cjkMonths,
+		weekdays:    [7]string{"日曜日", "月曜日", "火曜日", "水曜日", "木曜日", "金曜日", "土曜日"},
+	},
+	"zh": {
+		patterns:    [4]string{"y/M/d", "y年M月d日", "y年M月d日", "y年M月d日EEEE"},
+		months:      cjkMonths,
+		shortMonths: cjkMonths,
+		weekdays:    [7]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"},
+	},
+}
+
+func dateSymbolsFor(locale string) *dateSymbols {
+	if symbols, ok := lookupLocale(dateSymbolsByLocale, locale); ok {
+		return symbols
+	}
+	return dateSymbolsByLocale["en"]
+}
+
+type relativeTimeUnit int
+
+const (
+	relativeSecond relativeTimeUnit = iota
+	relativeMinute
+	relativeHour
+	relativeDay
+	relativeWeek
+	relativeMonth
+	relativeYear
+)
+
+// relativePhrases builds relative times: future and past hold {n} and
+// {unit}, and units the unit names by plural category.
+type relativePhrases struct {
+	now    string
+	future string
+	past   string
+	units  [7]map[PluralCategory]string
+}
+
+// oneOther returns unit names for languages with the one and other forms.
+func oneOther(pairs ...string) [7]map[PluralCategory]string {
+	var units [7]map[PluralCategory]string
+	for i := range units {
+		units[i] = map[PluralCategory]string{PluralOne: pairs[2*i], PluralOther: pairs[2*i+1]}
+	}
+	return units
+}
+
+// invariant returns unit names for languages without plural forms.
+func invariant(names ...string) [7]map[PluralCategory]string {
+	var units [7]map[PluralCategory]string
+	for i := range units {
+		units[i] = map[PluralCategory]string{PluralOther: names[i]}
+	}
+	return units
+}
+
+var relativePhrasesByLocale = map[string]*relativePhrases{
+	"en": {now: "now", future: "in {n} {unit}", past: "{n} {unit} ago", units: oneOther(
+		"second", "seconds", "minute", "minutes", "hour", "hours", "day", "days",
+		"week", "weeks", "month", "months", "year", "years")},
+	"de": {now: "jetzt", future: "in {n} {unit}", past: "vor {n} {unit}", units: oneOther(
+		"Sekunde", "Sekunden", "Minute", "Minuten", "Stunde", "Stunden", "Tag", "Tagen",
+		"Woche", "Wochen", "Monat", "Monaten", "Jahr", "Jahren")},
+	"fr": {now: "maintenant", future: "dans {n} {unit}", past: "il y a {n} {unit}", units: oneOther(
+		"seconde", "secondes", "minute", "minutes", "heure", "heures", "jour", "jours",
+		"semaine", "semaines", "mois", "mois", "an", "ans")},
+	"es": {now: "ahora", future: "dentro de {n} {unit}", past: "hace {n} {unit}", units: oneOther(
+		"segundo", "segundos", "minuto", "minutos", "hora", "horas", "día", "días",
+		"semana", "semanas", "mes", "meses", "año", "años")},
+	"it": {now: "ora", future: "tra {n} {unit}", past: "{n} {unit} fa", units: oneOther(
+		"secondo", "secondi", "minuto", "minuti", "ora", "ore", "giorno", "giorni",
+		"settimana", "settimane", "mese", "mesi", "anno", "anni")},
+	"pt": {now: "agora", future: "em {n} {unit}", past: "há {n} {unit}", units: oneOther(
+		"segundo", "segundos", "minuto", "minutos", "hora", "horas", "dia", "dias",
+		"semana", "semanas", "mês", "meses", "ano", "anos")},
+	"nl": {now: "nu", future: "over {n} {unit}", past: "{n} {unit} geleden", units: oneOther(
+		"seconde", "seconden", "minuut", "minuten", "uur", "uur", "dag", "dagen",
+		"week", "weken", "maand", "maanden", "jaar", "jaar")},
+	"ru": {now: "сейчас", future: "через {n} {unit}", past: "{n} {unit} назад", units: [7]map[PluralCategory]string{
+		{PluralOne: "секунду", PluralFew: "секунды", PluralMany: "секунд", PluralOther: "секунды"},
+		{PluralOne: "минуту", PluralFew: "минуты", PluralMany: "минут", PluralOther: "минуты"},
+		{PluralOne: "час", PluralFew: "часа", PluralMany: "часов", PluralOther: "часа"},
+		{PluralOne: "день", PluralFew: "дня", PluralMany: "дней", PluralOther: "дня"},
+		{PluralOne: "неделю", PluralFew: "недели", PluralMany: "недель", PluralOther: "недели"},
+		{PluralOne: "месяц", PluralFew: "месяца", PluralMany: "месяцев", PluralOther: "месяца"},
+		{PluralOne: "год", PluralFew: "года", PluralMany: "лет", PluralOther: "года"},
+	}},
+	"ja": {now: "今", future: "{n} {unit}後", past: "{n} {unit}前", units: invariant(
+		"秒", "分", "時間", "日", "週間", "か月", "年")},
+	"zh": {now: "现在", future: "{n}{unit}后", past: "{n}{unit}前", units: invariant(
+		"秒钟", "分钟", "小时", "天", "周", "个月", "年")},
+}
+
+// relativePhrasesFor returns the phrases of locale and the locale to format
+// their numbers in: English for locales without phrases of their own.
+func relativePhrasesFor(locale string) (*relativePhrases, string) {
+	if phrases, ok := lookupLocale(relativePhrasesByLocale, locale); ok {
+		return phrases, locale
+	}
+	return relativePhrasesByLocale["en"], "en"
+}
@@ -0,0 +1,21 @@
+package i18n
+
+import "testing"
+
+// The examples in the FormatCurrency doc comment.
+func TestFormatCurrencyDocExamples(t *testing.T) {
+	tests := []struct {
+		locale, code string
+		want         string
+	}{
+		{"en", "EUR", "€1,234.50"},
+		{"de", "EUR", "1.234,50\u00a0€"},
+		{"ja", "JPY", "￥1,234"},
+		{"en", "XYZ", "1,234.5 XYZ"},
+	}
+	for _, tt := range tests {
+		if got := FormatCurrency(tt.locale, 1234.5, tt.code); got != tt.want {
+			t.Errorf("FormatCurrency(%q, 1234.5, %q) = %q, want %q", tt.locale, tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"go.yaml.in/yaml/v3"
+)
+
+// bundleExts are the bundle file extensions WithDir, WithFS and LoadFile
+// understand.
+var bundleExts = []string{".json", ".yaml", ".yml", ".toml"}
+
+// WithDir loads messages from a directory with files named <locale>.json,
+// <locale>.yaml, <locale>.yml or <locale>.toml into a domain. Nested maps are
+// flattened to dotted keys; see LoadFile.
+func WithDir(domain, dir string) Option {
+	return func(t *Translator) error {
+		return t.LoadFS(domain, os.DirFS(dir), ".")
+	}
+}
+
+// WithFS loads messages from dir in fsys, in the formats WithDir reads, so
+// locales can be compiled into the binary:
+//
+//	//go:embed locales
+//	var locales embed.FS
+//
+//	tr := i18n.New(i18n.WithFS("default", locales, "locales"))
+func WithFS(domain string, fsys fs.FS, dir string) Option {
+	return func(t *Translator) error {
+		return t.LoadFS(domain, fsys, dir)
+	}
+}
+
+// LoadFS loads every <locale>.<ext> bundle in dir of fsys into domain, in
+// file name order. Files with other extensions and subdirectories are
+// skipped.
+func (t *Translator) LoadFS(domain string, fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		ext := strings.ToLower(path.Ext(name))
+		if !isBundleExt(ext) {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		bundle, err := parseBundle(ext, data)
+		if err != nil {
+			return fmt.Errorf("i18n: %s: %w", path.Join(dir, name), err)
+		}
+		t.AddBundle(domain, strings.TrimSuffix(name, path.Ext(name)), bundle)
+	}
+	return nil
+}
+
+// LoadFile loads a bundle file into domain/locale, picking the format from
+// its extension: .json, .yaml, .yml or .toml. Nested maps are flattened to
+// dotted keys, so
+//
+//	cart:
+//	  items:
+//	    one: "{{count}} item"
+//	    other: "{{count}} items"
+//
+// defines cart.items.one and cart.items.other. Numbers and booleans are
+// kept as text; lists are rejected.
+func (t *Translator) LoadFile(domain, locale, file string) error {
+	ext := strings.ToLower(filepath.Ext(file))
+	if !isBundleExt(ext) {
+		return fmt.Errorf("i18n: %s: unsupported bundle format %q", file, ext)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	bundle, err := parseBundle(ext, data)
+	if err != nil {
+		return fmt.Errorf("i18n: %s: %w", file, err)
+	}
+	t.AddBundle(domain, locale, bundle)
+	return nil
+}
+
+func isBundleExt(ext string) bool {
+	for _, e := range bundleExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBundle decodes a bundle in the format of ext and flattens it.
+func parseBundle(ext string, data []byte) (map[string]string, error) {
+	raw, err := decodeBundle(ext, data)
+	if err != nil {
+		return nil, err
+	}
+	bundle := make(map[string]string, len(raw))
+	if err := flattenBundle("", raw, bundle); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// decodeBundle decodes a document in the format of ext without flattening.
+func decodeBundle(ext string, data []byte) (map[string]any, error) {
+	raw := map[string]any{}
+	var err error
+	switch ext {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported bundle format %q", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// flattenBundle adds the messages of nested to bundle under dotted keys.
+func flattenBundle(prefix string, nested map[string]any, bundle map[string]string) error {
+	for k, v := range nested {
+		key := prefix + k
+		switch vv := v.(type) {
+		case string:
+			bundle[key] = vv
+		case map[string]any:
+			if err := flattenBundle(key+".", vv, bundle); err != nil {
+				return err
+			}
+		case map[any]any:
+			converted := make(map[string]any, len(vv))
+			for nk, nv := range vv {
+				converted[fmt.Sprint(nk)] = nv
+			}
+			if err := flattenBundle(key+".", converted, bundle); err != nil {
+				return err
+			}
+		case nil:
+			return fmt.Errorf("key %q has no message", key)
+		case []any:
+			return fmt.Errorf("key %q holds a list; messages must be strings or maps", key)
+		default:
+			bundle[key] = fmt.Sprint(vv)
+		}
+	}
+	return nil
+}
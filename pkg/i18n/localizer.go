@@ -0,0 +1,85 @@
+package i18n
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+const translatorCtxKey ctxKey = "i18n_translator"
+
+// Localizer is a Translator bound to one locale, so handler and service code can
+// translate without threading locale strings through every call.
+type Localizer struct {
+	tr     *Translator
+	locale string
+}
+
+// ForLocale returns a Localizer for locale; an empty locale uses the default.
+func (t *Translator) ForLocale(locale string) *Localizer {
+	if locale == "" && t != nil {
+		locale = t.defaultLocale
+	}
+	return &Localizer{tr: t, locale: locale}
+}
+
+// Locale returns the bound locale.
+func (l *Localizer) Locale() string {
+	if l == nil {
+		return ""
+	}
+	return l.locale
+}
+
+// T translates key in the bound locale, with the same data and plural rules as Translator.T.
+// Without a Translator it returns the key with data interpolated.
+func (l *Localizer) T(key string, data map[string]any, n ...int) string {
+	if l == nil || l.tr == nil {
+		_, k := splitDomain(key)
+		if len(data) == 0 {
+			return k
+		}
+		return interpolate(k, data, l.Locale())
+	}
+	return l.tr.T(l.locale, key, data, n...)
+}
+
+// Translate translates key in the bound locale like Translator.Translate,
+// reporting whether the key was found. Without a Translator it reports false.
+func (l *Localizer) Translate(key string, data map[string]any, n ...int) (string, bool) {
+	if l == nil || l.tr == nil {
+		return l.T(key, data, n...), false
+	}
+	return l.tr.Translate(l.locale, key, data, n...)
+}
+
+// Lookup returns the raw message for key in the bound locale.
+func (l *Localizer) Lookup(key string) (string, error) {
+	if l == nil || l.tr == nil {
+		return "", ErrNotFound
+	}
+	return l.tr.Lookup(l.locale, key)
+}
+
+// ContextWithTranslator returns a child context carrying tr for FromContext.
+func ContextWithTranslator(ctx context.Context, tr *Translator) context.Context {
+	return context.WithValue(ctx, translatorCtxKey, tr)
+}
+
+// FromContext returns a Localizer for the translator and locale stored by
+// GinMiddleware (or ContextWithTranslator/ContextWithLocale). It never returns
+// nil: without a translator, T falls back to the key itself.
+//
+//	func (s *OrderService) Confirm(ctx context.Context, order Order) string {
+//		return i18n.FromContext(ctx).T("orders:confirmed", map[string]any{"id": order.ID})
+//	}
+func FromContext(ctx context.Context) *Localizer {
+	if c, ok := ctx.(*gin.Context); ok && c.Request != nil {
+		ctx = c.Request.Context()
+	}
+	if ctx == nil {
+		return &Localizer{}
+	}
+	tr, _ := ctx.Value(translatorCtxKey).(*Translator)
+	return tr.ForLocale(LocaleFromContext(ctx))
+}
@@ -0,0 +1,216 @@
+package i18n
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxMissingKeys caps the keys recorded by WithMissingKeyTracking, so
+// keys or locales built from request data cannot grow the report unbounded.
+const DefaultMaxMissingKeys = 10000
+
+// WithMissingKeyTracking records every key T and Rich could not find in the
+// requested locale, whether a fallback locale served it or the key itself
+// was shown. Read the misses with MissingReport, serve them with
+// MissingHandler, or write them as bundles for translators with
+// WriteMissingBundles. Lookup does not record misses.
+func WithMissingKeyTracking() Option {
+	return func(t *Translator) error {
+		t.missing = &missingTracker{max: DefaultMaxMissingKeys, keys: map[missingID]*MissingKey{}}
+		return nil
+	}
+}
+
+// MissingKey is a key missing from a locale's bundle.
+type MissingKey struct {
+	Domain string `json:"domain"`
+	Locale string `json:"locale"`
+	Key    string `json:"key"`
+	// Plural is set when the key was requested with a count, so the bundle
+	// needs a key.<category> message per plural category of the locale.
+	Plural bool `json:"plural,omitempty"`
+	// Fallback is the message served instead from a fallback locale; empty
+	// when none had it and the key itself was shown.
+	Fallback  string    `json:"fallback,omitempty"`
+	Count     int64     `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// MissingReport lists the keys missing per domain and locale, sorted by
+// domain, locale and key.
+type MissingReport struct {
+	Keys []MissingKey `json:"keys"`
+	// Dropped counts misses not recorded because DefaultMaxMissingKeys
+	// distinct keys were already recorded.
+	Dropped int64 `json:"dropped,omitempty"`
+}
+
+// Bundle returns a skeleton bundle of the keys missing from domain and
+// locale, in the <locale>.json format WithJSONDir loads. Each key holds its
+// fallback message as the source text to translate, or "" when there was
+// none; plural keys are split into the locale's plural categories
+// (PluralCategories), e.g. key.one, key.few, key.many and key.other for ru.
+func (r MissingReport) Bundle(domain, locale string) map[string]string {
+	bundle := map[string]string{}
+	for _, m := range r.Keys {
+		if m.Domain != domain || m.Locale != locale {
+			continue
+		}
+		if m.Plural {
+			for _, category := range PluralCategories(locale) {
+				bundle[m.Key+"."+string(category)] = m.Fallback
+			}
+			continue
+		}
+		bundle[m.Key] = m.Fallback
+	}
+	return bundle
+}
+
+type missingID struct {
+	domain, locale, key string
+}
+
+type missingTracker struct {
+	mu      sync.Mutex
+	max     int
+	keys    map[missingID]*MissingKey
+	dropped int64
+}
+
+func (m *missingTracker) record(domain, locale, key string, plural bool, fallback string) {
+	now := time.Now()
+	id := missingID{domain: domain, locale: locale, key: key}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.keys[id]
+	if !ok {
+		if len(m.keys) >= m.max {
+			m.dropped++
+			return
+		}
+		entry = &MissingKey{Domain: domain, Locale: locale, Key: key, FirstSeen: now}
+		m.keys[id] = entry
+	}
+	entry.Count++
+	entry.LastSeen = now
+	entry.Plural = entry.Plural || plural
+	if fallback != "" {
+		entry.Fallback = fallback
+	}
+}
+
+// recordMissing records key as missing from locale when tracking is on and
+// the message was not served by the requested locale.
+func (t *Translator) recordMissing(domain, locale, key string, keys []string, fallback string) {
+	if t.missing != nil {
+		t.missing.record(domain, locale, key, len(keys) > 1, fallback)
+	}
+}
+
+// MissingReport returns the keys recorded by WithMissingKeyTracking. It is
+// empty when tracking is off.
+func (t *Translator) MissingReport() MissingReport {
+	var report MissingReport
+	if t.missing == nil {
+		return report
+	}
+	t.missing.mu.Lock()
+	for _, entry := range t.missing.keys {
+		report.Keys = append(report.Keys, *entry)
+	}
+	report.Dropped = t.missing.dropped
+	t.missing.mu.Unlock()
+	sort.Slice(report.Keys, func(i, j int) bool {
+		a, b := report.Keys[i], report.Keys[j]
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+		if a.Locale != b.Locale {
+			return a.Locale < b.Locale
+		}
+		return a.Key < b.Key
+	})
+	return report
+}
+
+// ResetMissing clears the recorded keys, e.g. after the bundles have been
+// updated.
+func (t *Translator) ResetMissing() {
+	if t.missing == nil {
+		return
+	}
+	t.missing.mu.Lock()
+	t.missing.keys = map[missingID]*MissingKey{}
+	t.missing.dropped = 0
+	t.missing.mu.Unlock()
+}
+
+// WriteMissingBundles writes a skeleton bundle (see MissingReport.Bundle) for
+// every domain and locale with missing keys to dir/<domain>/<locale>.json,
+// for translators to fill in and merge into the real bundles. Keep dir apart
+// from the WithJSONDir directories, or the skeletons would be loaded too.
+func (t *Translator) WriteMissingBundles(dir string) error {
+	report := t.MissingReport()
+	written := map[missingID]bool{}
+	for _, m := range report.Keys {
+		id := missingID{domain: m.Domain, locale: m.Locale}
+		if written[id] || !safePathElement(m.Domain) || !safePathElement(m.Locale) {
+			// locales can come from request data; never write outside dir
+			continue
+		}
+		written[id] = true
+		data, err := json.MarshalIndent(report.Bundle(m.Domain, m.Locale), "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Join(dir, m.Domain), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, m.Domain, m.Locale+".json"), append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MissingHandler serves the missing-key report as JSON for debugging, with
+// optional domain and locale query filters. With ?format=bundle and both
+// filters it serves the skeleton bundle instead. Mount it on an internal
+// route only:
+//
+//	admin.GET("/debug/i18n/missing", tr.MissingHandler())
+func (t *Translator) MissingHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		domain, locale := c.Query("domain"), c.Query("locale")
+		report := t.MissingReport()
+		if c.Query("format") == "bundle" {
+			if domain == "" || locale == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "domain and locale are required for format=bundle"})
+				return
+			}
+			c.JSON(http.StatusOK, report.Bundle(domain, locale))
+			return
+		}
+		filtered := []MissingKey{}
+		for _, m := range report.Keys {
+			if (domain == "" || m.Domain == domain) && (locale == "" || m.Locale == locale) {
+				filtered = append(filtered, m)
+			}
+		}
+		report.Keys = filtered
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+func safePathElement(name string) bool {
+	return name != "" && name != "." && name != ".." && filepath.Base(name) == name
+}
@@ -0,0 +1,604 @@
+package i18n
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PluralCategory is a CLDR plural category, used as the key suffix of a
+// plural message (key.one, key.few, ...).
+type PluralCategory string
+
+// CLDR plural categories. Every locale uses PluralOther; the rest depend on
+// the language.
+const (
+	PluralZero  PluralCategory = "zero"
+	PluralOne   PluralCategory = "one"
+	PluralTwo   PluralCategory = "two"
+	PluralFew   PluralCategory = "few"
+	PluralMany  PluralCategory = "many"
+	PluralOther PluralCategory = "other"
+)
+
+// PluralOperands are the CLDR plural operands of a count: N its absolute
+// value, I its integer digits, V the number of visible fraction digits, F the
+// visible fraction digits and T those without trailing zeros. "1.50" has
+// N=1.5, I=1, V=2, F=50, T=5.
+type PluralOperands struct {
+	N    float64
+	I    int64
+	V    int
+	F, T int64
+}
+
+// PluralRule returns the plural category of a count in a language.
+type PluralRule func(PluralOperands) PluralCategory
+
+// WithPluralRule sets the plural rule of locale, a language ("pt") or a
+// language and region ("pt-PT"), replacing the built-in CLDR rule. Lookups
+// for pt-BR use a pt-BR rule if one is set and fall back to the pt one; a
+// region with its own built-in rule, such as pt-PT, keeps it unless a rule
+// is set for the region itself.
+func WithPluralRule(locale string, rule PluralRule) Option {
+	return func(t *Translator) error {
+		if t.pluralRules == nil {
+			t.pluralRules = make(map[string]PluralRule)
+		}
+		t.pluralRules[normalizePluralLocale(locale)] = rule
+		return nil
+	}
+}
+
+// PluralCategoryFor returns the CLDR plural category of count in locale,
+// using the built-in rules. count may be any integer or float type, or a
+// decimal string, which keeps visible fraction digits ("1.0" is not one in
+// English). Unknown languages use one for exactly 1 and other otherwise.
+func PluralCategoryFor(locale string, count any) PluralCategory {
+	ops, ok := NewPluralOperands(count)
+	if !ok {
+		return PluralOther
+	}
+	return builtinPluralRule(locale).rule(ops)
+}
+
+// PluralCategories returns the plural categories the built-in rules use for
+// locale, in CLDR order, ending with PluralOther. A bundle for the locale
+// needs a key.<category> message for each of them.
+func PluralCategories(locale string) []PluralCategory {
+	return append([]PluralCategory(nil), builtinPluralRule(locale).categories...)
+}
+
+// NewPluralOperands returns the operands of count, which may be any integer
+// or float type, or a decimal string such as "1.50".
+func NewPluralOperands(count any) (PluralOperands, bool) {
+	switch v := count.(type) {
+	case int:
+		return intOperands(int64(v)), true
+	case int8:
+		return intOperands(int64(v)), true
+	case int16:
+		return intOperands(int64(v)), true
+	case int32:
+		return intOperands(int64(v)), true
+	case int64:
+		return intOperands(v), true
+	case uint:
+		return decimalOperands(strconv.FormatUint(uint64(v), 10))
+	case uint8:
+		return intOperands(int64(v)), true
+	case uint16:
+		return intOperands(int64(v)), true
+	case uint32:
+		return intOperands(int64(v)), true
+	case uint64:
+		return decimalOperands(strconv.FormatUint(v, 10))
+	case float32:
+		return decimalOperands(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	case float64:
+		return decimalOperands(strconv.FormatFloat(v, 'f', -1, 64))
+	case string:
+		return decimalOperands(strings.TrimSpace(v))
+	}
+	return PluralOperands{}, false
+}
+
+func intOperands(i int64) PluralOperands {
+	if i < 0 {
+		i = -i
+	}
+	return PluralOperands{N: float64(i), I: i}
+}
+
+func decimalOperands(s string) (PluralOperands, bool) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	intPart, frac, _ := strings.Cut(s, ".")
+	if intPart == "" {
+		intPart = "0"
+	}
+	i, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return PluralOperands{}, false
+	}
+	n, err := strconv.ParseFloat(intPart+"."+frac+"0", 64)
+	if err != nil || math.IsInf(n, 0) {
+		return PluralOperands{}, false
+	}
+	ops := PluralOperands{N: n, I: i, V: len(frac)}
+	if frac != "" {
+		// keep the last 18 digits so F and T fit in an int64; rules only
+		// look at the last two
+		if len(frac) > 18 {
+			frac = frac[len(frac)-18:]
+		}
+		if ops.F, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return PluralOperands{}, false
+		}
+		if trimmed := strings.TrimRight(frac, "0"); trimmed != "" {
+			ops.T, _ = strconv.ParseInt(trimmed, 10, 64)
+		}
+	}
+	return ops, true
+}
+
+// countOperands returns the operands of the count passed to T: n if given,
+// otherwise data["count"].
+func countOperands(data map[string]any, n []int) (PluralOperands, bool) {
+	if len(n) > 0 {
+		return intOperands(int64(n[0])), true
+	}
+	if v, ok := data["count"]; ok {
+		return NewPluralOperands(v)
+	}
+	return PluralOperands{}, false
+}
+
+// pluralKeys returns the keys to try for k in locale, most specific first.
+// With a count (n, or a numeric data["count"]) the locale's CLDR category
+// comes first, then key.one for a count of exactly 1 and key.other, so
+// bundles written with only one/other keep working, then the bare key.
+func (t *Translator) pluralKeys(locale, k string, data map[string]any, n []int) []string {
+	ops, ok := countOperands(data, n)
+	if !ok {
+		return []string{k}
+	}
+	category := t.pluralRule(locale)(ops)
+	keys := []string{k + "." + string(category)}
+	if category != PluralOne && ops.N == 1 && ops.V == 0 {
+		keys = append(keys, k+".one")
+	}
+	if category != PluralOther {
+		keys = append(keys, k+".other")
+	}
+	return append(keys, k)
+}
+
+// pluralLocale returns the locale whose rule picks the plural category when
+// bundle loc is searched for requested: requested itself when loc is the same
+// language, so a pt-PT reader of a pt bundle gets the pt-PT rule, and loc
+// otherwise, since its messages are in another language.
+func pluralLocale(requested, loc string) string {
+	reqLang, _, _ := strings.Cut(normalizePluralLocale(requested), "-")
+	locLang, _, _ := strings.Cut(normalizePluralLocale(loc), "-")
+	if reqLang == locLang {
+		return requested
+	}
+	return loc
+}
+
+// pluralRule returns the WithPluralRule rule of locale, the built-in rule of
+// a region that has its own (pt-PT), the WithPluralRule rule of its language,
+// or the built-in one.
+func (t *Translator) pluralRule(locale string) PluralRule {
+	locale = normalizePluralLocale(locale)
+	if rule, ok := t.pluralRules[locale]; ok {
+		return rule
+	}
+	if set, ok := pluralRulesByLocale[locale]; ok {
+		return set.rule
+	}
+	if lang, _, found := strings.Cut(locale, "-"); found {
+		if rule, ok := t.pluralRules[lang]; ok {
+			return rule
+		}
+	}
+	return builtinPluralRule(locale).rule
+}
+
+func normalizePluralLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+type pluralRuleSet struct {
+	categories []PluralCategory
+	rule       PluralRule
+}
+
+func builtinPluralRule(locale string) pluralRuleSet {
+	locale = normalizePluralLocale(locale)
+	if set, ok := pluralRulesByLocale[locale]; ok {
+		return set
+	}
+	lang, _, _ := strings.Cut(locale, "-")
+	if set, ok := pluralRulesByLocale[lang]; ok {
+		return set
+	}
+	return pluralRuleOneOther
+}
+
+func between(x, lo, hi int64) bool { return x >= lo && x <= hi }
+
+func isInt(ops PluralOperands, x int64) bool { return ops.V == 0 && ops.I == x }
+
+// Rules follow the CLDR 44 cardinal plural rules. The compact exponent
+// operand (1.2c6) is not supported and always 0.
+var (
+	pluralRuleOther = pluralRuleSet{
+		categories: []PluralCategory{PluralOther},
+		rule:       func(PluralOperands) PluralCategory { return PluralOther },
+	}
+	// one: i = 1 and v = 0
+	pluralRuleOneOther = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			if isInt(ops, 1) {
+				return PluralOne
+			}
+			return PluralOther
+		},
+	}
+	// one: n = 1
+	pluralRuleOneN = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			if ops.N == 1 {
+				return PluralOne
+			}
+			return PluralOther
+		},
+	}
+	// one: n = 1 or t != 0 and i = 0,1
+	pluralRuleDanish = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			if ops.N == 1 || ops.T != 0 && (ops.I == 0 || ops.I == 1) {
+				return PluralOne
+			}
+			return PluralOther
+		},
+	}
+	// one: i = 0 or n = 1
+	pluralRuleOneZeroOrN = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			if ops.I == 0 || ops.N == 1 {
+				return PluralOne
+			}
+			return PluralOther
+		},
+	}
+	// one: i = 1 and v = 0; many: e = 0 and i != 0 and i % 1000000 = 0 and v = 0
+	pluralRuleOneMany = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralMany, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			switch {
+			case isInt(ops, 1):
+				return PluralOne
+			case millions(ops):
+				return PluralMany
+			}
+			return PluralOther
+		},
+	}
+	// one: n = 1; many as above
+	pluralRuleSpanish = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralMany, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			switch {
+			case ops.N == 1:
+				return PluralOne
+			case millions(ops):
+				return PluralMany
+			}
+			return PluralOther
+		},
+	}
+	// one: i = 0,1; many as above
+	pluralRuleFrench = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralMany, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			switch {
+			case ops.I == 0 || ops.I == 1:
+				return PluralOne
+			case millions(ops):
+				return PluralMany
+			}
+			return PluralOther
+		},
+	}
+	pluralRuleEastSlavic = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralFew, PluralMany, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			if ops.V != 0 {
+				return PluralOther
+			}
+			i10, i100 := ops.I%10, ops.I%100
+			switch {
+			case i10 == 1 && i100 != 11:
+				return PluralOne
+			case between(i10, 2, 4) && !between(i100, 12, 14):
+				return PluralFew
+			}
+			return PluralMany
+		},
+	}
+	pluralRuleBelarusian = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralFew, PluralMany, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			if ops.V != 0 && ops.T != 0 {
+				return PluralOther
+			}
+			n10, n100 := ops.I%10, ops.I%100
+			switch {
+			case n10 == 1 && n100 != 11:
+				return PluralOne
+			case between(n10, 2, 4) && !between(n100, 12, 14):
+				return PluralFew
+			}
+			return PluralMany
+		},
+	}
+	pluralRulePolish = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralFew, PluralMany, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			if ops.V != 0 {
+				return PluralOther
+			}
+			i10, i100 := ops.I%10, ops.I%100
+			switch {
+			case ops.I == 1:
+				return PluralOne
+			case between(i10, 2, 4) && !between(i100, 12, 14):
+				return PluralFew
+			}
+			return PluralMany
+		},
+	}
+	pluralRuleCzech = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralFew, PluralMany, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			switch {
+			case ops.V != 0:
+				return PluralMany
+			case ops.I == 1:
+				return PluralOne
+			case between(ops.I, 2, 4):
+				return PluralFew
+			}
+			return PluralOther
+		},
+	}
+	pluralRuleSerboCroatian = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralFew, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			i10, i100, f10, f100 := ops.I%10, ops.I%100, ops.F%10, ops.F%100
+			switch {
+			case ops.V == 0 && i10 == 1 && i100 != 11, f10 == 1 && f100 != 11:
+				return PluralOne
+			case ops.V == 0 && between(i10, 2, 4) && !between(i100, 12, 14),
+				between(f10, 2, 4) && !between(f100, 12, 14):
+				return PluralFew
+			}
+			return PluralOther
+		},
+	}
+	pluralRuleSlovenian = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralTwo, PluralFew, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			i100 := ops.I % 100
+			switch {
+			case ops.V != 0:
+				return PluralFew
+			case i100 == 1:
+				return PluralOne
+			case i100 == 2:
+				return PluralTwo
+			case between(i100, 3, 4):
+				return PluralFew
+			}
+			return PluralOther
+		},
+	}
+	pluralRuleLithuanian = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralFew, PluralMany, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			if ops.F != 0 {
+				return PluralMany
+			}
+			n10, n100 := ops.I%10, ops.I%100
+			switch {
+			case n10 == 1 && !between(n100, 11, 19):
+				return PluralOne
+			case between(n10, 2, 9) && !between(n100, 11, 19):
+				return PluralFew
+			}
+			return PluralOther
+		},
+	}
+	pluralRuleLatvian = pluralRuleSet{
+		categories: []PluralCategory{PluralZero, PluralOne, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			// n % 10 and n % 100 only match integers when f = 0
+			n10, n100 := int64(-1), int64(-1)
+			if ops.F == 0 {
+				n10, n100 = ops.I%10, ops.I%100
+			}
+			f10, f100 := ops.F%10, ops.F%100
+			switch {
+			case n10 == 0 || between(n100, 11, 19) || ops.V == 2 && between(f100, 11, 19):
+				return PluralZero
+			case n10 == 1 && n100 != 11 || ops.V == 2 && f10 == 1 && f100 != 11 || ops.V != 2 && f10 == 1:
+				return PluralOne
+			}
+			return PluralOther
+		},
+	}
+	pluralRuleRomanian = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralFew, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			switch {
+			case isInt(ops, 1):
+				return PluralOne
+			case ops.V != 0 || ops.N == 0 || ops.N != 1 && ops.F == 0 && between(ops.I%100, 1, 19):
+				return PluralFew
+			}
+			return PluralOther
+		},
+	}
+	pluralRuleArabic = pluralRuleSet{
+		categories: []PluralCategory{PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			if ops.F != 0 {
+				return PluralOther
+			}
+			n100 := ops.I % 100
+			switch {
+			case ops.I == 0:
+				return PluralZero
+			case ops.I == 1:
+				return PluralOne
+			case ops.I == 2:
+				return PluralTwo
+			case between(n100, 3, 10):
+				return PluralFew
+			case between(n100, 11, 99):
+				return PluralMany
+			}
+			return PluralOther
+		},
+	}
+	pluralRuleHebrew = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralTwo, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			switch {
+			case isInt(ops, 1) || ops.I == 0 && ops.V != 0:
+				return PluralOne
+			case isInt(ops, 2):
+				return PluralTwo
+			}
+			return PluralOther
+		},
+	}
+	pluralRuleIrish = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			if ops.F != 0 {
+				return PluralOther
+			}
+			switch {
+			case ops.I == 1:
+				return PluralOne
+			case ops.I == 2:
+				return PluralTwo
+			case between(ops.I, 3, 6):
+				return PluralFew
+			case between(ops.I, 7, 10):
+				return PluralMany
+			}
+			return PluralOther
+		},
+	}
+	pluralRuleWelsh = pluralRuleSet{
+		categories: []PluralCategory{PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			if ops.F != 0 {
+				return PluralOther
+			}
+			switch ops.I {
+			case 0:
+				return PluralZero
+			case 1:
+				return PluralOne
+			case 2:
+				return PluralTwo
+			case 3:
+				return PluralFew
+			case 6:
+				return PluralMany
+			}
+			return PluralOther
+		},
+	}
+	pluralRuleIcelandic = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			if ops.T == 0 && ops.I%10 == 1 && ops.I%100 != 11 || ops.T%10 == 1 && ops.T%100 != 11 {
+				return PluralOne
+			}
+			return PluralOther
+		},
+	}
+	pluralRuleMacedonian = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			if ops.V == 0 && ops.I%10 == 1 && ops.I%100 != 11 || ops.F%10 == 1 && ops.F%100 != 11 {
+				return PluralOne
+			}
+			return PluralOther
+		},
+	}
+	pluralRuleFilipino = pluralRuleSet{
+		categories: []PluralCategory{PluralOne, PluralOther},
+		rule: func(ops PluralOperands) PluralCategory {
+			switch {
+			case ops.V == 0 && between(ops.I, 1, 3),
+				ops.V == 0 && ops.I%10 != 4 && ops.I%10 != 6 && ops.I%10 != 9,
+				ops.V != 0 && ops.F%10 != 4 && ops.F%10 != 6 && ops.F%10 != 9:
+				return PluralOne
+			}
+			return PluralOther
+		},
+	}
+)
+
+// millions reports e = 0 and i != 0 and i % 1000000 = 0 and v = 0.
+func millions(ops PluralOperands) bool {
+	return ops.V == 0 && ops.I != 0 && ops.I%1000000 == 0
+}
+
+// pluralRulesByLocale maps languages, and regions whose rule differs from
+// their language's, to their rules. Languages not listed use one/other.
+var pluralRulesByLocale = map[string]pluralRuleSet{}
+
+func init() {
+	for rule, locales := range map[*pluralRuleSet]string{
+		&pluralRuleOther:         "bo dz id ig ii ja jbo jv kde kea km ko lkt lo ms my nqo osa sah ses sg su th to tpi vi wo yo yue zh",
+		&pluralRuleOneOther:      "en de nl sv fi et gl fy ur sw ji yi sc scn lij",
+		&pluralRuleOneN:          "af an asa az bal bem bez bg brx ce cgg chr ckb dv ee el eo eu fo fur gsw ha haw hu jgo jmc ka kaj kcg kk kkj kl ks ksb ku ky lb lg mas mgo ml mn mr nah nb nd ne nn nnh no nr ny nyn om or os pap ps rm rof rwk saq sd sdh seh sn so sq ss ssy st syr ta te teo tig tk tn tr ts ug uz ve vo vun wae xh xog",
+		&pluralRuleDanish:        "da",
+		&pluralRuleOneZeroOrN:    "am as bn doi fa gu hi kn pcm zu",
+		&pluralRuleOneMany:       "it ca pt-pt vec",
+		&pluralRuleSpanish:       "es",
+		&pluralRuleFrench:        "fr pt",
+		&pluralRuleEastSlavic:    "ru uk",
+		&pluralRuleBelarusian:    "be",
+		&pluralRulePolish:        "pl",
+		&pluralRuleCzech:         "cs sk",
+		&pluralRuleSerboCroatian: "bs hr sh sr",
+		&pluralRuleSlovenian:     "sl",
+		&pluralRuleLithuanian:    "lt",
+		&pluralRuleLatvian:       "lv prg",
+		&pluralRuleRomanian:      "ro mo",
+		&pluralRuleArabic:        "ar ars",
+		&pluralRuleHebrew:        "he iw",
+		&pluralRuleIrish:         "ga",
+		&pluralRuleWelsh:         "cy",
+		&pluralRuleIcelandic:     "is",
+		&pluralRuleMacedonian:    "mk",
+		&pluralRuleFilipino:      "fil tl",
+	} {
+		for _, locale := range strings.Fields(locales) {
+			pluralRulesByLocale[locale] = *rule
+		}
+	}
+}
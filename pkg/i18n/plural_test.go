@@ -0,0 +1,116 @@
+package i18n
+
+import "testing"
+
+// Expected categories follow the CLDR 44 cardinal rules and examples.
+func TestPluralCategoryFor(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   map[any]PluralCategory
+	}{
+		{"en", map[any]PluralCategory{0: PluralOther, 1: PluralOne, 2: PluralOther, "1.0": PluralOther, 1.5: PluralOther}},
+		{"en_US", map[any]PluralCategory{1: PluralOne, 2: PluralOther}},
+		{"ja", map[any]PluralCategory{0: PluralOther, 1: PluralOther, 2: PluralOther}},
+		{"tr", map[any]PluralCategory{1: PluralOne, "1.0": PluralOne, 2: PluralOther}},
+		{"da", map[any]PluralCategory{0: PluralOther, 1: PluralOne, "0.5": PluralOne, "1.0": PluralOne, 2: PluralOther}},
+		{"hi", map[any]PluralCategory{0: PluralOne, 1: PluralOne, "0.5": PluralOne, 2: PluralOther}},
+		{"fr", map[any]PluralCategory{0: PluralOne, 1: PluralOne, "1.5": PluralOne, 2: PluralOther, 1000000: PluralMany, 2000000: PluralMany, 1000001: PluralOther}},
+		{"es", map[any]PluralCategory{0: PluralOther, 1: PluralOne, "1.0": PluralOne, 2: PluralOther, 1000000: PluralMany}},
+		{"it", map[any]PluralCategory{0: PluralOther, 1: PluralOne, "1.0": PluralOther, 1000000: PluralMany}},
+		{"pt", map[any]PluralCategory{0: PluralOne, 1: PluralOne, "1.5": PluralOne, 2: PluralOther, 1000000: PluralMany}},
+		{"pt-BR", map[any]PluralCategory{0: PluralOne, 1: PluralOne, "1.5": PluralOne, 2: PluralOther}},
+		{"pt-PT", map[any]PluralCategory{0: PluralOther, 1: PluralOne, "1.5": PluralOther, 2: PluralOther, 1000000: PluralMany}},
+		{"pt_pt", map[any]PluralCategory{0: PluralOther, 1: PluralOne}},
+		{"ru", map[any]PluralCategory{0: PluralMany, 1: PluralOne, 2: PluralFew, 5: PluralMany, 11: PluralMany, 12: PluralMany, 21: PluralOne, 22: PluralFew, 111: PluralMany, "1.5": PluralOther}},
+		{"uk-UA", map[any]PluralCategory{1: PluralOne, 3: PluralFew, 14: PluralMany}},
+		{"be", map[any]PluralCategory{1: PluralOne, "1.0": PluralOne, 2: PluralFew, 5: PluralMany, 11: PluralMany, "1.5": PluralOther}},
+		{"pl", map[any]PluralCategory{0: PluralMany, 1: PluralOne, 2: PluralFew, 5: PluralMany, 12: PluralMany, 21: PluralMany, 22: PluralFew, "1.5": PluralOther}},
+		{"cs", map[any]PluralCategory{0: PluralOther, 1: PluralOne, 2: PluralFew, 4: PluralFew, 5: PluralOther, "1.5": PluralMany}},
+		{"sr", map[any]PluralCategory{1: PluralOne, 2: PluralFew, 5: PluralOther, 11: PluralOther, 21: PluralOne, "0.1": PluralOne, "0.2": PluralFew, "0.5": PluralOther}},
+		{"sl", map[any]PluralCategory{0: PluralOther, 1: PluralOne, 2: PluralTwo, 3: PluralFew, 4: PluralFew, 5: PluralOther, 101: PluralOne, 102: PluralTwo, "1.5": PluralFew}},
+		{"lt", map[any]PluralCategory{0: PluralOther, 1: PluralOne, 2: PluralFew, 9: PluralFew, 10: PluralOther, 11: PluralOther, 21: PluralOne, "1.5": PluralMany}},
+		{"lv", map[any]PluralCategory{0: PluralZero, 1: PluralOne, 2: PluralOther, 10: PluralZero, 11: PluralZero, 21: PluralOne, "0.1": PluralOne, "0.2": PluralOther}},
+		{"ro", map[any]PluralCategory{0: PluralFew, 1: PluralOne, 2: PluralFew, 19: PluralFew, 20: PluralOther, 101: PluralFew, "1.5": PluralFew}},
+		{"ar", map[any]PluralCategory{0: PluralZero, 1: PluralOne, 2: PluralTwo, 3: PluralFew, 10: PluralFew, 11: PluralMany, 99: PluralMany, 100: PluralOther, 102: PluralOther, 103: PluralFew, "1.5": PluralOther}},
+		{"he", map[any]PluralCategory{0: PluralOther, 1: PluralOne, 2: PluralTwo, 20: PluralOther, "0.5": PluralOne}},
+		{"ga", map[any]PluralCategory{1: PluralOne, 2: PluralTwo, 3: PluralFew, 6: PluralFew, 7: PluralMany, 10: PluralMany, 11: PluralOther}},
+		{"cy", map[any]PluralCategory{0: PluralZero, 1: PluralOne, 2: PluralTwo, 3: PluralFew, 4: PluralOther, 6: PluralMany, 7: PluralOther}},
+		{"is", map[any]PluralCategory{1: PluralOne, 2: PluralOther, 11: PluralOther, 21: PluralOne, "0.1": PluralOne}},
+		{"mk", map[any]PluralCategory{1: PluralOne, 2: PluralOther, 11: PluralOther, 21: PluralOne}},
+		{"fil", map[any]PluralCategory{1: PluralOne, 4: PluralOther, 5: PluralOne, 10: PluralOne, 14: PluralOther, "0.5": PluralOne, "0.4": PluralOther}},
+		{"xx", map[any]PluralCategory{1: PluralOne, 2: PluralOther, "1.0": PluralOther}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			categories := map[PluralCategory]bool{}
+			for _, category := range PluralCategories(tt.locale) {
+				categories[category] = true
+			}
+			for count, want := range tt.want {
+				if got := PluralCategoryFor(tt.locale, count); got != want {
+					t.Errorf("PluralCategoryFor(%q, %v) = %s, want %s", tt.locale, count, got, want)
+				}
+				if !categories[want] {
+					t.Errorf("PluralCategories(%q) is missing %s", tt.locale, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewPluralOperands(t *testing.T) {
+	tests := []struct {
+		count any
+		want  PluralOperands
+	}{
+		{1, PluralOperands{N: 1, I: 1}},
+		{-3, PluralOperands{N: 3, I: 3}},
+		{uint64(7), PluralOperands{N: 7, I: 7}},
+		{"1.50", PluralOperands{N: 1.5, I: 1, V: 2, F: 50, T: 5}},
+		{"1.0", PluralOperands{N: 1, I: 1, V: 1}},
+		{1.25, PluralOperands{N: 1.25, I: 1, V: 2, F: 25, T: 25}},
+		{".5", PluralOperands{N: 0.5, V: 1, F: 5, T: 5}},
+	}
+	for _, tt := range tests {
+		if got, ok := NewPluralOperands(tt.count); !ok || got != tt.want {
+			t.Errorf("NewPluralOperands(%v) = %+v, %v, want %+v", tt.count, got, ok, tt.want)
+		}
+	}
+	for _, count := range []any{"abc", "1e3", struct{}{}} {
+		if _, ok := NewPluralOperands(count); ok {
+			t.Errorf("NewPluralOperands(%v) accepted", count)
+		}
+	}
+}
+
+func TestTranslatePluralRegion(t *testing.T) {
+	tr := New(WithDefaultLocale("pt"))
+	tr.AddBundle("default", "pt", map[string]string{
+		"files.one":   "{{count}} ficheiro",
+		"files.other": "{{count}} ficheiros",
+	})
+
+	// A pt-PT reader of the pt bundle gets the pt-PT rule: 0 is other.
+	if got := tr.T("pt-PT", "files", map[string]any{"count": 0}); got != "0 ficheiros" {
+		t.Fatalf("pt-PT 0 = %q", got)
+	}
+	if got := tr.T("pt", "files", map[string]any{"count": 0}); got != "0 ficheiro" {
+		t.Fatalf("pt 0 = %q", got)
+	}
+	// A fallback in another language uses its own rule.
+	en := New(WithDefaultLocale("en"))
+	en.AddBundle("default", "en", map[string]string{"files.one": "{{count}} file", "files.other": "{{count}} files"})
+	if got := en.T("pt", "files", map[string]any{"count": 0}); got != "0 files" {
+		t.Fatalf("pt falling back to en, 0 = %q", got)
+	}
+
+	// A rule set for pt does not replace pt-PT's built-in one.
+	custom := New(WithDefaultLocale("pt"), WithPluralRule("pt", func(PluralOperands) PluralCategory { return PluralOne }))
+	custom.AddBundle("default", "pt", map[string]string{"files.one": "um", "files.other": "vários"})
+	if got := custom.T("pt-BR", "files", nil, 5); got != "um" {
+		t.Fatalf("pt-BR with a pt rule = %q", got)
+	}
+	if got := custom.T("pt-PT", "files", nil, 5); got != "vários" {
+		t.Fatalf("pt-PT with a pt rule = %q", got)
+	}
+}
@@ -0,0 +1,96 @@
+package i18n
+
+import (
+	"regexp"
+	"strings"
+)
+
+// WithPseudoLocalization makes T and Rich return pseudo-localized messages
+// for every locale (see Pseudolocalize), so truncated layouts and hard-coded
+// strings show up before real translations exist. Keys without a message
+// are returned unchanged so they stand out. Meant for development and test
+// environments only.
+func WithPseudoLocalization() Option {
+	return func(t *Translator) error {
+		t.pseudo = true
+		return nil
+	}
+}
+
+var pseudoAccents = func() map[rune]rune {
+	plain := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	accented := []rune("åƀçðéƒĝĥîĵķļɱñöþǫŕšţûṽŵẋýžÅƁÇÐÉƑĜĤÎĴĶĻṀÑÖÞǪŔŠŢÛṼŴẊÝŽ")
+	m := make(map[rune]rune, len(plain))
+	for i, r := range plain {
+		m[r] = accented[i]
+	}
+	return m
+}()
+
+// pseudoLinePrefix matches the markdown markup at the start of a line, which
+// must stay in front of the opening marker: indentation, headings, list
+// bullets and numbers.
+var pseudoLinePrefix = regexp.MustCompile(`^\s*(#{1,6}\s+|[-*+]\s+|\d+\.\s+)?`)
+
+// Pseudolocalize returns s with ASCII letters replaced by accented look-alikes,
+// every line wrapped in "[" and "]", and about 40% padding of "~" before the
+// closing marker, mimicking the longer text of most translations:
+//
+//	Pseudolocalize("Save changes") // "[Šåṽé çĥåñĝéš ~~~~~]"
+//
+// {{placeholders}}, backslash escapes, markdown line markup and link
+// destinations are left intact, so the result interpolates and renders like
+// the original.
+func Pseudolocalize(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		prefix := pseudoLinePrefix.FindString(line)
+		body, letters := pseudoBody(line[len(prefix):])
+		lines[i] = prefix + "[" + body + " " + strings.Repeat("~", letters*4/10+1) + "]"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// pseudoBody accents the letters of line, returning it and how many letters
+// it has.
+func pseudoBody(line string) (string, int) {
+	var b strings.Builder
+	letters := 0
+	for i := 0; i < len(line); {
+		rest := line[i:]
+		switch {
+		case strings.HasPrefix(rest, "{{"):
+			end := strings.Index(rest, "}}")
+			if end < 0 {
+				end = len(rest) - 2
+			}
+			b.WriteString(rest[:end+2])
+			i += end + 2
+			continue
+		case strings.HasPrefix(rest, "]("):
+			end := strings.IndexByte(rest, ')')
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			b.WriteString(rest[:end+1])
+			i += end + 1
+			continue
+		case rest[0] == '\\' && len(rest) > 1:
+			b.WriteString(rest[:2])
+			i += 2
+			continue
+		}
+		r := rune(line[i])
+		if accented, ok := pseudoAccents[r]; ok {
+			b.WriteRune(accented)
+			letters++
+		} else {
+			b.WriteByte(line[i])
+		}
+		i++
+	}
+	return b.String(), letters
+}
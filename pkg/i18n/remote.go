@@ -0,0 +1,507 @@
+package i18n
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotModified is returned by a BundleSource whose bundles have not changed
+// since its previous successful Load.
+var ErrNotModified = errors.New("i18n: bundles not modified")
+
+// DefaultRemoteTimeout bounds a single HTTPSource request when no client is
+// configured.
+const DefaultRemoteTimeout = 10 * time.Second
+
+// maxRemoteBundleSize caps a remote bundle document so a misbehaving endpoint
+// cannot exhaust memory.
+const maxRemoteBundleSize = 16 << 20
+
+// BundleSource loads bundles from outside the binary, e.g. the export API of
+// a translation management system or an object store.
+type BundleSource interface {
+	// Load returns the source's bundles as locale -> key -> message, with
+	// nested keys already flattened. It returns ErrNotModified when nothing
+	// changed since the previous successful Load.
+	Load(ctx context.Context) (map[string]map[string]string, error)
+}
+
+// BundleChange describes a reload that changed the messages of a domain.
+type BundleChange struct {
+	Domain string
+	// Locales lists the locales whose messages were added, changed or
+	// removed, sorted.
+	Locales []string
+}
+
+type remoteSource struct {
+	// mu serializes loads of the source, so an older load cannot be applied
+	// after a newer one.
+	mu       sync.Mutex
+	domain   string
+	source   BundleSource
+	interval time.Duration
+	// bundles holds the last successful load: locale -> key -> message.
+	bundles map[string]map[string]string
+}
+
+// WithRemoteSource layers the bundles of src over domain. Remote messages
+// win over bundles loaded from files or added with AddBundle, and keys the
+// source drops fall back to those local messages on the next reload.
+//
+// Nothing is fetched until Reload is called; StartRefresh then reloads the
+// source every interval. An interval of zero leaves refreshes to Reload.
+func WithRemoteSource(domain string, src BundleSource, interval time.Duration) Option {
+	return func(t *Translator) error {
+		if src == nil {
+			return errors.New("i18n: remote source is nil")
+		}
+		if domain == "" {
+			domain = "default"
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.local == nil {
+			t.local = copyStore(t.store)
+		}
+		t.remotes = append(t.remotes, &remoteSource{domain: domain, source: src, interval: interval})
+		return nil
+	}
+}
+
+// OnChange registers fn to be called after a reload changed the messages of
+// a domain, e.g. to purge rendered caches. fn runs on the reloading
+// goroutine after the new messages are visible. Call unsubscribe to remove
+// it.
+func (t *Translator) OnChange(fn func(BundleChange)) (unsubscribe func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.changeHandlers == nil {
+		t.changeHandlers = map[int]func(BundleChange){}
+	}
+	id := t.nextHandlerID
+	t.nextHandlerID++
+	t.changeHandlers[id] = fn
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.changeHandlers, id)
+	}
+}
+
+// Reload loads every remote source now and applies the bundles that
+// changed. A failing source keeps serving its previous bundles; the errors
+// of all failing sources are returned joined.
+func (t *Translator) Reload(ctx context.Context) error {
+	t.mu.RLock()
+	remotes := append([]*remoteSource(nil), t.remotes...)
+	t.mu.RUnlock()
+
+	var errs []error
+	for _, r := range remotes {
+		if err := t.reloadSource(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StartRefresh reloads every remote source with a positive interval in the
+// background until ctx is done. onError, when set, receives the errors of
+// failed refreshes; the source keeps serving its previous bundles.
+func (t *Translator) StartRefresh(ctx context.Context, onError func(error)) {
+	t.mu.RLock()
+	remotes := append([]*remoteSource(nil), t.remotes...)
+	t.mu.RUnlock()
+
+	for _, r := range remotes {
+		if r.interval <= 0 {
+			continue
+		}
+		go func(r *remoteSource) {
+			ticker := time.NewTicker(r.interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := t.reloadSource(ctx, r); err != nil && onError != nil && ctx.Err() == nil {
+						onError(err)
+					}
+				}
+			}
+		}(r)
+	}
+}
+
+// reloadSource loads r and, when its bundles differ, rebuilds its domain and
+// notifies the change handlers.
+func (t *Translator) reloadSource(ctx context.Context, r *remoteSource) error {
+	change, handlers, err := t.applySource(ctx, r)
+	if err != nil || len(change.Locales) == 0 {
+		return err
+	}
+	for _, fn := range handlers {
+		fn(change)
+	}
+	return nil
+}
+
+// applySource loads r and rebuilds its domain, returning the change and the
+// handlers to notify of it.
+func (t *Translator) applySource(ctx context.Context, r *remoteSource) (BundleChange, []func(BundleChange), error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bundles, err := r.source.Load(ctx)
+	if errors.Is(err, ErrNotModified) {
+		return BundleChange{}, nil, nil
+	}
+	if err != nil {
+		return BundleChange{}, nil, fmt.Errorf("i18n: reload %s: %w", r.domain, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	before := t.store[r.domain]
+	r.bundles = bundles
+	t.rebuildDomainLocked(r.domain)
+	changed := changedLocales(before, t.store[r.domain])
+	handlers := make([]func(BundleChange), 0, len(t.changeHandlers))
+	ids := make([]int, 0, len(t.changeHandlers))
+	for id := range t.changeHandlers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		handlers = append(handlers, t.changeHandlers[id])
+	}
+	return BundleChange{Domain: r.domain, Locales: changed}, handlers, nil
+}
+
+// rebuildDomainLocked replaces the served messages of domain with its local
+// bundles overlaid by the remote sources in registration order. Callers hold
+// t.mu.
+func (t *Translator) rebuildDomainLocked(domain string) {
+	merged := map[string]map[string]string{}
+	layers := []map[string]map[string]string{t.local[domain]}
+	for _, r := range t.remotes {
+		if r.domain == domain {
+			layers = append(layers, r.bundles)
+		}
+	}
+	for _, layer := range layers {
+		for locale, bundle := range layer {
+			if merged[locale] == nil {
+				merged[locale] = make(map[string]string, len(bundle))
+			}
+			for k, v := range bundle {
+				merged[locale][k] = v
+			}
+		}
+	}
+	if len(merged) == 0 {
+		delete(t.store, domain)
+		return
+	}
+	t.store[domain] = merged
+}
+
+// changedLocales returns the sorted locales whose messages differ between
+// before and after.
+func changedLocales(before, after map[string]map[string]string) []string {
+	var out []string
+	for locale, bundle := range after {
+		if !sameBundle(before[locale], bundle) {
+			out = append(out, locale)
+		}
+	}
+	for locale := range before {
+		if _, ok := after[locale]; !ok {
+			out = append(out, locale)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sameBundle(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+func copyStore(store map[string]map[string]map[string]string) map[string]map[string]map[string]string {
+	out := make(map[string]map[string]map[string]string, len(store))
+	for domain, locales := range store {
+		out[domain] = make(map[string]map[string]string, len(locales))
+		for locale, bundle := range locales {
+			out[domain][locale] = make(map[string]string, len(bundle))
+			for k, v := range bundle {
+				out[domain][locale][k] = v
+			}
+		}
+	}
+	return out
+}
+
+// HTTPSource loads bundles over HTTP with ETag caching: each document is
+// requested with If-None-Match and a 304 reuses the bundle parsed before, so
+// polling an unchanged export costs one round trip and no parsing.
+//
+// A URL containing {locale} is requested once per locale given with
+// WithSourceLocales and holds that locale's bundle. Any other URL holds a
+// document keyed by locale:
+//
+//	{"en": {"greeting": "Hello"}, "de": {"greeting": "Hallo"}}
+//
+// The format is taken from the Content-Type (JSON, YAML or TOML), then from
+// the URL's extension, and defaults to JSON.
+type HTTPSource struct {
+	resolve func(ctx context.Context, locale string) (string, error)
+	client  *http.Client
+	header  http.Header
+	locales []string
+	format  string
+
+	mu    sync.Mutex
+	cache map[string]cachedDocument // keyed by locale; "" for a multi-locale document
+	// pending is set when a document changed during a Load that failed, so
+	// the next Load reports the change even if every document answers 304.
+	pending bool
+}
+
+type cachedDocument struct {
+	etag    string
+	bundles map[string]map[string]string
+}
+
+// HTTPSourceOption customizes an HTTPSource.
+type HTTPSourceOption func(*HTTPSource)
+
+// WithSourceClient sets the HTTP client; the default times out after
+// DefaultRemoteTimeout.
+func WithSourceClient(client *http.Client) HTTPSourceOption {
+	return func(s *HTTPSource) {
+		if client != nil {
+			s.client = client
+		}
+	}
+}
+
+// WithSourceHeader adds a request header, e.g. the API token of a
+// translation management system.
+func WithSourceHeader(key, value string) HTTPSourceOption {
+	return func(s *HTTPSource) {
+		s.header.Add(key, value)
+	}
+}
+
+// WithSourceLocales sets the locales substituted into a {locale} URL.
+func WithSourceLocales(locales ...string) HTTPSourceOption {
+	return func(s *HTTPSource) {
+		s.locales = append([]string(nil), locales...)
+	}
+}
+
+// WithSourceFormat fixes the bundle format (".json", ".yaml", ".yml" or
+// ".toml") for endpoints that send a generic Content-Type.
+func WithSourceFormat(ext string) HTTPSourceOption {
+	return func(s *HTTPSource) {
+		s.format = strings.ToLower(ext)
+	}
+}
+
+// NewHTTPSource creates a source loading bundles from rawURL.
+func NewHTTPSource(rawURL string, opts ...HTTPSourceOption) *HTTPSource {
+	return newHTTPSource(func(_ context.Context, locale string) (string, error) {
+		return strings.ReplaceAll(rawURL, "{locale}", url.PathEscape(locale)), nil
+	}, strings.Contains(rawURL, "{locale}"), opts...)
+}
+
+// ObjectURLFunc returns a URL downloading the object key, e.g. a presigned
+// URL from a blob.Presigner.
+type ObjectURLFunc func(ctx context.Context, key string) (string, error)
+
+// NewObjectSource creates a source loading bundles from object storage
+// through the URLs objectURL returns, so buckets can stay private. key
+// follows the NewHTTPSource URL rules, e.g. "i18n/checkout/{locale}.json".
+// The store's ETag is used across presigned URLs, which change on every
+// load.
+func NewObjectSource(objectURL ObjectURLFunc, key string, opts ...HTTPSourceOption) *HTTPSource {
+	return newHTTPSource(func(ctx context.Context, locale string) (string, error) {
+		objectKey := strings.ReplaceAll(key, "{locale}", locale)
+		signed, err := objectURL(ctx, objectKey)
+		if err != nil {
+			return "", fmt.Errorf("presign %s: %w", objectKey, err)
+		}
+		return signed, nil
+	}, strings.Contains(key, "{locale}"), opts...)
+}
+
+func newHTTPSource(resolve func(context.Context, string) (string, error), perLocale bool, opts ...HTTPSourceOption) *HTTPSource {
+	s := &HTTPSource{
+		resolve: resolve,
+		client:  &http.Client{Timeout: DefaultRemoteTimeout},
+		header:  http.Header{},
+		cache:   map[string]cachedDocument{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if !perLocale {
+		s.locales = []string{""}
+	}
+	return s
+}
+
+// Load implements BundleSource. It returns ErrNotModified when every
+// document answered 304 Not Modified.
+func (s *HTTPSource) Load(ctx context.Context) (map[string]map[string]string, error) {
+	if len(s.locales) == 0 {
+		return nil, errors.New("url has {locale} but no locales were given")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := map[string]map[string]string{}
+	for _, locale := range s.locales {
+		doc, changed, err := s.fetch(ctx, locale)
+		s.pending = s.pending || changed
+		if err != nil {
+			return nil, err
+		}
+		for loc, bundle := range doc.bundles {
+			out[loc] = bundle
+		}
+	}
+	if !s.pending {
+		return nil, ErrNotModified
+	}
+	s.pending = false
+	return out, nil
+}
+
+// fetch loads the document of locale ("" for a multi-locale document),
+// reporting whether it differs from the cached one.
+func (s *HTTPSource) fetch(ctx context.Context, locale string) (cachedDocument, bool, error) {
+	target, err := s.resolve(ctx, locale)
+	if err != nil {
+		return cachedDocument{}, false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return cachedDocument{}, false, err
+	}
+	for k, vs := range s.header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	cached, ok := s.cache[locale]
+	if ok && cached.etag != "" {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return cachedDocument{}, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && ok {
+		return cached, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return cachedDocument{}, false, fmt.Errorf("%s: unexpected status %s", redactURL(req.URL), resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteBundleSize+1))
+	if err != nil {
+		return cachedDocument{}, false, err
+	}
+	if len(data) > maxRemoteBundleSize {
+		return cachedDocument{}, false, fmt.Errorf("%s: bundle exceeds %d bytes", redactURL(req.URL), maxRemoteBundleSize)
+	}
+
+	doc := cachedDocument{etag: resp.Header.Get("ETag")}
+	ext := s.formatOf(resp, req.URL)
+	if locale != "" {
+		bundle, err := parseBundle(ext, data)
+		if err != nil {
+			return cachedDocument{}, false, fmt.Errorf("%s: %w", redactURL(req.URL), err)
+		}
+		doc.bundles = map[string]map[string]string{locale: bundle}
+	} else if doc.bundles, err = parseLocaleDocument(ext, data); err != nil {
+		return cachedDocument{}, false, fmt.Errorf("%s: %w", redactURL(req.URL), err)
+	}
+	s.cache[locale] = doc
+	return doc, true, nil
+}
+
+// formatOf picks the bundle format of a response.
+func (s *HTTPSource) formatOf(resp *http.Response, u *url.URL) string {
+	if s.format != "" {
+		return s.format
+	}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		switch {
+		case strings.HasSuffix(mediaType, "json"):
+			return ".json"
+		case strings.HasSuffix(mediaType, "yaml"):
+			return ".yaml"
+		case strings.HasSuffix(mediaType, "toml"):
+			return ".toml"
+		}
+	}
+	if ext := strings.ToLower(path.Ext(u.Path)); isBundleExt(ext) {
+		return ext
+	}
+	return ".json"
+}
+
+// parseLocaleDocument decodes a document keyed by locale into flattened
+// bundles.
+func parseLocaleDocument(ext string, data []byte) (map[string]map[string]string, error) {
+	raw, err := decodeBundle(ext, data)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]map[string]string, len(raw))
+	for locale, v := range raw {
+		nested, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("locale %q must hold a map of messages", locale)
+		}
+		bundle := map[string]string{}
+		if err := flattenBundle("", nested, bundle); err != nil {
+			return nil, fmt.Errorf("locale %q: %w", locale, err)
+		}
+		out[locale] = bundle
+	}
+	return out, nil
+}
+
+// redactURL drops the query, which holds the signature of presigned URLs.
+func redactURL(u *url.URL) string {
+	clean := *u
+	clean.RawQuery = ""
+	clean.User = nil
+	return clean.String()
+}
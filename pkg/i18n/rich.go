@@ -0,0 +1,523 @@
+package i18n
+
+import (
+	"html"
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Format is the output format of a rich message.
+type Format string
+
+const (
+	// FormatText is plain text with markup removed (API payloads, SMS, push).
+	FormatText Format = "text"
+	// FormatMarkdown is the message source, with interpolated values escaped.
+	FormatMarkdown Format = "markdown"
+	// FormatHTML is sanitized HTML (email bodies, web views).
+	FormatHTML Format = "html"
+)
+
+// richSuffix marks the rich (markdown) variant of a key: "welcome.body.md" is
+// the rich form of "welcome.body", so JSON bundles can carry both.
+const richSuffix = ".md"
+
+// AddRich adds a markdown message for key into domain/locale.
+func (t *Translator) AddRich(domain, locale, key, markdown string) {
+	t.Add(domain, locale, key+richSuffix, markdown)
+}
+
+// Rich translates key and renders it in format. The rich variant (key.md)
+// is preferred; otherwise the plain message is used and escaped for the
+// format. Locale fallbacks and plural rules match T, and each locale is
+// searched for both variants before moving on, so a plain message in the
+// requested locale wins over a rich one in a fallback locale.
+//
+// Interpolated values are escaped as markdown before rendering, so data
+// cannot inject markup or links.
+func (t *Translator) Rich(locale, key string, data map[string]any, format Format, n ...int) string {
+	if locale == "" {
+		locale = t.defaultLocale
+	}
+	domain, k := splitDomain(key)
+
+	var msg string
+	var keys []string
+	found, rich := false, false
+	t.mu.RLock()
+	for i, loc := range t.searchLocales(locale) {
+		bundle := t.store[domain][loc]
+		keys = t.pluralKeys(pluralLocale(locale, loc), k, data, n)
+		if msg, found = findKey(bundle, keys, richSuffix); found {
+			rich = true
+		} else {
+			msg, found = findKey(bundle, keys, "")
+		}
+		if found {
+			if i > 0 {
+				t.recordMissing(domain, locale, k, keys, msg)
+			}
+			break
+		}
+	}
+	t.mu.RUnlock()
+
+	if !found {
+		t.recordMissing(domain, locale, k, keys, "")
+		msg = k
+	} else if t.pseudo {
+		msg = Pseudolocalize(msg)
+	}
+	if rich {
+		return RenderMarkdown(t.interpolate(locale, msg, data, EscapeMarkdown), format)
+	}
+	return renderPlain(t.interpolate(locale, msg, data, nil), format)
+}
+
+// Rich translates key in the bound locale and renders it in format; see Translator.Rich.
+func (l *Localizer) Rich(key string, data map[string]any, format Format, n ...int) string {
+	if l == nil || l.tr == nil {
+		_, k := splitDomain(key)
+		return renderPlain(interpolate(k, data, l.Locale()), format)
+	}
+	return l.tr.Rich(l.locale, key, data, format, n...)
+}
+
+// NegotiateFormat picks the format for an Accept header: text/html (or
+// application/xhtml+xml) selects HTML, text/markdown selects markdown, and
+// anything else, including an empty header, selects plain text. Quality
+// values are honored; ties go to the first listed type.
+func NegotiateFormat(accept string) Format {
+	best, bestQ := FormatText, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		var format Format
+		switch mediaType {
+		case "text/html", "application/xhtml+xml":
+			format = FormatHTML
+		case "text/markdown", "text/x-markdown":
+			format = FormatMarkdown
+		case "text/plain":
+			format = FormatText
+		default:
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	return best
+}
+
+// RenderMarkdown renders markdown source in format.
+func RenderMarkdown(src string, format Format) string {
+	switch format {
+	case FormatMarkdown:
+		return src
+	case FormatHTML:
+		return MarkdownToHTML(src)
+	default:
+		return MarkdownToText(src)
+	}
+}
+
+// renderPlain renders a plain (non-markdown) message in format.
+func renderPlain(msg string, format Format) string {
+	switch format {
+	case FormatMarkdown:
+		return EscapeMarkdown(msg)
+	case FormatHTML:
+		return strings.ReplaceAll(html.EscapeString(msg), "\n", "<br>\n")
+	default:
+		return msg
+	}
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`(`, `\(`, `)`, `\)`, `#`, `\#`, `+`, `\+`, `-`, `\-`, `.`, `\.`, `!`, `\!`, `<`, `\<`, `>`, `\>`,
+)
+
+// EscapeMarkdown backslash-escapes markdown syntax in s so it renders literally.
+func EscapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// The markdown subset supported by MarkdownToHTML and MarkdownToText:
+// paragraphs, ATX headings, "-"/"*"/"+" and numbered lists, **strong**,
+// *emphasis* / _emphasis_, `code`, [links](url), and backslash escapes.
+// Raw HTML is not passed through; it is escaped like any other text, which
+// is what keeps the output safe to embed.
+
+// MarkdownToHTML renders markdown to sanitized HTML. All text is escaped, and
+// links are kept only for http, https, mailto, and tel URLs or relative
+// references; other links (javascript:, data:, …) render as their text.
+func MarkdownToHTML(src string) string {
+	var b strings.Builder
+	for i, block := range parseMarkdownBlocks(src) {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		switch block.kind {
+		case mdHeading:
+			tag := "h" + strconv.Itoa(block.level)
+			b.WriteString("<" + tag + ">" + inlineHTML(parseInline(block.lines[0])) + "</" + tag + ">")
+		case mdParagraph:
+			b.WriteString("<p>" + inlineHTML(parseInline(strings.Join(block.lines, "\n"))) + "</p>")
+		case mdList:
+			tag := "ul"
+			if block.ordered {
+				tag = "ol"
+			}
+			b.WriteString("<" + tag + ">")
+			for _, item := range block.lines {
+				b.WriteString("<li>" + inlineHTML(parseInline(item)) + "</li>")
+			}
+			b.WriteString("</" + tag + ">")
+		}
+	}
+	return b.String()
+}
+
+// MarkdownToText renders markdown as plain text: markup is removed, list
+// items keep their bullets or numbers, and links read "text (url)".
+func MarkdownToText(src string) string {
+	var b strings.Builder
+	for i, block := range parseMarkdownBlocks(src) {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		switch block.kind {
+		case mdHeading:
+			b.WriteString(inlineText(parseInline(block.lines[0])))
+		case mdParagraph:
+			b.WriteString(inlineText(parseInline(strings.Join(block.lines, "\n"))))
+		case mdList:
+			for j, item := range block.lines {
+				if j > 0 {
+					b.WriteByte('\n')
+				}
+				if block.ordered {
+					b.WriteString(strconv.Itoa(j+1) + ". ")
+				} else {
+					b.WriteString("- ")
+				}
+				b.WriteString(inlineText(parseInline(item)))
+			}
+		}
+	}
+	return b.String()
+}
+
+type mdBlockKind int
+
+const (
+	mdParagraph mdBlockKind = iota
+	mdHeading
+	mdList
+)
+
+// mdBlock is a paragraph (lines), a heading (one line), or a list (one line per item).
+type mdBlock struct {
+	kind    mdBlockKind
+	level   int
+	ordered bool
+	lines   []string
+}
+
+var (
+	headingRe     = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?$`)
+	bulletItemRe  = regexp.MustCompile(`^\s{0,3}[-*+]\s+(.*)$`)
+	orderedItemRe = regexp.MustCompile(`^\s{0,3}\d{1,9}[.)]\s+(.*)$`)
+)
+
+func parseMarkdownBlocks(src string) []mdBlock {
+	var blocks []mdBlock
+	var cur *mdBlock
+	flush := func() {
+		if cur != nil {
+			blocks = append(blocks, *cur)
+			cur = nil
+		}
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if m := headingRe.FindStringSubmatch(line); m != nil {
+			flush()
+			blocks = append(blocks, mdBlock{kind: mdHeading, level: len(m[1]), lines: []string{m[2]}})
+			continue
+		}
+		bullet := bulletItemRe.FindStringSubmatch(line)
+		ordered := orderedItemRe.FindStringSubmatch(line)
+		if bullet != nil || ordered != nil {
+			isOrdered := ordered != nil
+			item := ""
+			if isOrdered {
+				item = ordered[1]
+			} else {
+				item = bullet[1]
+			}
+			if cur == nil || cur.kind != mdList || cur.ordered != isOrdered {
+				flush()
+				cur = &mdBlock{kind: mdList, ordered: isOrdered}
+			}
+			cur.lines = append(cur.lines, item)
+			continue
+		}
+		if cur != nil && cur.kind == mdList && (line[0] == ' ' || line[0] == '\t') {
+			// indented continuation of the last list item
+			cur.lines[len(cur.lines)-1] += "\n" + strings.TrimSpace(line)
+			continue
+		}
+		if cur == nil || cur.kind != mdParagraph {
+			flush()
+			cur = &mdBlock{kind: mdParagraph}
+		}
+		cur.lines = append(cur.lines, strings.TrimSpace(line))
+	}
+	flush()
+	return blocks
+}
+
+type mdInlineKind int
+
+const (
+	mdText mdInlineKind = iota
+	mdCode
+	mdStrong
+	mdEmphasis
+	mdLink
+)
+
+// mdInline is literal text or code (text), strong/emphasis (children), or a
+// link (children and href).
+type mdInline struct {
+	kind     mdInlineKind
+	text     string
+	children []mdInline
+}
+
+func parseInline(s string) []mdInline {
+	var nodes []mdInline
+	var text strings.Builder
+	emit := func(node mdInline) {
+		if text.Len() > 0 {
+			nodes = append(nodes, mdInline{kind: mdText, text: text.String()})
+			text.Reset()
+		}
+		nodes = append(nodes, node)
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			text.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end > 0 {
+				emit(mdInline{kind: mdCode, text: s[i+1 : i+1+end]})
+				i += end + 2
+				continue
+			}
+		case (c == '*' || c == '_') && strings.HasPrefix(s[i:], string([]byte{c, c})):
+			delim := s[i : i+2]
+			if end := findCloser(s, i+2, delim); end > i+2 {
+				emit(mdInline{kind: mdStrong, children: parseInline(s[i+2 : end])})
+				i = end + 2
+				continue
+			}
+		case c == '*' || c == '_':
+			if c == '_' && i > 0 && isWordByte(s[i-1]) {
+				break
+			}
+			if end := findCloser(s, i+1, string(c)); end > i+1 && (c == '*' || end+1 >= len(s) || !isWordByte(s[end+1])) {
+				emit(mdInline{kind: mdEmphasis, children: parseInline(s[i+1 : end])})
+				i = end + 1
+				continue
+			}
+		case c == '[':
+			if label, href, next, ok := parseLink(s, i); ok {
+				emit(mdInline{kind: mdLink, text: href, children: parseInline(label)})
+				i = next
+				continue
+			}
+		}
+		text.WriteByte(c)
+		i++
+	}
+	if text.Len() > 0 {
+		nodes = append(nodes, mdInline{kind: mdText, text: text.String()})
+	}
+	return nodes
+}
+
+// findCloser returns the index of the next unescaped delim at or after from,
+// or -1. The delimited content may not start or end with whitespace.
+func findCloser(s string, from int, delim string) int {
+	if from >= len(s) || s[from] == ' ' || s[from] == '\n' {
+		return -1
+	}
+	for i := from; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], delim) && s[i-1] != ' ' && s[i-1] != '\n' {
+			if len(delim) == 1 && i+1 < len(s) && s[i+1] == delim[0] {
+				// part of a strong delimiter; skip it
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// parseLink parses "[label](href)" at s[i], returning the index after it.
+func parseLink(s string, i int) (label, href string, next int, ok bool) {
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				if j+1 >= len(s) || s[j+1] != '(' {
+					return "", "", 0, false
+				}
+				end := closingParen(s, j+2)
+				if end < 0 {
+					return "", "", 0, false
+				}
+				return s[i+1 : j], unescapeMarkdown(strings.TrimSpace(s[j+2 : end])), end + 1, true
+			}
+		}
+	}
+	return "", "", 0, false
+}
+
+// closingParen returns the index of the ")" closing a link destination that
+// starts at from, allowing balanced and escaped parentheses inside it.
+func closingParen(s string, from int) int {
+	depth := 0
+	for i := from; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case '\n':
+			return -1
+		}
+	}
+	return -1
+}
+
+func unescapeMarkdown(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// safeHref reports whether href may be rendered as a link: http, https,
+// mailto, and tel URLs, and relative references.
+func safeHref(href string) bool {
+	if href == "" {
+		return false
+	}
+	colon := strings.IndexByte(href, ':')
+	if colon < 0 || strings.ContainsAny(href[:colon], "/?#") {
+		return true
+	}
+	switch strings.ToLower(href[:colon]) {
+	case "http", "https", "mailto", "tel":
+		return true
+	}
+	return false
+}
+
+func inlineHTML(nodes []mdInline) string {
+	return inlineHTMLIn(nodes, false)
+}
+
+// inlineHTMLIn renders nodes; inside a link, nested links render as their
+// text, since HTML does not allow an <a> within another.
+func inlineHTMLIn(nodes []mdInline, inLink bool) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		switch node.kind {
+		case mdText:
+			b.WriteString(strings.ReplaceAll(html.EscapeString(node.text), "\n", "<br>\n"))
+		case mdCode:
+			b.WriteString("<code>" + html.EscapeString(node.text) + "</code>")
+		case mdStrong:
+			b.WriteString("<strong>" + inlineHTMLIn(node.children, inLink) + "</strong>")
+		case mdEmphasis:
+			b.WriteString("<em>" + inlineHTMLIn(node.children, inLink) + "</em>")
+		case mdLink:
+			if inLink || !safeHref(node.text) {
+				b.WriteString(inlineHTMLIn(node.children, inLink))
+				continue
+			}
+			b.WriteString(`<a href="` + html.EscapeString(node.text) + `">` + inlineHTMLIn(node.children, true) + "</a>")
+		}
+	}
+	return b.String()
+}
+
+func inlineText(nodes []mdInline) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		switch node.kind {
+		case mdText, mdCode:
+			b.WriteString(node.text)
+		case mdStrong, mdEmphasis:
+			b.WriteString(inlineText(node.children))
+		case mdLink:
+			label := inlineText(node.children)
+			b.WriteString(label)
+			if safeHref(node.text) && node.text != label {
+				b.WriteString(" (" + node.text + ")")
+			}
+		}
+	}
+	return b.String()
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isWordByte(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
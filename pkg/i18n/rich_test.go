@@ -0,0 +1,76 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownToHTMLSanitizes(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"javascript url", "[x](javascript:alert(1))", "<p>x</p>"},
+		{"mixed-case scheme", "[x](JaVaScRiPt:alert(1))", "<p>x</p>"},
+		{"leading space", "[x]( javascript:alert(1))", "<p>x</p>"},
+		{"tab in scheme", "[x](java\tscript:alert(1))", "<p>x</p>"},
+		{"control character", "[x](\x01javascript:alert(1))", "<p>x</p>"},
+		{"data url", "[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>x</p>"},
+		{"vbscript url", "[x](vbscript:msgbox)", "<p>x</p>"},
+		{"entity-encoded colon", "[x](javascript&#58;alert(1))", `<p><a href="javascript&amp;#58;alert(1)">x</a></p>`},
+		{"attribute injection", `[x](https://ok.example/"onmouseover="alert(1))`, `<p><a href="https://ok.example/&#34;onmouseover=&#34;alert(1)">x</a></p>`},
+		{"query string", "[x](https://ok.example/?a=1&b=<2>)", `<p><a href="https://ok.example/?a=1&amp;b=&lt;2&gt;">x</a></p>`},
+		{"mailto", "[x](mailto:a@b.c)", `<p><a href="mailto:a@b.c">x</a></p>`},
+		{"relative", "[x](/settings#billing)", `<p><a href="/settings#billing">x</a></p>`},
+		{"script tag", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{"event handler", "<img src=x onerror=alert(1)>", "<p>&lt;img src=x onerror=alert(1)&gt;</p>"},
+		{"raw anchor", `<a href="javascript:alert(1)">x</a>`, "<p>&lt;a href=&#34;javascript:alert(1)&#34;&gt;x&lt;/a&gt;</p>"},
+		{"tag inside strong", "**<b>bold</b>**", "<p><strong>&lt;b&gt;bold&lt;/b&gt;</strong></p>"},
+		{"tag inside code", "`<script>`", "<p><code>&lt;script&gt;</code></p>"},
+		{"unsafe link nested in link", "[**[y](javascript:alert(1))**](https://ok.example)", `<p><a href="https://ok.example"><strong>y</strong></a></p>`},
+		{"safe link nested in link", "[a [b](https://other.example)](https://ok.example)", `<p><a href="https://ok.example">a b</a></p>`},
+		{"heading and list", "# <i>t</i>\n\n- [x](javascript:1)", "<h1>&lt;i&gt;t&lt;/i&gt;</h1>\n<ul><li>x</li></ul>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarkdownToHTML(tt.src); got != tt.want {
+				t.Fatalf("MarkdownToHTML(%q) =\n%s\nwant\n%s", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRichEscapesInterpolatedValues(t *testing.T) {
+	tr := New()
+	tr.AddRich("", "en", "welcome", "Hello **{{name}}**, see [the docs]({{url}})")
+	tr.Add("", "en", "plain", "Hi {{name}}")
+
+	got := tr.Rich("en", "welcome", map[string]any{
+		"name": "[x](javascript:alert(1)) <script>",
+		"url":  "javascript:alert(1)",
+	}, FormatHTML)
+	want := "<p>Hello <strong>[x](javascript:alert(1)) &lt;script&gt;</strong>, see the docs</p>"
+	if got != want {
+		t.Fatalf("Rich html =\n%s\nwant\n%s", got, want)
+	}
+	if strings.Contains(got, "<a ") {
+		t.Fatalf("interpolated value produced a link: %s", got)
+	}
+
+	got = tr.Rich("en", "plain", map[string]any{"name": "<img src=x onerror=alert(1)>"}, FormatHTML)
+	if got != "Hi &lt;img src=x onerror=alert(1)&gt;" {
+		t.Fatalf("plain message html = %s", got)
+	}
+	if got := tr.Rich("en", "plain", map[string]any{"name": "*bold* [x](y)"}, FormatMarkdown); got != `Hi \*bold\* \[x\]\(y\)` {
+		t.Fatalf("plain message markdown = %s", got)
+	}
+}
+
+func TestMarkdownToText(t *testing.T) {
+	got := MarkdownToText("# Title\n\nSee **[docs](https://ok.example)** and [x](javascript:alert(1)).\n\n1. one\n2. two")
+	want := "Title\n\nSee docs (https://ok.example) and x.\n\n1. one\n2. two"
+	if got != want {
+		t.Fatalf("MarkdownToText =\n%q\nwant\n%q", got, want)
+	}
+}
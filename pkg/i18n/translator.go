@@ -2,14 +2,12 @@ package i18n
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
-	"strconv"
 	"strings"
 	"sync"
 
@@ -21,8 +19,18 @@ type Translator struct {
 	mu            sync.RWMutex
 	defaultLocale string
 	fallbacks     []string
+	pseudo        bool
+	missing       *missingTracker
+	pluralRules   map[string]PluralRule
+	formatFuncs   map[string]FormatFunc
 	// store: domain -> locale -> key -> message
 	store map[string]map[string]map[string]string
+	// local holds the bundles added in-process once a remote source is
+	// registered; store is then local overlaid by the remote bundles.
+	local          map[string]map[string]map[string]string
+	remotes        []*remoteSource
+	changeHandlers map[int]func(BundleChange)
+	nextHandlerID  int
 }
 
 // Option customizes Translator on creation.
@@ -59,6 +67,7 @@ func WithFallbackLocales(locales ...string) Option {
 }
 
 // WithJSONDir loads messages from a directory with files named <locale>.json into a domain.
+// Nested objects are flattened to dotted keys. Use WithDir to load YAML and TOML too.
 func WithJSONDir(domain, dir string) Option {
 	return func(t *Translator) error {
 		entries, err := os.ReadDir(dir)
@@ -83,13 +92,14 @@ func WithJSONDir(domain, dir string) Option {
 }
 
 // LoadJSONFile loads a key->message map from a JSON file into domain/locale.
+// Nested objects are flattened to dotted keys, as in LoadFile.
 func (t *Translator) LoadJSONFile(domain, locale, path string) error {
 	b, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
-	m := map[string]string{}
-	if err := json.Unmarshal(b, &m); err != nil {
+	m, err := parseBundle(".json", b)
+	if err != nil {
 		return err
 	}
 	t.AddBundle(domain, locale, m)
@@ -103,14 +113,23 @@ func (t *Translator) AddBundle(domain, locale string, bundle map[string]string)
 	}
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	if _, ok := t.store[domain]; !ok {
-		t.store[domain] = make(map[string]map[string]string)
+	if t.local != nil {
+		mergeBundle(t.local, domain, locale, bundle)
+		t.rebuildDomainLocked(domain)
+		return
+	}
+	mergeBundle(t.store, domain, locale, bundle)
+}
+
+func mergeBundle(store map[string]map[string]map[string]string, domain, locale string, bundle map[string]string) {
+	if _, ok := store[domain]; !ok {
+		store[domain] = make(map[string]map[string]string)
 	}
-	if _, ok := t.store[domain][locale]; !ok {
-		t.store[domain][locale] = make(map[string]string)
+	if _, ok := store[domain][locale]; !ok {
+		store[domain][locale] = make(map[string]string)
 	}
 	for k, v := range bundle {
-		t.store[domain][locale][k] = v
+		store[domain][locale][k] = v
 	}
 }
 
@@ -152,92 +171,115 @@ func splitDomain(key string) (domain, k string) {
 	return "default", key
 }
 
-var placeholderRe = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_\.]+)\s*\}\}`)
+var placeholderRe = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_\.]+)((?:\s+(?:"[^"]*"|[a-zA-Z0-9_\.]+))*)\s*\}\}`)
 
 // T translates a key for a locale with optional data and pluralization.
-// If data contains a numeric "count" (or n provided), it tries key.one / key.other.
+// If data contains a numeric "count" (or n provided), it tries the CLDR plural
+// category of the count in each locale searched (key.one, key.few, ...), then
+// key.one / key.other.
 func (t *Translator) T(locale, key string, data map[string]any, n ...int) string {
+	msg, _ := t.Translate(locale, key, data, n...)
+	return msg
+}
+
+// Translate is T reporting whether any locale searched had key. When none
+// did, it returns the key itself, like T, and false, so callers can keep a
+// message of their own instead.
+func (t *Translator) Translate(locale, key string, data map[string]any, n ...int) (string, bool) {
 	if locale == "" {
 		locale = t.defaultLocale
 	}
 	domain, k := splitDomain(key)
-	// resolve plural variant
-	count := -1
-	if len(n) > 0 {
-		count = n[0]
-	} else if v, ok := data["count"]; ok {
-		switch vv := v.(type) {
-		case int:
-			count = vv
-		case int64:
-			count = int(vv)
-		case float64:
-			count = int(vv)
-		case string:
-			if i, err := strconv.Atoi(vv); err == nil {
-				count = i
-			}
-		}
-	}
-	keys := []string{k}
-	if count >= 0 {
-		if count == 1 {
-			keys = append([]string{k + ".one"}, keys...)
-		} else {
-			keys = append([]string{k + ".other"}, keys...)
-		}
-	}
-
-	// locales search order: requested -> fallbacks -> default
-	locales := append([]string{locale}, t.fallbacks...)
-	if t.defaultLocale != "" {
-		locales = append(locales, t.defaultLocale)
-	}
 
 	var msg string
+	var keys []string
 	found := false
 	t.mu.RLock()
-	for _, loc := range locales {
-		bundle := t.store[domain][loc]
-		if bundle == nil {
-			continue
-		}
-		for _, kk := range keys {
-			if v, ok := bundle[kk]; ok {
-				msg = v
-				found = true
-				break
+	for i, loc := range t.searchLocales(locale) {
+		keys = t.pluralKeys(pluralLocale(locale, loc), k, data, n)
+		if msg, found = findKey(t.store[domain][loc], keys, ""); found {
+			if i > 0 {
+				t.recordMissing(domain, locale, k, keys, msg)
 			}
-		}
-		if found {
 			break
 		}
 	}
 	t.mu.RUnlock()
 
 	if !found {
+		t.recordMissing(domain, locale, k, keys, "")
 		// fallback to key itself
 		msg = k
+	} else if t.pseudo {
+		msg = Pseudolocalize(msg)
 	}
 	if len(data) == 0 {
-		return msg
+		return msg, found
+	}
+	return t.interpolate(locale, msg, data, nil), found
+}
+
+// searchLocales returns the locale search order: requested -> fallbacks -> default.
+func (t *Translator) searchLocales(locale string) []string {
+	locales := append([]string{locale}, t.fallbacks...)
+	if t.defaultLocale != "" {
+		locales = append(locales, t.defaultLocale)
 	}
-	return interpolate(msg, data)
+	return locales
 }
 
-func interpolate(template string, data map[string]any) string {
+// findKey returns the first of keys (with suffix appended) present in bundle.
+func findKey(bundle map[string]string, keys []string, suffix string) (string, bool) {
+	for _, k := range keys {
+		if v, ok := bundle[k+suffix]; ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// interpolate substitutes the placeholders of template in locale, with the
+// translator's format functions.
+func (t *Translator) interpolate(locale, template string, data map[string]any, escape func(string) string) string {
+	funcs := t.formatFuncs
+	if funcs == nil {
+		funcs = builtinFormatFuncs
+	}
+	return interpolateWith(template, data, locale, funcs, escape)
+}
+
+func interpolate(template string, data map[string]any, locale string) string {
+	return interpolateWith(template, data, locale, builtinFormatFuncs, nil)
+}
+
+// interpolateWith replaces {{name}} with data values and {{fn args}} with
+// the output of funcs[fn] in locale, passing each substituted value through
+// escape, when set. Placeholders that cannot be resolved are kept.
+func interpolateWith(template string, data map[string]any, locale string, funcs map[string]FormatFunc, escape func(string) string) string {
 	return placeholderRe.ReplaceAllStringFunc(template, func(m string) string {
 		sub := placeholderRe.FindStringSubmatch(m)
-		if len(sub) != 2 {
+		if len(sub) != 3 {
 			return m
 		}
 		key := sub[1]
-		// dot path support: a.b -> data[a][b]
-		cur, ok := dig(data, key)
-		if !ok {
-			return m
+		var out string
+		if strings.TrimSpace(sub[2]) != "" {
+			var ok bool
+			if out, ok = callFormatFunc(funcs, locale, key, sub[2], data); !ok {
+				return m
+			}
+		} else {
+			// dot path support: a.b -> data[a][b]
+			cur, ok := dig(data, key)
+			if !ok {
+				return m
+			}
+			out = fmt.Sprint(cur)
 		}
-		return fmt.Sprint(cur)
+		if escape != nil {
+			return escape(out)
+		}
+		return out
 	})
 }
 
@@ -307,6 +349,9 @@ func ContextWithLocale(ctx context.Context, locale string) context.Context {
 
 // LocaleFromContext returns the stored locale or empty.
 func LocaleFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
 	if v := ctx.Value(localeCtxKey); v != nil {
 		if s, ok := v.(string); ok {
 			return s
@@ -327,7 +372,8 @@ func defaultGinDetectOptions() GinDetectOptions {
 	return GinDetectOptions{QueryParam: "lang", HeaderName: "Accept-Language", CookieName: "lang"}
 }
 
-// GinMiddleware detects locale and stores it into context for handlers.
+// GinMiddleware detects locale and stores it, together with the translator,
+// into context for handlers; use FromContext to translate in that locale.
 func (t *Translator) GinMiddleware(opts ...GinDetectOptions) gin.HandlerFunc {
 	var o GinDetectOptions
 	if len(opts) > 0 {
@@ -350,7 +396,8 @@ func (t *Translator) GinMiddleware(opts ...GinDetectOptions) gin.HandlerFunc {
 			loc = t.defaultLocale
 		}
 		// attach to gin.Context (std context + gin keys)
-		c.Request = c.Request.WithContext(ContextWithLocale(c.Request.Context(), loc))
+		ctx := ContextWithTranslator(c.Request.Context(), t)
+		c.Request = c.Request.WithContext(ContextWithLocale(ctx, loc))
 		c.Set(string(localeCtxKey), loc)
 		c.Next()
 	}
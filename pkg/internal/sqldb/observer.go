@@ -0,0 +1,263 @@
+// Package sqldb holds the GORM query observer, connection pool settings and
+// migration logger shared by pkg/postgres and pkg/mysql, which differ only in
+// how they redact SQL and which attributes they add.
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/observability"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// DefaultSlowQueryThreshold is the duration above which an Observer logs a
+// query as slow.
+const DefaultSlowQueryThreshold = 200 * time.Millisecond
+
+// Span and metric attribute keys recorded by Observer, alongside
+// observability.AttrDBOperation and observability.AttrDBStatement.
+var (
+	AttrDBTable = attribute.Key("db.sql.table")
+	AttrDBName  = attribute.Key("db.name")
+	attrRows    = attribute.Key("db.rows_affected")
+	attrError   = attribute.Key("error.type")
+)
+
+// ObserverConfig configures an Observer.
+type ObserverConfig struct {
+	// Database is recorded as db.name and peer.service.
+	Database string
+	// Tracer defaults to the global tracer provider's.
+	Tracer trace.Tracer
+	// Meter defaults to the global meter provider's.
+	Meter metric.Meter
+	// Logger receives slow query logs; nil uses the standard library logger.
+	Logger logger.LogManager
+	// SlowThreshold defaults to DefaultSlowQueryThreshold; negative disables.
+	SlowThreshold time.Duration
+}
+
+// Dialect is what an Observer needs to know about the database it watches.
+type Dialect struct {
+	// Plugin is the GORM plugin name, also prefixing the callback names.
+	Plugin string
+	// System is recorded as db.system and names spans until the operation
+	// is known.
+	System string
+	// Scope is the instrumentation scope of the default tracer and meter.
+	Scope string
+	// LogPrefix starts slow query lines written to the standard library
+	// logger, e.g. "[MySQL]".
+	LogPrefix string
+	// Redact strips literal values from a statement before it is recorded.
+	Redact func(query string) string
+	// Attributes, when set, adds span and metric attributes for the
+	// statement context.
+	Attributes func(ctx context.Context) []attribute.KeyValue
+	// LogFields, when set, adds key-value pairs to slow query logs.
+	LogFields func(ctx context.Context) []any
+}
+
+// Observer is a GORM plugin that runs every statement in a client span with
+// the redacted SQL as db.statement, records db.client.operation.duration and
+// db.client.operation.errors, and logs slow queries.
+type Observer struct {
+	cfg      ObserverConfig
+	dialect  Dialect
+	duration metric.Float64Histogram
+	errors   metric.Int64Counter
+}
+
+// NewObserver creates an Observer for dialect. If the meter cannot create an
+// instrument, the error goes to the global OpenTelemetry error handler and
+// that instrument is not recorded; spans and slow query logs still work.
+func NewObserver(cfg ObserverConfig, dialect Dialect) *Observer {
+	if cfg.Tracer == nil {
+		cfg.Tracer = otel.Tracer(dialect.Scope)
+	}
+	if cfg.Meter == nil {
+		cfg.Meter = otel.Meter(dialect.Scope)
+	}
+	if cfg.SlowThreshold == 0 {
+		cfg.SlowThreshold = DefaultSlowQueryThreshold
+	}
+
+	o := &Observer{cfg: cfg, dialect: dialect}
+	var err error
+	if o.duration, err = cfg.Meter.Float64Histogram("db.client.operation.duration",
+		metric.WithDescription("Duration of database queries"), metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10)); err != nil {
+		otel.Handle(fmt.Errorf("create db.client.operation.duration: %w", err))
+		o.duration = nil
+	}
+	if o.errors, err = cfg.Meter.Int64Counter("db.client.operation.errors",
+		metric.WithDescription("Failed database queries"), metric.WithUnit("{operation}")); err != nil {
+		otel.Handle(fmt.Errorf("create db.client.operation.errors: %w", err))
+		o.errors = nil
+	}
+	return o
+}
+
+// Tracer returns the tracer spans are started with.
+func (o *Observer) Tracer() trace.Tracer { return o.cfg.Tracer }
+
+// Name implements gorm.Plugin.
+func (o *Observer) Name() string { return o.dialect.Plugin }
+
+// Initialize implements gorm.Plugin.
+func (o *Observer) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	registrations := []struct {
+		name   string
+		before func(string, func(*gorm.DB)) error
+		after  func(string, func(*gorm.DB)) error
+	}{
+		{"gorm:create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"gorm:query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"gorm:update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"gorm:delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"gorm:row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"gorm:raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+	for _, r := range registrations {
+		if err := r.before(o.Name()+":before_"+r.name, o.before); err != nil {
+			return fmt.Errorf("register query observer before %s: %w", r.name, err)
+		}
+		if err := r.after(o.Name()+":after_"+r.name, o.after); err != nil {
+			return fmt.Errorf("register query observer after %s: %w", r.name, err)
+		}
+	}
+	return nil
+}
+
+func (o *Observer) startKey() string  { return o.dialect.Plugin + ":start" }
+func (o *Observer) spanKey() string   { return o.dialect.Plugin + ":span" }
+func (o *Observer) parentKey() string { return o.dialect.Plugin + ":parent" }
+
+// before starts the span and binds it to the statement context, so
+// annotations added by later callbacks point at the query span. after
+// restores the caller's context, since a statement may be reused for
+// further queries.
+func (o *Observer) before(db *gorm.DB) {
+	stmt := db.Statement
+	if stmt == nil || db.DryRun {
+		return
+	}
+	parent := stmt.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, span := o.cfg.Tracer.Start(parent, o.dialect.System, trace.WithSpanKind(trace.SpanKindClient))
+	stmt.Context = ctx
+	db.InstanceSet(o.parentKey(), parent)
+	db.InstanceSet(o.spanKey(), span)
+	db.InstanceSet(o.startKey(), time.Now())
+}
+
+func (o *Observer) after(db *gorm.DB) {
+	stmt := db.Statement
+	if stmt == nil {
+		return
+	}
+	startValue, ok := db.InstanceGet(o.startKey())
+	if !ok {
+		return
+	}
+	elapsed := time.Since(startValue.(time.Time))
+	spanValue, _ := db.InstanceGet(o.spanKey())
+	span, _ := spanValue.(trace.Span)
+
+	statement := o.dialect.Redact(stmt.SQL.String())
+	operation := Operation(statement)
+	failed := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
+
+	attrs := []attribute.KeyValue{observability.AttrDBSystem.String(o.dialect.System)}
+	if operation != "" {
+		attrs = append(attrs, observability.AttrDBOperation.String(operation))
+	}
+	if stmt.Table != "" {
+		attrs = append(attrs, AttrDBTable.String(stmt.Table))
+	}
+	if o.dialect.Attributes != nil {
+		attrs = append(attrs, o.dialect.Attributes(stmt.Context)...)
+	}
+	if o.cfg.Database != "" {
+		attrs = append(attrs, AttrDBName.String(o.cfg.Database))
+	}
+	metricAttrs := attrs
+	if failed {
+		metricAttrs = append(append([]attribute.KeyValue{}, attrs...), attrError.String(fmt.Sprintf("%T", db.Error)))
+	}
+
+	ctx := stmt.Context
+	if o.duration != nil {
+		o.duration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(metricAttrs...))
+	}
+	if failed && o.errors != nil {
+		o.errors.Add(ctx, 1, metric.WithAttributes(metricAttrs...))
+	}
+
+	if span != nil {
+		name := operation
+		if stmt.Table != "" {
+			name = strings.TrimSpace(name + " " + stmt.Table)
+		}
+		if name != "" {
+			span.SetName(name)
+		}
+		span.SetAttributes(attrs...)
+		span.SetAttributes(observability.AttrDBStatement.String(statement), attrRows.Int64(db.RowsAffected))
+		if o.cfg.Database != "" {
+			span.SetAttributes(observability.AttrPeerService.String(o.cfg.Database))
+		}
+		if failed {
+			span.RecordError(db.Error)
+			span.SetStatus(codes.Error, db.Error.Error())
+		}
+		span.End()
+	}
+
+	if o.cfg.SlowThreshold > 0 && elapsed >= o.cfg.SlowThreshold {
+		o.logSlow(ctx, elapsed, statement, db.RowsAffected)
+	}
+	if parent, ok := db.InstanceGet(o.parentKey()); ok {
+		stmt.Context = parent.(context.Context)
+	}
+}
+
+func (o *Observer) logSlow(ctx context.Context, elapsed time.Duration, statement string, rows int64) {
+	if o.cfg.Logger == nil {
+		log.Printf("%s slow query (%s, %d rows): %s", o.dialect.LogPrefix, elapsed, rows, statement)
+		return
+	}
+	fields := []any{
+		"log_type", "slow_query",
+		"duration_ms", elapsed.Milliseconds(),
+		"rows", rows,
+	}
+	if o.dialect.LogFields != nil {
+		fields = append(fields, o.dialect.LogFields(ctx)...)
+	}
+	o.cfg.Logger.With(fields...).WarnFCtx(ctx, "slow query: %s", statement)
+}
+
+// Operation returns the upper-cased leading keyword of statement.
+func Operation(statement string) string {
+	statement = strings.TrimLeft(statement, " \t\r\n(")
+	end := strings.IndexAny(statement, " \t\r\n(;")
+	if end < 0 {
+		end = len(statement)
+	}
+	return strings.ToUpper(statement[:end])
+}
@@ -0,0 +1,52 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+// Pool is the connection pool part of a database Config. Zero values keep
+// the database/sql defaults (unlimited open connections, 2 idle, no
+// lifetime or idle timeout).
+type Pool struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// Apply sets the non-zero settings of p on db.
+func (p Pool) Apply(db *sql.DB) {
+	if p.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(p.MaxOpenConns)
+	}
+	if p.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(p.MaxIdleConns)
+	}
+	if p.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	}
+	if p.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+	}
+}
+
+// MigrateLog adapts a LogManager to migrate.Logger, logging at debug level
+// with Prefix (e.g. "postgres: ") in front of each line.
+type MigrateLog struct {
+	Log    logger.LogManager
+	Ctx    context.Context
+	Prefix string
+}
+
+// Printf implements migrate.Logger.
+func (l MigrateLog) Printf(format string, v ...any) {
+	l.Log.DebugFCtx(l.Ctx, l.Prefix+strings.TrimSpace(format), v...)
+}
+
+// Verbose implements migrate.Logger.
+func (l MigrateLog) Verbose() bool { return true }
@@ -0,0 +1,222 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/observability"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	gormmysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// fakeConnector serves every statement from memory: Exec affects one row,
+// or fails with err, and queries return no rows.
+type fakeConnector struct{ err error }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(c), nil }
+func (c fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ err error }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+func (c fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return fakeRows{}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string         { return []string{"id"} }
+func (fakeRows) Close() error              { return nil }
+func (fakeRows) Next([]driver.Value) error { return io.EOF }
+
+func openObserved(t *testing.T, driverErr error, cfg ObserverConfig) *gorm.DB {
+	t.Helper()
+	sqlDB := sql.OpenDB(fakeConnector{err: driverErr})
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	db, err := gorm.Open(gormmysql.New(gormmysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("gorm.Open() error = %v", err)
+	}
+	observer := NewObserver(cfg, Dialect{
+		Plugin: "test:observer",
+		System: "testdb",
+		Scope:  "test",
+		Redact: func(query string) string { return strings.ReplaceAll(query, "'bob'", "?") },
+		Attributes: func(context.Context) []attribute.KeyValue {
+			return []attribute.KeyValue{attribute.String("db.target", "primary")}
+		},
+	})
+	if err := db.Use(observer); err != nil {
+		t.Fatalf("Use(observer) error = %v", err)
+	}
+	return db
+}
+
+func TestObserverRecordsSpansAndMetrics(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	db := openObserved(t, nil, ObserverConfig{
+		Database:      "orders",
+		Tracer:        sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)).Tracer("test"),
+		Meter:         sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"),
+		SlowThreshold: -1,
+	})
+
+	if err := db.Exec("UPDATE users SET name = 'bob' WHERE id = 7").Error; err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("spans = %d, want 1", len(ended))
+	}
+	span := ended[0]
+	if span.Name() != "UPDATE" {
+		t.Fatalf("span name = %q, want UPDATE", span.Name())
+	}
+	want := map[attribute.Key]string{
+		observability.AttrDBSystem:    "testdb",
+		observability.AttrDBOperation: "UPDATE",
+		observability.AttrDBStatement: "UPDATE users SET name = ? WHERE id = 7",
+		AttrDBName:                    "orders",
+		"db.target":                   "primary",
+	}
+	got := make(map[attribute.Key]string)
+	for _, kv := range span.Attributes() {
+		got[kv.Key] = kv.Value.Emit()
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("span %s = %q, want %q", key, got[key], value)
+		}
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if count := histogramCount(rm, "db.client.operation.duration"); count != 1 {
+		t.Fatalf("db.client.operation.duration count = %d, want 1", count)
+	}
+}
+
+func TestObserverRecordsFailures(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	db := openObserved(t, errors.New("connection reset"), ObserverConfig{
+		Tracer:        sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)).Tracer("test"),
+		Meter:         sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"),
+		SlowThreshold: -1,
+	})
+
+	if err := db.Exec("DELETE FROM users").Error; err == nil {
+		t.Fatal("Exec() error = nil, want the driver error")
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 1 || ended[0].Status().Code != codes.Error {
+		t.Fatalf("spans = %d, want one with an error status", len(ended))
+	}
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	var failures int64
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == "db.client.operation.errors" {
+				for _, point := range sum.DataPoints {
+					failures += point.Value
+				}
+			}
+		}
+	}
+	if failures != 1 {
+		t.Fatalf("db.client.operation.errors = %d, want 1", failures)
+	}
+}
+
+func histogramCount(rm metricdata.ResourceMetrics, name string) uint64 {
+	var count uint64
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if hist, ok := m.Data.(metricdata.Histogram[float64]); ok && m.Name == name {
+				for _, point := range hist.DataPoints {
+					count += point.Count
+				}
+			}
+		}
+	}
+	return count
+}
+
+func TestOperation(t *testing.T) {
+	tests := map[string]string{
+		"SELECT * FROM users":         "SELECT",
+		"  insert INTO users VALUES":  "INSERT",
+		"(SELECT 1) UNION (SELECT 2)": "SELECT",
+		"\tupdate\nusers SET a = ?":   "UPDATE",
+		"BEGIN;":                      "BEGIN",
+		"COMMIT":                      "COMMIT",
+		"":                            "",
+	}
+	for statement, want := range tests {
+		if got := Operation(statement); got != want {
+			t.Errorf("Operation(%q) = %q, want %q", statement, got, want)
+		}
+	}
+}
+
+func TestPoolApply(t *testing.T) {
+	tests := []struct {
+		name        string
+		pool        Pool
+		wantMaxOpen int
+	}{
+		{name: "zero keeps the unlimited default", pool: Pool{}, wantMaxOpen: 0},
+		{name: "max open", pool: Pool{MaxOpenConns: 25}, wantMaxOpen: 25},
+		{name: "every setting", pool: Pool{MaxOpenConns: 10, MaxIdleConns: 5, ConnMaxLifetime: time.Hour, ConnMaxIdleTime: time.Minute}, wantMaxOpen: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := sql.OpenDB(fakeConnector{})
+			defer db.Close()
+			tt.pool.Apply(db)
+			if got := db.Stats().MaxOpenConnections; got != tt.wantMaxOpen {
+				t.Fatalf("MaxOpenConnections = %d, want %d", got, tt.wantMaxOpen)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/milan604/core-lab/pkg/auth"
+	"github.com/milan604/core-lab/pkg/logger"
+	coretenant "github.com/milan604/core-lab/pkg/tenant"
+)
+
+const tracerName = "github.com/milan604/core-lab/pkg/jobs"
+
+// MetadataSubject records the subject of the claims a job was enqueued with.
+const MetadataSubject = "subject"
+
+type subjectContextKey struct{}
+
+// SubjectFromContext returns the claims subject of the caller that enqueued the
+// running job.
+func SubjectFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	subject, ok := ctx.Value(subjectContextKey{}).(string)
+	return subject, ok && subject != ""
+}
+
+// captureContext records the caller's trace context and claims subject in
+// metadata so the worker can restore them; see executionContext. The claims
+// subject replaces any subject already in metadata.
+func captureContext(ctx context.Context, metadata map[string]string) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(metadata))
+	if claims, ok := auth.ClaimsFromContext(ctx); ok && strings.TrimSpace(claims.Subject) != "" {
+		metadata[MetadataSubject] = claims.Subject
+	}
+}
+
+// executionContext derives the context a handler runs with: the enqueuing
+// request's tenant context, correlation ID (as the logger request ID) and
+// claims subject, plus a consumer span linked to the enqueuing trace.
+func executionContext(ctx context.Context, job Job) (context.Context, trace.Span) {
+	parent := otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(job.Metadata))
+	ctx, span := otel.Tracer(tracerName).Start(parent, "job "+job.Type,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("job.id", job.ID),
+			attribute.String("job.type", job.Type),
+			attribute.String("job.queue", job.Queue),
+			attribute.Int("job.attempt", job.Attempt),
+		),
+	)
+
+	if requestContext, ok := coretenant.RequestContextFromMetadata(job.Metadata); ok {
+		ctx = coretenant.ContextWithRequestContext(ctx, requestContext)
+		if requestContext.CorrelationID != "" {
+			ctx = context.WithValue(ctx, logger.RequestIDKey, requestContext.CorrelationID)
+		}
+	}
+	if subject := strings.TrimSpace(job.Metadata[MetadataSubject]); subject != "" {
+		ctx = context.WithValue(ctx, subjectContextKey{}, subject)
+	}
+	return ctx, span
+}
+
+func endJobSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
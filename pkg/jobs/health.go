@@ -0,0 +1,197 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/health"
+)
+
+// HealthConfig sets the thresholds of the Manager's health checks. Zero
+// values take the defaults; a negative threshold disables that level.
+type HealthConfig struct {
+	// ScanTimeout is how long the dispatcher may go without a successful
+	// store scan before the heartbeat check is down. Default: ten claim
+	// intervals, at least 30s.
+	ScanTimeout time.Duration
+	// LagDegraded and LagDown are how long the oldest ready job may wait
+	// before the queue lag check is degraded or down. Defaults: 1m and 15m.
+	LagDegraded time.Duration
+	LagDown     time.Duration
+	// FailuresDegraded and FailuresDown are how many attempts of one job type
+	// may fail in a row before the failures check is degraded or down.
+	// Defaults: 3 and 10.
+	FailuresDegraded int
+	FailuresDown     int
+	// AffectReadiness registers the checks as health.Critical, so a check
+	// that is down fails readiness and takes the instance out of rotation.
+	AffectReadiness bool
+}
+
+func (m *Manager) normalizeHealthConfig(cfg HealthConfig) HealthConfig {
+	if cfg.ScanTimeout == 0 {
+		cfg.ScanTimeout = max(10*m.cfg.ClaimInterval, 30*time.Second)
+	}
+	if cfg.LagDegraded == 0 {
+		cfg.LagDegraded = time.Minute
+	}
+	if cfg.LagDown == 0 {
+		cfg.LagDown = 15 * time.Minute
+	}
+	if cfg.FailuresDegraded == 0 {
+		cfg.FailuresDegraded = 3
+	}
+	if cfg.FailuresDown == 0 {
+		cfg.FailuresDown = 10
+	}
+	return cfg
+}
+
+// RegisterHealthChecks registers the heartbeat, queue lag and failures
+// checks on reg as "jobs.<name>.heartbeat", "jobs.<name>.queue_lag" and
+// "jobs.<name>.failures".
+//
+//	reg := health.NewRegistry()
+//	manager.RegisterHealthChecks(reg, jobs.HealthConfig{AffectReadiness: true})
+//	reg.RegisterRoutes(engine)
+func (m *Manager) RegisterHealthChecks(reg *health.Registry, cfg HealthConfig) {
+	var opts []health.CheckOption
+	if cfg.AffectReadiness {
+		opts = append(opts, health.Critical())
+	}
+	prefix := "jobs." + m.cfg.Name + "."
+	reg.Register(prefix+"heartbeat", m.HeartbeatChecker(cfg), opts...)
+	reg.Register(prefix+"queue_lag", m.QueueLagChecker(cfg), opts...)
+	reg.Register(prefix+"failures", m.FailureChecker(cfg), opts...)
+}
+
+// HeartbeatChecker reports whether the manager is running and its dispatcher
+// still polls the store. It is down when the manager is stopped or no scan
+// succeeded within ScanTimeout, and degraded while every worker is busy and
+// the local buffer is full.
+func (m *Manager) HeartbeatChecker(cfg HealthConfig) health.Checker {
+	cfg = m.normalizeHealthConfig(cfg)
+	return health.CheckerFunc(func(context.Context) health.Result {
+		snapshot := m.Health()
+		details := map[string]any{
+			"configured_workers": snapshot.ConfiguredWorkers,
+			"active_workers":     snapshot.ActiveWorkers,
+			"buffered_jobs":      len(m.workCh),
+		}
+		if snapshot.LastScanAt != nil {
+			details["last_scan_at"] = *snapshot.LastScanAt
+		}
+
+		if !snapshot.Running {
+			return health.Down("job manager is not running").WithDetails(details)
+		}
+		lastBeat := snapshot.StartedAt
+		if snapshot.LastScanAt != nil {
+			lastBeat = *snapshot.LastScanAt
+		}
+		if cfg.ScanTimeout > 0 && time.Since(lastBeat) > cfg.ScanTimeout {
+			return health.Down(fmt.Sprintf("no successful store scan for %s", time.Since(lastBeat).Round(time.Second))).WithDetails(details)
+		}
+		if snapshot.ActiveWorkers >= int64(snapshot.ConfiguredWorkers) && len(m.workCh) == cap(m.workCh) {
+			return health.Degraded("all workers are busy and the buffer is full").WithDetails(details)
+		}
+		return health.Up().WithDetails(details)
+	})
+}
+
+// QueueLagChecker reports how long the oldest job this manager handles has
+// been ready without being claimed. It needs a store implementing LagStore.
+func (m *Manager) QueueLagChecker(cfg HealthConfig) health.Checker {
+	cfg = m.normalizeHealthConfig(cfg)
+	return health.CheckerFunc(func(ctx context.Context) health.Result {
+		lag, err := m.QueueLag(ctx)
+		if err != nil {
+			return health.Down(fmt.Sprintf("read queue lag: %v", err))
+		}
+		details := map[string]any{"lag_seconds": lag.Seconds()}
+		switch {
+		case cfg.LagDown > 0 && lag >= cfg.LagDown:
+			return health.Down(fmt.Sprintf("oldest ready job has waited %s", lag.Round(time.Second))).WithDetails(details)
+		case cfg.LagDegraded > 0 && lag >= cfg.LagDegraded:
+			return health.Degraded(fmt.Sprintf("oldest ready job has waited %s", lag.Round(time.Second))).WithDetails(details)
+		}
+		return health.Up().WithDetails(details)
+	})
+}
+
+// QueueLag returns how long the oldest ready job of a registered type has
+// been waiting, or zero when none is waiting.
+func (m *Manager) QueueLag(ctx context.Context) (time.Duration, error) {
+	lagStore, ok := m.store.(LagStore)
+	if !ok {
+		return 0, fmt.Errorf("job store %T does not report queue lag", m.store)
+	}
+	filter := ClaimFilter{Types: m.handlerTypes()}
+	if len(filter.Types) == 0 {
+		return 0, nil
+	}
+	now := time.Now().UTC()
+	oldest, found, err := lagStore.OldestReady(ctx, now, filter)
+	if err != nil || !found {
+		return 0, err
+	}
+	return max(now.Sub(oldest), 0), nil
+}
+
+// FailureChecker reports job types whose attempts keep failing. A success
+// of the type resets its count.
+func (m *Manager) FailureChecker(cfg HealthConfig) health.Checker {
+	cfg = m.normalizeHealthConfig(cfg)
+	return health.CheckerFunc(func(context.Context) health.Result {
+		failures := m.ConsecutiveFailures()
+		worst, worstType := 0, ""
+		types := make([]string, 0, len(failures))
+		for jobType := range failures {
+			types = append(types, jobType)
+		}
+		sort.Strings(types)
+		details := make(map[string]any, len(failures))
+		for _, jobType := range types {
+			details[jobType] = failures[jobType]
+			if failures[jobType] > worst {
+				worst, worstType = failures[jobType], jobType
+			}
+		}
+
+		message := fmt.Sprintf("%d consecutive failures of %s", worst, worstType)
+		switch {
+		case cfg.FailuresDown > 0 && worst >= cfg.FailuresDown:
+			return health.Down(message).WithDetails(details)
+		case cfg.FailuresDegraded > 0 && worst >= cfg.FailuresDegraded:
+			return health.Degraded(message).WithDetails(details)
+		}
+		return health.Up().WithDetails(details)
+	})
+}
+
+// ConsecutiveFailures returns, per job type, how many attempts processed by
+// this manager failed since the type last succeeded. Types without failures
+// are omitted.
+func (m *Manager) ConsecutiveFailures() map[string]int {
+	m.failuresMu.Lock()
+	defer m.failuresMu.Unlock()
+
+	out := make(map[string]int, len(m.failures))
+	for jobType, count := range m.failures {
+		out[jobType] = count
+	}
+	return out
+}
+
+func (m *Manager) recordOutcome(jobType string, err error) {
+	m.failuresMu.Lock()
+	defer m.failuresMu.Unlock()
+
+	if err != nil {
+		m.failures[jobType]++
+		return
+	}
+	delete(m.failures, jobType)
+}
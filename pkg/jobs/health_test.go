@@ -0,0 +1,170 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/milan604/core-lab/pkg/health"
+)
+
+func TestFailureCheckerTracksConsecutiveFailures(t *testing.T) {
+	manager := newTestManager(t)
+	var failing atomic.Bool
+	failing.Store(true)
+	if err := manager.RegisterHandler("report.build", func(context.Context, Job) (any, error) {
+		if failing.Load() {
+			return nil, errors.New("upstream unavailable")
+		}
+		return nil, nil
+	}); err != nil {
+		t.Fatalf("register handler: %v", err)
+	}
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("start manager: %v", err)
+	}
+	defer manager.Stop(context.Background())
+
+	checker := manager.FailureChecker(HealthConfig{FailuresDegraded: 2, FailuresDown: 4})
+	if result := checker.Check(context.Background()); result.Status != health.StatusUp {
+		t.Fatalf("expected up before any run, got %+v", result)
+	}
+
+	job, err := manager.Enqueue(context.Background(), EnqueueRequest{Type: "report.build"})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	waitForStatus(t, manager, job.ID, StatusFailed)
+	result := checker.Check(context.Background())
+	if result.Status != health.StatusDegraded || result.Details["report.build"] != 2 {
+		t.Fatalf("expected degraded after 2 failed attempts, got %+v", result)
+	}
+
+	failing.Store(false)
+	job, err = manager.Enqueue(context.Background(), EnqueueRequest{Type: "report.build"})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	waitForStatus(t, manager, job.ID, StatusSucceeded)
+	if result := checker.Check(context.Background()); result.Status != health.StatusUp {
+		t.Fatalf("expected a success to reset failures, got %+v", result)
+	}
+}
+
+func TestQueueLagCheckerReportsOldestReadyJob(t *testing.T) {
+	store := NewMemoryStore()
+	manager, err := NewManager(Config{Name: "lag-jobs"}, store)
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+	if err := manager.RegisterHandler("email.send", func(context.Context, Job) (any, error) { return nil, nil }); err != nil {
+		t.Fatalf("register handler: %v", err)
+	}
+
+	now := time.Now().UTC()
+	for _, job := range []Job{
+		{ID: "mine", Type: "email.send", Queue: "default", Status: StatusQueued, AvailableAt: now.Add(-2 * time.Minute)},
+		{ID: "other-type", Type: "sms.send", Queue: "default", Status: StatusQueued, AvailableAt: now.Add(-time.Hour)},
+		{ID: "future", Type: "email.send", Queue: "default", Status: StatusScheduled, AvailableAt: now.Add(time.Hour)},
+	} {
+		if _, err := store.Create(context.Background(), job); err != nil {
+			t.Fatalf("create %s: %v", job.ID, err)
+		}
+	}
+
+	lag, err := manager.QueueLag(context.Background())
+	if err != nil {
+		t.Fatalf("queue lag: %v", err)
+	}
+	if lag < 2*time.Minute || lag > 3*time.Minute {
+		t.Fatalf("expected about 2m lag from the oldest handled job, got %s", lag)
+	}
+
+	result := manager.QueueLagChecker(HealthConfig{LagDegraded: time.Minute, LagDown: 5 * time.Minute}).Check(context.Background())
+	if result.Status != health.StatusDegraded {
+		t.Fatalf("expected degraded, got %+v", result)
+	}
+	result = manager.QueueLagChecker(HealthConfig{LagDegraded: -1, LagDown: 90 * time.Second}).Check(context.Background())
+	if result.Status != health.StatusDown {
+		t.Fatalf("expected down, got %+v", result)
+	}
+}
+
+func TestHeartbeatCheckerAndReadiness(t *testing.T) {
+	manager := newTestManager(t)
+	if err := manager.RegisterHandler("noop", func(context.Context, Job) (any, error) { return nil, nil }); err != nil {
+		t.Fatalf("register handler: %v", err)
+	}
+
+	reg := health.NewRegistry()
+	manager.RegisterHealthChecks(reg, HealthConfig{AffectReadiness: true})
+	report := reg.Check(context.Background())
+	if report.Ready || report.Checks["jobs.test-jobs.heartbeat"].Status != health.StatusDown {
+		t.Fatalf("a stopped manager must fail readiness: %+v", report)
+	}
+
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("start manager: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for manager.Health().LastScanAt == nil && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if manager.Health().LastScanAt == nil {
+		t.Fatal("dispatcher never recorded a scan")
+	}
+	if report := reg.Check(context.Background()); !report.Ready || report.Status != health.StatusUp {
+		t.Fatalf("expected a running manager to be ready: %+v", report)
+	}
+
+	stale := manager.HeartbeatChecker(HealthConfig{ScanTimeout: time.Nanosecond})
+	if result := stale.Check(context.Background()); result.Status != health.StatusDown {
+		t.Fatalf("expected a stale heartbeat to be down, got %+v", result)
+	}
+
+	_ = manager.Stop(context.Background())
+	if result := manager.HeartbeatChecker(HealthConfig{}).Check(context.Background()); result.Status != health.StatusDown {
+		t.Fatalf("expected a stopped manager to be down, got %+v", result)
+	}
+}
+
+func TestRedisStoreOldestReadyAppliesFilter(t *testing.T) {
+	mini, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mini.Close()
+
+	ctx := context.Background()
+	store, err := NewRedisStoreFromConfig(ctx, RedisStoreConfig{Address: mini.Addr(), Namespace: "test-lag-jobs"})
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for _, job := range []Job{
+		{ID: "other", Type: "sms.send", Queue: "default", Status: StatusQueued, AvailableAt: now.Add(-time.Hour)},
+		{ID: "mine", Type: "email.send", Queue: "default", Status: StatusQueued, AvailableAt: now.Add(-time.Minute)},
+		{ID: "later", Type: "email.send", Queue: "default", Status: StatusScheduled, AvailableAt: now.Add(time.Minute)},
+	} {
+		if _, err := store.Create(ctx, job); err != nil {
+			t.Fatalf("create %s: %v", job.ID, err)
+		}
+	}
+
+	oldest, found, err := store.OldestReady(ctx, now, ClaimFilter{Types: []string{"email.send"}})
+	if err != nil || !found {
+		t.Fatalf("oldest ready: found=%v err=%v", found, err)
+	}
+	if !oldest.Equal(now.Add(-time.Minute)) {
+		t.Fatalf("expected %s, got %s", now.Add(-time.Minute), oldest)
+	}
+	if _, found, _ := store.OldestReady(ctx, now, ClaimFilter{Types: []string{"push.send"}}); found {
+		t.Fatal("expected no ready job for an unknown type")
+	}
+}
@@ -1,6 +1,7 @@
 package jobs
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/milan604/core-lab/pkg/apperr"
 	"github.com/milan604/core-lab/pkg/response"
 	"github.com/milan604/core-lab/pkg/server"
+	coretenant "github.com/milan604/core-lab/pkg/tenant"
 )
 
 // RegisterAdminRoutes mounts job administration routes onto the provided router.
@@ -68,8 +70,15 @@ func RegisterAdminRoutes(router gin.IRoutes, manager *Manager) {
 				AddSuggestion("body", err.Error()))
 			return
 		}
+		// Identity comes from the authenticated request, never the body.
+		req.Metadata = coretenant.StripReservedMetadata(req.Metadata)
+		delete(req.Metadata, MetadataSubject)
 
 		job, err := manager.Enqueue(c.Request.Context(), req)
+		if errors.Is(err, ErrDuplicateJob) {
+			response.JSONSuccess(c, http.StatusOK, job, map[string]any{"duplicate": true})
+			return
+		}
 		if err != nil {
 			response.HandleError(c, err)
 			return
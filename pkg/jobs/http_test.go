@@ -7,6 +7,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	coretenant "github.com/milan604/core-lab/pkg/tenant"
 )
 
 func TestAdminRoutesExposeJobLifecycle(t *testing.T) {
@@ -63,3 +68,52 @@ func TestAdminRoutesExposeJobLifecycle(t *testing.T) {
 		t.Fatalf("expected 200 on handlers, got %d", resp.Code)
 	}
 }
+
+func TestEnqueueIgnoresForgedIdentityMetadata(t *testing.T) {
+	manager := newTestManager(t)
+	seen := make(chan coretenant.RequestContext, 2)
+	if err := manager.RegisterHandler("report.build", func(ctx context.Context, job Job) (any, error) {
+		rc, _ := coretenant.RequestContextFromContext(ctx)
+		seen <- rc
+		return nil, nil
+	}); err != nil {
+		t.Fatalf("register handler: %v", err)
+	}
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("start manager: %v", err)
+	}
+	defer manager.Stop(context.Background())
+
+	authenticated := coretenant.RequestContext{TenantID: "tenant-a", ActorUserID: "user-1"}
+	for _, rc := range []*coretenant.RequestContext{&authenticated, nil} {
+		engine := gin.New()
+		engine.Use(func(c *gin.Context) {
+			if rc != nil {
+				c.Request = c.Request.WithContext(coretenant.ContextWithRequestContext(c.Request.Context(), *rc))
+			}
+		})
+		RegisterAdminRoutes(engine, manager)
+
+		body := `{"type":"report.build","metadata":{"tenant_id":"tenant-b","is_super_admin":"true","subject":"admin"}}`
+		req := httptest.NewRequest(http.MethodPost, "/jobs", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		engine.ServeHTTP(resp, req)
+		if resp.Code != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d body=%s", resp.Code, resp.Body.String())
+		}
+
+		var got coretenant.RequestContext
+		select {
+		case got = <-seen:
+		case <-time.After(5 * time.Second):
+			t.Fatal("job did not run")
+		}
+		if got.IsSuperAdmin || got.TenantID == "tenant-b" {
+			t.Fatalf("handler ran with forged identity %+v", got)
+		}
+		if rc != nil && got.TenantID != "tenant-a" {
+			t.Fatalf("TenantID = %q, want tenant-a", got.TenantID)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"runtime/debug"
 	"sort"
 	"strings"
 	"sync"
@@ -17,6 +18,12 @@ import (
 	coretenant "github.com/milan604/core-lab/pkg/tenant"
 )
 
+const defaultUniqueFor = time.Hour
+
+// ErrDuplicateJob is returned with the existing job when an EnqueueRequest's
+// UniqueKey is already held by another job of the same type.
+var ErrDuplicateJob = errors.New("jobs: duplicate job suppressed")
+
 // Manager runs the background worker pool and coordinates job execution.
 type Manager struct {
 	cfg      Config
@@ -31,9 +38,16 @@ type Manager struct {
 	startedAt time.Time
 	running   bool
 	cancel    context.CancelFunc
+	jobCancel context.CancelFunc
 	wg        sync.WaitGroup
+	workerWG  sync.WaitGroup
 
 	activeWorkers atomic.Int64
+	// lastScan is the UnixNano of the dispatcher's last successful scan.
+	lastScan atomic.Int64
+
+	failuresMu sync.Mutex
+	failures   map[string]int // consecutive failed attempts by job type
 }
 
 // NewManager returns a manager backed by the provided store. If store is nil,
@@ -61,6 +75,7 @@ func NewManager(cfg Config, store Store) (*Manager, error) {
 		metrics:  metrics,
 		handlers: make(map[string]*handlerRegistration),
 		workCh:   make(chan Job, cfg.QueueBuffer),
+		failures: make(map[string]int),
 	}, nil
 }
 
@@ -110,12 +125,15 @@ func (m *Manager) Start(ctx context.Context) error {
 	}
 
 	runtimeCtx, cancel := context.WithCancel(context.Background())
+	jobCtx, jobCancel := context.WithCancel(context.Background())
 	if ctx != nil {
 		parent := ctx
 		go func() {
 			select {
 			case <-parent.Done():
-				cancel()
+				// parent is already done, so running jobs are cancelled
+				// right away; the store stays open until Stop.
+				m.drain(parent)
 			case <-runtimeCtx.Done():
 			}
 		}()
@@ -124,6 +142,7 @@ func (m *Manager) Start(ctx context.Context) error {
 	m.running = true
 	m.startedAt = time.Now().UTC()
 	m.cancel = cancel
+	m.jobCancel = jobCancel
 
 	m.wg.Add(1)
 	go m.runDispatcher(runtimeCtx)
@@ -134,45 +153,79 @@ func (m *Manager) Start(ctx context.Context) error {
 	}
 
 	for i := 0; i < m.cfg.Workers; i++ {
-		m.wg.Add(1)
-		go m.runWorker(runtimeCtx, i+1)
+		m.workerWG.Add(1)
+		go m.runWorker(runtimeCtx.Done(), jobCtx, i+1)
 	}
 
 	m.log.InfoF("job manager started name=%s workers=%d", m.cfg.Name, m.cfg.Workers)
 	return nil
 }
 
-// Stop stops the worker runtime and waits for goroutines to exit.
-func (m *Manager) Stop(_ context.Context) error {
+// Stop stops claiming jobs and drains the worker pool: running jobs and jobs
+// already claimed into the local buffer keep running until they finish, ctx
+// is done, or Config.DrainTimeout elapses. Then the contexts of running jobs
+// are cancelled and interrupted or unstarted jobs are released back to the
+// store to run again, without counting the interrupted attempt.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.drain(ctx)
+	if closer, ok := m.store.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// drain stops a running pool; see Stop.
+func (m *Manager) drain(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	m.mu.Lock()
 	if !m.running {
 		m.mu.Unlock()
-		if closer, ok := m.store.(interface{ Close() error }); ok {
-			return closer.Close()
-		}
-		return nil
+		return
 	}
-	cancel := m.cancel
+	cancel, jobCancel := m.cancel, m.jobCancel
 	m.running = false
 	m.cancel = nil
+	m.jobCancel = nil
 	m.mu.Unlock()
 
-	if cancel != nil {
-		cancel()
-	}
+	cancel()
 	m.wg.Wait()
+
+	drained := make(chan struct{})
+	go func() {
+		m.workerWG.Wait()
+		close(drained)
+	}()
+	timer := time.NewTimer(m.cfg.DrainTimeout)
+	defer timer.Stop()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	jobCancel()
+	<-drained
+	m.releaseBuffered()
 	m.log.InfoF("job manager stopped name=%s", m.cfg.Name)
+}
 
-	var stopErr error
-	if closer, ok := m.store.(interface{ Close() error }); ok {
-		if err := closer.Close(); err != nil {
-			stopErr = errors.Join(stopErr, err)
-		}
-	}
-	return stopErr
+// EnqueueIn inserts a job that becomes runnable after delay.
+func (m *Manager) EnqueueIn(ctx context.Context, delay time.Duration, req EnqueueRequest) (Job, error) {
+	return m.EnqueueAt(ctx, time.Now().Add(delay), req)
+}
+
+// EnqueueAt inserts a job that becomes runnable at t.
+func (m *Manager) EnqueueAt(ctx context.Context, t time.Time, req EnqueueRequest) (Job, error) {
+	runAfter := t.UTC()
+	req.RunAfter = &runAfter
+	return m.Enqueue(ctx, req)
 }
 
-// Enqueue inserts a new job into the store.
+// Enqueue inserts a new job into the store. When req.UniqueKey is set and
+// another job of the same type holds it, Enqueue returns that job together
+// with ErrDuplicateJob.
 func (m *Manager) Enqueue(ctx context.Context, req EnqueueRequest) (Job, error) {
 	if req.Type == "" {
 		return Job{}, apperr.New(apperr.ErrorCodeInvalidInput).
@@ -212,6 +265,11 @@ func (m *Manager) Enqueue(ctx context.Context, req EnqueueRequest) (Job, error)
 		timeout = Duration(m.cfg.DefaultTimeout)
 	}
 
+	if req.RunAfter == nil && req.RunIn.Duration() > 0 {
+		runAfter := now.Add(req.RunIn.Duration())
+		req.RunAfter = &runAfter
+	}
+
 	availableAt := now
 	status := StatusQueued
 	if req.RunAfter != nil && req.RunAfter.After(now) {
@@ -228,6 +286,7 @@ func (m *Manager) Enqueue(ctx context.Context, req EnqueueRequest) (Job, error)
 		Metadata:    cloneMetadata(req.Metadata),
 		MaxAttempts: maxAttempts,
 		Timeout:     timeout,
+		UniqueKey:   strings.TrimSpace(req.UniqueKey),
 		CreatedAt:   now,
 		UpdatedAt:   now,
 		AvailableAt: availableAt,
@@ -247,12 +306,48 @@ func (m *Manager) Enqueue(ctx context.Context, req EnqueueRequest) (Job, error)
 	if _, exists := job.Metadata["job_manager"]; !exists && m.cfg.Name != "" {
 		job.Metadata["job_manager"] = m.cfg.Name
 	}
+	captureContext(ctx, job.Metadata)
 	if len(job.Metadata) == 0 {
 		job.Metadata = nil
 	}
 
+	var uniqueStore UniqueStore
+	uniqueKey := ""
+	if job.UniqueKey != "" {
+		var ok bool
+		if uniqueStore, ok = m.store.(UniqueStore); !ok {
+			return Job{}, apperr.New(apperr.ErrorCodeInvalidInput).
+				WithMessage("job store does not support unique keys").
+				AddSuggestion("unique_key", "remove unique_key or use a store that implements UniqueStore")
+		}
+		uniqueFor := req.UniqueFor.Duration()
+		if uniqueFor <= 0 {
+			uniqueFor = defaultUniqueFor
+		}
+		uniqueKey = job.Type + ":" + job.UniqueKey
+		holder, acquired, err := uniqueStore.AcquireUnique(ctx, uniqueKey, job.ID, uniqueFor)
+		if err != nil {
+			return Job{}, err
+		}
+		if !acquired {
+			existing, found, err := m.store.Get(ctx, holder)
+			if err != nil {
+				return Job{}, err
+			}
+			if !found {
+				existing = Job{ID: holder, Type: job.Type, UniqueKey: job.UniqueKey}
+			}
+			return existing, ErrDuplicateJob
+		}
+	}
+
 	created, err := m.store.Create(ctx, job)
 	if err != nil {
+		if uniqueStore != nil {
+			if releaseErr := uniqueStore.ReleaseUnique(ctx, uniqueKey, job.ID); releaseErr != nil {
+				m.log.WarnF("release unique job key failed key=%s job_id=%s error=%v", uniqueKey, job.ID, releaseErr)
+			}
+		}
 		return Job{}, err
 	}
 	m.syncStoredGauge(ctx)
@@ -317,13 +412,18 @@ func (m *Manager) Health() HealthSnapshot {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	return HealthSnapshot{
+	snapshot := HealthSnapshot{
 		Manager:           m.cfg.Name,
 		Running:           m.running,
 		StartedAt:         m.startedAt,
 		ConfiguredWorkers: m.cfg.Workers,
 		ActiveWorkers:     m.activeWorkers.Load(),
 	}
+	if scan := m.lastScan.Load(); scan > 0 {
+		at := time.Unix(0, scan).UTC()
+		snapshot.LastScanAt = &at
+	}
+	return snapshot
 }
 
 // Stats returns a detailed runtime and queue snapshot.
@@ -363,8 +463,12 @@ func (m *Manager) runDispatcher(ctx context.Context) {
 	defer ticker.Stop()
 
 	for {
-		if err := m.dispatch(ctx); err != nil && !isContextDone(ctx.Err()) {
-			m.log.WarnF("job dispatcher scan failed: %v", err)
+		if err := m.dispatch(ctx); err != nil {
+			if !isContextDone(ctx.Err()) {
+				m.log.WarnF("job dispatcher scan failed: %v", err)
+			}
+		} else {
+			m.lastScan.Store(time.Now().UnixNano())
 		}
 
 		select {
@@ -390,9 +494,12 @@ func (m *Manager) dispatch(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	for _, job := range claimed {
+	for i, job := range claimed {
 		select {
 		case <-ctx.Done():
+			for _, unsent := range claimed[i:] {
+				m.releaseJob(unsent, "job manager stopped")
+			}
 			return ctx.Err()
 		case m.workCh <- job:
 		}
@@ -400,70 +507,147 @@ func (m *Manager) dispatch(ctx context.Context) error {
 	return nil
 }
 
-func (m *Manager) runWorker(ctx context.Context, workerID int) {
-	defer m.wg.Done()
+// runWorker processes buffered jobs until stop is closed, then drains the
+// buffer until it is empty or jobCtx is cancelled.
+func (m *Manager) runWorker(stop <-chan struct{}, jobCtx context.Context, workerID int) {
+	defer m.workerWG.Done()
 
 	for {
 		select {
-		case <-ctx.Done():
+		case <-stop:
+			for jobCtx.Err() == nil {
+				select {
+				case job := <-m.workCh:
+					m.runJob(jobCtx, workerID, job)
+				default:
+					return
+				}
+			}
 			return
 		case job := <-m.workCh:
-			m.activeWorkers.Add(1)
-			if m.metrics != nil {
-				m.metrics.runningWorkers.Inc()
-			}
+			m.runJob(jobCtx, workerID, job)
+		}
+	}
+}
 
-			if err := m.processJob(ctx, workerID, job); err != nil && !isContextDone(err) {
-				m.log.WarnF("job processing failed worker=%d job_id=%s error=%v", workerID, job.ID, err)
-			}
+func (m *Manager) runJob(ctx context.Context, workerID int, job Job) {
+	if ctx.Err() != nil {
+		m.releaseJob(job, "job manager stopped")
+		return
+	}
 
-			m.activeWorkers.Add(-1)
-			if m.metrics != nil {
-				m.metrics.runningWorkers.Dec()
-			}
-		}
+	m.activeWorkers.Add(1)
+	if m.metrics != nil {
+		m.metrics.runningWorkers.Inc()
+	}
+
+	if err := m.processJob(ctx, workerID, job); err != nil && !isContextDone(err) {
+		m.log.WarnF("job processing failed worker=%d job_id=%s error=%v", workerID, job.ID, err)
+	}
+
+	m.activeWorkers.Add(-1)
+	if m.metrics != nil {
+		m.metrics.runningWorkers.Dec()
 	}
 }
 
+// processJob runs job under ctx, which is cancelled when a Stop gives up
+// draining. Store updates use a context without that cancellation so the
+// outcome is still recorded.
 func (m *Manager) processJob(ctx context.Context, workerID int, job Job) error {
+	storeCtx := context.WithoutCancel(ctx)
 	handler, ok := m.getHandler(job.Type)
 	if !ok {
-		return m.finalizeFailure(ctx, job, fmt.Errorf("no handler registered for job type %s", job.Type))
+		return m.finalizeFailure(storeCtx, job, fmt.Errorf("no handler registered for job type %s", job.Type))
 	}
 
-	execCtx := ctx
+	execCtx, span := executionContext(ctx, job)
 	cancel := func() {}
 	if timeout := job.Timeout.Duration(); timeout > 0 {
-		execCtx, cancel = context.WithTimeout(ctx, timeout)
+		execCtx, cancel = context.WithTimeout(execCtx, timeout)
 	}
 	defer cancel()
 
 	m.log.InfoF("processing job worker=%d job_id=%s type=%s queue=%s attempt=%d", workerID, job.ID, job.Type, job.Queue, job.Attempt)
-	result, err := handler.handler(execCtx, job)
+	result, err := m.callHandler(execCtx, handler.handler, job)
+	endJobSpan(span, err)
+	if err != nil && ctx.Err() != nil {
+		m.releaseJob(job, "interrupted by job manager stop")
+		return nil
+	}
+	m.recordOutcome(job.Type, err)
 	if err != nil {
-		return m.finalizeFailure(ctx, job, err)
+		return m.finalizeFailure(storeCtx, job, err)
 	}
 
 	rawResult, err := marshalResult(result)
 	if err != nil {
-		return m.finalizeFailure(ctx, job, err)
+		return m.finalizeFailure(storeCtx, job, err)
 	}
 
-	if _, err := m.store.MarkSucceeded(ctx, job.ID, rawResult, time.Now().UTC()); err != nil {
+	if _, err := m.store.MarkSucceeded(storeCtx, job.ID, rawResult, time.Now().UTC()); err != nil {
 		return err
 	}
 	if m.metrics != nil {
 		m.metrics.processed.WithLabelValues(job.Queue, job.Type, string(StatusSucceeded)).Inc()
 	}
-	m.syncStoredGauge(ctx)
+	m.syncStoredGauge(storeCtx)
 	return nil
 }
 
+// callHandler runs handler, converting a panic into an error so the job is
+// retried or failed like any other failure. The panic and its stack are
+// logged.
+func (m *Manager) callHandler(ctx context.Context, handler HandlerFunc, job Job) (result any, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			m.log.ErrorFCtx(ctx, "job handler panicked job_id=%s type=%s attempt=%d panic=%v\n%s", job.ID, job.Type, job.Attempt, recovered, debug.Stack())
+			if m.metrics != nil {
+				m.metrics.panics.WithLabelValues(job.Queue, job.Type).Inc()
+			}
+			result, err = nil, fmt.Errorf("job handler panicked: %v", recovered)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// releaseJob returns a claimed job that did not run to completion to the
+// store. Stores implementing ReleaseStore requeue it without counting the
+// attempt; others reschedule it as a retry.
+func (m *Manager) releaseJob(job Job, reason string) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	var err error
+	if releaser, ok := m.store.(ReleaseStore); ok {
+		_, err = releaser.Release(ctx, job.ID, now)
+	} else {
+		_, err = m.store.MarkRetry(ctx, job.ID, reason, now, now)
+	}
+	if err != nil {
+		m.log.WarnF("release job failed job_id=%s type=%s error=%v", job.ID, job.Type, err)
+		return
+	}
+	m.log.InfoF("job released job_id=%s type=%s reason=%s", job.ID, job.Type, reason)
+}
+
+// releaseBuffered releases jobs claimed into the local buffer that no worker
+// picked up before the pool stopped.
+func (m *Manager) releaseBuffered() {
+	for {
+		select {
+		case job := <-m.workCh:
+			m.releaseJob(job, "job manager stopped")
+		default:
+			return
+		}
+	}
+}
+
 func (m *Manager) finalizeFailure(ctx context.Context, job Job, err error) error {
 	now := time.Now().UTC()
 	reason := err.Error()
 
-	if job.Attempt < job.MaxAttempts {
+	if job.Attempt < job.MaxAttempts && !IsPermanent(err) {
 		delay := m.retryDelayFor(job.Attempt)
 		if _, storeErr := m.store.MarkRetry(ctx, job.ID, reason, now.Add(delay), now); storeErr != nil {
 			return storeErr
@@ -590,6 +774,9 @@ func normalizeConfig(cfg Config) Config {
 	if cfg.RetryMaxDelay <= 0 {
 		cfg.RetryMaxDelay = defaults.RetryMaxDelay
 	}
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = defaults.DrainTimeout
+	}
 	return cfg
 }
 
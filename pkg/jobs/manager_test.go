@@ -183,6 +183,242 @@ func TestManagerEnqueueAddsCanonicalRequestMetadata(t *testing.T) {
 	}
 }
 
+func TestManagerEnqueueInSchedulesJob(t *testing.T) {
+	manager := newTestManager(t)
+	manager.cfg.AllowEnqueueWithoutHandler = true
+
+	before := time.Now().UTC()
+	job, err := manager.EnqueueIn(context.Background(), time.Hour, EnqueueRequest{Type: "report.generate"})
+	if err != nil {
+		t.Fatalf("enqueue in: %v", err)
+	}
+	if job.Status != StatusScheduled {
+		t.Fatalf("status = %s, want %s", job.Status, StatusScheduled)
+	}
+	if job.AvailableAt.Before(before.Add(time.Hour)) {
+		t.Fatalf("available_at = %s, want at least %s", job.AvailableAt, before.Add(time.Hour))
+	}
+
+	job, err = manager.Enqueue(context.Background(), EnqueueRequest{Type: "report.generate", RunIn: Duration(time.Hour)})
+	if err != nil {
+		t.Fatalf("enqueue with run_in: %v", err)
+	}
+	if job.Status != StatusScheduled {
+		t.Fatalf("run_in status = %s, want %s", job.Status, StatusScheduled)
+	}
+}
+
+func TestManagerUniqueKeySuppressesDuplicates(t *testing.T) {
+	manager := newTestManager(t)
+	manager.cfg.AllowEnqueueWithoutHandler = true
+	ctx := context.Background()
+
+	first, err := manager.Enqueue(ctx, EnqueueRequest{Type: "invoice.sync", UniqueKey: "tenant-1"})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	dup, err := manager.Enqueue(ctx, EnqueueRequest{Type: "invoice.sync", UniqueKey: "tenant-1"})
+	if !errors.Is(err, ErrDuplicateJob) {
+		t.Fatalf("duplicate enqueue error = %v, want ErrDuplicateJob", err)
+	}
+	if dup.ID != first.ID {
+		t.Fatalf("duplicate returned job %s, want %s", dup.ID, first.ID)
+	}
+
+	if _, err := manager.Enqueue(ctx, EnqueueRequest{Type: "invoice.sync", UniqueKey: "tenant-2"}); err != nil {
+		t.Fatalf("enqueue other key: %v", err)
+	}
+	if _, err := manager.Enqueue(ctx, EnqueueRequest{Type: "invoice.export", UniqueKey: "tenant-1"}); err != nil {
+		t.Fatalf("enqueue other type: %v", err)
+	}
+
+	if _, err := manager.Enqueue(ctx, EnqueueRequest{Type: "invoice.purge", UniqueKey: "k", UniqueFor: Duration(time.Millisecond)}); err != nil {
+		t.Fatalf("enqueue short hold: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := manager.Enqueue(ctx, EnqueueRequest{Type: "invoice.purge", UniqueKey: "k"}); err != nil {
+		t.Fatalf("enqueue after hold expired: %v", err)
+	}
+}
+
+func TestManagerPropagatesRequestContextToHandler(t *testing.T) {
+	manager := newTestManager(t)
+
+	type seen struct{ tenantID, subject, requestID string }
+	seenCh := make(chan seen, 1)
+	if err := manager.RegisterHandler("audit.export", func(ctx context.Context, job Job) (any, error) {
+		tenantID, _ := auth.TenantIDFromContext(ctx)
+		subject, _ := SubjectFromContext(ctx)
+		requestID, _ := ctx.Value(logger.RequestIDKey).(string)
+		seenCh <- seen{tenantID: tenantID, subject: subject, requestID: requestID}
+		return nil, nil
+	}); err != nil {
+		t.Fatalf("register handler: %v", err)
+	}
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("start manager: %v", err)
+	}
+	defer manager.Stop(context.Background())
+
+	ctx := auth.ContextWithClaims(context.Background(), auth.Claims{
+		Subject: "user-1",
+		Raw:     map[string]any{"tenant_id": "tenant-1"},
+	})
+	ctx = context.WithValue(ctx, logger.RequestIDKey, "req-1")
+	job, err := manager.Enqueue(ctx, EnqueueRequest{Type: "audit.export"})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if got := job.Metadata[MetadataSubject]; got != "user-1" {
+		t.Fatalf("subject metadata = %q, want user-1", got)
+	}
+
+	select {
+	case got := <-seenCh:
+		if got != (seen{tenantID: "tenant-1", subject: "user-1", requestID: "req-1"}) {
+			t.Fatalf("handler context = %+v", got)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("handler was not called")
+	}
+}
+
+func TestManagerRecoversHandlerPanics(t *testing.T) {
+	manager := newTestManager(t)
+
+	var attempts atomic.Int32
+	if err := manager.RegisterHandler("report.build", func(ctx context.Context, job Job) (any, error) {
+		if attempts.Add(1) == 1 {
+			panic("nil report")
+		}
+		return "ok", nil
+	}); err != nil {
+		t.Fatalf("register handler: %v", err)
+	}
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("start manager: %v", err)
+	}
+	defer manager.Stop(context.Background())
+
+	job, err := manager.Enqueue(context.Background(), EnqueueRequest{Type: "report.build"})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	finalJob := waitForStatus(t, manager, job.ID, StatusSucceeded)
+	if finalJob.Attempt != 2 || attempts.Load() != 2 {
+		t.Fatalf("expected a retry after the panic, got attempt=%d calls=%d", finalJob.Attempt, attempts.Load())
+	}
+}
+
+func TestRegisterDecodesTypedPayload(t *testing.T) {
+	manager := newTestManager(t)
+
+	type remind struct {
+		InvoiceID string `json:"invoice_id"`
+	}
+	got := make(chan string, 1)
+	if err := Register(manager, "invoice.remind", func(ctx context.Context, payload remind, job Job) (any, error) {
+		got <- payload.InvoiceID
+		return nil, nil
+	}); err != nil {
+		t.Fatalf("register handler: %v", err)
+	}
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("start manager: %v", err)
+	}
+	defer manager.Stop(context.Background())
+
+	if _, err := EnqueuePayload(context.Background(), manager, EnqueueRequest{Type: "invoice.remind"}, remind{InvoiceID: "inv-7"}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	select {
+	case id := <-got:
+		if id != "inv-7" {
+			t.Fatalf("expected inv-7, got %q", id)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("handler was not called")
+	}
+
+	// A payload of the wrong shape fails at once instead of using the retry budget.
+	bad, err := manager.Enqueue(context.Background(), EnqueueRequest{
+		Type:    "invoice.remind",
+		Payload: json.RawMessage(`["inv-8"]`),
+	})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	failed := waitForStatus(t, manager, bad.ID, StatusFailed)
+	if failed.Attempt != 1 {
+		t.Fatalf("expected 1 attempt for an undecodable payload, got %d", failed.Attempt)
+	}
+}
+
+func TestManagerStopDrainsRunningJobs(t *testing.T) {
+	manager := newTestManager(t)
+
+	started := make(chan struct{})
+	if err := manager.RegisterHandler("export.run", func(ctx context.Context, job Job) (any, error) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		return "done", nil
+	}); err != nil {
+		t.Fatalf("register handler: %v", err)
+	}
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("start manager: %v", err)
+	}
+	job, err := manager.Enqueue(context.Background(), EnqueueRequest{Type: "export.run"})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	<-started
+	if err := manager.Stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	stored, err := manager.GetJob(context.Background(), job.ID)
+	if err != nil {
+		t.Fatalf("get job: %v", err)
+	}
+	if stored.Status != StatusSucceeded {
+		t.Fatalf("expected the running job to finish during drain, got %s", stored.Status)
+	}
+}
+
+func TestManagerStopReleasesInterruptedJobs(t *testing.T) {
+	manager := newTestManager(t)
+	manager.cfg.DrainTimeout = 20 * time.Millisecond
+
+	started := make(chan struct{})
+	if err := manager.RegisterHandler("export.run", func(ctx context.Context, job Job) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, WithHandlerTimeout(time.Minute)); err != nil {
+		t.Fatalf("register handler: %v", err)
+	}
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("start manager: %v", err)
+	}
+	job, err := manager.Enqueue(context.Background(), EnqueueRequest{Type: "export.run"})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	<-started
+	if err := manager.Stop(context.Background()); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	stored, err := manager.GetJob(context.Background(), job.ID)
+	if err != nil {
+		t.Fatalf("get job: %v", err)
+	}
+	if stored.Status != StatusQueued || stored.Attempt != 0 {
+		t.Fatalf("expected the interrupted job back in the queue without a spent attempt, got status=%s attempt=%d", stored.Status, stored.Attempt)
+	}
+}
+
 func newTestManager(t *testing.T) *Manager {
 	t.Helper()
 
@@ -5,6 +5,7 @@ import "github.com/prometheus/client_golang/prometheus"
 type metrics struct {
 	enqueued       *prometheus.CounterVec
 	processed      *prometheus.CounterVec
+	panics         *prometheus.CounterVec
 	stored         prometheus.Gauge
 	runningWorkers prometheus.Gauge
 }
@@ -39,6 +40,18 @@ func newMetrics(name string, reg prometheus.Registerer) (*metrics, error) {
 			},
 			[]string{"queue", "type", "status"},
 		),
+		panics: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "corelab",
+				Subsystem: "jobs",
+				Name:      "panics_total",
+				Help:      "Total number of job handler panics recovered.",
+				ConstLabels: prometheus.Labels{
+					"manager": name,
+				},
+			},
+			[]string{"queue", "type"},
+		),
 		stored: prometheus.NewGauge(
 			prometheus.GaugeOpts{
 				Namespace: "corelab",
@@ -69,6 +82,9 @@ func newMetrics(name string, reg prometheus.Registerer) (*metrics, error) {
 	if err := reg.Register(m.processed); err != nil {
 		return nil, err
 	}
+	if err := reg.Register(m.panics); err != nil {
+		return nil, err
+	}
 	if err := reg.Register(m.stored); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS platform_job_uniques;
+DROP TABLE IF EXISTS platform_jobs;
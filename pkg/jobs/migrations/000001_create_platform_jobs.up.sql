@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS platform_jobs (
+    id           text PRIMARY KEY,
+    type         text NOT NULL,
+    queue        text NOT NULL,
+    status       text NOT NULL,
+    payload      jsonb,
+    result       jsonb,
+    metadata     jsonb,
+    attempt      integer NOT NULL DEFAULT 0,
+    max_attempts integer NOT NULL,
+    timeout_ns   bigint NOT NULL DEFAULT 0,
+    unique_key   text NOT NULL DEFAULT '',
+    last_error   text NOT NULL DEFAULT '',
+    created_at   timestamptz NOT NULL,
+    updated_at   timestamptz NOT NULL,
+    available_at timestamptz NOT NULL,
+    started_at   timestamptz,
+    completed_at timestamptz
+);
+
+-- Workers claim queued and scheduled jobs in available_at order.
+CREATE INDEX IF NOT EXISTS platform_jobs_ready_idx
+    ON platform_jobs (available_at, id)
+    WHERE status IN ('queued', 'scheduled');
+
+-- The janitor prunes terminal jobs by completion time.
+CREATE INDEX IF NOT EXISTS platform_jobs_completed_idx
+    ON platform_jobs (completed_at)
+    WHERE status IN ('succeeded', 'failed', 'canceled');
+
+CREATE INDEX IF NOT EXISTS platform_jobs_created_idx
+    ON platform_jobs (created_at DESC, id);
+
+CREATE TABLE IF NOT EXISTS platform_job_uniques (
+    key        text PRIMARY KEY,
+    job_id     text NOT NULL,
+    expires_at timestamptz NOT NULL
+);
@@ -0,0 +1,501 @@
+package jobs
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/milan604/core-lab/pkg/apperr"
+	"github.com/milan604/core-lab/pkg/postgres"
+)
+
+const (
+	// PostgresJobsTable holds the jobs of a PostgresStore.
+	PostgresJobsTable = "platform_jobs"
+	// PostgresUniquesTable holds the unique keys of a PostgresStore.
+	PostgresUniquesTable = "platform_job_uniques"
+	// PostgresMigrationsTable records the applied version of PostgresMigrations.
+	PostgresMigrationsTable = "platform_jobs_schema_migrations"
+)
+
+//go:embed migrations/*.sql
+var postgresMigrationFiles embed.FS
+
+const postgresJobColumns = `id, type, queue, status, payload, result, metadata, attempt, max_attempts, timeout_ns,
+	unique_key, last_error, created_at, updated_at, available_at, started_at, completed_at`
+
+// PostgresMigrations returns the migrations creating the PostgresStore tables,
+// for postgres.DB.Migrate. Their version is tracked in PostgresMigrationsTable,
+// so they run alongside the application's own migrations.
+//
+//	err := db.Migrate(ctx, jobs.PostgresMigrations(), postgres.MigrateUp())
+func PostgresMigrations() postgres.MigrationSource {
+	return postgres.MigrationsFS(postgresMigrationFiles, "migrations").WithMigrationsTable(PostgresMigrationsTable)
+}
+
+// PostgresStore persists jobs in Postgres so multiple replicas and services
+// can share one job table. Claims use FOR UPDATE SKIP LOCKED, so concurrent
+// managers never claim the same job.
+type PostgresStore struct {
+	db *postgres.DB
+}
+
+var (
+	_ Store        = (*PostgresStore)(nil)
+	_ UniqueStore  = (*PostgresStore)(nil)
+	_ ReleaseStore = (*PostgresStore)(nil)
+)
+
+// NewPostgresStore returns a store on the primary of db. Apply
+// PostgresMigrations first.
+func NewPostgresStore(db *postgres.DB) (*PostgresStore, error) {
+	if db == nil || db.Client == nil {
+		return nil, apperr.New(apperr.ErrorCodeInvalidInput).
+			WithMessage("postgres database is required")
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+type postgresJobRow struct {
+	ID          string
+	Type        string
+	Queue       string
+	Status      string
+	Payload     []byte
+	Result      []byte
+	Metadata    []byte
+	Attempt     int
+	MaxAttempts int
+	TimeoutNs   int64
+	UniqueKey   string
+	LastError   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	AvailableAt time.Time
+	StartedAt   *time.Time
+	CompletedAt *time.Time
+}
+
+func (r postgresJobRow) job() (Job, error) {
+	job := Job{
+		ID:          r.ID,
+		Type:        r.Type,
+		Queue:       r.Queue,
+		Status:      Status(r.Status),
+		Attempt:     r.Attempt,
+		MaxAttempts: r.MaxAttempts,
+		Timeout:     Duration(r.TimeoutNs),
+		UniqueKey:   r.UniqueKey,
+		LastError:   r.LastError,
+		CreatedAt:   r.CreatedAt.UTC(),
+		UpdatedAt:   r.UpdatedAt.UTC(),
+		AvailableAt: r.AvailableAt.UTC(),
+		StartedAt:   utcTime(r.StartedAt),
+		CompletedAt: utcTime(r.CompletedAt),
+	}
+	if len(r.Payload) > 0 {
+		job.Payload = append(json.RawMessage(nil), r.Payload...)
+	}
+	if len(r.Result) > 0 {
+		job.Result = append(json.RawMessage(nil), r.Result...)
+	}
+	if len(r.Metadata) > 0 {
+		if err := json.Unmarshal(r.Metadata, &job.Metadata); err != nil {
+			return Job{}, fmt.Errorf("decode metadata of job %s: %w", r.ID, err)
+		}
+	}
+	return job, nil
+}
+
+func decodePostgresJobs(rows []postgresJobRow) ([]Job, error) {
+	jobs := make([]Job, 0, len(rows))
+	for _, row := range rows {
+		job, err := row.job()
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// Create inserts a new job.
+func (s *PostgresStore) Create(ctx context.Context, job Job) (Job, error) {
+	metadata, err := postgresMetadata(job.Metadata)
+	if err != nil {
+		return Job{}, err
+	}
+	result := s.db.Primary(ctx).Exec(`INSERT INTO `+PostgresJobsTable+` (`+postgresJobColumns+`)
+		VALUES (?, ?, ?, ?, ?::jsonb, ?::jsonb, ?::jsonb, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO NOTHING`,
+		job.ID, job.Type, job.Queue, string(job.Status), postgresJSON(job.Payload), postgresJSON(job.Result), metadata,
+		job.Attempt, job.MaxAttempts, int64(job.Timeout), job.UniqueKey, job.LastError,
+		job.CreatedAt.UTC(), job.UpdatedAt.UTC(), job.AvailableAt.UTC(), job.StartedAt, job.CompletedAt,
+	)
+	if result.Error != nil {
+		return Job{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return Job{}, apperr.New(apperr.ErrorCodeInvalidInput).
+			WithMessage("job id already exists").
+			AddSuggestion("id", "provide a unique job id")
+	}
+	return job.clone(), nil
+}
+
+// Get returns a job by id.
+func (s *PostgresStore) Get(ctx context.Context, id string) (Job, bool, error) {
+	var rows []postgresJobRow
+	err := s.db.Primary(ctx).Raw(`SELECT `+postgresJobColumns+` FROM `+PostgresJobsTable+` WHERE id = ?`, id).Scan(&rows).Error
+	if err != nil {
+		return Job{}, false, err
+	}
+	if len(rows) == 0 {
+		return Job{}, false, nil
+	}
+	job, err := rows[0].job()
+	return job, err == nil, err
+}
+
+// List returns jobs that match the filter, newest first.
+func (s *PostgresStore) List(ctx context.Context, filter JobFilter) ([]Job, error) {
+	query := s.db.Primary(ctx).Table(PostgresJobsTable).Select(postgresJobColumns)
+	if filter.Queue != "" {
+		query = query.Where("queue = ?", filter.Queue)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var rows []postgresJobRow
+	if err := query.Order("created_at DESC, id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return decodePostgresJobs(rows)
+}
+
+// ClaimReady claims due queued and scheduled jobs for workers.
+func (s *PostgresStore) ClaimReady(ctx context.Context, now time.Time, limit int, filter ClaimFilter) ([]Job, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	conditions, args := readyConditions(now, filter)
+	args["limit"] = limit
+
+	var rows []postgresJobRow
+	err := s.db.Primary(ctx).Raw(`UPDATE `+PostgresJobsTable+` AS j
+		SET status = 'running', attempt = j.attempt + 1, started_at = @now, updated_at = @now
+		FROM (
+			SELECT id FROM `+PostgresJobsTable+`
+			WHERE `+strings.Join(conditions, " AND ")+`
+			ORDER BY available_at, id
+			LIMIT @limit
+			FOR UPDATE SKIP LOCKED
+		) AS picked
+		WHERE j.id = picked.id
+		RETURNING j.*`, args).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	claimed, err := decodePostgresJobs(rows)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(claimed, func(i, j int) bool {
+		if claimed[i].AvailableAt.Equal(claimed[j].AvailableAt) {
+			return claimed[i].ID < claimed[j].ID
+		}
+		return claimed[i].AvailableAt.Before(claimed[j].AvailableAt)
+	})
+	return claimed, nil
+}
+
+// MarkSucceeded marks a job as completed successfully.
+func (s *PostgresStore) MarkSucceeded(ctx context.Context, id string, result jsonRawResult, now time.Time) (Job, error) {
+	return s.updateJob(ctx, id, func(job *Job) error {
+		completedAt := now.UTC()
+		job.Status = StatusSucceeded
+		job.Result = append(jsonRawResult(nil), result...)
+		job.LastError = ""
+		job.CompletedAt = &completedAt
+		job.UpdatedAt = completedAt
+		return nil
+	})
+}
+
+// MarkRetry reschedules a failed attempt for another run.
+func (s *PostgresStore) MarkRetry(ctx context.Context, id, reason string, runAt, now time.Time) (Job, error) {
+	return s.updateJob(ctx, id, func(job *Job) error {
+		job.Status = StatusScheduled
+		job.LastError = reason
+		job.AvailableAt = runAt.UTC()
+		job.CompletedAt = nil
+		job.UpdatedAt = now.UTC()
+		return nil
+	})
+}
+
+// MarkFailed marks a job as terminally failed.
+func (s *PostgresStore) MarkFailed(ctx context.Context, id, reason string, now time.Time) (Job, error) {
+	return s.updateJob(ctx, id, func(job *Job) error {
+		completedAt := now.UTC()
+		job.Status = StatusFailed
+		job.LastError = reason
+		job.CompletedAt = &completedAt
+		job.UpdatedAt = completedAt
+		return nil
+	})
+}
+
+// OldestReady implements LagStore.
+func (s *PostgresStore) OldestReady(ctx context.Context, now time.Time, filter ClaimFilter) (time.Time, bool, error) {
+	conditions, args := readyConditions(now, filter)
+	var row struct {
+		Oldest *time.Time
+	}
+	err := s.db.Primary(ctx).Raw(`SELECT min(available_at) AS oldest FROM `+PostgresJobsTable+`
+		WHERE `+strings.Join(conditions, " AND "), args).Scan(&row).Error
+	if err != nil || row.Oldest == nil {
+		return time.Time{}, false, err
+	}
+	return row.Oldest.UTC(), true, nil
+}
+
+// readyConditions returns the WHERE conditions and named arguments selecting
+// jobs due at now that match filter.
+func readyConditions(now time.Time, filter ClaimFilter) ([]string, map[string]any) {
+	conditions := []string{"status IN ('queued', 'scheduled')", "available_at <= @now"}
+	args := map[string]any{"now": now.UTC()}
+	if queues := nonEmpty(filter.Queues); len(queues) > 0 {
+		conditions = append(conditions, "queue IN @queues")
+		args["queues"] = queues
+	}
+	if types := nonEmpty(filter.Types); len(types) > 0 {
+		conditions = append(conditions, "type IN @types")
+		args["types"] = types
+	}
+	return conditions, args
+}
+
+// Release implements ReleaseStore.
+func (s *PostgresStore) Release(ctx context.Context, id string, now time.Time) (Job, error) {
+	return s.updateJob(ctx, id, func(job *Job) error {
+		releaseRunning(job, now.UTC())
+		return nil
+	})
+}
+
+// Cancel cancels a queued or scheduled job.
+func (s *PostgresStore) Cancel(ctx context.Context, id string, now time.Time) (Job, error) {
+	return s.updateJob(ctx, id, func(job *Job) error {
+		if job.Status == StatusRunning {
+			return apperr.New(apperr.ErrorCodeInvalidInput).
+				WithMessage("running jobs cannot be canceled")
+		}
+		if job.Status.IsTerminal() {
+			return apperr.New(apperr.ErrorCodeInvalidInput).
+				WithMessage("job is already in a terminal state")
+		}
+
+		completedAt := now.UTC()
+		job.Status = StatusCanceled
+		job.CompletedAt = &completedAt
+		job.UpdatedAt = completedAt
+		return nil
+	})
+}
+
+// Retry resets a failed or canceled job and queues it again immediately.
+func (s *PostgresStore) Retry(ctx context.Context, id string, now time.Time) (Job, error) {
+	return s.updateJob(ctx, id, func(job *Job) error {
+		if !(job.Status == StatusFailed || job.Status == StatusCanceled) {
+			return apperr.New(apperr.ErrorCodeInvalidInput).
+				WithMessage("only failed or canceled jobs can be retried")
+		}
+
+		job.Status = StatusQueued
+		job.Attempt = 0
+		job.LastError = ""
+		job.Result = nil
+		job.AvailableAt = now.UTC()
+		job.StartedAt = nil
+		job.CompletedAt = nil
+		job.UpdatedAt = now.UTC()
+		return nil
+	})
+}
+
+// PruneTerminalBefore deletes finished jobs completed before the cutoff.
+func (s *PostgresStore) PruneTerminalBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	result := s.db.Primary(ctx).Exec(`DELETE FROM `+PostgresJobsTable+`
+		WHERE status IN ('succeeded', 'failed', 'canceled') AND completed_at < ?`, cutoff.UTC())
+	return int(result.RowsAffected), result.Error
+}
+
+// Stats returns an aggregate snapshot of the store state.
+func (s *PostgresStore) Stats(ctx context.Context) (storeStats, error) {
+	var rows []struct {
+		Queue  string
+		Status string
+		Total  int
+	}
+	err := s.db.Primary(ctx).Raw(`SELECT queue, status, count(*) AS total FROM ` + PostgresJobsTable + `
+		GROUP BY queue, status`).Scan(&rows).Error
+	if err != nil {
+		return storeStats{}, err
+	}
+
+	stats := storeStats{
+		Totals: make(map[Status]int),
+		Queues: make(map[string]QueueStats),
+	}
+	for _, row := range rows {
+		status := Status(row.Status)
+		stats.JobsStored += row.Total
+		stats.Totals[status] += row.Total
+
+		queueStats := stats.Queues[row.Queue]
+		queueStats.Name = row.Queue
+		if queueStats.Totals == nil {
+			queueStats.Totals = make(map[Status]int)
+		}
+		queueStats.Totals[status] += row.Total
+		queueStats.JobsTotal += row.Total
+		stats.Queues[row.Queue] = queueStats
+	}
+	return stats, nil
+}
+
+// AcquireUnique claims key for jobID until ttl elapses, taking over holds
+// that have expired.
+func (s *PostgresStore) AcquireUnique(ctx context.Context, key, jobID string, ttl time.Duration) (string, bool, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		now := time.Now().UTC()
+		var acquired []string
+		err := s.db.Primary(ctx).Raw(`INSERT INTO `+PostgresUniquesTable+` AS u (key, job_id, expires_at)
+			VALUES (?, ?, ?)
+			ON CONFLICT (key) DO UPDATE SET job_id = EXCLUDED.job_id, expires_at = EXCLUDED.expires_at
+			WHERE u.expires_at <= ?
+			RETURNING job_id`, key, jobID, now.Add(ttl), now).Scan(&acquired).Error
+		if err != nil {
+			return "", false, err
+		}
+		if len(acquired) > 0 {
+			return jobID, true, nil
+		}
+
+		var holders []string
+		err = s.db.Primary(ctx).Raw(`SELECT job_id FROM `+PostgresUniquesTable+` WHERE key = ?`, key).Scan(&holders).Error
+		if err != nil {
+			return "", false, err
+		}
+		if len(holders) > 0 {
+			return holders[0], false, nil
+		}
+		// The hold was released in between; try again.
+	}
+	return "", false, apperr.New(apperr.ErrorCodeInternal).
+		WithMessage("failed to acquire unique job key due to concurrent updates")
+}
+
+// ReleaseUnique frees key if jobID still holds it.
+func (s *PostgresStore) ReleaseUnique(ctx context.Context, key, jobID string) error {
+	return s.db.Primary(ctx).Exec(`DELETE FROM `+PostgresUniquesTable+` WHERE key = ? AND job_id = ?`, key, jobID).Error
+}
+
+// updateJob applies mutate to the job under a row lock and writes it back.
+func (s *PostgresStore) updateJob(ctx context.Context, id string, mutate func(*Job) error) (Job, error) {
+	var updated Job
+	err := s.db.WithinTx(ctx, func(tx *gorm.DB) error {
+		var rows []postgresJobRow
+		if err := tx.Raw(`SELECT `+postgresJobColumns+` FROM `+PostgresJobsTable+` WHERE id = ? FOR UPDATE`, id).Scan(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return notFoundJob(id)
+		}
+		job, err := rows[0].job()
+		if err != nil {
+			return err
+		}
+		if err := mutate(&job); err != nil {
+			return err
+		}
+		metadata, err := postgresMetadata(job.Metadata)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec(`UPDATE `+PostgresJobsTable+`
+			SET status = ?, result = ?::jsonb, metadata = ?::jsonb, attempt = ?, last_error = ?,
+				updated_at = ?, available_at = ?, started_at = ?, completed_at = ?
+			WHERE id = ?`,
+			string(job.Status), postgresJSON(job.Result), metadata, job.Attempt, job.LastError,
+			job.UpdatedAt.UTC(), job.AvailableAt.UTC(), job.StartedAt, job.CompletedAt, id,
+		).Error
+		if err != nil {
+			return err
+		}
+		updated = job.clone()
+		return nil
+	})
+	if err != nil {
+		return Job{}, err
+	}
+	return updated, nil
+}
+
+// postgresJSON passes raw JSON as text, so the ::jsonb cast parses it; empty
+// values are stored as NULL.
+func postgresJSON(raw []byte) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+func postgresMetadata(metadata map[string]string) (any, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	raw, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("encode job metadata: %w", err)
+	}
+	return string(raw), nil
+}
+
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+func nonEmpty(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" {
+			out = append(out, value)
+		}
+	}
+	return out
+}
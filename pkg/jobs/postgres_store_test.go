@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	gormpostgres "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/milan604/core-lab/pkg/postgres"
+	"github.com/milan604/core-lab/pkg/postgres/migrations"
+)
+
+func TestPostgresMigrationsPassLint(t *testing.T) {
+	report, err := migrations.Lint(postgresMigrationFiles, migrations.WithDir("migrations"), migrations.WithWarningsAsErrors())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := report.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostgresStoreStatements(t *testing.T) {
+	client, err := gorm.Open(gormpostgres.New(gormpostgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stmt *gorm.Statement
+	capture := func(tx *gorm.DB) { stmt = tx.Statement }
+	if err := client.Callback().Raw().After("gorm:raw").Register("capture", capture); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Callback().Row().After("gorm:row").Register("capture", capture); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewPostgresStore(&postgres.DB{Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	_, _ = store.Create(context.Background(), Job{
+		ID:          "job-1",
+		Type:        "email.send",
+		Queue:       "default",
+		Status:      StatusQueued,
+		Payload:     []byte(`{"to":"a@example.com"}`),
+		Metadata:    map[string]string{"job_manager": "mailer"},
+		MaxAttempts: 3,
+		Timeout:     Duration(30 * time.Second),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		AvailableAt: now,
+	})
+	if !strings.HasPrefix(stmt.SQL.String(), "INSERT INTO platform_jobs") {
+		t.Fatalf("SQL = %s", stmt.SQL.String())
+	}
+	wantVars := []any{"job-1", "email.send", "default", "queued", `{"to":"a@example.com"}`, nil, `{"job_manager":"mailer"}`, 0, 3, int64(30 * time.Second)}
+	for i, want := range wantVars {
+		if stmt.Vars[i] != want {
+			t.Fatalf("var %d = %#v, want %#v (vars %v)", i, stmt.Vars[i], want, stmt.Vars)
+		}
+	}
+
+	_, _ = store.ClaimReady(context.Background(), now, 5, ClaimFilter{Types: []string{"email.send", ""}})
+	sql := stmt.SQL.String()
+	for _, want := range []string{"FOR UPDATE SKIP LOCKED", "type IN ($", "attempt = j.attempt + 1"} {
+		if !strings.Contains(sql, want) {
+			t.Fatalf("claim SQL lacks %q: %s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "queue IN") {
+		t.Fatalf("claim SQL filters queues without a queue filter: %s", sql)
+	}
+
+	_, _, _ = store.OldestReady(context.Background(), now, ClaimFilter{Queues: []string{"default"}})
+	sql = stmt.SQL.String()
+	for _, want := range []string{"min(available_at)", "queue IN ($", "available_at <= $"} {
+		if !strings.Contains(sql, want) {
+			t.Fatalf("oldest ready SQL lacks %q: %s", want, sql)
+		}
+	}
+}
+
+func TestNewPostgresStoreRequiresDB(t *testing.T) {
+	if _, err := NewPostgresStore(nil); err == nil {
+		t.Fatal("NewPostgresStore(nil) succeeded")
+	}
+}
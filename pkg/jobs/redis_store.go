@@ -31,6 +31,10 @@ type RedisStore struct {
 	ownsClient bool
 }
 
+// redisLagScanLimit bounds how many due jobs OldestReady inspects when the
+// oldest ones belong to other managers.
+const redisLagScanLimit = 1000
+
 var claimReadyScript = redis.NewScript(`
 local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
 local claimed = {}
@@ -91,6 +95,13 @@ end
 return claimed
 `)
 
+var releaseUniqueScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
 var pruneTerminalScript = redis.NewScript(`
 local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
 local removed = 0
@@ -215,6 +226,39 @@ func (s *RedisStore) Create(ctx context.Context, job Job) (Job, error) {
 		WithMessage("failed to create job due to concurrent updates")
 }
 
+// AcquireUnique claims key for jobID with SET NX until ttl elapses.
+func (s *RedisStore) AcquireUnique(ctx context.Context, key, jobID string, ttl time.Duration) (string, bool, error) {
+	uniqueKey := s.uniqueKey(key)
+	acquired, err := s.client.SetNX(ctx, uniqueKey, jobID, ttl).Result()
+	if err != nil {
+		return "", false, err
+	}
+	if acquired {
+		return jobID, true, nil
+	}
+	holder, err := s.client.Get(ctx, uniqueKey).Result()
+	if err == redis.Nil {
+		// The hold expired between SETNX and GET; try once more.
+		acquired, err = s.client.SetNX(ctx, uniqueKey, jobID, ttl).Result()
+		if err != nil {
+			return "", false, err
+		}
+		if acquired {
+			return jobID, true, nil
+		}
+		holder, err = s.client.Get(ctx, uniqueKey).Result()
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return holder, false, nil
+}
+
+// ReleaseUnique frees key if jobID still holds it.
+func (s *RedisStore) ReleaseUnique(ctx context.Context, key, jobID string) error {
+	return releaseUniqueScript.Run(ctx, s.client, []string{s.uniqueKey(key)}, jobID).Err()
+}
+
 func (s *RedisStore) Get(ctx context.Context, id string) (Job, bool, error) {
 	raw, err := s.client.Get(ctx, s.jobKey(id)).Bytes()
 	if err == redis.Nil {
@@ -382,6 +426,61 @@ func (s *RedisStore) MarkFailed(ctx context.Context, id, reason string, now time
 	})
 }
 
+// OldestReady implements LagStore. It pages through the available index from
+// the oldest entry until a job matches filter, reading at most
+// redisLagScanLimit jobs.
+func (s *RedisStore) OldestReady(ctx context.Context, now time.Time, filter ClaimFilter) (time.Time, bool, error) {
+	const page = 100
+	for offset := int64(0); offset < redisLagScanLimit; offset += page {
+		entries, err := s.client.ZRangeByScoreWithScores(ctx, s.availableIndexKey(), &redis.ZRangeBy{
+			Min:    "-inf",
+			Max:    fmt.Sprintf("%.0f", timeScore(now.UTC())),
+			Offset: offset,
+			Count:  page,
+		}).Result()
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		if len(entries) == 0 {
+			return time.Time{}, false, nil
+		}
+
+		keys := make([]string, len(entries))
+		for i, entry := range entries {
+			keys[i] = s.jobKey(fmt.Sprint(entry.Member))
+		}
+		raws, err := s.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		for _, raw := range raws {
+			data, ok := raw.(string)
+			if !ok {
+				continue
+			}
+			job, err := decodeRedisJob([]byte(data))
+			if err != nil {
+				return time.Time{}, false, err
+			}
+			if (job.Status == StatusQueued || job.Status == StatusScheduled) && filter.matches(job) {
+				return job.AvailableAt, true, nil
+			}
+		}
+		if len(entries) < page {
+			return time.Time{}, false, nil
+		}
+	}
+	return time.Time{}, false, nil
+}
+
+// Release implements ReleaseStore.
+func (s *RedisStore) Release(ctx context.Context, id string, now time.Time) (Job, error) {
+	return s.updateJob(ctx, id, func(job *Job) error {
+		releaseRunning(job, now.UTC())
+		return nil
+	})
+}
+
 func (s *RedisStore) Cancel(ctx context.Context, id string, now time.Time) (Job, error) {
 	return s.updateJob(ctx, id, func(job *Job) error {
 		if job.Status == StatusRunning {
@@ -587,6 +686,10 @@ func (s *RedisStore) jobKey(id string) string {
 	return s.jobPrefix() + id
 }
 
+func (s *RedisStore) uniqueKey(key string) string {
+	return s.namespace + ":unique:" + key
+}
+
 func (s *RedisStore) createdIndexKey() string {
 	return s.namespace + ":index:created"
 }
@@ -136,6 +136,17 @@ func TestRedisStoreClaimAndLifecycle(t *testing.T) {
 		t.Fatalf("expected attempt 1 after claim, got %d", claimed[0].Attempt)
 	}
 
+	released, err := store.Release(ctx, job.ID, now)
+	if err != nil {
+		t.Fatalf("failed to release job: %v", err)
+	}
+	if released.Status != StatusQueued || released.Attempt != 0 {
+		t.Fatalf("expected queued job with attempt 0 after release, got %s attempt=%d", released.Status, released.Attempt)
+	}
+	if claimed, err = store.ClaimReady(ctx, now, 1, ClaimFilter{}); err != nil || len(claimed) != 1 {
+		t.Fatalf("expected the released job to be claimable again, got %d jobs err=%v", len(claimed), err)
+	}
+
 	failedAt := now.Add(2 * time.Second)
 	if _, err := store.MarkFailed(ctx, job.ID, "boom", failedAt); err != nil {
 		t.Fatalf("failed to mark job failed: %v", err)
@@ -233,3 +244,48 @@ func TestRedisStoreClaimRespectsTypeFilter(t *testing.T) {
 		t.Fatalf("expected blocked job to remain queued, got %s", blocked.Status)
 	}
 }
+
+func TestRedisStoreUniqueKeys(t *testing.T) {
+	t.Parallel()
+
+	mini, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mini.Close()
+
+	ctx := context.Background()
+	store, err := NewRedisStoreFromConfig(ctx, RedisStoreConfig{
+		Address:   mini.Addr(),
+		Namespace: "test-unique-jobs",
+	})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	if holder, acquired, err := store.AcquireUnique(ctx, "sync:tenant-1", "job-1", time.Minute); err != nil || !acquired || holder != "job-1" {
+		t.Fatalf("first acquire = %q, %v, %v", holder, acquired, err)
+	}
+	if holder, acquired, err := store.AcquireUnique(ctx, "sync:tenant-1", "job-2", time.Minute); err != nil || acquired || holder != "job-1" {
+		t.Fatalf("second acquire = %q, %v, %v; want held by job-1", holder, acquired, err)
+	}
+
+	if err := store.ReleaseUnique(ctx, "sync:tenant-1", "job-2"); err != nil {
+		t.Fatalf("release by non-holder: %v", err)
+	}
+	if _, acquired, _ := store.AcquireUnique(ctx, "sync:tenant-1", "job-2", time.Minute); acquired {
+		t.Fatalf("non-holder release must not free the key")
+	}
+
+	mini.FastForward(2 * time.Minute)
+	if holder, acquired, err := store.AcquireUnique(ctx, "sync:tenant-1", "job-3", time.Minute); err != nil || !acquired || holder != "job-3" {
+		t.Fatalf("acquire after expiry = %q, %v, %v", holder, acquired, err)
+	}
+	if err := store.ReleaseUnique(ctx, "sync:tenant-1", "job-3"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, acquired, _ := store.AcquireUnique(ctx, "sync:tenant-1", "job-4", time.Minute); !acquired {
+		t.Fatalf("expected key to be free after release")
+	}
+}
@@ -25,6 +25,34 @@ type Store interface {
 	Stats(context.Context) (storeStats, error)
 }
 
+// UniqueStore is implemented by stores that support EnqueueRequest.UniqueKey.
+// MemoryStore and RedisStore implement it.
+type UniqueStore interface {
+	// AcquireUnique claims key for jobID until ttl elapses. When another job
+	// already holds key it returns that job's ID and false.
+	AcquireUnique(ctx context.Context, key, jobID string, ttl time.Duration) (holder string, acquired bool, err error)
+	// ReleaseUnique frees key if jobID still holds it.
+	ReleaseUnique(ctx context.Context, key, jobID string) error
+}
+
+// ReleaseStore is implemented by stores that can hand a claimed job back
+// without counting the attempt, which the Manager does for jobs interrupted
+// or left unstarted by Stop. All built-in stores implement it.
+type ReleaseStore interface {
+	// Release returns a running job to the queue, available at now, and
+	// reverts the attempt counted by ClaimReady.
+	Release(ctx context.Context, id string, now time.Time) (Job, error)
+}
+
+// LagStore is implemented by stores that can report how long ready jobs
+// have been waiting, which the queue lag health check reads. All built-in
+// stores implement it.
+type LagStore interface {
+	// OldestReady returns the AvailableAt of the oldest queued or scheduled
+	// job matching filter that is due at now, and false when there is none.
+	OldestReady(ctx context.Context, now time.Time, filter ClaimFilter) (time.Time, bool, error)
+}
+
 type jsonRawResult = []byte
 
 type storeStats struct {
@@ -36,15 +64,51 @@ type storeStats struct {
 // MemoryStore is an in-memory Store implementation suitable for embedded
 // workers, local development, and single-process job servers.
 type MemoryStore struct {
-	mu   sync.RWMutex
-	jobs map[string]*Job
+	mu      sync.RWMutex
+	jobs    map[string]*Job
+	uniques map[string]uniqueHold
+}
+
+type uniqueHold struct {
+	jobID     string
+	expiresAt time.Time
 }
 
 // NewMemoryStore returns a new in-memory job store.
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
-		jobs: make(map[string]*Job),
+		jobs:    make(map[string]*Job),
+		uniques: make(map[string]uniqueHold),
+	}
+}
+
+// AcquireUnique claims key for jobID until ttl elapses.
+func (s *MemoryStore) AcquireUnique(_ context.Context, key, jobID string, ttl time.Duration) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if hold, ok := s.uniques[key]; ok && now.Before(hold.expiresAt) {
+		return hold.jobID, false, nil
+	}
+	for k, hold := range s.uniques {
+		if !now.Before(hold.expiresAt) {
+			delete(s.uniques, k)
+		}
 	}
+	s.uniques[key] = uniqueHold{jobID: jobID, expiresAt: now.Add(ttl)}
+	return jobID, true, nil
+}
+
+// ReleaseUnique frees key if jobID still holds it.
+func (s *MemoryStore) ReleaseUnique(_ context.Context, key, jobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if hold, ok := s.uniques[key]; ok && hold.jobID == jobID {
+		delete(s.uniques, key)
+	}
+	return nil
 }
 
 // Create inserts a new job.
@@ -253,6 +317,40 @@ func (s *MemoryStore) MarkFailed(_ context.Context, id, reason string, now time.
 	return job.clone(), nil
 }
 
+// OldestReady implements LagStore.
+func (s *MemoryStore) OldestReady(_ context.Context, now time.Time, filter ClaimFilter) (time.Time, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var oldest time.Time
+	found := false
+	for _, job := range s.jobs {
+		if job.Status != StatusQueued && job.Status != StatusScheduled {
+			continue
+		}
+		if job.AvailableAt.After(now) || !filter.matches(*job) {
+			continue
+		}
+		if !found || job.AvailableAt.Before(oldest) {
+			oldest, found = job.AvailableAt, true
+		}
+	}
+	return oldest, found, nil
+}
+
+// Release implements ReleaseStore.
+func (s *MemoryStore) Release(_ context.Context, id string, now time.Time) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, notFoundJob(id)
+	}
+	releaseRunning(job, now)
+	return job.clone(), nil
+}
+
 // Cancel cancels a queued or scheduled job.
 func (s *MemoryStore) Cancel(_ context.Context, id string, now time.Time) (Job, error) {
 	s.mu.Lock()
@@ -343,6 +441,21 @@ func (s *MemoryStore) Stats(_ context.Context) (storeStats, error) {
 	return stats, nil
 }
 
+// releaseRunning requeues a running job at now and reverts its claimed
+// attempt. Jobs in other states are left alone.
+func releaseRunning(job *Job, now time.Time) {
+	if job.Status != StatusRunning {
+		return
+	}
+	job.Status = StatusQueued
+	if job.Attempt > 0 {
+		job.Attempt--
+	}
+	job.AvailableAt = now
+	job.StartedAt = nil
+	job.UpdatedAt = now
+}
+
 func notFoundJob(id string) error {
 	return apperr.New(apperr.ErrorCodeNotFound).
 		WithMessage("job not found").
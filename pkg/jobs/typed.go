@@ -0,0 +1,65 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// TypedHandlerFunc processes a job whose payload has been decoded into a T.
+type TypedHandlerFunc[T any] func(ctx context.Context, payload T, job Job) (any, error)
+
+// Register registers a handler for jobType that receives the job payload
+// decoded from JSON into a T. A payload that does not decode fails the job
+// without retrying.
+//
+//	err := jobs.Register(manager, "invoice.remind", func(ctx context.Context, p RemindPayload, job jobs.Job) (any, error) {
+//		return nil, billing.SendReminder(ctx, p.InvoiceID)
+//	}, jobs.WithHandlerMaxAttempts(5))
+func Register[T any](m *Manager, jobType string, handler TypedHandlerFunc[T], opts ...HandlerOption) error {
+	if handler == nil {
+		return m.RegisterHandler(jobType, nil, opts...)
+	}
+	return m.RegisterHandler(jobType, func(ctx context.Context, job Job) (any, error) {
+		var payload T
+		if len(job.Payload) > 0 {
+			if err := json.Unmarshal(job.Payload, &payload); err != nil {
+				return nil, Permanent(fmt.Errorf("decode %s payload: %w", jobType, err))
+			}
+		}
+		return handler(ctx, payload, job)
+	}, opts...)
+}
+
+// EnqueuePayload encodes payload as the JSON payload of req and enqueues it.
+func EnqueuePayload[T any](ctx context.Context, m *Manager, req EnqueueRequest, payload T) (Job, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return Job{}, fmt.Errorf("encode %s payload: %w", req.Type, err)
+	}
+	req.Payload = raw
+	return m.Enqueue(ctx, req)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a handler error as not worth retrying: the job fails on
+// this attempt whatever its retry budget.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
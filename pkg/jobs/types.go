@@ -95,6 +95,7 @@ type Job struct {
 	Attempt     int               `json:"attempt"`
 	MaxAttempts int               `json:"max_attempts"`
 	Timeout     Duration          `json:"timeout"`
+	UniqueKey   string            `json:"unique_key,omitempty"`
 	LastError   string            `json:"last_error,omitempty"`
 	CreatedAt   time.Time         `json:"created_at"`
 	UpdatedAt   time.Time         `json:"updated_at"`
@@ -138,6 +139,13 @@ type EnqueueRequest struct {
 	MaxAttempts int               `json:"max_attempts,omitempty"`
 	Timeout     Duration          `json:"timeout,omitempty"`
 	RunAfter    *time.Time        `json:"run_after,omitempty"`
+	// RunIn delays the job relative to enqueue time; RunAfter wins when both are set.
+	RunIn Duration `json:"run_in,omitempty"`
+	// UniqueKey suppresses duplicates: while a job of the same type holds the
+	// key, Enqueue returns that job with ErrDuplicateJob instead of creating one.
+	UniqueKey string `json:"unique_key,omitempty"`
+	// UniqueFor is how long UniqueKey is held. Default: 1h.
+	UniqueFor Duration `json:"unique_for,omitempty"`
 }
 
 // HandlerFunc processes a single job.
@@ -205,6 +213,26 @@ type ClaimFilter struct {
 	Types  []string
 }
 
+// matches reports whether job passes the queue and type filters. Empty
+// entries are ignored, like in ClaimReady.
+func (f ClaimFilter) matches(job Job) bool {
+	return matchesAny(f.Queues, job.Queue) && matchesAny(f.Types, job.Type)
+}
+
+func matchesAny(allowed []string, value string) bool {
+	restricted := false
+	for _, candidate := range allowed {
+		if candidate == "" {
+			continue
+		}
+		if candidate == value {
+			return true
+		}
+		restricted = true
+	}
+	return !restricted
+}
+
 // QueueStats describes aggregate queue state.
 type QueueStats struct {
 	Name      string         `json:"name"`
@@ -232,6 +260,8 @@ type HealthSnapshot struct {
 	StartedAt         time.Time `json:"started_at,omitempty"`
 	ConfiguredWorkers int       `json:"configured_workers"`
 	ActiveWorkers     int64     `json:"active_workers"`
+	// LastScanAt is when the dispatcher last polled the store successfully.
+	LastScanAt *time.Time `json:"last_scan_at,omitempty"`
 }
 
 // Config controls the manager runtime.
@@ -250,6 +280,9 @@ type Config struct {
 	AllowEnqueueWithoutHandler bool
 	Logger                     logger.LogManager
 	Registerer                 prometheus.Registerer
+	// DrainTimeout bounds how long Stop waits for running and buffered jobs
+	// before cancelling them. Default: 30s.
+	DrainTimeout time.Duration
 }
 
 // DefaultConfig returns production-safe defaults for a job manager.
@@ -266,6 +299,7 @@ func DefaultConfig() Config {
 		DefaultTimeout:             30 * time.Second,
 		RetryBaseDelay:             1 * time.Second,
 		RetryMaxDelay:              30 * time.Second,
+		DrainTimeout:               30 * time.Second,
 		AllowEnqueueWithoutHandler: false,
 	}
 }
@@ -0,0 +1,245 @@
+// Package lock provides distributed locks for work that must run on one
+// replica at a time, such as one-time bootstrap tasks, role syncs, and long
+// scheduled jobs. Locks are leases: they expire unless renewed, and every
+// acquisition carries a fencing token that increases per key, so storage that
+// records the token can reject writes from a holder whose lease has lapsed.
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTTL is the lease duration when WithTTL is not given.
+	DefaultTTL = 30 * time.Second
+	// DefaultRetryInterval is how often Acquire retries a held lock.
+	DefaultRetryInterval = 100 * time.Millisecond
+)
+
+var (
+	// ErrNotAcquired is returned by TryAcquire when another holder has the lock.
+	ErrNotAcquired = errors.New("lock: not acquired")
+	// ErrLockLost is returned by Release, and by WithLock, when the lease
+	// expired or could not be renewed before it was released.
+	ErrLockLost = errors.New("lock: lease lost")
+)
+
+// Locker acquires distributed locks.
+type Locker interface {
+	// TryAcquire claims key once, returning ErrNotAcquired when it is held.
+	TryAcquire(ctx context.Context, key string, opts ...Option) (Lock, error)
+	// Acquire waits for key until it is claimed or ctx is done.
+	Acquire(ctx context.Context, key string, opts ...Option) (Lock, error)
+}
+
+// Lock is a held lease.
+type Lock interface {
+	// Key returns the locked key.
+	Key() string
+	// Token returns the fencing token of this acquisition. Tokens of a key
+	// increase with every acquisition.
+	Token() uint64
+	// Lost is closed when the lease expires or can no longer be renewed. Work
+	// under the lock must stop when it fires.
+	Lost() <-chan struct{}
+	// Release stops renewal and frees the lock. It returns ErrLockLost when
+	// the lease was lost before it was released.
+	Release(ctx context.Context) error
+}
+
+// Option configures an acquisition.
+type Option func(*options)
+
+type options struct {
+	ttl           time.Duration
+	renew         bool
+	retryInterval time.Duration
+}
+
+func newOptions(opts []Option) options {
+	o := options{ttl: DefaultTTL, renew: true, retryInterval: DefaultRetryInterval}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+// WithTTL sets the lease duration. Renewed leases are extended every third of
+// it. Default: DefaultTTL.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		if ttl > 0 {
+			o.ttl = ttl
+		}
+	}
+}
+
+// WithoutRenewal lets the lease expire after its TTL even while held.
+func WithoutRenewal() Option {
+	return func(o *options) { o.renew = false }
+}
+
+// WithRetryInterval sets how often Acquire retries. Default: DefaultRetryInterval.
+func WithRetryInterval(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.retryInterval = d
+		}
+	}
+}
+
+// WithLock runs fn while holding key. fn's context is cancelled when the
+// lease is lost; the lock is released when fn returns.
+//
+//	err := lock.WithLock(ctx, locker, "roles:sync", func(ctx context.Context) error {
+//		return roles.Sync(ctx, definitions, cfg, log)
+//	})
+func WithLock(ctx context.Context, locker Locker, key string, fn func(ctx context.Context) error, opts ...Option) error {
+	l, err := locker.Acquire(ctx, key, opts...)
+	if err != nil {
+		return err
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-l.Lost():
+			cancel()
+		case <-runCtx.Done():
+		}
+	}()
+
+	fnErr := fn(runCtx)
+	cancel()
+	releaseErr := l.Release(context.WithoutCancel(ctx))
+	return errors.Join(fnErr, releaseErr)
+}
+
+// acquire retries try until it claims key or ctx is done.
+func acquire(ctx context.Context, key string, o options, try func(context.Context, string, options) (Lock, error)) (Lock, error) {
+	for {
+		l, err := try(ctx, key, o)
+		if !errors.Is(err, ErrNotAcquired) {
+			return l, err
+		}
+		timer := time.NewTimer(o.retryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("lock: acquire %s: %w", key, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// leaseBackend is the store-specific part of a held lease.
+type leaseBackend interface {
+	// renew extends the lease by ttl, reporting false when it is no longer held.
+	renew(ctx context.Context, ttl time.Duration) (bool, error)
+	// release frees the lock if still held. It may be called more than once.
+	release(ctx context.Context) error
+}
+
+// lease implements Lock on a leaseBackend, renewing it in the background.
+type lease struct {
+	key     string
+	token   uint64
+	ttl     time.Duration
+	backend leaseBackend
+
+	lost     chan struct{}
+	lostOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+
+	releaseOnce sync.Once
+	releaseErr  error
+}
+
+func newLease(key string, token uint64, o options, backend leaseBackend) *lease {
+	l := &lease{
+		key:     key,
+		token:   token,
+		ttl:     o.ttl,
+		backend: backend,
+		lost:    make(chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if o.renew {
+		go l.renewLoop()
+	} else {
+		go l.expireLoop()
+	}
+	return l
+}
+
+func (l *lease) Key() string           { return l.key }
+func (l *lease) Token() uint64         { return l.token }
+func (l *lease) Lost() <-chan struct{} { return l.lost }
+
+func (l *lease) Release(ctx context.Context) error {
+	l.releaseOnce.Do(func() {
+		close(l.stop)
+		<-l.done
+		err := l.backend.release(ctx)
+		select {
+		case <-l.lost:
+			err = errors.Join(ErrLockLost, err)
+		default:
+		}
+		l.releaseErr = err
+	})
+	return l.releaseErr
+}
+
+func (l *lease) markLost() {
+	l.lostOnce.Do(func() { close(l.lost) })
+}
+
+// renewLoop extends the lease every third of its TTL. Failed renewals are
+// retried until the lease would have expired.
+func (l *lease) renewLoop() {
+	defer close(l.done)
+	interval := l.ttl / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	expiresAt := time.Now().Add(l.ttl)
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+		started := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		held, err := l.backend.renew(ctx, l.ttl)
+		cancel()
+		switch {
+		case err == nil && held:
+			expiresAt = started.Add(l.ttl)
+		case err == nil || !time.Now().Before(expiresAt):
+			l.markLost()
+			return
+		}
+	}
+}
+
+// expireLoop ends a lease without renewal after its TTL. Backends whose locks
+// do not expire on their own are released then.
+func (l *lease) expireLoop() {
+	defer close(l.done)
+	timer := time.NewTimer(l.ttl)
+	defer timer.Stop()
+	select {
+	case <-l.stop:
+	case <-timer.C:
+		l.markLost()
+		_ = l.backend.release(context.Background())
+	}
+}
@@ -0,0 +1,202 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redis "github.com/redis/go-redis/v9"
+
+	"github.com/milan604/core-lab/pkg/postgres/migrations"
+)
+
+func newRedisClient(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	t.Helper()
+	mini := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mini.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+	return mini, client
+}
+
+func TestRedisLockerFencesAcquisitions(t *testing.T) {
+	_, client := newRedisClient(t)
+	locker := NewRedisLocker(client)
+	ctx := context.Background()
+
+	first, err := locker.TryAcquire(ctx, "bootstrap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := locker.TryAcquire(ctx, "bootstrap"); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("second TryAcquire = %v, want ErrNotAcquired", err)
+	}
+	if err := first.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := locker.TryAcquire(ctx, "bootstrap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Release(ctx)
+	if first.Token() != 1 || second.Token() != 2 || second.Key() != "bootstrap" {
+		t.Fatalf("tokens = %d, %d", first.Token(), second.Token())
+	}
+	// Releasing a stale lease leaves the new holder alone.
+	if err := first.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := locker.TryAcquire(ctx, "bootstrap"); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("stale release freed the lock: %v", err)
+	}
+}
+
+func TestRedlockRequiresMajority(t *testing.T) {
+	var clients []redis.UniversalClient
+	var instances []*miniredis.Miniredis
+	for range 3 {
+		mini, client := newRedisClient(t)
+		instances = append(instances, mini)
+		clients = append(clients, client)
+	}
+	locker := NewRedlock(clients)
+	ctx := context.Background()
+
+	// One instance held elsewhere: a majority still grants the lock.
+	if err := instances[0].Set("lock:{sync}", "other"); err != nil {
+		t.Fatal(err)
+	}
+	l, err := locker.TryAcquire(ctx, "sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two held elsewhere: no majority, and the one granted claim is undone.
+	if err := instances[1].Set("lock:{sync}", "other"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := locker.TryAcquire(ctx, "sync"); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("TryAcquire = %v, want ErrNotAcquired", err)
+	}
+	if instances[2].Exists("lock:{sync}") {
+		t.Fatal("minority claim was not released")
+	}
+
+	// Tokens stay monotonic whichever majority grants the next lock.
+	instances[0].Del("lock:{sync}")
+	instances[1].Del("lock:{sync}")
+	instances[2].Close()
+	next, err := locker.TryAcquire(ctx, "sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer next.Release(ctx)
+	if next.Token() <= l.Token() {
+		t.Fatalf("token %d not above previous %d", next.Token(), l.Token())
+	}
+}
+
+func TestLeaseRenewalAndLoss(t *testing.T) {
+	mini, client := newRedisClient(t)
+	locker := NewRedisLocker(client)
+	ctx := context.Background()
+
+	l, err := locker.TryAcquire(ctx, "export", WithTTL(90*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mini.FastForward(60 * time.Millisecond)
+	time.Sleep(60 * time.Millisecond)
+	if ttl := mini.TTL("lock:{export}"); ttl <= 60*time.Millisecond {
+		t.Fatalf("lease not renewed, ttl = %v", ttl)
+	}
+
+	mini.Del("lock:{export}")
+	select {
+	case <-l.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("Lost did not fire")
+	}
+	if err := l.Release(ctx); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("Release = %v, want ErrLockLost", err)
+	}
+
+	short, err := locker.TryAcquire(ctx, "report", WithTTL(30*time.Millisecond), WithoutRenewal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-short.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("unrenewed lease did not expire")
+	}
+}
+
+func TestAcquireWaitsForRelease(t *testing.T) {
+	_, client := newRedisClient(t)
+	locker := NewRedisLocker(client)
+	ctx := context.Background()
+
+	held, err := locker.TryAcquire(ctx, "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer cancel()
+	if _, err := locker.Acquire(timeout, "migrate", WithRetryInterval(5*time.Millisecond)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire = %v, want deadline exceeded", err)
+	}
+
+	time.AfterFunc(20*time.Millisecond, func() { _ = held.Release(ctx) })
+	next, err := locker.Acquire(ctx, "migrate", WithRetryInterval(5*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = next.Release(ctx)
+}
+
+func TestWithLockCancelsWorkWhenLeaseIsLost(t *testing.T) {
+	mini, client := newRedisClient(t)
+	locker := NewRedisLocker(client)
+
+	err := WithLock(context.Background(), locker, "roles:sync", func(ctx context.Context) error {
+		mini.Del("lock:{roles:sync}")
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTTL(30*time.Millisecond))
+	if !errors.Is(err, ErrLockLost) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("WithLock = %v", err)
+	}
+
+	ran := false
+	if err := WithLock(context.Background(), locker, "roles:sync", func(context.Context) error {
+		ran = true
+		return nil
+	}); err != nil || !ran {
+		t.Fatalf("WithLock = %v ran = %v", err, ran)
+	}
+	if mini.Exists("lock:{roles:sync}") {
+		t.Fatal("lock not released")
+	}
+}
+
+func TestPostgresMigrationsPassLint(t *testing.T) {
+	report, err := migrations.Lint(postgresMigrationFiles, migrations.WithDir("migrations"), migrations.WithWarningsAsErrors())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := report.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewPostgresLockerRequiresDB(t *testing.T) {
+	if _, err := NewPostgresLocker(nil); err == nil {
+		t.Fatal("expected an error for a nil database")
+	}
+}
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS platform_lock_fences (
+    key        text PRIMARY KEY,
+    token      bigint NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
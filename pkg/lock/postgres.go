@@ -0,0 +1,115 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/apperr"
+	"github.com/milan604/core-lab/pkg/postgres"
+)
+
+const (
+	// PostgresFencesTable holds the fencing counters of a PostgresLocker.
+	PostgresFencesTable = "platform_lock_fences"
+	// PostgresMigrationsTable records the applied version of PostgresMigrations.
+	PostgresMigrationsTable = "platform_lock_schema_migrations"
+)
+
+//go:embed migrations/*.sql
+var postgresMigrationFiles embed.FS
+
+// PostgresMigrations returns the migrations creating PostgresFencesTable, for
+// postgres.DB.Migrate. Their version is tracked in PostgresMigrationsTable.
+func PostgresMigrations() postgres.MigrationSource {
+	return postgres.MigrationsFS(postgresMigrationFiles, "migrations").WithMigrationsTable(PostgresMigrationsTable)
+}
+
+// PostgresLocker implements Locker with session-level advisory locks
+// (pg_try_advisory_lock on a 64-bit hash of the key), each held on a dedicated
+// connection from the primary's pool. Postgres frees the lock when that
+// connection drops, so renewal checks the connection instead of extending a
+// server-side expiry. Fencing tokens are counted in PostgresFencesTable.
+type PostgresLocker struct {
+	db *postgres.DB
+}
+
+// NewPostgresLocker returns a Locker on the primary of db. Apply
+// PostgresMigrations first.
+func NewPostgresLocker(db *postgres.DB) (*PostgresLocker, error) {
+	if db == nil || db.Client == nil {
+		return nil, apperr.New(apperr.ErrorCodeInvalidInput).
+			WithMessage("postgres database is required")
+	}
+	return &PostgresLocker{db: db}, nil
+}
+
+// TryAcquire implements Locker.
+func (l *PostgresLocker) TryAcquire(ctx context.Context, key string, opts ...Option) (Lock, error) {
+	return l.tryAcquire(ctx, key, newOptions(opts))
+}
+
+// Acquire implements Locker.
+func (l *PostgresLocker) Acquire(ctx context.Context, key string, opts ...Option) (Lock, error) {
+	return acquire(ctx, key, newOptions(opts), l.tryAcquire)
+}
+
+func (l *PostgresLocker) tryAcquire(ctx context.Context, key string, o options) (Lock, error) {
+	sqlDB, err := l.db.Client.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("lock: acquire %s: %w", key, err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtextextended($1, 0))`, key).Scan(&acquired); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("lock: acquire %s: %w", key, err)
+	}
+	if !acquired {
+		_ = conn.Close()
+		return nil, ErrNotAcquired
+	}
+
+	backend := &postgresLease{conn: conn, key: key}
+	var fence int64
+	err = conn.QueryRowContext(ctx, `INSERT INTO `+PostgresFencesTable+` (key, token, updated_at)
+VALUES ($1, 1, now())
+ON CONFLICT (key) DO UPDATE SET token = `+PostgresFencesTable+`.token + 1, updated_at = now()
+RETURNING token`, key).Scan(&fence)
+	if err != nil {
+		_ = backend.release(context.WithoutCancel(ctx))
+		return nil, fmt.Errorf("lock: record fencing token for %s: %w", key, err)
+	}
+	return newLease(key, uint64(fence), o, backend), nil
+}
+
+type postgresLease struct {
+	conn *sql.Conn
+	key  string
+
+	once sync.Once
+	err  error
+}
+
+// renew checks the session holding the lock is still alive. A session that
+// does not answer may have lost the lock, so the lease is given up.
+func (p *postgresLease) renew(ctx context.Context, _ time.Duration) (bool, error) {
+	return p.conn.PingContext(ctx) == nil, nil
+}
+
+func (p *postgresLease) release(ctx context.Context) error {
+	p.once.Do(func() {
+		defer p.conn.Close()
+		if _, err := p.conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtextextended($1, 0))`, p.key); err != nil {
+			p.err = fmt.Errorf("lock: release %s: %w", p.key, err)
+		}
+	})
+	return p.err
+}
@@ -0,0 +1,198 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisPrefix prefixes the keys RedisLocker writes.
+const DefaultRedisPrefix = "lock:"
+
+// acquireScript sets the lock and, when it was free, increments the key's
+// fencing counter, returning the new value (0 when the lock is held).
+var acquireScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+  return redis.call('INCR', KEYS[2])
+end
+return 0
+`)
+
+// fenceScript raises the fencing counter to ARGV[1], so every instance of a
+// quorum has seen the token handed out.
+var fenceScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+if current < tonumber(ARGV[1]) then
+  redis.call('SET', KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+var renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// RedisLocker implements Locker on one Redis deployment, or redlock-style on
+// several independent ones: a lock is held when a majority of instances
+// granted it within its TTL, allowing for clock drift.
+//
+// Keys are "<prefix>{<key>}" with a "<prefix>{<key>}:fence" counter, so both
+// land in one Redis Cluster slot. Fencing counters are not expired.
+type RedisLocker struct {
+	clients []redis.UniversalClient
+	prefix  string
+}
+
+// RedisOption configures a RedisLocker.
+type RedisOption func(*RedisLocker)
+
+// WithRedisPrefix replaces DefaultRedisPrefix.
+func WithRedisPrefix(prefix string) RedisOption {
+	return func(l *RedisLocker) { l.prefix = prefix }
+}
+
+// NewRedisLocker returns a Locker on client.
+func NewRedisLocker(client redis.UniversalClient, opts ...RedisOption) *RedisLocker {
+	return NewRedlock([]redis.UniversalClient{client}, opts...)
+}
+
+// NewRedlock returns a Locker requiring a majority of clients, which should
+// be independent Redis deployments (not replicas of one another).
+func NewRedlock(clients []redis.UniversalClient, opts ...RedisOption) *RedisLocker {
+	l := &RedisLocker{clients: clients, prefix: DefaultRedisPrefix}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(l)
+		}
+	}
+	return l
+}
+
+// TryAcquire implements Locker.
+func (l *RedisLocker) TryAcquire(ctx context.Context, key string, opts ...Option) (Lock, error) {
+	return l.tryAcquire(ctx, key, newOptions(opts))
+}
+
+// Acquire implements Locker.
+func (l *RedisLocker) Acquire(ctx context.Context, key string, opts ...Option) (Lock, error) {
+	return acquire(ctx, key, newOptions(opts), l.tryAcquire)
+}
+
+func (l *RedisLocker) tryAcquire(ctx context.Context, key string, o options) (Lock, error) {
+	if len(l.clients) == 0 {
+		return nil, fmt.Errorf("lock: no redis clients configured")
+	}
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	backend := &redisLease{locker: l, lockKey: l.prefix + "{" + key + "}", value: token}
+	fenceKey := backend.lockKey + ":fence"
+
+	started := time.Now()
+	var (
+		granted []redis.UniversalClient
+		fence   int64
+		errs    []error
+	)
+	for _, client := range l.clients {
+		n, err := acquireScript.Run(ctx, client, []string{backend.lockKey, fenceKey}, token, o.ttl.Milliseconds()).Int64()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if n > 0 {
+			granted = append(granted, client)
+			fence = max(fence, n)
+		}
+	}
+
+	// Redlock: the lock is valid for what remains of the TTL after acquisition
+	// and an allowance for clock drift between instances.
+	drift := o.ttl/100 + 2*time.Millisecond
+	if len(granted) < l.quorum() || time.Since(started)+drift >= o.ttl {
+		_ = backend.release(context.WithoutCancel(ctx))
+		if len(granted) == 0 && len(errs) == len(l.clients) {
+			return nil, fmt.Errorf("lock: acquire %s: %w", key, errors.Join(errs...))
+		}
+		return nil, ErrNotAcquired
+	}
+
+	// Raise every granting instance's counter to the token handed out, so any
+	// later majority includes an instance that has seen it.
+	for _, client := range granted {
+		if err := fenceScript.Run(ctx, client, []string{fenceKey}, fence).Err(); err != nil {
+			_ = backend.release(context.WithoutCancel(ctx))
+			return nil, fmt.Errorf("lock: record fencing token for %s: %w", key, err)
+		}
+	}
+	return newLease(key, uint64(fence), o, backend), nil
+}
+
+func (l *RedisLocker) quorum() int {
+	return len(l.clients)/2 + 1
+}
+
+type redisLease struct {
+	locker  *RedisLocker
+	lockKey string
+	value   string
+}
+
+// renew extends the lock on every instance and reports whether a majority
+// still holds it.
+func (r *redisLease) renew(ctx context.Context, ttl time.Duration) (bool, error) {
+	var held int
+	var errs []error
+	for _, client := range r.locker.clients {
+		n, err := renewScript.Run(ctx, client, []string{r.lockKey}, r.value, ttl.Milliseconds()).Int64()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if n > 0 {
+			held++
+		}
+	}
+	if held >= r.locker.quorum() {
+		return true, nil
+	}
+	if len(errs) > 0 {
+		return false, errors.Join(errs...)
+	}
+	return false, nil
+}
+
+// release deletes the lock wherever this lease still holds it.
+func (r *redisLease) release(ctx context.Context) error {
+	var errs []error
+	for _, client := range r.locker.clients {
+		if err := releaseScript.Run(ctx, client, []string{r.lockKey}, r.value).Err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -30,3 +30,12 @@ func withContext(ctx context.Context) []any {
 	}
 	return fields
 }
+
+// ContextFields returns the values of registered context keys found in ctx as
+// alternating field names and values, the fields the *FCtx methods add.
+func ContextFields(ctx context.Context) []any {
+	if ctx == nil {
+		return nil
+	}
+	return withContext(ctx)
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ShutdownHookTimeout bounds how long Exit waits for the registered shutdown
+// hooks together.
+const ShutdownHookTimeout = 10 * time.Second
+
+// ShutdownHook releases a resource before the process exits, e.g. flushing
+// traces or closing a publisher.
+type ShutdownHook func(ctx context.Context) error
+
+type shutdownHookEntry struct {
+	fn ShutdownHook
+}
+
+var (
+	shutdownHooksMu sync.Mutex
+	shutdownHooks   []*shutdownHookEntry
+	exiting         atomic.Bool
+)
+
+// RegisterShutdownHook registers fn to run when Fatal or Exit ends the
+// process, so cleanup normally done by deferred calls is not skipped. It
+// returns a function that unregisters fn; call it once the resource has been
+// released on the normal path.
+func RegisterShutdownHook(fn ShutdownHook) (unregister func()) {
+	if fn == nil {
+		return func() {}
+	}
+	entry := &shutdownHookEntry{fn: fn}
+	shutdownHooksMu.Lock()
+	shutdownHooks = append(shutdownHooks, entry)
+	shutdownHooksMu.Unlock()
+
+	return func() {
+		shutdownHooksMu.Lock()
+		defer shutdownHooksMu.Unlock()
+		for i, registered := range shutdownHooks {
+			if registered == entry {
+				shutdownHooks = append(shutdownHooks[:i], shutdownHooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Exit runs the registered shutdown hooks, newest first, and exits with code.
+// Use it instead of os.Exit. A hook that fails or panics is reported on
+// stderr and the remaining hooks still run. Once ShutdownHookTimeout passes,
+// Exit stops waiting for a hook that ignores its context and skips the rest.
+// Concurrent calls wait for the first one to exit.
+func Exit(code int) {
+	if exiting.CompareAndSwap(false, true) {
+		ctx, cancel := context.WithTimeout(context.Background(), ShutdownHookTimeout)
+		runShutdownHooks(ctx)
+		cancel()
+		os.Exit(code)
+	}
+	// Another call is running the hooks. Give it time to finish, then exit
+	// anyway in case this call came from one of the hooks.
+	time.Sleep(ShutdownHookTimeout)
+	os.Exit(code)
+}
+
+// runShutdownHooks runs the hooks newest first until ctx is done.
+func runShutdownHooks(ctx context.Context) {
+	shutdownHooksMu.Lock()
+	hooks := append([]*shutdownHookEntry(nil), shutdownHooks...)
+	shutdownHooksMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "logger: skipped %d shutdown hooks: %v\n", i+1, ctx.Err())
+			return
+		}
+		if err := runShutdownHook(ctx, hooks[i].fn); err != nil {
+			fmt.Fprintf(os.Stderr, "logger: shutdown hook failed: %v\n", err)
+		}
+	}
+}
+
+// runShutdownHook runs fn in its own goroutine, so a hook that ignores ctx
+// cannot hold up the exit past the deadline.
+func runShutdownHook(ctx context.Context, fn ShutdownHook) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("abandoned: %w", ctx.Err())
+	}
+}
+
+// exitHook is zap's fatal hook: the entry has been written and synced when it
+// runs, so it only has to run the shutdown hooks and exit.
+type exitHook struct{}
+
+func (exitHook) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {
+	Exit(1)
+}
@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunShutdownHooksHonoursDeadline(t *testing.T) {
+	var mu sync.Mutex
+	var ran []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, name)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	unregisterSkipped := RegisterShutdownHook(func(context.Context) error {
+		record("skipped")
+		return nil
+	})
+	defer unregisterSkipped()
+	unregisterBlocking := RegisterShutdownHook(func(context.Context) error {
+		record("blocking")
+		<-release // ignores its context
+		return nil
+	})
+	defer unregisterBlocking()
+	unregisterPanicking := RegisterShutdownHook(func(context.Context) error {
+		record("panicking")
+		panic("boom")
+	})
+	defer unregisterPanicking()
+	unregisterFailing := RegisterShutdownHook(func(context.Context) error {
+		record("failing")
+		return errors.New("flush failed")
+	})
+	defer unregisterFailing()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	runShutdownHooks(ctx)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("runShutdownHooks took %s, want it bounded by the deadline", elapsed)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"failing", "panicking", "blocking"}
+	if len(ran) != len(want) {
+		t.Fatalf("ran %v, want %v", ran, want)
+	}
+	for i := range want {
+		if ran[i] != want[i] {
+			t.Fatalf("ran %v, want %v", ran, want)
+		}
+	}
+}
+
+func TestRegisterShutdownHookUnregister(t *testing.T) {
+	called := false
+	unregister := RegisterShutdownHook(func(context.Context) error {
+		called = true
+		return nil
+	})
+	unregister()
+	unregister()
+
+	runShutdownHooks(context.Background())
+	if called {
+		t.Fatal("unregistered hook ran")
+	}
+}
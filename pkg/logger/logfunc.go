@@ -52,6 +52,26 @@ func (l *logger) ErrorFCtx(ctx context.Context, format string, args ...any) {
 	l.Log.With(withContext(ctx)...).Error(fmt.Sprintf(format, args...))
 }
 
+func (l *logger) DPanic(args ...any) {
+	l.Log.DPanic(args...)
+}
+func (l *logger) DPanicF(format string, args ...any) {
+	l.Log.DPanic(fmt.Sprintf(format, args...))
+}
+func (l *logger) DPanicFCtx(ctx context.Context, format string, args ...any) {
+	l.Log.With(withContext(ctx)...).DPanic(fmt.Sprintf(format, args...))
+}
+
+func (l *logger) Fatal(args ...any) {
+	l.Log.Fatal(args...)
+}
+func (l *logger) FatalF(format string, args ...any) {
+	l.Log.Fatal(fmt.Sprintf(format, args...))
+}
+func (l *logger) FatalFCtx(ctx context.Context, format string, args ...any) {
+	l.Log.With(withContext(ctx)...).Fatal(fmt.Sprintf(format, args...))
+}
+
 func (l *logger) With(fields ...any) LogManager {
 	return &logger{
 		Log:         l.Log.With(fields...),
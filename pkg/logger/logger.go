@@ -49,6 +49,18 @@ type LogManager interface {
 	WarnFCtx(ctx context.Context, format string, args ...any)
 	ErrorFCtx(ctx context.Context, format string, args ...any)
 
+	// DPanic logs at error level in production; in development (debug level)
+	// it panics after the entry has been written and exported.
+	DPanic(args ...any)
+	DPanicF(format string, args ...any)
+	DPanicFCtx(ctx context.Context, format string, args ...any)
+
+	// Fatal logs, flushes the log exporters, runs the shutdown hooks
+	// registered with RegisterShutdownHook, and exits with status 1.
+	Fatal(args ...any)
+	FatalF(format string, args ...any)
+	FatalFCtx(ctx context.Context, format string, args ...any)
+
 	With(keyValues ...any) LogManager
 
 	Sync() error
@@ -119,7 +131,7 @@ func NewLogger(opts LoggerOptions) (LogManager, error) {
 		cfg.EncoderConfig.CallerKey = ""
 	}
 
-	zapLogger, err := cfg.Build(zap.AddStacktrace(zap.ErrorLevel))
+	zapLogger, err := cfg.Build(zap.AddStacktrace(zap.ErrorLevel), zap.WithFatalHook(exitHook{}))
 	if err != nil {
 		return nil, err
 	}
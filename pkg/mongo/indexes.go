@@ -0,0 +1,61 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mongooptions "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Index declares an index of a collection in DB.Database, for EnsureIndexes.
+type Index struct {
+	Collection string
+	// Keys is an ordered document such as bson.D; maps are rejected.
+	Keys any
+	// Options, from the driver's options.Index(), may be nil.
+	Options *mongooptions.IndexOptionsBuilder
+}
+
+// EnsureIndexes creates indexes at startup, one createIndexes command per
+// collection. Indexes that already exist with the same keys and options are
+// left as they are, so it is safe to call on every start; an existing index
+// with the same name but different options makes it fail. Every collection is
+// attempted and the failures are joined into the returned error.
+//
+//	err := db.EnsureIndexes(ctx,
+//		mongo.Index{Collection: "orders", Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}}},
+//		mongo.Index{Collection: "orders", Keys: bson.D{{Key: "reference", Value: 1}}, Options: options.Index().SetUnique(true)},
+//	)
+func (db *DB) EnsureIndexes(ctx context.Context, indexes ...Index) error {
+	if db.Database == nil {
+		return errors.New("mongo: EnsureIndexes needs Config.Name")
+	}
+
+	var order []string
+	byCollection := make(map[string][]mongo.IndexModel)
+	for _, index := range indexes {
+		if index.Collection == "" {
+			return errors.New("mongo: index collection is required")
+		}
+		if _, ok := byCollection[index.Collection]; !ok {
+			order = append(order, index.Collection)
+		}
+		byCollection[index.Collection] = append(byCollection[index.Collection], mongo.IndexModel{Keys: index.Keys, Options: index.Options})
+	}
+
+	var errs []error
+	for _, collection := range order {
+		names, err := db.Database.Collection(collection).Indexes().CreateMany(ctx, byCollection[collection])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mongo: create indexes on %s: %w", collection, err))
+			continue
+		}
+		if db.log != nil {
+			db.log.InfoFCtx(ctx, "mongo: indexes ensured on %s: %s", collection, strings.Join(names, ", "))
+		}
+	}
+	return errors.Join(errs...)
+}
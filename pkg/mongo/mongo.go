@@ -0,0 +1,299 @@
+package mongo
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/logger"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	mongooptions "go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+// DefaultDisconnectTimeout bounds Disconnect when its context has no deadline.
+const DefaultDisconnectTimeout = 10 * time.Second
+
+// Config describes a MongoDB connection. It mirrors postgres.Config; set URI
+// for replica sets, SRV records or driver options not listed here.
+type Config struct {
+	// URI is a mongodb:// or mongodb+srv:// connection string. When set, Host
+	// and Port are ignored; credentials set below still override the URI's.
+	URI  string
+	Host string
+	Port string
+	// Name is the database returned as DB.Database.
+	Name     string
+	Username string
+	Password string
+	// AuthSource is the database holding the user's credentials ("admin" by
+	// default on the server).
+	AuthSource string
+	ReplicaSet string
+	AppName    string
+	// TLS enables TLS verified against the system roots.
+	TLS bool
+	// TLSRootCert is the CA bundle used to verify the server: either PEM
+	// content (e.g. from a secret) or a file path. Setting it enables TLS.
+	TLSRootCert string
+
+	// Connection pool settings; zero keeps the driver default.
+	MaxPoolSize     uint64
+	MinPoolSize     uint64
+	MaxConnIdleTime time.Duration
+	// ConnectTimeout bounds dialing and the initial ping (10s by default).
+	ConnectTimeout time.Duration
+}
+
+// DB holds a MongoDB client and the database named in Config.
+type DB struct {
+	Client   *mongo.Client
+	Database *mongo.Database
+	// URI describes the connection, with the password and secret options
+	// masked.
+	URI string
+
+	log            logger.LogManager
+	disconnectOnce sync.Once
+	disconnectErr  error
+	unregisterHook func()
+}
+
+// New connects to the deployment described by cfg and verifies it with a
+// ping to the primary. The client is disconnected by logger.Fatal and
+// logger.Exit through a shutdown hook; call Disconnect on the normal shutdown
+// path, e.g. from app.OnShutdown.
+func New(cfg Config, opts ...Option) (*DB, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	connectTimeout := cfg.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = 10 * time.Second
+	}
+	clientOpts, uri, err := clientOptions(cfg, connectTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if o.obs != nil {
+		observer := NewCommandObserver(CommandObserverConfig{
+			Database:      cfg.Name,
+			Tracer:        o.obs.GetTracer(),
+			Logger:        o.log,
+			SlowThreshold: o.slowThreshold,
+		})
+		clientOpts.SetMonitor(observer.Monitor())
+	}
+
+	client, err := mongo.Connect(clientOpts)
+	if err != nil {
+		return nil, fmt.Errorf("mongo: connect: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, fmt.Errorf("mongo: ping: %w", err)
+	}
+
+	db := &DB{Client: client, URI: maskURI(uri), log: o.log}
+	if cfg.Name != "" {
+		db.Database = client.Database(cfg.Name)
+	}
+	db.unregisterHook = logger.RegisterShutdownHook(db.disconnect)
+	logConnection(cfg, db.URI)
+	return db, nil
+}
+
+// Collection returns the named collection of DB.Database.
+func (db *DB) Collection(name string) *mongo.Collection {
+	return db.Database.Collection(name)
+}
+
+// Disconnect waits for in-use connections to be returned, then closes the
+// client. Without a deadline on ctx it waits at most
+// DefaultDisconnectTimeout, after which in-flight operations are cut off. It
+// is safe to call more than once; later calls return the first result.
+func (db *DB) Disconnect(ctx context.Context) error {
+	if db.unregisterHook != nil {
+		db.unregisterHook()
+	}
+	return db.disconnect(ctx)
+}
+
+func (db *DB) disconnect(ctx context.Context) error {
+	db.disconnectOnce.Do(func() {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, DefaultDisconnectTimeout)
+			defer cancel()
+		}
+		if err := db.Client.Disconnect(ctx); err != nil && !errors.Is(err, mongo.ErrClientDisconnected) {
+			db.disconnectErr = fmt.Errorf("mongo: disconnect: %w", err)
+		}
+	})
+	return db.disconnectErr
+}
+
+// clientOptions translates cfg into driver options and returns the URI they
+// were built from.
+func clientOptions(cfg Config, connectTimeout time.Duration) (*mongooptions.ClientOptions, string, error) {
+	uri := strings.TrimSpace(cfg.URI)
+	if uri == "" {
+		if cfg.Host == "" {
+			return nil, "", errors.New("mongo: URI or Host is required")
+		}
+		port := cfg.Port
+		if port == "" {
+			port = "27017"
+		}
+		uri = "mongodb://" + net.JoinHostPort(cfg.Host, port) + "/"
+	}
+
+	opts := mongooptions.Client().ApplyURI(uri).
+		SetConnectTimeout(connectTimeout).
+		SetServerSelectionTimeout(connectTimeout)
+	if cfg.Username != "" {
+		opts.SetAuth(mongooptions.Credential{
+			Username:    cfg.Username,
+			Password:    cfg.Password,
+			PasswordSet: cfg.Password != "",
+			AuthSource:  cfg.AuthSource,
+		})
+	}
+	if cfg.ReplicaSet != "" {
+		opts.SetReplicaSet(cfg.ReplicaSet)
+	}
+	if cfg.AppName != "" {
+		opts.SetAppName(cfg.AppName)
+	}
+	switch {
+	case strings.TrimSpace(cfg.TLSRootCert) != "":
+		tlsCfg, err := rootCertTLSConfig(cfg.TLSRootCert)
+		if err != nil {
+			return nil, "", err
+		}
+		opts.SetTLSConfig(tlsCfg)
+	case cfg.TLS:
+		opts.SetTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	if cfg.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(cfg.MaxPoolSize)
+	}
+	if cfg.MinPoolSize > 0 {
+		opts.SetMinPoolSize(cfg.MinPoolSize)
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		opts.SetMaxConnIdleTime(cfg.MaxConnIdleTime)
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, "", fmt.Errorf("mongo: %w", err)
+	}
+	return opts, uri, nil
+}
+
+// rootCertTLSConfig verifies the server against the CA bundle in rootCert,
+// given as PEM content or a file path.
+func rootCertTLSConfig(rootCert string) (*tls.Config, error) {
+	pemBytes := []byte(rootCert)
+	if !strings.Contains(rootCert, "-----BEGIN") {
+		data, err := os.ReadFile(rootCert)
+		if err != nil {
+			return nil, fmt.Errorf("mongo: read TLSRootCert: %w", err)
+		}
+		pemBytes = data
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemBytes) {
+		return nil, errors.New("mongo: TLSRootCert contains no PEM certificates")
+	}
+	return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
+}
+
+// sensitiveURIOptions are connection string options whose values are
+// secrets, keyed in lower case since option names are case-insensitive.
+var sensitiveURIOptions = map[string]bool{
+	"tlscertificatekeyfilepassword":   true,
+	"sslclientcertificatekeypassword": true,
+}
+
+// maskURI redacts secrets from a connection string when logging: the
+// userinfo password, the values of sensitiveURIOptions, and an
+// AWS_SESSION_TOKEN in authMechanismProperties. Host lists such as
+// "a:27017,b:27017" are kept as they are.
+func maskURI(uri string) string {
+	scheme := strings.Index(uri, "://")
+	if scheme == -1 {
+		return uri
+	}
+	prefix, rest := uri[:scheme+3], uri[scheme+3:]
+	query, hasQuery := "", false
+	if q := strings.IndexByte(rest, '?'); q != -1 {
+		rest, query, hasQuery = rest[:q], rest[q+1:], true
+	}
+	hostsEnd := strings.IndexByte(rest, '/')
+	if hostsEnd == -1 {
+		hostsEnd = len(rest)
+	}
+	if at := strings.LastIndex(rest[:hostsEnd], "@"); at != -1 {
+		if colon := strings.Index(rest[:at], ":"); colon != -1 {
+			rest = rest[:colon+1] + "********" + rest[at:]
+		}
+	}
+	if !hasQuery {
+		return prefix + rest
+	}
+	return prefix + rest + "?" + maskURIOptions(query)
+}
+
+// maskURIOptions redacts secret values in the query of a connection string.
+func maskURIOptions(query string) string {
+	options := strings.Split(query, "&")
+	for i, option := range options {
+		key, value, ok := strings.Cut(option, "=")
+		if !ok {
+			continue
+		}
+		switch name := strings.ToLower(key); {
+		case sensitiveURIOptions[name]:
+			options[i] = key + "=********"
+		case name == "authmechanismproperties":
+			options[i] = key + "=" + maskAuthMechanismProperties(value)
+		}
+	}
+	return strings.Join(options, "&")
+}
+
+// maskAuthMechanismProperties redacts AWS_SESSION_TOKEN from a
+// comma-separated list of key:value properties.
+func maskAuthMechanismProperties(value string) string {
+	properties := strings.Split(value, ",")
+	for i, property := range properties {
+		if name, _, ok := strings.Cut(property, ":"); ok && strings.EqualFold(name, "AWS_SESSION_TOKEN") {
+			properties[i] = name + ":********"
+		}
+	}
+	return strings.Join(properties, ",")
+}
+
+func logConnection(cfg Config, uri string) {
+	log.Printf("\n==============================")
+	log.Printf("🚀 MongoDB Connected Successfully!")
+	log.Printf("DB: %s | User: %s", cfg.Name, cfg.Username)
+	log.Printf("URI: %s", uri)
+	log.Printf("==============================\n")
+	log.Printf("[MongoDB] Connection established. Ready for queries! 🚀")
+}
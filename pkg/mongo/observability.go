@@ -0,0 +1,210 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/observability"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	meterName = "github.com/milan604/core-lab/pkg/mongo"
+
+	// DefaultSlowCommandThreshold is the duration above which CommandObserver
+	// logs a command as slow.
+	DefaultSlowCommandThreshold = 200 * time.Millisecond
+)
+
+// Span and metric attribute keys recorded by CommandObserver, alongside
+// observability.AttrDBSystem and observability.AttrDBOperation.
+var (
+	AttrDBName       = attribute.Key("db.name")
+	AttrDBCollection = attribute.Key("db.mongodb.collection")
+	attrError        = attribute.Key("error.type")
+)
+
+// Option configures New.
+type Option func(*options)
+
+type options struct {
+	obs           observability.ObservabilityIface
+	log           logger.LogManager
+	slowThreshold time.Duration
+}
+
+// WithObservability installs a CommandObserver as the client's command
+// monitor, tracing commands with obs's tracer and recording metrics on the
+// global meter provider.
+func WithObservability(obs observability.ObservabilityIface) Option {
+	return func(o *options) { o.obs = obs }
+}
+
+// WithSlowCommandThreshold sets the duration above which commands are logged
+// as slow (DefaultSlowCommandThreshold by default). A negative value turns
+// slow command logging off.
+func WithSlowCommandThreshold(d time.Duration) Option {
+	return func(o *options) { o.slowThreshold = d }
+}
+
+// WithLogger sets the logger used for slow command logs. Without it they go
+// to the standard library logger.
+func WithLogger(l logger.LogManager) Option {
+	return func(o *options) { o.log = l }
+}
+
+// CommandObserverConfig configures a CommandObserver.
+type CommandObserverConfig struct {
+	// Database is recorded as peer.service; db.name comes from each command.
+	Database string
+	// Tracer defaults to the global tracer provider's.
+	Tracer trace.Tracer
+	// Meter defaults to the global meter provider's.
+	Meter metric.Meter
+	// Logger receives slow command logs; nil uses the standard library logger.
+	Logger logger.LogManager
+	// SlowThreshold defaults to DefaultSlowCommandThreshold; negative disables.
+	SlowThreshold time.Duration
+}
+
+// CommandObserver runs every command in a client span named after the command
+// and collection (e.g. "find orders"), records db.client.operation.duration
+// and db.client.operation.errors with db.system "mongodb", and logs slow
+// commands. Command documents are not recorded, since filters and updates
+// carry user data. Install it with clientOptions.SetMonitor(observer.Monitor()),
+// or pass WithObservability to New.
+type CommandObserver struct {
+	cfg      CommandObserverConfig
+	duration metric.Float64Histogram
+	errors   metric.Int64Counter
+	spans    sync.Map // commandKey -> *commandSpan
+}
+
+type commandKey struct {
+	connectionID string
+	requestID    int64
+}
+
+type commandSpan struct {
+	ctx   context.Context
+	span  trace.Span
+	attrs []attribute.KeyValue
+}
+
+// NewCommandObserver creates a CommandObserver. If the meter rejects the
+// duration or error instrument, the error goes to the global OpenTelemetry
+// error handler and commands are still traced and slow ones logged, without
+// that metric.
+func NewCommandObserver(cfg CommandObserverConfig) *CommandObserver {
+	if cfg.Tracer == nil {
+		cfg.Tracer = otel.Tracer(meterName)
+	}
+	if cfg.Meter == nil {
+		cfg.Meter = otel.Meter(meterName)
+	}
+	if cfg.SlowThreshold == 0 {
+		cfg.SlowThreshold = DefaultSlowCommandThreshold
+	}
+
+	o := &CommandObserver{cfg: cfg}
+	var err error
+	if o.duration, err = cfg.Meter.Float64Histogram("db.client.operation.duration",
+		metric.WithDescription("Duration of database queries"), metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10)); err != nil {
+		otel.Handle(fmt.Errorf("create db.client.operation.duration: %w", err))
+		o.duration = nil
+	}
+	if o.errors, err = cfg.Meter.Int64Counter("db.client.operation.errors",
+		metric.WithDescription("Failed database queries"), metric.WithUnit("{operation}")); err != nil {
+		otel.Handle(fmt.Errorf("create db.client.operation.errors: %w", err))
+		o.errors = nil
+	}
+	return o
+}
+
+// Monitor returns the driver command monitor that feeds the observer.
+func (o *CommandObserver) Monitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started:   o.started,
+		Succeeded: o.succeeded,
+		Failed:    o.failed,
+	}
+}
+
+func (o *CommandObserver) started(ctx context.Context, evt *event.CommandStartedEvent) {
+	attrs := []attribute.KeyValue{
+		observability.AttrDBSystem.String("mongodb"),
+		observability.AttrDBOperation.String(evt.CommandName),
+		AttrDBName.String(evt.DatabaseName),
+	}
+	name := evt.CommandName
+	// The first element of a command names the operation; its value is the
+	// collection for collection-level commands.
+	if elem, err := evt.Command.IndexErr(0); err == nil {
+		if collection, ok := elem.Value().StringValueOK(); ok && collection != "" {
+			attrs = append(attrs, AttrDBCollection.String(collection))
+			name += " " + collection
+		}
+	}
+
+	spanCtx, span := o.cfg.Tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	if o.cfg.Database != "" {
+		span.SetAttributes(observability.AttrPeerService.String(o.cfg.Database))
+	}
+	o.spans.Store(commandKey{evt.ConnectionID, evt.RequestID}, &commandSpan{ctx: spanCtx, span: span, attrs: attrs})
+}
+
+func (o *CommandObserver) succeeded(_ context.Context, evt *event.CommandSucceededEvent) {
+	o.finish(evt.CommandFinishedEvent, nil)
+}
+
+func (o *CommandObserver) failed(_ context.Context, evt *event.CommandFailedEvent) {
+	o.finish(evt.CommandFinishedEvent, evt.Failure)
+}
+
+func (o *CommandObserver) finish(evt event.CommandFinishedEvent, failure error) {
+	value, ok := o.spans.LoadAndDelete(commandKey{evt.ConnectionID, evt.RequestID})
+	if !ok {
+		return
+	}
+	cs := value.(*commandSpan)
+
+	metricAttrs := cs.attrs
+	if failure != nil {
+		metricAttrs = append(append([]attribute.KeyValue{}, cs.attrs...), attrError.String(fmt.Sprintf("%T", failure)))
+		cs.span.RecordError(failure)
+		cs.span.SetStatus(codes.Error, failure.Error())
+	}
+	cs.span.End()
+
+	if o.duration != nil {
+		o.duration.Record(cs.ctx, evt.Duration.Seconds(), metric.WithAttributes(metricAttrs...))
+	}
+	if failure != nil && o.errors != nil {
+		o.errors.Add(cs.ctx, 1, metric.WithAttributes(metricAttrs...))
+	}
+	if o.cfg.SlowThreshold > 0 && evt.Duration >= o.cfg.SlowThreshold {
+		o.logSlow(cs.ctx, evt)
+	}
+}
+
+func (o *CommandObserver) logSlow(ctx context.Context, evt event.CommandFinishedEvent) {
+	if o.cfg.Logger == nil {
+		log.Printf("[MongoDB] slow command (%s): %s on %s", evt.Duration, evt.CommandName, evt.DatabaseName)
+		return
+	}
+	o.cfg.Logger.With(
+		"log_type", "slow_query",
+		"duration_ms", evt.Duration.Milliseconds(),
+		"database", evt.DatabaseName,
+	).WarnFCtx(ctx, "slow command: %s", evt.CommandName)
+}
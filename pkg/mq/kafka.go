@@ -0,0 +1,101 @@
+package mq
+
+import (
+	"context"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+const defaultKafkaRetryBackoff = 2 * time.Second
+
+// FromKafka converts a kafka-go message.
+func FromKafka(m kafka.Message) Message {
+	headers := make(map[string]string, len(m.Headers))
+	for _, h := range m.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	return Message{
+		Topic:     m.Topic,
+		Partition: m.Partition,
+		Offset:    m.Offset,
+		Key:       m.Key,
+		Value:     m.Value,
+		Headers:   headers,
+		Time:      m.Time,
+	}
+}
+
+// ToKafka converts msg for a kafka.Writer. Partition and offset are left to
+// the writer.
+func ToKafka(msg Message) kafka.Message {
+	headers := make([]kafka.Header, 0, len(msg.Headers))
+	for k, v := range msg.Headers {
+		headers = append(headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	return kafka.Message{
+		Topic:   msg.Topic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+}
+
+// KafkaPublisher publishes through a kafka.Writer. The writer must not set
+// Topic, since every message names its own.
+type KafkaPublisher struct {
+	Writer *kafka.Writer
+}
+
+// Publish implements Publisher.
+func (p KafkaPublisher) Publish(ctx context.Context, msg Message) error {
+	return p.Writer.WriteMessages(ctx, ToKafka(msg))
+}
+
+// ConsumeKafka fetches messages from reader and runs handler on each until
+// ctx is cancelled, committing a message once handler returns nil. A failed
+// message is retried after a backoff rather than skipped; wrap handler with
+// DeadLetter to move poison messages aside.
+func ConsumeKafka(ctx context.Context, reader *kafka.Reader, handler Handler, log logger.LogManager) error {
+	for {
+		m, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if log != nil {
+				log.ErrorFCtx(ctx, "failed to fetch message: %v", err)
+			}
+			if !sleep(ctx, defaultKafkaRetryBackoff) {
+				return nil
+			}
+			continue
+		}
+
+		msg := FromKafka(m)
+		for {
+			if err := handler(ctx, msg); err == nil {
+				break
+			}
+			if !sleep(ctx, defaultKafkaRetryBackoff) {
+				return nil
+			}
+		}
+
+		if err := reader.CommitMessages(ctx, m); err != nil && log != nil {
+			log.ErrorFCtx(ctx, "failed to commit message %s/%d at offset %d: %v", m.Topic, m.Partition, m.Offset, err)
+		}
+	}
+}
+
+// sleep waits for d and reports false if ctx ended first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
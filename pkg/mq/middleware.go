@@ -0,0 +1,311 @@
+package mq
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	coreerrors "github.com/milan604/core-lab/pkg/errors"
+	"github.com/milan604/core-lab/pkg/logger"
+	coretenant "github.com/milan604/core-lab/pkg/tenant"
+)
+
+const tracerName = "github.com/milan604/core-lab/pkg/mq"
+
+// DefaultDeadLetterSuffix is appended to the source topic to name its dead
+// letter topic.
+const DefaultDeadLetterSuffix = ".dlq"
+
+// Headers added to dead-lettered messages.
+const (
+	HeaderDLQOriginalTopic     = "dlq-original-topic"
+	HeaderDLQOriginalPartition = "dlq-original-partition"
+	HeaderDLQOriginalOffset    = "dlq-original-offset"
+	HeaderDLQErrorCode         = "dlq-error-code"
+	HeaderDLQErrorMessage      = "dlq-error-message"
+	HeaderDLQAttempts          = "dlq-attempts"
+	HeaderDLQFailedAt          = "dlq-failed-at"
+)
+
+// EventRetry is the span event recorded before each retry.
+const EventRetry = "mq.retry"
+
+// Recovery turns a panic in the handler into a non-retryable internal
+// ServiceError, so the message is dead-lettered instead of crashing the
+// consumer.
+func Recovery(l logger.LogManager) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg Message) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					if l != nil {
+						l.With("log_type", "panic", "topic", msg.Topic, "offset", msg.Offset).
+							ErrorFCtx(ctx, "panic recovered: %v\n%s", r, string(debug.Stack()))
+					}
+					err = coreerrors.Internal("panic while handling message", coreerrors.WithDetail("panic", fmt.Sprint(r)))
+				}
+			}()
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Logging logs every handled message with its topic, partition, offset, and
+// duration, at error level with the ServiceError code when it fails.
+func Logging(l logger.LogManager) Middleware {
+	return func(next Handler) Handler {
+		if l == nil {
+			return next
+		}
+		return func(ctx context.Context, msg Message) error {
+			start := time.Now()
+			err := next(ctx, msg)
+			entry := l.With(
+				"topic", msg.Topic,
+				"partition", msg.Partition,
+				"offset", msg.Offset,
+				"duration_ms", time.Since(start).Milliseconds(),
+			)
+			if err != nil {
+				se := coreerrors.ParseServiceError(err)
+				entry.With("error_code", se.Code).ErrorFCtx(ctx, "message handling failed: %v", err)
+				return err
+			}
+			entry.DebugFCtx(ctx, "message handled")
+			return nil
+		}
+	}
+}
+
+// Tracing continues the producer's trace from the message headers and runs
+// the handler in a consumer span.
+func Tracing() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg Message) error {
+			ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(msg.Headers))
+			ctx, span := otel.Tracer(tracerName).Start(ctx, msg.Topic+" process",
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(
+					attribute.String("messaging.operation.type", "process"),
+					attribute.String("messaging.destination.name", msg.Topic),
+					attribute.String("messaging.destination.partition.id", formatInt(int64(msg.Partition))),
+					attribute.Int64("messaging.kafka.offset", msg.Offset),
+				),
+			)
+			defer span.End()
+
+			err := next(ctx, msg)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return err
+		}
+	}
+}
+
+// Claims restores the producer's tenant request context from the message
+// headers (see InjectHeaders), with the correlation ID as the logger request
+// ID.
+//
+// Headers are whatever the publisher wrote, not a verified identity: anyone
+// able to publish to the topic can name any tenant or actor. Use Claims only
+// on topics where broker ACLs limit publishing to trusted services. The super
+// admin flag is never restored.
+func Claims() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg Message) error {
+			if requestContext, ok := coretenant.RequestContextFromMetadata(msg.Headers); ok {
+				requestContext.IsSuperAdmin = false
+				ctx = coretenant.ContextWithRequestContext(ctx, requestContext)
+				if requestContext.CorrelationID != "" {
+					ctx = context.WithValue(ctx, logger.RequestIDKey, requestContext.CorrelationID)
+				}
+			}
+			return next(ctx, msg)
+		}
+	}
+}
+
+// correlation makes the correlation ID header the logger request ID without
+// restoring any identity, for chains that do not trust the claim headers.
+func correlation() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg Message) error {
+			if correlationID := strings.TrimSpace(msg.Headers[coretenant.MetadataCorrelationID]); correlationID != "" {
+				ctx = context.WithValue(ctx, logger.RequestIDKey, correlationID)
+			}
+			return next(ctx, msg)
+		}
+	}
+}
+
+// RetryPolicy configures Retry.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy returns 3 attempts with backoff doubling from 200ms up
+// to 5s.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, InitialBackoff: 200 * time.Millisecond, MaxBackoff: 5 * time.Second}
+}
+
+// delay returns the backoff before the given attempt (2 for the first retry).
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 2; i < attempt; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return delay
+}
+
+// RetriesExhaustedError is returned by Retry once the handler has failed on
+// every attempt, or on the first non-retryable failure.
+type RetriesExhaustedError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetriesExhaustedError) Error() string {
+	return fmt.Sprintf("message failed after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *RetriesExhaustedError) Unwrap() error { return e.Err }
+
+type attemptContextKey struct{}
+
+// AttemptFromContext returns the current attempt number (1 for the first)
+// inside Retry, or 0 outside it.
+func AttemptFromContext(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptContextKey{}).(int)
+	return attempt
+}
+
+// Retry re-runs the handler with exponential backoff while Retryable reports
+// the failure as transient. Each retry is recorded as an EventRetry span
+// event.
+func Retry(policy RetryPolicy) Middleware {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 1
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg Message) error {
+			for attempt := 1; ; attempt++ {
+				err := next(context.WithValue(ctx, attemptContextKey{}, attempt), msg)
+				if err == nil {
+					return nil
+				}
+				if ctx.Err() != nil {
+					return err
+				}
+				if attempt >= policy.MaxAttempts || !Retryable(err) {
+					return &RetriesExhaustedError{Attempts: attempt, Err: err}
+				}
+
+				delay := policy.delay(attempt + 1)
+				trace.SpanFromContext(ctx).AddEvent(EventRetry, trace.WithAttributes(
+					attribute.Int("mq.attempt", attempt+1),
+					attribute.Float64("mq.retry.delay", delay.Seconds()),
+					attribute.String("mq.retry.reason", err.Error()),
+				))
+				select {
+				case <-ctx.Done():
+					return err
+				case <-time.After(delay):
+				}
+			}
+		}
+	}
+}
+
+// Retryable reports whether err is worth retrying. A ServiceError is
+// retryable when marked so or when its status is a transient one (408, 429,
+// 502, 503, 504); validation, not-found, and internal errors are not.
+// Cancellation is never retried, and any other error is assumed transient.
+func Retryable(err error) bool {
+	if err == nil || stderrors.Is(err, context.Canceled) {
+		return false
+	}
+	var se *coreerrors.ServiceError
+	if !stderrors.As(err, &se) {
+		return true
+	}
+	if se.Retryable {
+		return true
+	}
+	switch se.HTTPStatus {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// DeadLetter forwards messages whose handler still fails to topic (the
+// source topic plus DefaultDeadLetterSuffix when empty), adding the error
+// and original position as headers, and then reports success so the
+// consumer moves on. Failures caused by cancellation are returned as is, so
+// the message is redelivered after a restart.
+func DeadLetter(publisher Publisher, topic string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg Message) error {
+			err := next(ctx, msg)
+			if err == nil || ctx.Err() != nil {
+				return err
+			}
+
+			attempts := 1
+			var exhausted *RetriesExhaustedError
+			if stderrors.As(err, &exhausted) {
+				attempts = exhausted.Attempts
+			}
+			se := coreerrors.ParseServiceError(err)
+
+			dead := msg
+			dead.Topic = topic
+			if dead.Topic == "" {
+				dead.Topic = msg.Topic + DefaultDeadLetterSuffix
+			}
+			dead.Headers = make(map[string]string, len(msg.Headers)+7)
+			for k, v := range msg.Headers {
+				dead.Headers[k] = v
+			}
+			dead.Headers[HeaderDLQOriginalTopic] = msg.Topic
+			dead.Headers[HeaderDLQOriginalPartition] = formatInt(int64(msg.Partition))
+			dead.Headers[HeaderDLQOriginalOffset] = formatInt(msg.Offset)
+			dead.Headers[HeaderDLQErrorCode] = se.Code
+			dead.Headers[HeaderDLQErrorMessage] = err.Error()
+			dead.Headers[HeaderDLQAttempts] = formatInt(int64(attempts))
+			dead.Headers[HeaderDLQFailedAt] = time.Now().UTC().Format(time.RFC3339Nano)
+
+			if pubErr := publisher.Publish(ctx, dead); pubErr != nil {
+				return fmt.Errorf("dead-letter to %s: %w (handler error: %v)", dead.Topic, pubErr, err)
+			}
+			trace.SpanFromContext(ctx).AddEvent("mq.dead_letter", trace.WithAttributes(
+				attribute.String("messaging.destination.name", dead.Topic),
+				attribute.String("error.type", se.Code),
+			))
+			return nil
+		}
+	}
+}
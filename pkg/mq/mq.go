@@ -0,0 +1,109 @@
+// Package mq provides a transport-neutral consumer handler and a middleware
+// chain for message consumers, mirroring the HTTP middleware stack: panic
+// recovery, structured logging, trace extraction, tenant context
+// propagation, retries with exponential backoff, and dead-lettering.
+package mq
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/milan604/core-lab/pkg/logger"
+	coretenant "github.com/milan604/core-lab/pkg/tenant"
+)
+
+// Message is a consumed message, independent of the broker client.
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+	Time      time.Time
+}
+
+// Handler processes one message. A nil error means the message is done and
+// may be committed.
+type Handler func(ctx context.Context, msg Message) error
+
+// Middleware wraps a Handler.
+type Middleware func(Handler) Handler
+
+// Publisher writes a message to a topic; DeadLetter uses it to forward
+// messages that cannot be processed.
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+// Chain wraps h in mws. The first middleware is the outermost, as with
+// engine.Use.
+func Chain(h Handler, mws ...Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// ConsumerConfig configures Standard.
+type ConsumerConfig struct {
+	Logger logger.LogManager
+	// Retry defaults to DefaultRetryPolicy.
+	Retry RetryPolicy
+	// DeadLetter receives messages that still fail after retries. Without it
+	// the final error is returned to the consumer loop.
+	DeadLetter Publisher
+	// DeadLetterTopic defaults to the source topic plus DefaultDeadLetterSuffix.
+	DeadLetterTopic string
+	// TrustClaimHeaders installs Claims, restoring the producer's tenant
+	// request context from the headers. Enable it only when broker ACLs
+	// limit publishing on the topic to trusted services, since headers are
+	// not verified. Without it only the correlation ID is restored.
+	TrustClaimHeaders bool
+}
+
+// Standard returns the recommended consumer chain, outermost first: Tracing,
+// Claims (when TrustClaimHeaders is set), DeadLetter (when configured),
+// Logging, Retry, Recovery.
+//
+//	handler := mq.Chain(handleInvoice, mq.Standard(mq.ConsumerConfig{Logger: log, DeadLetter: dlq})...)
+func Standard(cfg ConsumerConfig) []Middleware {
+	if cfg.Retry.MaxAttempts <= 0 {
+		cfg.Retry = DefaultRetryPolicy()
+	}
+	mws := []Middleware{Tracing(), correlation()}
+	if cfg.TrustClaimHeaders {
+		mws[1] = Claims()
+	}
+	if cfg.DeadLetter != nil {
+		mws = append(mws, DeadLetter(cfg.DeadLetter, cfg.DeadLetterTopic))
+	}
+	return append(mws, Logging(cfg.Logger), Retry(cfg.Retry), Recovery(cfg.Logger))
+}
+
+// InjectHeaders writes the trace context and tenant request context of ctx
+// into headers, the producer-side counterpart of Tracing and Claims. It
+// returns headers, allocating it when nil.
+func InjectHeaders(ctx context.Context, headers map[string]string) map[string]string {
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(headers))
+
+	requestContext, _ := coretenant.RequestContextFromContext(ctx)
+	if requestContext.CorrelationID == "" {
+		if requestID, ok := ctx.Value(logger.RequestIDKey).(string); ok {
+			requestContext.CorrelationID = strings.TrimSpace(requestID)
+		}
+	}
+	return coretenant.MergeMetadata(headers, requestContext)
+}
+
+func formatInt(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
@@ -0,0 +1,185 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	coreerrors "github.com/milan604/core-lab/pkg/errors"
+	"github.com/milan604/core-lab/pkg/logger"
+	coretenant "github.com/milan604/core-lab/pkg/tenant"
+)
+
+type recordingPublisher struct {
+	published []Message
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, msg Message) error {
+	p.published = append(p.published, msg)
+	return nil
+}
+
+func TestStandardChainPropagatesContextAndRetries(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	producerCtx, producerSpan := otel.Tracer("producer").Start(
+		coretenant.ContextWithRequestContext(context.Background(), coretenant.RequestContext{TenantID: "t-1", ActorUserID: "u-1"}),
+		"publish")
+	producerCtx = context.WithValue(producerCtx, logger.RequestIDKey, "req-9")
+	headers := InjectHeaders(producerCtx, nil)
+	producerSpan.End()
+
+	dlq := &recordingPublisher{}
+	var attempts []int
+	var tenantID, requestID string
+	var traceID trace.TraceID
+	handler := Chain(func(ctx context.Context, msg Message) error {
+		attempts = append(attempts, AttemptFromContext(ctx))
+		rc, _ := coretenant.RequestContextFromContext(ctx)
+		tenantID = rc.TenantID
+		requestID, _ = ctx.Value(logger.RequestIDKey).(string)
+		traceID = trace.SpanContextFromContext(ctx).TraceID()
+		if len(attempts) < 3 {
+			return coreerrors.ServiceUnavailable("ledger down")
+		}
+		return nil
+	}, Standard(ConsumerConfig{
+		Retry:             RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+		DeadLetter:        dlq,
+		TrustClaimHeaders: true,
+	})...)
+
+	err := handler(context.Background(), Message{Topic: "invoices", Offset: 42, Headers: headers})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	if len(attempts) != 3 || attempts[0] != 1 || attempts[2] != 3 {
+		t.Fatalf("attempts = %v, want [1 2 3]", attempts)
+	}
+	if tenantID != "t-1" || requestID != "req-9" {
+		t.Fatalf("tenant = %q, request id = %q", tenantID, requestID)
+	}
+	if traceID != producerSpan.SpanContext().TraceID() {
+		t.Fatalf("consumer trace = %s, want producer trace %s", traceID, producerSpan.SpanContext().TraceID())
+	}
+	if len(dlq.published) != 0 {
+		t.Fatalf("dead-lettered %d messages, want 0", len(dlq.published))
+	}
+
+	var consumer sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.SpanKind() == trace.SpanKindConsumer {
+			consumer = span
+		}
+	}
+	if consumer == nil || consumer.Name() != "invoices process" || len(consumer.Events()) != 2 || consumer.Events()[0].Name != EventRetry {
+		t.Fatalf("consumer span = %+v", consumer)
+	}
+}
+
+func TestStandardChainIgnoresClaimHeadersUnlessTrusted(t *testing.T) {
+	headers := coretenant.MergeMetadata(nil, coretenant.RequestContext{
+		TenantID:      "t-victim",
+		ActorUserID:   "u-forged",
+		CorrelationID: "req-1",
+		IsSuperAdmin:  true,
+	})
+
+	for _, trusted := range []bool{false, true} {
+		var rc coretenant.RequestContext
+		var restored bool
+		var requestID string
+		handler := Chain(func(ctx context.Context, _ Message) error {
+			rc, restored = coretenant.RequestContextFromContext(ctx)
+			requestID, _ = ctx.Value(logger.RequestIDKey).(string)
+			return nil
+		}, Standard(ConsumerConfig{TrustClaimHeaders: trusted})...)
+
+		if err := handler(context.Background(), Message{Topic: "invoices", Headers: headers}); err != nil {
+			t.Fatalf("handler: %v", err)
+		}
+		if requestID != "req-1" {
+			t.Fatalf("trusted=%v: request id = %q", trusted, requestID)
+		}
+		if !trusted && restored {
+			t.Fatalf("untrusted headers restored %+v", rc)
+		}
+		if trusted && (rc.TenantID != "t-victim" || rc.ActorUserID != "u-forged") {
+			t.Fatalf("trusted headers restored %+v", rc)
+		}
+		if rc.IsSuperAdmin {
+			t.Fatalf("trusted=%v: super admin restored from headers", trusted)
+		}
+	}
+}
+
+func TestStandardChainDeadLettersPermanentFailures(t *testing.T) {
+	dlq := &recordingPublisher{}
+	calls := 0
+	chain := Standard(ConsumerConfig{
+		Retry:      RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond},
+		DeadLetter: dlq,
+	})
+
+	invalid := Chain(func(context.Context, Message) error {
+		calls++
+		return coreerrors.ValidationFailed("amount must be positive")
+	}, chain...)
+	if err := invalid(context.Background(), Message{Topic: "invoices", Partition: 2, Offset: 7, Value: []byte(`{}`), Headers: map[string]string{"kind": "invoice"}}); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("calls = %d, want 1 for a non-retryable error", calls)
+	}
+
+	panicking := Chain(func(context.Context, Message) error { panic("nil ledger") }, chain...)
+	if err := panicking(context.Background(), Message{Topic: "invoices"}); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+
+	if len(dlq.published) != 2 {
+		t.Fatalf("dead-lettered %d messages, want 2", len(dlq.published))
+	}
+	dead := dlq.published[0]
+	if dead.Topic != "invoices.dlq" || string(dead.Value) != `{}` || dead.Headers["kind"] != "invoice" {
+		t.Fatalf("dead letter = %+v", dead)
+	}
+	for key, want := range map[string]string{
+		HeaderDLQOriginalTopic:     "invoices",
+		HeaderDLQOriginalPartition: "2",
+		HeaderDLQOriginalOffset:    "7",
+		HeaderDLQErrorCode:         "validation_failed",
+		HeaderDLQAttempts:          "1",
+	} {
+		if got := dead.Headers[key]; got != want {
+			t.Fatalf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if got := dlq.published[1].Headers[HeaderDLQErrorCode]; got != "internal_error" {
+		t.Fatalf("panic error code = %q, want internal_error", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cancelled := Chain(func(ctx context.Context, _ Message) error { return ctx.Err() }, chain...)
+	if err := cancelled(ctx, Message{Topic: "invoices"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled handler err = %v, want context.Canceled", err)
+	}
+	if len(dlq.published) != 2 {
+		t.Fatal("cancelled message was dead-lettered")
+	}
+}
@@ -0,0 +1,145 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+// HeaderNATSKey carries Message.Key on NATS, which has no message key.
+const HeaderNATSKey = "mq-key"
+
+const defaultNATSNakDelay = 2 * time.Second
+
+// FromNATS converts a JetStream message. Offset is the stream sequence and
+// Partition is always 0.
+func FromNATS(m jetstream.Msg) Message {
+	headers := make(map[string]string, len(m.Headers()))
+	var key []byte
+	for k, values := range m.Headers() {
+		if len(values) == 0 {
+			continue
+		}
+		if k == HeaderNATSKey {
+			key = []byte(values[0])
+			continue
+		}
+		headers[k] = values[0]
+	}
+	msg := Message{
+		Topic:   m.Subject(),
+		Key:     key,
+		Value:   m.Data(),
+		Headers: headers,
+	}
+	if meta, err := m.Metadata(); err == nil {
+		msg.Offset = int64(meta.Sequence.Stream)
+		msg.Time = meta.Timestamp
+	}
+	return msg
+}
+
+// ToNATS converts msg for publishing on the subject msg.Topic. A non-empty
+// Key is sent in the HeaderNATSKey header.
+func ToNATS(msg Message) *nats.Msg {
+	m := nats.NewMsg(msg.Topic)
+	m.Data = msg.Value
+	for k, v := range msg.Headers {
+		m.Header.Set(k, v)
+	}
+	if len(msg.Key) > 0 {
+		m.Header.Set(HeaderNATSKey, string(msg.Key))
+	}
+	return m
+}
+
+// NATSPublisher publishes to JetStream. Topics are subjects, and a stream
+// must capture them.
+type NATSPublisher struct {
+	JS jetstream.JetStream
+}
+
+// Publish implements Publisher. It returns once the stream has stored the
+// message.
+func (p NATSPublisher) Publish(ctx context.Context, msg Message) error {
+	_, err := p.JS.PublishMsg(ctx, ToNATS(msg))
+	return err
+}
+
+// NATSSubscriber subscribes through a durable JetStream pull consumer.
+// Subscribers sharing a Durable name form a consumer group: each message is
+// delivered to one of them.
+type NATSSubscriber struct {
+	JS jetstream.JetStream
+	// Stream is the stream capturing the subscribed subjects.
+	Stream string
+	// Durable names the consumer; it is created or updated on Subscribe.
+	Durable string
+	// AckWait is how long JetStream waits for an ack before redelivering
+	// (the server default, 30s, when zero).
+	AckWait time.Duration
+	// MaxDeliver caps deliveries per message; zero means unlimited.
+	MaxDeliver int
+	// NakDelay is the redelivery delay after a failure (2s by default).
+	NakDelay time.Duration
+	Logger   logger.LogManager
+}
+
+// Subscribe implements Subscriber. topic is the consumer's filter subject and
+// may use wildcards. A message is acked when handler returns nil and nacked
+// with NakDelay otherwise. Subscribe blocks until ctx is cancelled and then
+// stops the consumer, leaving unacked messages to be redelivered.
+func (s NATSSubscriber) Subscribe(ctx context.Context, topic string, handler Handler) error {
+	if s.JS == nil || s.Stream == "" || s.Durable == "" {
+		return errors.New("mq: NATSSubscriber needs JS, a Stream, and a Durable name")
+	}
+	consumer, err := s.JS.CreateOrUpdateConsumer(ctx, s.Stream, jetstream.ConsumerConfig{
+		Durable:       s.Durable,
+		FilterSubject: topic,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       s.AckWait,
+		MaxDeliver:    s.MaxDeliver,
+	})
+	if err != nil {
+		return fmt.Errorf("mq: create consumer %s on %s: %w", s.Durable, s.Stream, err)
+	}
+
+	consumeCtx, err := consumer.Consume(func(m jetstream.Msg) {
+		s.handle(ctx, m, handler)
+	}, jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+		if s.Logger != nil {
+			s.Logger.ErrorFCtx(ctx, "failed to fetch messages for %s: %v", s.Durable, err)
+		}
+	}))
+	if err != nil {
+		return fmt.Errorf("mq: consume %s: %w", s.Durable, err)
+	}
+
+	<-ctx.Done()
+	consumeCtx.Stop()
+	<-consumeCtx.Closed()
+	return nil
+}
+
+func (s NATSSubscriber) handle(ctx context.Context, m jetstream.Msg, handler Handler) {
+	msg := FromNATS(m)
+	if err := handler(ctx, msg); err != nil {
+		delay := s.NakDelay
+		if delay <= 0 {
+			delay = defaultNATSNakDelay
+		}
+		if nakErr := m.NakWithDelay(delay); nakErr != nil && s.Logger != nil {
+			s.Logger.ErrorFCtx(ctx, "failed to nak message %s at sequence %d: %v", msg.Topic, msg.Offset, nakErr)
+		}
+		return
+	}
+	if err := m.Ack(); err != nil && s.Logger != nil {
+		s.Logger.ErrorFCtx(ctx, "failed to ack message %s at sequence %d: %v", msg.Topic, msg.Offset, err)
+	}
+}
@@ -0,0 +1,48 @@
+package mq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// fakeNATSMsg implements the parts of jetstream.Msg that FromNATS reads.
+type fakeNATSMsg struct {
+	jetstream.Msg
+	msg  *nats.Msg
+	meta *jetstream.MsgMetadata
+}
+
+func (m fakeNATSMsg) Subject() string                           { return m.msg.Subject }
+func (m fakeNATSMsg) Data() []byte                              { return m.msg.Data }
+func (m fakeNATSMsg) Headers() nats.Header                      { return m.msg.Header }
+func (m fakeNATSMsg) Metadata() (*jetstream.MsgMetadata, error) { return m.meta, nil }
+
+func TestNATSMessageRoundTrip(t *testing.T) {
+	stored := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	msg := Message{
+		Topic:   "orders.created",
+		Key:     []byte("tenant-1"),
+		Value:   []byte(`{"id":"o-1"}`),
+		Headers: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+	}
+
+	out := ToNATS(msg)
+	if out.Subject != msg.Topic || out.Header.Get(HeaderNATSKey) != "tenant-1" {
+		t.Fatalf("ToNATS() = %+v", out)
+	}
+
+	meta := &jetstream.MsgMetadata{Sequence: jetstream.SequencePair{Stream: 17}, Timestamp: stored}
+	got := FromNATS(fakeNATSMsg{msg: out, meta: meta})
+	if got.Topic != msg.Topic || string(got.Key) != "tenant-1" || string(got.Value) != string(msg.Value) {
+		t.Fatalf("FromNATS() = %+v", got)
+	}
+	if got.Offset != 17 || !got.Time.Equal(stored) {
+		t.Fatalf("FromNATS() position = %d at %s", got.Offset, got.Time)
+	}
+	if len(got.Headers) != 1 || got.Headers["traceparent"] != msg.Headers["traceparent"] {
+		t.Fatalf("FromNATS() headers = %v", got.Headers)
+	}
+}
@@ -0,0 +1,46 @@
+package mq
+
+import (
+	"context"
+	"errors"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+// Subscriber delivers the messages of a topic to a handler until ctx is
+// cancelled. Delivery is at least once: a message is acknowledged when the
+// handler returns nil and redelivered when it returns an error, so handlers
+// must be idempotent. Wrap the handler with Standard to retry and dead-letter
+// failures before they reach the broker.
+type Subscriber interface {
+	Subscribe(ctx context.Context, topic string, handler Handler) error
+}
+
+// KafkaSubscriber subscribes through a kafka.Reader per topic. Subscribers
+// sharing a GroupID form a consumer group and split the topic's partitions.
+type KafkaSubscriber struct {
+	Brokers []string
+	GroupID string
+	Logger  logger.LogManager
+	// Config, when set, is the base reader configuration; Brokers, GroupID
+	// and Topic are always taken from the subscriber.
+	Config kafka.ReaderConfig
+}
+
+// Subscribe implements Subscriber with ConsumeKafka: offsets are committed
+// after the handler succeeds, and a failed message is retried in place.
+func (s KafkaSubscriber) Subscribe(ctx context.Context, topic string, handler Handler) error {
+	if len(s.Brokers) == 0 || s.GroupID == "" {
+		return errors.New("mq: KafkaSubscriber needs Brokers and a GroupID")
+	}
+	cfg := s.Config
+	cfg.Brokers = s.Brokers
+	cfg.GroupID = s.GroupID
+	cfg.Topic = topic
+
+	reader := kafka.NewReader(cfg)
+	defer reader.Close()
+	return ConsumeKafka(ctx, reader, handler, s.Logger)
+}
@@ -0,0 +1,263 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync/atomic"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/golang-migrate/migrate/v4"
+	migratemysql "github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/source"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/milan604/core-lab/pkg/internal/sqldb"
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+const filePrefix = "file://"
+
+// MigrationSource is where Migrate reads migration files from: a directory on
+// disk (MigrationsDir) or an fs.FS such as an embed.FS (MigrationsFS), so the
+// SQL can ship inside the binary.
+type MigrationSource struct {
+	fsys fs.FS
+	dir  string
+}
+
+// MigrationsDir reads migrations from the directory at path.
+func MigrationsDir(path string) MigrationSource {
+	return MigrationSource{dir: path}
+}
+
+// MigrationsFS reads migrations from dir within fsys ("." for its root).
+//
+//	//go:embed migrations/*.sql
+//	var migrationFiles embed.FS
+//
+//	err := db.Migrate(ctx, mysql.MigrationsFS(migrationFiles, "migrations"), mysql.MigrateUp())
+func MigrationsFS(fsys fs.FS, dir string) MigrationSource {
+	if dir == "" {
+		dir = "."
+	}
+	return MigrationSource{fsys: fsys, dir: dir}
+}
+
+func (s MigrationSource) String() string {
+	if s.fsys != nil {
+		return "fs:" + s.dir
+	}
+	return filePrefix + s.dir
+}
+
+func (s MigrationSource) open() (source.Driver, error) {
+	if s.fsys != nil {
+		return iofs.New(s.fsys, s.dir)
+	}
+	return source.Open(filePrefix + s.dir)
+}
+
+type migrationAction int
+
+const (
+	migrateUp migrationAction = iota
+	migrateDown
+	migrateTo
+	migrateSteps
+	migrateForce
+)
+
+// MigrationTarget is what Migrate brings the schema to.
+type MigrationTarget struct {
+	action  migrationAction
+	version uint
+	// steps is the step count of MigrateSteps or the version of MigrateForce.
+	steps int
+}
+
+// MigrateUp applies every pending up migration.
+func MigrateUp() MigrationTarget { return MigrationTarget{action: migrateUp} }
+
+// MigrateDown rolls back every applied migration.
+func MigrateDown() MigrationTarget { return MigrationTarget{action: migrateDown} }
+
+// MigrateTo migrates up or down to version.
+func MigrateTo(version uint) MigrationTarget {
+	return MigrationTarget{action: migrateTo, version: version}
+}
+
+// MigrateSteps applies n up migrations, or rolls back -n when n is negative.
+func MigrateSteps(n int) MigrationTarget { return MigrationTarget{action: migrateSteps, steps: n} }
+
+// MigrateForce records version as applied and clears the dirty flag without
+// running any migration, to recover from a failed one. -1 marks no migration
+// as applied.
+func MigrateForce(version int) MigrationTarget {
+	return MigrationTarget{action: migrateForce, steps: version}
+}
+
+func (t MigrationTarget) String() string {
+	switch t.action {
+	case migrateDown:
+		return "down"
+	case migrateTo:
+		return fmt.Sprintf("version %d", t.version)
+	case migrateSteps:
+		return fmt.Sprintf("%d steps", t.steps)
+	case migrateForce:
+		return fmt.Sprintf("force version %d", t.steps)
+	}
+	return "up"
+}
+
+// Migrate brings the schema to target using the migrations in source. It
+// runs on a dedicated connection built from the same driver config as the
+// pool, so migrations use the same credentials and TLS settings as queries,
+// including PasswordProvider, but with multiStatements enabled so a file may
+// hold several statements. Concurrent runs are serialized by GET_LOCK.
+// Reaching the target with nothing to apply is not an error. Cancelling ctx
+// stops after the migration in progress.
+//
+// Progress is logged through the logger passed to New with WithLogger, or a
+// default logger, with the fields migration_source, migration_target,
+// from_version, to_version, dirty and duration_ms; golang-migrate's per-file
+// progress goes to the debug level.
+func (db *DB) Migrate(ctx context.Context, src MigrationSource, target MigrationTarget) error {
+	log := db.migrationLogger().With("migration_source", src.String(), "migration_target", target.String())
+	m, closeMigrate, err := db.newMigrate(ctx, src, log)
+	if err != nil {
+		log.ErrorFCtx(ctx, "mysql: open migrations: %v", err)
+		return err
+	}
+	defer closeMigrate()
+
+	from, _, err := migrationVersion(m)
+	if err != nil {
+		log.ErrorFCtx(ctx, "mysql: read migration version: %v", err)
+		return err
+	}
+
+	var stopped atomic.Bool
+	stop := context.AfterFunc(ctx, func() {
+		stopped.Store(true)
+		select {
+		case m.GracefulStop <- true:
+		default:
+		}
+	})
+	defer stop()
+
+	start := time.Now()
+	switch target.action {
+	case migrateDown:
+		err = m.Down()
+	case migrateTo:
+		err = m.Migrate(target.version)
+	case migrateSteps:
+		err = m.Steps(target.steps)
+	case migrateForce:
+		err = m.Force(target.steps)
+	default:
+		err = m.Up()
+	}
+	if err == nil && stopped.Load() {
+		err = ctx.Err()
+	}
+
+	to, dirty, verErr := migrationVersion(m)
+	log = log.With("from_version", from, "to_version", to, "dirty", dirty, "duration_ms", time.Since(start).Milliseconds())
+	switch {
+	case errors.Is(err, migrate.ErrNoChange):
+		log.InfoFCtx(ctx, "mysql: migrations already at target")
+		return nil
+	case err != nil:
+		log.ErrorFCtx(ctx, "mysql: migrate: %v", err)
+		return fmt.Errorf("mysql: migrate %s: %w", target, err)
+	case verErr != nil:
+		return verErr
+	}
+	log.InfoFCtx(ctx, "mysql: migrations applied")
+	return nil
+}
+
+// MigrationVersion returns the current schema version and whether the last
+// migration failed part way (dirty). A schema without migrations reports 0.
+func (db *DB) MigrationVersion(ctx context.Context, src MigrationSource) (version uint, dirty bool, err error) {
+	m, closeMigrate, err := db.newMigrate(ctx, src, db.migrationLogger())
+	if err != nil {
+		return 0, false, err
+	}
+	defer closeMigrate()
+	return migrationVersion(m)
+}
+
+func (db *DB) newMigrate(ctx context.Context, src MigrationSource, log logger.LogManager) (*migrate.Migrate, func(), error) {
+	sourceDriver, err := src.open()
+	if err != nil {
+		return nil, nil, fmt.Errorf("mysql: open migration source %s: %w", src, err)
+	}
+	sqlDB, err := db.openMigrationDB()
+	if err != nil {
+		_ = sourceDriver.Close()
+		return nil, nil, fmt.Errorf("mysql: open migration connection: %w", err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		_ = sqlDB.Close()
+		_ = sourceDriver.Close()
+		return nil, nil, fmt.Errorf("mysql: acquire migration connection: %w", err)
+	}
+	// The driver closes conn but not sqlDB, which is closed with m below.
+	dbDriver, err := migratemysql.WithConnection(ctx, conn, &migratemysql.Config{DatabaseName: db.name})
+	if err != nil {
+		_ = conn.Close()
+		_ = sqlDB.Close()
+		_ = sourceDriver.Close()
+		return nil, nil, fmt.Errorf("mysql: prepare migrations: %w", err)
+	}
+	m, err := migrate.NewWithInstance("source", sourceDriver, "mysql", dbDriver)
+	if err != nil {
+		_ = dbDriver.Close()
+		_ = sqlDB.Close()
+		_ = sourceDriver.Close()
+		return nil, nil, fmt.Errorf("mysql: prepare migrations: %w", err)
+	}
+	m.Log = sqldb.MigrateLog{Log: log, Ctx: ctx, Prefix: "mysql: "}
+	return m, func() {
+		_, _ = m.Close()
+		_ = sqlDB.Close()
+	}, nil
+}
+
+// openMigrationDB opens a single-connection pool from the driver config of db
+// with multiStatements enabled, which the primary pool leaves off.
+func (db *DB) openMigrationDB() (*sql.DB, error) {
+	cfg := db.driver.Clone()
+	cfg.MultiStatements = true
+	connector, err := gomysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB := sql.OpenDB(connector)
+	sqlDB.SetMaxOpenConns(1)
+	return sqlDB, nil
+}
+
+func migrationVersion(m *migrate.Migrate) (uint, bool, error) {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+func (db *DB) migrationLogger() logger.LogManager {
+	if db.log != nil {
+		return db.log
+	}
+	return logger.MustNewDefaultLogger()
+}
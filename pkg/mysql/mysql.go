@@ -0,0 +1,244 @@
+package mysql
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/milan604/core-lab/pkg/internal/sqldb"
+	"github.com/milan604/core-lab/pkg/logger"
+	gormmysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// Config describes a MySQL connection. It mirrors postgres.Config.
+type Config struct {
+	Host     string
+	Port     string
+	Name     string
+	Username string
+	Password string
+	// TLS is the driver's tls mode: "true", "skip-verify", "preferred", or
+	// the name of a config registered with RegisterTLSConfig. Empty or
+	// "false" disables TLS unless TLSRootCert is set.
+	TLS string
+	// TLSRootCert is the CA bundle used to verify the server and its
+	// hostname: either PEM content (e.g. from a secret) or a file path.
+	// Setting it enables TLS and takes precedence over TLS.
+	TLSRootCert string
+	// Params are extra session variables sent on connect, e.g.
+	// {"time_zone": "'+00:00'"}.
+	Params map[string]string
+	// PasswordProvider, when set, supplies the password for every new
+	// connection instead of Password, so short-lived credentials such as RDS
+	// IAM tokens are refreshed on reconnect.
+	PasswordProvider PasswordProvider
+
+	// Connection pool settings; zero keeps the database/sql default
+	// (unlimited open connections, 2 idle, no lifetime or idle timeout).
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// PasswordProvider supplies the password for a new connection. It is called
+// every time the pool dials, so implementations can return short-lived tokens.
+type PasswordProvider interface {
+	Password(ctx context.Context) (string, error)
+}
+
+// PasswordProviderFunc adapts a function to PasswordProvider.
+type PasswordProviderFunc func(ctx context.Context) (string, error)
+
+// Password implements PasswordProvider.
+func (f PasswordProviderFunc) Password(ctx context.Context) (string, error) { return f(ctx) }
+
+// DB holds the GORM and database/sql clients of one MySQL database.
+type DB struct {
+	Client *gorm.DB
+	SQL    *sql.DB
+	// DSN describes the connection, with the password masked; connections
+	// are made from the driver config, not from it.
+	DSN string
+
+	name     string
+	driver   *gomysql.Config
+	observer *QueryObserver
+	log      logger.LogManager
+}
+
+// New connects to the database described by cfg and verifies it with a ping.
+func New(cfg Config, opts ...Option) (*DB, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	driverCfg, err := driverConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	connector, err := gomysql.NewConnector(driverCfg)
+	if err != nil {
+		return nil, fmt.Errorf("mysql: %w", err)
+	}
+	sqlDB := sql.OpenDB(connector)
+	cfg.pool().Apply(sqlDB)
+	client, err := gorm.Open(gormmysql.New(gormmysql.Config{Conn: sqlDB, DSNConfig: driverCfg}), &gorm.Config{})
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+	if err := sqlDB.Ping(); err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+
+	db := &DB{Client: client, SQL: sqlDB, DSN: maskDSN(driverCfg.FormatDSN()), name: cfg.Name, driver: driverCfg, log: o.log}
+	if o.obs != nil {
+		db.observer = NewQueryObserver(QueryObserverConfig{
+			Database:      cfg.Name,
+			Tracer:        o.obs.GetTracer(),
+			Logger:        o.log,
+			SlowThreshold: o.slowThreshold,
+		})
+		if err := client.Use(db.observer); err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("mysql: register query observer: %w", err)
+		}
+	}
+	logConnection(cfg, db.DSN)
+	return db, nil
+}
+
+// Stats returns the connection pool statistics.
+func (db *DB) Stats() sql.DBStats {
+	return db.SQL.Stats()
+}
+
+// Close closes the connection pool.
+func (db *DB) Close() error {
+	return db.SQL.Close()
+}
+
+// driverConfig translates cfg into a go-sql-driver config. Times are parsed
+// into time.Time in UTC and the connection uses utf8mb4.
+func driverConfig(cfg Config) (*gomysql.Config, error) {
+	port := cfg.Port
+	if port == "" {
+		port = "3306"
+	}
+	c := gomysql.NewConfig()
+	c.Net = "tcp"
+	c.Addr = net.JoinHostPort(cfg.Host, port)
+	c.User = cfg.Username
+	c.Passwd = cfg.Password
+	c.DBName = cfg.Name
+	c.ParseTime = true
+	c.Collation = "utf8mb4_unicode_ci"
+	if len(cfg.Params) > 0 {
+		c.Params = make(map[string]string, len(cfg.Params))
+		for k, v := range cfg.Params {
+			c.Params[k] = v
+		}
+	}
+
+	switch {
+	case strings.TrimSpace(cfg.TLSRootCert) != "":
+		tlsCfg, err := rootCertTLSConfig(cfg.TLSRootCert, cfg.Host)
+		if err != nil {
+			return nil, err
+		}
+		c.TLS = tlsCfg
+	case cfg.TLS != "" && cfg.TLS != "false":
+		c.TLSConfig = cfg.TLS
+	}
+
+	if cfg.PasswordProvider != nil {
+		provider := cfg.PasswordProvider
+		if err := c.Apply(gomysql.BeforeConnect(func(ctx context.Context, cc *gomysql.Config) error {
+			password, err := provider.Password(ctx)
+			if err != nil {
+				return fmt.Errorf("mysql: resolve password: %w", err)
+			}
+			cc.Passwd = password
+			return nil
+		})); err != nil {
+			return nil, err
+		}
+		// RDS IAM tokens are sent as cleartext passwords over TLS.
+		c.AllowCleartextPasswords = c.TLS != nil || c.TLSConfig != ""
+	}
+	return c, nil
+}
+
+// rootCertTLSConfig verifies the server against the CA bundle in rootCert,
+// given as PEM content or a file path.
+func rootCertTLSConfig(rootCert, host string) (*tls.Config, error) {
+	pemBytes := []byte(rootCert)
+	if !strings.Contains(rootCert, "-----BEGIN") {
+		data, err := os.ReadFile(rootCert)
+		if err != nil {
+			return nil, fmt.Errorf("mysql: read TLSRootCert: %w", err)
+		}
+		pemBytes = data
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemBytes) {
+		return nil, errors.New("mysql: TLSRootCert contains no PEM certificates")
+	}
+	return &tls.Config{RootCAs: pool, ServerName: host, MinVersion: tls.VersionTLS12}, nil
+}
+
+// RegisterTLSConfig registers a custom tls.Config under name, for use as
+// Config.TLS.
+func RegisterTLSConfig(name string, cfg *tls.Config) error {
+	return gomysql.RegisterTLSConfig(name, cfg)
+}
+
+// pool returns the connection pool settings of cfg.
+func (cfg Config) pool() sqldb.Pool {
+	return sqldb.Pool{
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: cfg.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+	}
+}
+
+// maskDSN redacts the password from a go-sql-driver DSN when logging.
+func maskDSN(dsn string) string {
+	c, err := gomysql.ParseDSN(dsn)
+	if err != nil {
+		// Fallback: redact between the first ':' and the last '@'.
+		at := strings.LastIndex(dsn, "@")
+		colon := strings.Index(dsn, ":")
+		if at == -1 || colon == -1 || colon > at {
+			return dsn
+		}
+		return dsn[:colon+1] + "********" + dsn[at:]
+	}
+	if c.Passwd != "" {
+		c.Passwd = "********"
+	}
+	return c.FormatDSN()
+}
+
+func logConnection(cfg Config, dsn string) {
+	log.Printf("\n==============================")
+	log.Printf("🚀 MySQL Connected Successfully!")
+	log.Printf("Host: %s | Port: %s | DB: %s | User: %s", cfg.Host, cfg.Port, cfg.Name, cfg.Username)
+	log.Printf("DSN: %s", dsn)
+	log.Printf("==============================\n")
+	log.Printf("[MySQL] Connection established. Ready for queries! 🚀")
+}
@@ -0,0 +1,197 @@
+package mysql
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/internal/sqldb"
+)
+
+func testCAPEM(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestDriverConfig(t *testing.T) {
+	caPEM := testCAPEM(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte(caPEM), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider := PasswordProviderFunc(func(context.Context) (string, error) { return "token", nil })
+
+	tests := []struct {
+		name          string
+		cfg           Config
+		wantAddr      string
+		wantTLSConfig string
+		wantTLS       bool
+		wantCleartext bool
+		wantErr       string
+	}{
+		{name: "default port", cfg: Config{Host: "db.internal"}, wantAddr: "db.internal:3306"},
+		{name: "explicit port", cfg: Config{Host: "db.internal", Port: "3307"}, wantAddr: "db.internal:3307"},
+		{name: "ipv6 host", cfg: Config{Host: "::1"}, wantAddr: "[::1]:3306"},
+		{name: "tls false", cfg: Config{Host: "db", TLS: "false"}, wantAddr: "db:3306"},
+		{name: "tls mode", cfg: Config{Host: "db", TLS: "skip-verify"}, wantAddr: "db:3306", wantTLSConfig: "skip-verify"},
+		{name: "root cert pem", cfg: Config{Host: "db", TLS: "false", TLSRootCert: caPEM}, wantAddr: "db:3306", wantTLS: true},
+		{name: "root cert file", cfg: Config{Host: "db", TLSRootCert: caFile}, wantAddr: "db:3306", wantTLS: true},
+		{name: "root cert without certificates", cfg: Config{Host: "db", TLSRootCert: "-----BEGIN nothing"}, wantErr: "no PEM certificates"},
+		{name: "missing root cert file", cfg: Config{Host: "db", TLSRootCert: filepath.Join(t.TempDir(), "missing.pem")}, wantErr: "read TLSRootCert"},
+		{name: "password provider without tls", cfg: Config{Host: "db", PasswordProvider: provider}, wantAddr: "db:3306"},
+		{name: "password provider over tls", cfg: Config{Host: "db", TLS: "true", PasswordProvider: provider}, wantAddr: "db:3306", wantTLSConfig: "true", wantCleartext: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := driverConfig(tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("driverConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("driverConfig() error = %v", err)
+			}
+			if c.Net != "tcp" || c.Addr != tt.wantAddr {
+				t.Errorf("address = %s %s, want tcp %s", c.Net, c.Addr, tt.wantAddr)
+			}
+			if !c.ParseTime || c.Collation != "utf8mb4_unicode_ci" {
+				t.Errorf("ParseTime = %t, Collation = %q, want true and utf8mb4_unicode_ci", c.ParseTime, c.Collation)
+			}
+			if c.TLSConfig != tt.wantTLSConfig {
+				t.Errorf("TLSConfig = %q, want %q", c.TLSConfig, tt.wantTLSConfig)
+			}
+			if (c.TLS != nil) != tt.wantTLS {
+				t.Errorf("TLS set = %t, want %t", c.TLS != nil, tt.wantTLS)
+			}
+			if tt.wantTLS && (c.TLS.ServerName != tt.cfg.Host || c.TLS.RootCAs == nil) {
+				t.Errorf("TLS = ServerName %q RootCAs %v, want the host and the CA pool", c.TLS.ServerName, c.TLS.RootCAs)
+			}
+			if c.AllowCleartextPasswords != tt.wantCleartext {
+				t.Errorf("AllowCleartextPasswords = %t, want %t", c.AllowCleartextPasswords, tt.wantCleartext)
+			}
+		})
+	}
+}
+
+func TestDriverConfigCopiesCredentialsAndParams(t *testing.T) {
+	params := map[string]string{"time_zone": "'+00:00'"}
+	c, err := driverConfig(Config{Host: "db", Name: "orders", Username: "app", Password: "secret", Params: params})
+	if err != nil {
+		t.Fatalf("driverConfig() error = %v", err)
+	}
+	if c.User != "app" || c.Passwd != "secret" || c.DBName != "orders" {
+		t.Fatalf("credentials = %q %q %q", c.User, c.Passwd, c.DBName)
+	}
+	params["time_zone"] = "'+05:00'"
+	if got := c.Params["time_zone"]; got != "'+00:00'" {
+		t.Fatalf("Params[time_zone] = %q, want a copy unaffected by later changes", got)
+	}
+}
+
+func TestMaskDSN(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+	}{
+		{name: "plain", password: "secret"},
+		{name: "at sign", password: "p@ss"},
+		{name: "colon", password: "pa:ss"},
+		{name: "at signs and colons", password: "a@b:c@d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := driverConfig(Config{Host: "db.internal", Name: "orders", Username: "app", Password: tt.password})
+			if err != nil {
+				t.Fatalf("driverConfig() error = %v", err)
+			}
+			masked := maskDSN(c.FormatDSN())
+			want := "app:********@tcp(db.internal:3306)/orders?"
+			if !strings.HasPrefix(masked, want) {
+				t.Fatalf("maskDSN() = %q, want prefix %q", masked, want)
+			}
+			if strings.Contains(masked, tt.password) {
+				t.Fatalf("maskDSN() = %q leaks the password", masked)
+			}
+		})
+	}
+
+	fallbacks := map[string]string{
+		"app@tcp(db:3306)/orders": "app@tcp(db:3306)/orders",
+		"app:p@ss@bad((":          "app:********@bad((",
+		"not a dsn":               "not a dsn",
+	}
+	for dsn, want := range fallbacks {
+		if got := maskDSN(dsn); got != want {
+			t.Errorf("maskDSN(%q) = %q, want %q", dsn, got, want)
+		}
+	}
+}
+
+func TestConfigPool(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want sqldb.Pool
+	}{
+		{name: "defaults", cfg: Config{}, want: sqldb.Pool{}},
+		{
+			name: "every setting",
+			cfg:  Config{MaxOpenConns: 20, MaxIdleConns: 5, ConnMaxLifetime: 30 * time.Minute, ConnMaxIdleTime: 5 * time.Minute},
+			want: sqldb.Pool{MaxOpenConns: 20, MaxIdleConns: 5, ConnMaxLifetime: 30 * time.Minute, ConnMaxIdleTime: 5 * time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.pool(); got != tt.want {
+				t.Fatalf("pool() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactSQL(t *testing.T) {
+	tests := map[string]string{
+		"SELECT * FROM users WHERE id = 42":           "SELECT * FROM users WHERE id = ?",
+		"SELECT * FROM users WHERE name = 'o''brien'": "SELECT * FROM users WHERE name = ?",
+		`SELECT * FROM users WHERE name = "bob\"s"`:   "SELECT * FROM users WHERE name = ?",
+		"SELECT `col1` FROM t2 WHERE a = 1.5":         "SELECT `col1` FROM t2 WHERE a = ?",
+		"SELECT 1 -- secret\nFROM dual":               "SELECT ? \nFROM dual",
+		"SELECT a FROM t # secret":                    "SELECT a FROM t",
+		"SELECT /* 'secret' */ a FROM t WHERE b = ?":  "SELECT  a FROM t WHERE b = ?",
+		"INSERT INTO t (a, b) VALUES ('x', 2)":        "INSERT INTO t (a, b) VALUES (?, ?)",
+	}
+	for query, want := range tests {
+		if got := RedactSQL(query); got != want {
+			t.Errorf("RedactSQL(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
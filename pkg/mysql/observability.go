@@ -0,0 +1,143 @@
+package mysql
+
+import (
+	"strings"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/internal/sqldb"
+	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/observability"
+)
+
+const (
+	meterName = "github.com/milan604/core-lab/pkg/mysql"
+
+	// DefaultSlowQueryThreshold is the duration above which QueryObserver logs
+	// a query as slow.
+	DefaultSlowQueryThreshold = sqldb.DefaultSlowQueryThreshold
+)
+
+// Span and metric attribute keys recorded by QueryObserver, alongside
+// observability.AttrDBOperation and observability.AttrDBStatement. They match
+// the keys recorded by pkg/postgres.
+var (
+	AttrDBTable = sqldb.AttrDBTable
+	AttrDBName  = sqldb.AttrDBName
+)
+
+// Option configures New.
+type Option func(*options)
+
+type options struct {
+	obs           observability.ObservabilityIface
+	log           logger.LogManager
+	slowThreshold time.Duration
+}
+
+// WithObservability registers a QueryObserver, tracing queries with obs's
+// tracer and recording metrics on the global meter provider.
+func WithObservability(obs observability.ObservabilityIface) Option {
+	return func(o *options) { o.obs = obs }
+}
+
+// WithSlowQueryThreshold sets the duration above which queries are logged as
+// slow (DefaultSlowQueryThreshold by default). A negative value turns slow
+// query logging off.
+func WithSlowQueryThreshold(d time.Duration) Option {
+	return func(o *options) { o.slowThreshold = d }
+}
+
+// WithLogger sets the logger used for slow query and migration logs. Without
+// it slow queries go to the standard library logger and migrations to a
+// default logger.
+func WithLogger(l logger.LogManager) Option {
+	return func(o *options) { o.log = l }
+}
+
+// QueryObserverConfig configures a QueryObserver.
+type QueryObserverConfig = sqldb.ObserverConfig
+
+// QueryObserver is the GORM plugin pkg/postgres registers, recording the same
+// spans, db.client.operation.* metrics and slow query logs with db.system
+// "mysql" and statements redacted by RedactSQL. Register it with
+// db.Client.Use(mysql.NewQueryObserver(cfg)), or pass WithObservability to
+// New.
+type QueryObserver = sqldb.Observer
+
+// NewQueryObserver creates a QueryObserver for MySQL.
+func NewQueryObserver(cfg QueryObserverConfig) *QueryObserver {
+	return sqldb.NewObserver(cfg, sqldb.Dialect{
+		Plugin:    "corelab:mysql_observer",
+		System:    "mysql",
+		Scope:     meterName,
+		LogPrefix: "[MySQL]",
+		Redact:    RedactSQL,
+	})
+}
+
+// RedactSQL replaces string and numeric literals in query with "?" and drops
+// comments, so statements can be recorded without the values they carry.
+// Backquoted identifiers are kept.
+func RedactSQL(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			i = skipQuoted(query, i+1, c)
+			b.WriteByte('?')
+		case c == '`':
+			end := strings.IndexByte(query[i+1:], '`')
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+end+2])
+			i += end + 2
+		case c == '#' || (c == '-' && i+2 < len(query) && query[i+1] == '-' && (query[i+2] == ' ' || query[i+2] == '\t')):
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return strings.TrimSpace(b.String())
+			}
+			i += end + 4
+		case isDigit(c) && (i == 0 || !isIdentChar(query[i-1])):
+			for i < len(query) && (isDigit(query[i]) || query[i] == '.') {
+				i++
+			}
+			b.WriteByte('?')
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// skipQuoted returns the index after the string literal opened at start-1,
+// honouring backslash escapes and doubled quotes.
+func skipQuoted(query string, start int, quote byte) int {
+	for i := start; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || (c|0x20 >= 'a' && c|0x20 <= 'z')
+}
@@ -0,0 +1,235 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultDependencyInventoryInterval = 5 * time.Minute
+
+// Span attribute keys used to identify a dependency.
+var (
+	AttrPeerService     = attribute.Key("peer.service")
+	AttrRPCSystem       = attribute.Key("rpc.system")
+	AttrRPCService      = attribute.Key("rpc.service")
+	AttrMessagingSystem = attribute.Key("messaging.system")
+	AttrDBSystem        = attribute.Key("db.system")
+	AttrProtocol        = attribute.Key("dependency.protocol")
+)
+
+// Dependency is one downstream service observed at runtime.
+type Dependency struct {
+	PeerService string    `json:"peer_service"`
+	Protocol    string    `json:"protocol"`
+	Calls       int64     `json:"calls"`
+	Errors      int64     `json:"errors"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+type dependencyKey struct {
+	peer     string
+	protocol string
+}
+
+// DependencyInventory records the services this process actually calls. It is
+// an sdktrace.SpanProcessor: every ended client or producer span carrying
+// peer.service (pkg/http sets it from WithPeerService) or rpc.service (gRPC
+// instrumentation) counts as a call. Start emits the inventory periodically as
+// a log line and as the service.dependency.calls / service.dependency.errors
+// metrics, so service maps and the CMDB reflect real runtime dependencies.
+//
+// New registers one automatically; use Record for clients that are not traced.
+type DependencyInventory struct {
+	serviceName string
+	log         logger.LogManager
+
+	mu   sync.Mutex
+	deps map[dependencyKey]*Dependency
+
+	stopOnce     sync.Once
+	stop         chan struct{}
+	done         chan struct{}
+	registration metric.Registration
+}
+
+// NewDependencyInventory creates an empty inventory for serviceName.
+func NewDependencyInventory(serviceName string, log logger.LogManager) *DependencyInventory {
+	return &DependencyInventory{
+		serviceName: serviceName,
+		log:         log,
+		deps:        make(map[dependencyKey]*Dependency),
+		stop:        make(chan struct{}),
+	}
+}
+
+// Record counts one call to peer over protocol.
+func (d *DependencyInventory) Record(peer, protocol string, failed bool) {
+	peer = strings.TrimSpace(peer)
+	if peer == "" {
+		return
+	}
+	protocol = strings.TrimSpace(protocol)
+	if protocol == "" {
+		protocol = "unknown"
+	}
+
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := dependencyKey{peer: peer, protocol: protocol}
+	dep, ok := d.deps[key]
+	if !ok {
+		dep = &Dependency{PeerService: peer, Protocol: protocol, FirstSeen: now}
+		d.deps[key] = dep
+	}
+	dep.Calls++
+	if failed {
+		dep.Errors++
+	}
+	dep.LastSeen = now
+}
+
+// Snapshot returns the dependencies seen so far, sorted by peer and protocol.
+func (d *DependencyInventory) Snapshot() []Dependency {
+	d.mu.Lock()
+	out := make([]Dependency, 0, len(d.deps))
+	for _, dep := range d.deps {
+		out = append(out, *dep)
+	}
+	d.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].PeerService != out[j].PeerService {
+			return out[i].PeerService < out[j].PeerService
+		}
+		return out[i].Protocol < out[j].Protocol
+	})
+	return out
+}
+
+// Start registers the inventory metrics and logs the inventory every interval
+// (default 5m) until Shutdown.
+func (d *DependencyInventory) Start(interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultDependencyInventoryInterval
+	}
+
+	meter := otel.Meter(d.serviceName)
+	calls, err := meter.Int64ObservableCounter("service.dependency.calls",
+		metric.WithDescription("Calls made to each downstream dependency"))
+	if err != nil {
+		return fmt.Errorf("create dependency calls metric: %w", err)
+	}
+	errs, err := meter.Int64ObservableCounter("service.dependency.errors",
+		metric.WithDescription("Failed calls to each downstream dependency"))
+	if err != nil {
+		return fmt.Errorf("create dependency errors metric: %w", err)
+	}
+	d.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for _, dep := range d.Snapshot() {
+			attrs := metric.WithAttributes(AttrPeerService.String(dep.PeerService), AttrProtocol.String(dep.Protocol))
+			o.ObserveInt64(calls, dep.Calls, attrs)
+			o.ObserveInt64(errs, dep.Errors, attrs)
+		}
+		return nil
+	}, calls, errs)
+	if err != nil {
+		return fmt.Errorf("register dependency metrics: %w", err)
+	}
+
+	d.done = make(chan struct{})
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.Emit()
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Emit logs the current inventory once.
+func (d *DependencyInventory) Emit() {
+	if d.log == nil {
+		return
+	}
+	deps := d.Snapshot()
+	if len(deps) == 0 {
+		return
+	}
+	parts := make([]string, len(deps))
+	for i, dep := range deps {
+		parts[i] = fmt.Sprintf("%s/%s calls=%d errors=%d", dep.PeerService, dep.Protocol, dep.Calls, dep.Errors)
+	}
+	d.log.InfoF("Dependency inventory: service=%s dependencies=%d [%s]",
+		d.serviceName, len(deps), strings.Join(parts, ", "))
+}
+
+// OnStart implements sdktrace.SpanProcessor.
+func (d *DependencyInventory) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+// OnEnd implements sdktrace.SpanProcessor, recording outgoing calls.
+func (d *DependencyInventory) OnEnd(span sdktrace.ReadOnlySpan) {
+	kind := span.SpanKind()
+	if kind != trace.SpanKindClient && kind != trace.SpanKindProducer {
+		return
+	}
+
+	var peer, rpcService, protocol string
+	for _, attr := range span.Attributes() {
+		switch attr.Key {
+		case AttrPeerService:
+			peer = attr.Value.AsString()
+		case AttrRPCService:
+			rpcService = attr.Value.AsString()
+		case AttrRPCSystem, AttrMessagingSystem, AttrDBSystem:
+			protocol = attr.Value.AsString()
+		case "http.request.method", AttrHTTPMethod:
+			if protocol == "" {
+				protocol = "http"
+			}
+		}
+	}
+	if peer == "" {
+		peer = rpcService
+	}
+	d.Record(peer, protocol, span.Status().Code == codes.Error)
+}
+
+// Shutdown implements sdktrace.SpanProcessor. It stops periodic emission and
+// logs the final inventory.
+func (d *DependencyInventory) Shutdown(context.Context) error {
+	d.stopOnce.Do(func() {
+		close(d.stop)
+		if d.done != nil {
+			<-d.done
+		}
+		if d.registration != nil {
+			_ = d.registration.Unregister()
+		}
+		d.Emit()
+	})
+	return nil
+}
+
+// ForceFlush implements sdktrace.SpanProcessor.
+func (d *DependencyInventory) ForceFlush(context.Context) error { return nil }
@@ -0,0 +1,67 @@
+package observability
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestDependencyInventoryRecordsClientSpans(t *testing.T) {
+	inventory := NewDependencyInventory("orders", nil)
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(inventory))
+	tracer := tp.Tracer("test")
+	ctx := context.Background()
+
+	_, span := tracer.Start(ctx, "HTTP GET", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(AttrPeerService.String("billing"), AttrHTTPMethod.String("GET")))
+	span.End()
+	_, span = tracer.Start(ctx, "HTTP POST", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(AttrPeerService.String("billing"), AttrHTTPMethod.String("POST")))
+	span.SetStatus(codes.Error, "boom")
+	span.End()
+	_, span = tracer.Start(ctx, "Check", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(AttrRPCSystem.String("grpc"), AttrRPCService.String("inventory.v1.Stock")))
+	span.End()
+	// Server spans and client spans without a peer are ignored.
+	_, span = tracer.Start(ctx, "GET /orders", trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(AttrPeerService.String("gateway")))
+	span.End()
+	_, span = tracer.Start(ctx, "anonymous", trace.WithSpanKind(trace.SpanKindClient))
+	span.End()
+
+	if err := tp.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	deps := inventory.Snapshot()
+	if len(deps) != 2 {
+		t.Fatalf("Snapshot() = %+v, want 2 dependencies", deps)
+	}
+	if deps[0].PeerService != "billing" || deps[0].Protocol != "http" || deps[0].Calls != 2 || deps[0].Errors != 1 {
+		t.Fatalf("billing dependency = %+v", deps[0])
+	}
+	if deps[1].PeerService != "inventory.v1.Stock" || deps[1].Protocol != "grpc" || deps[1].Calls != 1 {
+		t.Fatalf("grpc dependency = %+v", deps[1])
+	}
+}
+
+func TestDependencyInventoryStartAndShutdown(t *testing.T) {
+	inventory := NewDependencyInventory("orders", nil)
+	if err := inventory.Start(0); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	inventory.Record("billing", "", false)
+	if err := inventory.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	// A second shutdown (e.g. from the tracer provider) is a no-op.
+	if err := inventory.Shutdown(context.Background()); err != nil {
+		t.Fatalf("second Shutdown: %v", err)
+	}
+	if deps := inventory.Snapshot(); len(deps) != 1 || deps[0].Protocol != "unknown" {
+		t.Fatalf("Snapshot() = %+v", deps)
+	}
+}
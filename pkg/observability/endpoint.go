@@ -52,6 +52,16 @@ func otlpTraceExporterOptions(endpoint string) []otlptracehttp.Option {
 }
 
 func buildSignozLogsURL(endpoint string) string {
+	return buildSignozSignalURL(endpoint, "/v1/logs")
+}
+
+// buildSignozMetricsURL resolves the OTLP/HTTP metrics URL the same way logs
+// are resolved, so one base endpoint serves every signal.
+func buildSignozMetricsURL(endpoint string) string {
+	return buildSignozSignalURL(endpoint, "/v1/metrics")
+}
+
+func buildSignozSignalURL(endpoint, signalPath string) string {
 	endpoint = strings.TrimSpace(endpoint)
 	if endpoint == "" {
 		endpoint = defaultSignozEndpoint
@@ -62,27 +72,31 @@ func buildSignozLogsURL(endpoint string) string {
 
 	u, err := url.Parse(endpoint)
 	if err != nil || u.Host == "" {
-		return defaultSignozEndpoint + "/v1/logs"
+		return defaultSignozEndpoint + signalPath
 	}
 
 	u.RawQuery = ""
 	u.Fragment = ""
-	u.Path = normalizeLogsPath(u.Path)
+	u.Path = normalizeSignalPath(u.Path, signalPath)
 	return u.String()
 }
 
-func normalizeLogsPath(path string) string {
+// normalizeSignalPath points a base path, or a path for another signal, at
+// signalPath.
+func normalizeSignalPath(path, signalPath string) string {
 	trimmed := strings.TrimSuffix(strings.TrimSpace(path), "/")
 	if trimmed == "" {
-		return "/v1/logs"
+		return signalPath
 	}
-	if strings.HasSuffix(trimmed, "/v1/logs") {
+	if strings.HasSuffix(trimmed, signalPath) {
 		return trimmed
 	}
-	if strings.HasSuffix(trimmed, "/v1/traces") {
-		return strings.TrimSuffix(trimmed, "/v1/traces") + "/v1/logs"
+	for _, other := range []string{"/v1/traces", "/v1/logs", "/v1/metrics"} {
+		if strings.HasSuffix(trimmed, other) {
+			return strings.TrimSuffix(trimmed, other) + signalPath
+		}
 	}
-	return trimmed + "/v1/logs"
+	return trimmed + signalPath
 }
 
 func hasHTTPScheme(endpoint string) bool {
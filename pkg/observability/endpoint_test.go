@@ -93,3 +93,17 @@ func TestBuildSignozLogsURL(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildSignozMetricsURL(t *testing.T) {
+	tests := map[string]string{
+		"localhost:4318":                      "http://localhost:4318/v1/metrics",
+		"https://otel.example.com/collector":  "https://otel.example.com/collector/v1/metrics",
+		"https://otel.example.com/v1/traces":  "https://otel.example.com/v1/metrics",
+		"https://otel.example.com/v1/metrics": "https://otel.example.com/v1/metrics",
+	}
+	for endpoint, want := range tests {
+		if got := buildSignozMetricsURL(endpoint); got != want {
+			t.Fatalf("buildSignozMetricsURL(%q) = %q, want %q", endpoint, got, want)
+		}
+	}
+}
@@ -1,47 +1,108 @@
 package observability
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/milan604/core-lab/pkg/config"
 	"github.com/milan604/core-lab/pkg/logger"
-	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/metric"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
 	"go.uber.org/zap/zapcore"
 )
 
-// LogExporter manages log export to SigNoz via HTTP
+const logExporterScope = "github.com/milan604/core-lab/pkg/observability"
+
+// Reasons recorded on the logs.export.dropped counter.
+const (
+	LogDropQueueFull    = "queue_full"
+	LogDropExportFailed = "export_failed"
+)
+
+// AttrDropReason labels the logs.export.dropped counter.
+var AttrDropReason = attribute.Key("reason")
+
+// LogExportConfig tunes the log pipeline built by NewLogExporter.
+type LogExportConfig struct {
+	// QueueSize bounds the records waiting for export. Records emitted while
+	// the queue is full are dropped and counted. Default: 2048.
+	QueueSize int
+	// BatchSize is the most records sent in one export. Default: 512.
+	BatchSize int
+	// Interval is the longest a record waits before its batch is exported. Default: 5s.
+	Interval time.Duration
+	// Timeout bounds each export attempt. Default: 10s.
+	Timeout time.Duration
+	// RetryMaxElapsed is how long a failing batch is retried with backoff
+	// before its records are dropped. Default: 1m.
+	RetryMaxElapsed time.Duration
+}
+
+// LogExportConfigFromConfig reads LogExportQueueSize, LogExportBatchSize,
+// LogExportInterval, LogExportTimeout, and LogExportRetryMaxElapsed.
+func LogExportConfigFromConfig(cfg *config.Config) LogExportConfig {
+	if cfg == nil {
+		return LogExportConfig{}
+	}
+	return LogExportConfig{
+		QueueSize:       cfg.GetIntD("LogExportQueueSize", 0),
+		BatchSize:       cfg.GetIntD("LogExportBatchSize", 0),
+		Interval:        cfg.GetDurationD("LogExportInterval", 0),
+		Timeout:         cfg.GetDurationD("LogExportTimeout", 0),
+		RetryMaxElapsed: cfg.GetDurationD("LogExportRetryMaxElapsed", 0),
+	}
+}
+
+func (c LogExportConfig) withDefaults() LogExportConfig {
+	if c.QueueSize <= 0 {
+		c.QueueSize = 2048
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 512
+	}
+	c.BatchSize = min(c.BatchSize, c.QueueSize)
+	if c.Interval <= 0 {
+		c.Interval = 5 * time.Second
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.RetryMaxElapsed <= 0 {
+		c.RetryMaxElapsed = time.Minute
+	}
+	return c
+}
+
+// LogExportStats reports the state of the log pipeline.
+type LogExportStats struct {
+	// Pending is the number of records queued or being exported.
+	Pending int64
+	// DroppedQueueFull counts records dropped because the queue was full.
+	DroppedQueueFull int64
+	// DroppedExportFailed counts records dropped after export retries failed.
+	DroppedExportFailed int64
+}
+
+// LogExporter ships logs to SigNoz through the OpenTelemetry log SDK: an
+// OTLP/HTTP exporter that retries transient failures, behind a BatchProcessor
+// with a bounded queue. Records that cannot be queued or delivered are dropped
+// and counted on the logs.export.dropped metric instead of buffering without
+// limit.
 type LogExporter struct {
-	signozLogsURL  string
+	provider       *sdklog.LoggerProvider
+	logger         otellog.Logger
+	gate           *logQueueGate
 	serviceName    string
 	serviceVersion string
-	httpClient     *http.Client
-	mu             sync.Mutex
-	buffer         []LogEntry
-	bufferSize     int
-	flushInterval  time.Duration
-	stopChan       chan struct{}
-}
-
-// LogEntry represents a log entry to be sent to SigNoz
-type LogEntry struct {
-	Timestamp  time.Time              `json:"timestamp"`
-	Level      string                 `json:"level"`
-	Message    string                 `json:"message"`
-	Service    string                 `json:"service"`
-	Version    string                 `json:"version"`
-	TraceID    string                 `json:"trace_id,omitempty"`
-	SpanID     string                 `json:"span_id,omitempty"`
-	Attributes map[string]interface{} `json:"attributes,omitempty"`
-	Caller     string                 `json:"caller,omitempty"`
-	Stacktrace string                 `json:"stacktrace,omitempty"`
 }
 
 // NewLogExporter creates a new log exporter for sending logs to SigNoz
@@ -56,202 +117,276 @@ func NewLogExporter(cfg *config.Config) (*LogExporter, error) {
 		serviceVersion = "1.0.0"
 	}
 
-	signozEndpoint := resolveSignozEndpoint(cfg)
+	res, err := newResource(cfg, serviceName, serviceVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	exportCfg := LogExportConfigFromConfig(cfg).withDefaults()
+	exporter, err := otlploghttp.New(context.Background(),
+		otlploghttp.WithEndpointURL(buildSignozLogsURL(resolveSignozEndpoint(cfg))),
+		otlploghttp.WithTimeout(exportCfg.Timeout),
+		otlploghttp.WithRetry(otlploghttp.RetryConfig{
+			Enabled:         true,
+			InitialInterval: time.Second,
+			MaxInterval:     10 * time.Second,
+			MaxElapsedTime:  exportCfg.RetryMaxElapsed,
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP log exporter: %w", err)
+	}
 
-	exporter := &LogExporter{
-		signozLogsURL:  buildSignozLogsURL(signozEndpoint),
+	return newLogExporter(serviceName, serviceVersion, res, exporter, exportCfg), nil
+}
+
+func newLogExporter(serviceName, serviceVersion string, res *resource.Resource, exporter sdklog.Exporter, cfg LogExportConfig) *LogExporter {
+	cfg = cfg.withDefaults()
+	gate := newLogQueueGate(int64(cfg.QueueSize))
+	processor := sdklog.NewBatchProcessor(&countingLogExporter{Exporter: exporter, gate: gate},
+		// The gate keeps at most QueueSize records in flight, so the
+		// processor's own queue never overflows and drops silently.
+		sdklog.WithMaxQueueSize(cfg.QueueSize),
+		sdklog.WithExportMaxBatchSize(cfg.BatchSize),
+		sdklog.WithExportInterval(cfg.Interval),
+		sdklog.WithExportTimeout(cfg.Timeout+cfg.RetryMaxElapsed),
+	)
+	gate.next = processor
+
+	opts := []sdklog.LoggerProviderOption{sdklog.WithProcessor(gate)}
+	if res != nil {
+		opts = append(opts, sdklog.WithResource(res))
+	}
+	provider := sdklog.NewLoggerProvider(opts...)
+
+	return &LogExporter{
+		provider:       provider,
+		logger:         provider.Logger(logExporterScope),
+		gate:           gate,
 		serviceName:    serviceName,
 		serviceVersion: serviceVersion,
-		httpClient: &http.Client{
-			Timeout: 5 * time.Second,
-		},
-		buffer:        make([]LogEntry, 0, 100),
-		bufferSize:    100,
-		flushInterval: 5 * time.Second,
-		stopChan:      make(chan struct{}),
-	}
-
-	// Start background flush goroutine
-	go exporter.flushLoop()
-
-	return exporter, nil
-}
-
-// flushLoop periodically flushes buffered logs
-func (le *LogExporter) flushLoop() {
-	ticker := time.NewTicker(le.flushInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			le.Flush(context.Background())
-		case <-le.stopChan:
-			// Final flush on shutdown
-			le.Flush(context.Background())
-			return
-		}
 	}
 }
 
-// EmitLog sends a log record to SigNoz (buffered)
-func (le *LogExporter) EmitLog(ctx context.Context, level string, message string, fields map[string]interface{}) {
-	// Extract trace context if available
-	var traceID, spanID string
-	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
-		traceID = span.SpanContext().TraceID().String()
-		spanID = span.SpanContext().SpanID().String()
-	}
+// LoggerProvider returns the underlying OpenTelemetry logger provider, e.g.
+// for NewZapCore or other log bridges.
+func (le *LogExporter) LoggerProvider() otellog.LoggerProvider {
+	return le.provider
+}
 
-	entry := LogEntry{
-		Timestamp:  time.Now(),
-		Level:      level,
-		Message:    message,
-		Service:    le.serviceName,
-		Version:    le.serviceVersion,
-		TraceID:    traceID,
-		SpanID:     spanID,
-		Attributes: fields,
+// Stats returns the pipeline counters.
+func (le *LogExporter) Stats() LogExportStats {
+	return LogExportStats{
+		Pending:             le.gate.pending.Load(),
+		DroppedQueueFull:    le.gate.droppedQueueFull.Load(),
+		DroppedExportFailed: le.gate.droppedExportFailed.Load(),
 	}
+}
 
-	le.mu.Lock()
-	le.buffer = append(le.buffer, entry)
-	shouldFlush := len(le.buffer) >= le.bufferSize
-	le.mu.Unlock()
+// EmitLog queues a log record for export. The trace and span IDs of the span
+// in ctx, if any, are attached for trace-log correlation.
+func (le *LogExporter) EmitLog(ctx context.Context, level string, message string, fields map[string]interface{}) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
 
-	if shouldFlush {
-		le.Flush(ctx)
+	now := time.Now()
+	var record otellog.Record
+	record.SetTimestamp(now)
+	record.SetObservedTimestamp(now)
+	record.SetSeverity(mapLogLevelToSeverity(level))
+	record.SetSeverityText(strings.ToUpper(level))
+	record.SetBody(otellog.StringValue(message))
+	for key, value := range fields {
+		record.AddAttributes(otellog.KeyValue{Key: key, Value: logValue(value)})
 	}
+	le.logger.Emit(ctx, record)
 }
 
-// Flush sends buffered logs to SigNoz
+// Flush exports queued logs, waiting until they are delivered or ctx ends.
 func (le *LogExporter) Flush(ctx context.Context) error {
-	le.mu.Lock()
-	if len(le.buffer) == 0 {
-		le.mu.Unlock()
-		return nil
+	return le.provider.ForceFlush(ctx)
+}
+
+// mapLogLevelToSeverity maps log level to OTLP severity number
+func mapLogLevelToSeverity(level string) otellog.Severity {
+	switch strings.ToLower(level) {
+	case "debug":
+		return otellog.SeverityDebug
+	case "info":
+		return otellog.SeverityInfo
+	case "warn", "warning":
+		return otellog.SeverityWarn
+	case "error":
+		return otellog.SeverityError
+	case "dpanic", "panic":
+		return otellog.SeverityFatal
+	case "fatal":
+		return otellog.SeverityFatal4
+	default:
+		return otellog.SeverityInfo
+	}
+}
+
+// logValue converts a field value to a log attribute value, keeping numbers,
+// booleans, errors, and nested maps and slices (as produced by zap's map
+// encoder) typed and formatting anything else.
+func logValue(value any) otellog.Value {
+	switch v := value.(type) {
+	case nil:
+		return otellog.Value{}
+	case string:
+		return otellog.StringValue(v)
+	case bool:
+		return otellog.BoolValue(v)
+	case int:
+		return otellog.IntValue(v)
+	case int8:
+		return otellog.Int64Value(int64(v))
+	case int16:
+		return otellog.Int64Value(int64(v))
+	case int32:
+		return otellog.Int64Value(int64(v))
+	case int64:
+		return otellog.Int64Value(v)
+	case uint8:
+		return otellog.Int64Value(int64(v))
+	case uint16:
+		return otellog.Int64Value(int64(v))
+	case uint32:
+		return otellog.Int64Value(int64(v))
+	case uint:
+		return otellog.Int64Value(int64(v))
+	case uint64:
+		return otellog.Int64Value(int64(v))
+	case uintptr:
+		return otellog.Int64Value(int64(v))
+	case float32:
+		return otellog.Float64Value(float64(v))
+	case float64:
+		return otellog.Float64Value(v)
+	case []byte:
+		return otellog.BytesValue(v)
+	case time.Duration:
+		return otellog.StringValue(v.String())
+	case time.Time:
+		return otellog.StringValue(v.Format(time.RFC3339Nano))
+	case map[string]any:
+		kvs := make([]otellog.KeyValue, 0, len(v))
+		for key, nested := range v {
+			kvs = append(kvs, otellog.KeyValue{Key: key, Value: logValue(nested)})
+		}
+		return otellog.MapValue(kvs...)
+	case []any:
+		values := make([]otellog.Value, len(v))
+		for i, nested := range v {
+			values[i] = logValue(nested)
+		}
+		return otellog.SliceValue(values...)
+	case error:
+		return otellog.StringValue(v.Error())
+	case fmt.Stringer:
+		return otellog.StringValue(v.String())
+	default:
+		return otellog.StringValue(fmt.Sprintf("%v", v))
 	}
+}
 
-	entries := make([]LogEntry, len(le.buffer))
-	copy(entries, le.buffer)
-	le.buffer = le.buffer[:0]
-	le.mu.Unlock()
-
-	// Send logs to SigNoz via HTTP
-	// SigNoz accepts logs via OTLP HTTP endpoint
-	// We'll send logs in OTLP format to /v1/logs endpoint
-	payload := map[string]interface{}{
-		"resourceLogs": []map[string]interface{}{
-			{
-				"resource": map[string]interface{}{
-					"attributes": []map[string]interface{}{
-						{"key": "service.name", "value": map[string]interface{}{"stringValue": le.serviceName}},
-						{"key": "service.version", "value": map[string]interface{}{"stringValue": le.serviceVersion}},
-					},
-				},
-				"scopeLogs": []map[string]interface{}{
-					{
-						"logRecords": le.convertToOTLPFormat(entries),
-					},
-				},
-			},
-		},
-	}
-
-	jsonData, err := json.Marshal(payload)
+// Shutdown exports queued logs and stops the pipeline.
+func (le *LogExporter) Shutdown(ctx context.Context) error {
+	return le.provider.Shutdown(ctx)
+}
+
+// logQueueGate sits in front of the BatchProcessor and bounds the records in
+// flight, counting what it has to drop.
+type logQueueGate struct {
+	next                sdklog.Processor
+	limit               int64
+	pending             atomic.Int64
+	droppedQueueFull    atomic.Int64
+	droppedExportFailed atomic.Int64
+	dropped             metric.Int64Counter
+	registration        metric.Registration
+}
+
+func newLogQueueGate(limit int64) *logQueueGate {
+	g := &logQueueGate{limit: limit}
+	meter := otel.Meter(logExporterScope)
+	dropped, err := meter.Int64Counter("logs.export.dropped",
+		metric.WithDescription("Log records dropped before reaching the collector"), metric.WithUnit("{record}"))
 	if err != nil {
-		return fmt.Errorf("failed to marshal logs: %w", err)
+		otel.Handle(err)
 	}
-
-	// Send to SigNoz OTLP logs endpoint.
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, le.signozLogsURL, bytes.NewReader(jsonData))
+	g.dropped = dropped
+	queued, err := meter.Int64ObservableGauge("logs.export.queue.size",
+		metric.WithDescription("Log records queued or being exported"), metric.WithUnit("{record}"))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		otel.Handle(err)
+		return g
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := le.httpClient.Do(req)
+	g.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveInt64(queued, g.pending.Load())
+		return nil
+	}, queued)
 	if err != nil {
-		return fmt.Errorf("failed to send logs: %w", err)
+		otel.Handle(err)
 	}
-	defer resp.Body.Close()
+	return g
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+func (g *logQueueGate) drop(ctx context.Context, reason string, n int64) {
+	if reason == LogDropQueueFull {
+		g.droppedQueueFull.Add(n)
+	} else {
+		g.droppedExportFailed.Add(n)
+	}
+	if g.dropped != nil {
+		g.dropped.Add(ctx, n, metric.WithAttributes(AttrDropReason.String(reason)))
 	}
-
-	return nil
 }
 
-// convertToOTLPFormat converts log entries to OTLP format
-func (le *LogExporter) convertToOTLPFormat(entries []LogEntry) []map[string]interface{} {
-	otlpRecords := make([]map[string]interface{}, 0, len(entries))
-
-	for _, entry := range entries {
-		// Map log level to OTLP severity
-		severityNumber := mapLogLevelToSeverity(entry.Level)
-
-		// Build attributes
-		attrs := []map[string]interface{}{
-			{"key": "message", "value": map[string]interface{}{"stringValue": entry.Message}},
-		}
-
-		if entry.TraceID != "" {
-			attrs = append(attrs, map[string]interface{}{
-				"key": "trace_id", "value": map[string]interface{}{"stringValue": entry.TraceID},
-			})
-		}
-		if entry.SpanID != "" {
-			attrs = append(attrs, map[string]interface{}{
-				"key": "span_id", "value": map[string]interface{}{"stringValue": entry.SpanID},
-			})
-		}
-
-		// Add custom attributes
-		for k, v := range entry.Attributes {
-			attrs = append(attrs, map[string]interface{}{
-				"key": k, "value": map[string]interface{}{"stringValue": fmt.Sprintf("%v", v)},
-			})
-		}
+// Enabled implements sdklog.Processor.
+func (g *logQueueGate) Enabled(ctx context.Context, param sdklog.EnabledParameters) bool {
+	return g.next.Enabled(ctx, param)
+}
 
-		record := map[string]interface{}{
-			"timeUnixNano":         entry.Timestamp.UnixNano(),
-			"observedTimeUnixNano": time.Now().UnixNano(),
-			"severityNumber":       severityNumber,
-			"severityText":         entry.Level,
-			"body": map[string]interface{}{
-				"stringValue": entry.Message,
-			},
-			"attributes": attrs,
-		}
+// OnEmit implements sdklog.Processor.
+func (g *logQueueGate) OnEmit(ctx context.Context, record *sdklog.Record) error {
+	if g.pending.Add(1) > g.limit {
+		g.pending.Add(-1)
+		g.drop(ctx, LogDropQueueFull, 1)
+		return nil
+	}
+	return g.next.OnEmit(ctx, record)
+}
 
-		otlpRecords = append(otlpRecords, record)
+// Shutdown implements sdklog.Processor.
+func (g *logQueueGate) Shutdown(ctx context.Context) error {
+	if g.registration != nil {
+		_ = g.registration.Unregister()
 	}
+	return g.next.Shutdown(ctx)
+}
 
-	return otlpRecords
+// ForceFlush implements sdklog.Processor.
+func (g *logQueueGate) ForceFlush(ctx context.Context) error {
+	return g.next.ForceFlush(ctx)
 }
 
-// mapLogLevelToSeverity maps log level to OTLP severity number
-func mapLogLevelToSeverity(level string) int {
-	switch level {
-	case "DEBUG", "debug":
-		return 5 // DEBUG
-	case "INFO", "info":
-		return 9 // INFO
-	case "WARN", "warn", "WARNING", "warning":
-		return 13 // WARN
-	case "ERROR", "error":
-		return 17 // ERROR
-	default:
-		return 9 // Default to INFO
-	}
+// countingLogExporter releases gate capacity as batches finish and counts the
+// records of batches that failed after retries.
+type countingLogExporter struct {
+	sdklog.Exporter
+	gate *logQueueGate
 }
 
-// Shutdown gracefully shuts down the log exporter
-func (le *LogExporter) Shutdown(ctx context.Context) error {
-	close(le.stopChan)
-	return le.Flush(ctx)
+func (e *countingLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	err := e.Exporter.Export(ctx, records)
+	e.gate.pending.Add(-int64(len(records)))
+	if err != nil {
+		e.gate.drop(ctx, LogDropExportFailed, int64(len(records)))
+	}
+	return err
 }
 
 // ZapHook is a zap hook that sends logs to SigNoz
@@ -274,10 +409,9 @@ func (h *ZapHook) Levels() []zapcore.Level {
 	}
 }
 
-// Fire is called when a log entry is written
+// Fire is called when a log entry is written. Prefer NewZapCore, which also
+// exports the entry's fields.
 func (h *ZapHook) Fire(entry zapcore.Entry) error {
-	// Extract trace context from entry fields if available
-	ctx := context.Background()
 	fields := make(map[string]interface{})
 
 	// Add caller information
@@ -290,13 +424,7 @@ func (h *ZapHook) Fire(entry zapcore.Entry) error {
 		fields["stacktrace"] = entry.Stack
 	}
 
-	// Convert zap fields to map
-	// Note: This is simplified - in production you'd want to properly extract all fields
-	level := entry.Level.String()
-
-	// Emit log to SigNoz
-	h.exporter.EmitLog(ctx, level, entry.Message, fields)
-
+	h.exporter.EmitLog(context.Background(), entry.Level.String(), entry.Message, fields)
 	return nil
 }
 
@@ -328,23 +456,34 @@ type LogManagerWrapper struct {
 	fields   map[string]interface{}
 }
 
-// keyValuesToFields converts logger.With-style key/value slices to a map.
+// keyValuesToFields converts logger.With-style arguments, alternating keys and
+// values or zap.Field values, to a map.
 func keyValuesToFields(keyValues ...any) map[string]interface{} {
 	if len(keyValues) == 0 {
 		return nil
 	}
 
 	fields := make(map[string]interface{}, len(keyValues)/2)
-	for i := 0; i < len(keyValues); i += 2 {
-		key := strings.TrimSpace(fmt.Sprintf("%v", keyValues[i]))
-		if key == "" {
+	for i := 0; i < len(keyValues); {
+		if field, ok := keyValues[i].(zapcore.Field); ok {
+			encoder := zapcore.NewMapObjectEncoder()
+			field.AddTo(encoder)
+			for key, value := range encoder.Fields {
+				fields[key] = value
+			}
+			i++
 			continue
 		}
 
+		key := strings.TrimSpace(fmt.Sprintf("%v", keyValues[i]))
 		var value interface{}
 		if i+1 < len(keyValues) {
 			value = keyValues[i+1]
 		}
+		i += 2
+		if key == "" {
+			continue
+		}
 
 		fields[key] = value
 	}
@@ -356,6 +495,40 @@ func keyValuesToFields(keyValues ...any) map[string]interface{} {
 	return fields
 }
 
+// splitMessageFields separates structured fields from the arguments of
+// Debug/Info/Warn/Error. zap.Field arguments are always fields; after them, a
+// string message followed by string-keyed pairs, as in
+// Info("user signed in", "user_id", id), is a message with fields. Anything
+// else is a message only, formatted like fmt.Sprint.
+func splitMessageFields(args []any) (string, []any) {
+	var fields []any
+	rest := make([]any, 0, len(args))
+	for _, arg := range args {
+		if field, ok := arg.(zapcore.Field); ok {
+			fields = append(fields, field)
+			continue
+		}
+		rest = append(rest, arg)
+	}
+
+	if len(rest) >= 3 && len(rest)%2 == 1 {
+		if message, ok := rest[0].(string); ok && pairKeysAreStrings(rest[1:]) {
+			return message, append(rest[1:], fields...)
+		}
+	}
+
+	return fmt.Sprint(rest...), fields
+}
+
+func pairKeysAreStrings(keyValues []any) bool {
+	for i := 0; i < len(keyValues); i += 2 {
+		if _, ok := keyValues[i].(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
 func cloneFields(fields map[string]interface{}) map[string]interface{} {
 	if len(fields) == 0 {
 		return nil
@@ -402,34 +575,76 @@ func normalizeLogMessage(message string, fields map[string]interface{}) string {
 	return "log_entry"
 }
 
-func (l *LogManagerWrapper) emit(ctx context.Context, level, message string) {
-	resolvedFields := cloneFields(l.fields)
+// emit exports message with the wrapper's With fields plus keyValues, which
+// take precedence.
+func (l *LogManagerWrapper) emit(ctx context.Context, level, message string, keyValues ...any) {
+	resolvedFields := mergeFields(l.fields, keyValuesToFields(keyValues...))
 	resolvedMessage := normalizeLogMessage(message, resolvedFields)
 	l.exporter.EmitLog(ctx, level, resolvedMessage, resolvedFields)
 }
 
-// Debug logs a debug message
+// log writes args to the original logger and exports them, keeping key/value
+// pairs and zap fields as attributes (see splitMessageFields).
+func (l *LogManagerWrapper) log(level string, args []any) {
+	message, keyValues := splitMessageFields(args)
+	original := l.original
+	if len(keyValues) > 0 {
+		original = original.With(keyValues...)
+		args = []any{message}
+	}
+
+	switch level {
+	case "DEBUG":
+		original.Debug(args...)
+	case "WARN":
+		original.Warn(args...)
+	case "ERROR":
+		original.Error(args...)
+	case "DPANIC":
+		l.emitAndFlush(context.Background(), level, message, keyValues...)
+		original.DPanic(args...)
+		return
+	case "FATAL":
+		l.emitAndFlush(context.Background(), level, message, keyValues...)
+		original.Fatal(args...)
+		return
+	default:
+		original.Info(args...)
+	}
+	l.emit(context.Background(), level, message, keyValues...)
+}
+
+// emitAndFlush exports a DPanic or Fatal entry and flushes the exporter
+// before the original logger panics or exits, which would lose it otherwise.
+func (l *LogManagerWrapper) emitAndFlush(ctx context.Context, level, message string, keyValues ...any) {
+	l.emit(ctx, level, message, keyValues...)
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = l.exporter.Flush(flushCtx)
+}
+
+// Debug logs a debug message. A string message followed by key/value pairs,
+// or zap.Field arguments, are exported as attributes.
 func (l *LogManagerWrapper) Debug(args ...any) {
-	l.original.Debug(args...)
-	l.emit(context.Background(), "DEBUG", fmt.Sprint(args...))
+	l.log("DEBUG", args)
 }
 
-// Info logs an info message
+// Info logs an info message. A string message followed by key/value pairs,
+// or zap.Field arguments, are exported as attributes.
 func (l *LogManagerWrapper) Info(args ...any) {
-	l.original.Info(args...)
-	l.emit(context.Background(), "INFO", fmt.Sprint(args...))
+	l.log("INFO", args)
 }
 
-// Warn logs a warning message
+// Warn logs a warning message. A string message followed by key/value pairs,
+// or zap.Field arguments, are exported as attributes.
 func (l *LogManagerWrapper) Warn(args ...any) {
-	l.original.Warn(args...)
-	l.emit(context.Background(), "WARN", fmt.Sprint(args...))
+	l.log("WARN", args)
 }
 
-// Error logs an error message
+// Error logs an error message. A string message followed by key/value pairs,
+// or zap.Field arguments, are exported as attributes.
 func (l *LogManagerWrapper) Error(args ...any) {
-	l.original.Error(args...)
-	l.emit(context.Background(), "ERROR", fmt.Sprint(args...))
+	l.log("ERROR", args)
 }
 
 // DebugF logs a formatted debug message
@@ -464,28 +679,64 @@ func (l *LogManagerWrapper) ErrorF(format string, args ...any) {
 func (l *LogManagerWrapper) DebugFCtx(ctx context.Context, format string, args ...any) {
 	message := fmt.Sprintf(format, args...)
 	l.original.DebugFCtx(ctx, format, args...)
-	l.emit(ctx, "DEBUG", message)
+	l.emit(ctx, "DEBUG", message, logger.ContextFields(ctx)...)
 }
 
 // InfoFCtx logs a formatted info message with context
 func (l *LogManagerWrapper) InfoFCtx(ctx context.Context, format string, args ...any) {
 	message := fmt.Sprintf(format, args...)
 	l.original.InfoFCtx(ctx, format, args...)
-	l.emit(ctx, "INFO", message)
+	l.emit(ctx, "INFO", message, logger.ContextFields(ctx)...)
 }
 
 // WarnFCtx logs a formatted warning message with context
 func (l *LogManagerWrapper) WarnFCtx(ctx context.Context, format string, args ...any) {
 	message := fmt.Sprintf(format, args...)
 	l.original.WarnFCtx(ctx, format, args...)
-	l.emit(ctx, "WARN", message)
+	l.emit(ctx, "WARN", message, logger.ContextFields(ctx)...)
 }
 
 // ErrorFCtx logs a formatted error message with context
 func (l *LogManagerWrapper) ErrorFCtx(ctx context.Context, format string, args ...any) {
 	message := fmt.Sprintf(format, args...)
 	l.original.ErrorFCtx(ctx, format, args...)
-	l.emit(ctx, "ERROR", message)
+	l.emit(ctx, "ERROR", message, logger.ContextFields(ctx)...)
+}
+
+// DPanic logs an error, exported and flushed before the original logger
+// panics in development.
+func (l *LogManagerWrapper) DPanic(args ...any) {
+	l.log("DPANIC", args)
+}
+
+// DPanicF logs a formatted DPanic message
+func (l *LogManagerWrapper) DPanicF(format string, args ...any) {
+	l.emitAndFlush(context.Background(), "DPANIC", fmt.Sprintf(format, args...))
+	l.original.DPanicF(format, args...)
+}
+
+// DPanicFCtx logs a formatted DPanic message with context
+func (l *LogManagerWrapper) DPanicFCtx(ctx context.Context, format string, args ...any) {
+	l.emitAndFlush(ctx, "DPANIC", fmt.Sprintf(format, args...), logger.ContextFields(ctx)...)
+	l.original.DPanicFCtx(ctx, format, args...)
+}
+
+// Fatal exports and flushes the message, then lets the original logger write
+// it, run the shutdown hooks, and exit.
+func (l *LogManagerWrapper) Fatal(args ...any) {
+	l.log("FATAL", args)
+}
+
+// FatalF logs a formatted fatal message and exits
+func (l *LogManagerWrapper) FatalF(format string, args ...any) {
+	l.emitAndFlush(context.Background(), "FATAL", fmt.Sprintf(format, args...))
+	l.original.FatalF(format, args...)
+}
+
+// FatalFCtx logs a formatted fatal message with context and exits
+func (l *LogManagerWrapper) FatalFCtx(ctx context.Context, format string, args ...any) {
+	l.emitAndFlush(ctx, "FATAL", fmt.Sprintf(format, args...), logger.ContextFields(ctx)...)
+	l.original.FatalFCtx(ctx, format, args...)
 }
 
 // With adds fields to the logger
@@ -501,7 +752,9 @@ func (l *LogManagerWrapper) With(keyValues ...any) logger.LogManager {
 func (l *LogManagerWrapper) Sync() error {
 	var exportErr error
 	if l.exporter != nil {
-		exportErr = l.exporter.Flush(context.Background())
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		exportErr = l.exporter.Flush(ctx)
 	}
 
 	return errors.Join(exportErr, l.original.Sync())
@@ -0,0 +1,91 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	otellog "go.opentelemetry.io/otel/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogExporterBoundsQueueAndCountsDrops(t *testing.T) {
+	recorder := &recordingLogExporter{block: make(chan struct{})}
+	exporter := newLogExporter("orders", "1.0.0", nil, recorder, LogExportConfig{
+		QueueSize: 4,
+		BatchSize: 2,
+		Interval:  time.Millisecond,
+	})
+
+	for range 10 {
+		exporter.EmitLog(context.Background(), "info", "queued", nil)
+	}
+	stats := exporter.Stats()
+	if stats.Pending != 4 || stats.DroppedQueueFull != 6 {
+		t.Fatalf("stats with blocked exporter = %+v, want 4 pending and 6 dropped", stats)
+	}
+
+	close(recorder.block)
+	if got := len(recorder.exported(t, exporter)); got != 4 {
+		t.Fatalf("exported %d records, want 4", got)
+	}
+
+	recorder.mu.Lock()
+	recorder.err = errors.New("collector unavailable")
+	recorder.mu.Unlock()
+	exporter.EmitLog(context.Background(), "error", "lost", nil)
+	_ = exporter.Flush(context.Background())
+	if stats := exporter.Stats(); stats.Pending != 0 || stats.DroppedExportFailed != 1 {
+		t.Fatalf("stats after failed export = %+v", stats)
+	}
+	if err := exporter.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
+
+func TestZapCoreExportsFieldsWithTraceContext(t *testing.T) {
+	recorder := &recordingLogExporter{}
+	exporter := newLogExporter("orders", "1.0.0", nil, recorder, LogExportConfig{})
+	log := zap.New(NewZapCore(exporter.LoggerProvider(), "orders", zapcore.InfoLevel)).
+		With(zap.String("component", "checkout"))
+
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "place-order")
+	log.Info("order placed",
+		zap.Any("ctx", ctx),
+		zap.Int("items", 3),
+		zap.Error(errors.New("card declined")),
+		zap.Object("customer", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("tier", "gold")
+			return nil
+		})),
+	)
+	log.Debug("filtered by level")
+	span.End()
+
+	records := recorder.exported(t, exporter)
+	if len(records) != 1 {
+		t.Fatalf("exported %d records, want 1", len(records))
+	}
+	record := records[0]
+	if record.Body().AsString() != "order placed" || record.Severity() != otellog.SeverityInfo {
+		t.Fatalf("record = %v %v", record.Body(), record.Severity())
+	}
+	if record.TraceID() != span.SpanContext().TraceID() || record.SpanID() != span.SpanContext().SpanID() {
+		t.Fatalf("record trace = %s/%s, want %s", record.TraceID(), record.SpanID(), span.SpanContext().TraceID())
+	}
+
+	attrs := recordAttributes(record)
+	if _, ok := attrs["ctx"]; ok {
+		t.Fatal("context field exported as an attribute")
+	}
+	if attrs["component"].AsString() != "checkout" || attrs["items"].AsInt64() != 3 || attrs["error"].AsString() != "card declined" {
+		t.Fatalf("attributes = %v", attrs)
+	}
+	customer := attrs["customer"].AsMap()
+	if len(customer) != 1 || customer[0].Value.AsString() != "gold" {
+		t.Fatalf("customer = %v", customer)
+	}
+}
@@ -2,9 +2,15 @@ package observability
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/milan604/core-lab/pkg/logger"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 type noopLogManager struct{}
@@ -25,96 +31,224 @@ func (n *noopLogManager) WarnFCtx(ctx context.Context, format string, args ...an
 }
 func (n *noopLogManager) ErrorFCtx(ctx context.Context, format string, args ...any) {
 }
+func (n *noopLogManager) DPanic(args ...any)                 {}
+func (n *noopLogManager) DPanicF(format string, args ...any) {}
+func (n *noopLogManager) DPanicFCtx(ctx context.Context, format string, args ...any) {
+}
+func (n *noopLogManager) Fatal(args ...any)                 {}
+func (n *noopLogManager) FatalF(format string, args ...any) {}
+func (n *noopLogManager) FatalFCtx(ctx context.Context, format string, args ...any) {
+}
 func (n *noopLogManager) With(keyValues ...any) logger.LogManager { return n }
 func (n *noopLogManager) Sync() error                             { return nil }
 func (n *noopLogManager) SetLogLevel(level string) error          { return nil }
 
-func newTestLogWrapper() (*LogManagerWrapper, *LogExporter) {
-	exporter := &LogExporter{
-		serviceName:    "test-service",
-		serviceVersion: "1.0.0",
-		buffer:         make([]LogEntry, 0, 8),
-		bufferSize:     100,
+// recordingLogExporter keeps exported records in memory.
+type recordingLogExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+	err     error
+	block   chan struct{}
+}
+
+func (e *recordingLogExporter) Export(_ context.Context, records []sdklog.Record) error {
+	if e.block != nil {
+		<-e.block
 	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.err != nil {
+		return e.err
+	}
+	for _, record := range records {
+		e.records = append(e.records, record.Clone())
+	}
+	return nil
+}
+
+func (e *recordingLogExporter) Shutdown(context.Context) error   { return nil }
+func (e *recordingLogExporter) ForceFlush(context.Context) error { return nil }
+
+// exported flushes le and returns the records exported so far.
+func (e *recordingLogExporter) exported(t *testing.T, le *LogExporter) []sdklog.Record {
+	t.Helper()
+	if err := le.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]sdklog.Record(nil), e.records...)
+}
+
+func recordAttributes(record sdklog.Record) map[string]otellog.Value {
+	attrs := map[string]otellog.Value{}
+	record.WalkAttributes(func(kv otellog.KeyValue) bool {
+		attrs[kv.Key] = kv.Value
+		return true
+	})
+	return attrs
+}
+
+func newTestLogWrapper() (*LogManagerWrapper, *LogExporter, *recordingLogExporter) {
+	recorder := &recordingLogExporter{}
+	exporter := newLogExporter("test-service", "1.0.0", nil, recorder, LogExportConfig{})
 
 	return &LogManagerWrapper{
 		original: &noopLogManager{},
 		exporter: exporter,
-	}, exporter
+	}, exporter, recorder
 }
 
 func TestLogManagerWrapperWithIncludesFieldsInExport(t *testing.T) {
-	wrapper, exporter := newTestLogWrapper()
+	wrapper, exporter, recorder := newTestLogWrapper()
 
 	wrapper.With("log_type", "access", "path", "/metrics", "status", 200).Info("http_request")
 
-	if got, want := len(exporter.buffer), 1; got != want {
+	records := recorder.exported(t, exporter)
+	if got, want := len(records), 1; got != want {
 		t.Fatalf("exported entries = %d, want %d", got, want)
 	}
 
-	entry := exporter.buffer[0]
-	if got, want := entry.Message, "http_request"; got != want {
+	entry := records[0]
+	if got, want := entry.Body().AsString(), "http_request"; got != want {
 		t.Fatalf("message = %q, want %q", got, want)
 	}
+	if got, want := entry.Severity(), otellog.SeverityInfo; got != want {
+		t.Fatalf("severity = %v, want %v", got, want)
+	}
 
-	if got, want := entry.Attributes["log_type"], "access"; got != want {
+	attrs := recordAttributes(entry)
+	if got, want := attrs["log_type"].AsString(), "access"; got != want {
 		t.Fatalf("log_type = %v, want %v", got, want)
 	}
-	if got, want := entry.Attributes["path"], "/metrics"; got != want {
+	if got, want := attrs["path"].AsString(), "/metrics"; got != want {
 		t.Fatalf("path = %v, want %v", got, want)
 	}
-	if got, want := entry.Attributes["status"], 200; got != want {
+	if got, want := attrs["status"].AsInt64(), int64(200); got != want {
 		t.Fatalf("status = %v, want %v", got, want)
 	}
 }
 
 func TestLogManagerWrapperWithMergesAndOverridesFields(t *testing.T) {
-	wrapper, exporter := newTestLogWrapper()
+	wrapper, exporter, recorder := newTestLogWrapper()
 
 	wrapper.
 		With("component", "http", "status", 201).
 		With("status", 202, "method", "GET").
 		Info("request_handled")
 
-	if got, want := len(exporter.buffer), 1; got != want {
+	records := recorder.exported(t, exporter)
+	if got, want := len(records), 1; got != want {
 		t.Fatalf("exported entries = %d, want %d", got, want)
 	}
 
-	entry := exporter.buffer[0]
-	if got, want := entry.Attributes["component"], "http"; got != want {
+	attrs := recordAttributes(records[0])
+	if got, want := attrs["component"].AsString(), "http"; got != want {
 		t.Fatalf("component = %v, want %v", got, want)
 	}
-	if got, want := entry.Attributes["status"], 202; got != want {
+	if got, want := attrs["status"].AsInt64(), int64(202); got != want {
 		t.Fatalf("status = %v, want %v", got, want)
 	}
-	if got, want := entry.Attributes["method"], "GET"; got != want {
+	if got, want := attrs["method"].AsString(), "GET"; got != want {
 		t.Fatalf("method = %v, want %v", got, want)
 	}
 }
 
+func TestLogManagerWrapperExportsKeyValueArgsAsAttributes(t *testing.T) {
+	wrapper, exporter, recorder := newTestLogWrapper()
+
+	wrapper.With("component", "auth", "status", 200).Info("user signed in", "user_id", 42, "status", 201)
+	wrapper.Warn("quota low", zap.String("tenant", "acme"), zap.Object("usage", zapObject{"used": 90}))
+	wrapper.Error("retrying", 3, "times")
+
+	records := recorder.exported(t, exporter)
+	if got, want := len(records), 3; got != want {
+		t.Fatalf("exported entries = %d, want %d", got, want)
+	}
+
+	if got, want := records[0].Body().AsString(), "user signed in"; got != want {
+		t.Fatalf("message = %q, want %q", got, want)
+	}
+	attrs := recordAttributes(records[0])
+	if got, want := attrs["user_id"].AsInt64(), int64(42); got != want {
+		t.Fatalf("user_id = %v, want %v", got, want)
+	}
+	if got, want := attrs["status"].AsInt64(), int64(201); got != want {
+		t.Fatalf("status = %v, want %v", got, want)
+	}
+	if got, want := attrs["component"].AsString(), "auth"; got != want {
+		t.Fatalf("component = %v, want %v", got, want)
+	}
+
+	if got, want := records[1].Body().AsString(), "quota low"; got != want {
+		t.Fatalf("message = %q, want %q", got, want)
+	}
+	attrs = recordAttributes(records[1])
+	if got, want := attrs["tenant"].AsString(), "acme"; got != want {
+		t.Fatalf("tenant = %v, want %v", got, want)
+	}
+	if usage := attrs["usage"].AsMap(); len(usage) != 1 || usage[0].Key != "used" || usage[0].Value.AsInt64() != 90 {
+		t.Fatalf("usage = %v", usage)
+	}
+
+	if got, want := records[2].Body().AsString(), fmt.Sprint("retrying", 3, "times"); got != want {
+		t.Fatalf("message = %q, want %q", got, want)
+	}
+}
+
+func TestLogManagerWrapperExportsContextFields(t *testing.T) {
+	type requestIDKey struct{}
+	logger.RegisterContextKey(requestIDKey{}, "request_id")
+	t.Cleanup(func() { logger.UnregisterContextKey(requestIDKey{}) })
+
+	wrapper, exporter, recorder := newTestLogWrapper()
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-1")
+	wrapper.InfoFCtx(ctx, "handled %s", "GET /orders")
+
+	records := recorder.exported(t, exporter)
+	if got, want := len(records), 1; got != want {
+		t.Fatalf("exported entries = %d, want %d", got, want)
+	}
+	if got, want := recordAttributes(records[0])["request_id"].AsString(), "req-1"; got != want {
+		t.Fatalf("request_id = %v, want %v", got, want)
+	}
+}
+
+// zapObject marshals a map of ints as a zap object.
+type zapObject map[string]int
+
+func (o zapObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for key, value := range o {
+		enc.AddInt(key, value)
+	}
+	return nil
+}
+
 func TestLogManagerWrapperNormalizesBlankMessages(t *testing.T) {
 	t.Run("uses log_type when message is blank", func(t *testing.T) {
-		wrapper, exporter := newTestLogWrapper()
+		wrapper, exporter, recorder := newTestLogWrapper()
 
 		wrapper.With("log_type", "access").InfoF("")
 
-		if got, want := len(exporter.buffer), 1; got != want {
+		records := recorder.exported(t, exporter)
+		if got, want := len(records), 1; got != want {
 			t.Fatalf("exported entries = %d, want %d", got, want)
 		}
-		if got, want := exporter.buffer[0].Message, "access"; got != want {
+		if got, want := records[0].Body().AsString(), "access"; got != want {
 			t.Fatalf("message = %q, want %q", got, want)
 		}
 	})
 
 	t.Run("uses fallback when log_type is missing", func(t *testing.T) {
-		wrapper, exporter := newTestLogWrapper()
+		wrapper, exporter, recorder := newTestLogWrapper()
 
 		wrapper.InfoF("")
 
-		if got, want := len(exporter.buffer), 1; got != want {
+		records := recorder.exported(t, exporter)
+		if got, want := len(records), 1; got != want {
 			t.Fatalf("exported entries = %d, want %d", got, want)
 		}
-		if got, want := exporter.buffer[0].Message, "log_entry"; got != want {
+		if got, want := records[0].Body().AsString(), "log_entry"; got != want {
 			t.Fatalf("message = %q, want %q", got, want)
 		}
 	})
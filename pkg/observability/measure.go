@@ -0,0 +1,129 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+
+	coreerrors "github.com/milan604/core-lab/pkg/errors"
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+// AttrCodeBlock names the block measured by Measure.
+var AttrCodeBlock = attribute.Key("code.block")
+
+// MeasureOption configures Measure.
+type MeasureOption func(*measureOptions)
+
+type measureOptions struct {
+	log   logger.LogManager
+	attrs []attribute.KeyValue
+}
+
+// WithMeasureLogger logs failures of the block to log.
+func WithMeasureLogger(log logger.LogManager) MeasureOption {
+	return func(o *measureOptions) { o.log = log }
+}
+
+// WithMeasureAttributes adds attributes to the span and to both instruments.
+// Keep them low-cardinality: they become metric dimensions.
+func WithMeasureAttributes(attrs ...attribute.KeyValue) MeasureOption {
+	return func(o *measureOptions) { o.attrs = append(o.attrs, attrs...) }
+}
+
+// Measure runs fn inside a span called name and returns its error. It records
+// the duration in the code.block.duration histogram and failures in the
+// code.block.errors counter, both labelled with code.block=name, and on
+// failure marks the span as errored and, with WithMeasureLogger, logs the
+// error with the block name and duration. A panic in fn is recorded as a
+// failure before it propagates.
+//
+//	err := observability.Measure(ctx, "cache.rebuild", func(ctx context.Context) error {
+//		return cache.Rebuild(ctx)
+//	}, observability.WithMeasureLogger(log))
+func Measure(ctx context.Context, name string, fn func(ctx context.Context) error, opts ...MeasureOption) (err error) {
+	o := measureOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	attrs := append([]attribute.KeyValue{AttrCodeBlock.String(name)}, o.attrs...)
+
+	ctx, span := otel.Tracer(logExporterScope).Start(ctx, name)
+	start := time.Now()
+	defer func() {
+		recovered := recover()
+		errorType := ""
+		if recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+			errorType = "panic"
+		} else if err != nil {
+			errorType = measureErrorType(err)
+		}
+		elapsed := time.Since(start)
+
+		metricAttrs := attrs
+		if err != nil {
+			metricAttrs = append(metricAttrs[:len(metricAttrs):len(metricAttrs)], AttrErrorType.String(errorType))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			if o.log != nil {
+				o.log.With("code.block", name, "duration", elapsed.String()).ErrorFCtx(ctx, "%s failed: %v", name, err)
+			}
+		}
+		recordMeasurement(ctx, elapsed, err != nil, metricAttrs)
+		span.End()
+
+		if recovered != nil {
+			panic(recovered)
+		}
+	}()
+
+	span.SetAttributes(attrs...)
+	return fn(ctx)
+}
+
+// recordMeasurement resolves the instruments on every call, so Measure follows
+// the current global meter provider; the SDK caches them by name.
+func recordMeasurement(ctx context.Context, elapsed time.Duration, failed bool, attrs []attribute.KeyValue) {
+	meter := otel.Meter(logExporterScope)
+	duration, err := meter.Float64Histogram("code.block.duration",
+		metric.WithDescription("Duration of blocks run with observability.Measure"), metric.WithUnit("s"))
+	if err != nil {
+		otel.Handle(fmt.Errorf("create code.block.duration: %w", err))
+	} else {
+		duration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(attrs...))
+	}
+	if !failed {
+		return
+	}
+	errorsCounter, err := meter.Int64Counter("code.block.errors",
+		metric.WithDescription("Failed blocks run with observability.Measure"), metric.WithUnit("{error}"))
+	if err != nil {
+		otel.Handle(fmt.Errorf("create code.block.errors: %w", err))
+		return
+	}
+	errorsCounter.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
+
+// measureErrorType is the error.type of a returned err: "timeout" or
+// "canceled" for context errors, the code of a ServiceError, and the Go type
+// otherwise. Panics are recorded as "panic".
+func measureErrorType(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	var serviceErr *coreerrors.ServiceError
+	if errors.As(err, &serviceErr) && serviceErr.Code != "" {
+		return serviceErr.Code
+	}
+	return fmt.Sprintf("%T", err)
+}
@@ -0,0 +1,118 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	coreerrors "github.com/milan604/core-lab/pkg/errors"
+)
+
+func TestMeasure(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	recorder := tracetest.NewSpanRecorder()
+	prevMeter, prevTracer := otel.GetMeterProvider(), otel.GetTracerProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() {
+		otel.SetMeterProvider(prevMeter)
+		otel.SetTracerProvider(prevTracer)
+	})
+
+	ctx := context.Background()
+	if err := Measure(ctx, "cache.rebuild", func(ctx context.Context) error {
+		if !SpanFromContext(ctx).SpanContext().IsValid() {
+			t.Error("fn did not receive the block's span")
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Measure: %v", err)
+	}
+	failure := coreerrors.ServiceUnavailable("cache backend down")
+	if err := Measure(ctx, "cache.rebuild", func(context.Context) error { return failure }); !errors.Is(err, failure) {
+		t.Fatalf("Measure returned %v, want %v", err, failure)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic was not propagated")
+			}
+		}()
+		_ = Measure(ctx, "cache.warm", func(context.Context) error { panic("boom") })
+	}()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("ended spans = %d, want 3", len(spans))
+	}
+	if spans[0].Status().Code == codes.Error || spans[1].Status().Code != codes.Error || spans[2].Status().Code != codes.Error {
+		t.Errorf("span statuses = %v, %v, %v", spans[0].Status(), spans[1].Status(), spans[2].Status())
+	}
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &data); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	found := map[string]metricdata.Aggregation{}
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			found[m.Name] = m.Data
+		}
+	}
+	duration, ok := found["code.block.duration"].(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatalf("code.block.duration missing from %v", found)
+	}
+	var total uint64
+	for _, point := range duration.DataPoints {
+		total += point.Count
+	}
+	if total != 3 {
+		t.Errorf("code.block.duration count = %d, want 3", total)
+	}
+
+	errorCounts := map[string]int64{}
+	counter, ok := found["code.block.errors"].(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("code.block.errors missing from %v", found)
+	}
+	for _, point := range counter.DataPoints {
+		block, _ := point.Attributes.Value(AttrCodeBlock)
+		errorType, _ := point.Attributes.Value(AttrErrorType)
+		errorCounts[block.AsString()+"/"+errorType.AsString()] += point.Value
+	}
+	want := map[string]int64{
+		"cache.rebuild/" + failure.Code: 1,
+		"cache.warm/panic":              1,
+	}
+	for key, n := range want {
+		if errorCounts[key] != n {
+			t.Errorf("code.block.errors = %v, want %v", errorCounts, want)
+			break
+		}
+	}
+}
+
+func TestMeasureErrorType(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{ctx.Err(), "canceled"},
+		{context.DeadlineExceeded, "timeout"},
+		{coreerrors.NotFound("missing"), coreerrors.NotFound("").Code},
+	} {
+		if got := measureErrorType(tc.err); got != tc.want {
+			t.Errorf("measureErrorType(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
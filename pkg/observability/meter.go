@@ -0,0 +1,260 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milan604/core-lab/pkg/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const (
+	defaultMetricsExportInterval = 30 * time.Second
+	defaultMetricsExportTimeout  = 10 * time.Second
+)
+
+// Attribute keys recorded on the HTTP server instruments, following the
+// current OpenTelemetry HTTP semantic conventions. AttrHTTPRoute is shared with
+// spans.
+var (
+	AttrHTTPRequestMethod      = attribute.Key("http.request.method")
+	AttrHTTPResponseStatusCode = attribute.Key("http.response.status_code")
+	AttrErrorType              = attribute.Key("error.type")
+)
+
+// httpDurationBuckets are the semantic-convention bucket boundaries for
+// http.server.request.duration, in seconds.
+var httpDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+
+// MetricsConfig configures the meter provider New installs.
+type MetricsConfig struct {
+	// Endpoint is the OTLP/HTTP collector. "/v1/metrics" is appended to base
+	// URLs. Default: the SignozEndpoint.
+	Endpoint string
+	// Interval between exports. Default: 30s.
+	Interval time.Duration
+	// Timeout bounds each export. Default: 10s.
+	Timeout time.Duration
+	// Runtime registers the Go runtime and process instruments (see
+	// RegisterRuntimeMetrics and RegisterProcessMetrics).
+	Runtime bool
+}
+
+// MetricsConfigFromConfig reads MetricsEndpoint (falling back to the SigNoz
+// endpoint), MetricsExportInterval, MetricsExportTimeout, and
+// RuntimeMetricsEnabled (default true).
+func MetricsConfigFromConfig(cfg *config.Config) MetricsConfig {
+	if cfg == nil {
+		return MetricsConfig{Endpoint: defaultSignozEndpoint, Runtime: true}
+	}
+	endpoint := strings.TrimSpace(cfg.GetString("MetricsEndpoint"))
+	if endpoint == "" {
+		endpoint = resolveSignozEndpoint(cfg)
+	}
+	return MetricsConfig{
+		Endpoint: endpoint,
+		Interval: cfg.GetDurationD("MetricsExportInterval", defaultMetricsExportInterval),
+		Timeout:  cfg.GetDurationD("MetricsExportTimeout", defaultMetricsExportTimeout),
+		Runtime:  cfg.GetBoolD("RuntimeMetricsEnabled", true),
+	}
+}
+
+// NewMeterProvider builds a meter provider that exports to cfg.Endpoint over
+// OTLP/HTTP on a periodic reader, tagging every metric with res. Extra options,
+// such as views or additional readers, are applied after the defaults.
+func NewMeterProvider(ctx context.Context, res *resource.Resource, cfg MetricsConfig, opts ...sdkmetric.Option) (*sdkmetric.MeterProvider, error) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultMetricsExportInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultMetricsExportTimeout
+	}
+
+	exporter, err := otlpmetrichttp.New(ctx,
+		otlpmetrichttp.WithEndpointURL(buildSignozMetricsURL(cfg.Endpoint)),
+		otlpmetrichttp.WithTimeout(cfg.Timeout),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP metric exporter: %w", err)
+	}
+
+	providerOpts := []sdkmetric.Option{
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter,
+			sdkmetric.WithInterval(cfg.Interval),
+			sdkmetric.WithTimeout(cfg.Timeout),
+		)),
+	}
+	if res != nil {
+		providerOpts = append(providerOpts, sdkmetric.WithResource(res))
+	}
+	return sdkmetric.NewMeterProvider(append(providerOpts, opts...)...), nil
+}
+
+// runtimeMetricSamples maps the runtime/metrics samples read on every
+// collection to the instruments RegisterRuntimeMetrics reports.
+var runtimeMetricSamples = []string{
+	"/sched/goroutines:goroutines",
+	"/sched/gomaxprocs:threads",
+	"/memory/classes/total:bytes",
+	"/memory/classes/heap/released:bytes",
+	"/gc/heap/goal:bytes",
+	"/gc/gomemlimit:bytes",
+	"/gc/heap/allocs:bytes",
+	"/gc/cycles/total:gc-cycles",
+	"/memory/classes/heap/objects:bytes",
+}
+
+// RegisterRuntimeMetrics registers Go runtime instruments on meter:
+// go.goroutine.count, go.processor.limit, go.memory.used, go.memory.heap.objects,
+// go.memory.gc.goal, go.memory.limit, go.memory.allocated, go.gc.count, and
+// go.gc.pause.duration. Values are read from runtime/metrics and
+// debug.ReadGCStats at collection time, without stopping the world.
+func RegisterRuntimeMetrics(meter metric.Meter) (metric.Registration, error) {
+	goroutines, err := meter.Int64ObservableUpDownCounter("go.goroutine.count",
+		metric.WithDescription("Count of live goroutines"), metric.WithUnit("{goroutine}"))
+	if err != nil {
+		return nil, fmt.Errorf("create go.goroutine.count: %w", err)
+	}
+	processors, err := meter.Int64ObservableUpDownCounter("go.processor.limit",
+		metric.WithDescription("GOMAXPROCS"), metric.WithUnit("{thread}"))
+	if err != nil {
+		return nil, fmt.Errorf("create go.processor.limit: %w", err)
+	}
+	memoryUsed, err := meter.Int64ObservableUpDownCounter("go.memory.used",
+		metric.WithDescription("Memory mapped by the Go runtime and not released to the OS"), metric.WithUnit("By"))
+	if err != nil {
+		return nil, fmt.Errorf("create go.memory.used: %w", err)
+	}
+	gcGoal, err := meter.Int64ObservableUpDownCounter("go.memory.gc.goal",
+		metric.WithDescription("Heap size target for the end of the GC cycle"), metric.WithUnit("By"))
+	if err != nil {
+		return nil, fmt.Errorf("create go.memory.gc.goal: %w", err)
+	}
+	memoryLimit, err := meter.Int64ObservableUpDownCounter("go.memory.limit",
+		metric.WithDescription("GOMEMLIMIT"), metric.WithUnit("By"))
+	if err != nil {
+		return nil, fmt.Errorf("create go.memory.limit: %w", err)
+	}
+	allocated, err := meter.Int64ObservableCounter("go.memory.allocated",
+		metric.WithDescription("Cumulative bytes allocated on the heap"), metric.WithUnit("By"))
+	if err != nil {
+		return nil, fmt.Errorf("create go.memory.allocated: %w", err)
+	}
+	gcCycles, err := meter.Int64ObservableCounter("go.gc.count",
+		metric.WithDescription("Completed GC cycles"), metric.WithUnit("{gc_cycle}"))
+	if err != nil {
+		return nil, fmt.Errorf("create go.gc.count: %w", err)
+	}
+	heapObjects, err := meter.Int64ObservableUpDownCounter("go.memory.heap.objects",
+		metric.WithDescription("Heap memory occupied by live and not yet swept objects"), metric.WithUnit("By"))
+	if err != nil {
+		return nil, fmt.Errorf("create go.memory.heap.objects: %w", err)
+	}
+	gcPause, err := meter.Float64ObservableCounter("go.gc.pause.duration",
+		metric.WithDescription("Cumulative stop-the-world GC pause time"), metric.WithUnit("s"))
+	if err != nil {
+		return nil, fmt.Errorf("create go.gc.pause.duration: %w", err)
+	}
+
+	return meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		samples := make([]metrics.Sample, len(runtimeMetricSamples))
+		for i, name := range runtimeMetricSamples {
+			samples[i].Name = name
+		}
+		metrics.Read(samples)
+		value := func(i int) int64 {
+			if samples[i].Value.Kind() != metrics.KindUint64 {
+				return 0
+			}
+			return int64(samples[i].Value.Uint64())
+		}
+
+		o.ObserveInt64(goroutines, value(0))
+		o.ObserveInt64(processors, value(1))
+		o.ObserveInt64(memoryUsed, value(2)-value(3))
+		o.ObserveInt64(gcGoal, value(4))
+		o.ObserveInt64(memoryLimit, value(5))
+		o.ObserveInt64(allocated, value(6))
+		o.ObserveInt64(gcCycles, value(7))
+		o.ObserveInt64(heapObjects, value(8))
+
+		var gcStats debug.GCStats
+		debug.ReadGCStats(&gcStats)
+		o.ObserveFloat64(gcPause, gcStats.PauseTotal.Seconds())
+		return nil
+	}, goroutines, processors, memoryUsed, gcGoal, memoryLimit, allocated, gcCycles, heapObjects, gcPause)
+}
+
+// HTTPMetrics holds the HTTP server instruments: http.server.request.duration,
+// http.server.active_requests, and http.server.response.body.size.
+type HTTPMetrics struct {
+	duration     metric.Float64Histogram
+	active       metric.Int64UpDownCounter
+	responseSize metric.Int64Histogram
+}
+
+// NewHTTPMetrics creates the HTTP server instruments on meter.
+func NewHTTPMetrics(meter metric.Meter) (*HTTPMetrics, error) {
+	duration, err := meter.Float64Histogram("http.server.request.duration",
+		metric.WithDescription("Duration of HTTP server requests"), metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(httpDurationBuckets...))
+	if err != nil {
+		return nil, fmt.Errorf("create http.server.request.duration: %w", err)
+	}
+	active, err := meter.Int64UpDownCounter("http.server.active_requests",
+		metric.WithDescription("In-flight HTTP server requests"), metric.WithUnit("{request}"))
+	if err != nil {
+		return nil, fmt.Errorf("create http.server.active_requests: %w", err)
+	}
+	responseSize, err := meter.Int64Histogram("http.server.response.body.size",
+		metric.WithDescription("Size of HTTP server response bodies"), metric.WithUnit("By"))
+	if err != nil {
+		return nil, fmt.Errorf("create http.server.response.body.size: %w", err)
+	}
+	return &HTTPMetrics{duration: duration, active: active, responseSize: responseSize}, nil
+}
+
+// Middleware records every request on the HTTP instruments, labelled by
+// method, route template, and status code; 5xx responses also carry
+// error.type, so errors can be split out per route. Unmatched requests carry
+// no route, keeping cardinality bounded. With the SDK's default exemplar
+// filter, duration points recorded inside a sampled span link to its trace.
+func (m *HTTPMetrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		method := metric.WithAttributes(AttrHTTPRequestMethod.String(c.Request.Method))
+		m.active.Add(ctx, 1, method)
+		start := time.Now()
+
+		defer func() {
+			m.active.Add(ctx, -1, method)
+			status := c.Writer.Status()
+			attrs := []attribute.KeyValue{
+				AttrHTTPRequestMethod.String(c.Request.Method),
+				AttrHTTPResponseStatusCode.Int(status),
+			}
+			if status >= 500 {
+				attrs = append(attrs, AttrErrorType.String(strconv.Itoa(status)))
+			}
+			if route := c.FullPath(); route != "" {
+				attrs = append(attrs, AttrHTTPRoute.String(route))
+			}
+			set := metric.WithAttributes(attrs...)
+			m.duration.Record(ctx, time.Since(start).Seconds(), set)
+			if size := c.Writer.Size(); size >= 0 {
+				m.responseSize.Record(ctx, int64(size), set)
+			}
+		}()
+		c.Next()
+	}
+}
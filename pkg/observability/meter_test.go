@@ -0,0 +1,142 @@
+package observability
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHTTPAndRuntimeMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	meter := provider.Meter("orders")
+
+	if _, err := RegisterRuntimeMetrics(meter); err != nil {
+		t.Fatalf("RegisterRuntimeMetrics: %v", err)
+	}
+	if _, err := RegisterProcessMetrics(meter); err != nil {
+		t.Fatalf("RegisterProcessMetrics: %v", err)
+	}
+	httpMetrics, err := NewHTTPMetrics(meter)
+	if err != nil {
+		t.Fatalf("NewHTTPMetrics: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(httpMetrics.Middleware())
+	router.GET("/orders/:id", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.GET("/broken", func(c *gin.Context) { c.Status(http.StatusBadGateway) })
+	for _, path := range []string{"/orders/1", "/orders/2", "/missing", "/broken"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	found := map[string]metricdata.Aggregation{}
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			found[m.Name] = m.Data
+		}
+	}
+
+	duration, ok := found["http.server.request.duration"].(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatalf("http.server.request.duration missing from %v", found)
+	}
+	counts := map[string]uint64{}
+	for _, point := range duration.DataPoints {
+		route, _ := point.Attributes.Value(AttrHTTPRoute)
+		status, _ := point.Attributes.Value(AttrHTTPResponseStatusCode)
+		errorType, _ := point.Attributes.Value(AttrErrorType)
+		counts[route.AsString()+"|"+status.Emit()+"|"+errorType.Emit()] += point.Count
+	}
+	if counts["/orders/:id|200|"] != 2 || counts["|404|"] != 1 || counts["/broken|502|502"] != 1 {
+		t.Fatalf("duration counts = %v", counts)
+	}
+
+	goroutines, ok := found["go.goroutine.count"].(metricdata.Sum[int64])
+	if !ok || len(goroutines.DataPoints) != 1 || goroutines.DataPoints[0].Value <= 0 {
+		t.Fatalf("go.goroutine.count = %+v", found["go.goroutine.count"])
+	}
+	for _, name := range []string{"go.memory.used", "go.memory.heap.objects", "go.memory.allocated", "go.gc.count", "go.gc.pause.duration", "process.uptime", "http.server.active_requests"} {
+		if _, ok := found[name]; !ok {
+			t.Fatalf("%s missing", name)
+		}
+	}
+	if runtime.GOOS == "linux" {
+		cpu, ok := found["process.cpu.time"].(metricdata.Sum[float64])
+		if !ok || len(cpu.DataPoints) != 2 {
+			t.Fatalf("process.cpu.time = %+v", found["process.cpu.time"])
+		}
+		rss, ok := found["process.memory.usage"].(metricdata.Sum[int64])
+		if !ok || rss.DataPoints[0].Value <= 0 {
+			t.Fatalf("process.memory.usage = %+v", found["process.memory.usage"])
+		}
+		if _, ok := found["process.open_file_descriptor.count"]; !ok {
+			t.Fatal("process.open_file_descriptor.count missing")
+		}
+	}
+}
+
+func TestNewMeterProviderExportsOTLP(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		paths    []string
+		requests []*collectormetrics.ExportMetricsServiceRequest
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req collectormetrics.ExportMetricsServiceRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			t.Errorf("decode export: %v", err)
+		}
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		requests = append(requests, &req)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	res := resource.NewSchemaless(attribute.String("service.name", "orders"))
+	provider, err := NewMeterProvider(context.Background(), res, MetricsConfig{Endpoint: collector.URL})
+	if err != nil {
+		t.Fatalf("NewMeterProvider: %v", err)
+	}
+	counter, _ := provider.Meter("orders").Int64Counter("orders.created")
+	counter.Add(context.Background(), 3)
+
+	// Shutdown flushes the pending export.
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) == 0 || !slices.Contains(paths, "/v1/metrics") {
+		t.Fatalf("collector saw paths %v", paths)
+	}
+	exported := requests[0].GetResourceMetrics()[0]
+	if exported.GetResource().GetAttributes()[0].GetValue().GetStringValue() != "orders" {
+		t.Fatalf("resource = %v", exported.GetResource())
+	}
+	if name := exported.GetScopeMetrics()[0].GetMetrics()[0].GetName(); name != "orders.created" {
+		t.Fatalf("metric = %q, want orders.created", name)
+	}
+}
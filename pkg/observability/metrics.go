@@ -58,16 +58,14 @@ func (m *Metrics) IncrementCounter(ctx context.Context, name string, attrs ...at
 
 // RecordGauge records a gauge metric
 func (m *Metrics) RecordGauge(ctx context.Context, name string, value float64, attrs ...attribute.KeyValue) {
-	gauge, err := m.meter.Float64ObservableGauge(
+	gauge, err := m.meter.Float64Gauge(
 		name,
 		metric.WithDescription(fmt.Sprintf("Gauge for %s", name)),
 	)
 	if err != nil {
 		return
 	}
-	// Note: ObservableGauge requires registration, this is simplified
-	// For production, use Int64Gauge or Float64Gauge instead
-	_, _ = gauge, value
+	gauge.Record(ctx, value, metric.WithAttributes(attrs...))
 }
 
 // RecordHistogram records a histogram metric
@@ -7,14 +7,79 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// GinOption customizes GinMiddleware.
+type GinOption func(*ginOptions)
+
+type ginOptions struct {
+	routeRatios map[string]float64
+	otelOptions []otelgin.Option
+}
+
+// WithRouteSampling overrides the sampling ratio per route, keyed by route
+// template (c.FullPath(), e.g. "/orders/:id") or, for unmatched requests, by
+// path: 0 never samples, 1 always samples. Requests that continue a sampled
+// upstream trace follow the override too. Overrides need the sampler from New
+// or NewSampler.
+//
+//	observability.GinMiddleware("orders", observability.WithRouteSampling(map[string]float64{
+//		"/healthz":   0,
+//		"/checkout": 1,
+//	}))
+func WithRouteSampling(ratios map[string]float64) GinOption {
+	return func(o *ginOptions) {
+		for route, ratio := range ratios {
+			o.routeRatios[route] = ratio
+		}
+	}
+}
+
+// WithOtelGinOptions passes options through to otelgin.Middleware.
+func WithOtelGinOptions(opts ...otelgin.Option) GinOption {
+	return func(o *ginOptions) {
+		o.otelOptions = append(o.otelOptions, opts...)
+	}
+}
+
 // GinMiddleware creates a Gin middleware for automatic tracing
-func GinMiddleware(serviceName string) gin.HandlerFunc {
-	return otelgin.Middleware(serviceName)
+func GinMiddleware(serviceName string, opts ...GinOption) gin.HandlerFunc {
+	options := ginOptions{routeRatios: map[string]float64{}}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	traced := otelgin.Middleware(serviceName, options.otelOptions...)
+	if len(options.routeRatios) == 0 {
+		return traced
+	}
+
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		if ratio, ok := options.routeRatios[route]; ok {
+			c.Request = c.Request.WithContext(ContextWithSampleRatio(c.Request.Context(), ratio))
+		}
+		traced(c)
+	}
+}
+
+// GinMetricsMiddleware records the HTTP server instruments (see HTTPMetrics)
+// on the global meter provider, which New installs.
+//
+//	engine.Use(observability.GinMiddleware("my-service"), observability.GinMetricsMiddleware("my-service"))
+func GinMetricsMiddleware(serviceName string) gin.HandlerFunc {
+	metrics, err := NewHTTPMetrics(otel.Meter(serviceName))
+	if err != nil {
+		otel.Handle(err)
+		return func(c *gin.Context) { c.Next() }
+	}
+	return metrics.Middleware()
 }
 
 // TraceHandler wraps a handler function with tracing
@@ -3,13 +3,16 @@ package observability
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/milan604/core-lab/pkg/config"
 	"github.com/milan604/core-lab/pkg/logger"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
@@ -31,8 +34,11 @@ type ObservabilityIface interface {
 // Observability manages OpenTelemetry tracing, metrics, and logs
 type Observability struct {
 	tracerProvider *sdktrace.TracerProvider
+	meterProvider  *sdkmetric.MeterProvider
 	tracer         trace.Tracer
 	logExporter    *LogExporter
+	dependencies   *DependencyInventory
+	watchdog       *Watchdog
 	log            logger.LogManager
 	serviceName    string
 	serviceVersion string
@@ -52,14 +58,8 @@ func New(log logger.LogManager, cfg *config.Config) (ObservabilityIface, error)
 
 	signozEndpoint := resolveSignozEndpoint(cfg)
 
-	// Create resource with service information
-	res, err := resource.New(
-		context.Background(),
-		resource.WithAttributes(
-			semconv.ServiceNameKey.String(serviceName),
-			semconv.ServiceVersionKey.String(serviceVersion),
-		),
-	)
+	// Create resource with service information, shared by traces and metrics
+	res, err := newResource(cfg, serviceName, serviceVersion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create resource: %w", err)
 	}
@@ -70,11 +70,43 @@ func New(log logger.LogManager, cfg *config.Config) (ObservabilityIface, error)
 		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
 	}
 
+	sampler, err := NewSampler(SamplerConfigFromConfig(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure trace sampler: %w", err)
+	}
+
+	// Install the meter provider before anything registers instruments.
+	var meterProvider *sdkmetric.MeterProvider
+	if cfg.GetBoolD("MetricsEnabled", true) {
+		metricsCfg := MetricsConfigFromConfig(cfg)
+		meterProvider, err = NewMeterProvider(context.Background(), res, metricsCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create meter provider: %w", err)
+		}
+		otel.SetMeterProvider(meterProvider)
+		if metricsCfg.Runtime {
+			meter := meterProvider.Meter(serviceName)
+			if _, err := RegisterRuntimeMetrics(meter); err != nil {
+				log.WarnF("Failed to register runtime metrics: %v", err)
+			}
+			if _, err := RegisterProcessMetrics(meter); err != nil {
+				log.WarnF("Failed to register process metrics: %v", err)
+			}
+		}
+	}
+
+	// Record outgoing calls so the dependency inventory reflects runtime peers.
+	dependencies := NewDependencyInventory(serviceName, log)
+	if err := dependencies.Start(cfg.GetDurationD("DependencyInventoryInterval", defaultDependencyInventoryInterval)); err != nil {
+		log.WarnF("Failed to start dependency inventory: %v", err)
+	}
+
 	// Create tracer provider
 	tp := sdktrace.NewTracerProvider(
 		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSpanProcessor(dependencies),
 		sdktrace.WithResource(res),
-		sdktrace.WithSampler(sdktrace.AlwaysSample()), // Use sdktrace.TraceIDRatioBased(0.1) for production
+		sdktrace.WithSampler(sampler),
 	)
 
 	// Set global tracer provider
@@ -92,6 +124,19 @@ func New(log logger.LogManager, cfg *config.Config) (ObservabilityIface, error)
 		trace.WithInstrumentationVersion(serviceVersion),
 	)
 
+	// Watch for goroutine leaks and deadlocks (optional)
+	var watchdog *Watchdog
+	if cfg.GetBoolD("WatchdogEnabled", false) {
+		watchdog = NewWatchdog(serviceName, log, WatchdogConfig{
+			Interval:       cfg.GetDurationD("WatchdogInterval", 0),
+			GoroutineLimit: cfg.GetIntD("WatchdogGoroutineLimit", 0),
+			BlockedFor:     cfg.GetDurationD("WatchdogBlockedFor", 0),
+		})
+		if err := watchdog.Start(); err != nil {
+			log.WarnF("Failed to start goroutine watchdog: %v", err)
+		}
+	}
+
 	// Create log exporter for sending logs to SigNoz
 	logExporter, err := NewLogExporter(cfg)
 	if err != nil {
@@ -101,15 +146,18 @@ func New(log logger.LogManager, cfg *config.Config) (ObservabilityIface, error)
 
 	obs := &Observability{
 		tracerProvider: tp,
+		meterProvider:  meterProvider,
 		tracer:         tracer,
 		logExporter:    logExporter,
+		dependencies:   dependencies,
+		watchdog:       watchdog,
 		log:            log,
 		serviceName:    serviceName,
 		serviceVersion: serviceVersion,
 	}
 
-	log.InfoF("Observability initialized: service=%s, version=%s, endpoint=%s",
-		serviceName, serviceVersion, signozEndpoint)
+	log.InfoF("Observability initialized: service=%s, version=%s, endpoint=%s, sampler=%s, metrics=%t",
+		serviceName, serviceVersion, signozEndpoint, sampler.Description(), meterProvider != nil)
 
 	return obs, nil
 }
@@ -133,11 +181,23 @@ func (o *Observability) Shutdown(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
+	if o.watchdog != nil {
+		_ = o.watchdog.Shutdown(ctx)
+	}
+
 	if err := o.tracerProvider.Shutdown(ctx); err != nil {
 		o.log.ErrorF("failed to shutdown tracer provider: %v", err)
 		return err
 	}
 
+	// Flush the last metric export
+	if o.meterProvider != nil {
+		if err := o.meterProvider.Shutdown(ctx); err != nil {
+			o.log.ErrorF("failed to shutdown meter provider: %v", err)
+			return err
+		}
+	}
+
 	// Shutdown log exporter if available
 	if o.logExporter != nil {
 		if err := o.logExporter.Shutdown(ctx); err != nil {
@@ -153,3 +213,39 @@ func (o *Observability) Shutdown(ctx context.Context) error {
 func (o *Observability) GetTracer() trace.Tracer {
 	return o.tracer
 }
+
+// MeterProvider returns the meter provider installed as the global provider,
+// or nil when MetricsEnabled is false.
+func (o *Observability) MeterProvider() *sdkmetric.MeterProvider {
+	return o.meterProvider
+}
+
+// Dependencies returns the runtime dependency inventory fed by client spans.
+func (o *Observability) Dependencies() *DependencyInventory {
+	return o.dependencies
+}
+
+// Watchdog returns the goroutine watchdog, or nil unless WatchdogEnabled is set.
+func (o *Observability) Watchdog() *Watchdog {
+	return o.watchdog
+}
+
+// newResource describes the service for every signal: service name and
+// version, deployment.environment.name from the Environment key, the SDK and
+// host, plus anything set in OTEL_RESOURCE_ATTRIBUTES.
+func newResource(cfg *config.Config, serviceName, serviceVersion string) (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(serviceName),
+		semconv.ServiceVersionKey.String(serviceVersion),
+	}
+	if environment := strings.TrimSpace(cfg.GetString("Environment")); environment != "" {
+		attrs = append(attrs, attribute.String("deployment.environment.name", environment))
+	}
+	return resource.New(
+		context.Background(),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(attrs...),
+	)
+}
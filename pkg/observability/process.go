@@ -0,0 +1,72 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// AttrCPUMode distinguishes user and system time on process.cpu.time.
+var AttrCPUMode = attribute.Key("cpu.mode")
+
+// processStats is a snapshot of the current process. The has* flags are
+// false where the platform does not expose a value.
+type processStats struct {
+	userCPU, systemCPU float64
+	residentBytes      int64
+	openFiles          int64
+	hasCPU, hasMemory  bool
+	hasOpenFiles       bool
+}
+
+// processStart approximates the process start time by package initialization.
+var processStart = time.Now()
+
+// RegisterProcessMetrics registers process instruments on meter:
+// process.cpu.time (by cpu.mode), process.memory.usage (resident set size),
+// process.open_file_descriptor.count, and process.uptime. CPU, memory, and
+// file descriptors are read from /proc on Linux; other platforms report
+// uptime only.
+func RegisterProcessMetrics(meter metric.Meter) (metric.Registration, error) {
+	cpuTime, err := meter.Float64ObservableCounter("process.cpu.time",
+		metric.WithDescription("CPU time consumed by the process"), metric.WithUnit("s"))
+	if err != nil {
+		return nil, fmt.Errorf("create process.cpu.time: %w", err)
+	}
+	memoryUsage, err := meter.Int64ObservableUpDownCounter("process.memory.usage",
+		metric.WithDescription("Resident memory of the process"), metric.WithUnit("By"))
+	if err != nil {
+		return nil, fmt.Errorf("create process.memory.usage: %w", err)
+	}
+	openFiles, err := meter.Int64ObservableUpDownCounter("process.open_file_descriptor.count",
+		metric.WithDescription("Open file descriptors"), metric.WithUnit("{file_descriptor}"))
+	if err != nil {
+		return nil, fmt.Errorf("create process.open_file_descriptor.count: %w", err)
+	}
+	uptime, err := meter.Float64ObservableGauge("process.uptime",
+		metric.WithDescription("Time since the process started"), metric.WithUnit("s"))
+	if err != nil {
+		return nil, fmt.Errorf("create process.uptime: %w", err)
+	}
+
+	user := metric.WithAttributes(AttrCPUMode.String("user"))
+	system := metric.WithAttributes(AttrCPUMode.String("system"))
+	return meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		stats := readProcessStats()
+		if stats.hasCPU {
+			o.ObserveFloat64(cpuTime, stats.userCPU, user)
+			o.ObserveFloat64(cpuTime, stats.systemCPU, system)
+		}
+		if stats.hasMemory {
+			o.ObserveInt64(memoryUsage, stats.residentBytes)
+		}
+		if stats.hasOpenFiles {
+			o.ObserveInt64(openFiles, stats.openFiles)
+		}
+		o.ObserveFloat64(uptime, time.Since(processStart).Seconds())
+		return nil
+	}, cpuTime, memoryUsage, openFiles, uptime)
+}
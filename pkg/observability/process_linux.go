@@ -0,0 +1,45 @@
+//go:build linux
+
+package observability
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// userHZ is the clock tick rate of the utime/stime fields in /proc/<pid>/stat,
+// fixed at 100 on every architecture Go supports.
+const userHZ = 100
+
+func readProcessStats() processStats {
+	var stats processStats
+
+	if data, err := os.ReadFile("/proc/self/stat"); err == nil {
+		// The command name in field 2 may contain spaces; fields after it
+		// start behind the closing parenthesis.
+		if end := strings.LastIndexByte(string(data), ')'); end >= 0 {
+			fields := strings.Fields(string(data[end+1:]))
+			// fields[0] is field 3 (state): utime is field 14, stime 15, rss 24.
+			if len(fields) > 21 {
+				utime, userErr := strconv.ParseUint(fields[11], 10, 64)
+				stime, systemErr := strconv.ParseUint(fields[12], 10, 64)
+				if userErr == nil && systemErr == nil {
+					stats.userCPU = float64(utime) / userHZ
+					stats.systemCPU = float64(stime) / userHZ
+					stats.hasCPU = true
+				}
+				if pages, err := strconv.ParseInt(fields[21], 10, 64); err == nil {
+					stats.residentBytes = pages * int64(os.Getpagesize())
+					stats.hasMemory = true
+				}
+			}
+		}
+	}
+
+	if entries, err := os.ReadDir("/proc/self/fd"); err == nil {
+		stats.openFiles = int64(len(entries))
+		stats.hasOpenFiles = true
+	}
+	return stats
+}
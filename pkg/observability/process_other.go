@@ -0,0 +1,7 @@
+//go:build !linux
+
+package observability
+
+func readProcessStats() processStats {
+	return processStats{}
+}
@@ -0,0 +1,139 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/milan604/core-lab/pkg/config"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+)
+
+// Sampler strategies accepted by SamplerConfig.Strategy and the TraceSampler
+// config key.
+const (
+	SamplerAlways      = "always"
+	SamplerNever       = "never"
+	SamplerRatio       = "ratio"
+	SamplerRateLimited = "rate_limited"
+)
+
+// SamplerConfig selects the trace sampler used by New.
+type SamplerConfig struct {
+	// Strategy is one of SamplerAlways, SamplerNever, SamplerRatio, or
+	// SamplerRateLimited. Default: SamplerAlways.
+	Strategy string
+	// Ratio is the fraction of traces sampled by SamplerRatio, from 0 to 1.
+	Ratio float64
+	// RatePerSecond caps root traces for SamplerRateLimited. Default: 10.
+	RatePerSecond float64
+	// ParentBased follows the caller's sampling decision when the request
+	// carries a trace context, and applies Strategy to new traces only.
+	ParentBased bool
+}
+
+// SamplerConfigFromConfig reads TraceSampler, TraceSampleRatio,
+// TraceSampleRatePerSecond, and TraceSamplerParentBased (default true).
+func SamplerConfigFromConfig(cfg *config.Config) SamplerConfig {
+	if cfg == nil {
+		return SamplerConfig{Strategy: SamplerAlways, ParentBased: true}
+	}
+	return SamplerConfig{
+		Strategy:      cfg.GetStringD("TraceSampler", SamplerAlways),
+		Ratio:         cfg.GetFloat64("TraceSampleRatio"),
+		RatePerSecond: cfg.GetFloat64("TraceSampleRatePerSecond"),
+		ParentBased:   cfg.GetBoolD("TraceSamplerParentBased", true),
+	}
+}
+
+// NewSampler builds the sampler described by cfg. The result honours
+// per-request overrides set with ContextWithSampleRatio, e.g. by the route
+// overrides of GinMiddleware.
+func NewSampler(cfg SamplerConfig) (sdktrace.Sampler, error) {
+	var root sdktrace.Sampler
+	switch strings.ToLower(strings.TrimSpace(cfg.Strategy)) {
+	case "", SamplerAlways:
+		root = sdktrace.AlwaysSample()
+	case SamplerNever:
+		root = sdktrace.NeverSample()
+	case SamplerRatio:
+		if cfg.Ratio < 0 || cfg.Ratio > 1 {
+			return nil, fmt.Errorf("trace sample ratio %v outside [0, 1]", cfg.Ratio)
+		}
+		root = sdktrace.TraceIDRatioBased(cfg.Ratio)
+	case SamplerRateLimited:
+		perSecond := cfg.RatePerSecond
+		if perSecond <= 0 {
+			perSecond = 10
+		}
+		root = &rateLimitedSampler{
+			limiter:     rate.NewLimiter(rate.Limit(perSecond), max(1, int(perSecond))),
+			description: fmt.Sprintf("RateLimited{%g/s}", perSecond),
+		}
+	default:
+		return nil, fmt.Errorf("unknown trace sampler %q", cfg.Strategy)
+	}
+	if cfg.ParentBased {
+		root = sdktrace.ParentBased(root)
+	}
+	return overridableSampler{next: root}, nil
+}
+
+// rateLimitedSampler samples at most a fixed number of traces per second.
+type rateLimitedSampler struct {
+	limiter     *rate.Limiter
+	description string
+}
+
+func (s *rateLimitedSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	decision := sdktrace.Drop
+	if s.limiter.Allow() {
+		decision = sdktrace.RecordAndSample
+	}
+	return sdktrace.SamplingResult{
+		Decision:   decision,
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+func (s *rateLimitedSampler) Description() string {
+	return s.description
+}
+
+type sampleRatioKey struct{}
+
+// ContextWithSampleRatio overrides the sampling ratio for spans started from
+// ctx that have no local parent, typically the server span of a request: 0
+// never samples, 1 always samples. It takes effect only with a sampler from
+// NewSampler (which New installs).
+func ContextWithSampleRatio(ctx context.Context, ratio float64) context.Context {
+	return context.WithValue(ctx, sampleRatioKey{}, ratio)
+}
+
+// overridableSampler applies a ContextWithSampleRatio override to new local
+// roots and delegates everything else.
+type overridableSampler struct {
+	next sdktrace.Sampler
+}
+
+func (s overridableSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	ratio, ok := p.ParentContext.Value(sampleRatioKey{}).(float64)
+	parent := trace.SpanContextFromContext(p.ParentContext)
+	if !ok || (parent.IsValid() && !parent.IsRemote()) {
+		return s.next.ShouldSample(p)
+	}
+	switch {
+	case ratio <= 0:
+		return sdktrace.NeverSample().ShouldSample(p)
+	case ratio >= 1:
+		return sdktrace.AlwaysSample().ShouldSample(p)
+	default:
+		return sdktrace.TraceIDRatioBased(ratio).ShouldSample(p)
+	}
+}
+
+func (s overridableSampler) Description() string {
+	return s.next.Description()
+}
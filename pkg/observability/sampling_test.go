@@ -0,0 +1,97 @@
+package observability
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestNewSamplerStrategies(t *testing.T) {
+	ctx := context.Background()
+	count := func(cfg SamplerConfig, spans int) int {
+		t.Helper()
+		sampler, err := NewSampler(cfg)
+		if err != nil {
+			t.Fatalf("NewSampler(%+v): %v", cfg, err)
+		}
+		tracer := sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler)).Tracer("test")
+		sampled := 0
+		for range spans {
+			_, span := tracer.Start(ctx, "op")
+			if span.SpanContext().IsSampled() {
+				sampled++
+			}
+			span.End()
+		}
+		return sampled
+	}
+
+	if got := count(SamplerConfig{}, 10); got != 10 {
+		t.Fatalf("always sampled %d/10", got)
+	}
+	if got := count(SamplerConfig{Strategy: SamplerNever, ParentBased: true}, 10); got != 0 {
+		t.Fatalf("never sampled %d/10", got)
+	}
+	if got := count(SamplerConfig{Strategy: SamplerRatio, Ratio: 0.5}, 2000); got < 800 || got > 1200 {
+		t.Fatalf("ratio 0.5 sampled %d/2000", got)
+	}
+	if got := count(SamplerConfig{Strategy: SamplerRateLimited, RatePerSecond: 5}, 50); got < 5 || got > 6 {
+		t.Fatalf("rate limited 5/s sampled %d/50", got)
+	}
+
+	if _, err := NewSampler(SamplerConfig{Strategy: SamplerRatio, Ratio: 2}); err == nil {
+		t.Fatal("NewSampler accepted ratio 2")
+	}
+	if _, err := NewSampler(SamplerConfig{Strategy: "sometimes"}); err == nil {
+		t.Fatal("NewSampler accepted unknown strategy")
+	}
+}
+
+func TestGinMiddlewareRouteSampling(t *testing.T) {
+	sampler, err := NewSampler(SamplerConfig{Strategy: SamplerNever, ParentBased: true})
+	if err != nil {
+		t.Fatalf("NewSampler: %v", err)
+	}
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler), sdktrace.WithSpanProcessor(recorder))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(GinMiddleware("orders",
+		WithRouteSampling(map[string]float64{"/checkout/:id": 1, "/healthz": 0}),
+		WithOtelGinOptions(otelgin.WithTracerProvider(provider), otelgin.WithPropagators(propagation.TraceContext{})),
+	))
+	router.GET("/checkout/:id", func(c *gin.Context) {
+		// Child spans follow the request span.
+		_, span := provider.Tracer("test").Start(c.Request.Context(), "charge")
+		span.End()
+		c.Status(http.StatusOK)
+	})
+	router.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/checkout/42", "/healthz", "/orders"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// An upstream trace that was sampled does not force /healthz to be traced.
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want the checkout request and its child", len(spans))
+	}
+	for _, span := range spans {
+		if span.Name() != "charge" && span.Name() != "GET /checkout/:id" {
+			t.Fatalf("unexpected span %q", span.Name())
+		}
+	}
+}
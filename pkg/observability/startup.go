@@ -0,0 +1,259 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+// StartupSpanName is the root span of a StartupTrace.
+const StartupSpanName = "service.startup"
+
+// AttrStartupPhase names the phase of a startup phase span.
+var AttrStartupPhase = attribute.Key("startup.phase")
+
+// StartupPhase is a completed or running phase of a StartupTrace.
+type StartupPhase struct {
+	Name string
+	// Depth is 0 for phases directly under service.startup, 1 for phases
+	// nested in those, and so on.
+	Depth    int
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+	// Running is set for phases that have not ended yet.
+	Running bool
+}
+
+type startupPhase struct {
+	StartupPhase
+	parent int // index of the enclosing phase, or -1
+	span   trace.Span
+	ctx    context.Context
+}
+
+// StartupTrace times the phases of a service's startup (config load, DB
+// connect, migrations, permission bootstrap, server bind) as child spans of a
+// root service.startup span, so slow startups can be broken down in SigNoz.
+//
+// Phases run before the tracer exists, such as loading the config that
+// configures it, are timed in memory and exported with their original
+// timestamps once Attach is called:
+//
+//	startup := observability.NewStartupTrace(log)
+//	var cfg *config.Config
+//	_ = startup.Phase("config.load", func(context.Context) error {
+//		cfg = config.New(config.WithFile("config.yaml"))
+//		return nil
+//	})
+//	obs := observability.MustNew(log, cfg)
+//	startup.Attach(obs.GetTracer())
+//	err := startup.Phase("db.connect", func(ctx context.Context) error { ... })
+//	...
+//	startup.End(err)
+//
+// A phase started while another is running is nested under it, so phases
+// are meant to run one after the other on the startup goroutine.
+type StartupTrace struct {
+	log   logger.LogManager
+	start time.Time
+
+	mu     sync.Mutex
+	phases []*startupPhase
+	open   []int // indexes of running phases, innermost last
+	tracer trace.Tracer
+	root   trace.Span
+	ctx    context.Context
+	ended  time.Time
+}
+
+// NewStartupTrace starts timing the startup. log, if not nil, receives a
+// summary of the phases from End.
+func NewStartupTrace(log logger.LogManager) *StartupTrace {
+	return &StartupTrace{log: log, start: time.Now(), ctx: context.Background()}
+}
+
+// SetLogger replaces the logger End logs the summary to, e.g. once the
+// SigNoz logger is ready.
+func (s *StartupTrace) SetLogger(log logger.LogManager) {
+	s.mu.Lock()
+	s.log = log
+	s.mu.Unlock()
+}
+
+// Phase runs fn as the phase name and returns its error. The ctx passed to
+// fn carries the phase span once the trace is attached, so spans fn starts
+// from it, and phases it starts, are nested under the phase. A panic in fn
+// is recorded as a failure before it propagates.
+func (s *StartupTrace) Phase(name string, fn func(ctx context.Context) error) (err error) {
+	ctx, end := s.Begin(name)
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			end(fmt.Errorf("panic: %v", recovered))
+			panic(recovered)
+		}
+		end(err)
+	}()
+	return fn(ctx)
+}
+
+// Begin starts the phase name and returns its context and a func ending it
+// with the phase's error, for phases that cannot be wrapped in a func, such
+// as binding the server's listener inside server.Start. Calls to end after
+// the first are ignored.
+func (s *StartupTrace) Begin(name string) (context.Context, func(err error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	phase := &startupPhase{StartupPhase: StartupPhase{Name: name, Start: time.Now(), Running: true}, parent: -1}
+	if n := len(s.open); n > 0 {
+		phase.parent = s.open[n-1]
+		phase.Depth = s.phases[phase.parent].Depth + 1
+	}
+	index := len(s.phases)
+	s.phases = append(s.phases, phase)
+	s.open = append(s.open, index)
+	if s.root != nil {
+		s.startSpan(phase)
+	}
+	ctx := phase.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return ctx, func(err error) { s.endPhase(index, err) }
+}
+
+func (s *StartupTrace) endPhase(index int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	phase := s.phases[index]
+	if !phase.Running {
+		return
+	}
+	phase.Running = false
+	phase.Duration = time.Since(phase.Start)
+	phase.Err = err
+	for i := len(s.open) - 1; i >= 0; i-- {
+		if s.open[i] == index {
+			s.open = append(s.open[:i], s.open[i+1:]...)
+			break
+		}
+	}
+	if phase.span != nil {
+		endStartupSpan(phase.span, err, phase.Start.Add(phase.Duration))
+	}
+}
+
+// Attach starts the service.startup span on tracer, backdated to
+// NewStartupTrace, and exports the phases run so far as its children with
+// their recorded timings. Later phases are traced as they run. Calls after
+// the first are ignored.
+func (s *StartupTrace) Attach(tracer trace.Tracer) {
+	if tracer == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.root != nil || !s.ended.IsZero() {
+		return
+	}
+	s.tracer = tracer
+	s.ctx, s.root = tracer.Start(context.Background(), StartupSpanName, trace.WithTimestamp(s.start))
+	for _, phase := range s.phases {
+		s.startSpan(phase)
+		if !phase.Running {
+			endStartupSpan(phase.span, phase.Err, phase.Start.Add(phase.Duration))
+		}
+	}
+}
+
+// startSpan starts the span of phase under its parent's span, which is
+// always started first. Callers hold s.mu.
+func (s *StartupTrace) startSpan(phase *startupPhase) {
+	parent := s.ctx
+	if phase.parent >= 0 {
+		parent = s.phases[phase.parent].ctx
+	}
+	phase.ctx, phase.span = s.tracer.Start(parent, phase.Name,
+		trace.WithTimestamp(phase.Start),
+		trace.WithAttributes(AttrStartupPhase.String(phase.Name)),
+	)
+}
+
+func endStartupSpan(span trace.Span, err error, at time.Time) {
+	if err != nil {
+		span.RecordError(err, trace.WithTimestamp(at))
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(at))
+}
+
+// End ends the startup with err, the error that aborted it if any: it ends
+// any phase still running, ends the service.startup span, logs a summary of
+// the phases, and returns the total startup time. Calls after the first
+// return the same total.
+func (s *StartupTrace) End(err error) time.Duration {
+	for {
+		s.mu.Lock()
+		n := len(s.open)
+		var index int
+		if n > 0 {
+			index = s.open[n-1]
+		}
+		s.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		s.endPhase(index, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended.IsZero() {
+		return s.ended.Sub(s.start)
+	}
+	s.ended = time.Now()
+	elapsed := s.ended.Sub(s.start)
+	if s.root != nil {
+		endStartupSpan(s.root, err, s.ended)
+	}
+	if s.log != nil {
+		if err != nil {
+			s.log.ErrorF("startup failed after %s (%s): %v", elapsed, s.summaryLocked(), err)
+		} else {
+			s.log.InfoF("startup completed in %s (%s)", elapsed, s.summaryLocked())
+		}
+	}
+	return elapsed
+}
+
+func (s *StartupTrace) summaryLocked() string {
+	parts := make([]string, 0, len(s.phases))
+	for _, phase := range s.phases {
+		if phase.Depth == 0 {
+			parts = append(parts, fmt.Sprintf("%s=%s", phase.Name, phase.Duration.Round(time.Millisecond)))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Phases returns the phases started so far, in start order.
+func (s *StartupTrace) Phases() []StartupPhase {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	phases := make([]StartupPhase, len(s.phases))
+	for i, phase := range s.phases {
+		phases[i] = phase.StartupPhase
+		if phase.Running {
+			phases[i].Duration = time.Since(phase.Start)
+		}
+	}
+	return phases
+}
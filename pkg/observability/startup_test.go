@@ -0,0 +1,80 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestStartupTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	startup := NewStartupTrace(nil)
+	_ = startup.Phase("config.load", func(ctx context.Context) error {
+		if trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			t.Error("phase before Attach received a span")
+		}
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	})
+	if len(recorder.Ended()) != 0 {
+		t.Fatal("spans exported before Attach")
+	}
+	startup.Attach(tracer)
+
+	bindErr := errors.New("address already in use")
+	_ = startup.Phase("service.setup", func(ctx context.Context) error {
+		if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			t.Error("phase after Attach did not receive its span")
+		}
+		return startup.Phase("db.connect", func(context.Context) error { return nil })
+	})
+	_, endBind := startup.Begin("server.bind")
+	endBind(bindErr)
+	endBind(nil)
+	total := startup.End(bindErr)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	if len(spans) != 5 {
+		t.Fatalf("ended spans = %v, want service.startup and 4 phases", spans)
+	}
+	root := spans[StartupSpanName]
+	if root.Status().Code != codes.Error {
+		t.Errorf("root status = %v, want error", root.Status())
+	}
+	if got := root.EndTime().Sub(root.StartTime()); got != total {
+		t.Errorf("root duration = %s, End returned %s", got, total)
+	}
+	for name, parent := range map[string]string{
+		"config.load":   StartupSpanName,
+		"service.setup": StartupSpanName,
+		"db.connect":    "service.setup",
+		"server.bind":   StartupSpanName,
+	} {
+		if got, want := spans[name].Parent().SpanID(), spans[parent].SpanContext().SpanID(); got != want {
+			t.Errorf("%s parent = %s, want %s", name, got, parent)
+		}
+	}
+	load := spans["config.load"]
+	if load.StartTime().Before(root.StartTime()) || load.EndTime().Sub(load.StartTime()) < 5*time.Millisecond {
+		t.Errorf("config.load replayed at %s-%s, root started %s", load.StartTime(), load.EndTime(), root.StartTime())
+	}
+	if spans["server.bind"].Status().Code != codes.Error || spans["service.setup"].Status().Code == codes.Error {
+		t.Errorf("phase statuses: server.bind=%v service.setup=%v", spans["server.bind"].Status(), spans["service.setup"].Status())
+	}
+
+	phases := startup.Phases()
+	if len(phases) != 4 || phases[2].Name != "db.connect" || phases[2].Depth != 1 || phases[3].Err != bindErr {
+		t.Errorf("Phases() = %+v", phases)
+	}
+}
@@ -0,0 +1,435 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// AttrWaitReason labels the runtime.goroutines.blocked metric.
+var AttrWaitReason = attribute.Key("goroutine.wait_reason")
+
+// Goroutine wait states the watchdog inspects. Mutex states suggest a
+// deadlock; channel and select states suggest a leak when many goroutines
+// share a stack.
+var (
+	mutexWaitStates   = []string{"sync.Mutex.Lock", "sync.RWMutex.Lock", "sync.RWMutex.RLock", "semacquire"}
+	channelWaitStates = []string{"select", "select (no cases)", "chan send", "chan receive", "chan send (nil chan)", "chan receive (nil chan)"}
+)
+
+// DefaultWatchdogIgnore are stack substrings of long-lived goroutines that are
+// idle by design and never reported.
+var DefaultWatchdogIgnore = []string{
+	"net/http.(*persistConn).readLoop",
+	"net/http.(*persistConn).writeLoop",
+	"database/sql.(*DB).connectionOpener",
+}
+
+// WatchdogConfig configures a Watchdog. Zero values take the defaults.
+type WatchdogConfig struct {
+	// Interval between samples. Default: 30s.
+	Interval time.Duration
+	// GoroutineLimit flags the process when more goroutines are running.
+	// Default: 10000.
+	GoroutineLimit int
+	// LeakGrowth flags a leak when the count exceeds the lowest count of the
+	// last LeakWindow samples by this much. Default: 500 over 10 samples.
+	LeakGrowth int
+	LeakWindow int
+	// BlockedFor is how long a goroutine must wait before it counts as
+	// blocked. The runtime reports waits in whole minutes. Default: 5m.
+	BlockedFor time.Duration
+	// BlockedGroupLimit flags goroutines blocked in select or on a channel
+	// when at least this many share a stack. Goroutines blocked on a mutex
+	// are always flagged. Default: 50.
+	BlockedGroupLimit int
+	// Ignore lists stack substrings that exclude a goroutine from blocking
+	// checks. Default: DefaultWatchdogIgnore.
+	Ignore []string
+	// DumpInterval limits how often the warning dump is logged while a problem
+	// persists. Default: 10m.
+	DumpInterval time.Duration
+	// MaxDumpBytes truncates the stacks in the warning dump. Default: 64 KiB.
+	MaxDumpBytes int
+}
+
+func (c WatchdogConfig) withDefaults() WatchdogConfig {
+	if c.Interval <= 0 {
+		c.Interval = 30 * time.Second
+	}
+	if c.GoroutineLimit <= 0 {
+		c.GoroutineLimit = 10000
+	}
+	if c.LeakGrowth <= 0 {
+		c.LeakGrowth = 500
+	}
+	if c.LeakWindow <= 0 {
+		c.LeakWindow = 10
+	}
+	if c.BlockedFor <= 0 {
+		c.BlockedFor = 5 * time.Minute
+	}
+	if c.BlockedGroupLimit <= 0 {
+		c.BlockedGroupLimit = 50
+	}
+	if c.Ignore == nil {
+		c.Ignore = DefaultWatchdogIgnore
+	}
+	if c.DumpInterval <= 0 {
+		c.DumpInterval = 10 * time.Minute
+	}
+	if c.MaxDumpBytes <= 0 {
+		c.MaxDumpBytes = 64 << 10
+	}
+	return c
+}
+
+// BlockedGroup is a set of goroutines blocked in the same state with the same
+// stack.
+type BlockedGroup struct {
+	WaitReason string `json:"wait_reason"`
+	Count      int    `json:"count"`
+	// WaitMinutes is the longest wait in the group.
+	WaitMinutes int `json:"wait_minutes"`
+	// GoroutineIDs are the first few goroutines in the group.
+	GoroutineIDs []int  `json:"goroutine_ids"`
+	Stack        string `json:"stack,omitempty"`
+	// Suspect is set when the group crosses a threshold: any mutex wait, or a
+	// channel or select wait shared by BlockedGroupLimit goroutines.
+	Suspect bool `json:"suspect"`
+}
+
+// WatchdogReport is one sample.
+type WatchdogReport struct {
+	SampledAt  time.Time `json:"sampled_at"`
+	Goroutines int       `json:"goroutines"`
+	// Baseline is the lowest count in the leak window.
+	Baseline int            `json:"baseline"`
+	Blocked  []BlockedGroup `json:"blocked,omitempty"`
+	// Problems describes each exceeded threshold; empty means healthy.
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Healthy reports whether no threshold was exceeded.
+func (r WatchdogReport) Healthy() bool {
+	return len(r.Problems) == 0
+}
+
+// Watchdog samples the goroutine count and long-blocked goroutine stacks to
+// catch leaks and deadlocks, for example from middleware or clients that never
+// release a goroutine. Start exports runtime.goroutines and
+// runtime.goroutines.blocked and logs a warning with the suspect stacks when a
+// threshold is exceeded; Handler serves the latest report on an admin port.
+type Watchdog struct {
+	serviceName string
+	log         logger.LogManager
+	cfg         WatchdogConfig
+
+	mu       sync.Mutex
+	last     WatchdogReport
+	history  []int
+	lastDump time.Time
+
+	stopOnce     sync.Once
+	stop         chan struct{}
+	done         chan struct{}
+	registration metric.Registration
+}
+
+// NewWatchdog creates a watchdog for serviceName. Call Start to sample in the
+// background, or Sample to take one sample.
+func NewWatchdog(serviceName string, log logger.LogManager, cfg WatchdogConfig) *Watchdog {
+	return &Watchdog{
+		serviceName: serviceName,
+		log:         log,
+		cfg:         cfg.withDefaults(),
+		stop:        make(chan struct{}),
+	}
+}
+
+// Start registers the watchdog metrics and samples every Interval until
+// Shutdown.
+func (w *Watchdog) Start() error {
+	meter := otel.Meter(w.serviceName)
+	goroutines, err := meter.Int64ObservableGauge("runtime.goroutines",
+		metric.WithDescription("Goroutines running in the process"))
+	if err != nil {
+		return fmt.Errorf("create goroutines metric: %w", err)
+	}
+	blocked, err := meter.Int64ObservableGauge("runtime.goroutines.blocked",
+		metric.WithDescription("Goroutines blocked longer than the watchdog threshold"))
+	if err != nil {
+		return fmt.Errorf("create blocked goroutines metric: %w", err)
+	}
+	w.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		report := w.Report()
+		if report.SampledAt.IsZero() {
+			return nil
+		}
+		o.ObserveInt64(goroutines, int64(report.Goroutines))
+		byReason := map[string]int{}
+		for _, group := range report.Blocked {
+			byReason[group.WaitReason] += group.Count
+		}
+		for reason, count := range byReason {
+			o.ObserveInt64(blocked, int64(count), metric.WithAttributes(AttrWaitReason.String(reason)))
+		}
+		return nil
+	}, goroutines, blocked)
+	if err != nil {
+		return fmt.Errorf("register watchdog metrics: %w", err)
+	}
+
+	w.Sample()
+	w.done = make(chan struct{})
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.Sample()
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Shutdown stops sampling.
+func (w *Watchdog) Shutdown(context.Context) error {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+		if w.done != nil {
+			<-w.done
+		}
+		if w.registration != nil {
+			_ = w.registration.Unregister()
+		}
+	})
+	return nil
+}
+
+// Report returns the latest sample.
+func (w *Watchdog) Report() WatchdogReport {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.last
+}
+
+// Sample inspects the running goroutines, stores the report, and logs a
+// warning dump when a threshold is exceeded.
+func (w *Watchdog) Sample() WatchdogReport {
+	report := WatchdogReport{SampledAt: time.Now(), Goroutines: runtime.NumGoroutine()}
+	report.Blocked = w.blockedGroups(goroutineStacks())
+
+	w.mu.Lock()
+	w.history = append(w.history, report.Goroutines)
+	if len(w.history) > w.cfg.LeakWindow {
+		w.history = w.history[len(w.history)-w.cfg.LeakWindow:]
+	}
+	report.Baseline = report.Goroutines
+	for _, count := range w.history {
+		report.Baseline = min(report.Baseline, count)
+	}
+
+	if report.Goroutines > w.cfg.GoroutineLimit {
+		report.Problems = append(report.Problems, fmt.Sprintf("%d goroutines exceed the limit of %d", report.Goroutines, w.cfg.GoroutineLimit))
+	}
+	if growth := report.Goroutines - report.Baseline; growth >= w.cfg.LeakGrowth {
+		report.Problems = append(report.Problems, fmt.Sprintf("goroutines grew by %d (from %d) within %d samples", growth, report.Baseline, len(w.history)))
+	}
+	for _, group := range report.Blocked {
+		if group.Suspect {
+			report.Problems = append(report.Problems, fmt.Sprintf("%d goroutines blocked in %s for up to %d minutes", group.Count, group.WaitReason, group.WaitMinutes))
+		}
+	}
+
+	w.last = report
+	dump := !report.Healthy() && report.SampledAt.Sub(w.lastDump) >= w.cfg.DumpInterval
+	if dump {
+		w.lastDump = report.SampledAt
+	}
+	w.mu.Unlock()
+
+	if dump {
+		w.logDump(report)
+	}
+	return report
+}
+
+// Handler serves the latest report as JSON. Add ?stacks=1 for the stacks of
+// blocked goroutines and ?sample=1 to take a fresh sample first. Mount it on
+// an admin port only.
+func (w *Watchdog) Handler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		report := w.Report()
+		if r.URL.Query().Get("sample") == "1" || report.SampledAt.IsZero() {
+			report = w.Sample()
+		}
+		if r.URL.Query().Get("stacks") != "1" {
+			report.Blocked = withoutStacks(report.Blocked)
+		}
+		status := http.StatusOK
+		if !report.Healthy() {
+			status = http.StatusServiceUnavailable
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(status)
+		_ = json.NewEncoder(rw).Encode(report)
+	})
+}
+
+func (w *Watchdog) logDump(report WatchdogReport) {
+	if w.log == nil {
+		return
+	}
+	var stacks strings.Builder
+	for _, group := range report.Blocked {
+		if !group.Suspect {
+			continue
+		}
+		fmt.Fprintf(&stacks, "\n--- %d goroutine(s) in %s, up to %d minutes, e.g. %v\n%s", group.Count, group.WaitReason, group.WaitMinutes, group.GoroutineIDs, group.Stack)
+		if stacks.Len() >= w.cfg.MaxDumpBytes {
+			break
+		}
+	}
+	dump := stacks.String()
+	if len(dump) > w.cfg.MaxDumpBytes {
+		dump = dump[:w.cfg.MaxDumpBytes] + "\n... truncated"
+	}
+	w.log.WarnF("Goroutine watchdog: service=%s goroutines=%d baseline=%d problems=[%s]%s",
+		w.serviceName, report.Goroutines, report.Baseline, strings.Join(report.Problems, "; "), dump)
+}
+
+// goroutineStack is one goroutine parsed from a runtime.Stack dump.
+type goroutineStack struct {
+	id     int
+	state  string
+	wait   time.Duration
+	frames string // function frames only, used to group identical stacks
+	stack  string
+}
+
+var goroutineHeader = regexp.MustCompile(`^goroutine (\d+) \[([^\]]+)\]:$`)
+
+func goroutineStacks() []goroutineStack {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return parseGoroutineStacks(buf[:n])
+		}
+		if len(buf) >= 64<<20 {
+			return parseGoroutineStacks(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// parseGoroutineStacks parses the output of runtime.Stack(buf, true).
+func parseGoroutineStacks(dump []byte) []goroutineStack {
+	var stacks []goroutineStack
+	for _, block := range bytes.Split(dump, []byte("\n\n")) {
+		lines := strings.Split(strings.TrimSpace(string(block)), "\n")
+		match := goroutineHeader.FindStringSubmatch(lines[0])
+		if match == nil {
+			continue
+		}
+		g := goroutineStack{stack: strings.Join(lines[1:], "\n")}
+		g.id, _ = strconv.Atoi(match[1])
+		// The header reads "state[, N minutes][, locked to thread]".
+		parts := strings.Split(match[2], ", ")
+		g.state = parts[0]
+		for _, part := range parts[1:] {
+			if minutes, ok := strings.CutSuffix(part, " minutes"); ok {
+				if n, err := strconv.Atoi(minutes); err == nil {
+					g.wait = time.Duration(n) * time.Minute
+				}
+			}
+		}
+		var frames []string
+		for i := 1; i < len(lines); i += 2 {
+			frame := lines[i]
+			if paren := strings.LastIndex(frame, "("); paren > 0 {
+				frame = frame[:paren]
+			}
+			frames = append(frames, frame)
+		}
+		g.frames = strings.Join(frames, "\n")
+		stacks = append(stacks, g)
+	}
+	return stacks
+}
+
+// blockedGroups groups goroutines waiting at least BlockedFor by state and
+// stack.
+func (w *Watchdog) blockedGroups(stacks []goroutineStack) []BlockedGroup {
+	type key struct{ state, frames string }
+	groups := map[key]*BlockedGroup{}
+	for _, g := range stacks {
+		mutex := slices.Contains(mutexWaitStates, g.state)
+		if g.wait < w.cfg.BlockedFor || (!mutex && !slices.Contains(channelWaitStates, g.state)) || w.ignored(g.stack) {
+			continue
+		}
+		k := key{state: g.state, frames: g.frames}
+		group, ok := groups[k]
+		if !ok {
+			group = &BlockedGroup{WaitReason: g.state, Stack: g.stack}
+			groups[k] = group
+		}
+		group.Count++
+		group.WaitMinutes = max(group.WaitMinutes, int(g.wait/time.Minute))
+		if len(group.GoroutineIDs) < 5 {
+			group.GoroutineIDs = append(group.GoroutineIDs, g.id)
+		}
+		group.Suspect = mutex || group.Count >= w.cfg.BlockedGroupLimit
+	}
+
+	out := make([]BlockedGroup, 0, len(groups))
+	for _, group := range groups {
+		out = append(out, *group)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Suspect != out[j].Suspect {
+			return out[i].Suspect
+		}
+		return out[i].Count > out[j].Count
+	})
+	return out
+}
+
+func (w *Watchdog) ignored(stack string) bool {
+	for _, pattern := range w.cfg.Ignore {
+		if strings.Contains(stack, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func withoutStacks(groups []BlockedGroup) []BlockedGroup {
+	out := make([]BlockedGroup, len(groups))
+	for i, group := range groups {
+		group.Stack = ""
+		out[i] = group
+	}
+	return out
+}
@@ -0,0 +1,124 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const watchdogTestDump = `goroutine 1 [running]:
+main.main()
+	/app/main.go:10 +0x1d
+
+goroutine 7 [sync.Mutex.Lock, 12 minutes]:
+sync.(*Mutex).Lock(0xc000010000)
+	/go/src/sync/mutex.go:81 +0x5a
+orders.(*Cache).Refresh(0xc000020000)
+	/app/cache.go:42 +0x25
+created by orders.Start in goroutine 1
+	/app/main.go:20 +0x3b
+
+goroutine 8 [select, 30 minutes]:
+orders.worker(0xc000030000)
+	/app/worker.go:12 +0x85
+created by orders.Start in goroutine 1
+	/app/main.go:21 +0x3b
+
+goroutine 9 [chan receive, 9 minutes, locked to thread]:
+orders.handler.func1(0xc000040000)
+	/app/handler.go:30 +0x40
+created by orders.handler in goroutine 1
+	/app/handler.go:28 +0x3b
+
+goroutine 10 [chan receive, 11 minutes]:
+orders.handler.func1(0xc000050000)
+	/app/handler.go:30 +0x40
+created by orders.handler in goroutine 1
+	/app/handler.go:28 +0x3b
+
+goroutine 11 [chan receive, 2 minutes]:
+orders.handler.func1(0xc000060000)
+	/app/handler.go:30 +0x40
+created by orders.handler in goroutine 1
+	/app/handler.go:28 +0x3b
+
+goroutine 12 [IO wait, 40 minutes]:
+internal/poll.runtime_pollWait(0x7f, 0x72)
+	/go/src/runtime/netpoll.go:345 +0x85
+`
+
+func TestWatchdogGroupsBlockedGoroutines(t *testing.T) {
+	w := NewWatchdog("orders", nil, WatchdogConfig{BlockedFor: 5 * time.Minute, BlockedGroupLimit: 2})
+
+	groups := w.blockedGroups(parseGoroutineStacks([]byte(watchdogTestDump)))
+	if len(groups) != 3 {
+		t.Fatalf("groups = %+v, want mutex, chan receive, and select groups", groups)
+	}
+	// Suspect groups sort first: the mutex wait and the two handler goroutines
+	// sharing a stack (goroutine 11 has not waited long enough).
+	if !groups[0].Suspect || !groups[1].Suspect || groups[2].Suspect {
+		t.Fatalf("suspect flags = %v %v %v", groups[0].Suspect, groups[1].Suspect, groups[2].Suspect)
+	}
+	for _, group := range groups {
+		switch group.WaitReason {
+		case "sync.Mutex.Lock":
+			if group.Count != 1 || group.WaitMinutes != 12 || !strings.Contains(group.Stack, "Cache).Refresh") {
+				t.Fatalf("mutex group = %+v", group)
+			}
+		case "chan receive":
+			if group.Count != 2 || group.WaitMinutes != 11 || len(group.GoroutineIDs) != 2 {
+				t.Fatalf("chan group = %+v", group)
+			}
+		case "select":
+			if group.Count != 1 || group.Suspect {
+				t.Fatalf("select group = %+v", group)
+			}
+		default:
+			t.Fatalf("unexpected group %+v", group)
+		}
+	}
+
+	w.cfg.Ignore = []string{"orders.worker"}
+	if groups := w.blockedGroups(parseGoroutineStacks([]byte(watchdogTestDump))); len(groups) != 2 {
+		t.Fatalf("groups with ignore = %+v, want 2", groups)
+	}
+}
+
+func TestWatchdogDetectsGrowthAndServesReport(t *testing.T) {
+	w := NewWatchdog("orders", nil, WatchdogConfig{LeakGrowth: 20})
+	if report := w.Sample(); !report.Healthy() {
+		t.Fatalf("first sample = %+v, want healthy", report)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	for range 25 {
+		go func() { <-release }()
+	}
+
+	report := w.Sample()
+	if report.Healthy() || report.Goroutines-report.Baseline < 20 || !strings.Contains(report.Problems[0], "grew by") {
+		t.Fatalf("sample = %+v, want growth problem", report)
+	}
+
+	recorder := httptest.NewRecorder()
+	w.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/watchdog", nil))
+	var served WatchdogReport
+	if err := json.Unmarshal(recorder.Body.Bytes(), &served); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if recorder.Code != http.StatusServiceUnavailable || served.Goroutines != report.Goroutines {
+		t.Fatalf("handler = %d %+v", recorder.Code, served)
+	}
+
+	if err := w.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
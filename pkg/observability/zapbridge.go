@@ -0,0 +1,100 @@
+package observability
+
+import (
+	"context"
+
+	otellog "go.opentelemetry.io/otel/log"
+	"go.uber.org/zap/zapcore"
+)
+
+// ZapCore is a zapcore.Core that emits entries, with their fields, to an
+// OpenTelemetry logger. Tee it with the console core to export a zap logger:
+//
+//	core := zapcore.NewTee(consoleCore, observability.NewZapCore(exporter.LoggerProvider(), "orders", zapcore.InfoLevel))
+//	log := zap.New(core)
+//	log.Info("order placed", zap.String("order_id", id), zap.Any("ctx", ctx))
+//
+// A context.Context passed as a field is not exported as an attribute; it is
+// used for trace correlation instead.
+type ZapCore struct {
+	zapcore.LevelEnabler
+	logger otellog.Logger
+	ctx    context.Context
+	fields []otellog.KeyValue
+}
+
+// NewZapCore creates a core emitting to provider's logger named name for
+// entries enabled by level.
+func NewZapCore(provider otellog.LoggerProvider, name string, level zapcore.LevelEnabler) *ZapCore {
+	return &ZapCore{
+		LevelEnabler: level,
+		logger:       provider.Logger(name),
+		ctx:          context.Background(),
+	}
+}
+
+// With implements zapcore.Core.
+func (c *ZapCore) With(fields []zapcore.Field) zapcore.Core {
+	ctx, attrs := zapFieldsToLog(c.ctx, fields)
+	clone := *c
+	clone.ctx = ctx
+	clone.fields = append(append([]otellog.KeyValue(nil), c.fields...), attrs...)
+	return &clone
+}
+
+// Check implements zapcore.Core.
+func (c *ZapCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write implements zapcore.Core.
+func (c *ZapCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	ctx, attrs := zapFieldsToLog(c.ctx, fields)
+
+	var record otellog.Record
+	record.SetTimestamp(entry.Time)
+	record.SetObservedTimestamp(entry.Time)
+	record.SetSeverity(mapLogLevelToSeverity(entry.Level.String()))
+	record.SetSeverityText(entry.Level.CapitalString())
+	record.SetBody(otellog.StringValue(entry.Message))
+	record.AddAttributes(c.fields...)
+	record.AddAttributes(attrs...)
+	if entry.LoggerName != "" {
+		record.AddAttributes(otellog.String("logger", entry.LoggerName))
+	}
+	if entry.Caller.Defined {
+		record.AddAttributes(otellog.String("caller", entry.Caller.TrimmedPath()))
+	}
+	if entry.Stack != "" {
+		record.AddAttributes(otellog.String("stacktrace", entry.Stack))
+	}
+
+	c.logger.Emit(ctx, record)
+	return nil
+}
+
+// Sync implements zapcore.Core. Flushing belongs to the logger provider.
+func (c *ZapCore) Sync() error {
+	return nil
+}
+
+// zapFieldsToLog converts zap fields to log attributes, picking out any
+// context.Context field.
+func zapFieldsToLog(ctx context.Context, fields []zapcore.Field) (context.Context, []otellog.KeyValue) {
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range fields {
+		if fieldCtx, ok := field.Interface.(context.Context); ok {
+			ctx = fieldCtx
+			continue
+		}
+		field.AddTo(encoder)
+	}
+	attrs := make([]otellog.KeyValue, 0, len(encoder.Fields))
+	for key, value := range encoder.Fields {
+		attrs = append(attrs, otellog.KeyValue{Key: key, Value: logValue(value)})
+	}
+	return ctx, attrs
+}
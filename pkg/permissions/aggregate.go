@@ -0,0 +1,101 @@
+package permissions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// serviceSeparator joins a service and a permission code in a qualified code.
+const serviceSeparator = ":"
+
+// QualifiedCode returns code qualified by service, e.g. "orders:ord-orders-list".
+// Stores loaded through MergeLoaders key every permission this way, so codes
+// from different upstream services cannot collide.
+func QualifiedCode(service, code string) string {
+	return normalize(service) + serviceSeparator + strings.TrimSpace(code)
+}
+
+// SplitQualifiedCode splits a code built by QualifiedCode into its service and
+// permission code. ok is false for an unqualified code.
+func SplitQualifiedCode(qualified string) (service, code string, ok bool) {
+	service, code, ok = strings.Cut(strings.TrimSpace(qualified), serviceSeparator)
+	if !ok || service == "" || code == "" {
+		return "", "", false
+	}
+	return service, code, true
+}
+
+// ServiceLoader pairs an upstream service with the loader of its catalog.
+type ServiceLoader struct {
+	// Service names the upstream, e.g. "orders". It qualifies the codes of the
+	// permissions it loads.
+	Service string
+	// Loader fetches the upstream's catalog, e.g. LoaderFromHTTP with that
+	// service's config.
+	Loader Loader
+	// Optional lets the merged load succeed without this upstream when its
+	// loader fails; its permissions are then absent until the next load.
+	Optional bool
+}
+
+// MergeLoaders returns a Loader that runs every upstream loader concurrently
+// and merges their catalogs into one map, for gateways and BFFs that enforce
+// the permissions of several backends with a single Store and Authorizer.
+//
+// Each permission is keyed by QualifiedCode(upstream.Service, code), and its
+// Metadata.Service is set to the upstream's service when empty. Permissions an
+// upstream loader returns for other services are dropped, so each backend
+// owns its own catalog. Store.Lookup still resolves a bare code when exactly
+// one upstream defines it; LookupService resolves within one upstream.
+//
+// The merged load fails if any required upstream fails.
+func MergeLoaders(loaders ...ServiceLoader) Loader {
+	return func(ctx context.Context) (map[string]Metadata, error) {
+		results := make([]map[string]Metadata, len(loaders))
+		errs := make([]error, len(loaders))
+
+		var wg sync.WaitGroup
+		for i, upstream := range loaders {
+			if strings.TrimSpace(upstream.Service) == "" || upstream.Loader == nil {
+				errs[i] = fmt.Errorf("permission loader %d: service and loader are required", i)
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], errs[i] = upstream.Loader(ctx)
+			}()
+		}
+		wg.Wait()
+
+		merged := make(map[string]Metadata)
+		var failed []error
+		for i, upstream := range loaders {
+			if errs[i] != nil {
+				if !upstream.Optional {
+					failed = append(failed, fmt.Errorf("load %s permissions: %w", upstream.Service, errs[i]))
+				}
+				continue
+			}
+			service := strings.TrimSpace(upstream.Service)
+			for code, meta := range results[i] {
+				if meta.Service == "" {
+					meta.Service = service
+				} else if !strings.EqualFold(meta.Service, service) {
+					continue
+				}
+				if _, bare, ok := SplitQualifiedCode(code); ok {
+					code = bare
+				}
+				merged[QualifiedCode(service, code)] = meta
+			}
+		}
+		if err := errors.Join(failed...); err != nil {
+			return nil, err
+		}
+		return merged, nil
+	}
+}
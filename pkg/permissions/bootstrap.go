@@ -2,26 +2,33 @@ package permissions
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/milan604/core-lab/pkg/config"
-	"github.com/milan604/core-lab/pkg/controlplane"
-	"github.com/milan604/core-lab/pkg/http"
 	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/sentinel"
 )
 
 // HTTPClient is the interface for making HTTP requests.
 // Services can pass core-lab's http.Client directly.
+//
+// Deprecated: the package calls Sentinel through pkg/sentinel and no longer
+// uses this interface.
 type HTTPClient interface {
 	PostJSON(ctx context.Context, url string, body interface{}, response interface{}) error
 	GetJSON(ctx context.Context, url string, response interface{}) error
 }
 
 // Bootstrap synchronizes permissions with the sentinel service and loads them into the store.
-// Since permission APIs and token provider are standardized, this function makes HTTP calls directly.
+// Since permission APIs and token provider are standardized, this function makes the Sentinel calls itself.
 // Services only need to provide config and logger - no API methods or token providers needed!
-// The function uses http.NewClientWithServiceToken directly from the http package.
-func Bootstrap(ctx context.Context, catalog *Catalog, cfg *config.Config, log logger.LogManager, store *Store) error {
+// The function uses a pkg/sentinel client authenticated with the service token.
+// Options add retries (WithRetry), chunked creation (WithChunkSize), a stale
+// cache fallback (WithStaleCache), or a drift report instead of a sync (WithDryRun).
+func Bootstrap(ctx context.Context, catalog *Catalog, cfg *config.Config, log logger.LogManager, store *Store, opts ...BootstrapOption) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -38,25 +45,72 @@ func Bootstrap(ctx context.Context, catalog *Catalog, cfg *config.Config, log lo
 		return fmt.Errorf("permission catalog not configured")
 	}
 
-	api := controlplane.APIFromConfig(cfg)
-	if !api.Valid() {
-		return fmt.Errorf("%s or %s not configured", controlplane.KeyBaseURL, controlplane.LegacyKeyBaseURL)
-	}
+	options := newBootstrapOptions(opts)
 
-	// Create HTTP client with token provider using http package directly
-	httpClient, err := http.NewClientWithServiceToken(log, cfg)
+	// Create the Sentinel client with the service token
+	client, err := sentinel.NewFromConfig(cfg, log, options.sentinelOptions()...)
 	if err != nil {
-		return fmt.Errorf("failed to create HTTP client with token provider: %w", err)
+		return err
+	}
+
+	if options.dryRun {
+		drift, err := detectDrift(ctx, catalog, client, store)
+		if err != nil {
+			return err
+		}
+		if !drift.Empty() {
+			log.WarnF("Permission drift detected: missing=%d extra=%d changed=%d", len(drift.Missing), len(drift.Extra), len(drift.Changed))
+			return &DriftError{Drift: drift}
+		}
+		log.InfoF("Permission catalog matches sentinel (%d permissions)", catalog.Count())
+		return nil
+	}
+
+	if err := synchronize(ctx, catalog, client, store, options); err != nil {
+		if options.cachePath == "" || store == nil || !staleCacheApplies(err) {
+			return err
+		}
+		cache, cacheErr := readCache(options.cachePath)
+		if cacheErr != nil {
+			return fmt.Errorf("%w (stale cache unavailable: %v)", err, cacheErr)
+		}
+		store.Replace(cache.Permissions)
+		log.WarnF("Permission bootstrap failed, continuing with stale cache from %s (%d permissions, saved %s): %v",
+			options.cachePath, len(cache.Permissions), cache.SavedAt.Format(time.RFC3339), err)
+		return nil
 	}
 
+	if options.cachePath != "" && store != nil {
+		if err := saveCache(options.cachePath, store.Snapshot()); err != nil {
+			log.WarnF("Permission bootstrap: failed to write stale cache %s: %v", options.cachePath, err)
+		}
+	}
+	return nil
+}
+
+// staleCacheApplies reports whether err means sentinel could not answer: a
+// transport failure or a 5xx response. A 4xx response means the request itself
+// is wrong (a revoked token, an invalid catalog), which a stale cache would
+// only hide.
+func staleCacheApplies(err error) bool {
+	var sentinelErr *sentinel.Error
+	if !errors.As(err, &sentinelErr) {
+		return false
+	}
+	return sentinelErr.StatusCode == 0 || sentinelErr.StatusCode >= http.StatusInternalServerError
+}
+
+// synchronize creates the catalog's permissions in sentinel and loads the
+// resulting metadata into store.
+func synchronize(ctx context.Context, catalog *Catalog, client *sentinel.Client, store *Store, options bootstrapOptions) error {
 	// Ensure permissions are created in sentinel service
-	if err := ensurePermissions(ctx, catalog, api, httpClient); err != nil {
+	if err := ensurePermissions(ctx, catalog, client, options); err != nil {
 		return fmt.Errorf("failed to ensure permissions: %w", err)
 	}
 
 	// Load permissions from sentinel service into the permission store
 	if store != nil {
-		if err := loadPermissions(ctx, api, httpClient, store); err != nil {
+		if err := loadPermissions(ctx, client, store); err != nil {
 			return fmt.Errorf("failed to load permissions: %w", err)
 		}
 	}
@@ -66,7 +120,8 @@ func Bootstrap(ctx context.Context, catalog *Catalog, cfg *config.Config, log lo
 
 // ensurePermissions creates permissions in the sentinel service if they don't exist.
 // Makes HTTP call directly to the sentinel service.
-func ensurePermissions(ctx context.Context, catalog *Catalog, api controlplane.API, httpClient HTTPClient) error {
+// Large catalogs are sent in chunks; the client retries each chunk on its own.
+func ensurePermissions(ctx context.Context, catalog *Catalog, client *sentinel.Client, options bootstrapOptions) error {
 	// Prepare bulk create request
 	requests := make([]StandardCreateRequest, 0, catalog.Count())
 	for _, def := range catalog.All() {
@@ -79,34 +134,33 @@ func ensurePermissions(ctx context.Context, catalog *Catalog, api controlplane.A
 		})
 	}
 
-	requestBody := map[string]interface{}{
-		"permissions": requests,
-	}
-
-	// Make HTTP call directly to sentinel service
-	var response struct {
-		Permissions []StandardCreateResponseEntry `json:"permissions"`
-	}
+	chunks := (len(requests) + options.chunkSize - 1) / options.chunkSize
+	for i := 0; i < chunks; i++ {
+		chunk := requests[i*options.chunkSize : min((i+1)*options.chunkSize, len(requests))]
 
-	err := httpClient.PostJSON(ctx, api.PermissionBulkURL(), requestBody, &response)
-	if err != nil {
-		return fmt.Errorf("failed to create permissions in sentinel service: %w", err)
+		if _, err := client.CreatePermissionsBulk(ctx, chunk); err != nil {
+			return fmt.Errorf("failed to create permissions in sentinel service (chunk %d/%d, %d permissions already submitted): %w", i+1, chunks, i*options.chunkSize, err)
+		}
 	}
 
 	return nil
 }
 
 // loadPermissions loads permissions from the sentinel service into the store.
-// Makes HTTP call directly to the sentinel service.
-func loadPermissions(ctx context.Context, api controlplane.API, httpClient HTTPClient, store *Store) error {
-	var catalogResponse StandardCatalogResponse
-
-	err := httpClient.GetJSON(ctx, api.PermissionCatalogURL(), &catalogResponse)
+func loadPermissions(ctx context.Context, client *sentinel.Client, store *Store) error {
+	catalogResponse, err := client.GetBitmaskCatalog(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to fetch permission catalog: %w", err)
 	}
 
-	// Convert catalog response to internal metadata map
+	// Update store with fetched permissions
+	store.Replace(metadataFromCatalog(catalogResponse))
+
+	return nil
+}
+
+// metadataFromCatalog converts a catalog response to the store's metadata map.
+func metadataFromCatalog(catalogResponse StandardCatalogResponse) map[string]Metadata {
 	metadata := make(map[string]Metadata, 0)
 	for service, serviceCatalog := range catalogResponse.Services {
 		for code, perm := range serviceCatalog.Permissions {
@@ -117,9 +171,5 @@ func loadPermissions(ctx context.Context, api controlplane.API, httpClient HTTPC
 			}
 		}
 	}
-
-	// Update store with fetched permissions
-	store.Replace(metadata)
-
-	return nil
+	return metadata
 }
@@ -0,0 +1,136 @@
+package permissions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/sentinel"
+)
+
+const defaultBootstrapChunkSize = 100
+
+// BootstrapOption customizes Bootstrap.
+type BootstrapOption func(*bootstrapOptions)
+
+type bootstrapOptions struct {
+	dryRun    bool
+	attempts  int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	chunkSize int
+	cachePath string
+}
+
+func newBootstrapOptions(opts []BootstrapOption) bootstrapOptions {
+	options := bootstrapOptions{
+		chunkSize: defaultBootstrapChunkSize,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithDryRun makes Bootstrap compare the catalog with sentinel instead of
+// synchronizing: nothing is created and the store is not reloaded. Bootstrap
+// returns a *DriftError carrying the structured diff when the two disagree, so
+// CI can fail on drift:
+//
+//	err := permissions.Bootstrap(ctx, catalog, cfg, log, store, permissions.WithDryRun())
+//	var drift *permissions.DriftError
+//	if errors.As(err, &drift) {
+//		// drift.Drift.Missing, .Extra, .Changed
+//	}
+//
+// Bit values are compared against store when it already holds the permission,
+// e.g. from a pinned fixture or an earlier load.
+func WithDryRun() BootstrapOption {
+	return func(o *bootstrapOptions) {
+		o.dryRun = true
+	}
+}
+
+// WithRetry sets the sentinel client's retry policy: each call that fails in
+// transport or is answered with 429 or 502-504 is tried up to attempts times,
+// doubling the delay from baseDelay up to maxDelay. Zero values keep the
+// client's defaults (see sentinel.WithRetry).
+func WithRetry(attempts int, baseDelay, maxDelay time.Duration) BootstrapOption {
+	return func(o *bootstrapOptions) {
+		o.attempts, o.baseDelay, o.maxDelay = attempts, baseDelay, maxDelay
+	}
+}
+
+// WithChunkSize splits bulk creation into requests of at most size
+// permissions. Default: 100.
+func WithChunkSize(size int) BootstrapOption {
+	return func(o *bootstrapOptions) {
+		if size > 0 {
+			o.chunkSize = size
+		}
+	}
+}
+
+// WithStaleCache keeps a copy of the loaded permission metadata at path. When
+// sentinel cannot be reached or answers with a 5xx, Bootstrap loads the store
+// from that copy, logs a warning, and returns nil so the service can start with
+// a stale catalog. A 4xx answer is returned as is. The first start still
+// requires sentinel.
+func WithStaleCache(path string) BootstrapOption {
+	return func(o *bootstrapOptions) {
+		o.cachePath = path
+	}
+}
+
+// sentinelOptions returns the client options for WithRetry. The sentinel
+// client is the only retry layer, so Bootstrap does not retry on top of it.
+func (o bootstrapOptions) sentinelOptions() []sentinel.Option {
+	return []sentinel.Option{
+		sentinel.WithRetry(o.attempts, o.baseDelay),
+		sentinel.WithMaxRetryDelay(o.maxDelay),
+	}
+}
+
+// permissionCache is the on-disk format written by WithStaleCache.
+type permissionCache struct {
+	SavedAt     time.Time           `json:"saved_at"`
+	Permissions map[string]Metadata `json:"permissions"`
+}
+
+func saveCache(path string, perms map[string]Metadata) error {
+	data, err := json.Marshal(permissionCache{SavedAt: time.Now().UTC(), Permissions: perms})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func readCache(path string) (permissionCache, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return permissionCache{}, err
+	}
+	var cache permissionCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return permissionCache{}, err
+	}
+	if len(cache.Permissions) == 0 {
+		return permissionCache{}, fmt.Errorf("permission cache %s is empty", path)
+	}
+	return cache, nil
+}
@@ -0,0 +1,91 @@
+package permissions_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/permissions"
+	"github.com/milan604/core-lab/pkg/sentinel"
+	"github.com/milan604/core-lab/pkg/sentinel/sentineltest"
+)
+
+const bulkPath = "/api/v1/permissions/bulk"
+
+func newBootstrapFixture(t *testing.T) (*sentineltest.Server, *permissions.Catalog, logger.LogManager) {
+	t.Helper()
+	log, err := logger.NewLogger(logger.LoggerOptions{Level: "error"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog := permissions.NewCatalog([]permissions.Definition{
+		{Name: "ReadUsers", Description: "Read users", Reference: permissions.Reference{Service: "usr", Category: "users", Action: "read"}},
+	})
+	return sentineltest.NewServer(t), catalog, log
+}
+
+func TestBootstrapStaleCache(t *testing.T) {
+	srv, catalog, log := newBootstrapFixture(t)
+	ctx := context.Background()
+	cachePath := filepath.Join(t.TempDir(), "perms.json")
+
+	if err := permissions.Bootstrap(ctx, catalog, srv.Config(), log, permissions.NewStore(nil), permissions.WithStaleCache(cachePath)); err != nil {
+		t.Fatalf("Bootstrap() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		status    int
+		cachePath string
+		wantCache bool
+	}{
+		{name: "server error uses the cache", status: http.StatusInternalServerError, cachePath: cachePath, wantCache: true},
+		{name: "bad request is returned", status: http.StatusBadRequest, cachePath: cachePath},
+		{name: "forbidden is returned", status: http.StatusForbidden, cachePath: cachePath},
+		{name: "missing cache", status: http.StatusInternalServerError, cachePath: filepath.Join(t.TempDir(), "missing.json")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv.FailNext(http.MethodPost, bulkPath, tt.status)
+			store := permissions.NewStore(nil)
+			err := permissions.Bootstrap(ctx, catalog, srv.Config(), log, store, permissions.WithStaleCache(tt.cachePath))
+
+			if tt.wantCache {
+				if err != nil {
+					t.Fatalf("Bootstrap() error = %v, want the stale cache", err)
+				}
+				if meta, ok := store.Lookup("usr-users-read"); !ok || meta.BitValue != 1 {
+					t.Fatalf("store = %+v", store.Snapshot())
+				}
+				return
+			}
+			var sentinelErr *sentinel.Error
+			if !errors.As(err, &sentinelErr) || sentinelErr.StatusCode != tt.status {
+				t.Fatalf("Bootstrap() error = %v, want the %d answer", err, tt.status)
+			}
+			if store.Count() != 0 {
+				t.Fatalf("store = %+v, want it left empty", store.Snapshot())
+			}
+		})
+	}
+}
+
+// The sentinel client is the only retry layer: WithRetry(2, ...) sends each
+// call twice at most, however many layers sit underneath.
+func TestBootstrapRetriesOnce(t *testing.T) {
+	srv, catalog, log := newBootstrapFixture(t)
+	srv.FailNext(http.MethodPost, bulkPath, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	err := permissions.Bootstrap(context.Background(), catalog, srv.Config(), log, permissions.NewStore(nil),
+		permissions.WithRetry(2, time.Millisecond, time.Millisecond))
+	if !errors.Is(err, sentinel.ErrUnavailable) {
+		t.Fatalf("Bootstrap() error = %v, want ErrUnavailable", err)
+	}
+	if calls := srv.Calls(http.MethodPost, bulkPath); calls != 2 {
+		t.Fatalf("bulk calls = %d, want 2", calls)
+	}
+}
@@ -0,0 +1,184 @@
+package permissions
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/milan604/core-lab/pkg/config"
+	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/sentinel"
+)
+
+// Drift lists the differences between a local Catalog and the sentinel
+// service's permission catalog.
+type Drift struct {
+	// Missing are declared locally but not registered in sentinel.
+	Missing []Definition `json:"missing,omitempty"`
+	// Extra are registered in sentinel for one of the catalog's services but
+	// not declared locally.
+	Extra []DriftEntry `json:"extra,omitempty"`
+	// Changed are registered on both sides with differing attributes.
+	Changed []DriftChange `json:"changed,omitempty"`
+}
+
+// DriftEntry is a sentinel permission absent from the local catalog.
+type DriftEntry struct {
+	Service  string `json:"service"`
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	BitValue int64  `json:"bit_value"`
+}
+
+// DriftChange is one attribute that differs between the local and the
+// sentinel definition of a permission. Field is "name", "description", or
+// "bit_value"; bit values are compared against the permission store.
+type DriftChange struct {
+	Code   string `json:"code"`
+	Field  string `json:"field"`
+	Local  string `json:"local"`
+	Remote string `json:"remote"`
+}
+
+// Empty reports whether the catalogs agree.
+func (d Drift) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Extra) == 0 && len(d.Changed) == 0
+}
+
+// String summarizes the drift on one line.
+func (d Drift) String() string {
+	if d.Empty() {
+		return "no permission drift"
+	}
+	var parts []string
+	for _, def := range d.Missing {
+		parts = append(parts, "missing "+def.Reference.Code())
+	}
+	for _, entry := range d.Extra {
+		parts = append(parts, "extra "+entry.Code)
+	}
+	for _, change := range d.Changed {
+		parts = append(parts, fmt.Sprintf("changed %s %s %q -> %q", change.Code, change.Field, change.Local, change.Remote))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// DriftError is returned by Bootstrap in dry-run mode when drift is found.
+type DriftError struct {
+	Drift Drift
+}
+
+func (e *DriftError) Error() string {
+	return "permission drift detected: " + e.Drift.String()
+}
+
+// DetectDrift fetches sentinel's permission catalog and compares it with
+// catalog. store may be nil; see WithDryRun.
+func DetectDrift(ctx context.Context, catalog *Catalog, cfg *config.Config, log logger.LogManager, store *Store) (Drift, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if cfg == nil {
+		return Drift{}, fmt.Errorf("config not configured")
+	}
+	if log == nil {
+		return Drift{}, fmt.Errorf("logger not configured")
+	}
+	if catalog == nil {
+		return Drift{}, fmt.Errorf("permission catalog not configured")
+	}
+
+	client, err := sentinel.NewFromConfig(cfg, log)
+	if err != nil {
+		return Drift{}, err
+	}
+	return detectDrift(ctx, catalog, client, store)
+}
+
+func detectDrift(ctx context.Context, catalog *Catalog, client *sentinel.Client, store *Store) (Drift, error) {
+	remote, err := client.GetBitmaskCatalog(ctx)
+	if err != nil {
+		return Drift{}, fmt.Errorf("failed to fetch permission catalog: %w", err)
+	}
+	var pinned map[string]Metadata
+	if store != nil {
+		pinned = store.Snapshot()
+	}
+	return CompareCatalog(catalog, remote, pinned), nil
+}
+
+// CompareCatalog computes the drift between catalog and a sentinel catalog
+// response. Codes and services are matched case-insensitively. pinned, when
+// non-nil, supplies the bit values the service expects (keyed by code).
+func CompareCatalog(catalog *Catalog, remote StandardCatalogResponse, pinned map[string]Metadata) Drift {
+	type remoteEntry struct {
+		service string
+		entry   StandardCatalogEntry
+	}
+	remoteByCode := make(map[string]remoteEntry)
+	for service, serviceCatalog := range remote.Services {
+		for code, entry := range serviceCatalog.Permissions {
+			if entry.Code == "" {
+				entry.Code = code
+			}
+			remoteByCode[strings.ToUpper(code)] = remoteEntry{service: service, entry: entry}
+		}
+	}
+	pinnedByCode := make(map[string]Metadata, len(pinned))
+	for code, meta := range pinned {
+		pinnedByCode[strings.ToUpper(code)] = meta
+	}
+
+	var drift Drift
+	localCodes := make(map[string]bool)
+	localServices := make(map[string]bool)
+	if catalog != nil {
+		for _, def := range catalog.All() {
+			code := def.Reference.Code()
+			key := strings.ToUpper(code)
+			localCodes[key] = true
+			localServices[strings.ToUpper(normalize(def.Reference.Service))] = true
+
+			got, ok := remoteByCode[key]
+			if !ok {
+				drift.Missing = append(drift.Missing, def)
+				continue
+			}
+			if def.Name != got.entry.Name {
+				drift.Changed = append(drift.Changed, DriftChange{Code: code, Field: "name", Local: def.Name, Remote: got.entry.Name})
+			}
+			if def.Description != got.entry.Description {
+				drift.Changed = append(drift.Changed, DriftChange{Code: code, Field: "description", Local: def.Description, Remote: got.entry.Description})
+			}
+			if meta, ok := pinnedByCode[key]; ok && meta.BitValue != got.entry.BitValue {
+				drift.Changed = append(drift.Changed, DriftChange{
+					Code:   code,
+					Field:  "bit_value",
+					Local:  strconv.FormatInt(meta.BitValue, 10),
+					Remote: strconv.FormatInt(got.entry.BitValue, 10),
+				})
+			}
+		}
+	}
+
+	for key, got := range remoteByCode {
+		if localCodes[key] || !localServices[strings.ToUpper(normalize(got.service))] {
+			continue
+		}
+		drift.Extra = append(drift.Extra, DriftEntry{
+			Service:  got.service,
+			Code:     got.entry.Code,
+			Name:     got.entry.Name,
+			BitValue: got.entry.BitValue,
+		})
+	}
+
+	sort.Slice(drift.Missing, func(i, j int) bool {
+		return drift.Missing[i].Reference.Code() < drift.Missing[j].Reference.Code()
+	})
+	sort.Slice(drift.Extra, func(i, j int) bool { return drift.Extra[i].Code < drift.Extra[j].Code })
+	sort.SliceStable(drift.Changed, func(i, j int) bool { return drift.Changed[i].Code < drift.Changed[j].Code })
+	return drift
+}
@@ -0,0 +1,109 @@
+package permissions_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/milan604/core-lab/pkg/permissions"
+)
+
+func TestCompareCatalog(t *testing.T) {
+	readUsers := permissions.Definition{
+		Name:        "ReadUsers",
+		Description: "Read users",
+		Reference:   permissions.Reference{Service: "usr", Category: "users", Action: "read"},
+	}
+	remote := func(entries map[string]permissions.StandardCatalogEntry) permissions.StandardCatalogResponse {
+		byService := map[string]map[string]permissions.StandardCatalogEntry{}
+		for code, entry := range entries {
+			service := code[:3]
+			if byService[service] == nil {
+				byService[service] = map[string]permissions.StandardCatalogEntry{}
+			}
+			byService[service][code] = entry
+		}
+		response := permissions.StandardCatalogResponse{Services: map[string]permissions.StandardServiceCatalog{}}
+		for service, perms := range byService {
+			response.Services[service] = permissions.StandardServiceCatalog{Permissions: perms}
+		}
+		return response
+	}
+	matching := permissions.StandardCatalogEntry{Name: "ReadUsers", Description: "Read users", BitValue: 1}
+
+	tests := []struct {
+		name   string
+		local  []permissions.Definition
+		remote map[string]permissions.StandardCatalogEntry
+		pinned map[string]permissions.Metadata
+		want   permissions.Drift
+	}{
+		{
+			name:   "in sync",
+			local:  []permissions.Definition{readUsers},
+			remote: map[string]permissions.StandardCatalogEntry{"usr-users-read": matching},
+		},
+		{
+			name:   "codes match case-insensitively",
+			local:  []permissions.Definition{readUsers},
+			remote: map[string]permissions.StandardCatalogEntry{"usr-USERS-read": matching},
+		},
+		{
+			name:  "missing in sentinel",
+			local: []permissions.Definition{readUsers},
+			want:  permissions.Drift{Missing: []permissions.Definition{readUsers}},
+		},
+		{
+			name:  "extra in sentinel for a local service",
+			local: []permissions.Definition{readUsers},
+			remote: map[string]permissions.StandardCatalogEntry{
+				"usr-users-read":   matching,
+				"usr-users-delete": {Name: "DeleteUsers", BitValue: 2},
+			},
+			want: permissions.Drift{Extra: []permissions.DriftEntry{
+				{Service: "usr", Code: "usr-users-delete", Name: "DeleteUsers", BitValue: 2},
+			}},
+		},
+		{
+			name:  "other services are not extra",
+			local: []permissions.Definition{readUsers},
+			remote: map[string]permissions.StandardCatalogEntry{
+				"usr-users-read":  matching,
+				"ord-orders-read": {Name: "ReadOrders", BitValue: 1},
+			},
+		},
+		{
+			name:   "changed name and description",
+			local:  []permissions.Definition{readUsers},
+			remote: map[string]permissions.StandardCatalogEntry{"usr-users-read": {Name: "ListUsers", Description: "List users", BitValue: 1}},
+			want: permissions.Drift{Changed: []permissions.DriftChange{
+				{Code: "usr-users-read", Field: "name", Local: "ReadUsers", Remote: "ListUsers"},
+				{Code: "usr-users-read", Field: "description", Local: "Read users", Remote: "List users"},
+			}},
+		},
+		{
+			name:   "changed bit value against the pinned store",
+			local:  []permissions.Definition{readUsers},
+			remote: map[string]permissions.StandardCatalogEntry{"usr-users-read": {Name: "ReadUsers", Description: "Read users", BitValue: 4}},
+			pinned: map[string]permissions.Metadata{"usr-users-read": {Service: "usr", BitValue: 1}},
+			want: permissions.Drift{Changed: []permissions.DriftChange{
+				{Code: "usr-users-read", Field: "bit_value", Local: "1", Remote: "4"},
+			}},
+		},
+		{
+			name:   "bit value is not compared without a pin",
+			local:  []permissions.Definition{readUsers},
+			remote: map[string]permissions.StandardCatalogEntry{"usr-users-read": {Name: "ReadUsers", Description: "Read users", BitValue: 4}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := permissions.CompareCatalog(permissions.NewCatalog(tt.local), remote(tt.remote), tt.pinned)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("CompareCatalog() = %+v, want %+v", got, tt.want)
+			}
+			if got.Empty() != tt.want.Empty() {
+				t.Fatalf("Empty() = %v", got.Empty())
+			}
+		})
+	}
+}
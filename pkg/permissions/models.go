@@ -1,5 +1,7 @@
 package permissions
 
+import "github.com/milan604/core-lab/pkg/sentinel"
+
 // Request models for creating permissions
 
 // CreateRequest represents a request to create a permission in the sentinel service.
@@ -81,18 +83,11 @@ type PermissionCatalogEntry = CatalogEntry
 
 // Standard API models - these match the structure used by all services
 // since permission create and catalog fetch APIs are always the same.
-// These are used internally by the permissions package when making HTTP calls.
+// They alias the pkg/sentinel client types.
 
 // StandardCreateRequest represents the standard request structure for creating permissions.
 // This matches the structure used by all services.
-type StandardCreateRequest struct {
-	Name         string   `json:"name" binding:"required"`
-	Service      string   `json:"service" binding:"required"`
-	Category     string   `json:"category" binding:"required"`
-	Action       string   `json:"action" binding:"required"`
-	FeatureFlags []string `json:"feature_flags,omitempty"`
-	Description  string   `json:"description" binding:"required"`
-}
+type StandardCreateRequest = sentinel.PermissionCreate
 
 // StandardCreateResponse represents the standard response structure from creating permissions.
 // This matches the structure returned by the sentinel service.
@@ -101,45 +96,17 @@ type StandardCreateResponse struct {
 }
 
 // StandardCreateResponseEntry represents a single permission entry in the create response.
-type StandardCreateResponseEntry struct {
-	ID       string `json:"id"`
-	Code     string `json:"code"`
-	Service  string `json:"service"`
-	BitValue int64  `json:"bit_value"`
-}
+type StandardCreateResponseEntry = sentinel.CreatedPermission
 
 // StandardCatalogResponse represents the standard catalog response structure.
 // This matches the structure returned by the sentinel service.
-type StandardCatalogResponse struct {
-	Services map[string]StandardServiceCatalog `json:"services"`
-}
+type StandardCatalogResponse = sentinel.BitmaskCatalog
 
 // StandardServiceCatalog represents permissions for a service in the catalog.
-type StandardServiceCatalog struct {
-	Permissions map[string]StandardCatalogEntry      `json:"permissions"`
-	Groups      map[string]StandardGroupCatalogEntry `json:"groups,omitempty"`
-}
+type StandardServiceCatalog = sentinel.ServiceCatalog
 
 // StandardCatalogEntry represents a permission entry in the catalog.
-type StandardCatalogEntry struct {
-	ID           string   `json:"id"`
-	Name         string   `json:"name"`
-	Description  string   `json:"description"`
-	Category     string   `json:"category"`
-	Action       string   `json:"action"`
-	Code         string   `json:"code"`
-	BitValue     int64    `json:"bit_value"`
-	FeatureFlags []string `json:"feature_flags,omitempty"`
-}
+type StandardCatalogEntry = sentinel.CatalogPermission
 
 // StandardGroupCatalogEntry represents a permission group entry in the catalog.
-type StandardGroupCatalogEntry struct {
-	ID            string   `json:"id"`
-	Name          string   `json:"name"`
-	Description   string   `json:"description"`
-	Category      string   `json:"category"`
-	Code          string   `json:"code"`
-	CategoryCode  string   `json:"category_code"`
-	Bitmask       int64    `json:"bitmask"`
-	PermissionIDs []string `json:"permission_ids"`
-}
+type StandardGroupCatalogEntry = sentinel.CatalogGroup
@@ -0,0 +1,178 @@
+package permissions
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultNegativeCacheTTL is how long Resolve remembers that a code is
+	// unknown before asking the resolver again.
+	DefaultNegativeCacheTTL = time.Minute
+	// DefaultNegativeCacheSize bounds the number of unknown codes remembered.
+	DefaultNegativeCacheSize = 1024
+)
+
+// CodeResolver fetches the metadata of a single code that is missing from a
+// Store, e.g. a permission registered in sentinel after the store was loaded.
+// found is false when the source does not define code either.
+type CodeResolver func(ctx context.Context, code string) (meta Metadata, found bool, err error)
+
+// ResolverFromLoader returns a CodeResolver that runs loader and picks code
+// from its result, leaving the rest of the catalog alone. Stores without a
+// resolver use their loader this way.
+func ResolverFromLoader(loader Loader) CodeResolver {
+	return func(ctx context.Context, code string) (Metadata, bool, error) {
+		data, err := loader(ctx)
+		if err != nil {
+			return Metadata{}, false, err
+		}
+		fetched := NewStore(nil)
+		fetched.Replace(data)
+		meta, ok := fetched.Lookup(code)
+		return meta, ok, nil
+	}
+}
+
+// SetResolver sets how Resolve fetches codes missing from the store. By
+// default the store's loader is used through ResolverFromLoader.
+func (s *Store) SetResolver(resolver CodeResolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolver = resolver
+}
+
+// SetNegativeCache sets how long Resolve remembers an unknown code and how
+// many codes it remembers at most; when full, the entry closest to expiry is
+// dropped. Defaults: DefaultNegativeCacheTTL and DefaultNegativeCacheSize.
+func (s *Store) SetNegativeCache(ttl time.Duration, size int) {
+	s.missMu.Lock()
+	defer s.missMu.Unlock()
+	if ttl > 0 {
+		s.missTTL = ttl
+	}
+	if size > 0 {
+		s.missSize = size
+	}
+}
+
+// Resolve looks code up like Lookup. On a miss it asks the resolver for that
+// code and adds the result to the store, so a permission registered after the
+// last Load works without a reload. Codes the resolver does not know, or
+// cannot fetch, are remembered in a bounded negative cache and answered
+// without asking again until their TTL expires. Concurrent misses of the
+// same code share one fetch. Load and Replace clear the negative cache.
+func (s *Store) Resolve(ctx context.Context, code string) (Metadata, bool, error) {
+	if meta, ok := s.Lookup(code); ok {
+		return meta, true, nil
+	}
+	code = strings.TrimSpace(code)
+	if code == "" || s.cachedMiss(code) {
+		return Metadata{}, false, nil
+	}
+
+	s.mu.RLock()
+	resolver := s.resolver
+	if resolver == nil && s.loader != nil {
+		resolver = ResolverFromLoader(s.loader)
+	}
+	s.mu.RUnlock()
+	if resolver == nil {
+		s.recordMiss(code)
+		return Metadata{}, false, nil
+	}
+
+	type result struct {
+		meta  Metadata
+		found bool
+	}
+	value, err, _ := s.resolving.Do(code, func() (any, error) {
+		// another caller may have resolved code meanwhile
+		if meta, ok := s.Lookup(code); ok {
+			return result{meta, true}, nil
+		}
+		meta, found, err := resolver(context.WithoutCancel(ctx), code)
+		if err != nil || !found {
+			s.recordMiss(code)
+			return result{}, err
+		}
+		s.put(code, meta)
+		return result{meta, true}, nil
+	})
+	if err != nil {
+		return Metadata{}, false, err
+	}
+	r := value.(result)
+	return r.meta, r.found, nil
+}
+
+// put adds a single code, keeping the bare-code index consistent. A code a
+// concurrent Load or Replace already brought in is left as loaded, and
+// unlike Replace the negative cache is kept.
+func (s *Store) put(code string, meta Metadata) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byCode[code]; ok {
+		return
+	}
+	perms := make(map[string]Metadata, len(s.byCode)+1)
+	for existing, m := range s.byCode {
+		perms[existing] = m
+	}
+	perms[code] = meta
+	s.byCode, s.bare = indexCodes(perms)
+}
+
+func (s *Store) cachedMiss(code string) bool {
+	s.missMu.Lock()
+	defer s.missMu.Unlock()
+	expiry, ok := s.misses[code]
+	if !ok {
+		return false
+	}
+	if time.Now().After(expiry) {
+		delete(s.misses, code)
+		return false
+	}
+	return true
+}
+
+func (s *Store) recordMiss(code string) {
+	s.missMu.Lock()
+	defer s.missMu.Unlock()
+	if s.misses == nil {
+		s.misses = make(map[string]time.Time)
+	}
+	ttl, size := s.missTTL, s.missSize
+	if ttl <= 0 {
+		ttl = DefaultNegativeCacheTTL
+	}
+	if size <= 0 {
+		size = DefaultNegativeCacheSize
+	}
+
+	now := time.Now()
+	if _, exists := s.misses[code]; !exists && len(s.misses) >= size {
+		oldest, oldestExpiry := "", time.Time{}
+		for cached, expiry := range s.misses {
+			if now.After(expiry) {
+				delete(s.misses, cached)
+				continue
+			}
+			if oldest == "" || expiry.Before(oldestExpiry) {
+				oldest, oldestExpiry = cached, expiry
+			}
+		}
+		if len(s.misses) >= size {
+			delete(s.misses, oldest)
+		}
+	}
+	s.misses[code] = now.Add(ttl)
+}
+
+func (s *Store) clearMisses() {
+	s.missMu.Lock()
+	defer s.missMu.Unlock()
+	s.misses = nil
+}
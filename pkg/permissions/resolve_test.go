@@ -0,0 +1,56 @@
+package permissions_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/milan604/core-lab/pkg/permissions"
+)
+
+func TestResolveKeepsNegativeCache(t *testing.T) {
+	ctx := context.Background()
+	calls := map[string]int{}
+	store := permissions.NewStore(nil)
+	store.SetResolver(func(_ context.Context, code string) (permissions.Metadata, bool, error) {
+		calls[code]++
+		if code == "usr-users-read" {
+			return permissions.Metadata{ID: "1", Service: "usr", BitValue: 1}, true, nil
+		}
+		return permissions.Metadata{}, false, nil
+	})
+
+	if _, found, err := store.Resolve(ctx, "usr-users-delete"); err != nil || found {
+		t.Fatalf("Resolve(unknown) = %v, %v, want a miss", found, err)
+	}
+	if meta, found, err := store.Resolve(ctx, "usr-users-read"); err != nil || !found || meta.BitValue != 1 {
+		t.Fatalf("Resolve(known) = %+v, %v, %v", meta, found, err)
+	}
+	if _, found, _ := store.Resolve(ctx, "usr-users-delete"); found {
+		t.Fatal("Resolve(unknown) found the code on the second call")
+	}
+	if calls["usr-users-delete"] != 1 {
+		t.Fatalf("resolver asked %d times for the unknown code, want the miss remembered across the added code", calls["usr-users-delete"])
+	}
+}
+
+func TestResolveKeepsConcurrentlyLoadedCode(t *testing.T) {
+	store := permissions.NewStore(nil)
+	store.SetResolver(func(_ context.Context, code string) (permissions.Metadata, bool, error) {
+		// a Load lands while the resolver is still fetching
+		store.Replace(map[string]permissions.Metadata{
+			code:             {ID: "2", Service: "usr", BitValue: 2},
+			"usr-users-list": {ID: "3", Service: "usr", BitValue: 3},
+		})
+		return permissions.Metadata{ID: "1", Service: "usr", BitValue: 1}, true, nil
+	})
+
+	if _, found, err := store.Resolve(context.Background(), "usr-users-read"); err != nil || !found {
+		t.Fatalf("Resolve() = %v, %v", found, err)
+	}
+	if meta, _ := store.Lookup("usr-users-read"); meta.BitValue != 2 {
+		t.Fatalf("Lookup(usr-users-read).BitValue = %d, want the loaded 2", meta.BitValue)
+	}
+	if _, ok := store.Lookup("usr-users-list"); !ok {
+		t.Fatalf("store = %+v, want the loaded catalog kept", store.Snapshot())
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"errors"
 	"strings"
 	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 // Metadata contains permission information for authorization.
@@ -26,7 +29,18 @@ var (
 type Store struct {
 	mu     sync.RWMutex
 	byCode map[string]Metadata
-	loader Loader
+	// bare indexes service-qualified entries by their unqualified code when
+	// exactly one service defines it.
+	bare     map[string]Metadata
+	loader   Loader
+	resolver CodeResolver
+
+	// negative cache of codes Resolve could not find; see SetNegativeCache
+	missMu    sync.Mutex
+	misses    map[string]time.Time
+	missTTL   time.Duration
+	missSize  int
+	resolving singleflight.Group
 }
 
 // NewStore creates a new permission store with an optional loader.
@@ -64,25 +78,46 @@ func (s *Store) Load(ctx context.Context) (map[string]Metadata, error) {
 }
 
 // Replace replaces all permissions in the store with the provided map.
+// Service-qualified codes (see QualifiedCode) can also be looked up by their
+// bare code when no other service defines it.
 func (s *Store) Replace(perms map[string]Metadata) {
+	s.clearMisses()
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.byCode, s.bare = indexCodes(perms)
+}
 
+// indexCodes trims the codes of perms and builds the bare-code index over
+// them.
+func indexCodes(perms map[string]Metadata) (map[string]Metadata, map[string]Metadata) {
 	if len(perms) == 0 {
-		s.byCode = make(map[string]Metadata)
-		return
+		return make(map[string]Metadata), nil
 	}
 
 	updated := make(map[string]Metadata, len(perms))
+	bare := make(map[string]Metadata)
+	ambiguous := make(map[string]struct{})
 	for code, meta := range perms {
 		trimmed := strings.TrimSpace(code)
 		if trimmed == "" {
 			continue
 		}
 		updated[trimmed] = meta
+		if _, unqualified, ok := SplitQualifiedCode(trimmed); ok {
+			if _, seen := bare[unqualified]; seen {
+				ambiguous[unqualified] = struct{}{}
+			}
+			bare[unqualified] = meta
+		}
 	}
-
-	s.byCode = updated
+	for code := range bare {
+		if _, ok := ambiguous[code]; ok {
+			delete(bare, code)
+		} else if _, ok := updated[code]; ok {
+			delete(bare, code)
+		}
+	}
+	return updated, bare
 }
 
 // Lookup retrieves permission metadata by code.
@@ -94,10 +129,34 @@ func (s *Store) Lookup(code string) (Metadata, bool) {
 
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	meta, ok := s.byCode[trimmed]
+	if meta, ok := s.byCode[trimmed]; ok {
+		return meta, true
+	}
+	meta, ok := s.bare[trimmed]
 	return meta, ok
 }
 
+// LookupService retrieves the permission metadata of code as defined by
+// service, whether the store holds it under its service-qualified code or,
+// for a single-service store, its bare code.
+func (s *Store) LookupService(service, code string) (Metadata, bool) {
+	trimmed := strings.TrimSpace(code)
+	if strings.TrimSpace(service) == "" || trimmed == "" {
+		return Metadata{}, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if meta, ok := s.byCode[QualifiedCode(service, trimmed)]; ok {
+		return meta, true
+	}
+	meta, ok := s.byCode[trimmed]
+	if !ok || !strings.EqualFold(meta.Service, strings.TrimSpace(service)) {
+		return Metadata{}, false
+	}
+	return meta, true
+}
+
 // ListByService returns all permissions for a given service.
 func (s *Store) ListByService(service string) []Metadata {
 	normalized := strings.TrimSpace(service)
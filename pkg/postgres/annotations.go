@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milan604/core-lab/pkg/logger"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// annotationClause is the clause name QueryAnnotator renders its comment under.
+const annotationClause = "CORELAB_ANNOTATION"
+
+type routeContextKey struct{}
+
+// ContextWithRoute records the route name that QueryAnnotator attaches to queries.
+func ContextWithRoute(ctx context.Context, route string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	route = strings.TrimSpace(route)
+	if route == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, routeContextKey{}, route)
+}
+
+// RouteAnnotationMiddleware stores "METHOD /route/:param" in the request context
+// so queries issued with c.Request.Context() are attributed to the endpoint.
+func RouteAnnotationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if route := c.FullPath(); route != "" {
+			c.Request = c.Request.WithContext(ContextWithRoute(c.Request.Context(), c.Request.Method+" "+route))
+		}
+		c.Next()
+	}
+}
+
+// QueryAnnotator is a GORM plugin that appends a sqlcommenter-style comment to
+// every statement, e.g.
+//
+//	SELECT ... /*application='orders',db_target='replica',route='GET%20%2Forders',traceparent='00-...'*/
+//
+// so DBAs can attribute slow queries in pg_stat_activity and logs to the
+// originating service, endpoint, request, and trace. Register it with
+// db.Client.Use(postgres.NewQueryAnnotator("orders")).
+type QueryAnnotator struct {
+	application string
+}
+
+// NewQueryAnnotator creates an annotator tagging queries with application.
+func NewQueryAnnotator(application string) *QueryAnnotator {
+	return &QueryAnnotator{application: strings.TrimSpace(application)}
+}
+
+// Name implements gorm.Plugin.
+func (a *QueryAnnotator) Name() string { return "corelab:query_annotator" }
+
+// Initialize implements gorm.Plugin.
+func (a *QueryAnnotator) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	registrations := []struct {
+		name     string
+		register func(string, func(*gorm.DB)) error
+	}{
+		{"gorm:create", callbacks.Create().Before("gorm:create").Register},
+		{"gorm:query", callbacks.Query().Before("gorm:query").Register},
+		{"gorm:update", callbacks.Update().Before("gorm:update").Register},
+		{"gorm:delete", callbacks.Delete().Before("gorm:delete").Register},
+		{"gorm:row", callbacks.Row().Before("gorm:row").Register},
+		{"gorm:raw", callbacks.Raw().Before("gorm:raw").Register},
+	}
+	for _, r := range registrations {
+		if err := r.register(a.Name()+":"+r.name, a.annotate); err != nil {
+			return fmt.Errorf("register query annotator before %s: %w", r.name, err)
+		}
+	}
+	return nil
+}
+
+// annotate appends the comment to raw SQL that is already built, or schedules it
+// as a trailing clause so it is rendered after GORM builds the statement.
+func (a *QueryAnnotator) annotate(db *gorm.DB) {
+	stmt := db.Statement
+	if stmt == nil || db.Error != nil {
+		return
+	}
+	comment := a.comment(stmt.Context)
+	if comment == "" {
+		return
+	}
+
+	if stmt.SQL.Len() > 0 {
+		if !strings.HasSuffix(stmt.SQL.String(), comment) {
+			stmt.SQL.WriteString(" " + comment)
+		}
+		return
+	}
+
+	if stmt.Clauses == nil {
+		stmt.Clauses = map[string]clause.Clause{}
+	}
+	stmt.Clauses[annotationClause] = clause.Clause{
+		Name: annotationClause,
+		Builder: func(_ clause.Clause, builder clause.Builder) {
+			builder.WriteString(comment)
+		},
+	}
+	for _, name := range stmt.BuildClauses {
+		if name == annotationClause {
+			return
+		}
+	}
+	// BuildClauses may share its backing array with the callback processor.
+	stmt.BuildClauses = append(append([]string{}, stmt.BuildClauses...), annotationClause)
+}
+
+func (a *QueryAnnotator) comment(ctx context.Context) string {
+	tags := map[string]string{}
+	if a.application != "" {
+		tags["application"] = a.application
+	}
+	if ctx != nil {
+		if target := dbTargetFromContext(ctx); target != "" {
+			tags["db_target"] = target
+		}
+		if route, ok := ctx.Value(routeContextKey{}).(string); ok && route != "" {
+			tags["route"] = route
+		}
+		if requestID, ok := ctx.Value(logger.RequestIDKey).(string); ok && requestID != "" {
+			tags["request_id"] = requestID
+		}
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+			tags["traceparent"] = fmt.Sprintf("00-%s-%s-%s", spanContext.TraceID(), spanContext.SpanID(), spanContext.TraceFlags())
+		}
+	}
+	return formatSQLComment(tags)
+}
+
+// formatSQLComment renders tags in sqlcommenter format. Values are URL-encoded,
+// which also keeps "*/" from terminating the comment early.
+func formatSQLComment(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, url.QueryEscape(key)+"='"+strings.ReplaceAll(url.QueryEscape(tags[key]), "+", "%20")+"'")
+	}
+	return "/*" + strings.Join(parts, ",") + "*/"
+}
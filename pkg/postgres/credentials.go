@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/internal/sigv4"
+)
+
+// PasswordProvider supplies the password for a new connection. It is called
+// every time the pool dials, so implementations can return short-lived tokens.
+type PasswordProvider interface {
+	Password(ctx context.Context) (string, error)
+}
+
+// PasswordProviderFunc adapts a function to PasswordProvider.
+type PasswordProviderFunc func(ctx context.Context) (string, error)
+
+// Password implements PasswordProvider.
+func (f PasswordProviderFunc) Password(ctx context.Context) (string, error) { return f(ctx) }
+
+// AWSCredentials are the AWS keys used to sign RDS IAM auth tokens.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// RDSIAMConfig configures RDSIAMAuth.
+type RDSIAMConfig struct {
+	// Region of the database, e.g. "eu-west-1". Default: AWS_REGION.
+	Region string
+	// Host and Port of the database endpoint; User is the database user
+	// granted rds_iam.
+	Host string
+	Port string
+	User string
+	// Credentials returns the signing credentials. Default: the
+	// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN variables.
+	// Plug in an AWS SDK credentials provider for instance or IRSA roles.
+	Credentials func(ctx context.Context) (AWSCredentials, error)
+	// Now overrides the clock; for tests.
+	Now func() time.Time
+}
+
+// RDSIAMAuth returns a PasswordProvider that generates RDS IAM authentication
+// tokens. Tokens are signed locally (SigV4) and are valid for 15 minutes, so a
+// fresh one is generated per connection. The server must be reached over TLS.
+func RDSIAMAuth(cfg RDSIAMConfig) PasswordProvider {
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_REGION")
+	}
+	if cfg.Credentials == nil {
+		cfg.Credentials = awsCredentialsFromEnv
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return PasswordProviderFunc(func(ctx context.Context) (string, error) {
+		if cfg.Region == "" || cfg.Host == "" || cfg.Port == "" || cfg.User == "" {
+			return "", errors.New("rds iam auth: region, host, port, and user are required")
+		}
+		creds, err := cfg.Credentials(ctx)
+		if err != nil {
+			return "", fmt.Errorf("rds iam auth: credentials: %w", err)
+		}
+		if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+			return "", errors.New("rds iam auth: credentials are empty")
+		}
+		return buildRDSAuthToken(net.JoinHostPort(cfg.Host, cfg.Port), cfg.Region, cfg.User, creds, cfg.Now().UTC()), nil
+	})
+}
+
+func awsCredentialsFromEnv(context.Context) (AWSCredentials, error) {
+	creds := AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return AWSCredentials{}, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set")
+	}
+	return creds, nil
+}
+
+// buildRDSAuthToken presigns "connect" for the rds-db service, which is what
+// the AWS SDKs' BuildAuthToken does.
+func buildRDSAuthToken(endpoint, region, user string, creds AWSCredentials, now time.Time) string {
+	query := sigv4.Presign(endpoint, "/", map[string]string{"Action": "connect", "DBUser": user},
+		sigv4.HashHex(nil), 15*time.Minute, sigv4.Credentials(creds), region, "rds-db", now)
+	return endpoint + "?" + query
+}
+
+// Azure Database for PostgreSQL token audience.
+const azurePostgresResource = "https://ossrdbms-aad.database.windows.net"
+
+// AzureManagedIdentityConfig configures AzureManagedIdentity.
+type AzureManagedIdentityConfig struct {
+	// ClientID selects a user-assigned identity; empty uses the system-assigned one.
+	ClientID string
+	// Resource is the token audience. Default: Azure Database for PostgreSQL.
+	Resource string
+	// HTTPClient calls the identity endpoint. Default: a client with a 10s timeout.
+	HTTPClient *http.Client
+}
+
+// AzureManagedIdentity returns a PasswordProvider that fetches Microsoft Entra
+// access tokens from the managed identity endpoint: IDENTITY_ENDPOINT on App
+// Service and Container Apps, otherwise the VM/AKS instance metadata service.
+// Tokens are cached until five minutes before they expire.
+func AzureManagedIdentity(cfg AzureManagedIdentityConfig) PasswordProvider {
+	if cfg.Resource == "" {
+		cfg.Resource = azurePostgresResource
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &cachedToken{fetch: cfg.fetch}
+}
+
+func (cfg AzureManagedIdentityConfig) fetch(ctx context.Context) (string, time.Time, error) {
+	var (
+		endpoint string
+		header   http.Header
+		params   = url.Values{"resource": {cfg.Resource}}
+	)
+	if identityEndpoint, identityHeader := os.Getenv("IDENTITY_ENDPOINT"), os.Getenv("IDENTITY_HEADER"); identityEndpoint != "" && identityHeader != "" {
+		endpoint = identityEndpoint
+		header = http.Header{"X-Identity-Header": {identityHeader}}
+		params.Set("api-version", "2019-08-01")
+	} else {
+		endpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+		header = http.Header{"Metadata": {"true"}}
+		params.Set("api-version", "2018-02-01")
+	}
+	if cfg.ClientID != "" {
+		params.Set("client_id", cfg.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header = header
+	resp, err := cfg.HTTPClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("azure managed identity: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("azure managed identity: token endpoint returned %s", resp.Status)
+	}
+
+	var body struct {
+		AccessToken string          `json:"access_token"`
+		ExpiresOn   json.RawMessage `json:"expires_on"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", time.Time{}, fmt.Errorf("azure managed identity: decode token: %w", err)
+	}
+	if body.AccessToken == "" {
+		return "", time.Time{}, errors.New("azure managed identity: empty access token")
+	}
+	// expires_on is epoch seconds, as a JSON string or number.
+	expiresOn, err := strconv.ParseInt(strings.Trim(string(body.ExpiresOn), `"`), 10, 64)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("azure managed identity: parse expires_on: %w", err)
+	}
+	return body.AccessToken, time.Unix(expiresOn, 0), nil
+}
+
+// tokenRefreshMargin is how long before expiry a cached token is replaced.
+const tokenRefreshMargin = 5 * time.Minute
+
+// cachedToken is a PasswordProvider that reuses a fetched token until shortly
+// before it expires.
+type cachedToken struct {
+	fetch func(ctx context.Context) (string, time.Time, error)
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func (c *cachedToken) Password(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Now().Add(tokenRefreshMargin).Before(c.expiresAt) {
+		return c.token, nil
+	}
+	token, expiresAt, err := c.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.expiresAt = token, expiresAt
+	return token, nil
+}
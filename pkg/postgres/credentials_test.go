@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRDSIAMAuthToken(t *testing.T) {
+	provider := RDSIAMAuth(RDSIAMConfig{
+		Region: "eu-west-1",
+		Host:   "orders.abc123.eu-west-1.rds.amazonaws.com",
+		Port:   "5432",
+		User:   "orders_app",
+		Credentials: func(context.Context) (AWSCredentials, error) {
+			return AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", SessionToken: "session/token"}, nil
+		},
+		Now: func() time.Time { return time.Date(2026, 10, 16, 9, 30, 0, 0, time.FixedZone("NPT", 5*3600+45*60)) },
+	})
+	token, err := provider.Password(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint, rawQuery, ok := strings.Cut(token, "?")
+	if !ok || endpoint != "orders.abc123.eu-west-1.rds.amazonaws.com:5432" {
+		t.Fatalf("token = %s", token)
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		"Action":               "connect",
+		"DBUser":               "orders_app",
+		"X-Amz-Credential":     "AKIDEXAMPLE/20261016/eu-west-1/rds-db/aws4_request",
+		"X-Amz-Date":           "20261016T034500Z",
+		"X-Amz-Expires":        "900",
+		"X-Amz-Security-Token": "session/token",
+	} {
+		if got := query.Get(key); got != want {
+			t.Fatalf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if len(query.Get("X-Amz-Signature")) != 64 {
+		t.Fatalf("signature = %q", query.Get("X-Amz-Signature"))
+	}
+
+	if _, err := RDSIAMAuth(RDSIAMConfig{Region: "eu-west-1"}).Password(context.Background()); err == nil {
+		t.Fatal("expected an error without host, port and user")
+	}
+}
@@ -1,117 +1,280 @@
 package postgres
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io/fs"
+	"sync/atomic"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	migratepostgres "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/milan604/core-lab/pkg/internal/sqldb"
+	"github.com/milan604/core-lab/pkg/logger"
 )
 
 const filePrefix = "file://"
-const errCreateMigration = "Failed to create migration instance: %v"
 
-// RunMigrationsUp applies all up migrations
-func (db *DB) RunMigrationsUp(migrationsPath string) error {
-	fmt.Println("Running up migrations...", maskDSN(db.DSN))
-	m, err := migrate.New(
-		filePrefix+migrationsPath,
-		db.DSN,
-	)
-	if err != nil {
-		log.Printf(errCreateMigration, err)
-		return err
+// MigrationSource is where Migrate reads migration files from: a directory on
+// disk (MigrationsDir) or an fs.FS such as an embed.FS (MigrationsFS), so the
+// SQL can ship inside the binary.
+type MigrationSource struct {
+	fsys  fs.FS
+	dir   string
+	table string
+}
+
+// MigrationsDir reads migrations from the directory at path.
+func MigrationsDir(path string) MigrationSource {
+	return MigrationSource{dir: path}
+}
+
+// MigrationsFS reads migrations from dir within fsys ("." for its root).
+//
+//	//go:embed migrations/*.sql
+//	var migrationFiles embed.FS
+//
+//	err := db.Migrate(ctx, postgres.MigrationsFS(migrationFiles, "migrations"), postgres.MigrateUp())
+func MigrationsFS(fsys fs.FS, dir string) MigrationSource {
+	if dir == "" {
+		dir = "."
 	}
-	if err := m.Up(); err != nil {
-		if err.Error() != "no change" {
-			log.Printf("Failed to apply up migrations: %v", err)
-			return err
-		}
-		log.Println("No new up migrations to apply")
-	} else {
-		log.Println("Up migrations applied successfully")
+	return MigrationSource{fsys: fsys, dir: dir}
+}
+
+// WithMigrationsTable records the applied version in table instead of
+// schema_migrations, so migrations shipped by a library are versioned
+// independently of the application's own.
+func (s MigrationSource) WithMigrationsTable(table string) MigrationSource {
+	s.table = table
+	return s
+}
+
+func (s MigrationSource) String() string {
+	if s.fsys != nil {
+		return "fs:" + s.dir
 	}
-	return nil
+	return filePrefix + s.dir
 }
 
-// RunMigrationsDown rolls back all migrations
-func (db *DB) RunMigrationsDown(migrationsPath string) error {
-	fmt.Println("Running down migrations...", maskDSN(db.DSN))
-	m, err := migrate.New(
-		filePrefix+migrationsPath,
-		db.DSN,
-	)
-	if err != nil {
-		log.Printf(errCreateMigration, err)
-		return err
+func (s MigrationSource) open() (source.Driver, error) {
+	if s.fsys != nil {
+		return iofs.New(s.fsys, s.dir)
 	}
-	if err := m.Down(); err != nil {
-		if err.Error() != "no change" {
-			log.Printf("Failed to apply down migrations: %v", err)
-			return err
-		}
-		log.Println("No down migrations to apply")
-	} else {
-		log.Println("Down migrations applied successfully")
+	return source.Open(filePrefix + s.dir)
+}
+
+type migrationAction int
+
+const (
+	migrateUp migrationAction = iota
+	migrateDown
+	migrateTo
+	migrateSteps
+	migrateForce
+)
+
+// MigrationTarget is what Migrate brings the schema to.
+type MigrationTarget struct {
+	action  migrationAction
+	version uint
+	// steps is the step count of MigrateSteps or the version of MigrateForce.
+	steps int
+}
+
+// MigrateUp applies every pending up migration.
+func MigrateUp() MigrationTarget { return MigrationTarget{action: migrateUp} }
+
+// MigrateDown rolls back every applied migration.
+func MigrateDown() MigrationTarget { return MigrationTarget{action: migrateDown} }
+
+// MigrateTo migrates up or down to version.
+func MigrateTo(version uint) MigrationTarget {
+	return MigrationTarget{action: migrateTo, version: version}
+}
+
+// MigrateSteps applies n up migrations, or rolls back -n when n is negative.
+func MigrateSteps(n int) MigrationTarget { return MigrationTarget{action: migrateSteps, steps: n} }
+
+// MigrateForce records version as applied and clears the dirty flag without
+// running any migration, to recover from a failed one. -1 marks no migration
+// as applied.
+func MigrateForce(version int) MigrationTarget {
+	return MigrationTarget{action: migrateForce, steps: version}
+}
+
+func (t MigrationTarget) String() string {
+	switch t.action {
+	case migrateDown:
+		return "down"
+	case migrateTo:
+		return fmt.Sprintf("version %d", t.version)
+	case migrateSteps:
+		return fmt.Sprintf("%d steps", t.steps)
+	case migrateForce:
+		return fmt.Sprintf("force version %d", t.steps)
 	}
-	return nil
+	return "up"
 }
 
-// RunMigrationsSteps applies N up or down steps
-func (db *DB) RunMigrationsSteps(migrationsPath string, steps int) error {
-	fmt.Println("Running migration steps...", maskDSN(db.DSN))
-	m, err := migrate.New(
-		filePrefix+migrationsPath,
-		db.DSN,
-	)
+// Migrate brings the schema to target using the migrations in source. It
+// runs on a connection from the primary pool, so migrations use the same
+// credentials and TLS settings as queries, including PasswordProvider and
+// rotated passwords. Concurrent runs are serialized by an advisory lock.
+// Reaching the target with nothing to apply is not an error. Cancelling ctx
+// stops after the migration in progress.
+//
+// Progress is logged through the logger passed to New with WithLogger, or a
+// default logger, with the fields migration_source, migration_target,
+// from_version, to_version, dirty and duration_ms; golang-migrate's per-file
+// progress goes to the debug level.
+func (db *DB) Migrate(ctx context.Context, src MigrationSource, target MigrationTarget) error {
+	log := db.migrationLogger().With("migration_source", src.String(), "migration_target", target.String())
+	m, closeMigrate, err := db.newMigrate(ctx, src, log)
 	if err != nil {
-		log.Printf(errCreateMigration, err)
-		return err
-	}
-	if err := m.Steps(steps); err != nil {
-		log.Printf("Failed to apply migration steps: %v", err)
+		log.ErrorFCtx(ctx, "postgres: open migrations: %v", err)
 		return err
 	}
-	log.Printf("Migration steps (%d) applied successfully", steps)
-	return nil
-}
+	defer closeMigrate()
 
-// RunMigrationsForce forces the migration version
-func (db *DB) RunMigrationsForce(migrationsPath string, version int) error {
-	fmt.Println("Forcing migration version...", maskDSN(db.DSN))
-	m, err := migrate.New(
-		filePrefix+migrationsPath,
-		db.DSN,
-	)
+	from, _, err := migrationVersion(m)
 	if err != nil {
-		log.Printf(errCreateMigration, err)
+		log.ErrorFCtx(ctx, "postgres: read migration version: %v", err)
 		return err
 	}
-	if err := m.Force(version); err != nil {
-		log.Printf("Failed to force migration version: %v", err)
-		return err
+
+	var stopped atomic.Bool
+	stop := context.AfterFunc(ctx, func() {
+		stopped.Store(true)
+		select {
+		case m.GracefulStop <- true:
+		default:
+		}
+	})
+	defer stop()
+
+	start := time.Now()
+	switch target.action {
+	case migrateDown:
+		err = m.Down()
+	case migrateTo:
+		err = m.Migrate(target.version)
+	case migrateSteps:
+		err = m.Steps(target.steps)
+	case migrateForce:
+		err = m.Force(target.steps)
+	default:
+		err = m.Up()
+	}
+	if err == nil && stopped.Load() {
+		err = ctx.Err()
+	}
+
+	to, dirty, verErr := migrationVersion(m)
+	log = log.With("from_version", from, "to_version", to, "dirty", dirty, "duration_ms", time.Since(start).Milliseconds())
+	switch {
+	case errors.Is(err, migrate.ErrNoChange):
+		log.InfoFCtx(ctx, "postgres: migrations already at target")
+		return nil
+	case err != nil:
+		log.ErrorFCtx(ctx, "postgres: migrate: %v", err)
+		return fmt.Errorf("postgres: migrate %s: %w", target, err)
+	case verErr != nil:
+		return verErr
 	}
-	log.Printf("Migration version forced to %d", version)
+	log.InfoFCtx(ctx, "postgres: migrations applied")
 	return nil
 }
 
-// RunMigrationsVersion returns the current migration version
-func (db *DB) RunMigrationsVersion(migrationsPath string) (uint, bool, error) {
-	fmt.Println("Getting migration version...", maskDSN(db.DSN))
-	m, err := migrate.New(
-		filePrefix+migrationsPath,
-		db.DSN,
-	)
+// MigrationVersion returns the current schema version and whether the last
+// migration failed part way (dirty). A schema without migrations reports 0.
+func (db *DB) MigrationVersion(ctx context.Context, src MigrationSource) (version uint, dirty bool, err error) {
+	m, closeMigrate, err := db.newMigrate(ctx, src, db.migrationLogger())
 	if err != nil {
-		log.Printf(errCreateMigration, err)
 		return 0, false, err
 	}
-	version, dirty, err := m.Version()
+	defer closeMigrate()
+	return migrationVersion(m)
+}
+
+func (db *DB) newMigrate(ctx context.Context, src MigrationSource, log logger.LogManager) (*migrate.Migrate, func(), error) {
+	sourceDriver, err := src.open()
 	if err != nil {
-		log.Printf("Failed to get migration version: %v", err)
-		return 0, false, err
+		return nil, nil, fmt.Errorf("postgres: open migration source %s: %w", src, err)
+	}
+	conn, err := db.SQL.Conn(ctx)
+	if err != nil {
+		_ = sourceDriver.Close()
+		return nil, nil, fmt.Errorf("postgres: acquire migration connection: %w", err)
+	}
+	// The driver closes conn, returning it to the pool, but never db.SQL.
+	dbDriver, err := migratepostgres.WithConnection(ctx, conn, &migratepostgres.Config{MigrationsTable: src.table})
+	if err != nil {
+		_ = conn.Close()
+		_ = sourceDriver.Close()
+		return nil, nil, fmt.Errorf("postgres: prepare migrations: %w", err)
 	}
-	log.Printf("Current migration version: %d (dirty: %v)", version, dirty)
-	return version, dirty, nil
+	m, err := migrate.NewWithInstance("source", sourceDriver, "postgres", dbDriver)
+	if err != nil {
+		_ = dbDriver.Close()
+		_ = sourceDriver.Close()
+		return nil, nil, fmt.Errorf("postgres: prepare migrations: %w", err)
+	}
+	m.Log = sqldb.MigrateLog{Log: log, Ctx: ctx, Prefix: "postgres: "}
+	return m, func() { _, _ = m.Close() }, nil
+}
+
+func migrationVersion(m *migrate.Migrate) (uint, bool, error) {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+func (db *DB) migrationLogger() logger.LogManager {
+	if db.log != nil {
+		return db.log
+	}
+	return logger.MustNewDefaultLogger()
+}
+
+// RunMigrationsUp applies all up migrations from the directory at
+// migrationsPath.
+//
+// Deprecated: use Migrate with MigrationsDir and MigrateUp.
+func (db *DB) RunMigrationsUp(migrationsPath string) error {
+	return db.Migrate(context.Background(), MigrationsDir(migrationsPath), MigrateUp())
+}
+
+// RunMigrationsDown rolls back all migrations.
+//
+// Deprecated: use Migrate with MigrationsDir and MigrateDown.
+func (db *DB) RunMigrationsDown(migrationsPath string) error {
+	return db.Migrate(context.Background(), MigrationsDir(migrationsPath), MigrateDown())
+}
+
+// RunMigrationsSteps applies N up or down steps.
+//
+// Deprecated: use Migrate with MigrationsDir and MigrateSteps.
+func (db *DB) RunMigrationsSteps(migrationsPath string, steps int) error {
+	return db.Migrate(context.Background(), MigrationsDir(migrationsPath), MigrateSteps(steps))
+}
+
+// RunMigrationsForce forces the migration version.
+//
+// Deprecated: use Migrate with MigrationsDir and MigrateForce.
+func (db *DB) RunMigrationsForce(migrationsPath string, version int) error {
+	return db.Migrate(context.Background(), MigrationsDir(migrationsPath), MigrateForce(version))
+}
+
+// RunMigrationsVersion returns the current migration version.
+//
+// Deprecated: use MigrationVersion.
+func (db *DB) RunMigrationsVersion(migrationsPath string) (uint, bool, error) {
+	return db.MigrationVersion(context.Background(), MigrationsDir(migrationsPath))
 }
@@ -0,0 +1,280 @@
+// Package migrations lints golang-migrate SQL migrations before they reach a
+// database: file naming and version ordering, up/down pairing, schema
+// ownership, and statements that take long or blocking locks on live tables.
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Rule identifies a lint check. Statement rules can be suppressed for one
+// statement with a "-- lint:ignore <rule>" comment.
+type Rule string
+
+const (
+	// RuleNaming requires files named <version>_<title>.up.sql / .down.sql with
+	// a lowercase snake_case title and versions of one width.
+	RuleNaming Rule = "naming"
+	// RuleVersionOrder rejects duplicate versions and warns on gaps between
+	// sequential versions.
+	RuleVersionOrder Rule = "version-order"
+	// RuleMissingDown requires a down file for every up file, and an up file
+	// for every down file.
+	RuleMissingDown Rule = "missing-down"
+	// RuleIndexConcurrently requires CREATE INDEX CONCURRENTLY on existing
+	// tables, warns on DROP INDEX without CONCURRENTLY, and rejects
+	// CONCURRENTLY statements that share a file with other statements, since
+	// Postgres runs a multi-statement file as one transaction.
+	RuleIndexConcurrently Rule = "index-concurrently"
+	// RuleColumnTypeChange requires a "-- lock-note:" comment on ALTER COLUMN
+	// ... TYPE, which rewrites the table under an ACCESS EXCLUSIVE lock.
+	RuleColumnTypeChange Rule = "column-type-change"
+	// RuleSchemaOwnership rejects DDL and writes against schemas the service
+	// does not own (see WithOwnedSchemas).
+	RuleSchemaOwnership Rule = "schema-ownership"
+)
+
+// Severity of an Issue. Errors fail Report.Err; warnings do not, unless
+// WithWarningsAsErrors is set.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is one lint finding. Line is 0 for file-level findings.
+type Issue struct {
+	File     string
+	Line     int
+	Rule     Rule
+	Severity Severity
+	Message  string
+}
+
+// String formats the issue as "file:line: severity [rule] message".
+func (i Issue) String() string {
+	location := i.File
+	if i.Line > 0 {
+		location += ":" + strconv.Itoa(i.Line)
+	}
+	return fmt.Sprintf("%s: %s [%s] %s", location, i.Severity, i.Rule, i.Message)
+}
+
+// Report is the result of Lint.
+type Report struct {
+	// Migrations is the number of migration versions found.
+	Migrations int
+	Issues     []Issue
+}
+
+// Errors returns the issues with SeverityError.
+func (r Report) Errors() []Issue {
+	var out []Issue
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			out = append(out, issue)
+		}
+	}
+	return out
+}
+
+// Err returns the error issues joined into one error, or nil. Use it to fail
+// a test:
+//
+//	report, err := migrations.Lint(os.DirFS("migrations"), migrations.WithOwnedSchemas("billing"))
+//	if err == nil {
+//		err = report.Err()
+//	}
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+func (r Report) Err() error {
+	var errs []error
+	for _, issue := range r.Errors() {
+		errs = append(errs, errors.New(issue.String()))
+	}
+	return errors.Join(errs...)
+}
+
+// Option configures Lint.
+type Option func(*options)
+
+type options struct {
+	dir              string
+	ownedSchemas     map[string]bool
+	allowMissingDown bool
+	warningsAsErrors bool
+}
+
+// WithDir lints the migrations in dir within the file system instead of its root.
+func WithDir(dir string) Option {
+	return func(o *options) {
+		o.dir = dir
+	}
+}
+
+// WithOwnedSchemas enables RuleSchemaOwnership: schema-qualified objects must
+// be in one of schemas. Unqualified names resolve through the search_path
+// and are not checked.
+func WithOwnedSchemas(schemas ...string) Option {
+	return func(o *options) {
+		if o.ownedSchemas == nil {
+			o.ownedSchemas = make(map[string]bool, len(schemas))
+		}
+		for _, schema := range schemas {
+			if schema = strings.ToLower(strings.TrimSpace(schema)); schema != "" {
+				o.ownedSchemas[schema] = true
+			}
+		}
+	}
+}
+
+// WithAllowMissingDown disables RuleMissingDown for forward-only migrations.
+func WithAllowMissingDown() Option {
+	return func(o *options) {
+		o.allowMissingDown = true
+	}
+}
+
+// WithWarningsAsErrors reports every issue as an error.
+func WithWarningsAsErrors() Option {
+	return func(o *options) {
+		o.warningsAsErrors = true
+	}
+}
+
+var fileNameRe = regexp.MustCompile(`^([0-9]+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// migration is one version: its up and down file names.
+type migration struct {
+	version uint64
+	digits  int
+	title   string
+	up      string
+	down    string
+}
+
+// Lint checks the *.sql migrations in fsys (golang-migrate layout) and
+// returns the findings. The error is non-nil only if the files cannot be
+// read. Files other than *.sql are ignored.
+func Lint(fsys fs.FS, opts ...Option) (Report, error) {
+	o := options{dir: "."}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	entries, err := fs.ReadDir(fsys, o.dir)
+	if err != nil {
+		return Report{}, fmt.Errorf("migrations: read %s: %w", o.dir, err)
+	}
+
+	l := &linter{opts: o}
+	byVersion := map[uint64]*migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+		m := fileNameRe.FindStringSubmatch(name)
+		if m == nil {
+			l.add(name, 0, RuleNaming, SeverityError, "file name must be <version>_<snake_case_title>.up.sql or .down.sql")
+			continue
+		}
+		version, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			l.add(name, 0, RuleNaming, SeverityError, "version does not fit in 64 bits")
+			continue
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &migration{version: version, digits: len(m[1]), title: m[2]}
+			byVersion[version] = mig
+		} else if mig.title != m[2] || mig.digits != len(m[1]) {
+			l.add(name, 0, RuleVersionOrder, SeverityError,
+				fmt.Sprintf("version %d is also used by %s", version, mig.fileName()))
+			continue
+		}
+		if m[3] == "up" {
+			mig.up = name
+		} else {
+			mig.down = name
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(o.dir, name))
+		if err != nil {
+			return Report{}, fmt.Errorf("migrations: read %s: %w", name, err)
+		}
+		l.lintSQL(name, string(content))
+	}
+
+	migrations := make([]*migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		migrations = append(migrations, mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	l.lintVersions(migrations)
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		if l.issues[i].File != l.issues[j].File {
+			return l.issues[i].File < l.issues[j].File
+		}
+		return l.issues[i].Line < l.issues[j].Line
+	})
+	return Report{Migrations: len(migrations), Issues: l.issues}, nil
+}
+
+func (m *migration) fileName() string {
+	if m.up != "" {
+		return m.up
+	}
+	return m.down
+}
+
+// sequentialDigits is the widest version still treated as a sequence
+// (000001); wider versions are timestamps, where gaps are expected.
+const sequentialDigits = 6
+
+func (l *linter) lintVersions(migrations []*migration) {
+	for i, mig := range migrations {
+		if !l.opts.allowMissingDown && mig.down == "" {
+			l.add(mig.up, 0, RuleMissingDown, SeverityError, "no matching .down.sql file")
+		}
+		if mig.up == "" {
+			l.add(mig.down, 0, RuleMissingDown, SeverityError, "no matching .up.sql file")
+		}
+		if i == 0 {
+			continue
+		}
+		prev := migrations[i-1]
+		if mig.digits != prev.digits {
+			l.add(mig.fileName(), 0, RuleNaming, SeverityError,
+				fmt.Sprintf("version width %d differs from %s; use one numbering style", mig.digits, prev.fileName()))
+			continue
+		}
+		if mig.digits <= sequentialDigits && mig.version != prev.version+1 {
+			l.add(mig.fileName(), 0, RuleVersionOrder, SeverityWarning,
+				fmt.Sprintf("version %d does not follow %d", mig.version, prev.version))
+		}
+	}
+}
+
+type linter struct {
+	opts   options
+	issues []Issue
+}
+
+func (l *linter) add(file string, line int, rule Rule, severity Severity, message string) {
+	if l.opts.warningsAsErrors {
+		severity = SeverityError
+	}
+	l.issues = append(l.issues, Issue{File: file, Line: line, Rule: rule, Severity: severity, Message: message})
+}
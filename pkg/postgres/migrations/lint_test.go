@@ -0,0 +1,131 @@
+package migrations
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLintReportsDangerousStatementsAndOwnership(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000001_create_invoices.up.sql": {Data: []byte(`
+CREATE TABLE billing.invoices (id bigint PRIMARY KEY, total numeric);
+-- new table, so a plain index is fine
+CREATE INDEX invoices_total_idx ON billing.invoices (total);
+`)},
+		"000001_create_invoices.down.sql": {Data: []byte(`DROP TABLE billing.invoices;`)},
+		"000002_tune_invoices.up.sql": {Data: []byte(`
+CREATE INDEX invoices_id_total_idx ON billing.invoices (id, total);
+
+ALTER TABLE billing.invoices ALTER COLUMN total TYPE bigint;
+
+-- lock-note: invoices has under 1k rows in every region
+ALTER TABLE billing.invoices ALTER COLUMN id SET DATA TYPE numeric;
+
+-- lint:ignore index-concurrently
+CREATE INDEX invoices_legacy_idx ON billing.invoices (id);
+
+INSERT INTO accounts.audit (note) VALUES ('CREATE INDEX x ON y (z); from a string');
+
+CREATE FUNCTION billing.touch() RETURNS trigger AS $$
+BEGIN
+  CREATE INDEX inside_body ON other.t (id);
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+`)},
+		"000002_tune_invoices.down.sql": {Data: []byte(`DROP INDEX invoices_id_total_idx;`)},
+		"000003_concurrent_index.up.sql": {Data: []byte(`
+BEGIN;
+CREATE INDEX CONCURRENTLY invoices_created_idx ON billing.invoices (id);
+COMMIT;
+`)},
+		"000003_concurrent_index.down.sql": {Data: []byte(`DROP INDEX CONCURRENTLY invoices_created_idx;`)},
+		"README.md":                        {Data: []byte("not a migration")},
+	}
+
+	report, err := Lint(fsys, WithOwnedSchemas("billing"))
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	if report.Migrations != 3 {
+		t.Fatalf("migrations = %d, want 3", report.Migrations)
+	}
+
+	got := make([]string, 0, len(report.Issues))
+	for _, issue := range report.Issues {
+		got = append(got, issue.File+":"+strconv.Itoa(issue.Line)+" "+string(issue.Severity)+" "+string(issue.Rule))
+	}
+	want := []string{
+		"000002_tune_invoices.down.sql:1 warning index-concurrently",
+		"000002_tune_invoices.up.sql:2 error index-concurrently",
+		"000002_tune_invoices.up.sql:4 error column-type-change",
+		"000002_tune_invoices.up.sql:12 error schema-ownership",
+		"000003_concurrent_index.up.sql:3 error index-concurrently",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if err := report.Err(); err == nil || !strings.Contains(err.Error(), `schema "accounts" is not owned`) {
+		t.Fatalf("Err() = %v", err)
+	}
+}
+
+func TestLintChecksNamingPairingAndOrder(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0001_init.up.sql":           {Data: []byte(`CREATE TABLE a (id int);`)},
+		"migrations/0001_init.down.sql":         {Data: []byte(`DROP TABLE a;`)},
+		"migrations/0001_other.up.sql":          {Data: []byte(`CREATE TABLE b (id int);`)},
+		"migrations/0003_add_c.up.sql":          {Data: []byte(`CREATE TABLE c (id int);`)},
+		"migrations/0004_drop_d.down.sql":       {Data: []byte(`CREATE TABLE d (id int);`)},
+		"migrations/20240101120000_ts.up.sql":   {Data: []byte(`SELECT 1;`)},
+		"migrations/20240101120000_ts.down.sql": {Data: []byte(`SELECT 1;`)},
+		"migrations/5_AddThing.up.sql":          {Data: []byte(`SELECT 1;`)},
+	}
+
+	report, err := Lint(fsys, WithDir("migrations"))
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	rules := map[string][]Rule{}
+	for _, issue := range report.Issues {
+		rules[issue.File] = append(rules[issue.File], issue.Rule)
+	}
+	want := map[string][]Rule{
+		"0001_other.up.sql":        {RuleVersionOrder},
+		"0003_add_c.up.sql":        {RuleMissingDown, RuleVersionOrder},
+		"0004_drop_d.down.sql":     {RuleMissingDown},
+		"20240101120000_ts.up.sql": {RuleNaming},
+		"5_AddThing.up.sql":        {RuleNaming},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("issues = %v, want %v", report.Issues, want)
+	}
+	for file, wantRules := range want {
+		if strings.Join(ruleNames(rules[file]), ",") != strings.Join(ruleNames(wantRules), ",") {
+			t.Fatalf("%s rules = %v, want %v (issues %v)", file, rules[file], wantRules, report.Issues)
+		}
+	}
+
+	report, err = Lint(fsys, WithDir("migrations"), WithAllowMissingDown(), WithWarningsAsErrors())
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	for _, issue := range report.Issues {
+		if issue.Rule == RuleMissingDown && issue.File == "0003_add_c.up.sql" {
+			t.Fatalf("missing down reported with WithAllowMissingDown: %v", issue)
+		}
+		if issue.Severity != SeverityError {
+			t.Fatalf("warning kept with WithWarningsAsErrors: %v", issue)
+		}
+	}
+}
+
+func ruleNames(rules []Rule) []string {
+	out := make([]string, len(rules))
+	for i, rule := range rules {
+		out[i] = string(rule)
+	}
+	return out
+}
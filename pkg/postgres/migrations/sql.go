@@ -0,0 +1,266 @@
+package migrations
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// statement is one SQL statement with string literals, dollar-quoted bodies,
+// and comments blanked out of code, and the comments that precede or appear
+// in it collected separately.
+type statement struct {
+	line     int
+	code     string
+	comments []string
+}
+
+// splitStatements splits src at top-level semicolons.
+func splitStatements(src string) []statement {
+	var (
+		out      []statement
+		code     strings.Builder
+		comments []string
+		line     = 1
+		start    = 0
+	)
+	flush := func() {
+		if text := strings.TrimSpace(code.String()); text != "" {
+			out = append(out, statement{line: start, code: text, comments: comments})
+			comments = nil
+		}
+		code.Reset()
+		start = 0
+	}
+	mark := func() {
+		if start == 0 {
+			start = line
+		}
+	}
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			code.WriteByte(c)
+		case c == '-' && strings.HasPrefix(src[i:], "--"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			comments = append(comments, strings.TrimSpace(src[i+2:i+end]))
+			i += end - 1
+		case c == '/' && strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				end = len(src) - i - 2
+			}
+			body := src[i+2 : i+2+end]
+			comments = append(comments, strings.TrimSpace(body))
+			line += strings.Count(body, "\n")
+			code.WriteByte(' ')
+			i += end + 3
+		case c == '\'' || c == '"':
+			mark()
+			end := i + 1
+			for end < len(src) {
+				if src[end] == c {
+					if end+1 < len(src) && src[end+1] == c {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			body := src[i+1 : min(end, len(src))]
+			line += strings.Count(body, "\n")
+			if c == '"' {
+				// quoted identifiers stay in code so names can be checked
+				code.WriteString(`"` + body + `"`)
+			} else {
+				code.WriteString("''")
+			}
+			i = end
+		case c == '$':
+			tag := dollarTagRe.FindString(src[i:])
+			if tag == "" {
+				mark()
+				code.WriteByte(c)
+				continue
+			}
+			mark()
+			end := strings.Index(src[i+len(tag):], tag)
+			if end < 0 {
+				end = len(src) - i - len(tag)
+			}
+			line += strings.Count(src[i:i+len(tag)+end], "\n")
+			code.WriteString("$$ $$")
+			i += len(tag) + end + len(tag) - 1
+		case c == ';':
+			flush()
+		default:
+			if c != ' ' && c != '\t' && c != '\r' {
+				mark()
+			}
+			code.WriteByte(c)
+		}
+	}
+	flush()
+	return out
+}
+
+var dollarTagRe = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// ignores reports whether the statement's comments suppress rule.
+func (s statement) ignores(rule Rule) bool {
+	for _, comment := range s.comments {
+		if fields := strings.Fields(comment); len(fields) > 1 && fields[0] == "lint:ignore" {
+			for _, name := range fields[1:] {
+				if Rule(strings.TrimSuffix(name, ",")) == rule {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// hasLockNote reports whether the statement carries a "-- lock-note: <reason>" comment.
+func (s statement) hasLockNote() bool {
+	for _, comment := range s.comments {
+		if note, ok := strings.CutPrefix(comment, "lock-note:"); ok && strings.TrimSpace(note) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	identPattern     = `(?:"[^"]+"|[A-Za-z_][A-Za-z0-9_$]*)`
+	qualifiedPattern = identPattern + `(?:\s*\.\s*` + identPattern + `)?`
+)
+
+var (
+	createTableRe     = regexp.MustCompile(`(?i)^CREATE\s+(?:(?:GLOBAL\s+|LOCAL\s+)?(?:TEMP|TEMPORARY|UNLOGGED)\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(` + qualifiedPattern + `)`)
+	createIndexRe     = regexp.MustCompile(`(?i)^CREATE\s+(?:UNIQUE\s+)?INDEX\b`)
+	dropIndexRe       = regexp.MustCompile(`(?i)^DROP\s+INDEX\b`)
+	concurrentlyRe    = regexp.MustCompile(`(?is)^(?:CREATE\s+(?:UNIQUE\s+)?INDEX|DROP\s+INDEX|REINDEX\b.*?)\s+CONCURRENTLY\b`)
+	indexTableRe      = regexp.MustCompile(`(?is)\bON\s+(?:ONLY\s+)?(` + qualifiedPattern + `)`)
+	alterTableRe      = regexp.MustCompile(`(?i)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?(` + qualifiedPattern + `)`)
+	alterColumnTypeRe = regexp.MustCompile(`(?i)\bALTER\s+(?:COLUMN\s+)?` + identPattern + `\s+(?:SET\s+DATA\s+)?TYPE\b`)
+)
+
+// ownershipRes match statements that change an object, capturing the schema
+// of the object changed: DDL on schema-qualified objects, indexes, triggers,
+// and policies on qualified tables, writes to qualified tables, and schemas
+// themselves.
+var ownershipRes = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)^(?:CREATE|ALTER|DROP)\s+(?:OR\s+REPLACE\s+)?(?:(?:GLOBAL\s+|LOCAL\s+)?(?:TEMP|TEMPORARY|UNLOGGED)\s+|MATERIALIZED\s+|UNIQUE\s+)?` +
+		`(?:TABLE|INDEX|VIEW|SEQUENCE|TYPE|DOMAIN|FUNCTION|PROCEDURE|AGGREGATE)\s+(?:CONCURRENTLY\s+)?(?:IF\s+(?:NOT\s+)?EXISTS\s+)?(?:ONLY\s+)?(` + identPattern + `)\s*\.`),
+	regexp.MustCompile(`(?is)^(?:CREATE\s+(?:UNIQUE\s+)?INDEX|CREATE\s+(?:OR\s+REPLACE\s+)?(?:CONSTRAINT\s+)?TRIGGER|(?:CREATE|ALTER|DROP)\s+POLICY|DROP\s+TRIGGER)\b.*?\bON\s+(?:ONLY\s+)?(` + identPattern + `)\s*\.`),
+	regexp.MustCompile(`(?i)^(?:INSERT\s+INTO|UPDATE(?:\s+ONLY)?|DELETE\s+FROM(?:\s+ONLY)?|TRUNCATE(?:\s+TABLE)?(?:\s+ONLY)?)\s+(` + identPattern + `)\s*\.`),
+	regexp.MustCompile(`(?i)^(?:CREATE|ALTER|DROP)\s+SCHEMA\s+(?:IF\s+(?:NOT\s+)?EXISTS\s+)?(` + identPattern + `)`),
+}
+
+// lintSQL applies the statement rules to one migration file.
+func (l *linter) lintSQL(file, src string) {
+	statements := splitStatements(src)
+
+	// Tables created in this file are new and empty, so locking them is harmless.
+	created := map[string]bool{}
+	for _, stmt := range statements {
+		if m := createTableRe.FindStringSubmatch(stmt.code); m != nil {
+			created[normalizeName(m[1])] = true
+		}
+	}
+
+	for _, stmt := range statements {
+		if !stmt.ignores(RuleIndexConcurrently) {
+			l.lintIndex(file, stmt, created, len(statements) > 1)
+		}
+		if !stmt.ignores(RuleColumnTypeChange) {
+			l.lintColumnType(file, stmt, created)
+		}
+		if l.opts.ownedSchemas != nil && !stmt.ignores(RuleSchemaOwnership) {
+			l.lintOwnership(file, stmt)
+		}
+	}
+}
+
+// lintIndex checks index builds and drops. golang-migrate sends a file as one
+// query string, which Postgres runs as a single transaction when it holds more
+// than one statement, so a CONCURRENTLY statement must be alone in its file.
+func (l *linter) lintIndex(file string, stmt statement, created map[string]bool, sharedFile bool) {
+	concurrent := concurrentlyRe.MatchString(stmt.code)
+	switch {
+	case concurrent && sharedFile:
+		l.add(file, stmt.line, RuleIndexConcurrently, SeverityError,
+			"CONCURRENTLY cannot run inside a transaction; put it alone in its own migration file")
+	case concurrent:
+	case createIndexRe.MatchString(stmt.code):
+		table := ""
+		if m := indexTableRe.FindStringSubmatch(stmt.code); m != nil {
+			table = normalizeName(m[1])
+		}
+		if table != "" && created[table] {
+			return
+		}
+		l.add(file, stmt.line, RuleIndexConcurrently, SeverityError,
+			fmt.Sprintf("CREATE INDEX on %s blocks writes while the index builds; use CREATE INDEX CONCURRENTLY", displayName(table)))
+	case dropIndexRe.MatchString(stmt.code):
+		l.add(file, stmt.line, RuleIndexConcurrently, SeverityWarning,
+			"DROP INDEX takes an ACCESS EXCLUSIVE lock on the table; prefer DROP INDEX CONCURRENTLY")
+	}
+}
+
+func (l *linter) lintColumnType(file string, stmt statement, created map[string]bool) {
+	m := alterTableRe.FindStringSubmatch(stmt.code)
+	if m == nil || !alterColumnTypeRe.MatchString(stmt.code) || created[normalizeName(m[1])] || stmt.hasLockNote() {
+		return
+	}
+	l.add(file, stmt.line, RuleColumnTypeChange, SeverityError,
+		fmt.Sprintf("changing a column type on %s can rewrite the table under an ACCESS EXCLUSIVE lock; "+
+			"add a \"-- lock-note: <why this is safe>\" comment", normalizeName(m[1])))
+}
+
+func (l *linter) lintOwnership(file string, stmt statement) {
+	seen := map[string]bool{}
+	check := func(schema string) {
+		schema = normalizeName(schema)
+		if l.opts.ownedSchemas[schema] || seen[schema] {
+			return
+		}
+		seen[schema] = true
+		l.add(file, stmt.line, RuleSchemaOwnership, SeverityError,
+			fmt.Sprintf("schema %q is not owned by this service", schema))
+	}
+	for _, re := range ownershipRes {
+		if m := re.FindStringSubmatch(stmt.code); m != nil {
+			check(m[1])
+		}
+	}
+}
+
+// normalizeName lowercases unquoted identifiers, strips quotes, and removes
+// whitespace around the schema separator.
+func normalizeName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if unquoted, ok := strings.CutPrefix(part, `"`); ok {
+			parts[i] = strings.TrimSuffix(unquoted, `"`)
+		} else {
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func displayName(table string) string {
+	if table == "" {
+		return "the table"
+	}
+	return table
+}
@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/internal/sqldb"
+	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/observability"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	meterName = "github.com/milan604/core-lab/pkg/postgres"
+
+	// DefaultSlowQueryThreshold is the duration above which QueryObserver logs
+	// a query as slow.
+	DefaultSlowQueryThreshold = sqldb.DefaultSlowQueryThreshold
+)
+
+// Span and metric attribute keys recorded by QueryObserver, alongside
+// observability.AttrDBOperation and observability.AttrDBStatement.
+var (
+	AttrDBTable  = sqldb.AttrDBTable
+	AttrDBTarget = attribute.Key("db.target")
+	AttrDBName   = sqldb.AttrDBName
+)
+
+// Option configures New.
+type Option func(*options)
+
+type options struct {
+	obs           observability.ObservabilityIface
+	log           logger.LogManager
+	slowThreshold time.Duration
+	poolMetrics   bool
+	queryCache    *QueryCache
+}
+
+// WithObservability registers a QueryObserver on the primary and on replicas
+// added with AddReplica, tracing queries with obs's tracer and recording
+// metrics on the global meter provider.
+func WithObservability(obs observability.ObservabilityIface) Option {
+	return func(o *options) { o.obs = obs }
+}
+
+// WithSlowQueryThreshold sets the duration above which queries are logged as
+// slow (DefaultSlowQueryThreshold by default). A negative value turns slow
+// query logging off.
+func WithSlowQueryThreshold(d time.Duration) Option {
+	return func(o *options) { o.slowThreshold = d }
+}
+
+// WithLogger sets the logger used for slow query and migration logs. Without
+// it slow queries go to the standard library logger and migrations to a
+// default logger.
+func WithLogger(l logger.LogManager) Option {
+	return func(o *options) { o.log = l }
+}
+
+// WithPoolMetrics registers the connection pool instruments of
+// RegisterPoolMetrics on the global meter provider.
+func WithPoolMetrics() Option {
+	return func(o *options) { o.poolMetrics = true }
+}
+
+// QueryObserverConfig configures a QueryObserver.
+type QueryObserverConfig = sqldb.ObserverConfig
+
+// QueryObserver is a GORM plugin that runs every statement in a client span
+// with the redacted SQL as db.statement, records db.client.operation.duration
+// and db.client.operation.errors, and logs slow queries. Statements sent to a
+// replica or a dedicated tenant pool carry db.target. Register it with
+// db.Client.Use(postgres.NewQueryObserver(cfg)), or pass WithObservability
+// to New.
+type QueryObserver = sqldb.Observer
+
+// NewQueryObserver creates a QueryObserver recording db.system "postgresql".
+func NewQueryObserver(cfg QueryObserverConfig) *QueryObserver {
+	return sqldb.NewObserver(cfg, sqldb.Dialect{
+		Plugin:    "corelab:query_observer",
+		System:    "postgresql",
+		Scope:     meterName,
+		LogPrefix: "[Postgres]",
+		Redact:    RedactSQL,
+		Attributes: func(ctx context.Context) []attribute.KeyValue {
+			if target := dbTargetFromContext(ctx); target != "" {
+				return []attribute.KeyValue{AttrDBTarget.String(target)}
+			}
+			return nil
+		},
+		LogFields: func(ctx context.Context) []any {
+			return []any{"db_target", dbTargetFromContext(ctx)}
+		},
+	})
+}
+
+// RedactSQL replaces string, dollar-quoted, and numeric literals in query
+// with "?" and drops comments, so statements can be recorded without the
+// values they carry. Bind placeholders ($1) are kept.
+func RedactSQL(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'':
+			i = skipQuoted(query, i+1, '\'')
+			b.WriteByte('?')
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 4
+			}
+		case c == '$':
+			j := i + 1
+			if j < len(query) && isDigit(query[j]) {
+				// Bind placeholder such as $1.
+				for j < len(query) && isDigit(query[j]) {
+					j++
+				}
+				b.WriteString(query[i:j])
+				i = j
+				continue
+			}
+			for j < len(query) && isIdentChar(query[j]) {
+				j++
+			}
+			if j >= len(query) || query[j] != '$' {
+				b.WriteByte(c)
+				i++
+				continue
+			}
+			// Dollar-quoted string: $tag$ ... $tag$.
+			tag := query[i : j+1]
+			if end := strings.Index(query[j+1:], tag); end < 0 {
+				i = len(query)
+			} else {
+				i = j + 1 + end + len(tag)
+			}
+			b.WriteByte('?')
+		case c == '"':
+			end := skipQuoted(query, i+1, '"')
+			b.WriteString(query[i:end])
+			i = end
+		case isDigit(c) && (i == 0 || !isIdentChar(query[i-1])):
+			j := i
+			for j < len(query) && (isDigit(query[j]) || query[j] == '.' || query[j] == 'e' || query[j] == 'E') {
+				j++
+			}
+			b.WriteByte('?')
+			i = j
+		case isIdentChar(c):
+			j := i
+			for j < len(query) && isIdentChar(query[j]) {
+				j++
+			}
+			b.WriteString(query[i:j])
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// skipQuoted returns the index after the closing quote, treating a doubled
+// quote as an escaped one.
+func skipQuoted(s string, i int, quote byte) int {
+	for i < len(s) {
+		if s[i] == quote {
+			if i+1 < len(s) && s[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return len(s)
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
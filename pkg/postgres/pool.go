@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/milan604/core-lab/pkg/internal/sqldb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Pool metric attribute keys.
+var (
+	AttrPoolName        = attribute.Key("db.client.connection.pool.name")
+	AttrConnectionState = attribute.Key("db.client.connection.state")
+	attrCloseReason     = attribute.Key("db.client.connection.close_reason")
+)
+
+// pool returns the connection pool settings of cfg.
+func (cfg Config) pool() sqldb.Pool {
+	return sqldb.Pool{
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: cfg.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+	}
+}
+
+// Stats returns the connection pool statistics of the primary.
+func (db *DB) Stats() sql.DBStats {
+	return db.SQL.Stats()
+}
+
+// ReplicaStats returns the connection pool statistics of each replica, in
+// the order they were added.
+func (db *DB) ReplicaStats() []sql.DBStats {
+	replicas := db.Replicas()
+	stats := make([]sql.DBStats, 0, len(replicas))
+	for _, replica := range replicas {
+		if sqlDB, err := replica.DB(); err == nil {
+			stats = append(stats, sqlDB.Stats())
+		}
+	}
+	return stats
+}
+
+// RegisterPoolMetrics registers connection pool instruments on meter for the
+// primary and every replica, read from sql.DBStats at each collection, so
+// they are exported on the meter reader's interval:
+// db.client.connection.count (by state idle or used),
+// db.client.connection.max, db.client.connection.waits,
+// db.client.connection.wait_duration, and db.client.connection.closed (by
+// close reason). Pools are named "<database>/primary",
+// "<database>/replica-<n>", and "<database>/tenant-<id>" for the dedicated
+// pools of TenantPools. Pass WithPoolMetrics to New to register them on
+// the global meter provider.
+func (db *DB) RegisterPoolMetrics(meter metric.Meter) (metric.Registration, error) {
+	connections, err := meter.Int64ObservableUpDownCounter("db.client.connection.count",
+		metric.WithDescription("Open connections by state"), metric.WithUnit("{connection}"))
+	if err != nil {
+		return nil, fmt.Errorf("create db.client.connection.count: %w", err)
+	}
+	maxConnections, err := meter.Int64ObservableUpDownCounter("db.client.connection.max",
+		metric.WithDescription("Maximum number of open connections allowed"), metric.WithUnit("{connection}"))
+	if err != nil {
+		return nil, fmt.Errorf("create db.client.connection.max: %w", err)
+	}
+	waits, err := meter.Int64ObservableCounter("db.client.connection.waits",
+		metric.WithDescription("Cumulative number of waits for a free connection"), metric.WithUnit("{wait}"))
+	if err != nil {
+		return nil, fmt.Errorf("create db.client.connection.waits: %w", err)
+	}
+	waitDuration, err := meter.Float64ObservableCounter("db.client.connection.wait_duration",
+		metric.WithDescription("Cumulative time spent waiting for a free connection"), metric.WithUnit("s"))
+	if err != nil {
+		return nil, fmt.Errorf("create db.client.connection.wait_duration: %w", err)
+	}
+	closed, err := meter.Int64ObservableCounter("db.client.connection.closed",
+		metric.WithDescription("Cumulative connections closed by the pool"), metric.WithUnit("{connection}"))
+	if err != nil {
+		return nil, fmt.Errorf("create db.client.connection.closed: %w", err)
+	}
+
+	return meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		observe := func(name string, stats sql.DBStats) {
+			pool := AttrPoolName.String(name)
+			o.ObserveInt64(connections, int64(stats.Idle), metric.WithAttributes(pool, AttrConnectionState.String("idle")))
+			o.ObserveInt64(connections, int64(stats.InUse), metric.WithAttributes(pool, AttrConnectionState.String("used")))
+			o.ObserveInt64(maxConnections, int64(stats.MaxOpenConnections), metric.WithAttributes(pool))
+			o.ObserveInt64(waits, stats.WaitCount, metric.WithAttributes(pool))
+			o.ObserveFloat64(waitDuration, stats.WaitDuration.Seconds(), metric.WithAttributes(pool))
+			o.ObserveInt64(closed, stats.MaxIdleClosed, metric.WithAttributes(pool, attrCloseReason.String("max_idle_conns")))
+			o.ObserveInt64(closed, stats.MaxIdleTimeClosed, metric.WithAttributes(pool, attrCloseReason.String("max_idle_time")))
+			o.ObserveInt64(closed, stats.MaxLifetimeClosed, metric.WithAttributes(pool, attrCloseReason.String("max_lifetime")))
+		}
+
+		observe(db.poolName(targetPrimary), db.Stats())
+		for i, stats := range db.ReplicaStats() {
+			observe(db.poolName(targetReplica+"-"+strconv.Itoa(i)), stats)
+		}
+		if pools := db.tenantPools.Load(); pools != nil {
+			for tenantID, stats := range pools.Stats() {
+				observe(db.poolName(targetTenant+"-"+tenantID), stats)
+			}
+		}
+		return nil
+	}, connections, maxConnections, waits, waitDuration, closed)
+}
+
+func (db *DB) poolName(target string) string {
+	if db.name == "" {
+		return target
+	}
+	return db.name + "/" + target
+}
@@ -1,13 +1,22 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/milan604/core-lab/pkg/logger"
+	"go.opentelemetry.io/otel"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -19,34 +28,162 @@ type Config struct {
 	Username string
 	Password string
 	SSLMode  string
+	// SSLRootCert is the CA bundle used to verify the server with sslmode
+	// verify-ca or verify-full: either PEM content (e.g. from a secret) or a
+	// file path. PEM content reaches DB.DSN through a private temporary file.
+	SSLRootCert string
+	// PasswordProvider, when set, supplies the password for every new
+	// connection instead of Password, so short-lived credentials such as
+	// RDSIAMAuth or AzureManagedIdentity tokens are refreshed on reconnect.
+	PasswordProvider PasswordProvider
+
+	// Connection pool settings; zero keeps the database/sql default
+	// (unlimited open connections, 2 idle, no lifetime or idle timeout).
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
 }
 
 type DB struct {
 	Client *gorm.DB
 	SQL    *sql.DB
-	DSN    string
+	// DSN is the connection string New connected with; it is not updated by
+	// RotatePassword (see CurrentDSN).
+	DSN string
+
+	name         string
+	replicasMu   sync.Mutex // serializes AddReplica
+	replicas     atomic.Pointer[[]*gorm.DB]
+	nextReplica  atomic.Uint64
+	observer     *QueryObserver
+	password     *rotatingPassword
+	maxIdleConns int
+	log          logger.LogManager
+	cfg          Config
+	tenantPools  atomic.Pointer[TenantPools]
 }
 
 // New creates a new DB connection from user-supplied config
-func New(cfg Config) (*DB, error) {
+func New(cfg Config, opts ...Option) (*DB, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// A static password is served through a provider so RotatePassword can
+	// replace it.
+	var password *rotatingPassword
+	if cfg.PasswordProvider == nil {
+		password = newRotatingPassword(cfg.Password)
+		cfg.PasswordProvider = password
+	}
+	client, sqlDB, dsn, err := open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db := &DB{Client: client, SQL: sqlDB, DSN: dsn, name: cfg.Name, password: password, maxIdleConns: cfg.MaxIdleConns, log: o.log, cfg: cfg}
+	if o.obs != nil {
+		db.observer = NewQueryObserver(QueryObserverConfig{
+			Database:      cfg.Name,
+			Tracer:        o.obs.GetTracer(),
+			Logger:        o.log,
+			SlowThreshold: o.slowThreshold,
+		})
+		if err := client.Use(db.observer); err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("postgres: register query observer: %w", err)
+		}
+	}
+	if o.queryCache != nil {
+		if err := client.Use(o.queryCache); err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("postgres: register query cache: %w", err)
+		}
+	}
+	if o.poolMetrics {
+		if _, err := db.RegisterPoolMetrics(otel.Meter(meterName)); err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("postgres: register pool metrics: %w", err)
+		}
+	}
+	logConnection(cfg, dsn)
+	return db, nil
+}
+
+// open connects to the database described by cfg and verifies it with a ping.
+func open(cfg Config) (*gorm.DB, *sql.DB, string, error) {
 	if cfg.SSLMode == "" {
 		cfg.SSLMode = "disable"
 	}
-	dbConnectionStr := "host=" + cfg.Host + " port=" + cfg.Port + " user=" + cfg.Username + " dbname=" + cfg.Name + " password=" + cfg.Password + " sslmode=" + cfg.SSLMode
-	dsn := "postgres://" + cfg.Username + ":" + cfg.Password + "@" + cfg.Host + ":" + cfg.Port + "/" + cfg.Name + "?sslmode=" + cfg.SSLMode
-	client, err := gorm.Open(postgres.Open(dbConnectionStr), &gorm.Config{})
+	dbConnectionStr := "host=" + cfg.Host + " port=" + cfg.Port + " user=" + cfg.Username + " dbname=" + cfg.Name + " sslmode=" + cfg.SSLMode
+	if cfg.PasswordProvider == nil {
+		dbConnectionStr += " password=" + cfg.Password
+	}
+	rootCertPath, rootCertPEM := splitRootCert(cfg.SSLRootCert)
+	if rootCertPath != "" {
+		dbConnectionStr += " sslrootcert=" + rootCertPath
+	}
+	connConfig, err := pgx.ParseConfig(dbConnectionStr)
 	if err != nil {
-		return nil, err
+		return nil, nil, "", err
+	}
+	if err := applyRootCertPEM(connConfig, rootCertPEM); err != nil {
+		return nil, nil, "", err
+	}
+
+	var opts []stdlib.OptionOpenDB
+	if cfg.PasswordProvider != nil {
+		provider := cfg.PasswordProvider
+		opts = append(opts, stdlib.OptionBeforeConnect(func(ctx context.Context, cc *pgx.ConnConfig) error {
+			password, err := provider.Password(ctx)
+			if err != nil {
+				return fmt.Errorf("postgres: resolve password: %w", err)
+			}
+			cc.Password = password
+			return nil
+		}))
+		// Migrate runs on pool connections, which resolve the password as
+		// they dial. DSN serves external tools, so it only carries a static
+		// password: a short-lived token would expire in it.
+		cfg.Password = ""
+		if static, ok := provider.(*rotatingPassword); ok {
+			cfg.Password, _ = static.Password(context.Background())
+		}
 	}
-	sqlDB, err := client.DB()
+	sqlDB := stdlib.OpenDB(*connConfig, opts...)
+	cfg.pool().Apply(sqlDB)
+	client, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
 	if err != nil {
-		return nil, err
+		_ = sqlDB.Close()
+		return nil, nil, "", err
 	}
 	if err := sqlDB.Ping(); err != nil {
-		return nil, err
+		_ = sqlDB.Close()
+		return nil, nil, "", err
 	}
-	logConnection(cfg, dsn)
-	return &DB{Client: client, SQL: sqlDB, DSN: dsn}, nil
+
+	user := url.User(cfg.Username)
+	if cfg.Password != "" {
+		user = url.UserPassword(cfg.Username, cfg.Password)
+	}
+	dsn := (&url.URL{
+		Scheme: "postgres",
+		User:   user,
+		Host:   cfg.Host + ":" + cfg.Port,
+		Path:   "/" + cfg.Name,
+	}).String() + "?sslmode=" + url.QueryEscape(cfg.SSLMode)
+	if rootCertPEM != "" {
+		// libpq reads the CA bundle from a file only.
+		if rootCertPath, err = rootCertFile(rootCertPEM); err != nil {
+			_ = sqlDB.Close()
+			return nil, nil, "", err
+		}
+	}
+	if rootCertPath != "" {
+		dsn += "&sslrootcert=" + url.QueryEscape(rootCertPath)
+	}
+	return client, sqlDB, dsn, nil
 }
 
 // maskDSN redacts password from a postgres DSN when logging.
@@ -0,0 +1,441 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+// DefaultQueryCacheTTL bounds how long a cached result is served when no
+// invalidation reaches the cache.
+const DefaultQueryCacheTTL = 5 * time.Minute
+
+// QueryCacheConfig configures a QueryCache.
+type QueryCacheConfig struct {
+	// Store holds results and table generations. Use a
+	// RedisQueryCacheStore when several replicas write the tables, so
+	// invalidations reach all of them.
+	Store QueryCacheStore
+	// TTL defaults to DefaultQueryCacheTTL. It is the fallback bound on
+	// staleness for writes the cache does not see: raw Exec statements,
+	// other services, or manual SQL.
+	TTL time.Duration
+	// Logger receives store errors; nil uses the standard library logger.
+	// Reads fall through to the database when the store fails.
+	Logger logger.LogManager
+}
+
+// QueryCache is an opt-in read-through cache for hot reference data, such as
+// catalogs and settings, read through Find and First. Results are keyed by
+// the query's SQL and arguments and by a generation counter per table read.
+// As a GORM plugin it bumps the generation of every table written with
+// Create, Update, Save or Delete once the write commits, so later reads miss
+// and reload; entries also expire after the TTL. Reads inside a transaction
+// bypass the cache. Register it with WithQueryCache, or with
+// db.Client.Use(cache).
+//
+//	var plans []Plan
+//	err := cache.Find(db.ReadOnly(ctx).Where("active = ?", true).Order("name"), &plans)
+//
+// Results are stored with encoding/gob, so only exported fields are cached.
+type QueryCache struct {
+	cfg   QueryCacheConfig
+	group singleflight.Group
+}
+
+// NewQueryCache creates a QueryCache backed by cfg.Store.
+func NewQueryCache(cfg QueryCacheConfig) (*QueryCache, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("postgres: query cache store is required")
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultQueryCacheTTL
+	}
+	return &QueryCache{cfg: cfg}, nil
+}
+
+// WithQueryCache registers cache on the primary, so writes through it
+// invalidate cached reads once they commit.
+func WithQueryCache(cache *QueryCache) Option {
+	return func(o *options) { o.queryCache = cache }
+}
+
+// CacheOption configures a single cached read.
+type CacheOption func(*cacheOptions)
+
+type cacheOptions struct {
+	ttl    time.Duration
+	tables []string
+	scope  string
+}
+
+// CacheTTL overrides the cache TTL for one read.
+func CacheTTL(d time.Duration) CacheOption {
+	return func(o *cacheOptions) { o.ttl = d }
+}
+
+// CacheTables adds tables the read depends on besides the statement's own
+// table, such as joined or preloaded tables, so writes to them invalidate
+// it too. Raw queries without a model must name their tables here.
+func CacheTables(tables ...string) CacheOption {
+	return func(o *cacheOptions) { o.tables = append(o.tables, tables...) }
+}
+
+// CacheScope partitions the cached result by scope. Reads whose rows depend
+// on session state rather than the SQL, such as Row Level Security under
+// SetTenantContext or a TenantPools connection, must pass the tenant ID, or
+// one tenant's rows would be served to another.
+func CacheScope(scope string) CacheOption {
+	return func(o *cacheOptions) { o.scope = scope }
+}
+
+// Find runs query.Find(dest) through the cache. dest must be a pointer.
+func (c *QueryCache) Find(query *gorm.DB, dest any, opts ...CacheOption) error {
+	return c.read(query, dest, opts, func(tx *gorm.DB, dest any) *gorm.DB { return tx.Find(dest) })
+}
+
+// First runs query.First(dest) through the cache. gorm.ErrRecordNotFound is
+// returned as usual and not cached.
+func (c *QueryCache) First(query *gorm.DB, dest any, opts ...CacheOption) error {
+	return c.read(query, dest, opts, func(tx *gorm.DB, dest any) *gorm.DB { return tx.First(dest) })
+}
+
+func (c *QueryCache) read(query *gorm.DB, dest any, opts []CacheOption, run func(*gorm.DB, any) *gorm.DB) error {
+	o := cacheOptions{ttl: c.cfg.TTL}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	destType := reflect.TypeOf(dest)
+	if destType == nil || destType.Kind() != reflect.Pointer {
+		return errors.New("postgres: query cache destination must be a pointer")
+	}
+
+	// Reads inside a transaction may see its uncommitted writes.
+	if isTransaction(query.Statement.ConnPool) {
+		return run(query, dest).Error
+	}
+
+	// A dry run renders the statement without executing it.
+	dry := run(query.Session(&gorm.Session{DryRun: true}), dest)
+	if dry.Error != nil {
+		return dry.Error
+	}
+	stmt := dry.Statement
+	tables := o.tables
+	if stmt.Table != "" {
+		tables = append([]string{stmt.Table}, tables...)
+	}
+	if len(tables) == 0 {
+		return errors.New("postgres: query cache cannot tell which tables the query reads; pass CacheTables")
+	}
+	ctx := stmt.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	generations, err := c.cfg.Store.Generations(ctx, tables)
+	if err != nil {
+		c.logf(ctx, "read table generations: %v", err)
+		return run(query, dest).Error
+	}
+	key := queryCacheKey(dry, destType, o.scope, tables, generations)
+
+	if data, ok, err := c.cfg.Store.Get(ctx, key); err != nil {
+		c.logf(ctx, "get %s: %v", key, err)
+	} else if ok {
+		err := decodeCachedResult(data, dest)
+		if err == nil {
+			return nil
+		}
+		c.logf(ctx, "decode %s: %v", key, err)
+	}
+
+	// Concurrent misses for the same key share one query.
+	value, err, _ := c.group.Do(key, func() (any, error) {
+		// start from a copy of dest, since First uses its primary key as a
+		// condition
+		fresh := reflect.New(destType.Elem())
+		fresh.Elem().Set(reflect.ValueOf(dest).Elem())
+		if err := run(query, fresh.Interface()).Error; err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(fresh.Interface()); err != nil {
+			return nil, fmt.Errorf("postgres: encode cached result: %w", err)
+		}
+		if err := c.cfg.Store.Set(ctx, key, buf.Bytes(), o.ttl); err != nil {
+			c.logf(ctx, "set %s: %v", key, err)
+		}
+		return buf.Bytes(), nil
+	})
+	if err != nil {
+		return err
+	}
+	return decodeCachedResult(value.([]byte), dest)
+}
+
+// decodeCachedResult zeroes dest first: gob leaves fields that were zero
+// when encoded untouched.
+func decodeCachedResult(data []byte, dest any) error {
+	reflect.ValueOf(dest).Elem().SetZero()
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(dest)
+}
+
+// Invalidate bumps the generation of tables, so cached reads of them miss.
+// Call it after writes the plugin does not see, such as raw Exec statements.
+func (c *QueryCache) Invalidate(ctx context.Context, tables ...string) error {
+	if len(tables) == 0 {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return c.cfg.Store.Bump(ctx, tables...)
+}
+
+// queryCacheKey hashes the rendered SQL with its arguments, the destination
+// type and the scope, and appends the generation of each table read.
+func queryCacheKey(dry *gorm.DB, destType reflect.Type, scope string, tables []string, generations []int64) string {
+	sql := dry.Statement.SQL.String()
+	// drop the QueryAnnotator comment, which differs per request
+	if strings.HasSuffix(sql, "*/") {
+		if i := strings.LastIndex(sql, " /*"); i >= 0 {
+			sql = sql[:i]
+		}
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s", dry.Dialector.Explain(sql, dry.Statement.Vars...), destType, scope)
+	var b strings.Builder
+	b.WriteString(hex.EncodeToString(h.Sum(nil)[:16]))
+	for i, table := range tables {
+		b.WriteString(":")
+		b.WriteString(table)
+		b.WriteString("@")
+		b.WriteString(strconv.FormatInt(generations[i], 10))
+	}
+	return b.String()
+}
+
+func (c *QueryCache) logf(ctx context.Context, format string, args ...any) {
+	if c.cfg.Logger == nil {
+		log.Printf("[Postgres] query cache: "+format, args...)
+		return
+	}
+	c.cfg.Logger.WarnFCtx(ctx, "query cache: "+format, args...)
+}
+
+// Name implements gorm.Plugin.
+func (c *QueryCache) Name() string { return "corelab:query_cache" }
+
+// Initialize implements gorm.Plugin. Besides the write callbacks it wraps the
+// connection pool of db, so transactions begun on it, GORM's default
+// transaction around each write included, report their commit.
+func (c *QueryCache) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	registrations := []struct {
+		name     string
+		register func(string, func(*gorm.DB)) error
+	}{
+		{"gorm:create", callbacks.Create().After("gorm:create").Register},
+		{"gorm:update", callbacks.Update().After("gorm:update").Register},
+		{"gorm:delete", callbacks.Delete().After("gorm:delete").Register},
+	}
+	for _, r := range registrations {
+		if err := r.register(c.Name()+":after_"+r.name, c.afterWrite); err != nil {
+			return fmt.Errorf("register query cache after %s: %w", r.name, err)
+		}
+	}
+	if _, wrapped := db.ConnPool.(*queryCacheConnPool); !wrapped {
+		db.ConnPool = &queryCacheConnPool{ConnPool: db.ConnPool, cache: c}
+		if db.Statement != nil {
+			db.Statement.ConnPool = db.ConnPool
+		}
+	}
+	return nil
+}
+
+// afterWrite invalidates the written table once the write is visible to
+// other connections: right away outside a transaction, and after the commit
+// inside one, since a read between an earlier bump and the commit would
+// cache the old rows under the new generation.
+func (c *QueryCache) afterWrite(db *gorm.DB) {
+	stmt := db.Statement
+	if stmt == nil || db.DryRun || db.Error != nil || stmt.Table == "" {
+		return
+	}
+	if tx := queryCacheTxOf(stmt.ConnPool); tx != nil {
+		tx.record(stmt.Table)
+		return
+	}
+	ctx := stmt.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if isTransaction(stmt.ConnPool) {
+		// A transaction begun on a pool the cache does not wrap, e.g. a
+		// connection taken with db.Connection: its commit is invisible, so
+		// invalidate now; the TTL bounds what a racing read may cache.
+		c.logf(ctx, "write to %s in a transaction the cache cannot track; call Invalidate after the commit", stmt.Table)
+	}
+	if err := c.cfg.Store.Bump(ctx, stmt.Table); err != nil {
+		c.logf(ctx, "invalidate %s: %v", stmt.Table, err)
+	}
+}
+
+// isTransaction reports whether statements on pool run in a transaction,
+// whose uncommitted rows must neither be cached nor served from the cache.
+func isTransaction(pool gorm.ConnPool) bool {
+	_, ok := pool.(gorm.TxCommitter)
+	return ok
+}
+
+// queryCacheConnPool wraps the connection pool of a database the cache is
+// registered on, so the transactions begun on it invalidate the tables they
+// wrote once they commit.
+type queryCacheConnPool struct {
+	gorm.ConnPool
+	cache *QueryCache
+}
+
+// BeginTx implements gorm.ConnPoolBeginner.
+func (p *queryCacheConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	var (
+		tx  gorm.ConnPool
+		err error
+	)
+	switch beginner := p.ConnPool.(type) {
+	case gorm.TxBeginner:
+		var sqlTx *sql.Tx
+		sqlTx, err = beginner.BeginTx(ctx, opts)
+		tx = sqlTx
+	case gorm.ConnPoolBeginner:
+		tx, err = beginner.BeginTx(ctx, opts)
+	default:
+		return nil, gorm.ErrInvalidTransaction
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &queryCacheTx{ConnPool: tx, pool: p, ctx: context.WithoutCancel(ctx)}, nil
+}
+
+// GetDBConn implements gorm.GetDBConnector, so gorm.DB.DB keeps working.
+func (p *queryCacheConnPool) GetDBConn() (*sql.DB, error) {
+	switch pool := p.ConnPool.(type) {
+	case *sql.DB:
+		return pool, nil
+	case gorm.GetDBConnector:
+		return pool.GetDBConn()
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+// Ping lets gorm.DB.Ping style checks reach the wrapped pool.
+func (p *queryCacheConnPool) Ping() error {
+	if pinger, ok := p.ConnPool.(interface{ Ping() error }); ok {
+		return pinger.Ping()
+	}
+	return nil
+}
+
+// queryCacheTx is a transaction begun on a queryCacheConnPool. It records the
+// tables written through it and bumps them after a successful commit.
+type queryCacheTx struct {
+	gorm.ConnPool
+	pool *queryCacheConnPool
+	ctx  context.Context
+
+	mu     sync.Mutex
+	tables map[string]struct{}
+}
+
+func (t *queryCacheTx) record(table string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tables == nil {
+		t.tables = make(map[string]struct{})
+	}
+	t.tables[table] = struct{}{}
+}
+
+// Commit implements gorm.TxCommitter.
+func (t *queryCacheTx) Commit() error {
+	committer, ok := t.ConnPool.(gorm.TxCommitter)
+	if !ok {
+		return gorm.ErrInvalidTransaction
+	}
+	if err := committer.Commit(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	tables := make([]string, 0, len(t.tables))
+	for table := range t.tables {
+		tables = append(tables, table)
+	}
+	t.tables = nil
+	t.mu.Unlock()
+	if err := t.pool.cache.Invalidate(t.ctx, tables...); err != nil {
+		t.pool.cache.logf(t.ctx, "invalidate after commit: %v", err)
+	}
+	return nil
+}
+
+// Rollback implements gorm.TxCommitter. Nothing was visible, so nothing is
+// invalidated.
+func (t *queryCacheTx) Rollback() error {
+	committer, ok := t.ConnPool.(gorm.TxCommitter)
+	if !ok {
+		return gorm.ErrInvalidTransaction
+	}
+	t.mu.Lock()
+	t.tables = nil
+	t.mu.Unlock()
+	return committer.Rollback()
+}
+
+// StmtContext implements gorm.Tx, so prepared statement sessions can wrap
+// the transaction.
+func (t *queryCacheTx) StmtContext(ctx context.Context, stmt *sql.Stmt) *sql.Stmt {
+	if tx, ok := t.ConnPool.(interface {
+		StmtContext(context.Context, *sql.Stmt) *sql.Stmt
+	}); ok {
+		return tx.StmtContext(ctx, stmt)
+	}
+	return stmt
+}
+
+// GetDBConn implements gorm.GetDBConnector.
+func (t *queryCacheTx) GetDBConn() (*sql.DB, error) {
+	return t.pool.GetDBConn()
+}
+
+// queryCacheTxOf returns the queryCacheTx pool runs statements in, if any.
+func queryCacheTxOf(pool gorm.ConnPool) *queryCacheTx {
+	switch p := pool.(type) {
+	case *queryCacheTx:
+		return p
+	case *gorm.PreparedStmtTX:
+		tx, _ := p.Tx.(*queryCacheTx)
+		return tx
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// QueryCacheStore holds QueryCache results and the generation counter of
+// each table. Implementations must be safe for concurrent use.
+type QueryCacheStore interface {
+	// Get returns the result stored under key, and false when none exists.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Generations returns the current generation of each table, 0 for
+	// tables never bumped.
+	Generations(ctx context.Context, tables []string) ([]int64, error)
+	// Bump increments the generation of each table, orphaning the results
+	// cached under the previous one until they expire.
+	Bump(ctx context.Context, tables ...string) error
+}
+
+// DefaultMemoryQueryCacheEntries caps a MemoryQueryCacheStore created with a
+// non-positive size.
+const DefaultMemoryQueryCacheEntries = 10000
+
+// MemoryQueryCacheStore keeps results in process memory. Invalidations only
+// reach the process that made the write, so use it for single-replica
+// services or data written elsewhere only rarely, and RedisQueryCacheStore
+// otherwise.
+type MemoryQueryCacheStore struct {
+	mu          sync.Mutex
+	maxEntries  int
+	entries     map[string]memoryQueryCacheEntry
+	generations map[string]int64
+}
+
+type memoryQueryCacheEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryQueryCacheStore creates an in-memory store holding at most
+// maxEntries results (DefaultMemoryQueryCacheEntries when maxEntries <= 0).
+func NewMemoryQueryCacheStore(maxEntries int) *MemoryQueryCacheStore {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMemoryQueryCacheEntries
+	}
+	return &MemoryQueryCacheStore{
+		maxEntries:  maxEntries,
+		entries:     make(map[string]memoryQueryCacheEntry),
+		generations: make(map[string]int64),
+	}
+}
+
+// Get returns the result stored under key unless it has expired.
+func (s *MemoryQueryCacheStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(s.entries, key)
+		return nil, false, nil
+	}
+	return entry.value, true, nil
+}
+
+// Set stores value under key for ttl. When the store is full, expired
+// entries are dropped first, then arbitrary ones.
+func (s *MemoryQueryCacheStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if _, exists := s.entries[key]; !exists && len(s.entries) >= s.maxEntries {
+		for k, entry := range s.entries {
+			if now.After(entry.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		for k := range s.entries {
+			if len(s.entries) < s.maxEntries {
+				break
+			}
+			delete(s.entries, k)
+		}
+	}
+	s.entries[key] = memoryQueryCacheEntry{value: value, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+// Generations returns the current generation of each table.
+func (s *MemoryQueryCacheStore) Generations(_ context.Context, tables []string) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	generations := make([]int64, len(tables))
+	for i, table := range tables {
+		generations[i] = s.generations[table]
+	}
+	return generations, nil
+}
+
+// Bump increments the generation of each table.
+func (s *MemoryQueryCacheStore) Bump(_ context.Context, tables ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, table := range tables {
+		s.generations[table]++
+	}
+	return nil
+}
+
+// RedisQueryCacheStore shares results and invalidations across replicas
+// through Redis.
+type RedisQueryCacheStore struct {
+	client    redis.UniversalClient
+	namespace string
+}
+
+// NewRedisQueryCacheStore creates a Redis-backed store. namespace prefixes
+// every key and defaults to "corelab:querycache".
+func NewRedisQueryCacheStore(client redis.UniversalClient, namespace string) (*RedisQueryCacheStore, error) {
+	if client == nil {
+		return nil, errors.New("postgres: query cache redis client is required")
+	}
+	namespace = strings.Trim(strings.TrimSpace(namespace), ":")
+	if namespace == "" {
+		namespace = "corelab:querycache"
+	}
+	return &RedisQueryCacheStore{client: client, namespace: namespace}, nil
+}
+
+// Get returns the result stored under key.
+func (s *RedisQueryCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := s.client.Get(ctx, s.resultKey(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set stores value under key for ttl.
+func (s *RedisQueryCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, s.resultKey(key), value, ttl).Err()
+}
+
+// Generations reads the generation of every table in one MGET.
+func (s *RedisQueryCacheStore) Generations(ctx context.Context, tables []string) ([]int64, error) {
+	keys := make([]string, len(tables))
+	for i, table := range tables {
+		keys[i] = s.generationKey(table)
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	generations := make([]int64, len(tables))
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if generations[i], err = strconv.ParseInt(raw, 10, 64); err != nil {
+			return nil, err
+		}
+	}
+	return generations, nil
+}
+
+// Bump increments the generation of each table with INCR. The counters
+// have no TTL; there is one per table.
+func (s *RedisQueryCacheStore) Bump(ctx context.Context, tables ...string) error {
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, table := range tables {
+			pipe.Incr(ctx, s.generationKey(table))
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *RedisQueryCacheStore) resultKey(key string) string {
+	return s.namespace + ":result:" + key
+}
+
+func (s *RedisQueryCacheStore) generationKey(table string) string {
+	// one hash tag keeps the counters in one Redis Cluster slot for MGET
+	return s.namespace + ":{generation}:" + table
+}
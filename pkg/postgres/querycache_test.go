@@ -0,0 +1,367 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gormpostgres "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type cachedPlan struct {
+	ID   int64
+	Name string
+}
+
+func (cachedPlan) TableName() string { return "cached_plans" }
+
+// fakePlanDB is a database/sql driver holding a single cached_plans row. UPDATE
+// statements set its name, visible to other connections only once committed.
+type fakePlanDB struct {
+	mu       sync.Mutex
+	name     string
+	selects  int
+	onCommit func()
+}
+
+func (f *fakePlanDB) Connect(context.Context) (driver.Conn, error) { return &fakePlanConn{db: f}, nil }
+func (f *fakePlanDB) Driver() driver.Driver                        { return nil }
+
+func (f *fakePlanDB) selectCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.selects
+}
+
+type fakePlanConn struct {
+	db      *fakePlanDB
+	inTx    bool
+	pending *string
+}
+
+func (c *fakePlanConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fake: prepare not supported")
+}
+func (c *fakePlanConn) Close() error { return nil }
+func (c *fakePlanConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakePlanConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.inTx, c.pending = true, nil
+	return c, nil
+}
+
+func (c *fakePlanConn) Commit() error {
+	if hook := c.db.onCommit; hook != nil {
+		hook()
+	}
+	c.db.mu.Lock()
+	if c.pending != nil {
+		c.db.name = *c.pending
+	}
+	c.db.mu.Unlock()
+	c.inTx, c.pending = false, nil
+	return nil
+}
+
+func (c *fakePlanConn) Rollback() error {
+	c.inTx, c.pending = false, nil
+	return nil
+}
+
+func (c *fakePlanConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if !strings.HasPrefix(query, "UPDATE") {
+		return driver.RowsAffected(0), nil
+	}
+	for _, arg := range args {
+		name, ok := arg.Value.(string)
+		if !ok {
+			continue
+		}
+		if c.inTx {
+			c.pending = &name
+		} else {
+			c.db.mu.Lock()
+			c.db.name = name
+			c.db.mu.Unlock()
+		}
+		break
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakePlanConn) QueryContext(_ context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.selects++
+	name := c.db.name
+	if c.inTx && c.pending != nil {
+		name = *c.pending
+	}
+	return &fakePlanRows{name: name}, nil
+}
+
+type fakePlanRows struct {
+	name string
+	done bool
+}
+
+func (r *fakePlanRows) Columns() []string { return []string{"id", "name"} }
+func (r *fakePlanRows) Close() error      { return nil }
+
+func (r *fakePlanRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0], dest[1] = int64(1), r.name
+	return nil
+}
+
+func newCachedPlanDB(t *testing.T) (*gorm.DB, *QueryCache, *fakePlanDB) {
+	t.Helper()
+	fake := &fakePlanDB{name: "free"}
+	sqlDB := sql.OpenDB(fake)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	client, err := gorm.Open(gormpostgres.New(gormpostgres.Config{Conn: sqlDB}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewQueryCache(QueryCacheConfig{Store: NewMemoryQueryCacheStore(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Use(cache); err != nil {
+		t.Fatal(err)
+	}
+	return client, cache, fake
+}
+
+func readPlan(t *testing.T, cache *QueryCache, query *gorm.DB, opts ...CacheOption) string {
+	t.Helper()
+	var plans []cachedPlan
+	if err := cache.Find(query.Model(&cachedPlan{}), &plans, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("plans = %+v", plans)
+	}
+	return plans[0].Name
+}
+
+func setPlan(db *gorm.DB, name string) error {
+	return db.Model(&cachedPlan{}).Where("id = ?", 1).Update("name", name).Error
+}
+
+func TestQueryCacheServesRepeatedReads(t *testing.T) {
+	client, cache, fake := newCachedPlanDB(t)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if name := readPlan(t, cache, client.WithContext(ctx)); name != "free" {
+			t.Fatalf("name = %q", name)
+		}
+	}
+	if n := fake.selectCount(); n != 1 {
+		t.Fatalf("selects = %d, want 1", n)
+	}
+
+	readPlan(t, cache, client.WithContext(ctx), CacheScope("tenant-a"))
+	if n := fake.selectCount(); n != 2 {
+		t.Fatalf("selects = %d, want a miss for another scope", n)
+	}
+
+	if err := cache.Invalidate(ctx, "cached_plans"); err != nil {
+		t.Fatal(err)
+	}
+	readPlan(t, cache, client.WithContext(ctx))
+	if n := fake.selectCount(); n != 3 {
+		t.Fatalf("selects = %d, want a miss after Invalidate", n)
+	}
+}
+
+func TestQueryCacheInvalidatesAfterCommit(t *testing.T) {
+	client, cache, fake := newCachedPlanDB(t)
+	ctx := context.Background()
+	readPlan(t, cache, client.WithContext(ctx))
+
+	// A read between the UPDATE and its commit still sees the old row; it
+	// must not be served once the write is visible.
+	var during string
+	fake.onCommit = func() {
+		fake.onCommit = nil
+		during = readPlan(t, cache, client.WithContext(ctx))
+	}
+	err := client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return setPlan(tx, "pro")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if during != "free" {
+		t.Fatalf("read before commit = %q", during)
+	}
+	if name := readPlan(t, cache, client.WithContext(ctx)); name != "pro" {
+		t.Fatalf("name after commit = %q, want pro", name)
+	}
+}
+
+func TestQueryCacheInvalidatesDefaultTransaction(t *testing.T) {
+	client, cache, _ := newCachedPlanDB(t)
+	ctx := context.Background()
+	readPlan(t, cache, client.WithContext(ctx))
+
+	if err := setPlan(client.WithContext(ctx), "pro"); err != nil {
+		t.Fatal(err)
+	}
+	if name := readPlan(t, cache, client.WithContext(ctx)); name != "pro" {
+		t.Fatalf("name = %q, want pro", name)
+	}
+}
+
+func TestQueryCacheKeepsEntriesOnRollback(t *testing.T) {
+	client, cache, fake := newCachedPlanDB(t)
+	ctx := context.Background()
+	readPlan(t, cache, client.WithContext(ctx))
+
+	errAbort := errors.New("abort")
+	err := client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := setPlan(tx, "pro"); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("err = %v", err)
+	}
+	if name := readPlan(t, cache, client.WithContext(ctx)); name != "free" {
+		t.Fatalf("name = %q", name)
+	}
+	if n := fake.selectCount(); n != 1 {
+		t.Fatalf("selects = %d, want the cached read to survive a rollback", n)
+	}
+}
+
+func TestQueryCacheBypassesTransactions(t *testing.T) {
+	client, cache, fake := newCachedPlanDB(t)
+	ctx := context.Background()
+	readPlan(t, cache, client.WithContext(ctx))
+
+	err := client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := setPlan(tx, "pro"); err != nil {
+			return err
+		}
+		for i := 0; i < 2; i++ {
+			if name := readPlan(t, cache, tx); name != "pro" {
+				t.Fatalf("read in transaction = %q, want its own write", name)
+			}
+		}
+		return errors.New("abort")
+	})
+	if err == nil {
+		t.Fatal("transaction committed")
+	}
+	if n := fake.selectCount(); n != 3 {
+		t.Fatalf("selects = %d, want every read in the transaction to hit the database", n)
+	}
+	if name := readPlan(t, cache, client.WithContext(ctx)); name != "free" {
+		t.Fatalf("name after rollback = %q, want the uncommitted write never cached", name)
+	}
+}
+
+func TestWithinTxInvalidatesQueryCacheAfterCommit(t *testing.T) {
+	client, cache, _ := newCachedPlanDB(t)
+	db := &DB{Client: client}
+	ctx := context.Background()
+	readPlan(t, cache, db.ReadOnly(ctx))
+
+	err := db.WithinTx(ctx, func(tx *gorm.DB) error {
+		if err := setPlan(tx, "pro"); err != nil {
+			return err
+		}
+		// still the committed row for readers outside the transaction
+		if name := readPlan(t, cache, db.ReadOnly(ctx)); name != "free" {
+			t.Fatalf("read outside transaction = %q", name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := readPlan(t, cache, db.ReadOnly(ctx)); name != "pro" {
+		t.Fatalf("name after commit = %q, want pro", name)
+	}
+	if sqlDB, err := client.DB(); err != nil || sqlDB == nil {
+		t.Fatalf("DB() = %v, %v", sqlDB, err)
+	}
+}
+
+func TestQueryCachePreparedStatementTransaction(t *testing.T) {
+	client, cache, _ := newCachedPlanDB(t)
+	ctx := context.Background()
+	readPlan(t, cache, client.WithContext(ctx))
+
+	session := client.Session(&gorm.Session{PrepareStmt: true, Context: ctx})
+	tx := session.Begin()
+	if tx.Error != nil {
+		t.Fatal(tx.Error)
+	}
+	if queryCacheTxOf(tx.Statement.ConnPool) == nil {
+		t.Fatalf("transaction pool %T is not tracked", tx.Statement.ConnPool)
+	}
+	if err := tx.Rollback().Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueryCacheRejectsNonPointerDestination(t *testing.T) {
+	client, cache, _ := newCachedPlanDB(t)
+	if err := cache.Find(client.Model(&cachedPlan{}), []cachedPlan{}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestMemoryQueryCacheStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryQueryCacheStore(2)
+
+	if err := store.Set(ctx, "a", []byte("1"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(ctx, "expired", []byte("2"), -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := store.Get(ctx, "expired"); ok {
+		t.Fatal("expired entry served")
+	}
+	_ = store.Set(ctx, "b", []byte("3"), time.Minute)
+	_ = store.Set(ctx, "c", []byte("4"), time.Minute)
+	kept := 0
+	for _, key := range []string{"a", "b", "c"} {
+		if _, ok, _ := store.Get(ctx, key); ok {
+			kept++
+		}
+	}
+	if kept != 2 {
+		t.Fatalf("kept %d entries, want the store capped at 2", kept)
+	}
+
+	if err := store.Bump(ctx, "plans", "plans", "prices"); err != nil {
+		t.Fatal(err)
+	}
+	generations, err := store.Generations(ctx, []string{"plans", "prices", "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if generations[0] < 1 || generations[1] != 1 || generations[2] != 0 {
+		t.Fatalf("generations = %v", generations)
+	}
+}
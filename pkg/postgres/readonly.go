@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+const (
+	targetPrimary = "primary"
+	targetReplica = "replica"
+)
+
+type dbTargetContextKey struct{}
+
+// AddReplica connects to a read replica and registers it for ReadOnly routing.
+// The QueryObserver from WithObservability is registered on the replica too;
+// other plugins registered on Client (such as the QueryAnnotator) are not
+// shared automatically, so register them on the replica via db.Replicas. It
+// is safe to call while queries are routed.
+func (db *DB) AddReplica(cfg Config) error {
+	client, sqlDB, dsn, err := open(cfg)
+	if err != nil {
+		return err
+	}
+	if db.observer != nil {
+		if err := client.Use(db.observer); err != nil {
+			_ = sqlDB.Close()
+			return fmt.Errorf("postgres: register query observer on replica: %w", err)
+		}
+	}
+	db.addReplica(client)
+	log.Printf("[Postgres] Read replica registered: %s", maskDSN(dsn))
+	return nil
+}
+
+// addReplica publishes a copy of the replica list with client appended, so
+// readers never see a slice that is being grown.
+func (db *DB) addReplica(client *gorm.DB) {
+	db.replicasMu.Lock()
+	defer db.replicasMu.Unlock()
+	replicas := db.Replicas()
+	replicas = append(replicas, client)
+	db.replicas.Store(&replicas)
+}
+
+// Replicas returns the read-only connections used by ReadOnly and
+// ReadOnlyTransaction, in the order they were added.
+func (db *DB) Replicas() []*gorm.DB {
+	replicas := db.replicas.Load()
+	if replicas == nil {
+		return nil
+	}
+	return append([]*gorm.DB(nil), (*replicas)...)
+}
+
+// Primary returns the primary connection bound to ctx. Use it to make write
+// routing explicit next to ReadOnly call sites.
+func (db *DB) Primary(ctx context.Context) *gorm.DB {
+	return db.Client.WithContext(withDBTarget(ctx, targetPrimary))
+}
+
+// ReadOnly returns a connection bound to ctx for queries that tolerate replica
+// lag. Replicas are picked round-robin; without replicas the primary is used.
+//
+//	var orders []Order
+//	db.ReadOnly(ctx).Where("tenant_id = ?", tenantID).Find(&orders)
+func (db *DB) ReadOnly(ctx context.Context) *gorm.DB {
+	conn, target := db.readTarget()
+	return conn.WithContext(withDBTarget(ctx, target))
+}
+
+// ReadOnlyTransaction runs fn in a READ ONLY transaction on the ReadOnly target,
+// so accidental writes fail even when the primary serves the read.
+func (db *DB) ReadOnlyTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return db.ReadOnly(ctx).Transaction(fn, &sql.TxOptions{ReadOnly: true})
+}
+
+func (db *DB) readTarget() (*gorm.DB, string) {
+	replicas := db.replicas.Load()
+	if replicas == nil || len(*replicas) == 0 {
+		return db.Client, targetPrimary
+	}
+	n := db.nextReplica.Add(1) - 1
+	return (*replicas)[n%uint64(len(*replicas))], targetReplica
+}
+
+func withDBTarget(ctx context.Context, target string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, dbTargetContextKey{}, target)
+}
+
+func dbTargetFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	target, _ := ctx.Value(dbTargetContextKey{}).(string)
+	return target
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestReadOnlyRoutesAcrossReplicas(t *testing.T) {
+	primary, _, _ := newCachedPlanDB(t)
+	db := &DB{Client: primary}
+	ctx := context.Background()
+
+	if conn, target := db.readTarget(); conn != primary || target != targetPrimary {
+		t.Fatalf("without replicas: target = %s", target)
+	}
+	if got := dbTargetFromContext(db.ReadOnly(ctx).Statement.Context); got != targetPrimary {
+		t.Fatalf("ReadOnly target = %q, want primary", got)
+	}
+
+	first, _, _ := newCachedPlanDB(t)
+	second, _, _ := newCachedPlanDB(t)
+	db.addReplica(first)
+	db.addReplica(second)
+	if replicas := db.Replicas(); len(replicas) != 2 || replicas[0] != first || replicas[1] != second {
+		t.Fatalf("Replicas() = %v", replicas)
+	}
+	seen := map[interface{}]int{}
+	for i := 0; i < 4; i++ {
+		conn, target := db.readTarget()
+		if target != targetReplica {
+			t.Fatalf("target = %s, want replica", target)
+		}
+		seen[conn]++
+	}
+	if seen[first] != 2 || seen[second] != 2 {
+		t.Fatalf("round-robin picks = %v", seen)
+	}
+	if got := dbTargetFromContext(db.Primary(ctx).Statement.Context); got != targetPrimary {
+		t.Fatalf("Primary target = %q", got)
+	}
+}
+
+// Run with -race: replicas are added while requests are routed.
+func TestAddReplicaWhileRouting(t *testing.T) {
+	primary, _, _ := newCachedPlanDB(t)
+	db := &DB{Client: primary}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		replica, _, _ := newCachedPlanDB(t)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			db.addReplica(replica)
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_ = db.ReadOnly(ctx)
+				_ = db.ReplicaStats()
+			}
+		}()
+	}
+	wg.Wait()
+	if n := len(db.Replicas()); n != 4 {
+		t.Fatalf("replicas = %d, want 4", n)
+	}
+}
@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/milan604/core-lab/pkg/config"
+)
+
+// defaultMaxIdleConns is database/sql's idle limit when none is configured.
+const defaultMaxIdleConns = 2
+
+// rotatingPassword is the PasswordProvider New installs for a static
+// Config.Password, so RotatePassword can swap it.
+type rotatingPassword struct {
+	value atomic.Pointer[string]
+}
+
+func newRotatingPassword(password string) *rotatingPassword {
+	p := &rotatingPassword{}
+	p.value.Store(&password)
+	return p
+}
+
+func (p *rotatingPassword) Password(context.Context) (string, error) {
+	return *p.value.Load(), nil
+}
+
+// RotatePassword makes new connections to the primary authenticate with
+// password and closes idle connections so the pool reconnects with it. DSN
+// keeps the password New connected with; CurrentDSN carries the rotated one. Postgres keeps established sessions after a
+// password change, so connections in use finish their work undisturbed. It
+// fails when Config.PasswordProvider was set, since the provider owns the
+// password; call RecycleIdleConns instead.
+func (db *DB) RotatePassword(password string) error {
+	if db.password == nil {
+		return errors.New("postgres: password is supplied by Config.PasswordProvider")
+	}
+	db.password.value.Store(&password)
+	db.RecycleIdleConns()
+	return nil
+}
+
+// CurrentDSN returns DSN with the password last set by RotatePassword, for
+// external tools that connect after a rotation. It is safe to call
+// concurrently with RotatePassword.
+func (db *DB) CurrentDSN() string {
+	if db.password == nil {
+		return db.DSN
+	}
+	u, err := url.Parse(db.DSN)
+	if err != nil || u.User == nil {
+		return db.DSN
+	}
+	password := *db.password.value.Load()
+	u.User = url.UserPassword(u.User.Username(), password)
+	return u.String()
+}
+
+// RecycleIdleConns closes the primary's idle connections, so the next queries
+// dial fresh ones, e.g. after the secret behind a PasswordProvider rotated.
+func (db *DB) RecycleIdleConns() {
+	maxIdle := db.maxIdleConns
+	if maxIdle <= 0 {
+		maxIdle = defaultMaxIdleConns
+	}
+	db.SQL.SetMaxIdleConns(-1)
+	db.SQL.SetMaxIdleConns(maxIdle)
+}
+
+// SubscribeRotation reconnects the primary whenever key is rotated on bus:
+// with RotatePassword for a static Config.Password, or RecycleIdleConns when
+// a PasswordProvider resolves the password. Publish rotations with
+// config.WithSecretRotation or config.WatchSecretFile. It returns a function
+// that removes the subscription.
+//
+//	bus := config.NewRotationBus()
+//	cfg := config.New(config.WithFile("config.yaml"), config.WithWatch(nil),
+//		config.WithSecretRotation(bus, "DBPassword"))
+//	db, err := postgres.New(postgres.Config{Password: cfg.GetString("DBPassword"), ...})
+//	unsubscribe := db.SubscribeRotation(bus, "DBPassword")
+//	defer unsubscribe()
+func (db *DB) SubscribeRotation(bus *config.RotationBus, key string) (unsubscribe func()) {
+	return bus.Subscribe(key, func(_ context.Context, rotation config.SecretRotation) error {
+		if db.password == nil {
+			db.RecycleIdleConns()
+			return nil
+		}
+		return db.RotatePassword(rotation.Value)
+	})
+}
@@ -0,0 +1,394 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"gorm.io/gorm"
+)
+
+const (
+	targetShared = "shared"
+	targetTenant = "tenant"
+
+	defaultTenantSharedMaxConns = 10
+	defaultTenantIdleTimeout    = 10 * time.Minute
+	defaultTenantMaxIdleConns   = 2
+)
+
+// AttrTenantID labels dedicated pool evictions, and shared pool waits when
+// TenantPoolConfig.TenantWaitMetrics is set.
+var AttrTenantID = attribute.Key("tenant.id")
+
+// ErrTenantPoolsClosed is returned by TenantPools once Close was called.
+var ErrTenantPoolsClosed = errors.New("postgres: tenant pools are closed")
+
+// TenantPoolConfig configures NewTenantPools.
+type TenantPoolConfig struct {
+	// Dedicated maps the IDs of large tenants to the connection limit of a
+	// pool of their own, opened on first use with the primary's settings.
+	Dedicated map[string]int
+	// SharedMaxConns caps how many operations one tenant may run at once on
+	// the shared pool (the primary), so a noisy tenant leaves connections for
+	// the others. Default: a quarter of the primary's MaxOpenConns, or 10
+	// when it is unlimited.
+	SharedMaxConns int
+	// IdleTimeout closes dedicated pools unused for this long; they reopen on
+	// the next use. Default: 10m.
+	IdleTimeout time.Duration
+	// MaxIdleConns is kept idle per dedicated pool. Default: 2.
+	MaxIdleConns int
+	// TenantWaitMetrics labels the shared pool wait metrics with tenant.id.
+	// Every tenant that waits adds a series, so leave it off unless the
+	// number of tenants is small.
+	TenantWaitMetrics bool
+}
+
+// TenantPools partitions database access by tenant. Tenants listed in
+// TenantPoolConfig.Dedicated run on pools of their own; every other tenant
+// shares the primary, limited to SharedMaxConns concurrent operations each.
+//
+//	pools, err := postgres.NewTenantPools(db, postgres.TenantPoolConfig{
+//		Dedicated:      map[string]int{"tenant-acme": 20},
+//		SharedMaxConns: 5,
+//	})
+//	defer pools.Close()
+//
+//	err = pools.Transaction(ctx, tenantID, func(tx *gorm.DB) error {
+//		return tx.Where("status = ?", "open").Find(&orders).Error
+//	})
+type TenantPools struct {
+	db      *DB
+	cfg     TenantPoolConfig
+	metrics tenantPoolMetrics
+
+	mu        sync.Mutex
+	closed    bool
+	dedicated map[string]*tenantPool
+	shares    map[string]*tenantShare
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+type tenantPool struct {
+	client   *gorm.DB
+	sql      *sql.DB
+	active   int
+	lastUsed time.Time
+}
+
+type tenantShare struct {
+	slots   chan struct{}
+	waiters int
+}
+
+// NewTenantPools returns TenantPools on db and starts evicting idle dedicated
+// pools. Call Close to stop it and close the dedicated pools.
+func NewTenantPools(db *DB, cfg TenantPoolConfig) (*TenantPools, error) {
+	if db == nil || db.Client == nil {
+		return nil, errors.New("postgres: database is required")
+	}
+	for tenantID, limit := range cfg.Dedicated {
+		if tenantID == "" || limit <= 0 {
+			return nil, fmt.Errorf("postgres: dedicated pool for tenant %q needs a positive connection limit", tenantID)
+		}
+	}
+	if cfg.SharedMaxConns <= 0 {
+		cfg.SharedMaxConns = defaultTenantSharedMaxConns
+		if db.cfg.MaxOpenConns > 0 {
+			cfg.SharedMaxConns = max(1, db.cfg.MaxOpenConns/4)
+		}
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = defaultTenantIdleTimeout
+	}
+	if cfg.MaxIdleConns <= 0 {
+		cfg.MaxIdleConns = defaultTenantMaxIdleConns
+	}
+
+	p := &TenantPools{
+		db:        db,
+		cfg:       cfg,
+		metrics:   newTenantPoolMetrics(otel.Meter(meterName), cfg.TenantWaitMetrics),
+		dedicated: make(map[string]*tenantPool),
+		shares:    make(map[string]*tenantShare),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	db.tenantPools.Store(p)
+	go p.evictLoop()
+	return p, nil
+}
+
+// Do runs fn with a connection for tenantID, bound to ctx. It waits while
+// the tenant is at its limit, until ctx is done.
+func (p *TenantPools) Do(ctx context.Context, tenantID string, fn func(db *gorm.DB) error) error {
+	client, target, release, err := p.acquire(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn(client.WithContext(withDBTarget(ctx, target)))
+}
+
+// Transaction runs fn in a transaction for tenantID with
+// app.current_tenant_id set, so row level security policies apply (see
+// SetTenantContext).
+func (p *TenantPools) Transaction(ctx context.Context, tenantID string, fn func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
+	return p.Do(ctx, tenantID, func(db *gorm.DB) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT set_config('app.current_tenant_id', ?, true)", tenantID).Error; err != nil {
+				return err
+			}
+			return fn(tx)
+		}, opts...)
+	})
+}
+
+// Stats returns the connection pool statistics of each open dedicated pool,
+// keyed by tenant ID.
+func (p *TenantPools) Stats() map[string]sql.DBStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make(map[string]sql.DBStats, len(p.dedicated))
+	for tenantID, pool := range p.dedicated {
+		stats[tenantID] = pool.sql.Stats()
+	}
+	return stats
+}
+
+// Close stops eviction and closes the dedicated pools. Operations running on
+// them fail; later calls return ErrTenantPoolsClosed.
+func (p *TenantPools) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	pools := p.dedicated
+	p.dedicated = map[string]*tenantPool{}
+	p.mu.Unlock()
+
+	close(p.stop)
+	<-p.done
+	p.db.tenantPools.CompareAndSwap(p, nil)
+
+	var errs []error
+	for _, pool := range pools {
+		errs = append(errs, pool.sql.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (p *TenantPools) acquire(ctx context.Context, tenantID string) (*gorm.DB, string, func(), error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if limit, ok := p.cfg.Dedicated[tenantID]; ok {
+		pool, err := p.dedicatedPool(tenantID, limit)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		return pool.client, targetTenant, func() { p.releaseDedicated(pool) }, nil
+	}
+	if err := p.acquireShare(ctx, tenantID); err != nil {
+		return nil, "", nil, err
+	}
+	return p.db.Client, targetShared, func() { p.releaseShare(tenantID) }, nil
+}
+
+func (p *TenantPools) dedicatedPool(tenantID string, limit int) (*tenantPool, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrTenantPoolsClosed
+	}
+	if pool, ok := p.dedicated[tenantID]; ok {
+		pool.active++
+		p.mu.Unlock()
+		return pool, nil
+	}
+	p.mu.Unlock()
+
+	// Dial outside the lock; a concurrent opener for the same tenant wins and
+	// this pool is closed.
+	cfg := p.db.cfg
+	cfg.MaxOpenConns = limit
+	cfg.MaxIdleConns = min(p.cfg.MaxIdleConns, limit)
+	client, sqlDB, _, err := open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: open pool for tenant %s: %w", tenantID, err)
+	}
+	if p.db.observer != nil {
+		if err := client.Use(p.db.observer); err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("postgres: register query observer on tenant pool: %w", err)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		_ = sqlDB.Close()
+		return nil, ErrTenantPoolsClosed
+	}
+	if existing, ok := p.dedicated[tenantID]; ok {
+		_ = sqlDB.Close()
+		existing.active++
+		return existing, nil
+	}
+	pool := &tenantPool{client: client, sql: sqlDB, active: 1}
+	p.dedicated[tenantID] = pool
+	log.Printf("[Postgres] Dedicated pool opened for tenant %s (max %d connections)", tenantID, limit)
+	return pool, nil
+}
+
+func (p *TenantPools) releaseDedicated(pool *tenantPool) {
+	p.mu.Lock()
+	pool.active--
+	pool.lastUsed = time.Now()
+	p.mu.Unlock()
+}
+
+// acquireShare takes one of tenantID's slots on the shared pool.
+func (p *TenantPools) acquireShare(ctx context.Context, tenantID string) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrTenantPoolsClosed
+	}
+	share, ok := p.shares[tenantID]
+	if !ok {
+		share = &tenantShare{slots: make(chan struct{}, p.cfg.SharedMaxConns)}
+		p.shares[tenantID] = share
+	}
+	select {
+	case share.slots <- struct{}{}:
+		p.mu.Unlock()
+		return nil
+	default:
+	}
+	share.waiters++
+	p.mu.Unlock()
+
+	started := time.Now()
+	var err error
+	select {
+	case share.slots <- struct{}{}:
+	case <-ctx.Done():
+		err = fmt.Errorf("postgres: wait for tenant %s connection slot: %w", tenantID, ctx.Err())
+	}
+	p.metrics.recordWait(ctx, tenantID, time.Since(started))
+
+	p.mu.Lock()
+	share.waiters--
+	p.dropIdleShareLocked(tenantID, share)
+	p.mu.Unlock()
+	return err
+}
+
+func (p *TenantPools) releaseShare(tenantID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	share, ok := p.shares[tenantID]
+	if !ok {
+		return
+	}
+	<-share.slots
+	p.dropIdleShareLocked(tenantID, share)
+}
+
+// dropIdleShareLocked forgets tenants with nothing running or waiting, so
+// the share map only holds active tenants.
+func (p *TenantPools) dropIdleShareLocked(tenantID string, share *tenantShare) {
+	if len(share.slots) == 0 && share.waiters == 0 {
+		delete(p.shares, tenantID)
+	}
+}
+
+func (p *TenantPools) evictLoop() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.cfg.IdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			p.evictIdle(now)
+		}
+	}
+}
+
+// evictIdle closes dedicated pools with nothing running that have been
+// unused for the idle timeout.
+func (p *TenantPools) evictIdle(now time.Time) {
+	p.mu.Lock()
+	var evicted []*tenantPool
+	for tenantID, pool := range p.dedicated {
+		if pool.active == 0 && now.Sub(pool.lastUsed) >= p.cfg.IdleTimeout {
+			delete(p.dedicated, tenantID)
+			evicted = append(evicted, pool)
+			p.metrics.recordEviction(tenantID)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, pool := range evicted {
+		_ = pool.sql.Close()
+	}
+}
+
+type tenantPoolMetrics struct {
+	byTenant     bool
+	waits        metric.Int64Counter
+	waitDuration metric.Float64Histogram
+	evictions    metric.Int64Counter
+}
+
+func newTenantPoolMetrics(meter metric.Meter, byTenant bool) tenantPoolMetrics {
+	m := tenantPoolMetrics{byTenant: byTenant}
+	var err error
+	if m.waits, err = meter.Int64Counter("db.client.tenant.waits",
+		metric.WithDescription("Operations that waited because their tenant was at its shared pool limit"), metric.WithUnit("{wait}")); err != nil {
+		otel.Handle(fmt.Errorf("create db.client.tenant.waits: %w", err))
+	}
+	if m.waitDuration, err = meter.Float64Histogram("db.client.tenant.wait_duration",
+		metric.WithDescription("Time operations waited for their tenant's shared pool slot"), metric.WithUnit("s")); err != nil {
+		otel.Handle(fmt.Errorf("create db.client.tenant.wait_duration: %w", err))
+	}
+	if m.evictions, err = meter.Int64Counter("db.client.tenant.pool.evictions",
+		metric.WithDescription("Dedicated tenant pools closed after being idle"), metric.WithUnit("{pool}")); err != nil {
+		otel.Handle(fmt.Errorf("create db.client.tenant.pool.evictions: %w", err))
+	}
+	return m
+}
+
+func (m tenantPoolMetrics) recordWait(ctx context.Context, tenantID string, waited time.Duration) {
+	var labels []attribute.KeyValue
+	if m.byTenant {
+		labels = append(labels, AttrTenantID.String(tenantID))
+	}
+	attrs := metric.WithAttributes(labels...)
+	if m.waits != nil {
+		m.waits.Add(ctx, 1, attrs)
+	}
+	if m.waitDuration != nil {
+		m.waitDuration.Record(ctx, waited.Seconds(), attrs)
+	}
+}
+
+func (m tenantPoolMetrics) recordEviction(tenantID string) {
+	if m.evictions != nil {
+		m.evictions.Add(context.Background(), 1, metric.WithAttributes(AttrTenantID.String(tenantID)))
+	}
+}
@@ -0,0 +1,192 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"gorm.io/gorm"
+)
+
+func newTestTenantPools(t *testing.T, cfg TenantPoolConfig) (*TenantPools, *sdkmetric.ManualReader) {
+	t.Helper()
+	client, _, _ := newCachedPlanDB(t)
+	sqlDB, err := client.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pools, err := NewTenantPools(&DB{Client: client, SQL: sqlDB}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = pools.Close() })
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	pools.metrics = newTenantPoolMetrics(meter, cfg.TenantWaitMetrics)
+	return pools, reader
+}
+
+// Run with -race: many operations of a few tenants share the primary.
+func TestTenantPoolsCapSharedConcurrency(t *testing.T) {
+	pools, _ := newTestTenantPools(t, TenantPoolConfig{SharedMaxConns: 2})
+	ctx := context.Background()
+
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := pools.Do(ctx, "tenant-a", func(db *gorm.DB) error {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				if target := dbTargetFromContext(db.Statement.Context); target != targetShared {
+					t.Errorf("target = %q", target)
+				}
+				time.Sleep(time.Millisecond)
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	// Other tenants are not held up by tenant-a.
+	for i := 0; i < 5; i++ {
+		if err := pools.Do(ctx, "tenant-b", func(*gorm.DB) error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p > 2 {
+		t.Fatalf("peak concurrency = %d, want at most 2", p)
+	}
+	pools.mu.Lock()
+	defer pools.mu.Unlock()
+	if len(pools.shares) != 0 {
+		t.Fatalf("shares = %v, want idle tenants forgotten", pools.shares)
+	}
+}
+
+func TestTenantPoolsWaitMetrics(t *testing.T) {
+	for _, byTenant := range []bool{false, true} {
+		pools, reader := newTestTenantPools(t, TenantPoolConfig{SharedMaxConns: 1, TenantWaitMetrics: byTenant})
+		ctx := context.Background()
+
+		holding, release := make(chan struct{}), make(chan struct{})
+		go func() {
+			_ = pools.Do(ctx, "tenant-a", func(*gorm.DB) error {
+				close(holding)
+				<-release
+				return nil
+			})
+		}()
+		<-holding
+
+		waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		err := pools.Do(waitCtx, "tenant-a", func(*gorm.DB) error { return nil })
+		cancel()
+		close(release)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("err = %v, want the wait to end with the context", err)
+		}
+
+		var rm metricdata.ResourceMetrics
+		if err := reader.Collect(ctx, &rm); err != nil {
+			t.Fatal(err)
+		}
+		waits := findSum(t, rm, "db.client.tenant.waits")
+		if len(waits.DataPoints) != 1 || waits.DataPoints[0].Value != 1 {
+			t.Fatalf("waits = %+v", waits.DataPoints)
+		}
+		tenant, ok := waits.DataPoints[0].Attributes.Value(AttrTenantID)
+		if ok != byTenant || (byTenant && tenant.AsString() != "tenant-a") {
+			t.Fatalf("TenantWaitMetrics=%v: tenant.id = %v, %v", byTenant, tenant, ok)
+		}
+	}
+}
+
+// Run with -race: dedicated pools are used while idle ones are evicted.
+func TestTenantPoolsEvictIdleDedicated(t *testing.T) {
+	pools, reader := newTestTenantPools(t, TenantPoolConfig{Dedicated: map[string]int{"tenant-big": 5}, IdleTimeout: time.Minute})
+	ctx := context.Background()
+	dedicated, _, _ := newCachedPlanDB(t)
+	sqlDB, err := dedicated.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pools.dedicated["tenant-big"] = &tenantPool{client: dedicated, sql: sqlDB, lastUsed: time.Now()}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			err := pools.Do(ctx, "tenant-big", func(db *gorm.DB) error {
+				if target := dbTargetFromContext(db.Statement.Context); target != targetTenant {
+					t.Errorf("target = %q", target)
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, ErrTenantPoolsClosed) {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			pools.evictIdle(time.Now())
+			_ = pools.Stats()
+		}()
+	}
+	wg.Wait()
+	if len(pools.Stats()) != 1 {
+		t.Fatal("pool evicted before its idle timeout")
+	}
+
+	pools.evictIdle(time.Now().Add(time.Minute))
+	if len(pools.Stats()) != 0 {
+		t.Fatal("idle pool not evicted")
+	}
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	evictions := findSum(t, rm, "db.client.tenant.pool.evictions")
+	want := attribute.NewSet(AttrTenantID.String("tenant-big"))
+	if len(evictions.DataPoints) != 1 || !evictions.DataPoints[0].Attributes.Equals(&want) {
+		t.Fatalf("evictions = %+v", evictions.DataPoints)
+	}
+
+	if err := pools.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pools.Do(ctx, "tenant-small", func(*gorm.DB) error { return nil }); !errors.Is(err, ErrTenantPoolsClosed) {
+		t.Fatalf("Do after Close = %v", err)
+	}
+}
+
+func findSum(t *testing.T, rm metricdata.ResourceMetrics, name string) metricdata.Sum[int64] {
+	t.Helper()
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name == name {
+				return m.Data.(metricdata.Sum[int64])
+			}
+		}
+	}
+	t.Fatalf("metric %s not recorded", name)
+	return metricdata.Sum[int64]{}
+}
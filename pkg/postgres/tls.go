@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// splitRootCert tells a PEM-encoded CA bundle apart from a file path.
+func splitRootCert(rootCert string) (path, pem string) {
+	rootCert = strings.TrimSpace(rootCert)
+	if strings.Contains(rootCert, "-----BEGIN") {
+		return "", rootCert
+	}
+	return rootCert, ""
+}
+
+var rootCertFiles sync.Map // sha256 of the PEM -> file path
+
+// rootCertFile writes pem to a temporary file readable only by the process
+// owner, so DSN consumers can point sslrootcert at it. Each bundle is written
+// once per process.
+func rootCertFile(pem string) (string, error) {
+	sum := sha256.Sum256([]byte(pem))
+	key := hex.EncodeToString(sum[:])
+	if path, ok := rootCertFiles.Load(key); ok {
+		return path.(string), nil
+	}
+	file, err := os.CreateTemp("", "postgres-root-*.pem")
+	if err != nil {
+		return "", fmt.Errorf("postgres: write SSLRootCert: %w", err)
+	}
+	_, err = file.WriteString(pem)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("postgres: write SSLRootCert: %w", err)
+	}
+	path, loaded := rootCertFiles.LoadOrStore(key, file.Name())
+	if loaded {
+		_ = os.Remove(file.Name())
+	}
+	return path.(string), nil
+}
+
+// applyRootCertPEM makes every TLS config pgx derived from sslmode trust the
+// CAs in pem. pgx's verify-ca check reads RootCAs from the same config, so
+// updating it in place covers verify-ca and verify-full alike.
+func applyRootCertPEM(config *pgx.ConnConfig, pem string) error {
+	if pem == "" {
+		return nil
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(pem)) {
+		return errors.New("postgres: SSLRootCert contains no valid PEM certificates")
+	}
+	tlsConfig := config.TLSConfig
+	if tlsConfig == nil || (tlsConfig.InsecureSkipVerify && tlsConfig.VerifyPeerCertificate == nil) {
+		return errors.New("postgres: a PEM SSLRootCert requires sslmode verify-ca or verify-full")
+	}
+	tlsConfig.RootCAs = pool
+	for _, fallback := range config.Fallbacks {
+		if fallback.TLSConfig != nil {
+			fallback.TLSConfig.RootCAs = pool
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"os"
+	"testing"
+)
+
+func TestRootCertFile(t *testing.T) {
+	const pem = "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"
+	path, err := rootCertFile(pem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		rootCertFiles.Clear()
+		_ = os.Remove(path)
+	})
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		t.Fatalf("permissions = %v, want readable by the owner only", perm)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != pem {
+		t.Fatalf("content = %q, %v", data, err)
+	}
+	again, err := rootCertFile(pem)
+	if err != nil || again != path {
+		t.Fatalf("second write = %q, %v; want %q reused", again, err, path)
+	}
+}
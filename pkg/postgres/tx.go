@@ -0,0 +1,183 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/milan604/core-lab/pkg/observability"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// SQLSTATE codes WithinTx retries.
+const (
+	SQLStateSerializationFailure = "40001"
+	SQLStateDeadlockDetected     = "40P01"
+)
+
+// EventTxRetry is the span event recorded before WithinTx retries a
+// transaction.
+const EventTxRetry = "db.transaction.retry"
+
+var (
+	attrTxAttempts  = attribute.Key("db.transaction.attempts")
+	attrTxIsolation = attribute.Key("db.transaction.isolation")
+	attrSQLState    = attribute.Key("db.response.status_code")
+)
+
+// TxOption configures WithinTx.
+type TxOption func(*txOptions)
+
+type txOptions struct {
+	sql            *sql.TxOptions
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// WithTxOptions sets the isolation level and read-only flag of the
+// transaction. Retries matter most under sql.LevelSerializable, where
+// Postgres reports conflicts as serialization failures.
+func WithTxOptions(opts *sql.TxOptions) TxOption {
+	return func(o *txOptions) { o.sql = opts }
+}
+
+// WithTxMaxAttempts sets how many times WithinTx runs the transaction,
+// including the first attempt (3 by default). 1 disables retries.
+func WithTxMaxAttempts(n int) TxOption {
+	return func(o *txOptions) { o.maxAttempts = n }
+}
+
+// WithTxBackoff sets the retry backoff: a random delay up to initial, doubling
+// per retry and capped at maxDelay (50ms and 1s by default).
+func WithTxBackoff(initial, maxDelay time.Duration) TxOption {
+	return func(o *txOptions) {
+		o.initialBackoff = initial
+		o.maxBackoff = maxDelay
+	}
+}
+
+// WithinTx runs fn in a transaction on the primary bound to ctx, committing
+// when fn returns nil and rolling back when it returns an error or panics.
+// When the transaction fails with a serialization failure or deadlock (see
+// IsRetryableTxError) it is rolled back and fn runs again after a jittered
+// backoff, so fn must only have effects inside tx. Cancelling ctx rolls the
+// transaction back and stops retrying.
+//
+// The attempts run in a "postgres.transaction" span, with an EventTxRetry
+// event per retry; statements issued through tx are its children when the
+// QueryObserver is registered.
+//
+//	err := db.WithinTx(ctx, func(tx *gorm.DB) error {
+//		if err := tx.Create(&order).Error; err != nil {
+//			return err
+//		}
+//		return tx.Model(&stock).Update("quantity", gorm.Expr("quantity - ?", order.Quantity)).Error
+//	}, postgres.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelSerializable}))
+func (db *DB) WithinTx(ctx context.Context, fn func(tx *gorm.DB) error, opts ...TxOption) error {
+	o := txOptions{maxAttempts: 3, initialBackoff: 50 * time.Millisecond, maxBackoff: time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.maxAttempts <= 0 {
+		o.maxAttempts = 1
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	attrs := []attribute.KeyValue{observability.AttrDBSystem.String("postgresql")}
+	if db.name != "" {
+		attrs = append(attrs, AttrDBName.String(db.name))
+	}
+	if o.sql != nil {
+		attrs = append(attrs, attrTxIsolation.String(o.sql.Isolation.String()))
+	}
+	ctx, span := db.tracer().Start(ctx, "postgres.transaction",
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	defer span.End()
+
+	for attempt := 1; ; attempt++ {
+		err := ctx.Err()
+		if err == nil {
+			err = db.Primary(ctx).Transaction(fn, o.sql)
+		}
+		if err == nil {
+			span.SetAttributes(attrTxAttempts.Int(attempt))
+			return nil
+		}
+		if attempt >= o.maxAttempts || ctx.Err() != nil || !IsRetryableTxError(err) {
+			span.SetAttributes(attrTxAttempts.Int(attempt))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+
+		delay := txBackoff(o, attempt)
+		span.AddEvent(EventTxRetry, trace.WithAttributes(
+			attrTxAttempts.Int(attempt+1),
+			attrSQLState.String(sqlState(err)),
+			attribute.Float64("db.transaction.retry.delay", delay.Seconds()),
+		))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			span.SetAttributes(attrTxAttempts.Int(attempt))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// IsRetryableTxError reports whether err is a Postgres serialization failure
+// (40001) or deadlock (40P01), after which the whole transaction can be
+// retried.
+func IsRetryableTxError(err error) bool {
+	switch sqlState(err) {
+	case SQLStateSerializationFailure, SQLStateDeadlockDetected:
+		return true
+	}
+	return false
+}
+
+func sqlState(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// txBackoff returns a full-jitter delay before the retry following attempt.
+func txBackoff(o txOptions, attempt int) time.Duration {
+	ceiling := o.initialBackoff
+	for i := 1; i < attempt && (o.maxBackoff <= 0 || ceiling < o.maxBackoff); i++ {
+		ceiling *= 2
+	}
+	if o.maxBackoff > 0 && ceiling > o.maxBackoff {
+		ceiling = o.maxBackoff
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling) + 1
+}
+
+// tracer returns the QueryObserver's tracer from WithObservability, or the
+// global one.
+func (db *DB) tracer() trace.Tracer {
+	if db.observer != nil {
+		return db.observer.Tracer()
+	}
+	return otel.Tracer(meterName)
+}
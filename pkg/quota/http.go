@@ -0,0 +1,105 @@
+package quota
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/milan604/core-lab/pkg/apperr"
+	"github.com/milan604/core-lab/pkg/response"
+)
+
+// SetLimitRequest is the body of the set-limit admin route. A negative limit
+// makes the policy unlimited for the tenant.
+type SetLimitRequest struct {
+	Limit *int64 `json:"limit" binding:"required"`
+}
+
+// SetUsageRequest is the body of the set-usage admin route.
+type SetUsageRequest struct {
+	Used *int64 `json:"used" binding:"required"`
+}
+
+// RegisterAdminRoutes mounts tenant quota administration routes onto the
+// provided router. Mount them on a group guarded by an admin permission:
+//
+//	GET    /tenants/:tenant_id/quotas                         usage of every policy
+//	PUT    /tenants/:tenant_id/quotas/:metric/:period         override the limit
+//	DELETE /tenants/:tenant_id/quotas/:metric/:period         restore the default limit
+//	PUT    /tenants/:tenant_id/quotas/:metric/:period/usage   overwrite the current usage
+//
+// Every route responds with the tenant's usage after the change.
+func RegisterAdminRoutes(router gin.IRoutes, manager *Manager) {
+	router.GET("/tenants/:tenant_id/quotas", func(c *gin.Context) {
+		respondUsage(c, manager, c.Param("tenant_id"))
+	})
+
+	router.PUT("/tenants/:tenant_id/quotas/:metric/:period", func(c *gin.Context) {
+		period, ok := periodParam(c)
+		if !ok {
+			return
+		}
+		var req SetLimitRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.HandleError(c, apperr.New(apperr.ErrorCodeInvalidRequest).
+				WithMessage("invalid quota limit request").
+				AddSuggestion("limit", "provide the new limit; a negative limit means unlimited"))
+			return
+		}
+		policy := Policy{Metric: c.Param("metric"), Period: period, Limit: *req.Limit}
+		if err := manager.SetLimit(c.Request.Context(), c.Param("tenant_id"), policy); err != nil {
+			response.HandleError(c, err)
+			return
+		}
+		respondUsage(c, manager, c.Param("tenant_id"))
+	})
+
+	router.DELETE("/tenants/:tenant_id/quotas/:metric/:period", func(c *gin.Context) {
+		period, ok := periodParam(c)
+		if !ok {
+			return
+		}
+		if err := manager.ResetLimit(c.Request.Context(), c.Param("tenant_id"), c.Param("metric"), period); err != nil {
+			response.HandleError(c, err)
+			return
+		}
+		respondUsage(c, manager, c.Param("tenant_id"))
+	})
+
+	router.PUT("/tenants/:tenant_id/quotas/:metric/:period/usage", func(c *gin.Context) {
+		period, ok := periodParam(c)
+		if !ok {
+			return
+		}
+		var req SetUsageRequest
+		if err := c.ShouldBindJSON(&req); err != nil || *req.Used < 0 {
+			response.HandleError(c, apperr.New(apperr.ErrorCodeInvalidRequest).
+				WithMessage("invalid quota usage request").
+				AddSuggestion("used", "provide the usage to record, zero or more"))
+			return
+		}
+		if err := manager.SetUsage(c.Request.Context(), c.Param("tenant_id"), c.Param("metric"), period, *req.Used); err != nil {
+			response.HandleError(c, err)
+			return
+		}
+		respondUsage(c, manager, c.Param("tenant_id"))
+	})
+}
+
+func periodParam(c *gin.Context) (Period, bool) {
+	period, err := ParsePeriod(c.Param("period"))
+	if err != nil {
+		response.HandleError(c, apperr.New(apperr.ErrorCodeInvalidInput).
+			WithMessage("unknown quota period").
+			AddSuggestion("period", "use daily or monthly"))
+		return "", false
+	}
+	return period, true
+}
+
+func respondUsage(c *gin.Context, manager *Manager, tenantID string) {
+	usage, err := manager.Usage(c.Request.Context(), tenantID)
+	if err != nil {
+		response.HandleError(c, err)
+		return
+	}
+	response.Success(c, usage)
+}
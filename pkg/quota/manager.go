@@ -0,0 +1,408 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/milan604/core-lab/pkg/auth"
+	coreerrors "github.com/milan604/core-lab/pkg/errors"
+	"github.com/milan604/core-lab/pkg/logger"
+	coretenant "github.com/milan604/core-lab/pkg/tenant"
+)
+
+// MetricAPICalls is the metric Manager.Middleware charges one unit per
+// request by default.
+const MetricAPICalls = "api_calls"
+
+// ManagerConfig configures tenant quotas backed by a shared Store.
+type ManagerConfig struct {
+	Enabled bool
+	// Store holds counters and overrides; use a RedisStore or PostgresStore
+	// when more than one replica serves the tenant. Defaults to a MemoryStore.
+	Store Store
+	// Policies are the default limits of every tenant.
+	Policies []Policy
+	// PoliciesFor, when set, returns the limits of a tenant, e.g. from its
+	// plan; a nil result falls back to Policies. Overrides set through the
+	// admin API win over both.
+	PoliciesFor func(ctx context.Context, tenantID string) ([]Policy, error)
+	// Metric is the metric Middleware charges per request.
+	Metric string
+	// TenantID resolves the tenant of a request. The default reads the
+	// tenant set by the auth middleware; requests without one are not
+	// charged.
+	TenantID func(c *gin.Context) string
+	// FailOpen lets requests through when the store is unavailable; by
+	// default they are rejected with 503.
+	FailOpen bool
+	// OnExceeded, when set, is called for every request rejected for usage.
+	OnExceeded func(c *gin.Context, usage Usage)
+	Logger     logger.LogManager
+}
+
+// DefaultManagerConfig charges api_calls against 10000 calls a day and
+// 250000 a month per tenant, counted in memory.
+func DefaultManagerConfig() ManagerConfig {
+	return ManagerConfig{
+		Enabled: true,
+		Policies: []Policy{
+			{Metric: MetricAPICalls, Period: PeriodDaily, Limit: 10000},
+			{Metric: MetricAPICalls, Period: PeriodMonthly, Limit: 250000},
+		},
+		Metric: MetricAPICalls,
+	}
+}
+
+// Usage is a tenant's consumption of one policy in the current window.
+type Usage struct {
+	TenantID string `json:"tenant_id"`
+	Metric   string `json:"metric"`
+	Period   Period `json:"period"`
+	Window   string `json:"window"`
+	// Limit is negative for unlimited policies, which report no Remaining.
+	Limit     int64     `json:"limit"`
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+	// Override is set when the limit comes from a per-tenant override.
+	Override bool `json:"override,omitempty"`
+}
+
+// Decision is the outcome of Manager.Charge.
+type Decision struct {
+	Allowed bool `json:"allowed"`
+	// Usage lists the policies of the metric after the charge, or before it
+	// when it was rejected.
+	Usage []Usage `json:"usage"`
+	// Exceeded is the policy that rejected the charge.
+	Exceeded *Usage `json:"exceeded,omitempty"`
+}
+
+// Manager enforces per-tenant daily and monthly quotas shared by every
+// replica through its Store, and lets operators inspect and adjust them.
+type Manager struct {
+	cfg ManagerConfig
+	now func() time.Time
+}
+
+// NewManager validates cfg and creates a Manager.
+func NewManager(cfg ManagerConfig) (*Manager, error) {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if strings.TrimSpace(cfg.Metric) == "" {
+		cfg.Metric = MetricAPICalls
+	}
+	if cfg.TenantID == nil {
+		cfg.TenantID = requestTenantID
+	}
+	for _, p := range cfg.Policies {
+		if err := validatePolicy(p); err != nil {
+			return nil, err
+		}
+	}
+	return &Manager{cfg: cfg, now: time.Now}, nil
+}
+
+func validatePolicy(p Policy) error {
+	if strings.TrimSpace(p.Metric) == "" {
+		return errors.New("quota: policy metric is required")
+	}
+	if _, err := ParsePeriod(string(p.Period)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Policies returns the effective policies of tenantID: its defaults with
+// the overrides applied, sorted by metric and period.
+func (m *Manager) Policies(ctx context.Context, tenantID string) ([]Policy, error) {
+	policies, _, err := m.policies(ctx, tenantID)
+	return policies, err
+}
+
+// policies returns the effective policies of tenantID and which of them are
+// overrides.
+func (m *Manager) policies(ctx context.Context, tenantID string) ([]Policy, map[overrideKey]bool, error) {
+	defaults := m.cfg.Policies
+	if m.cfg.PoliciesFor != nil {
+		planned, err := m.cfg.PoliciesFor(ctx, tenantID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("quota: resolve policies of tenant %s: %w", tenantID, err)
+		}
+		if planned != nil {
+			defaults = planned
+		}
+	}
+	overrides, err := m.cfg.Store.Overrides(ctx, tenantID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("quota: load overrides of tenant %s: %w", tenantID, err)
+	}
+
+	byKey := make(map[overrideKey]Policy, len(defaults)+len(overrides))
+	for _, p := range defaults {
+		byKey[overrideKey{p.Metric, p.Period}] = p
+	}
+	overridden := make(map[overrideKey]bool, len(overrides))
+	for _, p := range overrides {
+		key := overrideKey{p.Metric, p.Period}
+		byKey[key] = p
+		overridden[key] = true
+	}
+	out := make([]Policy, 0, len(byKey))
+	for _, p := range byKey {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Metric != out[j].Metric {
+			return out[i].Metric < out[j].Metric
+		}
+		return periodOrder(out[i].Period) < periodOrder(out[j].Period)
+	})
+	return out, overridden, nil
+}
+
+func periodOrder(p Period) int {
+	if p == PeriodMonthly {
+		return 1
+	}
+	return 0
+}
+
+// Charge adds delta units of metric to every policy of tenantID for that
+// metric. It is all or nothing: when one policy would pass its limit,
+// nothing is charged and the decision names the exceeded policy.
+func (m *Manager) Charge(ctx context.Context, tenantID, metric string, delta int64) (Decision, error) {
+	policies, overridden, err := m.policies(ctx, tenantID)
+	if err != nil {
+		return Decision{}, err
+	}
+	now := m.now()
+	decision := Decision{Allowed: true}
+	var charged []CounterKey
+	var expiries []time.Time
+	for _, p := range policies {
+		if p.Metric != metric {
+			continue
+		}
+		key, resetAt := counterKey(tenantID, p, now)
+		used, ok, err := m.cfg.Store.Increment(ctx, key, delta, p.Limit, resetAt)
+		if err != nil {
+			m.refund(ctx, charged, expiries, delta)
+			return Decision{}, fmt.Errorf("quota: charge %s of tenant %s: %w", metric, tenantID, err)
+		}
+		usage := newUsage(key, p, used, resetAt, overridden[overrideKey{p.Metric, p.Period}])
+		if !ok {
+			m.refund(ctx, charged, expiries, delta)
+			for i := range decision.Usage {
+				decision.Usage[i].Used -= delta
+				decision.Usage[i].Remaining = remaining(decision.Usage[i].Limit, decision.Usage[i].Used)
+			}
+			decision.Allowed = false
+			decision.Usage = append(decision.Usage, usage)
+			decision.Exceeded = &usage
+			return decision, nil
+		}
+		charged = append(charged, key)
+		expiries = append(expiries, resetAt)
+		decision.Usage = append(decision.Usage, usage)
+	}
+	return decision, nil
+}
+
+// refund takes delta back from counters charged before a policy rejected
+// the charge.
+func (m *Manager) refund(ctx context.Context, keys []CounterKey, expiries []time.Time, delta int64) {
+	for i, key := range keys {
+		if _, _, err := m.cfg.Store.Increment(ctx, key, -delta, -1, expiries[i]); err != nil && m.cfg.Logger != nil {
+			m.cfg.Logger.WarnFCtx(ctx, "quota: refund %s %s of tenant %s: %v", key.Metric, key.Period, key.TenantID, err)
+		}
+	}
+}
+
+// Usage returns the usage of every policy of tenantID in the current
+// windows.
+func (m *Manager) Usage(ctx context.Context, tenantID string) ([]Usage, error) {
+	policies, overridden, err := m.policies(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	now := m.now()
+	out := make([]Usage, 0, len(policies))
+	for _, p := range policies {
+		key, resetAt := counterKey(tenantID, p, now)
+		used, err := m.cfg.Store.Counter(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("quota: read %s of tenant %s: %w", p.Metric, tenantID, err)
+		}
+		out = append(out, newUsage(key, p, used, resetAt, overridden[overrideKey{p.Metric, p.Period}]))
+	}
+	return out, nil
+}
+
+// SetLimit overrides the limit of policy.Metric and policy.Period for
+// tenantID, e.g. to grant a temporary increase.
+func (m *Manager) SetLimit(ctx context.Context, tenantID string, policy Policy) error {
+	if err := validatePolicy(policy); err != nil {
+		return err
+	}
+	return m.cfg.Store.SetOverride(ctx, tenantID, policy)
+}
+
+// ResetLimit removes the override of metric and period for tenantID.
+func (m *Manager) ResetLimit(ctx context.Context, tenantID, metric string, period Period) error {
+	return m.cfg.Store.DeleteOverride(ctx, tenantID, metric, period)
+}
+
+// SetUsage overwrites the counter of metric in the current period window of
+// tenantID, e.g. zero to forgive usage after an incident.
+func (m *Manager) SetUsage(ctx context.Context, tenantID, metric string, period Period, used int64) error {
+	if used < 0 {
+		return errors.New("quota: usage cannot be negative")
+	}
+	key, resetAt := counterKey(tenantID, Policy{Metric: metric, Period: period}, m.now())
+	return m.cfg.Store.SetCounter(ctx, key, used, resetAt)
+}
+
+// Middleware charges one unit of the configured metric per request to the
+// caller's tenant. It sets X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset from the policy closest to its limit and rejects
+// exhausted tenants with 429 and Retry-After. Service tokens and requests
+// without a tenant are not charged.
+func (m *Manager) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !m.cfg.Enabled {
+			c.Next()
+			return
+		}
+		if claims, ok := auth.GetClaims(c); ok && claims.IsServiceToken() {
+			c.Next()
+			return
+		}
+		tenantID := strings.TrimSpace(m.cfg.TenantID(c))
+		if tenantID == "" {
+			c.Next()
+			return
+		}
+
+		decision, err := m.Charge(c.Request.Context(), tenantID, m.cfg.Metric, 1)
+		if err != nil {
+			if m.cfg.FailOpen {
+				if m.cfg.Logger != nil {
+					m.cfg.Logger.WarnFCtx(c, "tenant quota store failed, allowing request through: %v", err)
+				}
+				c.Next()
+				return
+			}
+			if m.cfg.Logger != nil {
+				m.cfg.Logger.ErrorFCtx(c, "tenant quota store failed: %v", err)
+			}
+			se := coreerrors.ServiceUnavailable("tenant quota service is unavailable")
+			c.AbortWithStatusJSON(se.HTTPStatus, gin.H{
+				"error":   se.Code,
+				"message": se.Message,
+			})
+			return
+		}
+
+		if tightest, ok := tightestUsage(decision); ok {
+			ApplyHeaders(c, tightest.checkResponse())
+		}
+		if decision.Allowed {
+			c.Next()
+			return
+		}
+
+		exceeded := *decision.Exceeded
+		if m.cfg.OnExceeded != nil {
+			m.cfg.OnExceeded(c, exceeded)
+		}
+		retryAfter := int64(exceeded.ResetAt.Sub(m.now()).Seconds() + 1)
+		c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error":     ReasonQuotaExceeded,
+			"message":   MessageForReason(ReasonQuotaExceeded),
+			"tenant_id": tenantID,
+			"metric":    exceeded.Metric,
+			"period":    exceeded.Period,
+			"limit":     exceeded.Limit,
+			"used":      exceeded.Used,
+			"reset_at":  exceeded.ResetAt,
+		})
+	}
+}
+
+// tightestUsage returns the exceeded policy, or else the limited policy
+// with the fewest units left.
+func tightestUsage(d Decision) (Usage, bool) {
+	if d.Exceeded != nil {
+		return *d.Exceeded, true
+	}
+	var best Usage
+	found := false
+	for _, u := range d.Usage {
+		if u.Limit < 0 {
+			continue
+		}
+		if !found || u.Remaining < best.Remaining {
+			best, found = u, true
+		}
+	}
+	return best, found
+}
+
+func (u Usage) checkResponse() CheckResponse {
+	limit, left := u.Limit, u.Remaining
+	resetAt := u.ResetAt.Format(time.RFC3339)
+	return CheckResponse{Limit: &limit, Remaining: &left, ResetAt: &resetAt}
+}
+
+func counterKey(tenantID string, p Policy, now time.Time) (CounterKey, time.Time) {
+	window, resetAt := p.Period.Window(now)
+	return CounterKey{TenantID: tenantID, Metric: p.Metric, Period: p.Period, Window: window}, resetAt
+}
+
+func newUsage(key CounterKey, p Policy, used int64, resetAt time.Time, override bool) Usage {
+	return Usage{
+		TenantID:  key.TenantID,
+		Metric:    key.Metric,
+		Period:    key.Period,
+		Window:    key.Window,
+		Limit:     p.Limit,
+		Used:      used,
+		Remaining: remaining(p.Limit, used),
+		ResetAt:   resetAt,
+		Override:  override,
+	}
+}
+
+func remaining(limit, used int64) int64 {
+	if limit < 0 || used >= limit {
+		return 0
+	}
+	return limit - used
+}
+
+// requestTenantID reads the tenant set by the auth middleware.
+func requestTenantID(c *gin.Context) string {
+	if tenantID := extractTenantID(c); tenantID != "" {
+		return tenantID
+	}
+	if claims, ok := auth.GetClaims(c); ok {
+		if tenantID := claims.TenantID(); tenantID != "" {
+			return tenantID
+		}
+	}
+	if rc, ok := coretenant.RequestContextFromContext(c.Request.Context()); ok {
+		return rc.TenantID
+	}
+	return ""
+}
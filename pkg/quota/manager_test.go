@@ -0,0 +1,157 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func testManager(t *testing.T, store Store, policies ...Policy) *Manager {
+	t.Helper()
+	cfg := DefaultManagerConfig()
+	cfg.Store = store
+	cfg.Policies = policies
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	m.now = func() time.Time { return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) }
+	return m
+}
+
+func TestManagerChargeIsAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	m := testManager(t, NewMemoryStore(),
+		Policy{Metric: MetricAPICalls, Period: PeriodDaily, Limit: 3},
+		Policy{Metric: MetricAPICalls, Period: PeriodMonthly, Limit: 4},
+	)
+	if err := m.SetUsage(ctx, "t1", MetricAPICalls, PeriodMonthly, 2); err != nil {
+		t.Fatalf("SetUsage: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if d, err := m.Charge(ctx, "t1", MetricAPICalls, 1); err != nil || !d.Allowed {
+			t.Fatalf("charge %d = %+v, %v; want allowed", i, d, err)
+		}
+	}
+	d, err := m.Charge(ctx, "t1", MetricAPICalls, 1)
+	if err != nil {
+		t.Fatalf("Charge: %v", err)
+	}
+	if d.Allowed || d.Exceeded == nil || d.Exceeded.Period != PeriodMonthly || d.Exceeded.Window != "2026-10" {
+		t.Fatalf("decision = %+v, want the monthly policy exceeded", d)
+	}
+
+	usage, err := m.Usage(ctx, "t1")
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+	if len(usage) != 2 || usage[0].Period != PeriodDaily || usage[0].Used != 2 || usage[1].Used != 4 {
+		t.Fatalf("usage = %+v, want the rejected charge refunded from daily", usage)
+	}
+
+	if err := m.SetLimit(ctx, "t1", Policy{Metric: MetricAPICalls, Period: PeriodMonthly, Limit: -1}); err != nil {
+		t.Fatalf("SetLimit: %v", err)
+	}
+	if d, err := m.Charge(ctx, "t1", MetricAPICalls, 1); err != nil || !d.Allowed || !d.Usage[1].Override {
+		t.Fatalf("charge after override = %+v, %v; want allowed by the override", d, err)
+	}
+	if err := m.ResetLimit(ctx, "t1", MetricAPICalls, PeriodMonthly); err != nil {
+		t.Fatalf("ResetLimit: %v", err)
+	}
+	if d, _ := m.Charge(ctx, "t2", "exports", 1); !d.Allowed || len(d.Usage) != 0 {
+		t.Fatalf("unlimited metric decision = %+v", d)
+	}
+}
+
+func TestManagerMiddlewareRejectsExhaustedTenants(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := testManager(t, NewMemoryStore(), Policy{Metric: MetricAPICalls, Period: PeriodDaily, Limit: 1})
+	var exceeded []Usage
+	m.cfg.OnExceeded = func(_ *gin.Context, u Usage) { exceeded = append(exceeded, u) }
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("tenant_id", c.GetHeader("X-Tenant"))
+		c.Next()
+	}, m.Middleware())
+	router.GET("/items", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	serve := func(tenantID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		req.Header.Set("X-Tenant", tenantID)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := serve("t1")
+	if first.Code != http.StatusNoContent || first.Header().Get("X-RateLimit-Remaining") != "0" || first.Header().Get("X-RateLimit-Reset") != "2026-10-17T00:00:00Z" {
+		t.Fatalf("first = %d %v", first.Code, first.Header())
+	}
+	second := serve("t1")
+	if second.Code != http.StatusTooManyRequests || second.Header().Get("Retry-After") != "43201" {
+		t.Fatalf("second = %d %v", second.Code, second.Header())
+	}
+	var body map[string]any
+	if err := json.Unmarshal(second.Body.Bytes(), &body); err != nil || body["error"] != ReasonQuotaExceeded || body["period"] != "daily" {
+		t.Fatalf("second body = %s", second.Body.String())
+	}
+	if len(exceeded) != 1 || exceeded[0].TenantID != "t1" {
+		t.Fatalf("OnExceeded = %+v", exceeded)
+	}
+	if rec := serve(""); rec.Code != http.StatusNoContent {
+		t.Fatalf("request without tenant = %d, want it uncharged", rec.Code)
+	}
+}
+
+func TestAdminRoutesAdjustTenantQuotas(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := testManager(t, NewMemoryStore(), Policy{Metric: MetricAPICalls, Period: PeriodDaily, Limit: 100})
+	router := gin.New()
+	RegisterAdminRoutes(router, m)
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder) []Usage {
+		t.Helper()
+		var out struct {
+			Data []Usage `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("decode %s: %v", rec.Body.String(), err)
+		}
+		return out.Data
+	}
+
+	if rec := call(http.MethodPut, "/tenants/t1/quotas/api_calls/daily", `{"limit": 500}`); rec.Code != http.StatusOK {
+		t.Fatalf("set limit = %d %s", rec.Code, rec.Body.String())
+	} else if usage := decode(rec); len(usage) != 1 || usage[0].Limit != 500 || !usage[0].Override {
+		t.Fatalf("usage after set limit = %+v", usage)
+	}
+	if rec := call(http.MethodPut, "/tenants/t1/quotas/api_calls/daily/usage", `{"used": 42}`); rec.Code != http.StatusOK {
+		t.Fatalf("set usage = %d %s", rec.Code, rec.Body.String())
+	} else if usage := decode(rec); usage[0].Used != 42 || usage[0].Remaining != 458 {
+		t.Fatalf("usage after set usage = %+v", usage)
+	}
+	if rec := call(http.MethodDelete, "/tenants/t1/quotas/api_calls/daily", ""); rec.Code != http.StatusOK {
+		t.Fatalf("reset limit = %d %s", rec.Code, rec.Body.String())
+	} else if usage := decode(rec); usage[0].Limit != 100 || usage[0].Override {
+		t.Fatalf("usage after reset = %+v", usage)
+	}
+	if rec := call(http.MethodPut, "/tenants/t1/quotas/api_calls/hourly", `{"limit": 5}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown period = %d, want 422", rec.Code)
+	}
+	if rec := call(http.MethodPut, "/tenants/t1/quotas/api_calls/daily", `{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("missing limit = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS platform_quota_overrides;
+DROP TABLE IF EXISTS platform_quota_counters;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS platform_quota_counters (
+    tenant_id  text NOT NULL,
+    metric     text NOT NULL,
+    period     text NOT NULL,
+    window_id  text NOT NULL,
+    used       bigint NOT NULL DEFAULT 0,
+    expires_at timestamptz NOT NULL,
+    PRIMARY KEY (tenant_id, metric, period, window_id)
+);
+
+-- PruneExpired deletes counters of past windows by expiry.
+CREATE INDEX IF NOT EXISTS platform_quota_counters_expires_idx
+    ON platform_quota_counters (expires_at);
+
+CREATE TABLE IF NOT EXISTS platform_quota_overrides (
+    tenant_id   text NOT NULL,
+    metric      text NOT NULL,
+    period      text NOT NULL,
+    limit_value bigint NOT NULL,
+    updated_at  timestamptz NOT NULL,
+    PRIMARY KEY (tenant_id, metric, period)
+);
@@ -0,0 +1,133 @@
+package quota
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/postgres"
+)
+
+const (
+	// PostgresCountersTable holds the counters of a PostgresStore.
+	PostgresCountersTable = "platform_quota_counters"
+	// PostgresOverridesTable holds the limit overrides of a PostgresStore.
+	PostgresOverridesTable = "platform_quota_overrides"
+	// PostgresMigrationsTable records the applied version of PostgresMigrations.
+	PostgresMigrationsTable = "platform_quota_schema_migrations"
+)
+
+//go:embed migrations/*.sql
+var postgresMigrationFiles embed.FS
+
+// PostgresMigrations returns the migrations creating the PostgresStore tables,
+// for postgres.DB.Migrate. Their version is tracked in PostgresMigrationsTable,
+// so they run alongside the application's own migrations.
+//
+//	err := db.Migrate(ctx, quota.PostgresMigrations(), postgres.MigrateUp())
+func PostgresMigrations() postgres.MigrationSource {
+	return postgres.MigrationsFS(postgresMigrationFiles, "migrations").WithMigrationsTable(PostgresMigrationsTable)
+}
+
+// PostgresStore keeps tenant quota counters and overrides in Postgres, so
+// usage survives restarts and can be reported on. Increments are a single
+// conditional upsert, so concurrent replicas never overshoot a limit.
+type PostgresStore struct {
+	db *postgres.DB
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+// NewPostgresStore returns a store on the primary of db. Apply
+// PostgresMigrations first.
+func NewPostgresStore(db *postgres.DB) (*PostgresStore, error) {
+	if db == nil || db.Client == nil {
+		return nil, errors.New("quota: postgres database is required")
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// Increment implements Store.
+func (s *PostgresStore) Increment(ctx context.Context, key CounterKey, delta, limit int64, expiresAt time.Time) (int64, bool, error) {
+	if limit >= 0 && delta > limit {
+		used, err := s.Counter(ctx, key)
+		return used, false, err
+	}
+	var used []int64
+	err := s.db.Primary(ctx).Raw(`INSERT INTO `+PostgresCountersTable+` AS c (tenant_id, metric, period, window_id, used, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (tenant_id, metric, period, window_id) DO UPDATE SET used = c.used + EXCLUDED.used, expires_at = EXCLUDED.expires_at
+		WHERE ? < 0 OR c.used + EXCLUDED.used <= ?
+		RETURNING used`,
+		key.TenantID, key.Metric, string(key.Period), key.Window, delta, expiresAt.UTC(), limit, limit).Scan(&used).Error
+	if err != nil {
+		return 0, false, err
+	}
+	if len(used) > 0 {
+		return used[0], true, nil
+	}
+	current, err := s.Counter(ctx, key)
+	return current, false, err
+}
+
+// Counter implements Store.
+func (s *PostgresStore) Counter(ctx context.Context, key CounterKey) (int64, error) {
+	var used []int64
+	err := s.db.Primary(ctx).Raw(`SELECT used FROM `+PostgresCountersTable+`
+		WHERE tenant_id = ? AND metric = ? AND period = ? AND window_id = ?`,
+		key.TenantID, key.Metric, string(key.Period), key.Window).Scan(&used).Error
+	if err != nil || len(used) == 0 {
+		return 0, err
+	}
+	return used[0], nil
+}
+
+// SetCounter implements Store.
+func (s *PostgresStore) SetCounter(ctx context.Context, key CounterKey, used int64, expiresAt time.Time) error {
+	return s.db.Primary(ctx).Exec(`INSERT INTO `+PostgresCountersTable+` (tenant_id, metric, period, window_id, used, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (tenant_id, metric, period, window_id) DO UPDATE SET used = EXCLUDED.used, expires_at = EXCLUDED.expires_at`,
+		key.TenantID, key.Metric, string(key.Period), key.Window, used, expiresAt.UTC()).Error
+}
+
+// Overrides implements Store.
+func (s *PostgresStore) Overrides(ctx context.Context, tenantID string) ([]Policy, error) {
+	var rows []struct {
+		Metric     string
+		Period     string
+		LimitValue int64
+	}
+	err := s.db.Primary(ctx).Raw(`SELECT metric, period, limit_value FROM `+PostgresOverridesTable+`
+		WHERE tenant_id = ?`, tenantID).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Policy, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, Policy{Metric: row.Metric, Period: Period(row.Period), Limit: row.LimitValue})
+	}
+	return out, nil
+}
+
+// SetOverride implements Store.
+func (s *PostgresStore) SetOverride(ctx context.Context, tenantID string, policy Policy) error {
+	return s.db.Primary(ctx).Exec(`INSERT INTO `+PostgresOverridesTable+` (tenant_id, metric, period, limit_value, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (tenant_id, metric, period) DO UPDATE SET limit_value = EXCLUDED.limit_value, updated_at = EXCLUDED.updated_at`,
+		tenantID, policy.Metric, string(policy.Period), policy.Limit, time.Now().UTC()).Error
+}
+
+// DeleteOverride implements Store.
+func (s *PostgresStore) DeleteOverride(ctx context.Context, tenantID, metric string, period Period) error {
+	return s.db.Primary(ctx).Exec(`DELETE FROM `+PostgresOverridesTable+`
+		WHERE tenant_id = ? AND metric = ? AND period = ?`, tenantID, metric, string(period)).Error
+}
+
+// PruneExpired deletes the counters of windows that ended before now and
+// returns how many were removed. Run it periodically, e.g. from a
+// scheduler job.
+func (s *PostgresStore) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := s.db.Primary(ctx).Exec(`DELETE FROM `+PostgresCountersTable+` WHERE expires_at <= ?`, now.UTC())
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,17 @@
+package quota
+
+import (
+	"testing"
+
+	"github.com/milan604/core-lab/pkg/postgres/migrations"
+)
+
+func TestPostgresMigrationsPassLint(t *testing.T) {
+	report, err := migrations.Lint(postgresMigrationFiles, migrations.WithDir("migrations"), migrations.WithWarningsAsErrors())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := report.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,121 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// incrementScript adds ARGV[1] to the counter unless it would pass the limit
+// in ARGV[2] (negative for none), and expires the counter at ARGV[3] (Unix
+// milliseconds). It returns the counter value and 1 when charged.
+var incrementScript = redis.NewScript(`
+local used = tonumber(redis.call('GET', KEYS[1]) or '0')
+local delta = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+if limit >= 0 and used + delta > limit then
+  return {used, 0}
+end
+used = redis.call('INCRBY', KEYS[1], delta)
+redis.call('PEXPIREAT', KEYS[1], ARGV[3])
+return {used, 1}
+`)
+
+// RedisStore shares tenant quota counters and overrides across replicas
+// through Redis. Counters expire with their window.
+type RedisStore struct {
+	client    redis.UniversalClient
+	namespace string
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore creates a Redis-backed store. namespace prefixes every key
+// and defaults to "corelab:quota".
+func NewRedisStore(client redis.UniversalClient, namespace string) (*RedisStore, error) {
+	if client == nil {
+		return nil, errors.New("quota: redis client is required")
+	}
+	namespace = strings.Trim(strings.TrimSpace(namespace), ":")
+	if namespace == "" {
+		namespace = "corelab:quota"
+	}
+	return &RedisStore{client: client, namespace: namespace}, nil
+}
+
+// Increment implements Store.
+func (s *RedisStore) Increment(ctx context.Context, key CounterKey, delta, limit int64, expiresAt time.Time) (int64, bool, error) {
+	res, err := incrementScript.Run(ctx, s.client, []string{s.counterKey(key)}, delta, limit, expiresAt.UnixMilli()).Int64Slice()
+	if err != nil {
+		return 0, false, err
+	}
+	if len(res) != 2 {
+		return 0, false, errors.New("quota: unexpected increment script result")
+	}
+	return res[0], res[1] == 1, nil
+}
+
+// Counter implements Store.
+func (s *RedisStore) Counter(ctx context.Context, key CounterKey) (int64, error) {
+	used, err := s.client.Get(ctx, s.counterKey(key)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return used, err
+}
+
+// SetCounter implements Store.
+func (s *RedisStore) SetCounter(ctx context.Context, key CounterKey, used int64, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return s.client.Del(ctx, s.counterKey(key)).Err()
+	}
+	return s.client.Set(ctx, s.counterKey(key), used, ttl).Err()
+}
+
+// Overrides implements Store.
+func (s *RedisStore) Overrides(ctx context.Context, tenantID string) ([]Policy, error) {
+	fields, err := s.client.HGetAll(ctx, s.overridesKey(tenantID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Policy, 0, len(fields))
+	for field, value := range fields {
+		metric, period, ok := strings.Cut(field, "|")
+		if !ok {
+			continue
+		}
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		out = append(out, Policy{Metric: metric, Period: Period(period), Limit: limit})
+	}
+	return out, nil
+}
+
+// SetOverride implements Store.
+func (s *RedisStore) SetOverride(ctx context.Context, tenantID string, policy Policy) error {
+	return s.client.HSet(ctx, s.overridesKey(tenantID), overrideField(policy.Metric, policy.Period), policy.Limit).Err()
+}
+
+// DeleteOverride implements Store.
+func (s *RedisStore) DeleteOverride(ctx context.Context, tenantID, metric string, period Period) error {
+	return s.client.HDel(ctx, s.overridesKey(tenantID), overrideField(metric, period)).Err()
+}
+
+func (s *RedisStore) counterKey(key CounterKey) string {
+	return s.namespace + ":counter:" + key.TenantID + ":" + key.Metric + ":" + string(key.Period) + ":" + key.Window
+}
+
+func (s *RedisStore) overridesKey(tenantID string) string {
+	return s.namespace + ":overrides:" + tenantID
+}
+
+func overrideField(metric string, period Period) string {
+	return metric + "|" + string(period)
+}
@@ -0,0 +1,51 @@
+package quota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisStoreEnforcesLimitsAtomically(t *testing.T) {
+	ctx := context.Background()
+	mini := miniredis.RunT(t)
+	store, err := NewRedisStore(redis.NewClient(&redis.Options{Addr: mini.Addr()}), "")
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	key := CounterKey{TenantID: "t1", Metric: MetricAPICalls, Period: PeriodDaily, Window: "2026-10-16"}
+	expiresAt := time.Now().Add(time.Hour)
+
+	if used, ok, err := store.Increment(ctx, key, 2, 3, expiresAt); err != nil || !ok || used != 2 {
+		t.Fatalf("first increment = %d %v %v", used, ok, err)
+	}
+	if used, ok, err := store.Increment(ctx, key, 2, 3, expiresAt); err != nil || ok || used != 2 {
+		t.Fatalf("over-limit increment = %d %v %v, want rejected at 2", used, ok, err)
+	}
+	if ttl := mini.TTL("corelab:quota:counter:t1:api_calls:daily:2026-10-16"); ttl <= 0 {
+		t.Fatalf("counter ttl = %v, want the window expiry", ttl)
+	}
+	if err := store.SetCounter(ctx, key, 0, expiresAt); err != nil {
+		t.Fatalf("SetCounter: %v", err)
+	}
+	if used, _ := store.Counter(ctx, key); used != 0 {
+		t.Fatalf("counter after reset = %d", used)
+	}
+
+	if err := store.SetOverride(ctx, "t1", Policy{Metric: MetricAPICalls, Period: PeriodMonthly, Limit: 9}); err != nil {
+		t.Fatalf("SetOverride: %v", err)
+	}
+	overrides, err := store.Overrides(ctx, "t1")
+	if err != nil || len(overrides) != 1 || overrides[0] != (Policy{Metric: MetricAPICalls, Period: PeriodMonthly, Limit: 9}) {
+		t.Fatalf("overrides = %+v, %v", overrides, err)
+	}
+	if err := store.DeleteOverride(ctx, "t1", MetricAPICalls, PeriodMonthly); err != nil {
+		t.Fatalf("DeleteOverride: %v", err)
+	}
+	if overrides, _ := store.Overrides(ctx, "t1"); len(overrides) != 0 {
+		t.Fatalf("overrides after delete = %+v", overrides)
+	}
+}
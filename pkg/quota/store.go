@@ -0,0 +1,195 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Period is the window a tenant quota counts over. Windows are calendar days
+// and months in UTC.
+type Period string
+
+const (
+	PeriodDaily   Period = "daily"
+	PeriodMonthly Period = "monthly"
+)
+
+// ParsePeriod parses "daily" or "monthly".
+func ParsePeriod(s string) (Period, error) {
+	switch p := Period(strings.ToLower(strings.TrimSpace(s))); p {
+	case PeriodDaily, PeriodMonthly:
+		return p, nil
+	default:
+		return "", fmt.Errorf("quota: unknown period %q", s)
+	}
+}
+
+// Window returns the identifier of the window containing now, e.g.
+// "2026-10-16" or "2026-10", and the time the next window starts.
+func (p Period) Window(now time.Time) (string, time.Time) {
+	now = now.UTC()
+	if p == PeriodMonthly {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start.Format("2006-01"), start.AddDate(0, 1, 0)
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return start.Format("2006-01-02"), start.AddDate(0, 0, 1)
+}
+
+// Policy limits a metric per period. A negative Limit means unlimited; zero
+// blocks the metric entirely.
+type Policy struct {
+	Metric string `json:"metric"`
+	Period Period `json:"period"`
+	Limit  int64  `json:"limit"`
+}
+
+// CounterKey identifies the counter of a tenant's metric in one window.
+type CounterKey struct {
+	TenantID string
+	Metric   string
+	Period   Period
+	Window   string
+}
+
+// Store persists tenant quota counters and per-tenant limit overrides, so
+// every replica of a service charges the same budget.
+type Store interface {
+	// Increment adds delta to the counter of key unless the result would pass
+	// limit (a negative limit never does). It returns the counter value after
+	// the call and whether delta was added. expiresAt is when the counter can
+	// be discarded.
+	Increment(ctx context.Context, key CounterKey, delta, limit int64, expiresAt time.Time) (used int64, ok bool, err error)
+	// Counter returns the value of the counter of key, zero when unset.
+	Counter(ctx context.Context, key CounterKey) (int64, error)
+	// SetCounter overwrites the counter of key.
+	SetCounter(ctx context.Context, key CounterKey, used int64, expiresAt time.Time) error
+	// Overrides returns the limit overrides of tenantID.
+	Overrides(ctx context.Context, tenantID string) ([]Policy, error)
+	// SetOverride sets the limit of policy.Metric and policy.Period for
+	// tenantID, replacing the default.
+	SetOverride(ctx context.Context, tenantID string, policy Policy) error
+	// DeleteOverride removes an override, restoring the default limit.
+	DeleteOverride(ctx context.Context, tenantID, metric string, period Period) error
+}
+
+// MemoryStore keeps counters and overrides in process memory. Counters are
+// per replica, so use it for development, tests and single-instance
+// services.
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[CounterKey]memoryCounter
+	overrides map[string]map[overrideKey]int64
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+type memoryCounter struct {
+	used      int64
+	expiresAt time.Time
+}
+
+type overrideKey struct {
+	metric string
+	period Period
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters:  map[CounterKey]memoryCounter{},
+		overrides: map[string]map[overrideKey]int64{},
+		now:       time.Now,
+	}
+}
+
+// Increment implements Store.
+func (s *MemoryStore) Increment(_ context.Context, key CounterKey, delta, limit int64, expiresAt time.Time) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.pruneLocked(now)
+
+	counter := s.counters[key]
+	if !now.Before(counter.expiresAt) {
+		counter = memoryCounter{}
+	}
+	if limit >= 0 && counter.used+delta > limit {
+		return counter.used, false, nil
+	}
+	counter.used += delta
+	counter.expiresAt = expiresAt
+	s.counters[key] = counter
+	return counter.used, true, nil
+}
+
+// Counter implements Store.
+func (s *MemoryStore) Counter(_ context.Context, key CounterKey) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counter, ok := s.counters[key]
+	if !ok || !s.now().Before(counter.expiresAt) {
+		return 0, nil
+	}
+	return counter.used, nil
+}
+
+// SetCounter implements Store.
+func (s *MemoryStore) SetCounter(_ context.Context, key CounterKey, used int64, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counters[key] = memoryCounter{used: used, expiresAt: expiresAt}
+	return nil
+}
+
+// Overrides implements Store.
+func (s *MemoryStore) Overrides(_ context.Context, tenantID string) ([]Policy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Policy, 0, len(s.overrides[tenantID]))
+	for k, limit := range s.overrides[tenantID] {
+		out = append(out, Policy{Metric: k.metric, Period: k.period, Limit: limit})
+	}
+	return out, nil
+}
+
+// SetOverride implements Store.
+func (s *MemoryStore) SetOverride(_ context.Context, tenantID string, policy Policy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.overrides[tenantID] == nil {
+		s.overrides[tenantID] = map[overrideKey]int64{}
+	}
+	s.overrides[tenantID][overrideKey{policy.Metric, policy.Period}] = policy.Limit
+	return nil
+}
+
+// DeleteOverride implements Store.
+func (s *MemoryStore) DeleteOverride(_ context.Context, tenantID, metric string, period Period) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.overrides[tenantID], overrideKey{metric, period})
+	if len(s.overrides[tenantID]) == 0 {
+		delete(s.overrides, tenantID)
+	}
+	return nil
+}
+
+// pruneLocked drops expired counters, at most once a minute. Callers hold
+// s.mu.
+func (s *MemoryStore) pruneLocked(now time.Time) {
+	if now.Sub(s.lastPrune) < time.Minute {
+		return
+	}
+	s.lastPrune = now
+	for k, counter := range s.counters {
+		if !now.Before(counter.expiresAt) {
+			delete(s.counters, k)
+		}
+	}
+}
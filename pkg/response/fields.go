@@ -0,0 +1,151 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/milan604/core-lab/pkg/apperr"
+)
+
+// FieldsQueryParam is the query parameter read by SparseFields.
+const FieldsQueryParam = "fields"
+
+const fieldsContextKey = "corelab_response_fields"
+
+// SparseFields returns a per-route middleware that lets clients trim the
+// success payload with ?fields=, a comma-separated list of JSON field paths
+// ("id,name,address.city"). Only paths in allowed, or nested under one of
+// them, may be requested; anything else is rejected with 400. JSONSuccess
+// then keeps just the selected fields of data, or of each element when data
+// is a list. Without ?fields= the full payload is returned.
+//
+//	router.GET("/orders/:id", response.SparseFields("id", "status", "total", "customer"), h.GetOrder)
+func SparseFields(allowed ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		raw, ok := ctx.GetQuery(FieldsQueryParam)
+		if !ok || strings.TrimSpace(raw) == "" {
+			ctx.Next()
+			return
+		}
+
+		var fields []string
+		for _, field := range strings.Split(raw, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			if !fieldAllowed(field, allowed) {
+				JSONError(ctx, apperr.New(apperr.ErrorCodeInvalidRequest).
+					WithMessage("unknown field in fields parameter").
+					AddSuggestion(FieldsQueryParam, fmt.Sprintf("%q is not selectable; allowed fields: %s", field, strings.Join(allowed, ", "))))
+				ctx.Abort()
+				return
+			}
+			fields = append(fields, field)
+		}
+		ctx.Set(fieldsContextKey, fields)
+		ctx.Next()
+	}
+}
+
+// SelectedFields returns the field paths requested for the current request,
+// or nil when the full payload is returned.
+func SelectedFields(ctx *gin.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	val, ok := ctx.Get(fieldsContextKey)
+	if !ok {
+		return nil
+	}
+	fields, _ := val.([]string)
+	return fields
+}
+
+func fieldAllowed(field string, allowed []string) bool {
+	for _, a := range allowed {
+		if field == a || strings.HasPrefix(field, a+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldTree is a projection: a nil subtree keeps the whole value.
+type fieldTree map[string]fieldTree
+
+func newFieldTree(fields []string) fieldTree {
+	tree := fieldTree{}
+	for _, field := range fields {
+		node := tree
+		parts := strings.Split(field, ".")
+		for i, part := range parts {
+			child, exists := node[part]
+			if exists && child == nil {
+				// An ancestor is already selected in full.
+				break
+			}
+			if i == len(parts)-1 {
+				node[part] = nil
+				break
+			}
+			if !exists {
+				child = fieldTree{}
+				node[part] = child
+			}
+			node = child
+		}
+	}
+	return tree
+}
+
+// withSelectedFields projects data onto the request's selected fields. Data
+// is round-tripped through JSON, so the projection follows its JSON field
+// names; numbers stay json.Number, so int64 IDs above 2^53 keep their
+// precision. Data that does not encode is returned unchanged and fails later
+// in the regular encoder.
+func withSelectedFields(ctx *gin.Context, data interface{}) interface{} {
+	fields := SelectedFields(ctx)
+	if len(fields) == 0 || data == nil {
+		return data
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return data
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return data
+	}
+	return newFieldTree(fields).apply(decoded)
+}
+
+func (t fieldTree) apply(value interface{}) interface{} {
+	if t == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		projected := make(map[string]interface{}, len(t))
+		for key, subtree := range t {
+			if child, ok := v[key]; ok {
+				projected[key] = subtree.apply(child)
+			}
+		}
+		return projected
+	case []interface{}:
+		projected := make([]interface{}, len(v))
+		for i, item := range v {
+			projected[i] = t.apply(item)
+		}
+		return projected
+	default:
+		return value
+	}
+}
@@ -0,0 +1,85 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fieldsTestAddress struct {
+	City string `json:"city"`
+	Zip  string `json:"zip"`
+}
+
+type fieldsTestOrder struct {
+	ID      int64             `json:"id"`
+	Status  string            `json:"status"`
+	Total   float64           `json:"total"`
+	Address fieldsTestAddress `json:"address"`
+	Secret  string            `json:"secret"`
+}
+
+func TestSparseFieldsProjectsData(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	order := fieldsTestOrder{
+		ID:      1<<53 + 1,
+		Status:  "paid",
+		Total:   12.5,
+		Address: fieldsTestAddress{City: "Kathmandu", Zip: "44600"},
+		Secret:  "s3cret",
+	}
+	tests := []struct {
+		name  string
+		data  interface{}
+		query string
+		want  string
+	}{
+		{"no selection", order, "", `{"id":9007199254740993,"status":"paid","total":12.5,"address":{"city":"Kathmandu","zip":"44600"},"secret":"s3cret"}`},
+		{"large int64 id", order, "?fields=id", `{"id":9007199254740993}`},
+		{"nested path", order, "?fields=total,address.city", `{"address":{"city":"Kathmandu"},"total":12.5}`},
+		{"parent wins over child", order, "?fields=address.city,address", `{"address":{"city":"Kathmandu","zip":"44600"}}`},
+		{"list elements", []fieldsTestOrder{order, {ID: -1 << 63, Status: "open"}}, "?fields=id,status", `[{"id":9007199254740993,"status":"paid"},{"id":-9223372036854775808,"status":"open"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/orders", SparseFields("id", "status", "total", "address"), func(c *gin.Context) {
+				Success(c, tt.data)
+			})
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/orders"+tt.query, nil))
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", recorder.Code, recorder.Body)
+			}
+			var envelope struct {
+				Data json.RawMessage `json:"data"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+				t.Fatal(err)
+			}
+			if got := string(envelope.Data); got != tt.want {
+				t.Fatalf("data = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSparseFieldsRejectsUnknownFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/orders", SparseFields("id", "status", "total", "address"), func(c *gin.Context) {
+		Success(c, fieldsTestOrder{})
+	})
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/orders?fields=id,secret", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", recorder.Code)
+	}
+	if !strings.Contains(recorder.Body.String(), "secret") {
+		t.Fatalf("body = %s, want the rejected field named", recorder.Body)
+	}
+}
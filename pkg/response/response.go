@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/milan604/core-lab/pkg/apperr"
+	"github.com/milan604/core-lab/pkg/i18n"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,7 +19,8 @@ type APIResponse struct {
 	Meta    map[string]interface{} `json:"meta,omitempty"`
 }
 
-// JSONSuccess writes a success envelope
+// JSONSuccess writes a success envelope. On routes using SparseFields, data
+// is trimmed to the fields requested with ?fields=.
 func JSONSuccess(ctx *gin.Context, status int, data interface{}, meta map[string]interface{}) {
 	if status == 0 {
 		status = http.StatusOK
@@ -27,17 +29,25 @@ func JSONSuccess(ctx *gin.Context, status int, data interface{}, meta map[string
 		Success: true,
 		Code:    apperr.ErrorCodeSuccess.Code(),
 		Message: apperr.ErrorCodeSuccess.Message(),
-		Data:    data,
-		Meta:    meta,
+		Data:    withSelectedFields(ctx, data),
+		Meta:    withWarnings(ctx, meta),
 	}
 	ctx.JSON(status, resp)
 }
 
 // JSONError writes an error envelope using *apperr.AppError
 func JSONError(ctx *gin.Context, appErr *apperr.AppError) {
+	JSONErrorWithMeta(ctx, appErr, nil)
+}
+
+// JSONErrorWithMeta writes an error envelope carrying meta, e.g. the allowed
+// methods of a 405. Behind the i18n middleware, the message and suggestions
+// are localized into the request's locale (see apperr.AppError.Localize).
+func JSONErrorWithMeta(ctx *gin.Context, appErr *apperr.AppError, meta map[string]interface{}) {
 	if appErr == nil {
 		appErr = apperr.New(apperr.ErrorCodeInternal)
 	}
+	appErr = appErr.Localize(i18n.FromContext(ctx))
 	status := appErr.HTTPStatus
 	if status == 0 {
 		status = http.StatusInternalServerError
@@ -47,6 +57,7 @@ func JSONError(ctx *gin.Context, appErr *apperr.AppError) {
 		Code:    appErr.Code,
 		Message: appErr.Message,
 		Errors:  appErr.Suggestions,
+		Meta:    withWarnings(ctx, meta),
 	}
 	ctx.JSON(status, resp)
 }
@@ -0,0 +1,64 @@
+package response
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Standard warning codes. Services may define their own, but should prefer
+// these for the common cases so clients can handle them uniformly.
+const (
+	// WarningDeprecated flags use of a deprecated parameter, field, or route.
+	WarningDeprecated = "deprecated"
+	// WarningPartialResult means some of the requested data could not be returned.
+	WarningPartialResult = "partial_result"
+	// WarningFallbackData means cached, default, or degraded data was served.
+	WarningFallbackData = "fallback_data"
+)
+
+const warningsContextKey = "corelab_response_warnings"
+
+// Warning is a non-fatal, coded message returned in meta.warnings.
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Field names the parameter or field the warning is about, if any.
+	Field string `json:"field,omitempty"`
+}
+
+// AddWarning records a warning for the current request. Handlers and
+// middleware may call it any number of times before the response is written;
+// JSONSuccess and JSONError emit the collected warnings as meta.warnings.
+func AddWarning(ctx *gin.Context, warning Warning) {
+	if ctx == nil || warning.Code == "" {
+		return
+	}
+	ctx.Set(warningsContextKey, append(Warnings(ctx), warning))
+}
+
+// Warnings returns the warnings recorded for the current request.
+func Warnings(ctx *gin.Context) []Warning {
+	if ctx == nil {
+		return nil
+	}
+	val, ok := ctx.Get(warningsContextKey)
+	if !ok {
+		return nil
+	}
+	warnings, _ := val.([]Warning)
+	return warnings
+}
+
+// withWarnings adds the request's warnings to meta without mutating the
+// caller's map.
+func withWarnings(ctx *gin.Context, meta map[string]interface{}) map[string]interface{} {
+	warnings := Warnings(ctx)
+	if len(warnings) == 0 {
+		return meta
+	}
+	merged := make(map[string]interface{}, len(meta)+1)
+	for k, v := range meta {
+		merged[k] = v
+	}
+	merged["warnings"] = warnings
+	return merged
+}
@@ -0,0 +1,197 @@
+package roles
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/milan604/core-lab/pkg/config"
+	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/permissions"
+	"github.com/milan604/core-lab/pkg/sentinel"
+)
+
+// SyncPlan lists the changes Sync would make in Sentinel, one entry per role
+// definition.
+type SyncPlan struct {
+	Roles []RolePlan `json:"roles"`
+}
+
+// RolePlan is the planned change for one role. Only the service slices in
+// Services are written; a role whose grants already match is left untouched.
+type RolePlan struct {
+	RoleID string `json:"role_id"`
+	Name   string `json:"name,omitempty"`
+	// Services are the managed services whose grants will be replaced.
+	Services []string `json:"services,omitempty"`
+	// Assign are permission codes the role is missing.
+	Assign []string `json:"assign,omitempty"`
+	// Revoke are stale permission codes granted in a managed service.
+	Revoke []string `json:"revoke,omitempty"`
+	// Unresolved are declared codes Sentinel does not know; they are skipped.
+	Unresolved []string `json:"unresolved,omitempty"`
+	// Unverified is set when the current grants could not be fetched, in which
+	// case every managed service is rewritten.
+	Unverified bool `json:"unverified,omitempty"`
+
+	permissionIDs []string
+}
+
+// Empty reports whether the role needs no changes.
+func (p RolePlan) Empty() bool {
+	return len(p.Services) == 0
+}
+
+// Empty reports whether every role is already in sync.
+func (p SyncPlan) Empty() bool {
+	for _, role := range p.Roles {
+		if !role.Empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// String summarizes the plan on one line.
+func (p SyncPlan) String() string {
+	var parts []string
+	for _, role := range p.Roles {
+		if role.Empty() {
+			continue
+		}
+		part := fmt.Sprintf("role %s: assign %d, revoke %d in %s", role.RoleID, len(role.Assign), len(role.Revoke), strings.Join(role.Services, ","))
+		if role.Unverified {
+			part += " (unverified)"
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "roles in sync"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Plan compares the role definitions with the permissions currently granted in
+// Sentinel and returns the changes Sync would make, without writing anything.
+func Plan(ctx context.Context, definitions []Definition, cfg *config.Config, log logger.LogManager) (SyncPlan, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if cfg == nil {
+		return SyncPlan{}, fmt.Errorf("config not configured")
+	}
+	if log == nil {
+		return SyncPlan{}, fmt.Errorf("logger not configured")
+	}
+
+	client, err := sentinel.NewFromConfig(cfg, log)
+	if err != nil {
+		return SyncPlan{}, fmt.Errorf("failed to create Sentinel client: %w", err)
+	}
+
+	validatedRoles := make([]*Definition, 0, len(definitions))
+	for i := range definitions {
+		if definitions[i].IsValid() {
+			validatedRoles = append(validatedRoles, &definitions[i])
+		}
+	}
+	return planSync(ctx, validatedRoles, client, log)
+}
+
+// planSync resolves every declared permission code in one call, then diffs
+// each role's current grants against its definition.
+func planSync(ctx context.Context, roleDefs []*Definition, client *sentinel.Client, log logger.LogManager) (SyncPlan, error) {
+	var codes []string
+	seen := map[string]bool{}
+	for _, roleDef := range roleDefs {
+		for _, ref := range roleDef.Permissions {
+			code := permissions.GenerateCode(ref.Service, ref.Category, ref.Action)
+			if !seen[code] {
+				seen[code] = true
+				codes = append(codes, code)
+			}
+		}
+	}
+
+	permissionIDs, err := getPermissionsByCode(ctx, codes, client, log)
+	if err != nil {
+		return SyncPlan{}, fmt.Errorf("failed to get permissions by code: %w", err)
+	}
+
+	plan := SyncPlan{Roles: make([]RolePlan, 0, len(roleDefs))}
+	for _, roleDef := range roleDefs {
+		current, err := client.GetRolePermissions(ctx, roleDef.RoleID)
+		if err != nil {
+			log.WarnFCtx(ctx, "Failed to fetch current permissions of role %s, rewriting all managed services: %v", roleDef.RoleID, err)
+		}
+		plan.Roles = append(plan.Roles, planRole(roleDef, permissionIDs, current, err == nil))
+	}
+	return plan, nil
+}
+
+// planRole diffs one role. permissionIDs maps lower-cased codes to Sentinel
+// IDs; verified is false when current could not be fetched.
+func planRole(roleDef *Definition, permissionIDs map[string]string, current []sentinel.RolePermission, verified bool) RolePlan {
+	plan := RolePlan{RoleID: roleDef.RoleID, Name: roleDef.Name, Unverified: !verified}
+
+	managed := uniqueManagedServices(roleDef)
+	desired := map[string]string{} // code -> service
+	for _, ref := range roleDef.Permissions {
+		code := permissions.GenerateCode(ref.Service, ref.Category, ref.Action)
+		if _, ok := permissionIDs[strings.ToLower(code)]; !ok {
+			plan.Unresolved = append(plan.Unresolved, code)
+			continue
+		}
+		desired[strings.ToLower(code)] = normalizeManagedService(ref.Service)
+	}
+
+	changed := map[string]bool{}
+	if !verified {
+		for _, service := range managed {
+			changed[service] = true
+		}
+		for code := range desired {
+			plan.Assign = append(plan.Assign, code)
+		}
+	} else {
+		isManaged := make(map[string]bool, len(managed))
+		for _, service := range managed {
+			isManaged[service] = true
+		}
+		granted := map[string]bool{}
+		for _, perm := range current {
+			code := strings.ToLower(strings.TrimSpace(perm.Code))
+			granted[code] = true
+			service := normalizeManagedService(perm.Service)
+			if service == "" {
+				service, _, _ = strings.Cut(code, "-")
+			}
+			if _, ok := desired[code]; !ok && isManaged[service] {
+				plan.Revoke = append(plan.Revoke, code)
+				changed[service] = true
+			}
+		}
+		for code, service := range desired {
+			if !granted[code] {
+				plan.Assign = append(plan.Assign, code)
+				changed[service] = true
+			}
+		}
+	}
+
+	for _, service := range managed {
+		if changed[service] {
+			plan.Services = append(plan.Services, service)
+		}
+	}
+	for code, service := range desired {
+		if changed[service] {
+			plan.permissionIDs = append(plan.permissionIDs, permissionIDs[code])
+		}
+	}
+	sort.Strings(plan.Assign)
+	sort.Strings(plan.Revoke)
+	sort.Strings(plan.permissionIDs)
+	return plan
+}
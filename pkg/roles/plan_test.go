@@ -0,0 +1,150 @@
+package roles
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/milan604/core-lab/pkg/permissions"
+	"github.com/milan604/core-lab/pkg/sentinel"
+)
+
+func TestPlanRole(t *testing.T) {
+	readInvoices := permissions.Reference{Service: "billing", Category: "invoice", Action: "read"}
+	payInvoices := permissions.Reference{Service: "billing", Category: "invoice", Action: "pay"}
+	permissionIDs := map[string]string{
+		"billing-invoice-read": "perm-read",
+		"billing-invoice-pay":  "perm-pay",
+	}
+	granted := func(code, service string) sentinel.RolePermission {
+		return sentinel.RolePermission{ID: "id-" + code, Code: code, Service: service}
+	}
+
+	tests := []struct {
+		name     string
+		def      Definition
+		current  []sentinel.RolePermission
+		verified bool
+		want     RolePlan
+	}{
+		{
+			name:     "already in sync",
+			def:      Definition{RoleID: "r1", Permissions: []permissions.Reference{readInvoices}},
+			current:  []sentinel.RolePermission{granted("billing-invoice-read", "billing")},
+			verified: true,
+			want:     RolePlan{RoleID: "r1"},
+		},
+		{
+			name:     "missing grant",
+			def:      Definition{RoleID: "r1", Permissions: []permissions.Reference{readInvoices, payInvoices}},
+			current:  []sentinel.RolePermission{granted("billing-invoice-read", "billing")},
+			verified: true,
+			want: RolePlan{
+				RoleID:        "r1",
+				Services:      []string{"billing"},
+				Assign:        []string{"billing-invoice-pay"},
+				permissionIDs: []string{"perm-pay", "perm-read"},
+			},
+		},
+		{
+			name: "stale grant in a managed service",
+			def:  Definition{RoleID: "r1", Permissions: []permissions.Reference{readInvoices}},
+			current: []sentinel.RolePermission{
+				granted("billing-invoice-read", "billing"),
+				granted("BILLING-invoice-delete", ""),
+			},
+			verified: true,
+			want: RolePlan{
+				RoleID:        "r1",
+				Services:      []string{"billing"},
+				Revoke:        []string{"billing-invoice-delete"},
+				permissionIDs: []string{"perm-read"},
+			},
+		},
+		{
+			name:     "managed service with no declared permissions",
+			def:      Definition{RoleID: "r1", ManagedServices: []string{" Reports "}},
+			current:  []sentinel.RolePermission{granted("reports-report-export", "reports")},
+			verified: true,
+			want: RolePlan{
+				RoleID:   "r1",
+				Services: []string{"reports"},
+				Revoke:   []string{"reports-report-export"},
+			},
+		},
+		{
+			name: "grant in an unmanaged service is kept",
+			def:  Definition{RoleID: "r1", Permissions: []permissions.Reference{readInvoices}},
+			current: []sentinel.RolePermission{
+				granted("billing-invoice-read", "billing"),
+				granted("orders-order-read", "orders"),
+			},
+			verified: true,
+			want:     RolePlan{RoleID: "r1"},
+		},
+		{
+			name:     "current grants could not be fetched",
+			def:      Definition{RoleID: "r1", Name: "Accountant", Permissions: []permissions.Reference{readInvoices, payInvoices}},
+			verified: false,
+			want: RolePlan{
+				RoleID:        "r1",
+				Name:          "Accountant",
+				Services:      []string{"billing"},
+				Assign:        []string{"billing-invoice-pay", "billing-invoice-read"},
+				Unverified:    true,
+				permissionIDs: []string{"perm-pay", "perm-read"},
+			},
+		},
+		{
+			name: "unresolved codes are skipped",
+			def: Definition{RoleID: "r1", Permissions: []permissions.Reference{
+				readInvoices,
+				{Service: "billing", Category: "invoice", Action: "void"},
+			}},
+			current:  []sentinel.RolePermission{granted("billing-invoice-read", "billing")},
+			verified: true,
+			want:     RolePlan{RoleID: "r1", Unresolved: []string{"billing-invoice-void"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := planRole(&tt.def, permissionIDs, tt.current, tt.verified)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("planRole() = %+v, want %+v", got, tt.want)
+			}
+			if got.Empty() != (len(tt.want.Services) == 0) {
+				t.Fatalf("Empty() = %t with services %v", got.Empty(), got.Services)
+			}
+		})
+	}
+}
+
+func TestSyncPlanSummary(t *testing.T) {
+	inSync := RolePlan{RoleID: "viewer"}
+	assign := RolePlan{RoleID: "admin", Services: []string{"billing", "orders"}, Assign: []string{"a", "b"}, Revoke: []string{"c"}}
+	unverified := RolePlan{RoleID: "auditor", Services: []string{"reports"}, Assign: []string{"d"}, Unverified: true}
+
+	tests := []struct {
+		name      string
+		plan      SyncPlan
+		wantEmpty bool
+		wantText  string
+	}{
+		{name: "no roles", plan: SyncPlan{}, wantEmpty: true, wantText: "roles in sync"},
+		{name: "all in sync", plan: SyncPlan{Roles: []RolePlan{inSync}}, wantEmpty: true, wantText: "roles in sync"},
+		{
+			name:     "changes skip roles in sync",
+			plan:     SyncPlan{Roles: []RolePlan{inSync, assign, unverified}},
+			wantText: "role admin: assign 2, revoke 1 in billing,orders; role auditor: assign 1, revoke 0 in reports (unverified)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.plan.Empty(); got != tt.wantEmpty {
+				t.Fatalf("Empty() = %t, want %t", got, tt.wantEmpty)
+			}
+			if got := tt.plan.String(); got != tt.wantText {
+				t.Fatalf("String() = %q, want %q", got, tt.wantText)
+			}
+		})
+	}
+}
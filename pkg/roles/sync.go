@@ -6,16 +6,44 @@ import (
 	"strings"
 
 	"github.com/milan604/core-lab/pkg/config"
-	"github.com/milan604/core-lab/pkg/controlplane"
-	httplib "github.com/milan604/core-lab/pkg/http"
+	"github.com/milan604/core-lab/pkg/lock"
 	"github.com/milan604/core-lab/pkg/logger"
-	"github.com/milan604/core-lab/pkg/permissions"
+	"github.com/milan604/core-lab/pkg/sentinel"
 )
 
+// SyncLockKey is the lock WithLocker holds while syncing.
+const SyncLockKey = "roles:sync"
+
+// SyncOption customizes Sync and Bootstrap.
+type SyncOption func(*syncOptions)
+
+type syncOptions struct {
+	dryRun bool
+	locker lock.Locker
+}
+
+// WithDryRun makes Sync log the planned changes instead of writing them. Use
+// Plan to get the structured report.
+func WithDryRun() SyncOption {
+	return func(o *syncOptions) {
+		o.dryRun = true
+	}
+}
+
+// WithLocker makes Sync plan and write under SyncLockKey, so replicas starting
+// together sync one at a time and the later ones find nothing left to change.
+func WithLocker(locker lock.Locker) SyncOption {
+	return func(o *syncOptions) {
+		o.locker = locker
+	}
+}
+
 // Sync validates role definitions by checking if role IDs exist in Sentinel
 // This is the main function that validates role IDs
 // Similar to permissions.Bootstrap, it creates HTTP client internally and calls Sentinel APIs
-func Sync(ctx context.Context, definitions []Definition, cfg *config.Config, log logger.LogManager) error {
+// Roles whose current grants already match their definition are not written;
+// see Plan.
+func Sync(ctx context.Context, definitions []Definition, cfg *config.Config, log logger.LogManager, opts ...SyncOption) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -48,18 +76,11 @@ func Sync(ctx context.Context, definitions []Definition, cfg *config.Config, log
 
 	log.InfoFCtx(ctx, "Verified %d role definitions", len(validatedRoles))
 
-	// Step 2: Create HTTP client for Sentinel service (similar to permissions package)
-	api := controlplane.APIFromConfig(cfg)
-	if !api.Valid() {
-		return fmt.Errorf("%s or %s not configured", controlplane.KeyBaseURL, controlplane.LegacyKeyBaseURL)
-	}
-
-	// Create HTTP client with service token authentication (similar to permissions package)
-	// This uses http.NewClientWithServiceToken internally, which handles service token authentication
-	httpClient, err := httplib.NewClientWithServiceToken(log, cfg)
+	// Step 2: Create the Sentinel client with service token authentication (similar to permissions package)
+	client, err := sentinel.NewFromConfig(cfg, log)
 	if err != nil {
-		log.ErrorFCtx(ctx, "Failed to create HTTP client with service token: %v", err)
-		return fmt.Errorf("failed to create HTTP client: %w", err)
+		log.ErrorFCtx(ctx, "Failed to create Sentinel client: %v", err)
+		return fmt.Errorf("failed to create Sentinel client: %w", err)
 	}
 
 	// Step 3: Validate role IDs in Sentinel (bulk validation)
@@ -68,54 +89,71 @@ func Sync(ctx context.Context, definitions []Definition, cfg *config.Config, log
 		roleIDs = append(roleIDs, roleDef.RoleID)
 	}
 
-	if err := validateRoleIDs(ctx, roleIDs, api, httpClient, log); err != nil {
+	if err := validateRoleIDs(ctx, roleIDs, client, log); err != nil {
 		log.ErrorFCtx(ctx, "Failed to validate roles in Sentinel: %v", err)
 		return fmt.Errorf("failed to validate roles: %w", err)
 	}
 
 	log.InfoFCtx(ctx, "Roles validation completed successfully. Validated %d roles", len(validatedRoles))
 
-	// Reconcile each service slice of the role to match the desired definition.
-	for _, roleDef := range validatedRoles {
-		if err := syncPermissionsToRole(ctx, roleDef, api, httpClient, log); err != nil {
-			log.ErrorFCtx(ctx, "Failed to sync permissions to role %s in Sentinel: %v", roleDef.RoleID, err)
-			return fmt.Errorf("failed to sync permissions to role %s: %w", roleDef.RoleID, err)
-		}
+	var options syncOptions
+	for _, opt := range opts {
+		opt(&options)
 	}
 
-	log.InfoFCtx(ctx, "Default permissions synchronized to native roles successfully")
-
-	return nil
+	if options.locker != nil && !options.dryRun {
+		return lock.WithLock(ctx, options.locker, SyncLockKey, func(ctx context.Context) error {
+			return applySync(ctx, validatedRoles, client, log, options)
+		})
+	}
+	return applySync(ctx, validatedRoles, client, log, options)
 }
 
-// validateRoleIDs validates that role IDs exist in Sentinel using bulk API
-func validateRoleIDs(ctx context.Context, roleIDs []string, api controlplane.API, httpClient *httplib.Client, log logger.LogManager) error {
-	if len(roleIDs) == 0 {
-		return nil
+// applySync plans the role changes and writes them unless this is a dry run.
+func applySync(ctx context.Context, validatedRoles []*Definition, client *sentinel.Client, log logger.LogManager, options syncOptions) error {
+	// Step 4: Diff the current grants against the definitions
+	plan, err := planSync(ctx, validatedRoles, client, log)
+	if err != nil {
+		log.ErrorFCtx(ctx, "Failed to plan role permission sync: %v", err)
+		return fmt.Errorf("failed to plan role sync: %w", err)
+	}
+	for _, rolePlan := range plan.Roles {
+		if len(rolePlan.Unresolved) > 0 {
+			log.WarnFCtx(ctx, "Role %s references permissions unknown to Sentinel: %v", rolePlan.RoleID, rolePlan.Unresolved)
+		}
 	}
 
-	// Request structure for bulk role lookup
-	type GetRolesByIDsRequest struct {
-		RoleIDs []string `json:"role_ids" binding:"required,min=1,dive,uuid"`
+	if options.dryRun {
+		log.InfoFCtx(ctx, "Dry run, planned role changes: %s", plan)
+		return nil
 	}
 
-	// Response structure
-	type RoleResponse struct {
-		ID          string `json:"id"`
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		Native      bool   `json:"native"`
-		Status      string `json:"status"`
+	// Reconcile the changed service slices of each role to match the desired definition.
+	written := 0
+	for _, rolePlan := range plan.Roles {
+		if rolePlan.Empty() {
+			continue
+		}
+		if err := syncPermissionsToRole(ctx, rolePlan, client, log); err != nil {
+			log.ErrorFCtx(ctx, "Failed to sync permissions to role %s in Sentinel: %v", rolePlan.RoleID, err)
+			return fmt.Errorf("failed to sync permissions to role %s: %w", rolePlan.RoleID, err)
+		}
+		written++
 	}
 
-	type GetRolesByIDsResponse []RoleResponse
+	log.InfoFCtx(ctx, "Default permissions synchronized to native roles successfully (%d updated, %d already in sync)", written, len(plan.Roles)-written)
+
+	return nil
+}
 
-	request := GetRolesByIDsRequest{
-		RoleIDs: roleIDs,
+// validateRoleIDs validates that role IDs exist in Sentinel using bulk API
+func validateRoleIDs(ctx context.Context, roleIDs []string, client *sentinel.Client, log logger.LogManager) error {
+	if len(roleIDs) == 0 {
+		return nil
 	}
 
-	var response GetRolesByIDsResponse
-	if err := httpClient.PostJSON(ctx, api.RolesBulkURL(), request, &response); err != nil {
+	response, err := client.GetRolesByIDs(ctx, roleIDs)
+	if err != nil {
 		log.ErrorFCtx(ctx, "Failed to get roles from Sentinel: %v", err)
 		return fmt.Errorf("sentinel service get roles: %w", err)
 	}
@@ -141,96 +179,41 @@ func validateRoleIDs(ctx context.Context, roleIDs []string, api controlplane.API
 	return nil
 }
 
-// getPermissionsByCode gets permission IDs from Sentinel using permission codes
-func getPermissionsByCode(ctx context.Context, codes []string, api controlplane.API, httpClient *httplib.Client, log logger.LogManager) ([]string, error) {
+// getPermissionsByCode gets permission IDs from Sentinel using permission codes,
+// keyed by lower-cased code
+func getPermissionsByCode(ctx context.Context, codes []string, client *sentinel.Client, log logger.LogManager) (map[string]string, error) {
 	if len(codes) == 0 {
-		return []string{}, nil
-	}
-
-	// Request structure
-	type GetPermissionsByCodesRequest struct {
-		Codes []string `json:"codes" binding:"required,min=1"`
-	}
-
-	// Response structure
-	type PermissionResponse struct {
-		ID   string `json:"id"`
-		Code string `json:"code"`
+		return map[string]string{}, nil
 	}
 
-	type GetPermissionsByCodesResponse []PermissionResponse
-
-	request := GetPermissionsByCodesRequest{
-		Codes: codes,
-	}
-
-	var response GetPermissionsByCodesResponse
-	if err := httpClient.PostJSON(ctx, api.PermissionByCodesURL(), request, &response); err != nil {
+	response, err := client.GetPermissionsByCodes(ctx, codes)
+	if err != nil {
 		log.ErrorFCtx(ctx, "Failed to get permissions from Sentinel: %v", err)
 		return nil, fmt.Errorf("sentinel service get permissions: %w", err)
 	}
 
 	// Extract permission IDs
-	permissionIDs := make([]string, 0, len(response))
+	permissionIDs := make(map[string]string, len(response))
 	for _, perm := range response {
-		permissionIDs = append(permissionIDs, perm.ID)
+		permissionIDs[strings.ToLower(perm.Code)] = perm.ID
 	}
 
 	log.InfoFCtx(ctx, "Retrieved %d permission IDs from Sentinel", len(permissionIDs))
 	return permissionIDs, nil
 }
 
-// assignPermissionsToRole assigns permissions to a role in Sentinel
-func syncPermissionsToRole(ctx context.Context, roleDef *Definition, api controlplane.API, httpClient *httplib.Client, log logger.LogManager) error {
-	if roleDef == nil {
-		return nil
-	}
-
-	// Convert permission references to codes
-	codes := make([]string, 0, len(roleDef.Permissions))
-	for _, ref := range roleDef.Permissions {
-		code := permissions.GenerateCode(ref.Service, ref.Category, ref.Action)
-		codes = append(codes, code)
-	}
-
-	managedServices := uniqueManagedServices(roleDef)
-	if len(codes) == 0 && len(managedServices) == 0 {
-		log.InfoFCtx(ctx, "No managed permissions or services configured for role %s", roleDef.RoleID)
-		return nil
-	}
-
-	permissionIDs := []string{}
-	if len(codes) > 0 {
-		var err error
-		permissionIDs, err = getPermissionsByCode(ctx, codes, api, httpClient, log)
-		if err != nil {
-			return fmt.Errorf("failed to get permissions by code: %w", err)
-		}
+// syncPermissionsToRole replaces the planned service slices of a role in Sentinel
+func syncPermissionsToRole(ctx context.Context, plan RolePlan, client *sentinel.Client, log logger.LogManager) error {
+	request := sentinel.AssignPermissionsRequest{
+		PermissionIDs: plan.permissionIDs,
+		Services:      plan.Services,
 	}
-
-	// Request structure
-	type SyncPermissionsToRoleRequestBody struct {
-		PermissionIDs []string `json:"permissions"`
-		Services      []string `json:"services,omitempty"`
-	}
-
-	// Response structure
-	type SyncPermissionsToRoleResponse struct {
-		Message string `json:"message"`
-	}
-
-	request := SyncPermissionsToRoleRequestBody{
-		PermissionIDs: permissionIDs,
-		Services:      managedServices,
-	}
-
-	var response []SyncPermissionsToRoleResponse
-	if err := httpClient.PutJSON(ctx, api.RolePermissionsURL(roleDef.RoleID), request, &response); err != nil {
-		log.ErrorFCtx(ctx, "Failed to sync permissions to role %s: %v", roleDef.RoleID, err)
+	if err := client.AssignPermissions(ctx, plan.RoleID, request); err != nil {
+		log.ErrorFCtx(ctx, "Failed to sync permissions to role %s: %v", plan.RoleID, err)
 		return fmt.Errorf("failed to sync permissions to role: %w", err)
 	}
 
-	log.InfoFCtx(ctx, "Successfully synchronized %d permissions across %d services to role %s", len(permissionIDs), len(managedServices), roleDef.RoleID)
+	log.InfoFCtx(ctx, "Synchronized role %s: assigned %d, revoked %d across services %v", plan.RoleID, len(plan.Assign), len(plan.Revoke), plan.Services)
 	return nil
 }
 
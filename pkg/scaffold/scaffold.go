@@ -0,0 +1,211 @@
+// Package scaffold generates the skeleton of a new service wired with
+// core-lab: config, logger, observability, auth, a permissions catalog,
+// postgres with migrations, health probes and example routes, laid out as
+// docs/platform-service-standards.md describes.
+//
+//	files, err := scaffold.NewService("./order-history", scaffold.ServiceOptions{
+//		Name:   "order-history",
+//		Module: "github.com/acme/order-history",
+//	})
+//
+// The corelab command exposes the same generator as
+// `corelab new service <name>`.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// DefaultPort is the port a generated service listens on unless
+// ServiceOptions.Port is set.
+const DefaultPort = "8080"
+
+// goVersion is the go directive of generated modules. Keep it in step with
+// core-lab's own go.mod.
+const goVersion = "1.26"
+
+// ErrFilesExist is returned by NewService when files it would write already
+// exist and ServiceOptions.Force is not set.
+var ErrFilesExist = errors.New("scaffold: files already exist")
+
+//go:embed all:templates/service
+var serviceTemplates embed.FS
+
+var (
+	serviceNameRe = regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`)
+	portRe        = regexp.MustCompile(`^[0-9]{1,5}$`)
+)
+
+// ServiceOptions describes the service to generate.
+type ServiceOptions struct {
+	// Name is the service name in kebab case, e.g. "order-history". It
+	// names the binary, the config defaults and the database. Required.
+	Name string
+	// Module is the Go module path of the service. Defaults to Name.
+	Module string
+	// PermissionService is the service segment of the generated permission
+	// codes, e.g. "orh" for "orh-examples-read". Defaults to Name without
+	// dashes.
+	PermissionService string
+	// Port is the HTTP port of the service. Defaults to DefaultPort.
+	Port string
+	// CoreLabVersion is the core-lab version required by the generated
+	// go.mod, e.g. "v0.3.0". When empty the requirement is left to
+	// `go mod tidy`, which picks the latest release.
+	CoreLabVersion string
+	// Force lets NewService overwrite files that already exist.
+	Force bool
+}
+
+// File is one generated file. Path is slash-separated and relative to the
+// service directory.
+type File struct {
+	Path    string
+	Content []byte
+}
+
+// templateData is what the templates see.
+type templateData struct {
+	Name              string
+	Title             string
+	Module            string
+	DatabaseName      string
+	PermissionService string
+	Port              string
+	CoreLabVersion    string
+	GoVersion         string
+}
+
+// RenderService renders the files of a new service without writing them.
+// Go files are gofmt-ed, so a template mistake surfaces as an error here.
+func RenderService(opts ServiceOptions) ([]File, error) {
+	data, err := opts.templateData()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	err = fs.WalkDir(serviceTemplates, "templates/service", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		raw, err := serviceTemplates.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimSuffix(strings.TrimPrefix(p, "templates/service/"), ".tmpl")
+		tmpl, err := template.New(rel).Option("missingkey=error").Parse(string(raw))
+		if err != nil {
+			return fmt.Errorf("scaffold: parse template %s: %w", rel, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return fmt.Errorf("scaffold: render %s: %w", rel, err)
+		}
+		content := buf.Bytes()
+		if path.Ext(rel) == ".go" {
+			if content, err = format.Source(content); err != nil {
+				return fmt.Errorf("scaffold: format %s: %w", rel, err)
+			}
+		}
+		files = append(files, File{Path: rel, Content: content})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// NewService renders a new service into dir, creating it when needed, and
+// returns the files written. Unless opts.Force is set it refuses to write
+// when any of the files already exists, and writes nothing.
+func NewService(dir string, opts ServiceOptions) ([]File, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, errors.New("scaffold: target directory is required")
+	}
+	files, err := RenderService(opts)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.Force {
+		var existing []string
+		for _, f := range files {
+			if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(f.Path))); err == nil {
+				existing = append(existing, f.Path)
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+		}
+		if len(existing) > 0 {
+			return nil, fmt.Errorf("%w in %s: %s", ErrFilesExist, dir, strings.Join(existing, ", "))
+		}
+	}
+	for _, f := range files {
+		target := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, f.Content, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func (opts ServiceOptions) templateData() (templateData, error) {
+	name := strings.TrimSpace(opts.Name)
+	if !serviceNameRe.MatchString(name) {
+		return templateData{}, fmt.Errorf("scaffold: service name %q must be kebab case, e.g. order-history", opts.Name)
+	}
+	module := strings.TrimSpace(opts.Module)
+	if module == "" {
+		module = name
+	}
+	if strings.ContainsAny(module, " \t\\") || strings.HasPrefix(module, "/") || strings.HasSuffix(module, "/") {
+		return templateData{}, fmt.Errorf("scaffold: invalid module path %q", opts.Module)
+	}
+	port := strings.TrimSpace(opts.Port)
+	if port == "" {
+		port = DefaultPort
+	}
+	if !portRe.MatchString(port) {
+		return templateData{}, fmt.Errorf("scaffold: invalid port %q", opts.Port)
+	}
+	version := strings.TrimSpace(opts.CoreLabVersion)
+	if version != "" && !strings.HasPrefix(version, "v") {
+		return templateData{}, fmt.Errorf("scaffold: core-lab version %q must look like v0.3.0", opts.CoreLabVersion)
+	}
+	permissionService := strings.ToLower(strings.TrimSpace(opts.PermissionService))
+	if permissionService == "" {
+		permissionService = strings.ReplaceAll(name, "-", "")
+	}
+
+	words := strings.Split(name, "-")
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return templateData{
+		Name:              name,
+		Title:             strings.Join(words, " "),
+		Module:            module,
+		DatabaseName:      strings.ReplaceAll(name, "-", "_"),
+		PermissionService: permissionService,
+		Port:              port,
+		CoreLabVersion:    version,
+		GoVersion:         goVersion,
+	}, nil
+}
@@ -0,0 +1,133 @@
+package scaffold
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/milan604/core-lab/pkg/postgres/migrations"
+)
+
+func TestRenderServiceFillsTemplates(t *testing.T) {
+	files, err := RenderService(ServiceOptions{
+		Name:              "order-history",
+		Module:            "github.com/acme/order-history",
+		PermissionService: "ORH",
+		Port:              "9090",
+		CoreLabVersion:    "v0.3.0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byPath := make(map[string]string, len(files))
+	migrationFS := fstest.MapFS{}
+	for _, f := range files {
+		byPath[f.Path] = string(f.Content)
+		if strings.Contains(string(f.Content), "{{.") {
+			t.Errorf("%s has an unrendered placeholder", f.Path)
+		}
+		if dir, name := filepath.Split(f.Path); dir == "core/db/migrations/" {
+			migrationFS[name] = &fstest.MapFile{Data: f.Content}
+		}
+	}
+	for _, path := range []string{"go.mod", "main.go", "config/config.go", "router/router.go", "core/service/permissions.go", "core/db/db.go", "env/config.json"} {
+		if _, ok := byPath[path]; !ok {
+			t.Fatalf("missing %s in %v", path, files)
+		}
+	}
+
+	wants := map[string][]string{
+		"go.mod":                      {"module github.com/acme/order-history", "go " + goVersion, "require github.com/milan604/core-lab v0.3.0"},
+		"main.go":                     {`"github.com/acme/order-history/router"`, "app.New(config.ServiceName, version.Version)"},
+		"config/config.go":            {`ServiceName = "order-history"`, `Port = "9090"`, `KeyDatabaseName:     "order_history"`},
+		"core/service/permissions.go": {`PermissionService = "orh"`},
+		"README.md":                   {"# order-history", "orh-examples-read"},
+	}
+	for path, substrings := range wants {
+		for _, want := range substrings {
+			if !strings.Contains(byPath[path], want) {
+				t.Errorf("%s does not contain %q:\n%s", path, want, byPath[path])
+			}
+		}
+	}
+
+	report, err := migrations.Lint(migrationFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Migrations == 0 || len(report.Issues) > 0 {
+		t.Fatalf("migrations = %d, issues = %v", report.Migrations, report.Issues)
+	}
+}
+
+func TestRenderServiceDefaults(t *testing.T) {
+	files, err := RenderService(ServiceOptions{Name: "orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		content := string(f.Content)
+		switch f.Path {
+		case "go.mod":
+			if !strings.HasPrefix(content, "module orders\n") || strings.Contains(content, "require") {
+				t.Errorf("go.mod = %q", content)
+			}
+		case "config/config.go":
+			if !strings.Contains(content, `Port = "`+DefaultPort+`"`) {
+				t.Errorf("config.go does not use the default port")
+			}
+		}
+	}
+}
+
+func TestRenderServiceRejectsInvalidOptions(t *testing.T) {
+	for _, opts := range []ServiceOptions{
+		{},
+		{Name: "Orders"},
+		{Name: "order_history"},
+		{Name: "orders-"},
+		{Name: "orders", Module: "/abs/path"},
+		{Name: "orders", Port: "http"},
+		{Name: "orders", CoreLabVersion: "0.3.0"},
+	} {
+		if _, err := RenderService(opts); err == nil {
+			t.Errorf("RenderService(%+v) succeeded", opts)
+		}
+	}
+}
+
+func TestNewServiceDoesNotOverwrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "orders")
+	files, err := NewService(dir, ServiceOptions{Name: "orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "core", "db", "migrations", "000001_create_examples.up.sql")); err != nil {
+		t.Fatal(err)
+	}
+
+	mainPath := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(mainPath, []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewService(dir, ServiceOptions{Name: "orders"}); !errors.Is(err, ErrFilesExist) {
+		t.Fatalf("second NewService error = %v, want ErrFilesExist", err)
+	}
+	if got, _ := os.ReadFile(mainPath); string(got) != "package main\n" {
+		t.Fatal("main.go was overwritten without Force")
+	}
+
+	if _, err := NewService(dir, ServiceOptions{Name: "orders", Force: true}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(mainPath)
+	for _, f := range files {
+		if f.Path == "main.go" && string(got) != string(f.Content) {
+			t.Fatal("main.go was not regenerated with Force")
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Package scheduler runs recurring tasks on cron schedules, with overlap
+// policies, distributed locking, and per-run spans and metrics. It also
+// provides cron spec validation, next-run previews, and admin routes for
+// inspecting registered jobs.
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// MaxPreviewRuns caps how many upcoming runs NextRuns returns.
+const MaxPreviewRuns = 100
+
+// ErrInvalidSpec is wrapped by every parse error, so callers can detect invalid
+// specs with errors.Is.
+var ErrInvalidSpec = errors.New("invalid cron spec")
+
+// specParser accepts standard five-field specs, an optional leading seconds
+// field, and descriptors such as @daily or @every 5m.
+var specParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// Schedule is a parsed cron spec bound to a time zone.
+type Schedule struct {
+	spec     string
+	location *time.Location
+	schedule cron.Schedule
+}
+
+// Parse parses spec in UTC unless it carries a CRON_TZ= or TZ= prefix, e.g.
+// "CRON_TZ=Asia/Kathmandu 0 9 * * MON-FRI".
+func Parse(spec string) (*Schedule, error) {
+	return ParseInLocation(spec, time.UTC)
+}
+
+// ParseInLocation parses spec, evaluating it in loc unless the spec carries its
+// own CRON_TZ= or TZ= prefix.
+func ParseInLocation(spec string, loc *time.Location) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("%w: spec is empty", ErrInvalidSpec)
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	body := spec
+	if tz, rest, ok := splitTimezonePrefix(spec); ok {
+		parsed, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown time zone %q", ErrInvalidSpec, tz)
+		}
+		loc, body = parsed, rest
+	}
+
+	schedule, err := specParser.Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSpec, err)
+	}
+	if specSchedule, ok := schedule.(*cron.SpecSchedule); ok {
+		specSchedule.Location = loc
+	}
+
+	return &Schedule{spec: spec, location: loc, schedule: schedule}, nil
+}
+
+// Validate reports whether spec parses, returning an error wrapping ErrInvalidSpec otherwise.
+func Validate(spec string) error {
+	_, err := Parse(spec)
+	return err
+}
+
+// NextRuns parses spec and returns its next n run times after now.
+func NextRuns(spec string, n int) ([]time.Time, error) {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	return schedule.NextRuns(time.Now(), n), nil
+}
+
+// Spec returns the spec the schedule was parsed from.
+func (s *Schedule) Spec() string { return s.spec }
+
+// Location returns the time zone the schedule is evaluated in.
+func (s *Schedule) Location() *time.Location { return s.location }
+
+// Next returns the first run time strictly after t, in the schedule's time zone.
+// It returns the zero time when the spec can never fire (e.g. "0 0 30 2 *").
+func (s *Schedule) Next(t time.Time) time.Time {
+	next := s.schedule.Next(t.In(s.location))
+	if next.IsZero() {
+		return next
+	}
+	return next.In(s.location)
+}
+
+// NextRuns returns up to n run times after from. n is capped at MaxPreviewRuns.
+func (s *Schedule) NextRuns(from time.Time, n int) []time.Time {
+	if n <= 0 {
+		return nil
+	}
+	if n > MaxPreviewRuns {
+		n = MaxPreviewRuns
+	}
+
+	runs := make([]time.Time, 0, n)
+	next := from
+	for len(runs) < n {
+		next = s.Next(next)
+		if next.IsZero() {
+			break
+		}
+		runs = append(runs, next)
+	}
+	return runs
+}
+
+func splitTimezonePrefix(spec string) (tz, rest string, ok bool) {
+	for _, prefix := range []string{"CRON_TZ=", "TZ="} {
+		if !strings.HasPrefix(spec, prefix) {
+			continue
+		}
+		fields := strings.SplitN(strings.TrimPrefix(spec, prefix), " ", 2)
+		if len(fields) != 2 {
+			return strings.TrimSpace(fields[0]), "", true
+		}
+		return strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1]), true
+	}
+	return "", spec, false
+}
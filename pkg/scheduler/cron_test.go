@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseNextRunsInTimezone(t *testing.T) {
+	schedule, err := Parse("CRON_TZ=Asia/Kathmandu 0 9 * * MON-FRI")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := schedule.Location().String(); got != "Asia/Kathmandu" {
+		t.Fatalf("Location() = %q, want Asia/Kathmandu", got)
+	}
+
+	// Friday 2026-10-16 10:00 Kathmandu: next runs are Mon, Tue, Wed at 09:00 local.
+	from := time.Date(2026, 10, 16, 10, 0, 0, 0, schedule.Location())
+	runs := schedule.NextRuns(from, 3)
+	want := []string{"2026-10-19T09:00:00+05:45", "2026-10-20T09:00:00+05:45", "2026-10-21T09:00:00+05:45"}
+	if len(runs) != len(want) {
+		t.Fatalf("NextRuns() returned %d runs, want %d", len(runs), len(want))
+	}
+	for i := range want {
+		if got := runs[i].Format(time.RFC3339); got != want[i] {
+			t.Fatalf("run[%d] = %s, want %s", i, got, want[i])
+		}
+	}
+}
+
+func TestParseSupportsSecondsAndDescriptors(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"*/30 * * * * *": from.Add(30 * time.Second),
+		"@hourly":        from.Add(time.Hour),
+		"@every 90s":     from.Add(90 * time.Second),
+	}
+	for spec, want := range cases {
+		schedule, err := Parse(spec)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", spec, err)
+		}
+		if got := schedule.Next(from); !got.Equal(want) {
+			t.Fatalf("Parse(%q).Next() = %s, want %s", spec, got, want)
+		}
+	}
+}
+
+func TestValidateRejectsInvalidSpecs(t *testing.T) {
+	for _, spec := range []string{"", "61 * * * *", "* * *", "CRON_TZ=Mars/Olympus 0 * * * *"} {
+		if err := Validate(spec); !errors.Is(err, ErrInvalidSpec) {
+			t.Fatalf("Validate(%q) error = %v, want ErrInvalidSpec", spec, err)
+		}
+	}
+}
+
+func TestNextRunsStopsForImpossibleSpec(t *testing.T) {
+	runs, err := NextRuns("0 0 30 2 *", 3)
+	if err != nil {
+		t.Fatalf("NextRuns() error = %v", err)
+	}
+	if len(runs) != 0 {
+		t.Fatalf("NextRuns() = %v, want none for February 30th", runs)
+	}
+}
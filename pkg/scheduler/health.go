@@ -0,0 +1,111 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/health"
+)
+
+// HealthConfig sets the thresholds of the scheduler's health checks. Zero
+// values take the defaults; a negative threshold disables that level.
+type HealthConfig struct {
+	// OverdueAfter is how long past its next run time a job may go without
+	// firing before the heartbeat check is down. Default: 1m.
+	OverdueAfter time.Duration
+	// FailuresDegraded and FailuresDown are how many runs of one job may fail
+	// in a row before the failures check is degraded or down. Scheduled jobs
+	// run rarely, so the defaults are 1 and 3.
+	FailuresDegraded int
+	FailuresDown     int
+	// AffectReadiness registers the checks as health.Critical, so a check
+	// that is down fails readiness and takes the instance out of rotation.
+	AffectReadiness bool
+}
+
+func normalizeHealthConfig(cfg HealthConfig) HealthConfig {
+	if cfg.OverdueAfter == 0 {
+		cfg.OverdueAfter = time.Minute
+	}
+	if cfg.FailuresDegraded == 0 {
+		cfg.FailuresDegraded = 1
+	}
+	if cfg.FailuresDown == 0 {
+		cfg.FailuresDown = 3
+	}
+	return cfg
+}
+
+// RegisterHealthChecks registers the heartbeat and failures checks on reg as
+// "scheduler.<name>.heartbeat" and "scheduler.<name>.failures".
+func (s *Scheduler) RegisterHealthChecks(reg *health.Registry, cfg HealthConfig) {
+	var opts []health.CheckOption
+	if cfg.AffectReadiness {
+		opts = append(opts, health.Critical())
+	}
+	prefix := "scheduler." + s.cfg.Name + "."
+	reg.Register(prefix+"heartbeat", s.HeartbeatChecker(cfg), opts...)
+	reg.Register(prefix+"failures", s.FailureChecker(cfg), opts...)
+}
+
+// HeartbeatChecker reports whether the scheduler is running and its jobs
+// fire on time. It is down when the scheduler is stopped or a job is more
+// than OverdueAfter past its next run time.
+func (s *Scheduler) HeartbeatChecker(cfg HealthConfig) health.Checker {
+	cfg = normalizeHealthConfig(cfg)
+	return health.CheckerFunc(func(context.Context) health.Result {
+		s.mu.RLock()
+		running := s.running
+		s.mu.RUnlock()
+		if !running {
+			return health.Down("scheduler is not running")
+		}
+
+		now := time.Now()
+		var overdue []string
+		details := map[string]any{}
+		for _, status := range s.Jobs() {
+			if status.NextRunAt == nil || cfg.OverdueAfter < 0 {
+				continue
+			}
+			if late := now.Sub(*status.NextRunAt); late > cfg.OverdueAfter {
+				overdue = append(overdue, status.Name)
+				details[status.Name] = map[string]any{"next_run_at": *status.NextRunAt, "late_seconds": late.Seconds()}
+			}
+		}
+		if len(overdue) > 0 {
+			return health.Down("overdue jobs: " + strings.Join(overdue, ", ")).WithDetails(details)
+		}
+		return health.Up()
+	})
+}
+
+// FailureChecker reports jobs whose recent runs keep failing. A successful
+// run resets the job's count.
+func (s *Scheduler) FailureChecker(cfg HealthConfig) health.Checker {
+	cfg = normalizeHealthConfig(cfg)
+	return health.CheckerFunc(func(context.Context) health.Result {
+		worst, worstJob := 0, ""
+		details := map[string]any{}
+		for _, status := range s.Jobs() {
+			if status.ConsecutiveFailures == 0 {
+				continue
+			}
+			details[status.Name] = map[string]any{"consecutive_failures": status.ConsecutiveFailures, "last_error": status.LastError}
+			if status.ConsecutiveFailures > worst {
+				worst, worstJob = status.ConsecutiveFailures, status.Name
+			}
+		}
+
+		message := fmt.Sprintf("%d consecutive failures of %s", worst, worstJob)
+		switch {
+		case cfg.FailuresDown > 0 && worst >= cfg.FailuresDown:
+			return health.Down(message).WithDetails(details)
+		case cfg.FailuresDegraded > 0 && worst >= cfg.FailuresDegraded:
+			return health.Degraded(message).WithDetails(details)
+		}
+		return health.Up().WithDetails(details)
+	})
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/health"
+)
+
+func TestFailureCheckerCountsConsecutiveFailures(t *testing.T) {
+	sched := New(Config{})
+	fail := true
+	if err := sched.Register("sync", "@hourly", func(context.Context) error {
+		if fail {
+			return errors.New("upstream unavailable")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	j := sched.jobs["sync"]
+	checker := sched.FailureChecker(HealthConfig{FailuresDegraded: 1, FailuresDown: 2})
+	run := func() {
+		sched.fire(context.Background(), j, time.Now())
+		sched.runs.Wait()
+	}
+
+	run()
+	if result := checker.Check(context.Background()); result.Status != health.StatusDegraded {
+		t.Fatalf("expected degraded after one failure, got %+v", result)
+	}
+	run()
+	if result := checker.Check(context.Background()); result.Status != health.StatusDown {
+		t.Fatalf("expected down after two failures, got %+v", result)
+	}
+	if status := sched.Jobs()[0]; status.ConsecutiveFailures != 2 {
+		t.Fatalf("status = %+v", status)
+	}
+
+	fail = false
+	run()
+	if result := checker.Check(context.Background()); result.Status != health.StatusUp {
+		t.Fatalf("expected a success to reset failures, got %+v", result)
+	}
+}
+
+func TestHeartbeatCheckerFlipsReadiness(t *testing.T) {
+	sched := New(Config{Name: "billing"})
+	if err := sched.Register("invoices", "@hourly", func(context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	reg := health.NewRegistry()
+	sched.RegisterHealthChecks(reg, HealthConfig{AffectReadiness: true})
+
+	if report := reg.Check(context.Background()); report.Ready {
+		t.Fatalf("a stopped scheduler must fail readiness: %+v", report)
+	}
+
+	if err := sched.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer sched.Stop(context.Background())
+	j := sched.jobs["invoices"]
+	waitFor(t, func() bool {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		return !j.nextRunAt.IsZero()
+	})
+	if report := reg.Check(context.Background()); !report.Ready || report.Status != health.StatusUp {
+		t.Fatalf("expected a running scheduler to be ready: %+v", report)
+	}
+
+	j.mu.Lock()
+	j.nextRunAt = time.Now().Add(-5 * time.Minute)
+	j.mu.Unlock()
+	report := reg.Check(context.Background())
+	if report.Ready || report.Checks["scheduler.billing.heartbeat"].Status != health.StatusDown {
+		t.Fatalf("an overdue job must fail readiness: %+v", report)
+	}
+}
@@ -0,0 +1,136 @@
+package scheduler
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/milan604/core-lab/pkg/apperr"
+	"github.com/milan604/core-lab/pkg/response"
+)
+
+const defaultPreviewRuns = 5
+
+// RunStatus is the outcome of a job's most recent run.
+type RunStatus string
+
+const (
+	RunStatusNever     RunStatus = "never"
+	RunStatusRunning   RunStatus = "running"
+	RunStatusSucceeded RunStatus = "succeeded"
+	RunStatusFailed    RunStatus = "failed"
+	RunStatusSkipped   RunStatus = "skipped"
+)
+
+// JobStatus describes a registered recurring job for the admin API.
+type JobStatus struct {
+	Name       string     `json:"name"`
+	Spec       string     `json:"spec"`
+	Timezone   string     `json:"timezone"`
+	LastRunAt  *time.Time `json:"last_run_at,omitempty"`
+	LastStatus RunStatus  `json:"last_status"`
+	LastError  string     `json:"last_error,omitempty"`
+	NextRunAt  *time.Time `json:"next_run_at,omitempty"`
+	// ConsecutiveFailures counts the failed runs since the last success.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+}
+
+// JobLister exposes registered jobs to the admin routes.
+type JobLister interface {
+	Jobs() []JobStatus
+}
+
+// SchedulePreview is the response body of the cron preview route.
+type SchedulePreview struct {
+	Spec     string      `json:"spec"`
+	Timezone string      `json:"timezone"`
+	NextRuns []time.Time `json:"next_runs"`
+}
+
+// RegisterAdminRoutes mounts scheduler administration routes onto the provided router:
+//
+//	GET /schedules                          registered jobs with last and next run
+//	GET /schedules/preview?spec=...&n=5&tz= validate a spec and preview its next runs
+func RegisterAdminRoutes(router gin.IRoutes, lister JobLister) {
+	router.GET("/schedules", func(c *gin.Context) {
+		jobs := lister.Jobs()
+		now := time.Now()
+		for i := range jobs {
+			if jobs[i].LastStatus == "" {
+				jobs[i].LastStatus = RunStatusNever
+			}
+			// Specs without a CRON_TZ= prefix run in the job's time zone,
+			// as the scheduler evaluates them.
+			loc := time.UTC
+			if jobs[i].Timezone != "" {
+				if parsed, err := time.LoadLocation(jobs[i].Timezone); err == nil {
+					loc = parsed
+				}
+			}
+			schedule, err := ParseInLocation(jobs[i].Spec, loc)
+			if err != nil {
+				continue
+			}
+			if jobs[i].Timezone == "" {
+				jobs[i].Timezone = schedule.Location().String()
+			}
+			if jobs[i].NextRunAt == nil {
+				if next := schedule.Next(now); !next.IsZero() {
+					jobs[i].NextRunAt = &next
+				}
+			}
+		}
+		sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+
+		response.JSONSuccess(c, http.StatusOK, jobs, map[string]any{"count": len(jobs)})
+	})
+
+	router.GET("/schedules/preview", func(c *gin.Context) {
+		preview, err := previewSchedule(c.Query("spec"), c.Query("tz"), c.Query("n"))
+		if err != nil {
+			response.HandleError(c, err)
+			return
+		}
+		response.Success(c, preview)
+	})
+}
+
+func previewSchedule(spec, tz, rawN string) (SchedulePreview, error) {
+	n := defaultPreviewRuns
+	if rawN != "" {
+		value, err := strconv.Atoi(rawN)
+		if err != nil || value <= 0 || value > MaxPreviewRuns {
+			return SchedulePreview{}, apperr.New(apperr.ErrorCodeInvalidInput).
+				WithMessage("invalid n").
+				AddSuggestion("n", "provide an integer between 1 and "+strconv.Itoa(MaxPreviewRuns))
+		}
+		n = value
+	}
+
+	loc := time.UTC
+	if tz != "" {
+		parsed, err := time.LoadLocation(tz)
+		if err != nil {
+			return SchedulePreview{}, apperr.New(apperr.ErrorCodeInvalidInput).
+				WithMessage("invalid time zone").
+				AddSuggestion("tz", "use an IANA time zone such as Asia/Kathmandu")
+		}
+		loc = parsed
+	}
+
+	schedule, err := ParseInLocation(spec, loc)
+	if err != nil {
+		return SchedulePreview{}, apperr.New(apperr.ErrorCodeInvalidInput).
+			WithMessage("invalid cron spec").
+			AddSuggestion("spec", err.Error())
+	}
+
+	return SchedulePreview{
+		Spec:     schedule.Spec(),
+		Timezone: schedule.Location().String(),
+		NextRuns: schedule.NextRuns(time.Now(), n),
+	}, nil
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type staticLister []JobStatus
+
+func (s staticLister) Jobs() []JobStatus { return append([]JobStatus(nil), s...) }
+
+func TestAdminRoutesListSchedules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterAdminRoutes(router, staticLister{
+		{Name: "reports.nightly", Spec: "TZ=Asia/Kathmandu 0 2 * * *"},
+		{Name: "cache.warm", Spec: "@every 5m", LastStatus: RunStatusSucceeded},
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/schedules", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d body = %s", recorder.Code, recorder.Body.String())
+	}
+
+	var envelope struct {
+		Data []JobStatus `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(envelope.Data) != 2 || envelope.Data[0].Name != "cache.warm" {
+		t.Fatalf("jobs = %#v, want sorted by name", envelope.Data)
+	}
+	nightly := envelope.Data[1]
+	if nightly.Timezone != "Asia/Kathmandu" || nightly.LastStatus != RunStatusNever || nightly.NextRunAt == nil {
+		t.Fatalf("nightly = %#v, want timezone, never-run status, and next run", nightly)
+	}
+}
+
+func TestAdminRoutesListSchedulesInJobTimezone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterAdminRoutes(router, staticLister{
+		{Name: "reports.nightly", Spec: "0 2 * * *", Timezone: "Asia/Kathmandu"},
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/schedules", nil))
+	var envelope struct {
+		Data []JobStatus `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(envelope.Data) != 1 || envelope.Data[0].NextRunAt == nil {
+		t.Fatalf("jobs = %#v", envelope.Data)
+	}
+	kathmandu, err := time.LoadLocation("Asia/Kathmandu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := envelope.Data[0].NextRunAt.In(kathmandu)
+	if next.Hour() != 2 || next.Minute() != 0 {
+		t.Fatalf("next run = %s, want 02:00 Asia/Kathmandu", next)
+	}
+}
+
+func TestAdminRoutesPreviewSchedule(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterAdminRoutes(router, staticLister{})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/schedules/preview?spec=0+*/6+*+*+*&n=3&tz=Europe/Berlin", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d body = %s", recorder.Code, recorder.Body.String())
+	}
+	var envelope struct {
+		Data SchedulePreview `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if envelope.Data.Timezone != "Europe/Berlin" || len(envelope.Data.NextRuns) != 3 {
+		t.Fatalf("preview = %#v", envelope.Data)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/schedules/preview?spec=bogus", nil))
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid spec status = %d, want %d", recorder.Code, http.StatusUnprocessableEntity)
+	}
+}
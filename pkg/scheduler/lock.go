@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+
+	"github.com/milan604/core-lab/pkg/postgres"
+)
+
+// Locker elects the replica that runs a due job.
+type Locker interface {
+	// TryLock claims key for at most ttl. It returns false, without error,
+	// when another holder has it.
+	TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, bool, error)
+}
+
+// Lock is a held Locker claim.
+type Lock interface {
+	// Unlock releases the claim after keepFor, or right away when keepFor is
+	// not positive.
+	Unlock(ctx context.Context, keepFor time.Duration) error
+}
+
+// RedisLocker locks with SET NX PX on a random token, so only the holder can
+// release or shorten its lock.
+type RedisLocker struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisLocker returns a Locker storing locks under "scheduler:lock:<key>".
+func NewRedisLocker(client redis.UniversalClient) *RedisLocker {
+	return &RedisLocker{client: client, prefix: "scheduler:lock:"}
+}
+
+// unlockScript deletes the lock, or shortens it to ARGV[2] milliseconds, if
+// ARGV[1] still holds it.
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+  return 0
+end
+if tonumber(ARGV[2]) > 0 then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return redis.call('DEL', KEYS[1])
+`)
+
+// TryLock implements Locker.
+func (l *RedisLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, bool, error) {
+	token, err := randomToken()
+	if err != nil {
+		return nil, false, err
+	}
+	ok, err := l.client.SetNX(ctx, l.prefix+key, token, ttl).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return &redisLock{client: l.client, key: l.prefix + key, token: token}, true, nil
+}
+
+type redisLock struct {
+	client redis.UniversalClient
+	key    string
+	token  string
+}
+
+func (l *redisLock) Unlock(ctx context.Context, keepFor time.Duration) error {
+	return unlockScript.Run(ctx, l.client, []string{l.key}, l.token, keepFor.Milliseconds()).Err()
+}
+
+// PostgresLocker locks with session-level advisory locks
+// (pg_try_advisory_lock), held on a dedicated connection from the primary's
+// pool until Unlock. ttl is not needed: the lock is released when the holder
+// unlocks or its connection closes.
+type PostgresLocker struct {
+	db *postgres.DB
+}
+
+// NewPostgresLocker returns a Locker on the primary of db.
+func NewPostgresLocker(db *postgres.DB) *PostgresLocker {
+	return &PostgresLocker{db: db}
+}
+
+// TryLock implements Locker.
+func (l *PostgresLocker) TryLock(ctx context.Context, key string, _ time.Duration) (Lock, bool, error) {
+	sqlDB, err := l.db.Client.DB()
+	if err != nil {
+		return nil, false, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, key).Scan(&acquired); err != nil {
+		_ = conn.Close()
+		return nil, false, fmt.Errorf("try advisory lock %s: %w", key, err)
+	}
+	if !acquired {
+		return nil, false, conn.Close()
+	}
+	return &postgresLock{conn: conn, key: key}, true, nil
+}
+
+type postgresLock struct {
+	conn *sql.Conn
+	key  string
+}
+
+func (l *postgresLock) Unlock(ctx context.Context, keepFor time.Duration) error {
+	if keepFor > 0 {
+		time.AfterFunc(keepFor, func() { _ = l.release(context.Background()) })
+		return nil
+	}
+	return l.release(ctx)
+}
+
+func (l *postgresLock) release(ctx context.Context) error {
+	defer l.conn.Close()
+	if _, err := l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext($1))`, l.key); err != nil {
+		return fmt.Errorf("advisory unlock %s: %w", l.key, err)
+	}
+	return nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
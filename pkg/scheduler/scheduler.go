@@ -0,0 +1,480 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/milan604/core-lab/pkg/apperr"
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+const instrumentationName = "github.com/milan604/core-lab/pkg/scheduler"
+
+const (
+	defaultLockTTL     = 10 * time.Minute
+	defaultLockAtLeast = 5 * time.Second
+)
+
+// Attribute keys recorded on scheduler spans and metrics.
+var (
+	AttrJob     = attribute.Key("scheduler.job")
+	AttrOutcome = attribute.Key("scheduler.outcome")
+)
+
+// Run outcomes recorded in the scheduler.runs metric.
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+	// OutcomeSkipped: the previous run was still going (OverlapSkip).
+	OutcomeSkipped = "skipped"
+	// OutcomeQueued: the run was deferred until the previous one finished
+	// (OverlapQueue).
+	OutcomeQueued = "queued"
+	// OutcomeLocked: another replica holds the job's lock.
+	OutcomeLocked = "locked"
+)
+
+// Task is the work of a recurring job. ctx is cancelled when the job's
+// timeout elapses or the scheduler stops.
+type Task func(ctx context.Context) error
+
+// OverlapPolicy decides what happens when a job is due while its previous run
+// is still going.
+type OverlapPolicy string
+
+const (
+	// OverlapSkip drops the due run. It is the default.
+	OverlapSkip OverlapPolicy = "skip"
+	// OverlapQueue runs once more as soon as the previous run finishes; due
+	// runs beyond that one are dropped.
+	OverlapQueue OverlapPolicy = "queue"
+)
+
+// Config configures a Scheduler.
+type Config struct {
+	// Name prefixes lock keys, so schedulers of different services sharing a
+	// Locker do not collide. Default: "scheduler".
+	Name string
+	// Location evaluates specs without a CRON_TZ= prefix. Default: UTC.
+	Location *time.Location
+	// Locker, when set, makes replicas elect one runner per due run.
+	Locker Locker
+	// LockTTL bounds how long a lock is held when a job has no timeout and its
+	// replica dies mid-run. Default: 10m.
+	LockTTL time.Duration
+	// LockAtLeast keeps the lock after a short run, so a replica whose clock
+	// lags does not run the same occurrence again. It never extends past the
+	// job's next run. Default: 5s.
+	LockAtLeast time.Duration
+	Logger      logger.LogManager
+}
+
+// JobOption configures a job at registration.
+type JobOption func(*job)
+
+// WithTimeout cancels the task's context after d.
+func WithTimeout(d time.Duration) JobOption {
+	return func(j *job) { j.timeout = d }
+}
+
+// WithOverlap sets the job's OverlapPolicy.
+func WithOverlap(policy OverlapPolicy) JobOption {
+	return func(j *job) { j.overlap = policy }
+}
+
+// WithoutLock runs the job on every replica even when the scheduler has a
+// Locker, for per-instance work such as cache refreshes.
+func WithoutLock() JobOption {
+	return func(j *job) { j.local = true }
+}
+
+type job struct {
+	name     string
+	schedule *Schedule
+	task     Task
+	timeout  time.Duration
+	overlap  OverlapPolicy
+	local    bool
+
+	mu         sync.Mutex
+	running    bool
+	pending    bool
+	lastRunAt  *time.Time
+	lastStatus RunStatus
+	lastError  string
+	failures   int // consecutive failed runs
+	nextRunAt  time.Time
+}
+
+// Scheduler runs registered tasks on their cron schedules. It implements
+// JobLister, so RegisterAdminRoutes can expose it.
+//
+//	sched := scheduler.New(scheduler.Config{Logger: log, Locker: scheduler.NewRedisLocker(rdb)})
+//	_ = sched.Register("reports.nightly", "0 2 * * *", reports.Build, scheduler.WithTimeout(30*time.Minute))
+//	_ = sched.Start(ctx)
+//	defer sched.Stop(context.Background())
+type Scheduler struct {
+	cfg     Config
+	log     logger.LogManager
+	tracer  trace.Tracer
+	metrics schedulerMetrics
+
+	mu        sync.RWMutex
+	jobs      map[string]*job
+	running   bool
+	cancel    context.CancelFunc
+	runCancel context.CancelFunc
+	loops     sync.WaitGroup
+	runs      sync.WaitGroup
+}
+
+// New returns a Scheduler. Register jobs, then call Start.
+func New(cfg Config) *Scheduler {
+	if cfg.Name == "" {
+		cfg.Name = "scheduler"
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = defaultLockTTL
+	}
+	if cfg.LockAtLeast <= 0 {
+		cfg.LockAtLeast = defaultLockAtLeast
+	}
+	log := cfg.Logger
+	if log == nil {
+		log = logger.MustNewDefaultLogger()
+	}
+	return &Scheduler{
+		cfg:     cfg,
+		log:     log,
+		tracer:  otel.Tracer(instrumentationName),
+		metrics: newSchedulerMetrics(otel.Meter(instrumentationName)),
+		jobs:    make(map[string]*job),
+	}
+}
+
+// Register adds a job running task on spec. Names must be unique; they key
+// the job's lock across replicas. Jobs registered after Start run from the
+// next Start.
+func (s *Scheduler) Register(name, spec string, task Task, opts ...JobOption) error {
+	if name == "" {
+		return apperr.New(apperr.ErrorCodeInvalidInput).
+			WithMessage("job name is required")
+	}
+	if task == nil {
+		return apperr.New(apperr.ErrorCodeInvalidInput).
+			WithMessage("job task is required")
+	}
+	schedule, err := ParseInLocation(spec, s.cfg.Location)
+	if err != nil {
+		return apperr.New(apperr.ErrorCodeInvalidInput).
+			WithMessage("invalid cron spec").
+			AddSuggestion("spec", err.Error())
+	}
+
+	j := &job{name: name, schedule: schedule, task: task, overlap: OverlapSkip}
+	for _, opt := range opts {
+		opt(j)
+	}
+	if j.overlap != OverlapQueue {
+		j.overlap = OverlapSkip
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.jobs[name]; exists {
+		return apperr.New(apperr.ErrorCodeInvalidInput).
+			WithMessage("job is already registered").
+			AddSuggestion("name", name)
+	}
+	s.jobs[name] = j
+	return nil
+}
+
+// Start runs every registered job on its schedule until Stop is called or ctx
+// is done.
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	loopCtx, cancel := context.WithCancel(ctx)
+	runCtx, runCancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.running = true
+	s.cancel = cancel
+	s.runCancel = runCancel
+	for _, j := range s.jobs {
+		s.loops.Add(1)
+		go s.loop(loopCtx, runCtx, j)
+	}
+	s.log.InfoF("scheduler started name=%s jobs=%d", s.cfg.Name, len(s.jobs))
+	return nil
+}
+
+// Stop stops scheduling and waits for running tasks to finish until ctx is
+// done, then cancels their contexts and waits for them to return.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return nil
+	}
+	cancel, runCancel := s.cancel, s.runCancel
+	s.running = false
+	s.cancel, s.runCancel = nil, nil
+	s.mu.Unlock()
+
+	cancel()
+	s.loops.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	runCancel()
+	<-done
+	s.log.InfoF("scheduler stopped name=%s", s.cfg.Name)
+	return err
+}
+
+// Jobs implements JobLister.
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		j.mu.Lock()
+		status := JobStatus{
+			Name:                j.name,
+			Spec:                j.schedule.Spec(),
+			Timezone:            j.schedule.Location().String(),
+			LastRunAt:           j.lastRunAt,
+			LastStatus:          j.lastStatus,
+			LastError:           j.lastError,
+			ConsecutiveFailures: j.failures,
+		}
+		if !j.nextRunAt.IsZero() {
+			next := j.nextRunAt
+			status.NextRunAt = &next
+		}
+		j.mu.Unlock()
+		if status.LastStatus == "" {
+			status.LastStatus = RunStatusNever
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, k int) bool { return statuses[i].Name < statuses[k].Name })
+	return statuses
+}
+
+func (s *Scheduler) loop(ctx, runCtx context.Context, j *job) {
+	defer s.loops.Done()
+
+	for {
+		next := j.schedule.Next(time.Now())
+		if next.IsZero() {
+			s.log.WarnF("scheduled job never fires job=%s spec=%s", j.name, j.schedule.Spec())
+			return
+		}
+		j.mu.Lock()
+		j.nextRunAt = next
+		j.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.fire(runCtx, j, next)
+		}
+	}
+}
+
+// fire starts the occurrence of j due at scheduledAt, applying the overlap
+// policy.
+func (s *Scheduler) fire(ctx context.Context, j *job, scheduledAt time.Time) {
+	j.mu.Lock()
+	if j.running {
+		outcome := OutcomeSkipped
+		if j.overlap == OverlapQueue {
+			outcome = OutcomeQueued
+			j.pending = true
+		}
+		j.mu.Unlock()
+		s.metrics.recordRun(ctx, j.name, outcome, 0)
+		s.log.WarnF("scheduled job still running job=%s policy=%s scheduled_at=%s", j.name, j.overlap, scheduledAt.Format(time.RFC3339))
+		return
+	}
+	j.running = true
+	j.mu.Unlock()
+
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		for {
+			s.runOnce(ctx, j, scheduledAt)
+
+			j.mu.Lock()
+			if !j.pending || ctx.Err() != nil {
+				j.running, j.pending = false, false
+				j.mu.Unlock()
+				return
+			}
+			j.pending = false
+			j.mu.Unlock()
+			scheduledAt = time.Now()
+		}
+	}()
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, j *job, scheduledAt time.Time) {
+	var lock Lock
+	if s.cfg.Locker != nil && !j.local {
+		ttl := s.cfg.LockTTL
+		if j.timeout > 0 {
+			ttl = j.timeout + time.Minute
+		}
+		acquired, ok, err := s.cfg.Locker.TryLock(ctx, s.cfg.Name+":"+j.name, ttl)
+		if err != nil {
+			s.log.ErrorFCtx(ctx, "scheduled job lock failed job=%s error=%v", j.name, err)
+			s.finish(ctx, j, scheduledAt, time.Now(), fmt.Errorf("acquire lock: %w", err))
+			return
+		}
+		if !ok {
+			s.metrics.recordRun(ctx, j.name, OutcomeLocked, 0)
+			s.log.DebugF("scheduled job running on another replica job=%s", j.name)
+			return
+		}
+		lock = acquired
+	}
+
+	started := time.Now()
+	runCtx, span := s.tracer.Start(ctx, "scheduler.run "+j.name,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(AttrJob.String(j.name), attribute.String("scheduler.spec", j.schedule.Spec())))
+	cancel := func() {}
+	if j.timeout > 0 {
+		runCtx, cancel = context.WithTimeout(runCtx, j.timeout)
+	}
+	j.mu.Lock()
+	startedAt := started.UTC()
+	j.lastRunAt = &startedAt
+	j.lastStatus = RunStatusRunning
+	j.mu.Unlock()
+
+	err := s.call(runCtx, j)
+	cancel()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+
+	if lock != nil {
+		// Hold the lock for LockAtLeast, but never into the next occurrence.
+		keepUntil := started.Add(s.cfg.LockAtLeast)
+		if next := j.schedule.Next(scheduledAt); !next.IsZero() && next.Before(keepUntil) {
+			keepUntil = next
+		}
+		if unlockErr := lock.Unlock(context.WithoutCancel(ctx), time.Until(keepUntil)); unlockErr != nil {
+			s.log.WarnF("scheduled job unlock failed job=%s error=%v", j.name, unlockErr)
+		}
+	}
+	s.finish(ctx, j, scheduledAt, started, err)
+}
+
+// call runs the task, converting a panic into an error.
+func (s *Scheduler) call(ctx context.Context, j *job) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			s.log.ErrorFCtx(ctx, "scheduled job panicked job=%s panic=%v\n%s", j.name, recovered, debug.Stack())
+			err = fmt.Errorf("scheduled job panicked: %v", recovered)
+		}
+	}()
+	return j.task(ctx)
+}
+
+func (s *Scheduler) finish(ctx context.Context, j *job, scheduledAt, started time.Time, err error) {
+	duration := time.Since(started)
+	outcome, status, lastError := OutcomeSucceeded, RunStatusSucceeded, ""
+	if err != nil {
+		outcome, status, lastError = OutcomeFailed, RunStatusFailed, err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			lastError = "timed out: " + lastError
+		}
+		s.log.ErrorFCtx(ctx, "scheduled job failed job=%s scheduled_at=%s duration=%s error=%v", j.name, scheduledAt.Format(time.RFC3339), duration, err)
+	} else {
+		s.log.InfoF("scheduled job succeeded job=%s duration=%s", j.name, duration)
+	}
+
+	j.mu.Lock()
+	j.lastStatus = status
+	j.lastError = lastError
+	if err != nil {
+		j.failures++
+	} else {
+		j.failures = 0
+	}
+	j.mu.Unlock()
+	s.metrics.recordRun(ctx, j.name, outcome, duration)
+}
+
+type schedulerMetrics struct {
+	runs     metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+func newSchedulerMetrics(meter metric.Meter) schedulerMetrics {
+	var m schedulerMetrics
+	var err error
+	if m.runs, err = meter.Int64Counter("scheduler.runs",
+		metric.WithDescription("Due runs of scheduled jobs by outcome"), metric.WithUnit("{run}")); err != nil {
+		otel.Handle(fmt.Errorf("create scheduler.runs: %w", err))
+	}
+	if m.duration, err = meter.Float64Histogram("scheduler.run.duration",
+		metric.WithDescription("Duration of scheduled job runs"), metric.WithUnit("s")); err != nil {
+		otel.Handle(fmt.Errorf("create scheduler.run.duration: %w", err))
+	}
+	return m
+}
+
+// recordRun counts a due run; duration is recorded for runs that executed.
+func (m schedulerMetrics) recordRun(ctx context.Context, name, outcome string, duration time.Duration) {
+	attrs := metric.WithAttributes(AttrJob.String(name), AttrOutcome.String(outcome))
+	if m.runs != nil {
+		m.runs.Add(ctx, 1, attrs)
+	}
+	if m.duration != nil && (outcome == OutcomeSucceeded || outcome == OutcomeFailed) {
+		m.duration.Record(ctx, duration.Seconds(), attrs)
+	}
+}
@@ -0,0 +1,236 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redis "github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestSchedulerOverlapPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy OverlapPolicy
+		want   int32
+	}{
+		{OverlapSkip, 1},
+		{OverlapQueue, 2},
+	} {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			sched := New(Config{})
+			release := make(chan struct{})
+			var calls atomic.Int32
+			if err := sched.Register("report", "@hourly", func(ctx context.Context) error {
+				calls.Add(1)
+				<-release
+				return nil
+			}, WithOverlap(tc.policy)); err != nil {
+				t.Fatal(err)
+			}
+			j := sched.jobs["report"]
+
+			ctx := context.Background()
+			now := time.Now()
+			sched.fire(ctx, j, now)
+			waitFor(t, func() bool { return calls.Load() == 1 })
+			sched.fire(ctx, j, now.Add(time.Hour))
+			sched.fire(ctx, j, now.Add(2*time.Hour))
+			close(release)
+			sched.runs.Wait()
+
+			if got := calls.Load(); got != tc.want {
+				t.Fatalf("calls = %d, want %d", got, tc.want)
+			}
+			if status := sched.Jobs()[0]; status.LastStatus != RunStatusSucceeded || status.LastRunAt == nil {
+				t.Fatalf("status = %+v", status)
+			}
+		})
+	}
+}
+
+func TestSchedulerRecordsFailuresTimeoutsAndPanics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	previous := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	t.Cleanup(func() { otel.SetMeterProvider(previous) })
+
+	sched := New(Config{})
+	if err := sched.Register("slow", "@hourly", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(10*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if err := sched.Register("broken", "@hourly", func(context.Context) error { panic("nil map") }); err != nil {
+		t.Fatal(err)
+	}
+	if err := sched.Register("ok", "@hourly", func(context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"slow", "broken", "ok"} {
+		sched.fire(context.Background(), sched.jobs[name], time.Now())
+	}
+	sched.runs.Wait()
+
+	statuses := map[string]JobStatus{}
+	for _, status := range sched.Jobs() {
+		statuses[status.Name] = status
+	}
+	if s := statuses["slow"]; s.LastStatus != RunStatusFailed || s.LastError != "timed out: context deadline exceeded" {
+		t.Fatalf("slow = %+v", s)
+	}
+	if s := statuses["broken"]; s.LastStatus != RunStatusFailed || s.LastError != "scheduled job panicked: nil map" {
+		t.Fatalf("broken = %+v", s)
+	}
+	if s := statuses["ok"]; s.LastStatus != RunStatusSucceeded {
+		t.Fatalf("ok = %+v", s)
+	}
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatal(err)
+	}
+	outcomes := map[string]int64{}
+	var durations uint64
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch agg := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, point := range agg.DataPoints {
+					job, _ := point.Attributes.Value(AttrJob)
+					outcome, _ := point.Attributes.Value(AttrOutcome)
+					outcomes[job.AsString()+"/"+outcome.AsString()] += point.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, point := range agg.DataPoints {
+					durations += point.Count
+				}
+			}
+		}
+	}
+	if outcomes["slow/failed"] != 1 || outcomes["broken/failed"] != 1 || outcomes["ok/succeeded"] != 1 || durations != 3 {
+		t.Fatalf("outcomes = %v durations = %d", outcomes, durations)
+	}
+}
+
+func TestSchedulerRedisLockElectsOneReplica(t *testing.T) {
+	mini := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mini.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	var calls atomic.Int32
+	task := func(context.Context) error {
+		calls.Add(1)
+		return nil
+	}
+	replicas := make([]*Scheduler, 2)
+	for i := range replicas {
+		replicas[i] = New(Config{Name: "billing", Locker: NewRedisLocker(client), LockAtLeast: time.Minute})
+		if err := replicas[i].Register("invoices.close", "0 0 * * *", task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	due := time.Now()
+	for _, replica := range replicas {
+		replica.fire(context.Background(), replica.jobs["invoices.close"], due)
+		replica.runs.Wait()
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("calls = %d, want 1", got)
+	}
+	// The lock outlives the short run by LockAtLeast.
+	if ttl := mini.TTL("scheduler:lock:billing:invoices.close"); ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("lock ttl = %v", ttl)
+	}
+
+	mini.FastForward(time.Minute)
+	replicas[1].fire(context.Background(), replicas[1].jobs["invoices.close"], due.Add(24*time.Hour))
+	replicas[1].runs.Wait()
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("calls after the lock expired = %d, want 2", got)
+	}
+}
+
+func TestSchedulerStartStop(t *testing.T) {
+	sched := New(Config{})
+	ran := make(chan struct{}, 1)
+	if err := sched.Register("tick", "* * * * * *", func(context.Context) error {
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sched.Register("tick", "@hourly", func(context.Context) error { return nil }); err == nil {
+		t.Fatal("duplicate name accepted")
+	}
+	if err := sched.Register("bad", "61 * * * *", func(context.Context) error { return nil }); err == nil {
+		t.Fatal("invalid spec accepted")
+	}
+
+	if err := sched.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ran:
+	case <-time.After(3 * time.Second):
+		t.Fatal("job did not run")
+	}
+	if next := sched.Jobs()[0].NextRunAt; next == nil {
+		t.Fatal("next run not reported")
+	}
+	if err := sched.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSchedulerStopCancelsRunsAfterDeadline(t *testing.T) {
+	sched := New(Config{})
+	started := make(chan struct{})
+	var once sync.Once
+	if err := sched.Register("export", "* * * * * *", func(ctx context.Context) error {
+		once.Do(func() { close(started) })
+		<-ctx.Done()
+		return ctx.Err()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sched.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-time.After(3 * time.Second):
+		t.Fatal("job did not run")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := sched.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Stop = %v, want deadline exceeded", err)
+	}
+	if status := sched.Jobs()[0]; status.LastStatus != RunStatusFailed {
+		t.Fatalf("status = %+v", status)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,303 @@
+// Package sentinel is a typed client for the Sentinel control-plane APIs used by
+// the permissions, roles, and auth packages.
+package sentinel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/milan604/core-lab/pkg/config"
+	"github.com/milan604/core-lab/pkg/controlplane"
+	httplib "github.com/milan604/core-lab/pkg/http"
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+const tracerName = "github.com/milan604/core-lab/pkg/sentinel"
+
+const (
+	defaultRetryAttempts = 3
+	defaultRetryDelay    = 200 * time.Millisecond
+	defaultMaxRetryDelay = 5 * time.Second
+)
+
+// Client calls Sentinel. The underlying pkg/http client handles
+// authentication and HTTP spans; Client adds typed requests, an operation
+// span, retries, and error mapping (see Error). Client is the only retry
+// layer: it retries transport failures and 429 and 502-504 responses, and the
+// pkg/http client it builds does not retry on its own.
+type Client struct {
+	api           controlplane.API
+	httpClient    *httplib.Client
+	retryAttempts int
+	retryDelay    time.Duration
+	maxRetryDelay time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithRetry retries calls that fail in transport or are answered with 429,
+// 502, 503, or 504 up to attempts times, doubling delay between attempts up
+// to the maximum (see WithMaxRetryDelay). Default: 3 attempts from 200ms.
+// WithRetry(1, 0) disables it.
+func WithRetry(attempts int, delay time.Duration) Option {
+	return func(c *Client) {
+		if attempts > 0 {
+			c.retryAttempts = attempts
+		}
+		if delay > 0 {
+			c.retryDelay = delay
+		}
+	}
+}
+
+// WithMaxRetryDelay caps the delay between retries. Default: 5s.
+func WithMaxRetryDelay(delay time.Duration) Option {
+	return func(c *Client) {
+		if delay > 0 {
+			c.maxRetryDelay = delay
+		}
+	}
+}
+
+// New creates a client for the Sentinel control plane at baseURL. Client
+// retries calls itself, so httpClient should be built with
+// http.WithRetry(1, 0); otherwise both layers retry and one outage multiplies
+// the calls. A nil httpClient is built that way.
+func New(baseURL string, httpClient *httplib.Client, opts ...Option) *Client {
+	if httpClient == nil {
+		httpClient = httplib.NewClient(httplib.WithRetry(1, 0))
+	}
+	c := &Client{
+		api:           controlplane.API{BaseURL: strings.TrimRight(strings.TrimSpace(baseURL), "/")},
+		httpClient:    httpClient,
+		retryAttempts: defaultRetryAttempts,
+		retryDelay:    defaultRetryDelay,
+		maxRetryDelay: defaultMaxRetryDelay,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NewFromConfig creates a client for the configured control plane that
+// authenticates with the service token (see http.NewClientWithServiceToken).
+// The pkg/http client has its retries turned off; Client retries instead.
+func NewFromConfig(cfg *config.Config, log logger.LogManager, opts ...Option) (*Client, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config not configured")
+	}
+	api := controlplane.APIFromConfig(cfg)
+	if !api.Valid() {
+		return nil, fmt.Errorf("%s or %s not configured", controlplane.KeyBaseURL, controlplane.LegacyKeyBaseURL)
+	}
+	httpClient, err := httplib.NewClientWithServiceToken(log, cfg, httplib.WithRetry(1, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client with token provider: %w", err)
+	}
+	return New(api.BaseURL, httpClient, opts...), nil
+}
+
+// API returns the control-plane URLs the client calls.
+func (c *Client) API() controlplane.API {
+	return c.api
+}
+
+// GetRolesByIDs returns the roles with the given IDs; unknown IDs are omitted.
+func (c *Client) GetRolesByIDs(ctx context.Context, roleIDs []string) ([]Role, error) {
+	if len(roleIDs) == 0 {
+		return nil, nil
+	}
+	request := struct {
+		RoleIDs []string `json:"role_ids"`
+	}{RoleIDs: roleIDs}
+	var roles []Role
+	if err := c.do(ctx, "get roles", http.MethodPost, c.api.RolesBulkURL(), request, &roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// CreatePermissionsBulk registers permissions. Permissions that already exist
+// are returned as they are.
+func (c *Client) CreatePermissionsBulk(ctx context.Context, permissions []PermissionCreate) ([]CreatedPermission, error) {
+	if len(permissions) == 0 {
+		return nil, nil
+	}
+	request := struct {
+		Permissions []PermissionCreate `json:"permissions"`
+	}{Permissions: permissions}
+	var response struct {
+		Permissions []CreatedPermission `json:"permissions"`
+	}
+	if err := c.do(ctx, "create permissions", http.MethodPost, c.api.PermissionBulkURL(), request, &response); err != nil {
+		return nil, err
+	}
+	return response.Permissions, nil
+}
+
+// GetBitmaskCatalog returns the permission catalog with bit values.
+func (c *Client) GetBitmaskCatalog(ctx context.Context) (BitmaskCatalog, error) {
+	var catalog BitmaskCatalog
+	if err := c.do(ctx, "get permission catalog", http.MethodGet, c.api.PermissionCatalogURL(), nil, &catalog); err != nil {
+		return BitmaskCatalog{}, err
+	}
+	return catalog, nil
+}
+
+// GetPermissionsByCodes resolves permission codes to IDs; unknown codes are
+// omitted.
+func (c *Client) GetPermissionsByCodes(ctx context.Context, codes []string) ([]PermissionRef, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	request := struct {
+		Codes []string `json:"codes"`
+	}{Codes: codes}
+	var permissions []PermissionRef
+	if err := c.do(ctx, "get permissions by code", http.MethodPost, c.api.PermissionByCodesURL(), request, &permissions); err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// GetRolePermissions returns the permissions currently granted to a role.
+func (c *Client) GetRolePermissions(ctx context.Context, roleID string) ([]RolePermission, error) {
+	var permissions []RolePermission
+	if err := c.do(ctx, "get role permissions", http.MethodGet, c.api.RolePermissionsURL(roleID), nil, &permissions); err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// AssignPermissions replaces the role's grants for request.Services.
+func (c *Client) AssignPermissions(ctx context.Context, roleID string, request AssignPermissionsRequest) error {
+	if request.PermissionIDs == nil {
+		request.PermissionIDs = []string{}
+	}
+	return c.do(ctx, "assign permissions", http.MethodPut, c.api.RolePermissionsURL(roleID), request, nil)
+}
+
+// IssueServiceToken exchanges service credentials for an access token. Call it
+// on a client whose http.Client has no token provider.
+func (c *Client) IssueServiceToken(ctx context.Context, request ServiceTokenRequest) (ServiceToken, error) {
+	var token ServiceToken
+	if err := c.do(ctx, "issue service token", http.MethodPost, c.api.ServiceTokenURL(), request, &token); err != nil {
+		return ServiceToken{}, err
+	}
+	if token.AccessToken == "" {
+		return ServiceToken{}, &Error{Op: "issue service token", StatusCode: http.StatusOK, Message: "empty access token in response"}
+	}
+	return token, nil
+}
+
+// Decide asks the authorization decision endpoint whether the subject may
+// perform the action.
+func (c *Client) Decide(ctx context.Context, request DecisionRequest) (DecisionResponse, error) {
+	var decision DecisionResponse
+	if err := c.do(ctx, "authorization decision", http.MethodPost, c.api.AuthorizationDecisionURL(), request, &decision); err != nil {
+		return DecisionResponse{}, err
+	}
+	return decision, nil
+}
+
+func (c *Client) do(ctx context.Context, op, method, url string, body, out any) (err error) {
+	if c == nil || c.httpClient == nil {
+		return fmt.Errorf("sentinel %s: client not configured", op)
+	}
+	if !c.api.Valid() {
+		return fmt.Errorf("sentinel %s: %s or %s not configured", op, controlplane.KeyBaseURL, controlplane.LegacyKeyBaseURL)
+	}
+
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "sentinel "+op,
+		trace.WithAttributes(attribute.String("sentinel.operation", op)))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	var payload []byte
+	if body != nil {
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("sentinel %s: encode request: %w", op, err)
+		}
+	}
+
+	delay := min(c.retryDelay, c.maxRetryDelay)
+	for attempt := 1; ; attempt++ {
+		err = c.attempt(ctx, op, method, url, payload, out)
+		var statusErr *Error
+		if err == nil || attempt >= c.retryAttempts || ctx.Err() != nil || !errors.As(err, &statusErr) || !retryableStatus(statusErr.StatusCode) {
+			return err
+		}
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("sentinel.attempt", attempt+1)))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > c.maxRetryDelay {
+			delay = c.maxRetryDelay
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, op, method, url string, payload []byte, out any) error {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return fmt.Errorf("sentinel %s: create request: %w", op, err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(ctx, req)
+	if err != nil {
+		return &Error{Op: op, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return newStatusError(op, resp.StatusCode, respBody)
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+		return fmt.Errorf("sentinel %s: decode response: %w", op, err)
+	}
+	return nil
+}
+
+// retryableStatus reports whether a call answered with status is retried;
+// status 0 is a transport failure.
+func retryableStatus(status int) bool {
+	switch status {
+	case 0, http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package sentinel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	httplib "github.com/milan604/core-lab/pkg/http"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return New(server.URL+"/control-plane", httplib.NewClient(httplib.WithRetry(1, 0)), opts...)
+}
+
+func TestClientTypedCalls(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /control-plane/api/v1/roles/bulk":
+			var body struct {
+				RoleIDs []string `json:"role_ids"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if len(body.RoleIDs) != 1 || body.RoleIDs[0] != "role-1" {
+				t.Errorf("role_ids = %v", body.RoleIDs)
+			}
+			_, _ = w.Write([]byte(`[{"id":"role-1","name":"Admin","native":true}]`))
+		case "GET /control-plane/api/v1/permissions/bitmask":
+			_, _ = w.Write([]byte(`{"services":{"usr":{"permissions":{"usr-users-read":{"id":"p1","bit_value":4}}}}}`))
+		case "PUT /control-plane/api/v1/roles/role-1/permissions":
+			var body AssignPermissionsRequest
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if len(body.PermissionIDs) != 0 || body.PermissionIDs == nil || len(body.Services) != 1 {
+				t.Errorf("assign body = %+v", body)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected call %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusTeapot)
+		}
+	})
+	ctx := context.Background()
+
+	roles, err := client.GetRolesByIDs(ctx, []string{"role-1"})
+	if err != nil || len(roles) != 1 || roles[0].Name != "Admin" || !roles[0].Native {
+		t.Fatalf("GetRolesByIDs() = %+v, %v", roles, err)
+	}
+	catalog, err := client.GetBitmaskCatalog(ctx)
+	if err != nil || catalog.Services["usr"].Permissions["usr-users-read"].BitValue != 4 {
+		t.Fatalf("GetBitmaskCatalog() = %+v, %v", catalog, err)
+	}
+	if err := client.AssignPermissions(ctx, "role-1", AssignPermissionsRequest{Services: []string{"usr"}}); err != nil {
+		t.Fatalf("AssignPermissions() error = %v", err)
+	}
+}
+
+func TestClientMapsErrors(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"success":false,"code":"role_not_found","message":"role does not exist"}`))
+	})
+
+	_, err := client.GetRolePermissions(context.Background(), "missing")
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnavailable) {
+		t.Fatalf("error = %v, want ErrNotFound", err)
+	}
+	var sentinelErr *Error
+	if !errors.As(err, &sentinelErr) || sentinelErr.Code != "role_not_found" || sentinelErr.Message != "role does not exist" {
+		t.Fatalf("error = %#v", err)
+	}
+}
+
+func TestClientRetriesUnavailable(t *testing.T) {
+	var calls atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error":"unavailable","message":"try again"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"tok","expires_in":60}`))
+	}, WithRetry(3, time.Millisecond))
+
+	token, err := client.IssueServiceToken(context.Background(), ServiceTokenRequest{ServiceID: "svc", APIKey: "key"})
+	if err != nil || token.AccessToken != "tok" || calls.Load() != 3 {
+		t.Fatalf("IssueServiceToken() = %+v, %v after %d calls", token, err, calls.Load())
+	}
+
+	calls.Store(0)
+	noRetry := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, WithRetry(1, 0))
+	if _, err := noRetry.GetBitmaskCatalog(context.Background()); !errors.Is(err, ErrUnavailable) || calls.Load() != 1 {
+		t.Fatalf("error = %v after %d calls, want one ErrUnavailable", err, calls.Load())
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// Client is the only retry layer, so a transport failure is sent once per
+// attempt and no more.
+func TestClientRetriesTransportFailures(t *testing.T) {
+	var calls atomic.Int32
+	transport := roundTripFunc(func(*http.Request) (*http.Response, error) {
+		calls.Add(1)
+		return nil, errors.New("connection refused")
+	})
+	httpClient := httplib.NewClient(httplib.WithRetry(1, 0), httplib.WithHTTPClient(&http.Client{Transport: transport}))
+	client := New("http://sentinel.internal", httpClient, WithRetry(3, time.Millisecond), WithMaxRetryDelay(time.Millisecond))
+
+	_, err := client.GetBitmaskCatalog(context.Background())
+	var sentinelErr *Error
+	if !errors.As(err, &sentinelErr) || sentinelErr.StatusCode != 0 || !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("error = %v, want a transport ErrUnavailable", err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("calls = %d, want 3", calls.Load())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls.Store(0)
+	if _, err := client.GetBitmaskCatalog(ctx); err == nil || calls.Load() > 1 {
+		t.Fatalf("canceled call = %v after %d calls, want no retries", err, calls.Load())
+	}
+}
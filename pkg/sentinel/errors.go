@@ -0,0 +1,91 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors matched by errors.Is against an *Error.
+var (
+	ErrInvalidRequest = errors.New("sentinel: invalid request")
+	ErrUnauthorized   = errors.New("sentinel: unauthorized")
+	ErrForbidden      = errors.New("sentinel: forbidden")
+	ErrNotFound       = errors.New("sentinel: not found")
+	ErrConflict       = errors.New("sentinel: conflict")
+	// ErrUnavailable covers transport failures, 429 and 5xx responses.
+	ErrUnavailable = errors.New("sentinel: unavailable")
+)
+
+// Error is a failed Sentinel call. StatusCode is zero when the request never
+// got a response, in which case Err holds the transport error.
+type Error struct {
+	Op         string
+	StatusCode int
+	Code       string
+	Message    string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("sentinel %s: %v", e.Op, e.Err)
+	}
+	msg := fmt.Sprintf("sentinel %s: status %d", e.Op, e.StatusCode)
+	if e.Code != "" {
+		msg += " " + e.Code
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is maps the status code to one of the package's sentinel errors.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrInvalidRequest:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrUnavailable:
+		return e.StatusCode == 0 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// newStatusError builds an *Error from a non-2xx response body. It accepts
+// the core-lab response envelope ({"code", "message"}) and the middleware
+// shape ({"error", "message"}), falling back to the raw body.
+func newStatusError(op string, status int, body []byte) *Error {
+	e := &Error{Op: op, StatusCode: status}
+	var payload struct {
+		Code    string          `json:"code"`
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		e.Code, e.Message = payload.Code, payload.Message
+		var code string
+		if e.Code == "" && json.Unmarshal(payload.Error, &code) == nil {
+			e.Code = code
+		}
+	}
+	if e.Code == "" && e.Message == "" {
+		e.Message = strings.TrimSpace(string(body))
+		if len(e.Message) > 512 {
+			e.Message = e.Message[:512]
+		}
+	}
+	return e
+}
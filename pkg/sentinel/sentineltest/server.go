@@ -0,0 +1,514 @@
+// Package sentineltest runs an in-memory Sentinel for tests. It serves the
+// service-token, permission, role, and authorization decision endpoints over
+// mTLS, so permissions.Bootstrap, roles.Sync, and
+// http.NewClientWithServiceToken can be exercised without a live control plane.
+//
+//	srv := sentineltest.NewServer(t, sentineltest.WithRole("role-admin", "Admin"))
+//	err := permissions.Bootstrap(ctx, catalog, srv.Config(), log, store)
+package sentineltest
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/config"
+	"github.com/milan604/core-lab/pkg/controlplane"
+	"github.com/milan604/core-lab/pkg/sentinel"
+)
+
+const (
+	// DefaultServiceID and DefaultAPIKey are the credentials accepted by the
+	// service-token endpoint unless WithCredentials is used.
+	DefaultServiceID = "sentineltest"
+	DefaultAPIKey    = "sentineltest-api-key"
+
+	tokenTTL = time.Hour
+)
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithCredentials sets the service ID and API key the service-token endpoint
+// accepts and Config returns.
+func WithCredentials(serviceID, apiKey string) Option {
+	return func(s *Server) {
+		s.serviceID = serviceID
+		s.apiKey = apiKey
+	}
+}
+
+// WithRole registers a native role.
+func WithRole(id, name string) Option {
+	return func(s *Server) {
+		s.roles[id] = &role{Role: sentinel.Role{ID: id, Name: name, Native: true, Status: "active"}, grants: map[string]bool{}}
+	}
+}
+
+// WithDecider answers authorization decisions with fn. Without it every
+// decision is denied.
+func WithDecider(fn func(sentinel.DecisionRequest) sentinel.DecisionResponse) Option {
+	return func(s *Server) {
+		s.decider = fn
+	}
+}
+
+// Server is an in-memory Sentinel. Permissions get IDs and per-service bit
+// values in creation order; API calls require a token issued by the
+// service-token endpoint. It is safe for concurrent use.
+type Server struct {
+	server *httptest.Server
+	cert   testCertificate
+
+	mu          sync.Mutex
+	serviceID   string
+	apiKey      string
+	tokens      map[string]bool
+	permissions map[string]*permission // lower-cased code -> permission
+	byID        map[string]*permission
+	nextBit     map[string]int64 // service -> next bit value
+	roles       map[string]*role
+	decider     func(sentinel.DecisionRequest) sentinel.DecisionResponse
+	calls       map[string]int
+	failures    map[string][]int
+}
+
+type permission struct {
+	sentinel.CatalogPermission
+	Service string
+}
+
+type role struct {
+	sentinel.Role
+	grants map[string]bool // permission IDs
+}
+
+// NewServer starts a Server that is closed when the test ends.
+func NewServer(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+
+	s := &Server{
+		cert:        newTestCertificate(t),
+		serviceID:   DefaultServiceID,
+		apiKey:      DefaultAPIKey,
+		tokens:      map[string]bool{},
+		permissions: map[string]*permission{},
+		byID:        map[string]*permission{},
+		nextBit:     map[string]int64{},
+		roles:       map[string]*role{},
+		calls:       map[string]int{},
+		failures:    map[string][]int{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /internal/api/v1/service-token", s.handleServiceToken)
+	mux.HandleFunc("POST /internal/api/v1/authz/decide", s.authorized(s.handleDecide))
+	mux.HandleFunc("POST /api/v1/permissions/bulk", s.authorized(s.handleCreatePermissions))
+	mux.HandleFunc("GET /api/v1/permissions/bitmask", s.authorized(s.handleCatalog))
+	mux.HandleFunc("POST /api/v1/permissions/by-codes", s.authorized(s.handlePermissionsByCodes))
+	mux.HandleFunc("POST /api/v1/roles/bulk", s.authorized(s.handleRolesBulk))
+	mux.HandleFunc("GET /api/v1/roles/{id}/permissions", s.authorized(s.handleGetRolePermissions))
+	mux.HandleFunc("PUT /api/v1/roles/{id}/permissions", s.authorized(s.handleAssignPermissions))
+
+	s.server = httptest.NewUnstartedServer(s.record(mux))
+	s.server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{s.cert.tls},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    s.cert.pool,
+		MinVersion:   tls.VersionTLS12,
+	}
+	s.server.StartTLS()
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// URL returns the control-plane base URL.
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Config returns a config pointing at the server with the service
+// credentials and mTLS files set, as read by sentinel.NewFromConfig and
+// http.NewClientWithServiceToken.
+func (s *Server) Config() *config.Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return config.New(config.WithDefaults(map[string]any{
+		controlplane.KeyBaseURL:       s.server.URL,
+		controlplane.KeyServiceID:     s.serviceID,
+		controlplane.KeyServiceAPIKey: s.apiKey,
+		controlplane.KeyMTLSCertFile:  s.cert.certFile,
+		controlplane.KeyMTLSKeyFile:   s.cert.keyFile,
+		controlplane.KeyMTLSCAFile:    s.cert.caFile,
+	}))
+}
+
+// AddRole registers a native role.
+func (s *Server) AddRole(id, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	WithRole(id, name)(s)
+}
+
+// AddPermission registers a permission as if it had been created through the
+// bulk endpoint and returns it.
+func (s *Server) AddPermission(create sentinel.PermissionCreate) sentinel.CreatedPermission {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createPermission(create).created()
+}
+
+// Grant grants the permissions with the given codes to a role. It fails the
+// test if the role or a code is unknown.
+func (s *Server) Grant(t testing.TB, roleID string, codes ...string) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.roles[roleID]
+	if !ok {
+		t.Fatalf("sentineltest: unknown role %q", roleID)
+	}
+	for _, code := range codes {
+		perm, ok := s.permissions[strings.ToLower(code)]
+		if !ok {
+			t.Fatalf("sentineltest: unknown permission %q", code)
+		}
+		r.grants[perm.ID] = true
+	}
+}
+
+// Permissions returns every registered permission, sorted by code.
+func (s *Server) Permissions() []sentinel.CreatedPermission {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]sentinel.CreatedPermission, 0, len(s.permissions))
+	for _, perm := range s.permissions {
+		out = append(out, perm.created())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Code < out[j].Code })
+	return out
+}
+
+// RolePermissions returns the permissions granted to a role, sorted by code.
+func (s *Server) RolePermissions(roleID string) []sentinel.RolePermission {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rolePermissions(s.roles[roleID])
+}
+
+// Calls returns how many requests were made to method and path, e.g.
+// Calls("PUT", "/api/v1/roles/role-1/permissions").
+func (s *Server) Calls(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method+" "+path]
+}
+
+// FailNext answers the next len(statuses) requests to method and path with
+// the given statuses instead of handling them.
+func (s *Server) FailNext(method, path string, statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := method + " " + path
+	s.failures[key] = append(s.failures[key], statuses...)
+}
+
+func (s *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Method + " " + r.URL.Path
+		s.mu.Lock()
+		s.calls[key]++
+		status := 0
+		if pending := s.failures[key]; len(pending) > 0 {
+			status, s.failures[key] = pending[0], pending[1:]
+		}
+		s.mu.Unlock()
+
+		if status != 0 {
+			writeError(w, status, "injected_failure", fmt.Sprintf("injected %d", status))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		s.mu.Lock()
+		valid := ok && s.tokens[token]
+		s.mu.Unlock()
+		if !valid {
+			writeError(w, http.StatusUnauthorized, "unauthorized", "missing or unknown service token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleServiceToken(w http.ResponseWriter, r *http.Request) {
+	var request sentinel.ServiceTokenRequest
+	if !decode(w, r, &request) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if request.ServiceID != s.serviceID || request.APIKey != s.apiKey {
+		writeError(w, http.StatusUnauthorized, "invalid_credentials", "invalid service credentials")
+		return
+	}
+	token := fmt.Sprintf("sentineltest-token-%d", len(s.tokens)+1)
+	s.tokens[token] = true
+
+	scope := request.Scope
+	if scope == "" {
+		scope = "service"
+	}
+	writeJSON(w, http.StatusOK, sentinel.ServiceToken{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(tokenTTL / time.Second),
+		ExpiresAt:   time.Now().Add(tokenTTL).UTC().Format(time.RFC3339),
+		Scope:       scope,
+		Audience:    request.Audience,
+	})
+}
+
+func (s *Server) handleDecide(w http.ResponseWriter, r *http.Request) {
+	var request sentinel.DecisionRequest
+	if !decode(w, r, &request) {
+		return
+	}
+
+	s.mu.Lock()
+	decider := s.decider
+	s.mu.Unlock()
+
+	decision := sentinel.DecisionResponse{
+		SubjectUserID:  request.SubjectUserID,
+		Service:        request.Service,
+		PermissionCode: generateCode(request.Service, request.Category, request.Action),
+		TenantID:       request.TenantID,
+		ResourceType:   request.ResourceType,
+		ResourceID:     request.ResourceID,
+		Reasons:        []string{"no decider configured"},
+	}
+	if decider != nil {
+		decision = decider(request)
+	}
+	writeJSON(w, http.StatusOK, decision)
+}
+
+func (s *Server) handleCreatePermissions(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Permissions []sentinel.PermissionCreate `json:"permissions"`
+	}
+	if !decode(w, r, &request) {
+		return
+	}
+	for i, create := range request.Permissions {
+		if strings.TrimSpace(create.Service) == "" || strings.TrimSpace(create.Category) == "" || strings.TrimSpace(create.Action) == "" {
+			writeError(w, http.StatusBadRequest, "invalid_permission", fmt.Sprintf("permission %d: service, category, and action are required", i))
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	created := make([]sentinel.CreatedPermission, 0, len(request.Permissions))
+	for _, create := range request.Permissions {
+		created = append(created, s.createPermission(create).created())
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"permissions": created})
+}
+
+func (s *Server) handleCatalog(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	catalog := sentinel.BitmaskCatalog{Services: map[string]sentinel.ServiceCatalog{}}
+	for code, perm := range s.permissions {
+		service, ok := catalog.Services[perm.Service]
+		if !ok {
+			service = sentinel.ServiceCatalog{Permissions: map[string]sentinel.CatalogPermission{}}
+			catalog.Services[perm.Service] = service
+		}
+		service.Permissions[code] = perm.CatalogPermission
+	}
+	writeJSON(w, http.StatusOK, catalog)
+}
+
+func (s *Server) handlePermissionsByCodes(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Codes []string `json:"codes"`
+	}
+	if !decode(w, r, &request) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	refs := make([]sentinel.PermissionRef, 0, len(request.Codes))
+	for _, code := range request.Codes {
+		if perm, ok := s.permissions[strings.ToLower(strings.TrimSpace(code))]; ok {
+			refs = append(refs, sentinel.PermissionRef{ID: perm.ID, Code: perm.Code})
+		}
+	}
+	writeJSON(w, http.StatusOK, refs)
+}
+
+func (s *Server) handleRolesBulk(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		RoleIDs []string `json:"role_ids"`
+	}
+	if !decode(w, r, &request) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	roles := make([]sentinel.Role, 0, len(request.RoleIDs))
+	for _, id := range request.RoleIDs {
+		if found, ok := s.roles[id]; ok {
+			roles = append(roles, found.Role)
+		}
+	}
+	writeJSON(w, http.StatusOK, roles)
+}
+
+func (s *Server) handleGetRolePermissions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found, ok := s.roles[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "role_not_found", "role does not exist")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.rolePermissions(found))
+}
+
+// handleAssignPermissions replaces the role's grants in the listed services,
+// or all of its grants when no services are listed.
+func (s *Server) handleAssignPermissions(w http.ResponseWriter, r *http.Request) {
+	var request sentinel.AssignPermissionsRequest
+	if !decode(w, r, &request) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found, ok := s.roles[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "role_not_found", "role does not exist")
+		return
+	}
+	for _, id := range request.PermissionIDs {
+		if _, ok := s.byID[id]; !ok {
+			writeError(w, http.StatusBadRequest, "permission_not_found", fmt.Sprintf("permission %s does not exist", id))
+			return
+		}
+	}
+
+	services := map[string]bool{}
+	for _, service := range request.Services {
+		services[strings.ToLower(strings.TrimSpace(service))] = true
+	}
+	for id := range found.grants {
+		if len(services) == 0 || services[s.byID[id].Service] {
+			delete(found.grants, id)
+		}
+	}
+	for _, id := range request.PermissionIDs {
+		found.grants[id] = true
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createPermission returns the permission for create, registering it first if
+// its code is new. The caller must hold s.mu.
+func (s *Server) createPermission(create sentinel.PermissionCreate) *permission {
+	code := generateCode(create.Service, create.Category, create.Action)
+	if perm, ok := s.permissions[code]; ok {
+		return perm
+	}
+
+	service := normalize(create.Service)
+	bit := s.nextBit[service]
+	if bit == 0 {
+		bit = 1
+	}
+	s.nextBit[service] = bit << 1
+
+	perm := &permission{
+		CatalogPermission: sentinel.CatalogPermission{
+			ID:           fmt.Sprintf("perm-%d", len(s.permissions)+1),
+			Name:         create.Name,
+			Description:  create.Description,
+			Category:     normalize(create.Category),
+			Action:       normalize(create.Action),
+			Code:         code,
+			BitValue:     bit,
+			FeatureFlags: create.FeatureFlags,
+		},
+		Service: service,
+	}
+	s.permissions[code] = perm
+	s.byID[perm.ID] = perm
+	return perm
+}
+
+// rolePermissions lists the grants of r. The caller must hold s.mu.
+func (s *Server) rolePermissions(r *role) []sentinel.RolePermission {
+	if r == nil {
+		return nil
+	}
+	out := make([]sentinel.RolePermission, 0, len(r.grants))
+	for id := range r.grants {
+		perm := s.byID[id]
+		out = append(out, sentinel.RolePermission{ID: perm.ID, Code: perm.Code, Service: perm.Service})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Code < out[j].Code })
+	return out
+}
+
+func (p *permission) created() sentinel.CreatedPermission {
+	return sentinel.CreatedPermission{ID: p.ID, Code: p.Code, Service: p.Service, BitValue: p.BitValue}
+}
+
+// generateCode matches permissions.GenerateCode. It is copied so the
+// permissions package can use sentineltest in its own tests.
+func generateCode(service, category, action string) string {
+	return normalize(service) + "-" + normalize(category) + "-" + normalize(action)
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), " ", ""))
+}
+
+func decode(w http.ResponseWriter, r *http.Request, out any) bool {
+	if err := json.NewDecoder(r.Body).Decode(out); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]any{"success": false, "code": code, "message": message})
+}
@@ -0,0 +1,121 @@
+package sentineltest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	httplib "github.com/milan604/core-lab/pkg/http"
+	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/permissions"
+	"github.com/milan604/core-lab/pkg/roles"
+	"github.com/milan604/core-lab/pkg/sentinel"
+	"github.com/milan604/core-lab/pkg/sentinel/sentineltest"
+)
+
+func newLogger(t *testing.T) logger.LogManager {
+	t.Helper()
+	log, err := logger.NewLogger(logger.LoggerOptions{Level: "error"})
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	return log
+}
+
+func TestBootstrapAndRoleSync(t *testing.T) {
+	srv := sentineltest.NewServer(t, sentineltest.WithRole("role-admin", "Admin"))
+	ctx := context.Background()
+	log := newLogger(t)
+
+	catalog := permissions.NewCatalog([]permissions.Definition{
+		{Name: "ReadUsers", Description: "Read users", Reference: permissions.Reference{Service: "usr", Category: "users", Action: "read"}},
+		{Name: "WriteUsers", Description: "Write users", Reference: permissions.Reference{Service: "usr", Category: "users", Action: "write"}},
+	})
+	store := permissions.NewStore(nil)
+	if err := permissions.Bootstrap(ctx, catalog, srv.Config(), log, store); err != nil {
+		t.Fatalf("Bootstrap() error = %v", err)
+	}
+	read, ok := store.Lookup("usr-users-read")
+	write, _ := store.Lookup("usr-users-write")
+	if !ok || read.BitValue != 1 || write.BitValue != 2 || read.Service != "usr" {
+		t.Fatalf("store = %+v", store.Snapshot())
+	}
+
+	srv.AddPermission(sentinel.PermissionCreate{Service: "usr", Category: "users", Action: "delete"})
+	srv.Grant(t, "role-admin", "usr-users-delete")
+
+	definitions := []roles.Definition{{
+		RoleID:      "role-admin",
+		Name:        "Admin",
+		Permissions: []permissions.Reference{{Service: "usr", Category: "users", Action: "read"}},
+	}}
+	if err := roles.Sync(ctx, definitions, srv.Config(), log); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	granted := srv.RolePermissions("role-admin")
+	if len(granted) != 1 || granted[0].Code != "usr-users-read" {
+		t.Fatalf("role permissions = %+v, want only usr-users-read", granted)
+	}
+
+	// A second sync finds nothing to change and writes nothing.
+	if err := roles.Sync(ctx, definitions, srv.Config(), log); err != nil {
+		t.Fatalf("second Sync() error = %v", err)
+	}
+	if calls := srv.Calls(http.MethodPut, "/api/v1/roles/role-admin/permissions"); calls != 1 {
+		t.Fatalf("assign calls = %d, want 1", calls)
+	}
+}
+
+func TestServiceTokenClient(t *testing.T) {
+	srv := sentineltest.NewServer(t, sentineltest.WithCredentials("sites", "secret"))
+	ctx := context.Background()
+
+	httpClient, err := httplib.NewClientWithServiceToken(nil, srv.Config())
+	if err != nil {
+		t.Fatalf("NewClientWithServiceToken() error = %v", err)
+	}
+	client := sentinel.New(srv.URL(), httpClient, sentinel.WithRetry(1, 0))
+	if _, err := client.GetBitmaskCatalog(ctx); err != nil {
+		t.Fatalf("GetBitmaskCatalog() error = %v", err)
+	}
+	if calls := srv.Calls(http.MethodPost, "/internal/api/v1/service-token"); calls != 1 {
+		t.Fatalf("service-token calls = %d, want 1", calls)
+	}
+
+	// Calls without a service token, and bad credentials, are rejected.
+	unauthenticated := sentinel.New(srv.URL(), httpClientWithoutToken(t, srv), sentinel.WithRetry(1, 0))
+	if _, err := unauthenticated.GetBitmaskCatalog(ctx); !errors.Is(err, sentinel.ErrUnauthorized) {
+		t.Fatalf("error = %v, want ErrUnauthorized", err)
+	}
+	if _, err := unauthenticated.IssueServiceToken(ctx, sentinel.ServiceTokenRequest{ServiceID: "sites", APIKey: "wrong"}); !errors.Is(err, sentinel.ErrUnauthorized) {
+		t.Fatalf("error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestFailNext(t *testing.T) {
+	srv := sentineltest.NewServer(t)
+	client, err := sentinel.NewFromConfig(srv.Config(), nil, sentinel.WithRetry(2, 1))
+	if err != nil {
+		t.Fatalf("NewFromConfig() error = %v", err)
+	}
+
+	srv.FailNext(http.MethodGet, "/api/v1/permissions/bitmask", http.StatusServiceUnavailable)
+	if _, err := client.GetBitmaskCatalog(context.Background()); err != nil {
+		t.Fatalf("GetBitmaskCatalog() error = %v, want retry to succeed", err)
+	}
+	if calls := srv.Calls(http.MethodGet, "/api/v1/permissions/bitmask"); calls != 2 {
+		t.Fatalf("catalog calls = %d, want 2", calls)
+	}
+}
+
+// httpClientWithoutToken returns an mTLS client with no token provider.
+func httpClientWithoutToken(t *testing.T, srv *sentineltest.Server) *httplib.Client {
+	t.Helper()
+	cfg := srv.Config()
+	return httplib.NewClient(httplib.WithRetry(1, 0), httplib.WithMTLS(
+		cfg.GetString("PlatformMTLSCertFile"),
+		cfg.GetString("PlatformMTLSKeyFile"),
+		cfg.GetString("PlatformMTLSCAFile"),
+	))
+}
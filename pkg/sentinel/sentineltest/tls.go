@@ -0,0 +1,80 @@
+package sentineltest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCertificate is a self-signed certificate used as the server certificate,
+// the client certificate, and the CA that trusts both.
+type testCertificate struct {
+	tls      tls.Certificate
+	pool     *x509.CertPool
+	certFile string
+	keyFile  string
+	caFile   string
+}
+
+func newTestCertificate(t testing.TB) testCertificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("sentineltest: generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "sentineltest"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("sentineltest: create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("sentineltest: marshal key: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("sentineltest: load key pair: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(certPEM)
+
+	dir := t.TempDir()
+	cert := testCertificate{
+		tls:      pair,
+		pool:     pool,
+		certFile: filepath.Join(dir, "client.crt"),
+		keyFile:  filepath.Join(dir, "client.key"),
+		caFile:   filepath.Join(dir, "ca.crt"),
+	}
+	for path, data := range map[string][]byte{cert.certFile: certPEM, cert.keyFile: keyPEM, cert.caFile: certPEM} {
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatalf("sentineltest: write %s: %v", path, err)
+		}
+	}
+	return cert
+}
@@ -0,0 +1,147 @@
+package sentinel
+
+import "time"
+
+// Role is a role returned by GetRolesByIDs.
+type Role struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Native      bool   `json:"native"`
+	Status      string `json:"status"`
+}
+
+// PermissionCreate describes a permission for CreatePermissionsBulk.
+type PermissionCreate struct {
+	Name         string   `json:"name" binding:"required"`
+	Service      string   `json:"service" binding:"required"`
+	Category     string   `json:"category" binding:"required"`
+	Action       string   `json:"action" binding:"required"`
+	FeatureFlags []string `json:"feature_flags,omitempty"`
+	Description  string   `json:"description" binding:"required"`
+}
+
+// CreatedPermission is a permission registered by CreatePermissionsBulk.
+type CreatedPermission struct {
+	ID       string `json:"id"`
+	Code     string `json:"code"`
+	Service  string `json:"service"`
+	BitValue int64  `json:"bit_value"`
+}
+
+// BitmaskCatalog is the permission catalog with bit values, keyed by service.
+type BitmaskCatalog struct {
+	Services map[string]ServiceCatalog `json:"services"`
+}
+
+// ServiceCatalog holds one service's permissions and groups, keyed by code.
+type ServiceCatalog struct {
+	Permissions map[string]CatalogPermission `json:"permissions"`
+	Groups      map[string]CatalogGroup      `json:"groups,omitempty"`
+}
+
+// CatalogPermission is a permission in the bitmask catalog.
+type CatalogPermission struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	Category     string   `json:"category"`
+	Action       string   `json:"action"`
+	Code         string   `json:"code"`
+	BitValue     int64    `json:"bit_value"`
+	FeatureFlags []string `json:"feature_flags,omitempty"`
+}
+
+// CatalogGroup is a permission group in the bitmask catalog.
+type CatalogGroup struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Category      string   `json:"category"`
+	Code          string   `json:"code"`
+	CategoryCode  string   `json:"category_code"`
+	Bitmask       int64    `json:"bitmask"`
+	PermissionIDs []string `json:"permission_ids"`
+}
+
+// PermissionRef identifies a permission by ID and code.
+type PermissionRef struct {
+	ID   string `json:"id"`
+	Code string `json:"code"`
+}
+
+// RolePermission is a permission currently granted to a role.
+type RolePermission struct {
+	ID      string `json:"id"`
+	Code    string `json:"code"`
+	Service string `json:"service"`
+}
+
+// AssignPermissionsRequest replaces a role's grants for Services with
+// PermissionIDs. Grants in services not listed are left untouched.
+type AssignPermissionsRequest struct {
+	PermissionIDs []string `json:"permissions"`
+	Services      []string `json:"services,omitempty"`
+}
+
+// ServiceTokenRequest asks for a service-to-service access token.
+type ServiceTokenRequest struct {
+	ServiceID string   `json:"service_id"`
+	APIKey    string   `json:"api_key"`
+	Scope     string   `json:"scope,omitempty"`
+	Audience  []string `json:"audience,omitempty"`
+}
+
+// ServiceToken is an issued service access token.
+type ServiceToken struct {
+	AccessToken string   `json:"access_token"`
+	TokenType   string   `json:"token_type"`
+	ExpiresIn   int      `json:"expires_in"`
+	ExpiresAt   string   `json:"expires_at"` // RFC3339
+	Scope       string   `json:"scope"`
+	Audience    []string `json:"audience"`
+}
+
+// ExpiresAtTime returns when the token expires: ExpiresAt when it parses,
+// otherwise now plus ExpiresIn, otherwise one hour from now.
+func (t ServiceToken) ExpiresAtTime() time.Time {
+	if t.ExpiresAt != "" {
+		if parsed, err := time.Parse(time.RFC3339, t.ExpiresAt); err == nil {
+			return parsed
+		}
+	}
+	if t.ExpiresIn > 0 {
+		return time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+	}
+	return time.Now().Add(time.Hour)
+}
+
+// DecisionRequest asks whether a subject may perform an action.
+type DecisionRequest struct {
+	SubjectUserID string            `json:"subject_user_id"`
+	TenantID      string            `json:"tenant_id,omitempty"`
+	Service       string            `json:"service"`
+	Category      string            `json:"category"`
+	Action        string            `json:"action"`
+	ResourceType  string            `json:"resource_type,omitempty"`
+	ResourceID    string            `json:"resource_id,omitempty"`
+	Context       map[string]string `json:"context,omitempty"`
+}
+
+// DecisionResponse is an authorization decision and the grants behind it.
+type DecisionResponse struct {
+	Allowed        bool     `json:"allowed"`
+	SubjectUserID  string   `json:"subject_user_id"`
+	Service        string   `json:"service"`
+	PermissionCode string   `json:"permission_code"`
+	TenantID       string   `json:"tenant_id,omitempty"`
+	ResourceType   string   `json:"resource_type,omitempty"`
+	ResourceID     string   `json:"resource_id,omitempty"`
+	RoleIDs        []string `json:"role_ids,omitempty"`
+	GroupIDs       []string `json:"group_ids,omitempty"`
+	AllowPolicyIDs []string `json:"allow_policy_ids,omitempty"`
+	DenyPolicyIDs  []string `json:"deny_policy_ids,omitempty"`
+	DelegationIDs  []string `json:"delegation_ids,omitempty"`
+	Reasons        []string `json:"reasons,omitempty"`
+	CacheHit       bool     `json:"cache_hit"`
+}
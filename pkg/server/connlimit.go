@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const meterName = "github.com/milan604/core-lab/pkg/server"
+
+// connMetrics are the connection instruments recorded by connLimitListener.
+type connMetrics struct {
+	active   metric.Int64UpDownCounter
+	accepted metric.Int64Counter
+	rejected metric.Int64Counter
+	culled   metric.Int64Counter
+}
+
+// newConnMetrics creates the connection instruments on the global meter
+// provider. Instruments that fail to register are left nil and skipped.
+func newConnMetrics() *connMetrics {
+	meter := otel.Meter(meterName)
+	m := &connMetrics{}
+	var err error
+	if m.active, err = meter.Int64UpDownCounter("http.server.connection.active",
+		metric.WithDescription("Open connections"), metric.WithUnit("{connection}")); err != nil {
+		otel.Handle(err)
+	}
+	if m.accepted, err = meter.Int64Counter("http.server.connection.accepted",
+		metric.WithDescription("Connections accepted"), metric.WithUnit("{connection}")); err != nil {
+		otel.Handle(err)
+	}
+	if m.rejected, err = meter.Int64Counter("http.server.connection.rejected",
+		metric.WithDescription("Connections closed on accept because the server was at its connection limit"), metric.WithUnit("{connection}")); err != nil {
+		otel.Handle(err)
+	}
+	if m.culled, err = meter.Int64Counter("http.server.connection.culled",
+		metric.WithDescription("Idle keep-alive connections closed to make room for new ones"), metric.WithUnit("{connection}")); err != nil {
+		otel.Handle(err)
+	}
+	return m
+}
+
+func (m *connMetrics) add(counter metric.Int64Counter) {
+	if counter != nil {
+		counter.Add(context.Background(), 1)
+	}
+}
+
+func (m *connMetrics) addActive(delta int64) {
+	if m.active != nil {
+		m.active.Add(context.Background(), delta)
+	}
+}
+
+// connLimitListener caps the number of open connections. When a connection
+// arrives at the cap, the longest-idle keep-alive connection is closed to make
+// room; when none is idle, the new connection is closed straight away, so
+// slow or stalled clients cannot queue up behind the limit.
+type connLimitListener struct {
+	net.Listener
+	max     int
+	metrics *connMetrics
+
+	mu     sync.Mutex
+	active int
+	idle   map[*trackedConn]time.Time
+}
+
+func newConnLimitListener(ln net.Listener, maxConns int) *connLimitListener {
+	return &connLimitListener{
+		Listener: ln,
+		max:      maxConns,
+		metrics:  newConnMetrics(),
+		idle:     map[*trackedConn]time.Time{},
+	}
+}
+
+// Accept returns the next connection admitted under the limit.
+func (l *connLimitListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		l.mu.Lock()
+		var victim *trackedConn
+		if l.max > 0 && l.active >= l.max {
+			victim = l.oldestIdleLocked()
+			if victim == nil {
+				l.mu.Unlock()
+				_ = c.Close()
+				l.metrics.add(l.metrics.rejected)
+				continue
+			}
+			delete(l.idle, victim)
+		}
+		l.active++
+		l.mu.Unlock()
+
+		if victim != nil {
+			_ = victim.Close()
+			l.metrics.add(l.metrics.culled)
+		}
+		l.metrics.add(l.metrics.accepted)
+		l.metrics.addActive(1)
+		return &trackedConn{Conn: c, l: l}, nil
+	}
+}
+
+func (l *connLimitListener) oldestIdleLocked() *trackedConn {
+	var oldest *trackedConn
+	var since time.Time
+	for c, t := range l.idle {
+		if oldest == nil || t.Before(since) {
+			oldest, since = c, t
+		}
+	}
+	return oldest
+}
+
+// connState is installed as http.Server.ConnState to track idle connections.
+func (l *connLimitListener) connState(c net.Conn, state http.ConnState) {
+	if tc, ok := c.(*tls.Conn); ok {
+		c = tc.NetConn()
+	}
+	tracked, ok := c.(*trackedConn)
+	if !ok {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if state == http.StateIdle {
+		l.idle[tracked] = time.Now()
+	} else {
+		delete(l.idle, tracked)
+	}
+}
+
+func (l *connLimitListener) release(c *trackedConn) {
+	l.mu.Lock()
+	l.active--
+	delete(l.idle, c)
+	l.mu.Unlock()
+	l.metrics.addActive(-1)
+}
+
+// trackedConn releases its slot in the listener when closed.
+type trackedConn struct {
+	net.Conn
+	l    *connLimitListener
+	once sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() { c.l.release(c) })
+	return err
+}
@@ -0,0 +1,361 @@
+package server
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CtxAbuseDecision is the gin context key holding the request's AbuseDecision.
+const CtxAbuseDecision = "abuse_decision"
+
+// AbuseAction is what AbuseDetectionMiddleware does with a scored request.
+type AbuseAction string
+
+const (
+	AbuseActionAllow    AbuseAction = "allow"
+	AbuseActionTag      AbuseAction = "tag"
+	AbuseActionThrottle AbuseAction = "throttle"
+	AbuseActionBlock    AbuseAction = "block"
+)
+
+// AbuseSignal is one detector's verdict. Score ranges from 0 (benign) to 1
+// (certainly abusive); a zero score means the detector did not fire.
+type AbuseSignal struct {
+	Detector string  `json:"detector"`
+	Score    float64 `json:"score"`
+	Reason   string  `json:"reason,omitempty"`
+}
+
+// AbuseDecision is the combined outcome for a request, available to handlers
+// via AbuseDecisionFromContext.
+type AbuseDecision struct {
+	Action  AbuseAction   `json:"action"`
+	Score   float64       `json:"score"`
+	Signals []AbuseSignal `json:"signals,omitempty"`
+}
+
+// AbuseDetector scores a request. Implementations must be safe for concurrent use.
+type AbuseDetector interface {
+	Name() string
+	Detect(c *gin.Context) AbuseSignal
+}
+
+type abuseDetectorFunc struct {
+	name   string
+	detect func(*gin.Context) AbuseSignal
+}
+
+func (d abuseDetectorFunc) Name() string                      { return d.name }
+func (d abuseDetectorFunc) Detect(c *gin.Context) AbuseSignal { return d.detect(c) }
+
+// AbuseDetectorFunc adapts a function into a named AbuseDetector.
+func AbuseDetectorFunc(name string, detect func(*gin.Context) AbuseSignal) AbuseDetector {
+	return abuseDetectorFunc{name: name, detect: detect}
+}
+
+// AbuseDetectionConfig configures AbuseDetectionMiddleware. Signal scores are
+// summed (capped at 1) and compared against the thresholds; a zero threshold
+// disables that action.
+type AbuseDetectionConfig struct {
+	Enabled   bool
+	Detectors []AbuseDetector
+	// TagThreshold records the decision without affecting the request. Default: 0.3.
+	TagThreshold float64
+	// ThrottleThreshold subjects the client to the Throttle policy. Default: 0.6.
+	ThrottleThreshold float64
+	// BlockThreshold rejects the request with 403. Default: 0.9.
+	BlockThreshold float64
+	// Throttle is the per-IP budget for throttled clients. Default: 1 RPS, burst 5.
+	Throttle RateLimitPolicy
+	// Registerer receives abuse decision and signal counters when set.
+	Registerer prometheus.Registerer
+	// OnDecision is called for every non-allow decision, e.g. to emit audit events.
+	OnDecision func(*gin.Context, AbuseDecision)
+	Logger     logger.LogManager
+}
+
+// DefaultAbuseDetectionConfig returns an enabled config with default thresholds.
+func DefaultAbuseDetectionConfig(detectors ...AbuseDetector) AbuseDetectionConfig {
+	return AbuseDetectionConfig{
+		Enabled:           true,
+		Detectors:         detectors,
+		TagThreshold:      0.3,
+		ThrottleThreshold: 0.6,
+		BlockThreshold:    0.9,
+		Throttle:          RateLimitPolicy{RPS: 1, Burst: 5},
+	}
+}
+
+// AbuseDetectionMiddleware scores each request with the configured detectors and
+// tags, throttles, or blocks it. The decision is stored in the gin and request
+// contexts for later handlers.
+//
+//	router.Use(middleware.AbuseDetectionMiddleware(middleware.DefaultAbuseDetectionConfig(
+//		middleware.UserAgentDetector(),
+//		middleware.IPReputationDetector(reputation.Score),
+//		middleware.RequestRateDetector(middleware.RateLimitPolicy{RPS: 20, Burst: 40}),
+//	)))
+func AbuseDetectionMiddleware(cfg AbuseDetectionConfig) gin.HandlerFunc {
+	if !cfg.Enabled || len(cfg.Detectors) == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	if cfg.Throttle.RPS <= 0 {
+		cfg.Throttle = DefaultAbuseDetectionConfig().Throttle
+	}
+	throttle := newPolicyLimiter(cfg.Throttle, 0)
+	metrics := newAbuseMetrics(cfg.Registerer)
+
+	return func(c *gin.Context) {
+		decision := AbuseDecision{Action: AbuseActionAllow}
+		for _, detector := range cfg.Detectors {
+			signal := detector.Detect(c)
+			if signal.Score <= 0 {
+				continue
+			}
+			if signal.Detector == "" {
+				signal.Detector = detector.Name()
+			}
+			decision.Signals = append(decision.Signals, signal)
+			decision.Score += signal.Score
+			metrics.signal(signal.Detector)
+		}
+		decision.Score = math.Min(decision.Score, 1)
+		decision.Action = cfg.actionFor(decision.Score)
+
+		c.Set(CtxAbuseDecision, decision)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), abuseDecisionContextKey{}, decision))
+		metrics.decision(decision.Action)
+
+		if decision.Action != AbuseActionAllow {
+			if cfg.Logger != nil {
+				cfg.Logger.InfoFCtx(c.Request.Context(), "Abuse detection: action=%s score=%.2f signals=%v path=%s", decision.Action, decision.Score, decision.Signals, c.Request.URL.Path)
+			}
+			if cfg.OnDecision != nil {
+				cfg.OnDecision(c, decision)
+			}
+		}
+
+		switch decision.Action {
+		case AbuseActionBlock:
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "request_blocked",
+				"message": "request was blocked by abuse protection",
+			})
+			return
+		case AbuseActionThrottle:
+			if !throttle.getLimiter(getRemoteIP(c)).Allow() {
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+					"error":   "rate limit exceeded",
+					"message": "too many requests, please try again later",
+				})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+func (cfg AbuseDetectionConfig) actionFor(score float64) AbuseAction {
+	switch {
+	case cfg.BlockThreshold > 0 && score >= cfg.BlockThreshold:
+		return AbuseActionBlock
+	case cfg.ThrottleThreshold > 0 && score >= cfg.ThrottleThreshold:
+		return AbuseActionThrottle
+	case cfg.TagThreshold > 0 && score >= cfg.TagThreshold:
+		return AbuseActionTag
+	default:
+		return AbuseActionAllow
+	}
+}
+
+type abuseDecisionContextKey struct{}
+
+// AbuseDecisionFromContext returns the decision recorded by AbuseDetectionMiddleware.
+func AbuseDecisionFromContext(c *gin.Context) (AbuseDecision, bool) {
+	if c == nil {
+		return AbuseDecision{}, false
+	}
+	if value, ok := c.Get(CtxAbuseDecision); ok {
+		decision, ok := value.(AbuseDecision)
+		return decision, ok
+	}
+	if c.Request == nil {
+		return AbuseDecision{}, false
+	}
+	decision, ok := c.Request.Context().Value(abuseDecisionContextKey{}).(AbuseDecision)
+	return decision, ok
+}
+
+// DefaultBotUserAgents are case-insensitive user-agent fragments of crawlers,
+// headless browsers, and scanners. Generic HTTP libraries such as curl or
+// Go's net/http are left out: legitimate API clients and service-to-service
+// calls use them. Pass them to UserAgentDetector for browser-only routes.
+var DefaultBotUserAgents = []string{
+	"scrapy", "headlesschrome", "phantomjs", "sqlmap", "nikto", "nmap", "masscan", "zgrab",
+}
+
+// UserAgentDetector flags requests with a missing user agent (score 0.5) or
+// one containing a listed fragment (score 0.6). It defaults to DefaultBotUserAgents.
+func UserAgentDetector(fragments ...string) AbuseDetector {
+	if len(fragments) == 0 {
+		fragments = DefaultBotUserAgents
+	}
+	lowered := make([]string, 0, len(fragments))
+	for _, fragment := range fragments {
+		if fragment = strings.ToLower(strings.TrimSpace(fragment)); fragment != "" {
+			lowered = append(lowered, fragment)
+		}
+	}
+
+	return AbuseDetectorFunc("user_agent", func(c *gin.Context) AbuseSignal {
+		userAgent := strings.ToLower(strings.TrimSpace(c.Request.UserAgent()))
+		if userAgent == "" {
+			return AbuseSignal{Score: 0.5, Reason: "missing user agent"}
+		}
+		for _, fragment := range lowered {
+			if strings.Contains(userAgent, fragment) {
+				return AbuseSignal{Score: 0.6, Reason: "automated user agent: " + fragment}
+			}
+		}
+		return AbuseSignal{}
+	})
+}
+
+// IPReputationFunc scores a client IP, e.g. from a threat-intelligence feed.
+type IPReputationFunc func(ctx context.Context, ip string) (score float64, reason string, err error)
+
+// IPReputationDetector scores requests with lookup. Lookup errors fail open.
+func IPReputationDetector(lookup IPReputationFunc) AbuseDetector {
+	return AbuseDetectorFunc("ip_reputation", func(c *gin.Context) AbuseSignal {
+		if lookup == nil {
+			return AbuseSignal{}
+		}
+		score, reason, err := lookup(c.Request.Context(), getRemoteIP(c))
+		if err != nil {
+			return AbuseSignal{}
+		}
+		return AbuseSignal{Score: score, Reason: reason}
+	})
+}
+
+// RequestRateDetector keeps a token bucket per client IP and scores requests
+// once the bucket is empty, rising from 0 to 1 as the client's deficit grows to
+// another full burst. Unlike RateLimitMiddleware it never rejects on its own.
+func RequestRateDetector(policy RateLimitPolicy) AbuseDetector {
+	if policy.RPS <= 0 {
+		policy.RPS = 10
+	}
+	if policy.Burst <= 0 {
+		policy.Burst = int(math.Max(1, policy.RPS))
+	}
+	buckets := &anomalyBuckets{policy: policy, clients: make(map[string]*anomalyBucket)}
+
+	return AbuseDetectorFunc("request_rate", func(c *gin.Context) AbuseSignal {
+		deficit := buckets.take(getRemoteIP(c), time.Now())
+		if deficit <= 0 {
+			return AbuseSignal{}
+		}
+		return AbuseSignal{
+			Score:  math.Min(deficit/float64(policy.Burst), 1),
+			Reason: "request rate above expected budget",
+		}
+	})
+}
+
+type anomalyBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+type anomalyBuckets struct {
+	policy    RateLimitPolicy
+	mu        sync.Mutex
+	clients   map[string]*anomalyBucket
+	lastSweep time.Time
+}
+
+// take refills and charges the client's bucket, returning how many tokens it is
+// overdrawn. The deficit is capped at one burst so a client recovers in bounded time.
+func (b *anomalyBuckets) take(ip string, now time.Time) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	burst := float64(b.policy.Burst)
+	bucket, ok := b.clients[ip]
+	if !ok {
+		bucket = &anomalyBucket{tokens: burst, lastSeen: now}
+		b.clients[ip] = bucket
+	}
+	bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.lastSeen).Seconds()*b.policy.RPS)
+	bucket.lastSeen = now
+	bucket.tokens = math.Max(bucket.tokens-1, -burst)
+
+	if now.Sub(b.lastSweep) > time.Minute {
+		b.lastSweep = now
+		for key, entry := range b.clients {
+			if now.Sub(entry.lastSeen) > 10*time.Minute {
+				delete(b.clients, key)
+			}
+		}
+	}
+
+	if bucket.tokens >= 0 {
+		return 0
+	}
+	return -bucket.tokens
+}
+
+type abuseMetrics struct {
+	decisions *prometheus.CounterVec
+	signals   *prometheus.CounterVec
+}
+
+func newAbuseMetrics(registerer prometheus.Registerer) *abuseMetrics {
+	if registerer == nil {
+		return nil
+	}
+	metrics := &abuseMetrics{
+		decisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_abuse_decisions_total",
+			Help: "Requests by abuse detection action",
+		}, []string{"action"}),
+		signals: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_abuse_signals_total",
+			Help: "Abuse detector signals fired",
+		}, []string{"detector"}),
+	}
+	metrics.decisions = registerOrExisting(registerer, metrics.decisions)
+	metrics.signals = registerOrExisting(registerer, metrics.signals)
+	return metrics
+}
+
+func registerOrExisting[C prometheus.Collector](registerer prometheus.Registerer, collector C) C {
+	if err := registerer.Register(collector); err != nil {
+		if existing, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if vec, ok := existing.ExistingCollector.(C); ok {
+				return vec
+			}
+		}
+	}
+	return collector
+}
+
+func (m *abuseMetrics) decision(action AbuseAction) {
+	if m != nil {
+		m.decisions.WithLabelValues(string(action)).Inc()
+	}
+}
+
+func (m *abuseMetrics) signal(detector string) {
+	if m != nil {
+		m.signals.WithLabelValues(detector).Inc()
+	}
+}
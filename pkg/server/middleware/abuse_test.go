@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func doAbuseRequest(router http.Handler, userAgent, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("X-Forwarded-For", ip)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestAbuseDetectionTagsAndBlocks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reputation := func(_ context.Context, ip string) (float64, string, error) {
+		if ip == "203.0.113.9" {
+			return 0.5, "listed on blocklist", nil
+		}
+		return 0, "", nil
+	}
+	registry := prometheus.NewRegistry()
+	cfg := DefaultAbuseDetectionConfig(UserAgentDetector(), IPReputationDetector(reputation))
+	cfg.ThrottleThreshold = 0
+	cfg.Registerer = registry
+
+	var seen AbuseDecision
+	router := gin.New()
+	router.Use(AbuseDetectionMiddleware(cfg))
+	router.GET("/items", func(c *gin.Context) {
+		seen, _ = AbuseDecisionFromContext(c)
+		c.Status(http.StatusOK)
+	})
+
+	if recorder := doAbuseRequest(router, "Mozilla/5.0", "198.51.100.1"); recorder.Code != http.StatusOK || seen.Action != AbuseActionAllow {
+		t.Fatalf("browser request: status = %d action = %s", recorder.Code, seen.Action)
+	}
+
+	for _, userAgent := range []string{"curl/8.4.0", "Go-http-client/1.1", "python-requests/2.31"} {
+		if recorder := doAbuseRequest(router, userAgent, "198.51.100.1"); recorder.Code != http.StatusOK || seen.Action != AbuseActionAllow {
+			t.Fatalf("%s: status = %d action = %s, want API clients allowed", userAgent, recorder.Code, seen.Action)
+		}
+	}
+
+	if recorder := doAbuseRequest(router, "Mozilla/5.0 HeadlessChrome/120.0", "198.51.100.1"); recorder.Code != http.StatusOK {
+		t.Fatalf("tagged request status = %d, want 200", recorder.Code)
+	}
+	if seen.Action != AbuseActionTag || len(seen.Signals) != 1 || seen.Signals[0].Detector != "user_agent" {
+		t.Fatalf("tagged decision = %#v", seen)
+	}
+
+	recorder := doAbuseRequest(router, "sqlmap/1.7", "203.0.113.9")
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("blocked request status = %d, want 403", recorder.Code)
+	}
+
+	if got := testutil.ToFloat64(newAbuseMetrics(registry).decisions.WithLabelValues("block")); got != 1 {
+		t.Fatalf("block decisions = %v, want 1", got)
+	}
+}
+
+func TestAbuseDetectionThrottlesAnomalousRate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := DefaultAbuseDetectionConfig(RequestRateDetector(RateLimitPolicy{RPS: 0.001, Burst: 2}))
+	cfg.Throttle = RateLimitPolicy{RPS: 0.001, Burst: 1}
+	router := gin.New()
+	router.Use(AbuseDetectionMiddleware(cfg))
+	router.GET("/items", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	statuses := make([]int, 0, 5)
+	for i := 0; i < 5; i++ {
+		statuses = append(statuses, doAbuseRequest(router, "Mozilla/5.0", "198.51.100.7").Code)
+	}
+	// Two requests fit the burst; the third is 0.5 over (tag), the fourth 1.0
+	// over (block), and the deficit never recovers at this rate.
+	want := []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusForbidden, http.StatusForbidden}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("statuses = %v, want %v", statuses, want)
+		}
+	}
+
+	if recorder := doAbuseRequest(router, "Mozilla/5.0", "198.51.100.8"); recorder.Code != http.StatusOK {
+		t.Fatalf("other client status = %d, want 200", recorder.Code)
+	}
+}
+
+func TestRequestRateDetectorRecovers(t *testing.T) {
+	buckets := &anomalyBuckets{policy: RateLimitPolicy{RPS: 1, Burst: 1}, clients: map[string]*anomalyBucket{}}
+	now := time.Now()
+	if deficit := buckets.take("ip", now); deficit != 0 {
+		t.Fatalf("first deficit = %v, want 0", deficit)
+	}
+	if deficit := buckets.take("ip", now); deficit != 1 {
+		t.Fatalf("second deficit = %v, want 1", deficit)
+	}
+	if deficit := buckets.take("ip", now.Add(3*time.Second)); deficit != 0 {
+		t.Fatalf("deficit after refill = %v, want 0", deficit)
+	}
+}
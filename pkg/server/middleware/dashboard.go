@@ -0,0 +1,197 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// REDDashboardConfig configures REDDashboard.
+type REDDashboardConfig struct {
+	// Job is the Prometheus job label the service is scraped under. Required.
+	Job string
+	// Title defaults to "<Job> RED".
+	Title string
+	// Routes are the routes to chart, usually engine.Routes().
+	Routes gin.RoutesInfo
+	// RateWindow is the range used in rate(). Default: "$__rate_interval".
+	RateWindow string
+	// Quantiles charted on the duration panels. Default: 0.5, 0.95, 0.99.
+	Quantiles []float64
+}
+
+// REDQueries are the PromQL expressions behind one route's panels, usable
+// directly in SigNoz or any PromQL panel.
+type REDQueries struct {
+	// Rate is requests per second.
+	Rate string
+	// Errors is the share of requests answered with a 5xx status.
+	Errors string
+	// Duration has one latency quantile query per configured quantile.
+	Duration []string
+}
+
+var defaultQuantiles = []float64{0.5, 0.95, 0.99}
+
+// RouteREDQueries returns the RED queries for one route over the metrics
+// PrometheusMiddleware records. An empty method or route matches all.
+func RouteREDQueries(job, method, route, window string, quantiles ...float64) REDQueries {
+	if window == "" {
+		window = "$__rate_interval"
+	}
+	if len(quantiles) == 0 {
+		quantiles = defaultQuantiles
+	}
+
+	matchers := []string{"job=" + strconv.Quote(job)}
+	if method != "" {
+		matchers = append(matchers, "method="+strconv.Quote(method))
+	}
+	if route != "" {
+		matchers = append(matchers, "path="+strconv.Quote(route))
+	}
+	selector := "{" + strings.Join(matchers, ",") + "}"
+
+	requests := fmt.Sprintf("sum(rate(%s%s[%s]))", MetricHTTPRequests, selector, window)
+	q := REDQueries{
+		Rate:   requests,
+		Errors: fmt.Sprintf("(sum(rate(%s%s[%s])) or vector(0)) / %s", MetricHTTPRequestErrors, selector, window, requests),
+	}
+	for _, quantile := range quantiles {
+		q.Duration = append(q.Duration, fmt.Sprintf("histogram_quantile(%s, sum by (le) (rate(%s_bucket%s[%s])))",
+			strconv.FormatFloat(quantile, 'f', -1, 64), MetricHTTPRequestDuration, selector, window))
+	}
+	return q
+}
+
+// REDDashboard renders a Grafana dashboard (JSON model, importable through
+// Dashboards > Import) with a service overview row and one row per route,
+// each with rate, error ratio, and latency quantile panels. Exemplars are
+// enabled on the latency panels so points link to their traces.
+//
+//	data, err := server.REDDashboard(server.REDDashboardConfig{Job: "orders", Routes: engine.Routes()})
+func REDDashboard(cfg REDDashboardConfig) ([]byte, error) {
+	if strings.TrimSpace(cfg.Job) == "" {
+		return nil, errors.New("red dashboard: job is required")
+	}
+	if cfg.Title == "" {
+		cfg.Title = cfg.Job + " RED"
+	}
+
+	routes := append(gin.RoutesInfo(nil), cfg.Routes...)
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	b := &dashboardBuilder{}
+	b.redRow("Service overview", RouteREDQueries(cfg.Job, "", "", cfg.RateWindow, cfg.Quantiles...), cfg.Quantiles)
+	for _, route := range routes {
+		queries := RouteREDQueries(cfg.Job, route.Method, route.Path, cfg.RateWindow, cfg.Quantiles...)
+		b.redRow(route.Method+" "+route.Path, queries, cfg.Quantiles)
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"title":         cfg.Title,
+		"uid":           dashboardUID(cfg.Job),
+		"tags":          []string{"red", cfg.Job},
+		"timezone":      "browser",
+		"schemaVersion": 39,
+		"refresh":       "30s",
+		"time":          map[string]string{"from": "now-6h", "to": "now"},
+		"templating": map[string]any{"list": []map[string]any{{
+			"name":  "datasource",
+			"label": "Data source",
+			"type":  "datasource",
+			"query": "prometheus",
+		}}},
+		"panels": b.panels,
+	}, "", "  ")
+}
+
+// dashboardBuilder lays panels out on Grafana's 24-column grid.
+type dashboardBuilder struct {
+	panels []map[string]any
+	y      int
+}
+
+const panelHeight = 8
+
+func (b *dashboardBuilder) redRow(title string, q REDQueries, quantiles []float64) {
+	b.add(map[string]any{"type": "row", "title": title, "collapsed": false}, 24, 1)
+
+	b.panel("Rate", "reqps", false, 0, target{Expr: q.Rate, Legend: "requests/s"})
+	b.panel("Errors", "percentunit", false, 8, target{Expr: q.Errors, Legend: "5xx ratio"})
+
+	if len(quantiles) == 0 {
+		quantiles = defaultQuantiles
+	}
+	durations := make([]target, len(q.Duration))
+	for i, expr := range q.Duration {
+		durations[i] = target{Expr: expr, Legend: "p" + strconv.FormatFloat(quantiles[i]*100, 'f', -1, 64)}
+	}
+	b.panel("Duration", "s", true, 16, durations...)
+	b.y += panelHeight
+}
+
+type target struct {
+	Expr   string
+	Legend string
+}
+
+func (b *dashboardBuilder) panel(title, unit string, exemplars bool, x int, targets ...target) {
+	rendered := make([]map[string]any, len(targets))
+	for i, t := range targets {
+		rendered[i] = map[string]any{
+			"refId":        string(rune('A' + i)),
+			"expr":         t.Expr,
+			"legendFormat": t.Legend,
+			"exemplar":     exemplars,
+		}
+	}
+	b.panels = append(b.panels, map[string]any{
+		"id":         len(b.panels) + 1,
+		"type":       "timeseries",
+		"title":      title,
+		"datasource": map[string]string{"type": "prometheus", "uid": "${datasource}"},
+		"gridPos":    map[string]int{"h": panelHeight, "w": 8, "x": x, "y": b.y},
+		"fieldConfig": map[string]any{
+			"defaults":  map[string]any{"unit": unit},
+			"overrides": []any{},
+		},
+		"targets": rendered,
+	})
+}
+
+func (b *dashboardBuilder) add(panel map[string]any, width, height int) {
+	panel["id"] = len(b.panels) + 1
+	panel["gridPos"] = map[string]int{"h": height, "w": width, "x": 0, "y": b.y}
+	b.panels = append(b.panels, panel)
+	b.y += height
+}
+
+// dashboardUID derives a stable UID (at most 40 characters) from the job.
+func dashboardUID(job string) string {
+	var uid strings.Builder
+	uid.WriteString("red-")
+	for _, r := range strings.ToLower(job) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			uid.WriteRune(r)
+		default:
+			uid.WriteByte('-')
+		}
+	}
+	out := uid.String()
+	if len(out) > 40 {
+		out = out[:40]
+	}
+	return out
+}
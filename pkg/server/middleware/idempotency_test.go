@@ -0,0 +1,378 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	redis "github.com/redis/go-redis/v9"
+)
+
+func doIdempotentRequest(router http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestIdempotencyMiddlewareReplaysStoredResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	router := gin.New()
+	router.POST("/payments", IdempotencyMiddleware(DefaultIdempotencyConfig(NewMemoryIdempotencyStore())), func(c *gin.Context) {
+		calls++
+		c.Header("X-Payment-ID", "pay-1")
+		c.JSON(http.StatusCreated, gin.H{"id": "pay-1", "call": calls})
+	})
+
+	first := doIdempotentRequest(router, "key-1", `{"amount":10}`)
+	second := doIdempotentRequest(router, "key-1", `{"amount":10}`)
+
+	if calls != 1 {
+		t.Fatalf("handler calls = %d, want 1", calls)
+	}
+	if second.Code != http.StatusCreated {
+		t.Fatalf("replay status = %d, want %d", second.Code, http.StatusCreated)
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Fatalf("replay body = %q, want %q", second.Body.String(), first.Body.String())
+	}
+	if got := second.Header().Get(HeaderIdempotentReplayed); got != "true" {
+		t.Fatalf("%s = %q, want true", HeaderIdempotentReplayed, got)
+	}
+	if got := second.Header().Get("X-Payment-ID"); got != "pay-1" {
+		t.Fatalf("X-Payment-ID = %q, want pay-1", got)
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsKeyReuseWithDifferentBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	router := gin.New()
+	router.POST("/payments", IdempotencyMiddleware(DefaultIdempotencyConfig(NewMemoryIdempotencyStore())), func(c *gin.Context) {
+		calls++
+		c.Header("X-Payment-ID", "pay-1")
+		c.JSON(http.StatusCreated, gin.H{"id": "pay-1", "call": calls})
+	})
+
+	doIdempotentRequest(router, "key-1", `{"amount":10}`)
+	recorder := doIdempotentRequest(router, "key-1", `{"amount":99}`)
+
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusUnprocessableEntity)
+	}
+	if calls != 1 {
+		t.Fatalf("handler calls = %d, want 1", calls)
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsConcurrentInFlightRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := NewMemoryIdempotencyStore()
+	if ok, _ := store.Lock(context.Background(), "key-1", "other-request", time.Minute); !ok {
+		t.Fatal("expected to acquire lock")
+	}
+
+	calls := 0
+	router := gin.New()
+	router.POST("/payments", IdempotencyMiddleware(DefaultIdempotencyConfig(store)), func(c *gin.Context) {
+		calls++
+		c.Header("X-Payment-ID", "pay-1")
+		c.JSON(http.StatusCreated, gin.H{"id": "pay-1", "call": calls})
+	})
+	recorder := doIdempotentRequest(router, "key-1", `{}`)
+
+	if recorder.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusConflict)
+	}
+	if calls != 0 {
+		t.Fatalf("handler calls = %d, want 0", calls)
+	}
+}
+
+func TestIdempotencyMiddlewarePassesThroughWithoutKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	router := gin.New()
+	router.POST("/payments", IdempotencyMiddleware(DefaultIdempotencyConfig(NewMemoryIdempotencyStore())), func(c *gin.Context) {
+		calls++
+		c.Header("X-Payment-ID", "pay-1")
+		c.JSON(http.StatusCreated, gin.H{"id": "pay-1", "call": calls})
+	})
+
+	doIdempotentRequest(router, "", `{}`)
+	doIdempotentRequest(router, "", `{}`)
+
+	if calls != 2 {
+		t.Fatalf("handler calls = %d, want 2", calls)
+	}
+}
+
+func TestRedisIdempotencyStoreReplaysAcrossRouters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mini, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mini.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: mini.Addr()})
+	defer client.Close()
+
+	store, err := NewRedisIdempotencyStore(client, "test")
+	if err != nil {
+		t.Fatalf("NewRedisIdempotencyStore() error = %v", err)
+	}
+
+	callsA, callsB := 0, 0
+	routerA := gin.New()
+	routerA.POST("/payments", IdempotencyMiddleware(DefaultIdempotencyConfig(store)), func(c *gin.Context) {
+		callsA++
+		c.Header("X-Payment-ID", "pay-1")
+		c.JSON(http.StatusCreated, gin.H{"id": "pay-1", "call": callsA})
+	})
+	routerB := gin.New()
+	routerB.POST("/payments", IdempotencyMiddleware(DefaultIdempotencyConfig(store)), func(c *gin.Context) {
+		callsB++
+		c.Header("X-Payment-ID", "pay-1")
+		c.JSON(http.StatusCreated, gin.H{"id": "pay-1", "call": callsB})
+	})
+
+	first := doIdempotentRequest(routerA, "key-1", `{"amount":10}`)
+	second := doIdempotentRequest(routerB, "key-1", `{"amount":10}`)
+
+	if callsA != 1 || callsB != 0 {
+		t.Fatalf("handler calls = (%d, %d), want (1, 0)", callsA, callsB)
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Fatalf("replay body = %q, want %q", second.Body.String(), first.Body.String())
+	}
+}
+
+// finishBeforeLockStore completes the original request between the
+// middleware's Get and Lock: the first Get misses, then the response is
+// saved and the original's lock released.
+type finishBeforeLockStore struct {
+	*MemoryIdempotencyStore
+	gets  int
+	saved IdempotentResponse
+}
+
+func (s *finishBeforeLockStore) Get(ctx context.Context, key string) (*IdempotentResponse, error) {
+	s.gets++
+	if s.gets == 1 {
+		if err := s.MemoryIdempotencyStore.Save(ctx, key, s.saved, time.Hour); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return s.MemoryIdempotencyStore.Get(ctx, key)
+}
+
+func TestIdempotencyMiddlewareRechecksStoreAfterLocking(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Record the response the original request stores.
+	recording := NewMemoryIdempotencyStore()
+	calls := 0
+	router := gin.New()
+	router.POST("/payments", IdempotencyMiddleware(DefaultIdempotencyConfig(recording)), func(c *gin.Context) {
+		calls++
+		c.Header("X-Payment-ID", "pay-1")
+		c.JSON(http.StatusCreated, gin.H{"id": "pay-1", "call": calls})
+	})
+	doIdempotentRequest(router, "key-1", `{"amount":10}`)
+	saved, err := recording.Get(context.Background(), "key-1")
+	if err != nil || saved == nil {
+		t.Fatalf("recorded response = %v, %v", saved, err)
+	}
+
+	calls = 0
+	store := &finishBeforeLockStore{MemoryIdempotencyStore: NewMemoryIdempotencyStore(), saved: *saved}
+	router = gin.New()
+	router.POST("/payments", IdempotencyMiddleware(DefaultIdempotencyConfig(store)), func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusCreated, gin.H{"id": "pay-1", "call": calls})
+	})
+	recorder := doIdempotentRequest(router, "key-1", `{"amount":10}`)
+
+	if calls != 0 {
+		t.Fatalf("handler calls = %d, want 0: the retry ran after the original finished", calls)
+	}
+	if recorder.Header().Get(HeaderIdempotentReplayed) != "true" || recorder.Body.String() != string(saved.Body) {
+		t.Fatalf("retry = %d %q, want the replayed response", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestIdempotencyMiddlewareHoldsLockForSlowHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := NewMemoryIdempotencyStore()
+	cfg := DefaultIdempotencyConfig(store)
+	cfg.LockTimeout = 60 * time.Millisecond
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := gin.New()
+	router.POST("/payments", IdempotencyMiddleware(cfg), func(c *gin.Context) {
+		close(started)
+		select {
+		case <-release:
+		case <-c.Request.Context().Done():
+			c.Status(http.StatusServiceUnavailable)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- doIdempotentRequest(router, "key-1", `{}`) }()
+	<-started
+	time.Sleep(4 * cfg.LockTimeout)
+
+	if retry := doIdempotentRequest(router, "key-1", `{}`); retry.Code != http.StatusConflict {
+		t.Fatalf("retry during slow handler = %d, want %d", retry.Code, http.StatusConflict)
+	}
+	close(release)
+	if original := <-done; original.Code != http.StatusCreated {
+		t.Fatalf("original = %d, want %d", original.Code, http.StatusCreated)
+	}
+}
+
+// nonExtendingStore hides MemoryIdempotencyStore.ExtendLock.
+type nonExtendingStore struct{ IdempotencyStore }
+
+func TestIdempotencyMiddlewareCancelsHandlerWhenLockLapses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := DefaultIdempotencyConfig(nonExtendingStore{NewMemoryIdempotencyStore()})
+	cfg.LockTimeout = 30 * time.Millisecond
+
+	var cause error
+	router := gin.New()
+	router.POST("/payments", IdempotencyMiddleware(cfg), func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+			cause = context.Cause(c.Request.Context())
+			c.Status(http.StatusServiceUnavailable)
+		case <-time.After(5 * time.Second):
+			c.Status(http.StatusCreated)
+		}
+	})
+
+	if recorder := doIdempotentRequest(router, "key-1", `{}`); recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want the handler to be cancelled", recorder.Code)
+	}
+	if !errors.Is(cause, errIdempotencyLockLost) {
+		t.Fatalf("cancel cause = %v, want errIdempotencyLockLost", cause)
+	}
+}
+
+func TestIdempotencyStoresOnlyReleaseOwnLock(t *testing.T) {
+	mini, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mini.Close()
+	client := redis.NewClient(&redis.Options{Addr: mini.Addr()})
+	defer client.Close()
+	redisStore, err := NewRedisIdempotencyStore(client, "test")
+	if err != nil {
+		t.Fatalf("NewRedisIdempotencyStore() error = %v", err)
+	}
+
+	stores := map[string]interface {
+		IdempotencyStore
+		IdempotencyLockExtender
+	}{
+		"memory": NewMemoryIdempotencyStore(),
+		"redis":  redisStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if ok, err := store.Lock(ctx, "key-1", "retry", time.Minute); !ok || err != nil {
+				t.Fatalf("Lock(retry) = %t, %v, want the lock", ok, err)
+			}
+
+			// The original request's lock lapsed; its extend and unlock must
+			// leave the retry's lock alone.
+			if ok, err := store.ExtendLock(ctx, "key-1", "original", time.Minute); ok || err != nil {
+				t.Fatalf("ExtendLock(original) = %t, %v, want false", ok, err)
+			}
+			if err := store.Unlock(ctx, "key-1", "original"); err != nil {
+				t.Fatalf("Unlock(original) error = %v", err)
+			}
+			if ok, _ := store.Lock(ctx, "key-1", "third", time.Minute); ok {
+				t.Fatal("Lock(third) succeeded after a stale unlock, want the retry to keep the key")
+			}
+
+			if ok, err := store.ExtendLock(ctx, "key-1", "retry", time.Minute); !ok || err != nil {
+				t.Fatalf("ExtendLock(retry) = %t, %v, want true", ok, err)
+			}
+			if err := store.Unlock(ctx, "key-1", "retry"); err != nil {
+				t.Fatalf("Unlock(retry) error = %v", err)
+			}
+			if ok, _ := store.Lock(ctx, "key-1", "third", time.Minute); !ok {
+				t.Fatal("Lock(third) failed after the owner released the key")
+			}
+		})
+	}
+}
+
+func TestIdempotencyMiddlewareKeepsRetryLockWhenOriginalLapses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := DefaultIdempotencyConfig(nonExtendingStore{NewMemoryIdempotencyStore()})
+	cfg.LockTimeout = 200 * time.Millisecond
+
+	// Handlers ignore their context, so the original keeps running after its
+	// lock lapses and the retry takes the key.
+	started := make(chan string, 3)
+	release := map[string]chan struct{}{"original": make(chan struct{}), "retry": make(chan struct{})}
+	router := gin.New()
+	router.POST("/payments", IdempotencyMiddleware(cfg), func(c *gin.Context) {
+		name := c.GetHeader("X-Attempt")
+		started <- name
+		if ch, ok := release[name]; ok {
+			<-ch
+		}
+		c.JSON(http.StatusCreated, gin.H{"attempt": name})
+	})
+	send := func(attempt string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(`{}`))
+		req.Header.Set(HeaderIdempotencyKey, "key-1")
+		req.Header.Set("X-Attempt", attempt)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	original := make(chan *httptest.ResponseRecorder)
+	go func() { original <- send("original") }()
+	<-started
+	time.Sleep(cfg.LockTimeout + 50*time.Millisecond)
+
+	retry := make(chan *httptest.ResponseRecorder)
+	go func() { retry <- send("retry") }()
+	if name := <-started; name != "retry" {
+		t.Fatalf("started %q, want the retry to take the lapsed lock", name)
+	}
+
+	close(release["original"])
+	<-original
+	if third := send("third"); third.Code != http.StatusConflict {
+		t.Fatalf("third request = %d, want %d while the retry holds the key", third.Code, http.StatusConflict)
+	}
+
+	close(release["retry"])
+	<-retry
+	if replay := send("third"); !strings.Contains(replay.Body.String(), `"retry"`) {
+		t.Fatalf("replayed body = %q, want the retry's response rather than the lapsed original's", replay.Body.String())
+	}
+}
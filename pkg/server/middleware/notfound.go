@@ -0,0 +1,83 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/milan604/core-lab/pkg/apperr"
+	"github.com/milan604/core-lab/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricHTTPUnmatchedRequests counts requests answered by NoRouteHandler and
+// NoMethodHandler.
+const MetricHTTPUnmatchedRequests = "http_unmatched_requests_total"
+
+// NoRouteHandler answers requests that match no route with a not_found
+// APIResponse instead of Gin's plain-text "404 page not found". Register it
+// with engine.NoRoute. When registerer is set, the requests are counted on
+// MetricHTTPUnmatchedRequests with reason "not_found".
+func NoRouteHandler(registerer prometheus.Registerer) gin.HandlerFunc {
+	metrics := newUnmatchedMetrics(registerer)
+	return func(c *gin.Context) {
+		metrics.observe(c.Request.Method, "not_found")
+		response.JSONError(c, apperr.New(apperr.ErrorCodeNotFound).
+			WithMessage("no route matches "+c.Request.Method+" "+c.Request.URL.Path))
+		c.Abort()
+	}
+}
+
+// NoMethodHandler answers requests whose path is routed only for other
+// methods with a method_not_allowed APIResponse listing those methods in
+// meta.allowed_methods, next to the Allow header Gin sets. Register it with
+// engine.NoMethod and set engine.HandleMethodNotAllowed. Requests are counted
+// like NoRouteHandler's, with reason "method_not_allowed".
+func NoMethodHandler(registerer prometheus.Registerer) gin.HandlerFunc {
+	metrics := newUnmatchedMetrics(registerer)
+	return func(c *gin.Context) {
+		metrics.observe(c.Request.Method, "method_not_allowed")
+		appErr := apperr.New(apperr.ErrorCodeMethodNotAllowed).
+			WithMessage("method " + c.Request.Method + " is not allowed on " + c.Request.URL.Path)
+		var allowed []string
+		for _, method := range strings.Split(c.Writer.Header().Get("Allow"), ",") {
+			if method = strings.TrimSpace(method); method != "" {
+				allowed = append(allowed, method)
+			}
+		}
+		response.JSONErrorWithMeta(c, appErr, map[string]interface{}{"allowed_methods": allowed})
+		c.Abort()
+	}
+}
+
+type unmatchedMetrics struct {
+	requests *prometheus.CounterVec
+}
+
+func newUnmatchedMetrics(registerer prometheus.Registerer) *unmatchedMetrics {
+	if registerer == nil {
+		return nil
+	}
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: MetricHTTPUnmatchedRequests,
+		Help: "Requests that matched no route (not_found) or no method of a route (method_not_allowed)",
+	}, []string{"method", "reason"})
+	return &unmatchedMetrics{requests: registerOrExisting(registerer, requests)}
+}
+
+func (m *unmatchedMetrics) observe(method, reason string) {
+	if m != nil {
+		m.requests.WithLabelValues(sanitizeMethodLabel(method), reason).Inc()
+	}
+}
+
+// sanitizeMethodLabel folds non-standard methods into "other" so arbitrary
+// request methods cannot grow the label set.
+func sanitizeMethodLabel(method string) string {
+	switch method {
+	case "GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "CONNECT", "TRACE":
+		return method
+	default:
+		return "other"
+	}
+}
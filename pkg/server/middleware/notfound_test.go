@@ -0,0 +1,60 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/milan604/core-lab/pkg/response"
+)
+
+func TestUnmatchedRequestsUseTheErrorEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := prometheus.NewRegistry()
+	engine := gin.New()
+	engine.HandleMethodNotAllowed = true
+	engine.NoRoute(NoRouteHandler(registry))
+	engine.NoMethod(NoMethodHandler(registry))
+	engine.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+	engine.POST("/orders", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	serve := func(method, path string) (*httptest.ResponseRecorder, response.APIResponse) {
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		var body response.APIResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s %s: body %q is not an APIResponse: %v", method, path, rec.Body.String(), err)
+		}
+		return rec, body
+	}
+
+	rec, body := serve(http.MethodGet, "/missing")
+	if rec.Code != http.StatusNotFound || body.Success || body.Code != "not_found" {
+		t.Fatalf("404 = %d %+v", rec.Code, body)
+	}
+
+	rec, body = serve(http.MethodDelete, "/orders")
+	if rec.Code != http.StatusMethodNotAllowed || body.Code != "method_not_allowed" {
+		t.Fatalf("405 = %d %+v", rec.Code, body)
+	}
+	if rec.Header().Get("Allow") != "GET, POST" {
+		t.Fatalf("Allow = %q", rec.Header().Get("Allow"))
+	}
+	allowed, _ := body.Meta["allowed_methods"].([]any)
+	if len(allowed) != 2 || allowed[0] != "GET" || allowed[1] != "POST" {
+		t.Fatalf("allowed_methods = %v", body.Meta["allowed_methods"])
+	}
+
+	serve("BREW", "/missing")
+	if got := testutil.CollectAndCount(registry, MetricHTTPUnmatchedRequests); got != 3 {
+		t.Fatalf("unmatched series = %d, want 3", got)
+	}
+	if got := testutil.ToFloat64(newUnmatchedMetrics(registry).requests.WithLabelValues("other", "not_found")); got != 1 {
+		t.Fatalf("other/not_found = %v", got)
+	}
+}
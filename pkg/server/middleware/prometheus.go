@@ -1,17 +1,33 @@
 package server
 
 import (
+	"context"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Metric names recorded by PrometheusMiddleware. Together they give the RED
+// signals per route: rate (requests), errors, and duration.
+const (
+	MetricHTTPRequests        = "http_requests_total"
+	MetricHTTPRequestErrors   = "http_request_errors_total"
+	MetricHTTPRequestDuration = "http_request_duration_seconds"
+)
+
+// unmatchedRoute is the path label for requests that match no route, so
+// probes and scans of random URLs cannot grow the label set.
+const unmatchedRoute = "unmatched"
+
 // PrometheusCollector holds the metrics and the handler path.
 type PrometheusCollector struct {
 	reqCount    *prometheus.CounterVec
+	reqErrors   *prometheus.CounterVec
 	reqDurHist  *prometheus.HistogramVec
 	inFlight    prometheus.Gauge
 	registry    *prometheus.Registry
@@ -24,14 +40,21 @@ func NewPrometheusCollector(metricsPath string) *PrometheusCollector {
 
 	reqCount := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "http_requests_total",
+			Name: MetricHTTPRequests,
 			Help: "Total number of HTTP requests",
 		},
 		[]string{"method", "path", "status"},
 	)
+	reqErrors := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: MetricHTTPRequestErrors,
+			Help: "Total number of HTTP requests answered with a 5xx status",
+		},
+		[]string{"method", "path"},
+	)
 	reqDurHist := prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name:    "http_request_duration_seconds",
+			Name:    MetricHTTPRequestDuration,
 			Help:    "Histogram of request durations",
 			Buckets: prometheus.DefBuckets,
 		},
@@ -42,10 +65,11 @@ func NewPrometheusCollector(metricsPath string) *PrometheusCollector {
 		Help: "Current number of in-flight requests",
 	})
 
-	reg.MustRegister(reqCount, reqDurHist, inFlight)
+	reg.MustRegister(reqCount, reqErrors, reqDurHist, inFlight)
 
 	return &PrometheusCollector{
 		reqCount:    reqCount,
+		reqErrors:   reqErrors,
 		reqDurHist:  reqDurHist,
 		inFlight:    inFlight,
 		registry:    reg,
@@ -53,7 +77,11 @@ func NewPrometheusCollector(metricsPath string) *PrometheusCollector {
 	}
 }
 
-// PrometheusMiddleware returns a gin middleware that collects metrics.
+// PrometheusMiddleware returns a gin middleware that collects metrics, labelled
+// by the route template ("unmatched" for requests no route handles). When the
+// request carries a sampled trace, the duration and error observations link to
+// it through a trace_id/span_id exemplar. Register it after the tracing
+// middleware so the span is in the request context.
 func (pc *PrometheusCollector) PrometheusMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
@@ -61,16 +89,46 @@ func (pc *PrometheusCollector) PrometheusMiddleware() gin.HandlerFunc {
 		c.Next()
 		pc.inFlight.Dec()
 
-		status := strconv.Itoa(c.Writer.Status())
+		code := c.Writer.Status()
+		status := strconv.Itoa(code)
 		method := c.Request.Method
 		path := c.FullPath()
 		if path == "" {
-			path = c.Request.URL.Path
+			path = unmatchedRoute
 		}
+		exemplar := traceExemplar(c.Request.Context())
 
 		pc.reqCount.WithLabelValues(method, path, status).Inc()
-		pc.reqDurHist.WithLabelValues(method, path).Observe(time.Since(start).Seconds())
+		if code >= 500 {
+			addWithExemplar(pc.reqErrors.WithLabelValues(method, path), exemplar)
+		}
+		observeWithExemplar(pc.reqDurHist.WithLabelValues(method, path), time.Since(start).Seconds(), exemplar)
+	}
+}
+
+// traceExemplar returns exemplar labels for the sampled span in ctx, or nil.
+func traceExemplar(ctx context.Context) prometheus.Labels {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsSampled() {
+		return nil
 	}
+	return prometheus.Labels{"trace_id": spanCtx.TraceID().String(), "span_id": spanCtx.SpanID().String()}
+}
+
+func addWithExemplar(counter prometheus.Counter, exemplar prometheus.Labels) {
+	if adder, ok := counter.(prometheus.ExemplarAdder); ok && exemplar != nil {
+		adder.AddWithExemplar(1, exemplar)
+		return
+	}
+	counter.Inc()
+}
+
+func observeWithExemplar(observer prometheus.Observer, value float64, exemplar prometheus.Labels) {
+	if eo, ok := observer.(prometheus.ExemplarObserver); ok && exemplar != nil {
+		eo.ObserveWithExemplar(value, exemplar)
+		return
+	}
+	observer.Observe(value)
 }
 
 // RegisterMetricsEndpoint registers /metrics (or custom path) on Gin engine.
@@ -78,6 +136,31 @@ func (pc *PrometheusCollector) RegisterMetricsEndpoint(engine *gin.Engine) {
 	if pc.MetricsPath == "" {
 		pc.MetricsPath = "/metrics"
 	}
-	// Use promhttp handler
-	engine.GET(pc.MetricsPath, gin.WrapH(promhttp.HandlerFor(pc.registry, promhttp.HandlerOpts{})))
+	// OpenMetrics is negotiated by scrapers that ask for it and is the only
+	// format that carries exemplars.
+	engine.GET(pc.MetricsPath, gin.WrapH(promhttp.HandlerFor(pc.registry, promhttp.HandlerOpts{EnableOpenMetrics: true})))
+}
+
+// Registerer returns the collector's registry so other middleware (for example
+// AbuseDetectionConfig.Registerer) can expose metrics on the same endpoint.
+func (pc *PrometheusCollector) Registerer() prometheus.Registerer {
+	return pc.registry
+}
+
+// RegisterRuntimeCollectors adds the Go runtime collector (goroutines, GC
+// pauses, heap and memstats) and the process collector (CPU seconds, resident
+// memory, open file descriptors) to the collector's registry. Calling it
+// again is a no-op.
+func (pc *PrometheusCollector) RegisterRuntimeCollectors() error {
+	for _, collector := range []prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	} {
+		if err := pc.registry.Register(collector); err != nil {
+			if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+				return err
+			}
+		}
+	}
+	return nil
 }
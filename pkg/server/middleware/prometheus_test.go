@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestPrometheusRuntimeCollectors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	collector := NewPrometheusCollector("/metrics")
+	for range 2 {
+		if err := collector.RegisterRuntimeCollectors(); err != nil {
+			t.Fatalf("RegisterRuntimeCollectors: %v", err)
+		}
+	}
+	collector.RegisterMetricsEndpoint(engine)
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	body := rec.Body.String()
+	want := []string{"go_goroutines", "go_gc_duration_seconds", "go_memstats_heap_alloc_bytes"}
+	if runtime.GOOS == "linux" {
+		want = append(want, "process_cpu_seconds_total", "process_resident_memory_bytes", "process_open_fds")
+	}
+	for _, name := range want {
+		if !strings.Contains(body, "\n"+name) && !strings.HasPrefix(body, name) {
+			t.Fatalf("%s missing from /metrics", name)
+		}
+	}
+}
+
+func TestPrometheusREDMetricsWithExemplars(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	collector := NewPrometheusCollector("/metrics")
+	engine.Use(collector.PrometheusMiddleware())
+	engine.GET("/orders/:id", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+	collector.RegisterMetricsEndpoint(engine)
+
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	req := httptest.NewRequest(http.MethodGet, "/orders/42", nil)
+	req = req.WithContext(trace.ContextWithSpanContext(req.Context(), spanCtx))
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/wp-login.php", nil))
+
+	scrape := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	scrape.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, scrape)
+	body := rec.Body.String()
+
+	if !strings.Contains(body, `http_requests_total{method="GET",path="unmatched",status="404"} 1.0`) {
+		t.Fatalf("scrape missing unmatched request count:\n%s", body)
+	}
+	// Exemplar labels come from a map, so their order is not fixed.
+	for _, prefix := range []string{
+		`http_request_errors_total{method="GET",path="/orders/:id"} 1.0 # {`,
+		`http_request_duration_seconds_bucket{method="GET",path="/orders/:id",le="0.005"} 1 # {`,
+	} {
+		line := scrapeLine(body, prefix)
+		if !strings.Contains(line, `trace_id="4bf92f3577b34da6a3ce929d0e0e4736"`) || !strings.Contains(line, `span_id="00f067aa0ba902b7"`) {
+			t.Fatalf("scrape missing exemplar on %s:\n%s", prefix, body)
+		}
+	}
+	if strings.Contains(body, "wp-login") {
+		t.Fatalf("unmatched path leaked into labels:\n%s", body)
+	}
+}
+
+func TestREDDashboard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/orders", func(*gin.Context) {})
+	engine.GET("/orders/:id", func(*gin.Context) {})
+
+	if _, err := REDDashboard(REDDashboardConfig{}); err == nil {
+		t.Fatal("REDDashboard without job: want error")
+	}
+	data, err := REDDashboard(REDDashboardConfig{Job: "Orders API", Routes: engine.Routes()})
+	if err != nil {
+		t.Fatalf("REDDashboard: %v", err)
+	}
+
+	var dashboard struct {
+		Title  string `json:"title"`
+		UID    string `json:"uid"`
+		Panels []struct {
+			Type    string `json:"type"`
+			Title   string `json:"title"`
+			Targets []struct {
+				Expr     string `json:"expr"`
+				Exemplar bool   `json:"exemplar"`
+			} `json:"targets"`
+		} `json:"panels"`
+	}
+	if err := json.Unmarshal(data, &dashboard); err != nil {
+		t.Fatalf("dashboard is not JSON: %v", err)
+	}
+	if dashboard.Title != "Orders API RED" || dashboard.UID != "red-orders-api" {
+		t.Fatalf("title/uid = %q/%q", dashboard.Title, dashboard.UID)
+	}
+	// Overview plus two routes, each a row and three panels.
+	if len(dashboard.Panels) != 12 {
+		t.Fatalf("panels = %d, want 12", len(dashboard.Panels))
+	}
+	if got := dashboard.Panels[8].Title; got != "GET /orders/:id" {
+		t.Fatalf("second route row = %q", got)
+	}
+	duration := dashboard.Panels[11]
+	if len(duration.Targets) != 3 || !duration.Targets[0].Exemplar {
+		t.Fatalf("duration panel = %+v", duration)
+	}
+	want := `histogram_quantile(0.5, sum by (le) (rate(http_request_duration_seconds_bucket{job="Orders API",method="GET",path="/orders/:id"}[$__rate_interval])))`
+	if duration.Targets[0].Expr != want {
+		t.Fatalf("expr = %s\nwant %s", duration.Targets[0].Expr, want)
+	}
+}
+
+// scrapeLine returns the line of body starting with prefix, or "".
+func scrapeLine(body, prefix string) string {
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, prefix) {
+			return line
+		}
+	}
+	return ""
+}
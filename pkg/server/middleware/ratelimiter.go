@@ -2,6 +2,7 @@ package server
 
 import (
 	"net"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +17,20 @@ type rateLimitEntry struct {
 	lastSeen time.Time
 }
 
+// RateLimitPolicy describes a per-client request budget that can be attached to
+// a specific route or router group.
+type RateLimitPolicy struct {
+	RPS   float64
+	Burst int
+}
+
+// routeRateLimit binds a policy limiter to an exact route or a path prefix.
+type routeRateLimit struct {
+	path    string
+	prefix  bool
+	limiter *RateLimitConfig
+}
+
 // RateLimitConfig encapsulates both configuration and runtime state for per-IP rate limiting.
 type RateLimitConfig struct {
 	Enabled         bool
@@ -23,8 +38,17 @@ type RateLimitConfig struct {
 	Burst           int
 	CleanupInterval time.Duration
 
+	// mu guards Enabled, RPS, Burst and limit against Update.
+	mu      sync.RWMutex
 	limit   rate.Limit
 	clients sync.Map // map[string]*rateLimitEntry
+
+	routesMu sync.RWMutex
+	routes   []routeRateLimit
+
+	// done stops cleanupLoop; nil when no loop runs.
+	done     chan struct{}
+	stopOnce sync.Once
 }
 
 // NewRateLimitConfig creates a new RateLimitConfig and initializes runtime state.
@@ -37,11 +61,112 @@ func NewRateLimitConfig(enabled bool, rps float64, burst int, cleanupInterval ti
 		limit:           rate.Limit(rps),
 	}
 	if cleanupInterval > 0 {
+		rl.done = make(chan struct{})
 		go rl.cleanupLoop()
 	}
 	return rl
 }
 
+// stopCleanup ends the cleanup goroutine of a limiter that is no longer used.
+func (rl *RateLimitConfig) stopCleanup() {
+	if rl.done == nil {
+		return
+	}
+	rl.stopOnce.Do(func() { close(rl.done) })
+}
+
+// newPolicyLimiter builds an enabled limiter with its own client state for a policy.
+func newPolicyLimiter(policy RateLimitPolicy, cleanupInterval time.Duration) *RateLimitConfig {
+	if cleanupInterval <= 0 {
+		cleanupInterval = 10 * time.Minute
+	}
+	return NewRateLimitConfig(true, policy.RPS, policy.Burst, cleanupInterval)
+}
+
+// ForRoute attaches a dedicated policy to a single route. The path is matched
+// against the registered route template (e.g. "/users/:id"), so every request
+// to that route shares one budget per client IP independent of the global limit.
+// It returns the receiver so calls can be chained.
+func (rl *RateLimitConfig) ForRoute(path string, policy RateLimitPolicy) *RateLimitConfig {
+	return rl.addRoute(path, false, policy)
+}
+
+// ForPrefix attaches a dedicated policy to every route under a path prefix,
+// which is the natural fit for router groups (e.g. "/auth"). When several
+// prefixes match, the longest one wins; ForRoute entries always win over prefixes.
+func (rl *RateLimitConfig) ForPrefix(prefix string, policy RateLimitPolicy) *RateLimitConfig {
+	return rl.addRoute(prefix, true, policy)
+}
+
+func (rl *RateLimitConfig) addRoute(path string, prefix bool, policy RateLimitPolicy) *RateLimitConfig {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return rl
+	}
+	entry := routeRateLimit{
+		path:    path,
+		prefix:  prefix,
+		limiter: newPolicyLimiter(policy, rl.CleanupInterval),
+	}
+
+	rl.routesMu.Lock()
+	defer rl.routesMu.Unlock()
+	for i, existing := range rl.routes {
+		if existing.path == path && existing.prefix == prefix {
+			existing.limiter.stopCleanup()
+			rl.routes[i] = entry
+			return rl
+		}
+	}
+	rl.routes = append(rl.routes, entry)
+	// Exact routes first, then prefixes from longest to shortest.
+	sort.SliceStable(rl.routes, func(i, j int) bool {
+		if rl.routes[i].prefix != rl.routes[j].prefix {
+			return !rl.routes[i].prefix
+		}
+		return len(rl.routes[i].path) > len(rl.routes[j].path)
+	})
+	return rl
+}
+
+// policyFor returns the limiter responsible for the current request: a matching
+// route or prefix policy, or the receiver itself when none applies.
+func (rl *RateLimitConfig) policyFor(c *gin.Context) *RateLimitConfig {
+	rl.routesMu.RLock()
+	defer rl.routesMu.RUnlock()
+	if len(rl.routes) == 0 {
+		return rl
+	}
+
+	route := c.FullPath()
+	path := route
+	if c.Request != nil && c.Request.URL != nil {
+		path = c.Request.URL.Path
+	}
+	for _, entry := range rl.routes {
+		if entry.prefix {
+			if matchesPathPrefix(path, entry.path) {
+				return entry.limiter
+			}
+			continue
+		}
+		if route == entry.path || (route == "" && path == entry.path) {
+			return entry.limiter
+		}
+	}
+	return rl
+}
+
+// matchesPathPrefix reports whether path sits under prefix on a segment boundary,
+// so "/auth" covers "/auth/login" but not "/authors".
+func matchesPathPrefix(path, prefix string) bool {
+	trimmed := strings.TrimSuffix(prefix, "/")
+	if trimmed == "" {
+		return true
+	}
+	return path == trimmed || strings.HasPrefix(path, trimmed+"/")
+}
+
 // getLimiter returns the rate limiter for the given IP, creating one if needed.
 func (rl *RateLimitConfig) getLimiter(ip string) *rate.Limiter {
 	now := time.Now()
@@ -50,12 +175,39 @@ func (rl *RateLimitConfig) getLimiter(ip string) *rate.Limiter {
 		entry.lastSeen = now
 		return entry.limiter
 	}
+	// Held until the entry is stored, so Update cannot miss it.
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
 	entry := &rateLimitEntry{
 		limiter:  rate.NewLimiter(rl.limit, rl.Burst),
 		lastSeen: now,
 	}
-	rl.clients.Store(ip, entry)
-	return entry.limiter
+	actual, _ := rl.clients.LoadOrStore(ip, entry)
+	return actual.(*rateLimitEntry).limiter
+}
+
+// Update changes the global budget at runtime, e.g. after a config reload.
+// Clients keep their accumulated tokens, and their rate and burst change
+// right away. ForRoute and ForPrefix policies are not affected.
+func (rl *RateLimitConfig) Update(enabled bool, rps float64, burst int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.Enabled, rl.RPS, rl.Burst = enabled, rps, burst
+	rl.limit = rate.Limit(rps)
+	rl.clients.Range(func(_, value interface{}) bool {
+		entry := value.(*rateLimitEntry)
+		entry.limiter.SetLimit(rl.limit)
+		entry.limiter.SetBurst(burst)
+		return true
+	})
+}
+
+// IsEnabled reports whether the global limit is enforced. ForRoute and
+// ForPrefix policies are enforced either way.
+func (rl *RateLimitConfig) IsEnabled() bool {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return rl.Enabled
 }
 
 // cleanupLoop runs periodic cleanup of stale entries.
@@ -63,7 +215,12 @@ func (rl *RateLimitConfig) getLimiter(ip string) *rate.Limiter {
 func (rl *RateLimitConfig) cleanupLoop() {
 	t := time.NewTicker(rl.CleanupInterval)
 	defer t.Stop()
-	for range t.C {
+	for {
+		select {
+		case <-rl.done:
+			return
+		case <-t.C:
+		}
 		expiry := time.Now().Add(-2 * rl.CleanupInterval)
 		rl.clients.Range(func(key, value interface{}) bool {
 			entry := value.(*rateLimitEntry)
@@ -93,15 +250,17 @@ func getRemoteIP(c *gin.Context) string {
 }
 
 // Middleware returns the gin middleware enforcing per-IP rate limits.
-// Returns 429 if limiter.Allow() is false.
+// Requests matching a ForRoute/ForPrefix policy are charged against that policy
+// instead of the global budget. Returns 429 if limiter.Allow() is false.
+// While the global limit is disabled only those policies are enforced.
 func (rl *RateLimitConfig) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if !rl.Enabled {
+		policy := rl.policyFor(c)
+		if policy == rl && !rl.IsEnabled() {
 			c.Next()
 			return
 		}
-		ip := getRemoteIP(c)
-		lim := rl.getLimiter(ip)
+		lim := policy.getLimiter(getRemoteIP(c))
 		if !lim.Allow() {
 			c.AbortWithStatusJSON(429, gin.H{"error": "rate limit exceeded"})
 			return
@@ -110,22 +269,15 @@ func (rl *RateLimitConfig) Middleware() gin.HandlerFunc {
 	}
 }
 
-// EndpointRateLimiter returns a per-route gin middleware with its own rate limit.
-// Use this on sensitive endpoints (login, register, password reset) for stricter limits.
+// RateLimitMiddleware returns a gin middleware enforcing the given policy with its
+// own per-IP state. Attach it to a route or a router group to give that scope a
+// budget separate from the engine-level limit.
 //
 // Usage:
 //
-//	authGroup.POST("/login", middleware.EndpointRateLimiter(5, 10), loginHandler)
-//	authGroup.POST("/register", middleware.EndpointRateLimiter(3, 5), registerHandler)
-func EndpointRateLimiter(rps float64, burst int) gin.HandlerFunc {
-	rl := &RateLimitConfig{
-		Enabled:         true,
-		RPS:             rps,
-		Burst:           burst,
-		CleanupInterval: 10 * time.Minute,
-		limit:           rate.Limit(rps),
-	}
-	go rl.cleanupLoop()
+//	auth := router.Group("/auth", middleware.RateLimitMiddleware(middleware.RateLimitPolicy{RPS: 5, Burst: 10}))
+func RateLimitMiddleware(policy RateLimitPolicy) gin.HandlerFunc {
+	rl := newPolicyLimiter(policy, 0)
 
 	return func(c *gin.Context) {
 		ip := getRemoteIP(c)
@@ -140,3 +292,14 @@ func EndpointRateLimiter(rps float64, burst int) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// EndpointRateLimiter returns a per-route gin middleware with its own rate limit.
+// Use this on sensitive endpoints (login, register, password reset) for stricter limits.
+//
+// Usage:
+//
+//	authGroup.POST("/login", middleware.EndpointRateLimiter(5, 10), loginHandler)
+//	authGroup.POST("/register", middleware.EndpointRateLimiter(3, 5), registerHandler)
+func EndpointRateLimiter(rps float64, burst int) gin.HandlerFunc {
+	return RateLimitMiddleware(RateLimitPolicy{RPS: rps, Burst: burst})
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitUpdateAppliesAtRuntime(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rl := NewRateLimitConfig(false, 1, 1, 0)
+	engine := gin.New()
+	engine.Use(rl.Middleware())
+	engine.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve := func() int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		engine.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := serve(); code != http.StatusOK {
+			t.Fatalf("disabled limiter answered %d", code)
+		}
+	}
+
+	rl.Update(true, 0.001, 2)
+	codes := []int{serve(), serve(), serve()}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Fatalf("codes after enabling with burst 2 = %v", codes)
+	}
+
+	rl.Update(false, 0.001, 2)
+	if code := serve(); code != http.StatusOK {
+		t.Fatalf("limiter disabled again answered %d", code)
+	}
+}
+
+func TestRateLimitRoutePolicies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rl := NewRateLimitConfig(true, 1000, 1000, 0).
+		ForRoute("/users/:id", RateLimitPolicy{RPS: 0.001, Burst: 1}).
+		ForPrefix("/auth", RateLimitPolicy{RPS: 0.001, Burst: 2})
+	engine := gin.New()
+	engine.Use(rl.Middleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	engine.GET("/users/:id", ok)
+	engine.GET("/auth/login", ok)
+	engine.GET("/authors", ok)
+
+	serve := func(path string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		engine.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Both user IDs share the route budget of one request.
+	if serve("/users/1") != http.StatusOK || serve("/users/2") != http.StatusTooManyRequests {
+		t.Fatal("route policy not shared across the route template")
+	}
+	codes := []int{serve("/auth/login"), serve("/auth/login"), serve("/auth/login")}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Fatalf("prefix policy codes = %v", codes)
+	}
+	for i := 0; i < 3; i++ {
+		if code := serve("/authors"); code != http.StatusOK {
+			t.Fatalf("/authors answered %d, want the global budget", code)
+		}
+	}
+}
+
+func TestRateLimitReplacedPolicyStopsCleanup(t *testing.T) {
+	rl := NewRateLimitConfig(true, 10, 10, time.Hour)
+	defer rl.stopCleanup()
+	rl.ForPrefix("/auth", RateLimitPolicy{RPS: 1, Burst: 1})
+	old := rl.routes[0].limiter
+
+	rl.ForPrefix("/auth", RateLimitPolicy{RPS: 2, Burst: 2})
+	if len(rl.routes) != 1 || rl.routes[0].limiter == old {
+		t.Fatalf("routes = %+v, want the policy replaced", rl.routes)
+	}
+	select {
+	case <-old.done:
+	default:
+		t.Fatal("replaced policy's cleanup loop still running")
+	}
+	select {
+	case <-rl.routes[0].limiter.done:
+		t.Fatal("current policy's cleanup loop stopped")
+	default:
+	}
+	rl.routes[0].limiter.stopCleanup()
+}
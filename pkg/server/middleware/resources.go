@@ -0,0 +1,224 @@
+package server
+
+import (
+	"math/rand/v2"
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milan604/core-lab/pkg/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CtxResourceUsage is the gin context key holding the request's ResourceUsage.
+const CtxResourceUsage = "resource_usage"
+
+// Span attributes set on measured requests.
+const (
+	AttrResourceAllocBytes   = attribute.Key("http.server.alloc_bytes")
+	AttrResourceAllocObjects = attribute.Key("http.server.alloc_objects")
+	AttrResourceCPUSeconds   = attribute.Key("http.server.cpu_seconds")
+	AttrResourceGCCycles     = attribute.Key("http.server.gc_cycles")
+	AttrResourceConcurrent   = attribute.Key("http.server.concurrent_requests")
+	AttrResourceOutlier      = attribute.Key("http.server.resource_outlier")
+)
+
+var resourceMetricNames = []string{
+	"/gc/heap/allocs:bytes",
+	"/gc/heap/allocs:objects",
+	"/gc/cycles/total:gc-cycles",
+}
+
+// ResourceUsage is the allocation and CPU a request is estimated to have
+// used. The runtime only counts them per process, so the deltas measured
+// while the request ran are shared evenly among the requests in flight: the
+// figures are exact for a request served alone, and an approximation that
+// still singles out heavy handlers under load.
+type ResourceUsage struct {
+	AllocBytes   uint64
+	AllocObjects uint64
+	// CPU is zero where process CPU time is unavailable (non-unix systems).
+	CPU time.Duration
+	// GCCycles is the number of garbage collections, process-wide, that
+	// completed while the request ran.
+	GCCycles uint64
+	// Concurrent is the number of requests in flight the deltas were shared
+	// among, at least 1.
+	Concurrent int
+	// Outlier is set when the request went over a threshold; Reasons names
+	// which ones.
+	Outlier bool
+	Reasons []string
+}
+
+// ResourceBudgetConfig configures ResourceBudgetMiddleware.
+type ResourceBudgetConfig struct {
+	Enabled bool
+	// SampleRate is the fraction of requests measured, from 0 to 1.
+	// Default: 0.1.
+	SampleRate float64
+	// AllocBytesThreshold marks requests allocating more as outliers.
+	// Default: 32 MiB.
+	AllocBytesThreshold uint64
+	// CPUThreshold marks requests using more CPU time as outliers.
+	// Default: 250ms.
+	CPUThreshold time.Duration
+	// Skip excludes requests from measurement, e.g. health checks. Skipped
+	// requests still count as in flight.
+	Skip func(*gin.Context) bool
+	// OnOutlier is called for every outlier, e.g. to record a metric.
+	OnOutlier func(*gin.Context, ResourceUsage)
+	// Logger receives a warning for every outlier when set.
+	Logger logger.LogManager
+}
+
+// DefaultResourceBudgetConfig returns an enabled config measuring 10% of the
+// requests with default thresholds.
+func DefaultResourceBudgetConfig() ResourceBudgetConfig {
+	return ResourceBudgetConfig{
+		Enabled:             true,
+		SampleRate:          0.1,
+		AllocBytesThreshold: 32 << 20,
+		CPUThreshold:        250 * time.Millisecond,
+	}
+}
+
+// ResourceBudgetMiddleware measures the heap allocation and CPU time of a
+// sample of requests, to find the handlers behind GC pressure. Measured
+// requests get span attributes with their usage, and the ones over a
+// threshold are tagged as outliers on the span and logged with their route.
+// The usage is stored in the gin context for later middleware such as the
+// access logger. Register it right after the tracing middleware:
+//
+//	cfg := middleware.DefaultResourceBudgetConfig()
+//	cfg.Logger = log
+//	engine.Use(middleware.ResourceBudgetMiddleware(cfg))
+func ResourceBudgetMiddleware(cfg ResourceBudgetConfig) gin.HandlerFunc {
+	if !cfg.Enabled || cfg.SampleRate <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	defaults := DefaultResourceBudgetConfig()
+	if cfg.AllocBytesThreshold == 0 {
+		cfg.AllocBytesThreshold = defaults.AllocBytesThreshold
+	}
+	if cfg.CPUThreshold <= 0 {
+		cfg.CPUThreshold = defaults.CPUThreshold
+	}
+	var inFlight atomic.Int64
+
+	return func(c *gin.Context) {
+		startInFlight := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		if (cfg.Skip != nil && cfg.Skip(c)) || rand.Float64() >= cfg.SampleRate {
+			c.Next()
+			return
+		}
+
+		before := readResourceSnapshot()
+		c.Next()
+		after := readResourceSnapshot()
+
+		usage := before.usage(after, max(startInFlight, inFlight.Load()))
+		if usage.AllocBytes > cfg.AllocBytesThreshold {
+			usage.Reasons = append(usage.Reasons, "alloc_bytes")
+		}
+		if usage.CPU > cfg.CPUThreshold {
+			usage.Reasons = append(usage.Reasons, "cpu")
+		}
+		usage.Outlier = len(usage.Reasons) > 0
+		c.Set(CtxResourceUsage, usage)
+		recordResourceUsage(c, cfg, usage)
+	}
+}
+
+// ResourceUsageFromContext returns the usage recorded by
+// ResourceBudgetMiddleware, which is only set for measured requests and
+// after the handler has run.
+func ResourceUsageFromContext(c *gin.Context) (ResourceUsage, bool) {
+	if c == nil {
+		return ResourceUsage{}, false
+	}
+	value, ok := c.Get(CtxResourceUsage)
+	if !ok {
+		return ResourceUsage{}, false
+	}
+	usage, ok := value.(ResourceUsage)
+	return usage, ok
+}
+
+func recordResourceUsage(c *gin.Context, cfg ResourceBudgetConfig, usage ResourceUsage) {
+	ctx := c.Request.Context()
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		span.SetAttributes(
+			AttrResourceAllocBytes.Int64(int64(usage.AllocBytes)),
+			AttrResourceAllocObjects.Int64(int64(usage.AllocObjects)),
+			AttrResourceCPUSeconds.Float64(usage.CPU.Seconds()),
+			AttrResourceGCCycles.Int64(int64(usage.GCCycles)),
+			AttrResourceConcurrent.Int(usage.Concurrent),
+			AttrResourceOutlier.Bool(usage.Outlier),
+		)
+	}
+	if !usage.Outlier {
+		return
+	}
+	if span.IsRecording() {
+		span.AddEvent("resource_budget_exceeded", trace.WithAttributes(attribute.StringSlice("reasons", usage.Reasons)))
+	}
+	if cfg.Logger != nil {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		cfg.Logger.With(
+			"route", route,
+			"method", c.Request.Method,
+			"alloc_bytes", usage.AllocBytes,
+			"alloc_objects", usage.AllocObjects,
+			"cpu_ms", usage.CPU.Milliseconds(),
+			"gc_cycles", usage.GCCycles,
+			"concurrent_requests", usage.Concurrent,
+		).WarnFCtx(ctx, "Resource budget exceeded (%v): %s %s", usage.Reasons, c.Request.Method, route)
+	}
+	if cfg.OnOutlier != nil {
+		cfg.OnOutlier(c, usage)
+	}
+}
+
+// resourceSnapshot holds the process-wide counters at one point in time.
+type resourceSnapshot struct {
+	allocBytes   uint64
+	allocObjects uint64
+	gcCycles     uint64
+	cpu          time.Duration
+}
+
+func readResourceSnapshot() resourceSnapshot {
+	samples := make([]metrics.Sample, len(resourceMetricNames))
+	for i, name := range resourceMetricNames {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+	snapshot := resourceSnapshot{cpu: processCPUTime()}
+	for i, target := range []*uint64{&snapshot.allocBytes, &snapshot.allocObjects, &snapshot.gcCycles} {
+		if samples[i].Value.Kind() == metrics.KindUint64 {
+			*target = samples[i].Value.Uint64()
+		}
+	}
+	return snapshot
+}
+
+// usage shares the deltas from s to after among concurrent requests.
+func (s resourceSnapshot) usage(after resourceSnapshot, concurrent int64) ResourceUsage {
+	concurrent = max(concurrent, 1)
+	n := uint64(concurrent)
+	return ResourceUsage{
+		AllocBytes:   (after.allocBytes - s.allocBytes) / n,
+		AllocObjects: (after.allocObjects - s.allocObjects) / n,
+		CPU:          max(after.cpu-s.cpu, 0) / time.Duration(concurrent),
+		GCCycles:     after.gcCycles - s.gcCycles,
+		Concurrent:   int(concurrent),
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package server
+
+import "time"
+
+func processCPUTime() time.Duration {
+	return 0
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var resourceTestSink [][]byte
+
+func TestResourceBudgetMiddlewareTagsOutliers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	outliers := make(chan ResourceUsage, 1)
+	cfg := DefaultResourceBudgetConfig()
+	cfg.SampleRate = 1
+	cfg.AllocBytesThreshold = 4 << 20
+	cfg.CPUThreshold = time.Hour
+	cfg.OnOutlier = func(c *gin.Context, usage ResourceUsage) {
+		if c.FullPath() != "/heavy" {
+			t.Errorf("outlier route = %q, want /heavy", c.FullPath())
+		}
+		outliers <- usage
+	}
+	usages := make(chan ResourceUsage, 2)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Next()
+		if usage, ok := ResourceUsageFromContext(c); ok {
+			usages <- usage
+		}
+	})
+	router.Use(ResourceBudgetMiddleware(cfg))
+	router.GET("/heavy", func(c *gin.Context) {
+		for i := 0; i < 16; i++ {
+			resourceTestSink = append(resourceTestSink, make([]byte, 1<<20))
+		}
+		resourceTestSink = nil
+		c.Status(http.StatusOK)
+	})
+	router.GET("/light", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/light", nil))
+	light := <-usages
+	if light.Outlier || light.Concurrent != 1 {
+		t.Fatalf("light usage = %+v, want no outlier with 1 request in flight", light)
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/heavy", nil))
+	heavy := <-usages
+	if !heavy.Outlier || len(heavy.Reasons) != 1 || heavy.Reasons[0] != "alloc_bytes" {
+		t.Fatalf("heavy usage = %+v, want an alloc_bytes outlier", heavy)
+	}
+	if heavy.AllocBytes < 16<<20 {
+		t.Fatalf("heavy AllocBytes = %d, want at least 16 MiB", heavy.AllocBytes)
+	}
+	select {
+	case usage := <-outliers:
+		if usage.AllocBytes != heavy.AllocBytes {
+			t.Fatalf("OnOutlier usage = %+v, want %+v", usage, heavy)
+		}
+	default:
+		t.Fatal("OnOutlier was not called")
+	}
+}
+
+func TestResourceBudgetMiddlewareSkipsUnsampledRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, cfg := range map[string]ResourceBudgetConfig{
+		"disabled": {SampleRate: 1},
+		"skipped": {Enabled: true, SampleRate: 1, Skip: func(c *gin.Context) bool {
+			return c.Request.URL.Path == "/heavy"
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			usages := make(chan ResourceUsage, 1)
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Next()
+				if usage, ok := ResourceUsageFromContext(c); ok {
+					usages <- usage
+				}
+			})
+			router.Use(ResourceBudgetMiddleware(cfg))
+			router.GET("/heavy", func(c *gin.Context) { c.Status(http.StatusOK) })
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/heavy", nil))
+			select {
+			case usage := <-usages:
+				t.Fatalf("usage recorded for an unmeasured request: %+v", usage)
+			default:
+			}
+		})
+	}
+}
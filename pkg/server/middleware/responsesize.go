@@ -0,0 +1,243 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milan604/core-lab/pkg/apperr"
+	"github.com/milan604/core-lab/pkg/logger"
+	"github.com/milan604/core-lab/pkg/response"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metric names recorded by ResponseSizeMiddleware.
+const (
+	MetricHTTPResponseSize      = "http_response_size_bytes"
+	MetricHTTPResponsesTooLarge = "http_responses_too_large_total"
+)
+
+// ErrorCodeResponseTooLarge answers requests whose response went over
+// ResponseSizeConfig.MaxBytes.
+var ErrorCodeResponseTooLarge = apperr.Register(apperr.NewErrorCode("response_too_large", "Response too large", 101, http.StatusInternalServerError))
+
+// ErrResponseTooLarge is returned by writes that would take a response
+// already sent to the client, such as a flushed stream, over the cap.
+var ErrResponseTooLarge = errors.New("response exceeds the configured size cap")
+
+// ResponseSizeConfig configures ResponseSizeMiddleware.
+type ResponseSizeConfig struct {
+	Enabled bool
+	// MaxBytes is the hard cap on a response body. Responses are buffered
+	// up to it, and one going over is replaced by a 500 response_too_large
+	// error. Zero only measures sizes.
+	MaxBytes int64
+	// Registerer receives the size histogram and the oversize counter,
+	// labelled by method and route template, when set.
+	Registerer prometheus.Registerer
+	// Buckets are the size histogram buckets, in bytes. Default: 256 B to
+	// 16 MiB in powers of 4.
+	Buckets []float64
+	// Skip excludes requests from the cap, e.g. file downloads. Their size
+	// is still measured.
+	Skip func(*gin.Context) bool
+	// OnTooLarge is called for every response over the cap, with the bytes
+	// the handler had written when it was caught.
+	OnTooLarge func(c *gin.Context, size int64)
+	// Logger receives an error for every response over the cap when set.
+	Logger logger.LogManager
+}
+
+// DefaultResponseSizeConfig returns an enabled config capping responses at
+// 10 MiB.
+func DefaultResponseSizeConfig() ResponseSizeConfig {
+	return ResponseSizeConfig{
+		Enabled:  true,
+		MaxBytes: 10 << 20,
+		Buckets:  prometheus.ExponentialBuckets(256, 4, 8),
+	}
+}
+
+// ResponseSizeMiddleware measures response body sizes per route and enforces
+// a hard cap, to catch handlers that serialize unbounded lists before the
+// payload takes down clients or proxies. Capped responses are held in memory
+// until the handler returns, so a response over the cap can still be
+// replaced by a 500 response_too_large error; the failure is logged with the
+// route and counted. Once a handler flushes, e.g. to stream, the response is
+// sent as it is written and writes past the cap fail with
+// ErrResponseTooLarge, truncating it.
+//
+//	cfg := middleware.DefaultResponseSizeConfig()
+//	cfg.Registerer = collector.Registerer()
+//	cfg.Logger = log
+//	engine.Use(middleware.ResponseSizeMiddleware(cfg))
+func ResponseSizeMiddleware(cfg ResponseSizeConfig) gin.HandlerFunc {
+	if !cfg.Enabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+	if len(cfg.Buckets) == 0 {
+		cfg.Buckets = DefaultResponseSizeConfig().Buckets
+	}
+	metrics := newResponseSizeMetrics(cfg.Registerer, cfg.Buckets)
+
+	return func(c *gin.Context) {
+		if cfg.MaxBytes <= 0 || (cfg.Skip != nil && cfg.Skip(c)) {
+			c.Next()
+			metrics.observe(c, int64(max(c.Writer.Size(), 0)), false)
+			return
+		}
+
+		original := c.Writer
+		writer := &cappedResponseWriter{ResponseWriter: original, max: cfg.MaxBytes}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		if !writer.tooLarge {
+			if !writer.committed {
+				writer.commit()
+			}
+			metrics.observe(c, writer.size, false)
+			return
+		}
+
+		metrics.observe(c, writer.size, true)
+		if cfg.Logger != nil {
+			cfg.Logger.ErrorFCtx(c.Request.Context(), "response to %s %s exceeds %d bytes (%d written), route %s",
+				c.Request.Method, c.Request.URL.Path, cfg.MaxBytes, writer.size, routeLabel(c))
+		}
+		if cfg.OnTooLarge != nil {
+			cfg.OnTooLarge(c, writer.size)
+		}
+		if writer.committed {
+			// the status and part of the body are already on the wire
+			return
+		}
+		// drop the headers describing the discarded body
+		for _, name := range []string{"Content-Type", "Content-Length", "Content-Encoding", "Content-Disposition", "ETag", "Last-Modified"} {
+			original.Header().Del(name)
+		}
+		response.JSONError(c, apperr.New(ErrorCodeResponseTooLarge).
+			WithMessage("response exceeds "+strconv.FormatInt(cfg.MaxBytes, 10)+" bytes"))
+		c.Abort()
+	}
+}
+
+// cappedResponseWriter buffers the body until the handler returns or
+// flushes, and stops accepting writes past max.
+type cappedResponseWriter struct {
+	gin.ResponseWriter
+	max       int64
+	buf       bytes.Buffer
+	size      int64
+	tooLarge  bool
+	committed bool
+}
+
+func (w *cappedResponseWriter) Write(data []byte) (int, error) {
+	w.size += int64(len(data))
+	if w.tooLarge || w.size > w.max {
+		w.tooLarge = true
+		w.buf = bytes.Buffer{}
+		return 0, ErrResponseTooLarge
+	}
+	if w.committed {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.buf.Write(data)
+}
+
+func (w *cappedResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow defers the header until the body is sent.
+func (w *cappedResponseWriter) WriteHeaderNow() {
+	if w.committed {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *cappedResponseWriter) Written() bool {
+	return w.committed || w.size > 0 || w.ResponseWriter.Written()
+}
+
+func (w *cappedResponseWriter) Size() int {
+	if w.size == 0 && !w.committed {
+		return -1
+	}
+	return int(w.size)
+}
+
+// Flush sends what is buffered and switches to writing through.
+func (w *cappedResponseWriter) Flush() {
+	if w.tooLarge {
+		return
+	}
+	if !w.committed {
+		w.commit()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *cappedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.committed = true
+	return w.ResponseWriter.Hijack()
+}
+
+func (w *cappedResponseWriter) commit() {
+	w.committed = true
+	w.ResponseWriter.WriteHeaderNow()
+	if w.buf.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf = bytes.Buffer{}
+	}
+}
+
+type responseSizeMetrics struct {
+	sizes    *prometheus.HistogramVec
+	tooLarge *prometheus.CounterVec
+}
+
+func newResponseSizeMetrics(registerer prometheus.Registerer, buckets []float64) *responseSizeMetrics {
+	if registerer == nil {
+		return nil
+	}
+	sizes := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    MetricHTTPResponseSize,
+		Help:    "Histogram of response body sizes in bytes",
+		Buckets: buckets,
+	}, []string{"method", "path"})
+	tooLarge := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: MetricHTTPResponsesTooLarge,
+		Help: "Responses that went over the response size cap",
+	}, []string{"method", "path"})
+	return &responseSizeMetrics{
+		sizes:    registerOrExisting(registerer, sizes),
+		tooLarge: registerOrExisting(registerer, tooLarge),
+	}
+}
+
+func (m *responseSizeMetrics) observe(c *gin.Context, size int64, tooLarge bool) {
+	if m == nil {
+		return
+	}
+	method := sanitizeMethodLabel(c.Request.Method)
+	path := routeLabel(c)
+	m.sizes.WithLabelValues(method, path).Observe(float64(size))
+	if tooLarge {
+		m.tooLarge.WithLabelValues(method, path).Inc()
+	}
+}
+
+// routeLabel is the route template of the request, or "unmatched".
+func routeLabel(c *gin.Context) string {
+	if path := c.FullPath(); path != "" {
+		return path
+	}
+	return unmatchedRoute
+}
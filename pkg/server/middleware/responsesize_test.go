@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestResponseSizeMiddlewareReplacesOversizedResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := prometheus.NewRegistry()
+	var caught []int64
+	cfg := DefaultResponseSizeConfig()
+	cfg.MaxBytes = 100
+	cfg.Registerer = registry
+	cfg.OnTooLarge = func(c *gin.Context, size int64) { caught = append(caught, size) }
+	router := gin.New()
+	router.Use(ResponseSizeMiddleware(cfg))
+	router.GET("/items", func(c *gin.Context) {
+		n := len(c.Query("n"))
+		c.Header("ETag", `"v1"`)
+		c.String(http.StatusOK, strings.Repeat("x", n*10))
+	})
+	router.GET("/empty", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	small := httptest.NewRecorder()
+	router.ServeHTTP(small, httptest.NewRequest(http.MethodGet, "/items?n=xxxxx", nil))
+	if small.Code != http.StatusOK || small.Body.Len() != 50 || small.Header().Get("ETag") == "" {
+		t.Fatalf("small response = %d %q %v, want 200 with 50 bytes", small.Code, small.Body.String(), small.Header())
+	}
+
+	large := httptest.NewRecorder()
+	router.ServeHTTP(large, httptest.NewRequest(http.MethodGet, "/items?n=xxxxxxxxxxxxxxx", nil))
+	if large.Code != http.StatusInternalServerError {
+		t.Fatalf("large response status = %d, want 500", large.Code)
+	}
+	if large.Header().Get("ETag") != "" {
+		t.Errorf("large response kept the discarded body's ETag")
+	}
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(large.Body.Bytes(), &body); err != nil || body.Code != ErrorCodeResponseTooLarge.Code() {
+		t.Fatalf("large response body = %s, want a %s error", large.Body.String(), ErrorCodeResponseTooLarge.Code())
+	}
+	if len(caught) != 1 || caught[0] != 150 {
+		t.Errorf("OnTooLarge sizes = %v, want [150]", caught)
+	}
+
+	empty := httptest.NewRecorder()
+	router.ServeHTTP(empty, httptest.NewRequest(http.MethodGet, "/empty", nil))
+	if empty.Code != http.StatusNoContent {
+		t.Errorf("empty response status = %d, want 204", empty.Code)
+	}
+
+	if got := testutil.ToFloat64(tooLargeCounter(t, registry, "GET", "/items")); got != 1 {
+		t.Errorf("%s{path=/items} = %v, want 1", MetricHTTPResponsesTooLarge, got)
+	}
+	if got := testutil.CollectAndCount(registry, MetricHTTPResponseSize); got != 2 {
+		t.Errorf("%s series = %d, want 2", MetricHTTPResponseSize, got)
+	}
+}
+
+func TestResponseSizeMiddlewareTruncatesFlushedResponses(t *testing.T) {
+	cfg := DefaultResponseSizeConfig()
+	cfg.MaxBytes = 100
+	var writeErr any
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Next()
+		writeErr, _ = c.Get("write_error")
+	})
+	router.Use(ResponseSizeMiddleware(cfg))
+	// Flush 4 chunks of 40 bytes, keeping the first write error.
+	router.GET("/stream", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		for i := 0; i < 4; i++ {
+			if _, err := c.Writer.WriteString(strings.Repeat("y", 40)); err != nil {
+				c.Set("write_error", err)
+				return
+			}
+			c.Writer.Flush()
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() != 80 {
+		t.Fatalf("stream response = %d with %d bytes, want 200 truncated to 80", rec.Code, rec.Body.Len())
+	}
+	if writeErr != ErrResponseTooLarge {
+		t.Fatalf("write error = %v, want ErrResponseTooLarge", writeErr)
+	}
+}
+
+func TestResponseSizeMiddlewareMeasuresWithoutCap(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := prometheus.NewRegistry()
+	cfg := DefaultResponseSizeConfig()
+	cfg.MaxBytes = 0
+	cfg.Registerer = registry
+	router := gin.New()
+	router.Use(ResponseSizeMiddleware(cfg))
+	router.GET("/items", func(c *gin.Context) {
+		n := len(c.Query("n"))
+		c.Header("ETag", `"v1"`)
+		c.String(http.StatusOK, strings.Repeat("x", n*10))
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?n=xxxxxxxxxxxxxxx", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() != 150 {
+		t.Fatalf("response = %d with %d bytes, want 200 with 150", rec.Code, rec.Body.Len())
+	}
+	if got := testutil.ToFloat64(tooLargeCounter(t, registry, "GET", "/items")); got != 0 {
+		t.Errorf("%s = %v without a cap, want 0", MetricHTTPResponsesTooLarge, got)
+	}
+}
+
+func tooLargeCounter(t *testing.T, registry *prometheus.Registry, method, path string) prometheus.Counter {
+	t.Helper()
+	metrics := newResponseSizeMetrics(registry, DefaultResponseSizeConfig().Buckets)
+	return metrics.tooLarge.WithLabelValues(method, path)
+}
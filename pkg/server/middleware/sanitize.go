@@ -0,0 +1,279 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultTraversalPatterns are the lower-cased substrings treated as path
+// traversal attempts in the escaped path and raw query.
+var DefaultTraversalPatterns = []string{
+	"../", "..\\",
+	"%2e%2e", "%2e.", ".%2e", // percent-encoded dots
+	"%252e", "%255c", "%252f", // double encoding
+	"%c0%ae", "%c0%af", "%c1%9c", // overlong UTF-8
+	"..;/", // servlet path parameter bypass
+}
+
+// SanitizeConfig configures SanitizeMiddleware. Zero limits disable the
+// corresponding check.
+type SanitizeConfig struct {
+	Enabled bool
+	// RejectNullBytes rejects NUL in the path, query, headers, or JSON and form
+	// bodies. Default: true.
+	RejectNullBytes bool
+	// MaxJSONDepth rejects JSON bodies nested deeper than this. Default: 32.
+	MaxJSONDepth int
+	// MaxBodyBytes bounds how much of a JSON or form body is buffered for
+	// inspection; larger bodies are rejected with 413. Other content types are
+	// not read. Default: 1 MiB.
+	MaxBodyBytes int64
+	// MaxQueryParams limits the number of query values. Default: 50.
+	MaxQueryParams int
+	// MaxQueryLength limits the length of each query key and value. Default: 1024.
+	MaxQueryLength int
+	// BlockPathTraversal rejects requests whose path or query matches
+	// TraversalPatterns or contains a ".." segment. Default: true.
+	BlockPathTraversal bool
+	// TraversalPatterns defaults to DefaultTraversalPatterns.
+	TraversalPatterns []string
+	// NormalizeIdentifiers rewrites route parameters and IdentifierParams query
+	// values to Unicode NFKC, so look-alike forms of an ID compare equal.
+	// Default: true.
+	NormalizeIdentifiers bool
+	// IdentifierParams are the query parameters normalized with route parameters.
+	IdentifierParams []string
+	// Skip bypasses sanitization for matching requests.
+	Skip func(*gin.Context) bool
+}
+
+// DefaultSanitizeConfig returns an enabled config with every protection on.
+func DefaultSanitizeConfig() SanitizeConfig {
+	return SanitizeConfig{
+		Enabled:              true,
+		RejectNullBytes:      true,
+		MaxJSONDepth:         32,
+		MaxBodyBytes:         1 << 20,
+		MaxQueryParams:       50,
+		MaxQueryLength:       1024,
+		BlockPathTraversal:   true,
+		TraversalPatterns:    DefaultTraversalPatterns,
+		NormalizeIdentifiers: true,
+	}
+}
+
+// SanitizeMiddleware rejects requests carrying common injection and traversal
+// payloads with a 400 and normalizes identifiers before handlers see them.
+// Register it with engine.Use so route parameters are available.
+//
+//	engine.Use(middleware.SanitizeMiddleware(middleware.DefaultSanitizeConfig()))
+func SanitizeMiddleware(cfg SanitizeConfig) gin.HandlerFunc {
+	if !cfg.Enabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+	if cfg.BlockPathTraversal && len(cfg.TraversalPatterns) == 0 {
+		cfg.TraversalPatterns = DefaultTraversalPatterns
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = DefaultSanitizeConfig().MaxBodyBytes
+	}
+	identifierParams := make(map[string]struct{}, len(cfg.IdentifierParams))
+	for _, name := range cfg.IdentifierParams {
+		identifierParams[name] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+		if cfg.Skip != nil && cfg.Skip(c) {
+			c.Next()
+			return
+		}
+		req := c.Request
+
+		if cfg.RejectNullBytes && (hasNullByte(req.URL.Path) || hasNullByte(req.URL.RawQuery) || hasNullByte(req.URL.RawPath) || headersHaveNullByte(req.Header)) {
+			abortSanitize(c, http.StatusBadRequest, "null_byte_rejected", "request contains a null byte")
+			return
+		}
+		if cfg.BlockPathTraversal && isPathTraversal(req.URL, cfg.TraversalPatterns) {
+			abortSanitize(c, http.StatusBadRequest, "path_traversal_rejected", "request path or query contains a traversal sequence")
+			return
+		}
+
+		query, err := url.ParseQuery(req.URL.RawQuery)
+		if err != nil {
+			abortSanitize(c, http.StatusBadRequest, "invalid_query", "query string is malformed")
+			return
+		}
+		if code, message := checkQuery(query, cfg); code != "" {
+			abortSanitize(c, http.StatusBadRequest, code, message)
+			return
+		}
+
+		if code, status, message := checkBody(c, cfg); code != "" {
+			abortSanitize(c, status, code, message)
+			return
+		}
+
+		if cfg.NormalizeIdentifiers {
+			normalizeIdentifiers(c, query, identifierParams)
+		}
+		c.Next()
+	}
+}
+
+func abortSanitize(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, gin.H{
+		"error":   code,
+		"message": message,
+	})
+}
+
+func checkQuery(query url.Values, cfg SanitizeConfig) (code, message string) {
+	count := 0
+	for key, values := range query {
+		count += len(values)
+		if cfg.MaxQueryParams > 0 && count > cfg.MaxQueryParams {
+			return "too_many_query_params", "query string has too many parameters"
+		}
+		if cfg.MaxQueryLength > 0 && len(key) > cfg.MaxQueryLength {
+			return "query_param_too_long", "query parameter name is too long"
+		}
+		for _, value := range values {
+			if cfg.MaxQueryLength > 0 && len(value) > cfg.MaxQueryLength {
+				return "query_param_too_long", "query parameter " + key + " is too long"
+			}
+		}
+	}
+	return "", ""
+}
+
+// checkBody inspects JSON and form bodies and restores them for the handler.
+func checkBody(c *gin.Context, cfg SanitizeConfig) (code string, status int, message string) {
+	req := c.Request
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", 0, ""
+	}
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	isJSON := mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+	isForm := mediaType == "application/x-www-form-urlencoded"
+	if !isJSON && !isForm {
+		return "", 0, ""
+	}
+	if !cfg.RejectNullBytes && (!isJSON || cfg.MaxJSONDepth <= 0) {
+		return "", 0, ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, cfg.MaxBodyBytes+1))
+	if err != nil {
+		return "invalid_request", http.StatusBadRequest, "request body could not be read"
+	}
+	if int64(len(body)) > cfg.MaxBodyBytes {
+		return "request_too_large", http.StatusRequestEntityTooLarge, "request body is too large to inspect"
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	if cfg.RejectNullBytes {
+		if bytes.IndexByte(body, 0) >= 0 || (isJSON && bytes.Contains(body, []byte(`\u0000`))) || (isForm && hasNullByte(string(body))) {
+			return "null_byte_rejected", http.StatusBadRequest, "request body contains a null byte"
+		}
+	}
+	if isJSON && cfg.MaxJSONDepth > 0 && jsonDepth(body) > cfg.MaxJSONDepth {
+		return "json_too_deep", http.StatusBadRequest, "JSON body is nested too deeply"
+	}
+	return "", 0, ""
+}
+
+// jsonDepth returns the deepest object or array nesting in data without
+// decoding it. Malformed JSON is left for the handler to reject.
+func jsonDepth(data []byte) int {
+	depth, maxDepth := 0, 0
+	inString, escaped := false, false
+	for _, b := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			switch b {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			depth++
+			maxDepth = max(maxDepth, depth)
+		case b == '}' || b == ']':
+			depth--
+		}
+	}
+	return maxDepth
+}
+
+func hasNullByte(s string) bool {
+	return strings.IndexByte(s, 0) >= 0 || strings.Contains(strings.ToLower(s), "%00")
+}
+
+func headersHaveNullByte(header http.Header) bool {
+	for key, values := range header {
+		if strings.IndexByte(key, 0) >= 0 {
+			return true
+		}
+		for _, value := range values {
+			if strings.IndexByte(value, 0) >= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isPathTraversal(u *url.URL, patterns []string) bool {
+	escaped := strings.ToLower(u.EscapedPath())
+	rawQuery := strings.ToLower(u.RawQuery)
+	for _, pattern := range patterns {
+		if strings.Contains(escaped, pattern) || strings.Contains(rawQuery, pattern) {
+			return true
+		}
+	}
+	for _, segment := range strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeIdentifiers rewrites route parameters and the named query
+// parameters to NFKC.
+func normalizeIdentifiers(c *gin.Context, query url.Values, names map[string]struct{}) {
+	for i, param := range c.Params {
+		c.Params[i].Value = norm.NFKC.String(param.Value)
+	}
+	if len(names) == 0 {
+		return
+	}
+	changed := false
+	for name := range names {
+		values, ok := query[name]
+		if !ok {
+			continue
+		}
+		for i, value := range values {
+			if normalized := norm.NFKC.String(value); normalized != value {
+				values[i] = normalized
+				changed = true
+			}
+		}
+	}
+	if changed {
+		c.Request.URL.RawQuery = query.Encode()
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSanitizeRejectsMaliciousRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := DefaultSanitizeConfig()
+	cfg.MaxJSONDepth = 3
+	cfg.MaxQueryParams = 3
+	cfg.MaxQueryLength = 16
+	router := gin.New()
+	router.Use(SanitizeMiddleware(cfg))
+	router.GET("/files/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/files/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cases := []struct {
+		name        string
+		method      string
+		target      string
+		body        string
+		contentType string
+		status      int
+		code        string
+	}{
+		{name: "clean", method: http.MethodGet, target: "/files/report?owner=ana", status: http.StatusOK},
+		{name: "null in path", method: http.MethodGet, target: "/files/report%00.txt", status: http.StatusBadRequest, code: "null_byte_rejected"},
+		{name: "null in query", method: http.MethodGet, target: "/files/a?owner=x%00y", status: http.StatusBadRequest, code: "null_byte_rejected"},
+		{name: "null in json", method: http.MethodPost, target: "/files/a", body: `{"name":"a\u0000b"}`, contentType: "application/json", status: http.StatusBadRequest, code: "null_byte_rejected"},
+		{name: "deep json", method: http.MethodPost, target: "/files/a", body: `{"a":[{"b":[1]}]}`, contentType: "application/json", status: http.StatusBadRequest, code: "json_too_deep"},
+		{name: "brackets in strings", method: http.MethodPost, target: "/files/a", body: `{"a":"[[[[{{{{\"]]"}`, contentType: "application/json", status: http.StatusOK},
+		{name: "too many params", method: http.MethodGet, target: "/files/a?a=1&b=2&c=3&c=4", status: http.StatusBadRequest, code: "too_many_query_params"},
+		{name: "long param", method: http.MethodGet, target: "/files/a?owner=" + strings.Repeat("x", 17), status: http.StatusBadRequest, code: "query_param_too_long"},
+		{name: "encoded traversal", method: http.MethodGet, target: "/files/%2e%2e%2fetc%2fpasswd", status: http.StatusBadRequest, code: "path_traversal_rejected"},
+		{name: "double encoded traversal", method: http.MethodGet, target: "/files/%252e%252e", status: http.StatusBadRequest, code: "path_traversal_rejected"},
+		{name: "traversal in query", method: http.MethodGet, target: "/files/a?owner=../../etc", status: http.StatusBadRequest, code: "path_traversal_rejected"},
+		{name: "binary body untouched", method: http.MethodPost, target: "/files/a", body: "\x00\x01", contentType: "application/octet-stream", status: http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tc.status {
+				t.Fatalf("status = %d, want %d (body %s)", recorder.Code, tc.status, recorder.Body.String())
+			}
+			if tc.code == "" {
+				return
+			}
+			var body map[string]string
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || body["error"] != tc.code || body["message"] == "" {
+				t.Fatalf("body = %s, want error %q", recorder.Body.String(), tc.code)
+			}
+		})
+	}
+}
+
+func TestSanitizeNormalizesIdentifiers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := DefaultSanitizeConfig()
+	cfg.IdentifierParams = []string{"owner"}
+	var seen string
+	router := gin.New()
+	router.Use(SanitizeMiddleware(cfg))
+	router.GET("/files/:id", func(c *gin.Context) {
+		seen = c.Param("id") + "|" + c.Query("owner")
+		c.Status(http.StatusOK)
+	})
+
+	// Fullwidth "ＡＢＣ１" and the "ﬁ" ligature fold to their ASCII forms under NFKC.
+	req := httptest.NewRequest(http.MethodGet, "/files/%EF%BC%A1%EF%BC%A2%EF%BC%A3%EF%BC%91?owner=%EF%AC%81le", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK || seen != "ABC1|file" {
+		t.Fatalf("status = %d, seen = %q, want ABC1|file", recorder.Code, seen)
+	}
+}
@@ -0,0 +1,295 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	httplib "github.com/milan604/core-lab/pkg/http"
+	"github.com/milan604/core-lab/pkg/logger"
+)
+
+const (
+	// HeaderShadowRequest marks requests mirrored by ShadowMiddleware so the
+	// shadow deployment can tell them apart (and must not mirror them again).
+	HeaderShadowRequest = "X-Shadow-Request"
+	// ShadowRedactedValue replaces redacted header values and body fields.
+	ShadowRedactedValue = "***REDACTED***"
+)
+
+var defaultShadowRedactHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"X-Api-Key",
+	"X-Service-Token",
+}
+
+// ShadowConfig configures ShadowMiddleware.
+type ShadowConfig struct {
+	Enabled bool
+	// Target is the base URL of the shadow deployment; the request path and
+	// query are appended to it.
+	Target string
+	// SampleRate is the fraction of requests mirrored, from 0 to 1.
+	SampleRate float64
+	// Client sends the mirrored requests. Default: a pkg/http client with a
+	// single attempt.
+	Client *httplib.Client
+	// Timeout bounds each mirrored request. Default: 5s.
+	Timeout time.Duration
+	// MaxBodyBytes skips requests with larger bodies. Default: 1 MiB.
+	MaxBodyBytes int64
+	// MaxInFlight caps concurrent mirrored requests; samples beyond it are
+	// dropped. Default: 32.
+	MaxInFlight int
+	// RedactHeaders are replaced with ShadowRedactedValue. Default:
+	// Authorization, Proxy-Authorization, Cookie, X-Api-Key, X-Service-Token.
+	RedactHeaders []string
+	// RedactFields are body fields replaced with ShadowRedactedValue: JSON
+	// keys at any depth, and form-urlencoded and multipart field names. Keys
+	// match case-insensitively. When set, requests with other body types are
+	// not mirrored, since they cannot be redacted.
+	RedactFields []string
+	// Skip excludes requests from mirroring, e.g. health checks.
+	Skip   func(*gin.Context) bool
+	Logger logger.LogManager
+}
+
+// DefaultShadowConfig returns an enabled config mirroring sampleRate of the
+// traffic to target.
+func DefaultShadowConfig(target string, sampleRate float64) ShadowConfig {
+	return ShadowConfig{
+		Enabled:       true,
+		Target:        target,
+		SampleRate:    sampleRate,
+		Timeout:       5 * time.Second,
+		MaxBodyBytes:  1 << 20,
+		MaxInFlight:   32,
+		RedactHeaders: append([]string(nil), defaultShadowRedactHeaders...),
+	}
+}
+
+// ShadowMiddleware mirrors a sample of requests, headers and body, to a shadow
+// deployment so a new service version can be exercised with real traffic.
+// Mirrors are sent in the background after sampling and never affect the
+// primary response: shadow errors and responses are only logged at debug
+// level. Credentials are redacted before the copy leaves the process, so the
+// shadow must not rely on the caller's token.
+//
+//	cfg := middleware.DefaultShadowConfig("http://orders-canary.internal", 0.05)
+//	cfg.RedactFields = []string{"card_number", "cvv"}
+//	engine.Use(middleware.ShadowMiddleware(cfg))
+func ShadowMiddleware(cfg ShadowConfig) gin.HandlerFunc {
+	cfg.Target = strings.TrimRight(strings.TrimSpace(cfg.Target), "/")
+	if !cfg.Enabled || cfg.Target == "" || cfg.SampleRate <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	defaults := DefaultShadowConfig(cfg.Target, cfg.SampleRate)
+	if cfg.Client == nil {
+		cfg.Client = httplib.NewClient(httplib.WithRetry(1, 0))
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = defaults.MaxBodyBytes
+	}
+	if cfg.MaxInFlight <= 0 {
+		cfg.MaxInFlight = defaults.MaxInFlight
+	}
+	if cfg.RedactHeaders == nil {
+		cfg.RedactHeaders = defaults.RedactHeaders
+	}
+	redactFields := make(map[string]struct{}, len(cfg.RedactFields))
+	for _, field := range cfg.RedactFields {
+		redactFields[strings.ToLower(strings.TrimSpace(field))] = struct{}{}
+	}
+	inFlight := make(chan struct{}, cfg.MaxInFlight)
+
+	return func(c *gin.Context) {
+		if c.GetHeader(HeaderShadowRequest) != "" || (cfg.Skip != nil && cfg.Skip(c)) || rand.Float64() >= cfg.SampleRate {
+			c.Next()
+			return
+		}
+
+		mirror, ok := buildShadowRequest(c, cfg, redactFields)
+		if ok {
+			select {
+			case inFlight <- struct{}{}:
+				go func() {
+					defer func() { <-inFlight }()
+					sendShadowRequest(mirror, cfg)
+				}()
+			default:
+				if cfg.Logger != nil {
+					cfg.Logger.DebugFCtx(c.Request.Context(), "shadow: %d requests in flight, dropping %s %s", cfg.MaxInFlight, c.Request.Method, c.Request.URL.Path)
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+// buildShadowRequest copies the request, restoring the body for the primary
+// handler. It reports false when the body is too large, unreadable, or cannot
+// be redacted.
+func buildShadowRequest(c *gin.Context, cfg ShadowConfig, redactFields map[string]struct{}) (*http.Request, bool) {
+	var body []byte
+	if c.Request.Body != nil && c.Request.Body != http.NoBody {
+		if c.Request.ContentLength > cfg.MaxBodyBytes {
+			return nil, false
+		}
+		read, err := io.ReadAll(io.LimitReader(c.Request.Body, cfg.MaxBodyBytes+1))
+		c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(read), c.Request.Body))
+		if err != nil || int64(len(read)) > cfg.MaxBodyBytes {
+			return nil, false
+		}
+		body = read
+	}
+	if len(body) > 0 && len(redactFields) > 0 {
+		var ok bool
+		if body, ok = redactShadowBody(c.GetHeader("Content-Type"), body, redactFields); !ok {
+			return nil, false
+		}
+	}
+
+	// Detach from the primary request's cancellation but keep its values
+	// (trace and request ID) for propagation.
+	ctx := context.WithoutCancel(c.Request.Context())
+	req, err := http.NewRequestWithContext(ctx, c.Request.Method, cfg.Target+c.Request.URL.RequestURI(), bytes.NewReader(body))
+	if err != nil {
+		return nil, false
+	}
+	req.Header = c.Request.Header.Clone()
+	for _, name := range cfg.RedactHeaders {
+		if req.Header.Get(name) != "" {
+			req.Header.Set(name, ShadowRedactedValue)
+		}
+	}
+	req.Header.Set(HeaderShadowRequest, "true")
+	return req, true
+}
+
+func sendShadowRequest(req *http.Request, cfg ShadowConfig) {
+	ctx, cancel := context.WithTimeout(req.Context(), cfg.Timeout)
+	defer cancel()
+
+	resp, err := cfg.Client.Do(ctx, req.WithContext(ctx))
+	if err != nil {
+		if cfg.Logger != nil {
+			cfg.Logger.DebugFCtx(ctx, "shadow: %s %s failed: %v", req.Method, req.URL.Path, err)
+		}
+		return
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if cfg.Logger != nil {
+		cfg.Logger.DebugFCtx(ctx, "shadow: %s %s returned %d", req.Method, req.URL.Path, resp.StatusCode)
+	}
+}
+
+// redactShadowBody masks redactFields in a JSON, form-urlencoded or
+// multipart body. It reports false for other content types and for bodies
+// that do not parse, which are not mirrored rather than sent unredacted.
+func redactShadowBody(contentType string, body []byte, redactFields map[string]struct{}) ([]byte, bool) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	switch {
+	case strings.Contains(mediaType, "json"):
+		var value any
+		if err := json.Unmarshal(body, &value); err != nil {
+			return nil, false
+		}
+		redacted, err := json.Marshal(redactShadowValue(value, redactFields))
+		if err != nil {
+			return nil, false
+		}
+		return redacted, true
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, false
+		}
+		for key, vs := range values {
+			if _, ok := redactFields[strings.ToLower(key)]; ok {
+				for i := range vs {
+					vs[i] = ShadowRedactedValue
+				}
+			}
+		}
+		return []byte(values.Encode()), true
+	case mediaType == "multipart/form-data":
+		return redactShadowMultipart(body, params["boundary"], redactFields)
+	}
+	return nil, false
+}
+
+// redactShadowMultipart rewrites a multipart body with the same boundary,
+// replacing the content of redacted fields, files included.
+func redactShadowMultipart(body []byte, boundary string, redactFields map[string]struct{}) ([]byte, bool) {
+	if boundary == "" {
+		return nil, false
+	}
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	var out bytes.Buffer
+	writer := multipart.NewWriter(&out)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return nil, false
+	}
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false
+		}
+		dst, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return nil, false
+		}
+		if _, ok := redactFields[strings.ToLower(part.FormName())]; ok {
+			_, err = io.WriteString(dst, ShadowRedactedValue)
+		} else {
+			_, err = io.Copy(dst, part)
+		}
+		if err != nil {
+			return nil, false
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, false
+	}
+	return out.Bytes(), true
+}
+
+func redactShadowValue(value any, redactFields map[string]struct{}) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, v := range typed {
+			if _, ok := redactFields[strings.ToLower(key)]; ok {
+				typed[key] = ShadowRedactedValue
+				continue
+			}
+			typed[key] = redactShadowValue(v, redactFields)
+		}
+	case []any:
+		for i, item := range typed {
+			typed[i] = redactShadowValue(item, redactFields)
+		}
+	}
+	return value
+}
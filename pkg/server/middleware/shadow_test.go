@@ -0,0 +1,182 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type shadowCapture struct {
+	method string
+	uri    string
+	header http.Header
+	body   []byte
+}
+
+func newShadowTarget(t *testing.T) (*httptest.Server, <-chan shadowCapture) {
+	t.Helper()
+	captured := make(chan shadowCapture, 4)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		captured <- shadowCapture{method: r.Method, uri: r.URL.RequestURI(), header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(target.Close)
+	return target, captured
+}
+
+func TestShadowMiddlewareMirrorsRedactedCopy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	target, captured := newShadowTarget(t)
+	cfg := DefaultShadowConfig(target.URL+"/", 1)
+	cfg.RedactFields = []string{"card_number"}
+
+	var primaryBody string
+	router := gin.New()
+	router.Use(ShadowMiddleware(cfg))
+	router.POST("/orders", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		primaryBody = string(body)
+		c.JSON(http.StatusCreated, gin.H{"id": "ord-1"})
+	})
+
+	payload := `{"item":"book","payment":{"card_number":"4111111111111111"}}`
+	req := httptest.NewRequest(http.MethodPost, "/orders?source=web", strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Tenant-ID", "tenant-a")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("primary status = %d, want 201 regardless of shadow failures", recorder.Code)
+	}
+	if primaryBody != payload {
+		t.Fatalf("primary handler body = %q, want the original payload", primaryBody)
+	}
+
+	var mirror shadowCapture
+	select {
+	case mirror = <-captured:
+	case <-time.After(2 * time.Second):
+		t.Fatal("shadow request was not sent")
+	}
+	if mirror.method != http.MethodPost || mirror.uri != "/orders?source=web" {
+		t.Fatalf("mirror = %s %s", mirror.method, mirror.uri)
+	}
+	if got := mirror.header.Get("Authorization"); got != ShadowRedactedValue {
+		t.Fatalf("Authorization = %q, want redacted", got)
+	}
+	if mirror.header.Get("X-Tenant-ID") != "tenant-a" || mirror.header.Get(HeaderShadowRequest) != "true" {
+		t.Fatalf("mirror headers = %v", mirror.header)
+	}
+	var body struct {
+		Payment map[string]any `json:"payment"`
+	}
+	if err := json.Unmarshal(mirror.body, &body); err != nil {
+		t.Fatalf("mirror body %q: %v", mirror.body, err)
+	}
+	if body.Payment["card_number"] != ShadowRedactedValue {
+		t.Fatalf("mirror body = %s, want card_number redacted", mirror.body)
+	}
+}
+
+func TestShadowMiddlewareSkipsUnsampledAndShadowRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	target, captured := newShadowTarget(t)
+
+	unsampled := gin.New()
+	unsampled.Use(ShadowMiddleware(DefaultShadowConfig(target.URL, 0)))
+	unsampled.POST("/orders", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	unsampled.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("{}")))
+
+	sampled := gin.New()
+	sampled.Use(ShadowMiddleware(DefaultShadowConfig(target.URL, 1)))
+	sampled.POST("/orders", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("{}"))
+	req.Header.Set(HeaderShadowRequest, "true")
+	sampled.ServeHTTP(httptest.NewRecorder(), req)
+
+	select {
+	case mirror := <-captured:
+		t.Fatalf("unexpected shadow request %s %s", mirror.method, mirror.uri)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestShadowMiddlewareRedactsFormBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	target, captured := newShadowTarget(t)
+	cfg := DefaultShadowConfig(target.URL, 1)
+	cfg.RedactFields = []string{"card_number"}
+	router := gin.New()
+	router.Use(ShadowMiddleware(cfg))
+	router.POST("/orders", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	receive := func() shadowCapture {
+		t.Helper()
+		select {
+		case mirror := <-captured:
+			return mirror
+		case <-time.After(2 * time.Second):
+			t.Fatal("shadow request was not sent")
+			return shadowCapture{}
+		}
+	}
+
+	form := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("item=book&Card_Number=4111111111111111"))
+	form.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(httptest.NewRecorder(), form)
+	values, err := url.ParseQuery(string(receive().body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values.Get("Card_Number") != ShadowRedactedValue || values.Get("item") != "book" {
+		t.Fatalf("mirrored form = %v", values)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	_ = writer.WriteField("item", "book")
+	_ = writer.WriteField("card_number", "4111111111111111")
+	file, _ := writer.CreateFormFile("card_number", "card.txt")
+	_, _ = file.Write([]byte("4111111111111111"))
+	_ = writer.Close()
+	upload := httptest.NewRequest(http.MethodPost, "/orders", &body)
+	upload.Header.Set("Content-Type", writer.FormDataContentType())
+	router.ServeHTTP(httptest.NewRecorder(), upload)
+	mirror := receive()
+	if bytes.Contains(mirror.body, []byte("4111111111111111")) {
+		t.Fatalf("mirrored multipart body leaks the card number: %s", mirror.body)
+	}
+	parsed, err := multipart.NewReader(bytes.NewReader(mirror.body), writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Value["item"][0] != "book" || parsed.Value["card_number"][0] != ShadowRedactedValue || len(parsed.File["card_number"]) != 1 {
+		t.Fatalf("mirrored multipart form = %v, files %v", parsed.Value, parsed.File)
+	}
+
+	// Bodies that cannot be redacted are not mirrored at all.
+	for _, contentType := range []string{"text/plain", "application/json", ""} {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`card_number=4111111111111111`))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	select {
+	case mirror := <-captured:
+		t.Fatalf("unredactable body mirrored: %s", mirror.body)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mountPathParam is the catch-all parameter used to route requests into a mounted app.
+const mountPathParam = "corelab_mount_path"
+
+// Mount serves app under prefix on engine, enabling modular-monolith composition
+// of independently built sub-applications on one listener.
+//
+// app is typically another *gin.Engine with its own middleware chain, but any
+// http.Handler works. The prefix is stripped before the request reaches app, so
+// a sub-app registering "/charges" is reachable at "/payments/charges".
+//
+// The sub-app's middleware only runs for its own routes. Middleware registered
+// on engine before Mount (request ID, access logging, recovery, ...) still wraps
+// the mounted app, so sub-apps should usually be built with gin.New() plus only
+// their app-specific middleware rather than with NewEngine.
+//
+// Mounting at "/" hands every path engine does not route to app through
+// engine.NoRoute, replacing the error-envelope 404 handler NewEngine installs:
+// app answers unmatched paths itself, so a gin sub-app should register
+// middleware.NoRouteHandler to keep the envelope.
+//
+// Usage:
+//
+//	payments := gin.New()
+//	payments.Use(paymentsAuth)
+//	payments.POST("/charges", createCharge)
+//	server.Mount(engine, "/payments", payments)
+func Mount(engine *gin.Engine, prefix string, app http.Handler) {
+	if engine == nil || app == nil {
+		return
+	}
+	prefix = normalizeMountPrefix(prefix)
+	if prefix == "/" {
+		engine.NoRoute(mountHandler(prefix, app))
+		return
+	}
+
+	handler := mountHandler(prefix, app)
+	engine.Any(prefix, handler)
+	engine.Any(prefix+"/*"+mountPathParam, handler)
+}
+
+// mountHandler adapts app into a gin handler that strips prefix from the request path.
+func mountHandler(prefix string, app http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		app.ServeHTTP(c.Writer, stripMountPrefix(c.Request, prefix))
+	}
+}
+
+// stripMountPrefix returns a shallow copy of req whose URL path is relative to prefix.
+func stripMountPrefix(req *http.Request, prefix string) *http.Request {
+	if prefix == "/" || req == nil || req.URL == nil {
+		return req
+	}
+
+	out := new(http.Request)
+	*out = *req
+	out.URL = new(url.URL)
+	*out.URL = *req.URL
+
+	out.URL.Path = ensureLeadingSlash(strings.TrimPrefix(req.URL.Path, prefix))
+	if req.URL.RawPath != "" {
+		out.URL.RawPath = ensureLeadingSlash(strings.TrimPrefix(req.URL.RawPath, prefix))
+	}
+	return out
+}
+
+func normalizeMountPrefix(prefix string) string {
+	prefix = strings.TrimSpace(prefix)
+	prefix = strings.TrimRight(prefix, "/")
+	return ensureLeadingSlash(prefix)
+}
+
+func ensureLeadingSlash(path string) string {
+	if path == "" {
+		return "/"
+	}
+	if !strings.HasPrefix(path, "/") {
+		return "/" + path
+	}
+	return path
+}
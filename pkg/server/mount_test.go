@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMountStripsPrefix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		prefix      string
+		target      string
+		wantPath    string
+		wantRawPath string
+	}{
+		{name: "nested path", prefix: "/payments", target: "/payments/charges", wantPath: "/charges"},
+		{name: "bare prefix", prefix: "/payments", target: "/payments", wantPath: "/"},
+		{name: "bare prefix with trailing slash", prefix: "/payments", target: "/payments/", wantPath: "/"},
+		{name: "prefix normalized", prefix: " payments/ ", target: "/payments/charges", wantPath: "/charges"},
+		{name: "escaped path", prefix: "/files", target: "/files/docs/a%2Fb", wantPath: "/docs/a/b", wantRawPath: "/docs/a%2Fb"},
+		{name: "root", prefix: "/", target: "/charges", wantPath: "/charges"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, rawPath string
+			app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path, rawPath = r.URL.Path, r.URL.RawPath
+				w.WriteHeader(http.StatusNoContent)
+			})
+			engine := gin.New()
+			Mount(engine, tt.prefix, app)
+
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.target, nil))
+			if rec.Code != http.StatusNoContent {
+				t.Fatalf("status = %d, want the mounted app to answer", rec.Code)
+			}
+			if path != tt.wantPath || rawPath != tt.wantRawPath {
+				t.Fatalf("app saw path %q (raw %q), want %q (raw %q)", path, rawPath, tt.wantPath, tt.wantRawPath)
+			}
+		})
+	}
+}
+
+func TestMountAtRootServesUnroutedPaths(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	Mount(engine, "/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	for target, want := range map[string]int{"/health": http.StatusOK, "/unknown": http.StatusTeapot} {
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != want {
+			t.Fatalf("GET %s = %d, want %d", target, rec.Code, want)
+		}
+	}
+}
+
+func TestMountIgnoresOtherPrefixes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	Mount(engine, "/payments", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/paymentsx/charges", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("GET /paymentsx/charges = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+// ClientCertPolicy restricts which verified client certificates may complete
+// the TLS handshake. Empty allow-lists impose no restriction; when several are
+// set, a certificate matching any one of them is accepted.
+type ClientCertPolicy struct {
+	// Optional verifies client certificates only when presented, so routes can
+	// mix certificate and bearer-token callers (see auth.ClientCertAuth).
+	Optional bool
+	// AllowedCommonNames lists accepted subject common names, matched
+	// case-insensitively.
+	AllowedCommonNames []string
+	// AllowedDNSNames lists accepted DNS SANs, matched case-insensitively.
+	AllowedDNSNames []string
+	// AllowedURIs lists accepted URI SANs, e.g. SPIFFE IDs such as
+	// "spiffe://platform.internal/ns/billing/sa/api". They are matched
+	// exactly, since URI paths are case-sensitive.
+	AllowedURIs []string
+}
+
+func (p ClientCertPolicy) restricted() bool {
+	return len(p.AllowedCommonNames) > 0 || len(p.AllowedDNSNames) > 0 || len(p.AllowedURIs) > 0
+}
+
+// allows reports whether cert matches at least one allow-list entry.
+func (p ClientCertPolicy) allows(cert *x509.Certificate) bool {
+	if !p.restricted() {
+		return true
+	}
+	if containsFold(p.AllowedCommonNames, cert.Subject.CommonName) {
+		return true
+	}
+	for _, name := range cert.DNSNames {
+		if containsFold(p.AllowedDNSNames, name) {
+			return true
+		}
+	}
+	for _, uri := range cert.URIs {
+		if containsExact(p.AllowedURIs, uri.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyConnection is installed as tls.Config.VerifyConnection. It runs after
+// chain verification, so only certificates signed by the client CA reach it.
+func (p ClientCertPolicy) verifyConnection(cs tls.ConnectionState) error {
+	if len(cs.VerifiedChains) == 0 || len(cs.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	leaf := cs.VerifiedChains[0][0]
+	if !p.allows(leaf) {
+		return fmt.Errorf("mTLS: client certificate %q is not allowed by policy", leaf.Subject.CommonName)
+	}
+	return nil
+}
+
+func containsFold(values []string, target string) bool {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return false
+	}
+	for _, value := range values {
+		if strings.EqualFold(strings.TrimSpace(value), target) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsExact(values []string, target string) bool {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return false
+	}
+	for _, value := range values {
+		if strings.TrimSpace(value) == target {
+			return true
+		}
+	}
+	return false
+}
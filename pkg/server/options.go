@@ -1,6 +1,7 @@
 package server
 
 import (
+	"net"
 	"time"
 
 	coreaudit "github.com/milan604/core-lab/pkg/audit"
@@ -30,8 +31,16 @@ type startOptions struct {
 	// mTLS — optional CA for verifying client certificates
 	tlsClientCAFile   string
 	tlsClientAuthMode int
+	tlsClientPolicy   *ClientCertPolicy
+
+	// connection limits; see StartWithMaxConnections
+	maxConns          int
+	readHeaderTimeout time.Duration
+	idleTimeout       time.Duration
 
 	addr string
+
+	onListen func(addr net.Addr, err error)
 }
 
 // StartWithConfig passes config to the server startup
@@ -54,6 +63,43 @@ func StartWithAddr(addr string) StartOption {
 	return func(o *startOptions) { o.addr = addr }
 }
 
+// StartWithOnListen calls fn once Start has bound its listener, with the
+// bound address, or with the error that kept it from binding. Use it to end
+// a server.bind startup phase or to signal readiness:
+//
+//	_, endBind := startup.Begin("server.bind")
+//	server.Start(engine, server.StartWithOnListen(func(_ net.Addr, err error) {
+//		endBind(err)
+//		startup.End(err)
+//	}))
+func StartWithOnListen(fn func(addr net.Addr, err error)) StartOption {
+	return func(o *startOptions) { o.onListen = fn }
+}
+
+// StartWithMaxConnections caps the number of open connections (unlimited by
+// default). A connection arriving at the cap takes the place of the
+// longest-idle keep-alive connection, or is closed immediately when every
+// connection is busy, so slow clients cannot pile up behind the limit. Falls
+// back to the service.max_connections config key.
+func StartWithMaxConnections(n int) StartOption {
+	return func(o *startOptions) { o.maxConns = n }
+}
+
+// StartWithReadHeaderTimeout bounds how long a client may take to send the
+// request headers, including the TLS handshake (5s by default), which cuts
+// off slowloris-style clients trickling headers. Falls back to the
+// service.read_header_timeout config key.
+func StartWithReadHeaderTimeout(d time.Duration) StartOption {
+	return func(o *startOptions) { o.readHeaderTimeout = d }
+}
+
+// StartWithIdleTimeout sets how long a keep-alive connection may wait for its
+// next request before it is closed (120s by default). Falls back to the
+// service.idle_timeout config key.
+func StartWithIdleTimeout(d time.Duration) StartOption {
+	return func(o *startOptions) { o.idleTimeout = d }
+}
+
 // StartWithTLS enables TLS with cert/key files
 func StartWithTLS(certFile, keyFile string) StartOption {
 	return func(o *startOptions) {
@@ -65,10 +111,24 @@ func StartWithTLS(certFile, keyFile string) StartOption {
 // StartWithMTLS enables mutual TLS. The server will require and verify
 // client certificates against the provided CA file. Must be used together
 // with StartWithTLS.
-func StartWithMTLS(clientCAFile string) StartOption {
+//
+// An optional ClientCertPolicy further restricts accepted certificates by
+// subject or SAN during the handshake, or makes them optional:
+//
+//	server.StartWithMTLS("/etc/tls/clients-ca.pem", server.ClientCertPolicy{
+//		AllowedURIs: []string{"spiffe://platform.internal/ns/billing/sa/api"},
+//	})
+func StartWithMTLS(clientCAFile string, policy ...ClientCertPolicy) StartOption {
 	return func(o *startOptions) {
 		o.tlsClientCAFile = clientCAFile
 		o.tlsClientAuthMode = 1
+		if len(policy) > 0 {
+			p := policy[0]
+			o.tlsClientPolicy = &p
+			if p.Optional {
+				o.tlsClientAuthMode = 2
+			}
+		}
 	}
 }
 
@@ -91,6 +151,8 @@ type engineOptions struct {
 	recovery              bool
 	corsConfig            middleware.CorsConfig
 	prometheus            bool
+	runtimeMetricsOff     bool
+	tracing               gin.HandlerFunc
 	rateLimitConfig       *middleware.RateLimitConfig
 	securityHeadersConfig middleware.SecurityHeadersConfig
 	tenantStatusConfig    middleware.TenantStatusConfig
@@ -122,6 +184,18 @@ func WithPrometheus(enabled bool) EngineOption {
 	return func(e *engineOptions) { e.prometheus = enabled }
 }
 
+// WithRuntimeMetrics toggles the Go runtime and process collectors on the
+// Prometheus endpoint. They are on by default whenever Prometheus is enabled.
+func WithRuntimeMetrics(enabled bool) EngineOption {
+	return func(e *engineOptions) { e.runtimeMetricsOff = !enabled }
+}
+
+// WithTracing installs the tracing middleware ahead of the Prometheus
+// middleware, so request metrics carry exemplars linking to the active trace.
+func WithTracing(m gin.HandlerFunc) EngineOption {
+	return func(e *engineOptions) { e.tracing = m }
+}
+
 func WithValidator(vi *validator.Validator) EngineOption {
 	return func(e *engineOptions) {
 		e.addMiddleware = append(e.addMiddleware, middleware.ValidatorMiddleware(vi))
@@ -19,12 +19,14 @@ import (
 	middleware "github.com/milan604/core-lab/pkg/server/middleware"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // NewEngine creates a Gin engine with recommended middleware ordering and modular options.
 func NewEngine(opts ...EngineOption) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	engine := gin.New()
+	engine.HandleMethodNotAllowed = true
 
 	// Collect options
 	var opt engineOptions
@@ -60,8 +62,9 @@ func NewEngine(opts ...EngineOption) *gin.Engine {
 		engine.Use(middleware.CORSMiddleware(opt.corsConfig))
 	}
 
-	// 7. Rate Limiting (optional)
-	if opt.rateLimitConfig != nil && opt.rateLimitConfig.Enabled {
+	// 7. Rate Limiting (optional — installed while disabled too, so route
+	// policies apply and Update can switch the global budget on at runtime)
+	if opt.rateLimitConfig != nil {
 		engine.Use(opt.rateLimitConfig.Middleware())
 	}
 
@@ -70,26 +73,42 @@ func NewEngine(opts ...EngineOption) *gin.Engine {
 		engine.Use(middleware.TenantStatusMiddleware(opt.tenantStatusConfig))
 	}
 
-	// 9. Prometheus (optional)
+	// 9. Tracing (optional — before Prometheus so metrics get trace exemplars)
+	if opt.tracing != nil {
+		engine.Use(opt.tracing)
+	}
+
+	// 10. Prometheus (optional)
+	var registerer prometheus.Registerer
 	if opt.prometheus {
 		prom := middleware.NewPrometheusCollector("/metrics")
+		if !opt.runtimeMetricsOff {
+			if err := prom.RegisterRuntimeCollectors(); err != nil {
+				logMgr.WarnF("failed to register runtime metrics collectors: %v", err)
+			}
+		}
 		engine.Use(prom.PrometheusMiddleware())
 		prom.RegisterMetricsEndpoint(engine)
+		registerer = prom.Registerer()
 	}
 
-	// 10. Error Handler
+	// 11. Error Handler
 	engine.Use(middleware.ErrorHandlerMiddleware())
 
-	// 11. User-provided middlewares
+	// 12. User-provided middlewares
 	for _, m := range opt.addMiddleware {
 		engine.Use(m)
 	}
 
-	// 12. Recovery (optional, last)
+	// 13. Recovery (optional, last)
 	if opt.recovery {
 		engine.Use(middleware.RecoveryMiddleware(logMgr))
 	}
 
+	// Unmatched paths and methods answer with the standard error envelope.
+	engine.NoRoute(middleware.NoRouteHandler(registerer))
+	engine.NoMethod(middleware.NoMethodHandler(registerer))
+
 	return engine
 }
 
@@ -137,10 +156,31 @@ func logServiceInfo(addr string, logger logger.LogManager) {
 	fmt.Print(block)
 }
 
-func startHTTPServer(srv *http.Server, so *startOptions) {
+// resolveConnLimits fills unset connection limits from config and defaults.
+func resolveConnLimits(so *startOptions) {
+	if so.cfg != nil {
+		if so.maxConns == 0 {
+			so.maxConns = so.cfg.GetIntD("service.max_connections", 0)
+		}
+		if so.readHeaderTimeout == 0 {
+			so.readHeaderTimeout = so.cfg.GetDurationD("service.read_header_timeout", 0)
+		}
+		if so.idleTimeout == 0 {
+			so.idleTimeout = so.cfg.GetDurationD("service.idle_timeout", 0)
+		}
+	}
+	if so.readHeaderTimeout <= 0 {
+		so.readHeaderTimeout = 5 * time.Second
+	}
+	if so.idleTimeout <= 0 {
+		so.idleTimeout = 120 * time.Second
+	}
+}
+
+func startHTTPServer(srv *http.Server, ln net.Listener, so *startOptions) {
 	logServiceInfo(srv.Addr, so.logger)
 	fmt.Println("Server started 🚀")
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
 		if so.logger != nil {
 			so.logger.ErrorF("ListenAndServe error: %v", err)
 		} else {
@@ -149,13 +189,15 @@ func startHTTPServer(srv *http.Server, so *startOptions) {
 	}
 }
 
-func startTLSServer(srv *http.Server, so *startOptions) {
+func startTLSServer(srv *http.Server, ln net.Listener, so *startOptions) {
 	if _, err := os.Stat(so.tlsCertFile); err != nil {
 		log.Printf("TLS cert file error: %v", err)
+		_ = ln.Close()
 		return
 	}
 	if _, err := os.Stat(so.tlsKeyFile); err != nil {
 		log.Printf("TLS key file error: %v", err)
+		_ = ln.Close()
 		return
 	}
 
@@ -164,11 +206,13 @@ func startTLSServer(srv *http.Server, so *startOptions) {
 		caCert, err := os.ReadFile(so.tlsClientCAFile)
 		if err != nil {
 			log.Printf("mTLS CA file error: %v", err)
+			_ = ln.Close()
 			return
 		}
 		caPool := x509.NewCertPool()
 		if !caPool.AppendCertsFromPEM(caCert) {
 			log.Print("mTLS: failed to parse CA certificate")
+			_ = ln.Close()
 			return
 		}
 		clientAuthMode := tls.RequireAndVerifyClientCert
@@ -180,6 +224,9 @@ func startTLSServer(srv *http.Server, so *startOptions) {
 			ClientAuth: clientAuthMode,
 			MinVersion: tls.VersionTLS12,
 		}
+		if so.tlsClientPolicy != nil && so.tlsClientPolicy.restricted() {
+			srv.TLSConfig.VerifyConnection = so.tlsClientPolicy.verifyConnection
+		}
 		if so.tlsClientAuthMode == 2 {
 			fmt.Println("Server started 🚀 (TLS + optional client certificates)")
 		} else {
@@ -190,7 +237,7 @@ func startTLSServer(srv *http.Server, so *startOptions) {
 	}
 
 	logServiceInfo(srv.Addr, so.logger)
-	if err := srv.ListenAndServeTLS(so.tlsCertFile, so.tlsKeyFile); err != nil && err != http.ErrServerClosed {
+	if err := srv.ServeTLS(ln, so.tlsCertFile, so.tlsKeyFile); err != nil && err != http.ErrServerClosed {
 		if so.logger != nil {
 			so.logger.ErrorF("ListenAndServeTLS error: %v", err)
 		} else {
@@ -235,8 +282,16 @@ func Start(engine *gin.Engine, opts ...StartOption) error {
 	}
 
 	addr := resolveAddress(so)
+	resolveConnLimits(so)
 
 	ln, err := net.Listen("tcp", addr)
+	if so.onListen != nil {
+		if err != nil {
+			so.onListen(nil, err)
+		} else {
+			so.onListen(ln.Addr(), nil)
+		}
+	}
 	if err != nil {
 		if so.logger != nil {
 			so.logger.ErrorF("port %s is already in use: %v", addr, err)
@@ -245,21 +300,23 @@ func Start(engine *gin.Engine, opts ...StartOption) error {
 		}
 		return err
 	}
-	ln.Close()
+	limited := newConnLimitListener(ln, so.maxConns)
 
 	srv := &http.Server{
-		Addr:         addr,
-		Handler:      engine,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		Addr:              addr,
+		Handler:           engine,
+		ReadHeaderTimeout: so.readHeaderTimeout,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       so.idleTimeout,
+		ConnState:         limited.connState,
 	}
 
 	go func() {
 		if so.tlsCertFile != "" && so.tlsKeyFile != "" {
-			startTLSServer(srv, so)
+			startTLSServer(srv, limited, so)
 		} else {
-			startHTTPServer(srv, so)
+			startHTTPServer(srv, limited, so)
 		}
 	}()
 
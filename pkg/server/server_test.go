@@ -0,0 +1,91 @@
+package server
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milan604/core-lab/pkg/logger"
+	middleware "github.com/milan604/core-lab/pkg/server/middleware"
+)
+
+func serveRateLimited(engine http.Handler, path string) int {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = "203.0.113.7:1234"
+	engine.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestNewEngineEnforcesRoutePoliciesWhileRateLimitDisabled(t *testing.T) {
+	rl := middleware.NewRateLimitConfig(false, 0.001, 1, 0).
+		ForPrefix("/auth", middleware.RateLimitPolicy{RPS: 0.001, Burst: 1})
+	engine := NewEngine(WithLogger(logger.MustNewDefaultLogger()), WithRateLimit(rl))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	engine.GET("/auth/login", ok)
+	engine.GET("/orders", ok)
+
+	if code := serveRateLimited(engine, "/auth/login"); code != http.StatusOK {
+		t.Fatalf("first login = %d, want %d", code, http.StatusOK)
+	}
+	if code := serveRateLimited(engine, "/auth/login"); code != http.StatusTooManyRequests {
+		t.Fatalf("second login = %d, want the /auth policy to apply while the global limit is off", code)
+	}
+	for i := 0; i < 3; i++ {
+		if code := serveRateLimited(engine, "/orders"); code != http.StatusOK {
+			t.Fatalf("/orders = %d, want no global limit while disabled", code)
+		}
+	}
+}
+
+func TestNewEngineRateLimitDisabledAtStartupCanBeEnabled(t *testing.T) {
+	rl := middleware.NewRateLimitConfig(false, 0.001, 1, 0)
+	engine := NewEngine(WithLogger(logger.MustNewDefaultLogger()), WithRateLimit(rl))
+	engine.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for i := 0; i < 3; i++ {
+		if code := serveRateLimited(engine, "/orders"); code != http.StatusOK {
+			t.Fatalf("disabled limiter answered %d", code)
+		}
+	}
+	rl.Update(true, 0.001, 1)
+	if code := serveRateLimited(engine, "/orders"); code != http.StatusOK {
+		t.Fatalf("first request after enabling = %d, want %d", code, http.StatusOK)
+	}
+	if code := serveRateLimited(engine, "/orders"); code != http.StatusTooManyRequests {
+		t.Fatalf("second request after enabling = %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
+
+func TestClientCertPolicyAllows(t *testing.T) {
+	spiffeID, _ := url.Parse("spiffe://platform.internal/ns/billing/sa/api")
+	cert := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "billing-api"},
+		DNSNames: []string{"billing.platform.internal"},
+		URIs:     []*url.URL{spiffeID},
+	}
+
+	tests := []struct {
+		name   string
+		policy ClientCertPolicy
+		want   bool
+	}{
+		{name: "unrestricted", want: true},
+		{name: "common name ignores case", policy: ClientCertPolicy{AllowedCommonNames: []string{"Billing-API"}}, want: true},
+		{name: "dns name ignores case", policy: ClientCertPolicy{AllowedDNSNames: []string{"BILLING.platform.internal"}}, want: true},
+		{name: "uri exact", policy: ClientCertPolicy{AllowedURIs: []string{"spiffe://platform.internal/ns/billing/sa/api"}}, want: true},
+		{name: "uri differs in case", policy: ClientCertPolicy{AllowedURIs: []string{"spiffe://platform.internal/ns/billing/sa/API"}}, want: false},
+		{name: "no match", policy: ClientCertPolicy{AllowedCommonNames: []string{"orders-api"}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.allows(cert); got != tt.want {
+				t.Fatalf("allows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package servertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// UpdateEnv is the environment variable that makes AssertGoldenJSON write
+// golden files instead of comparing against them:
+//
+//	UPDATE_GOLDEN=1 go test ./...
+const UpdateEnv = "UPDATE_GOLDEN"
+
+// Placeholders substituted for redacted values.
+const (
+	RedactedTimestamp = "<timestamp>"
+	RedactedUUID      = "<uuid>"
+	Redacted          = "<redacted>"
+)
+
+// defaultRedactedKeys are object keys whose values change on every request.
+var defaultRedactedKeys = []string{"request_id", "trace_id", "span_id", "traceparent"}
+
+var (
+	timestampPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?$`)
+	uuidPattern      = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// GoldenOption configures AssertGoldenJSON.
+type GoldenOption func(*goldenOptions)
+
+type goldenOptions struct {
+	dir       string
+	keys      map[string]struct{}
+	noDefault bool
+}
+
+// GoldenDir sets the directory holding golden files ("testdata" by default,
+// relative to the package under test).
+func GoldenDir(dir string) GoldenOption {
+	return func(o *goldenOptions) { o.dir = dir }
+}
+
+// RedactKeys replaces the value of every object member named by one of keys,
+// at any depth, with "<redacted>". Keys match case-insensitively.
+func RedactKeys(keys ...string) GoldenOption {
+	return func(o *goldenOptions) {
+		for _, k := range keys {
+			o.keys[strings.ToLower(k)] = struct{}{}
+		}
+	}
+}
+
+// WithoutDefaultRedaction keeps timestamps, UUIDs, and request_id, trace_id,
+// span_id and traceparent values, which are redacted by default. Keys added
+// with RedactKeys are still redacted.
+func WithoutDefaultRedaction() GoldenOption {
+	return func(o *goldenOptions) { o.noDefault = true }
+}
+
+// AssertGoldenJSON compares the JSON document got with the golden file
+// <dir>/<name>.golden.json, failing the test with a line diff when they
+// differ. Volatile values are redacted first: RFC 3339 timestamps become
+// "<timestamp>", UUIDs "<uuid>", and request, trace and span IDs (plus any
+// RedactKeys) "<redacted>". Both sides are compared in indented form with
+// sorted keys, so golden files are stable and reviewable.
+//
+// With UPDATE_GOLDEN=1 set, the golden file is written from got instead. A
+// missing golden file fails the test with a hint to do so. name may contain
+// slashes to group files; it defaults to the test name.
+func AssertGoldenJSON(t testing.TB, name string, got []byte, opts ...GoldenOption) {
+	t.Helper()
+	o := goldenOptions{dir: "testdata", keys: map[string]struct{}{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if name == "" {
+		name = t.Name()
+	}
+	path := filepath.Join(o.dir, filepath.FromSlash(name)+".golden.json")
+
+	normalized, err := normalizeGolden(got, o)
+	if err != nil {
+		t.Fatalf("servertest: response is not JSON: %v; body=%s", err, got)
+	}
+
+	if update, _ := strconv.ParseBool(os.Getenv(UpdateEnv)); update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("servertest: %v", err)
+		}
+		if err := os.WriteFile(path, normalized, 0o644); err != nil {
+			t.Fatalf("servertest: %v", err)
+		}
+		t.Logf("servertest: updated %s", path)
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("servertest: golden file %s does not exist; run with %s=1 to create it", path, UpdateEnv)
+	}
+	if err != nil {
+		t.Fatalf("servertest: %v", err)
+	}
+	// Re-indent the golden file so hand edits need not match the formatting.
+	var wantDoc any
+	if err := decodeJSON(want, &wantDoc); err != nil {
+		t.Fatalf("servertest: golden file %s is not JSON: %v", path, err)
+	}
+	want, _ = marshalGolden(wantDoc)
+
+	if diff := lineDiff(want, normalized); diff != "" {
+		t.Fatalf("servertest: response differs from %s (-want +got):\n%s\nrun with %s=1 to accept the new response", path, diff, UpdateEnv)
+	}
+}
+
+// normalizeGolden redacts doc and renders it in golden form.
+func normalizeGolden(doc []byte, o goldenOptions) ([]byte, error) {
+	var v any
+	if err := decodeJSON(doc, &v); err != nil {
+		return nil, err
+	}
+	keys := o.keys
+	if !o.noDefault {
+		keys = make(map[string]struct{}, len(o.keys)+len(defaultRedactedKeys))
+		for k := range o.keys {
+			keys[k] = struct{}{}
+		}
+		for _, k := range defaultRedactedKeys {
+			keys[k] = struct{}{}
+		}
+	}
+	return marshalGolden(redact(v, keys, !o.noDefault))
+}
+
+func decodeJSON(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after the JSON value")
+	}
+	return nil
+}
+
+// marshalGolden indents v with two spaces; maps are written with sorted keys.
+func marshalGolden(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func redact(v any, keys map[string]struct{}, patterns bool) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			if _, ok := keys[strings.ToLower(k)]; ok && child != nil {
+				val[k] = Redacted
+				continue
+			}
+			val[k] = redact(child, keys, patterns)
+		}
+		return val
+	case []any:
+		for i, child := range val {
+			val[i] = redact(child, keys, patterns)
+		}
+		return val
+	case string:
+		if !patterns {
+			return val
+		}
+		switch {
+		case timestampPattern.MatchString(val):
+			return RedactedTimestamp
+		case uuidPattern.MatchString(val):
+			return RedactedUUID
+		}
+	}
+	return v
+}
+
+// lineDiff returns the lines of want and got that differ, prefixed with - and
+// +, with two lines of context, or "" when they are equal.
+func lineDiff(want, got []byte) string {
+	if bytes.Equal(want, got) {
+		return ""
+	}
+	wantLines := strings.Split(strings.TrimSuffix(string(want), "\n"), "\n")
+	gotLines := strings.Split(strings.TrimSuffix(string(got), "\n"), "\n")
+
+	// Longest common subsequence, enough for response-sized documents.
+	n, m := len(wantLines), len(gotLines)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if wantLines[i] == gotLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && wantLines[i] == gotLines[j]:
+			lines = append(lines, line{' ', wantLines[i]})
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', wantLines[i]})
+			i++
+		default:
+			lines = append(lines, line{'+', gotLines[j]})
+			j++
+		}
+	}
+
+	const context = 2
+	show := make([]bool, len(lines))
+	for k, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for c := max(k-context, 0); c <= min(k+context, len(lines)-1); c++ {
+			show[c] = true
+		}
+	}
+	var b strings.Builder
+	for k, l := range lines {
+		if !show[k] {
+			continue
+		}
+		if k > 0 && !show[k-1] && b.Len() > 0 {
+			b.WriteString("  ...\n")
+		}
+		fmt.Fprintf(&b, "%c %s\n", l.op, l.text)
+	}
+	return b.String()
+}
@@ -0,0 +1,109 @@
+package servertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newOrdersEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/orders", func(c *gin.Context) {
+		var req struct {
+			Reference string `json:"reference"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{
+			"success": true,
+			"data": gin.H{
+				"id":         "9b2f4c1e-6a1d-4e43-9c3f-8d2b7e0a5f11",
+				"reference":  req.Reference,
+				"created_at": "2026-10-16T09:30:00.123Z",
+				"lines":      []gin.H{{"sku": "A-1", "qty": 2}},
+			},
+			"meta": gin.H{"request_id": c.GetHeader("X-Request-ID"), "auth": c.GetHeader("Authorization")},
+		})
+	})
+	return engine
+}
+
+func TestHarnessGoldenRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	h := New(t, newOrdersEngine()).
+		WithHeader("Authorization", "Bearer token").
+		WithHeader("X-Request-ID", "req-123").
+		WithGoldenOptions(GoldenDir(dir))
+
+	t.Setenv(UpdateEnv, "1")
+	h.POST("/orders", map[string]string{"reference": "PO-1"}).AssertStatus(http.StatusCreated).AssertGolden("orders/create")
+
+	golden, err := os.ReadFile(filepath.Join(dir, "orders", "create.golden.json"))
+	if err != nil {
+		t.Fatalf("golden file not written: %v", err)
+	}
+	for _, want := range []string{`"id": "<uuid>"`, `"created_at": "<timestamp>"`, `"request_id": "<redacted>"`, `"auth": "Bearer token"`, `"qty": 2`} {
+		if !strings.Contains(string(golden), want) {
+			t.Fatalf("golden file missing %s:\n%s", want, golden)
+		}
+	}
+
+	// Hand-formatted golden files compare equal once re-indented.
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, golden); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "orders", "create.golden.json"), compact.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(UpdateEnv, "")
+	h.WithHeader("X-Request-ID", "req-456").
+		POST("/orders", `{"reference":"PO-1"}`).
+		AssertStatus(http.StatusCreated).
+		AssertGolden("orders/create")
+}
+
+func TestNormalizeGoldenRedaction(t *testing.T) {
+	doc := []byte(`{"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","Token":"abc","at":"2026-10-16 09:30:00","nested":[{"token":null}]}`)
+
+	got, err := normalizeGolden(doc, goldenOptions{keys: map[string]struct{}{"token": {}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"trace_id": "<redacted>"`, `"Token": "<redacted>"`, `"at": "<timestamp>"`, `"token": null`} {
+		if !strings.Contains(string(got), want) {
+			t.Fatalf("normalized document missing %s:\n%s", want, got)
+		}
+	}
+
+	got, err = normalizeGolden(doc, goldenOptions{keys: map[string]struct{}{}, noDefault: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), `"at": "2026-10-16 09:30:00"`) || !strings.Contains(string(got), `"trace_id": "4bf92f`) {
+		t.Fatalf("WithoutDefaultRedaction redacted values:\n%s", got)
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	want := "{\n  \"a\": 1,\n  \"b\": 2,\n  \"c\": 3,\n  \"d\": 4,\n  \"e\": 5,\n  \"f\": 6\n}\n"
+	got := strings.Replace(want, `"b": 2`, `"b": 20`, 1)
+
+	diff := lineDiff([]byte(want), []byte(got))
+	wantDiff := "  {\n    \"a\": 1,\n-   \"b\": 2,\n+   \"b\": 20,\n    \"c\": 3,\n    \"d\": 4,\n"
+	if diff != wantDiff {
+		t.Fatalf("lineDiff() =\n%s\nwant\n%s", diff, wantDiff)
+	}
+	if diff := lineDiff([]byte(want), []byte(want)); diff != "" {
+		t.Fatalf("lineDiff(equal) = %q", diff)
+	}
+}
@@ -0,0 +1,156 @@
+// Package servertest drives a Gin engine, or any http.Handler, in tests and
+// compares JSON responses with golden files, so API contract tests stay a few
+// lines each.
+//
+//	h := servertest.New(t, server.NewEngine(...)).WithHeader("Authorization", "Bearer "+token)
+//	h.GET("/orders/42").AssertStatus(http.StatusOK).AssertGolden("orders/get")
+//
+// Golden files live under testdata and are rewritten when the tests run with
+// UPDATE_GOLDEN=1.
+package servertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Harness sends requests to a handler in-process.
+type Harness struct {
+	t       testing.TB
+	handler http.Handler
+	header  http.Header
+	golden  []GoldenOption
+}
+
+// New creates a Harness for handler, typically a *gin.Engine.
+func New(t testing.TB, handler http.Handler) *Harness {
+	t.Helper()
+	return &Harness{t: t, handler: handler, header: http.Header{}}
+}
+
+// WithHeader sets a header sent with every request, e.g. Authorization.
+func (h *Harness) WithHeader(key, value string) *Harness {
+	h.header.Set(key, value)
+	return h
+}
+
+// WithGoldenOptions sets options applied to every AssertGolden call of the
+// harness's responses, before the call's own options.
+func (h *Harness) WithGoldenOptions(opts ...GoldenOption) *Harness {
+	h.golden = append(h.golden, opts...)
+	return h
+}
+
+// GET sends a GET request.
+func (h *Harness) GET(path string) *Response {
+	h.t.Helper()
+	return h.Do(http.MethodGet, path, nil)
+}
+
+// POST sends a POST request with body; see Do.
+func (h *Harness) POST(path string, body any) *Response {
+	h.t.Helper()
+	return h.Do(http.MethodPost, path, body)
+}
+
+// PUT sends a PUT request with body; see Do.
+func (h *Harness) PUT(path string, body any) *Response {
+	h.t.Helper()
+	return h.Do(http.MethodPut, path, body)
+}
+
+// PATCH sends a PATCH request with body; see Do.
+func (h *Harness) PATCH(path string, body any) *Response {
+	h.t.Helper()
+	return h.Do(http.MethodPatch, path, body)
+}
+
+// DELETE sends a DELETE request.
+func (h *Harness) DELETE(path string) *Response {
+	h.t.Helper()
+	return h.Do(http.MethodDelete, path, nil)
+}
+
+// Do sends a request and records the response. body may be nil, a string,
+// []byte, or io.Reader sent as is, or any other value encoded as JSON with
+// Content-Type application/json.
+func (h *Harness) Do(method, path string, body any) *Response {
+	h.t.Helper()
+	reader, isJSON := h.requestBody(body)
+	req := httptest.NewRequest(method, path, reader)
+	for key, values := range h.header {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	if isJSON && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return h.Serve(req)
+}
+
+// Serve records the response to a request built by the caller. Headers set
+// with WithHeader are not added.
+func (h *Harness) Serve(req *http.Request) *Response {
+	h.t.Helper()
+	rec := httptest.NewRecorder()
+	h.handler.ServeHTTP(rec, req)
+	return &Response{ResponseRecorder: rec, t: h.t, golden: h.golden, method: req.Method, path: req.URL.RequestURI()}
+}
+
+func (h *Harness) requestBody(body any) (io.Reader, bool) {
+	h.t.Helper()
+	switch b := body.(type) {
+	case nil:
+		return nil, false
+	case string:
+		return strings.NewReader(b), false
+	case []byte:
+		return bytes.NewReader(b), false
+	case io.Reader:
+		return b, false
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		h.t.Fatalf("servertest: encode request body: %v", err)
+	}
+	return bytes.NewReader(data), true
+}
+
+// Response is a recorded response with assertion helpers. Assertions report
+// through the test's Fatalf, so a failed one stops the test.
+type Response struct {
+	*httptest.ResponseRecorder
+	t      testing.TB
+	golden []GoldenOption
+	method string
+	path   string
+}
+
+// AssertStatus fails the test unless the response has status code.
+func (r *Response) AssertStatus(code int) *Response {
+	r.t.Helper()
+	if r.Code != code {
+		r.t.Fatalf("%s %s status = %d, want %d; body=%s", r.method, r.path, r.Code, code, r.Body.String())
+	}
+	return r
+}
+
+// AssertGolden compares the JSON body with the golden file for name; see
+// AssertGoldenJSON.
+func (r *Response) AssertGolden(name string, opts ...GoldenOption) *Response {
+	r.t.Helper()
+	AssertGoldenJSON(r.t, name, r.Body.Bytes(), append(append([]GoldenOption(nil), r.golden...), opts...)...)
+	return r
+}
+
+// DecodeJSON decodes the body into v, failing the test on error.
+func (r *Response) DecodeJSON(v any) {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body.Bytes(), v); err != nil {
+		r.t.Fatalf("%s %s: decode response: %v; body=%s", r.method, r.path, err, r.Body.String())
+	}
+}
@@ -98,42 +98,85 @@ func RequestContextFromContext(ctx context.Context) (RequestContext, bool) {
 	return rc, true
 }
 
+// reservedMetadataKeys are the keys MergeMetadata owns.
+var reservedMetadataKeys = []string{
+	MetadataTenantID,
+	MetadataActorUserID,
+	MetadataServiceID,
+	MetadataCorrelationID,
+	MetadataIsSuperAdmin,
+	MetadataInitiator,
+	MetadataSource,
+}
+
+// MergeMetadata records rc in metadata under the Metadata* keys. rc is the
+// authenticated context, so its values replace any the caller put there, and
+// is_super_admin is removed unless rc is a super admin.
 func MergeMetadata(metadata map[string]string, rc RequestContext) map[string]string {
 	rc = rc.Normalize()
 	if metadata == nil {
 		metadata = make(map[string]string)
 	}
 
-	setIfEmpty := func(key, value string) {
-		if strings.TrimSpace(metadata[key]) != "" {
-			return
-		}
-		value = strings.TrimSpace(value)
+	set := func(key, value string) {
 		if value == "" {
 			return
 		}
 		metadata[key] = value
 	}
 
-	setIfEmpty(MetadataTenantID, rc.TenantID)
-	setIfEmpty(MetadataActorUserID, rc.ActorUserID)
-	setIfEmpty(MetadataServiceID, rc.ServiceID)
-	setIfEmpty(MetadataCorrelationID, rc.CorrelationID)
-	if _, exists := metadata[MetadataIsSuperAdmin]; !exists && rc.IsSuperAdmin {
+	set(MetadataTenantID, rc.TenantID)
+	set(MetadataActorUserID, rc.ActorUserID)
+	set(MetadataServiceID, rc.ServiceID)
+	set(MetadataCorrelationID, rc.CorrelationID)
+	if rc.IsSuperAdmin {
 		metadata[MetadataIsSuperAdmin] = strconv.FormatBool(true)
+	} else {
+		delete(metadata, MetadataIsSuperAdmin)
 	}
-	if strings.TrimSpace(metadata[MetadataSource]) == "" && rc.ServiceID != "" {
-		metadata[MetadataSource] = rc.ServiceID
-	}
-	if strings.TrimSpace(metadata[MetadataInitiator]) == "" {
-		switch {
-		case rc.ActorUserID != "":
-			metadata[MetadataInitiator] = "user:" + rc.ActorUserID
-		case rc.ServiceID != "":
-			metadata[MetadataInitiator] = "service:" + rc.ServiceID
-		case rc.IsSuperAdmin:
-			metadata[MetadataInitiator] = "super-admin"
-		}
+	set(MetadataSource, rc.ServiceID)
+	switch {
+	case rc.ActorUserID != "":
+		metadata[MetadataInitiator] = "user:" + rc.ActorUserID
+	case rc.ServiceID != "":
+		metadata[MetadataInitiator] = "service:" + rc.ServiceID
+	case rc.IsSuperAdmin:
+		metadata[MetadataInitiator] = "super-admin"
 	}
 	return metadata
 }
+
+// StripReservedMetadata returns a copy of metadata without the Metadata*
+// keys. Use it on metadata from untrusted input, e.g. a request body, so a
+// caller cannot claim another tenant or super admin rights for work that
+// RequestContextFromMetadata later restores.
+func StripReservedMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return nil
+	}
+	out := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		out[k] = v
+	}
+	for _, key := range reservedMetadataKeys {
+		delete(out, key)
+	}
+	return out
+}
+
+// RequestContextFromMetadata is the inverse of MergeMetadata. Background
+// workers use it to restore the request context a job or event was created in.
+func RequestContextFromMetadata(metadata map[string]string) (RequestContext, bool) {
+	isSuperAdmin, _ := strconv.ParseBool(strings.TrimSpace(metadata[MetadataIsSuperAdmin]))
+	rc := RequestContext{
+		TenantID:      metadata[MetadataTenantID],
+		ActorUserID:   metadata[MetadataActorUserID],
+		ServiceID:     metadata[MetadataServiceID],
+		CorrelationID: metadata[MetadataCorrelationID],
+		IsSuperAdmin:  isSuperAdmin,
+	}.Normalize()
+	if rc.IsEmpty() {
+		return RequestContext{}, false
+	}
+	return rc, true
+}
@@ -68,3 +68,44 @@ func TestMergeMetadataAddsCanonicalFields(t *testing.T) {
 		t.Fatalf("initiator metadata = %q, want user:user-1", metadata[MetadataInitiator])
 	}
 }
+
+func TestRequestContextFromMetadataRoundTrip(t *testing.T) {
+	want := RequestContext{
+		TenantID:      "tenant-1",
+		ActorUserID:   "user-1",
+		ServiceID:     "sites-service",
+		CorrelationID: "req-1",
+		IsSuperAdmin:  true,
+	}
+	got, ok := RequestContextFromMetadata(MergeMetadata(nil, want))
+	if !ok || got != want {
+		t.Fatalf("RequestContextFromMetadata() = %+v, %v; want %+v", got, ok, want)
+	}
+	if _, ok := RequestContextFromMetadata(map[string]string{"other": "x"}); ok {
+		t.Fatalf("expected no request context without canonical keys")
+	}
+}
+
+func TestMergeMetadataOverridesCallerIdentity(t *testing.T) {
+	forged := map[string]string{
+		MetadataTenantID:     "tenant-2",
+		MetadataIsSuperAdmin: "true",
+		MetadataInitiator:    "super-admin",
+		"existing":           "value",
+	}
+	metadata := MergeMetadata(forged, RequestContext{TenantID: "tenant-1", ActorUserID: "user-1"})
+	if metadata[MetadataTenantID] != "tenant-1" {
+		t.Fatalf("tenant metadata = %q, want tenant-1", metadata[MetadataTenantID])
+	}
+	if _, ok := metadata[MetadataIsSuperAdmin]; ok {
+		t.Fatalf("forged is_super_admin survived: %v", metadata)
+	}
+	if metadata[MetadataInitiator] != "user:user-1" || metadata["existing"] != "value" {
+		t.Fatalf("metadata = %v", metadata)
+	}
+
+	stripped := StripReservedMetadata(forged)
+	if len(stripped) != 1 || stripped["existing"] != "value" {
+		t.Fatalf("StripReservedMetadata() = %v", stripped)
+	}
+}
@@ -0,0 +1,226 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/milan604/core-lab/pkg/apperr"
+
+	gvalidator "github.com/go-playground/validator/v10"
+)
+
+// Tags registered by RegisterDefaults.
+const (
+	// TagUUID4 accepts an RFC 4122 version 4 UUID in either case.
+	TagUUID4 = "uuid4"
+	// TagULID accepts a ULID in Crockford base32, in either case.
+	TagULID = "ulid"
+	// TagSlug accepts lowercase letters and digits in dash-separated words,
+	// e.g. summer-sale-2024.
+	TagSlug = "slug"
+	// TagPhone accepts an E.164 phone number, e.g. +14155552671.
+	TagPhone = "phone"
+	// TagStrongPassword requires upper and lower case letters, a digit and a
+	// symbol, and at least DefaultPasswordMinLength characters;
+	// strong_password=12 raises the minimum.
+	TagStrongPassword = "strong_password"
+	// TagIBAN accepts an IBAN with a valid check digit, with or without the
+	// spaces of its printed form.
+	TagIBAN = "iban"
+	// TagCountryCode accepts an uppercase ISO 3166-1 alpha-2 country code.
+	TagCountryCode = "country_code"
+	// TagTimezone accepts an IANA time zone name, e.g. Europe/Berlin.
+	TagTimezone = "timezone"
+	// TagSemver accepts a semantic version, e.g. 1.4.2 or 2.0.0-rc.1; a
+	// leading v is allowed.
+	TagSemver = "semver"
+	// TagNotBlank rejects strings that are empty or only whitespace, and
+	// empty slices and maps.
+	TagNotBlank = "not_blank"
+)
+
+// DefaultPasswordMinLength is the minimum length of strong_password without
+// a param.
+const DefaultPasswordMinLength = 8
+
+var (
+	uuid4Re  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-4[0-9a-fA-F]{3}-[89abAB][0-9a-fA-F]{3}-[0-9a-fA-F]{12}$`)
+	ulidRe   = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Za-hjkmnp-tv-z]{25}$`)
+	slugRe   = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	phoneRe  = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+	semverRe = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+	ibanRe   = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+)
+
+// ibanLengths holds the IBAN length of the countries in the SWIFT IBAN
+// registry. IBANs of other countries only need a valid check digit.
+var ibanLengths = map[string]int{
+	"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16, "BG": 22,
+	"BH": 22, "BR": 29, "BY": 28, "CH": 21, "CR": 22, "CY": 28, "CZ": 24, "DE": 22,
+	"DK": 18, "DO": 28, "EE": 20, "EG": 29, "ES": 24, "FI": 18, "FO": 18, "FR": 27,
+	"GB": 22, "GE": 22, "GI": 23, "GL": 18, "GR": 27, "GT": 28, "HR": 21, "HU": 28,
+	"IE": 22, "IL": 23, "IQ": 23, "IS": 26, "IT": 27, "JO": 30, "KW": 30, "KZ": 20,
+	"LB": 28, "LC": 32, "LI": 21, "LT": 20, "LU": 20, "LV": 21, "MC": 27, "MD": 24,
+	"ME": 22, "MK": 19, "MR": 27, "MT": 31, "MU": 30, "NL": 18, "NO": 15, "PK": 24,
+	"PL": 28, "PS": 29, "PT": 25, "QA": 29, "RO": 24, "RS": 22, "SA": 24, "SC": 31,
+	"SE": 24, "SI": 19, "SK": 24, "SM": 27, "ST": 25, "SV": 28, "TL": 23, "TN": 24,
+	"TR": 26, "UA": 29, "VA": 22, "VG": 24, "XK": 20,
+}
+
+// RegisterDefaults registers the Tag* validations above on the engine, each
+// with a default message, so services don't redefine them. uuid4, ulid,
+// timezone, semver and country_code replace the engine's built-in tags of the
+// same name. Messages can be replaced afterwards with RegisterTagError.
+//
+//	type Body struct {
+//		Handle   string `json:"handle" binding:"required,slug"`
+//		Phone    string `json:"phone" binding:"omitempty,phone"`
+//		Password string `json:"password" binding:"required,strong_password=12"`
+//	}
+func (vi *Validator) RegisterDefaults() error {
+	validations := []struct {
+		tag     string
+		fn      gvalidator.Func
+		message func(gvalidator.FieldError) string
+	}{
+		{TagUUID4, stringMatches(uuid4Re), fixedMessage("%s must be a UUID v4")},
+		{TagULID, stringMatches(ulidRe), fixedMessage("%s must be a ULID")},
+		{TagSlug, stringMatches(slugRe), fixedMessage("%s must be lowercase letters and digits separated by single dashes, e.g. summer-sale")},
+		{TagPhone, stringMatches(phoneRe), fixedMessage("%s must be an E.164 phone number, e.g. +14155552671")},
+		{TagStrongPassword, validateStrongPassword, strongPasswordMessage},
+		{TagIBAN, validateIBAN, fixedMessage("%s must be a valid IBAN")},
+		{TagTimezone, validateTimezone, fixedMessage("%s must be an IANA time zone, e.g. Europe/Berlin")},
+		{TagSemver, stringMatches(semverRe), fixedMessage("%s must be a semantic version, e.g. 1.4.2")},
+		{TagNotBlank, validateNotBlank, fixedMessage("%s must not be blank")},
+	}
+	for _, v := range validations {
+		if err := vi.v.RegisterValidation(v.tag, v.fn); err != nil {
+			return fmt.Errorf("validator: register %s: %w", v.tag, err)
+		}
+		vi.RegisterTagError(v.tag, apperr.ErrorCodeValidationFail, v.message)
+	}
+	// country_code is a built-in alias, which takes precedence over a
+	// validation of the same name, so narrow the alias instead.
+	vi.v.RegisterAlias(TagCountryCode, "iso3166_1_alpha2")
+	vi.RegisterTagError(TagCountryCode, apperr.ErrorCodeValidationFail, fixedMessage("%s must be an ISO 3166-1 alpha-2 country code, e.g. DE"))
+	return nil
+}
+
+func fixedMessage(format string) func(gvalidator.FieldError) string {
+	return func(fe gvalidator.FieldError) string {
+		return fmt.Sprintf(format, fe.Field())
+	}
+}
+
+// stringMatches validates string fields against re.
+func stringMatches(re *regexp.Regexp) gvalidator.Func {
+	return func(fl gvalidator.FieldLevel) bool {
+		field := fl.Field()
+		return field.Kind() == reflect.String && re.MatchString(field.String())
+	}
+}
+
+func validateStrongPassword(fl gvalidator.FieldLevel) bool {
+	field := fl.Field()
+	if field.Kind() != reflect.String {
+		return false
+	}
+	minLength, ok := passwordMinLength(fl.Param())
+	if !ok {
+		return false
+	}
+	password := field.String()
+	var upper, lower, digit, symbol bool
+	length := 0
+	for _, r := range password {
+		length++
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	return length >= minLength && upper && lower && digit && symbol
+}
+
+func strongPasswordMessage(fe gvalidator.FieldError) string {
+	minLength, ok := passwordMinLength(fe.Param())
+	if !ok {
+		minLength = DefaultPasswordMinLength
+	}
+	return fmt.Sprintf("%s must be at least %d characters with upper and lower case letters, a digit and a symbol", fe.Field(), minLength)
+}
+
+func passwordMinLength(param string) (int, bool) {
+	if param == "" {
+		return DefaultPasswordMinLength, true
+	}
+	n, err := strconv.Atoi(param)
+	return n, err == nil && n > 0
+}
+
+// validateIBAN checks the format, the country's length when known, and the
+// ISO 7064 mod 97-10 check digits.
+func validateIBAN(fl gvalidator.FieldLevel) bool {
+	field := fl.Field()
+	if field.Kind() != reflect.String {
+		return false
+	}
+	iban := strings.ReplaceAll(field.String(), " ", "")
+	if !ibanRe.MatchString(iban) {
+		return false
+	}
+	if length, ok := ibanLengths[iban[:2]]; ok && len(iban) != length {
+		return false
+	}
+	// Move the country and check digits to the end and read letters as two
+	// digits (A=10 ... Z=35); the number mod 97 must be 1.
+	remainder := 0
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' && r <= 'Z' {
+			remainder = (remainder*100 + int(r-'A') + 10) % 97
+		} else {
+			remainder = (remainder*10 + int(r-'0')) % 97
+		}
+	}
+	return remainder == 1
+}
+
+func validateTimezone(fl gvalidator.FieldLevel) bool {
+	field := fl.Field()
+	if field.Kind() != reflect.String {
+		return false
+	}
+	// LoadLocation maps "" to UTC and "Local" to the host's zone, neither of
+	// which is a time zone name a client should send.
+	name := field.String()
+	if name == "" || strings.EqualFold(name, "local") {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+func validateNotBlank(fl gvalidator.FieldLevel) bool {
+	field := fl.Field()
+	switch field.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(field.String()) != ""
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return field.Len() > 0
+	case reflect.Pointer, reflect.Interface:
+		return !field.IsNil()
+	default:
+		return !field.IsZero()
+	}
+}
@@ -0,0 +1,94 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	gvalidator "github.com/go-playground/validator/v10"
+)
+
+// EnumTag is the validation tag for string enums declared as Go types:
+//
+//	type OrderStatus string
+//
+//	func (OrderStatus) Values() []string { return []string{"pending", "paid", "shipped"} }
+//
+//	type Body struct {
+//		Status OrderStatus `json:"status" binding:"required,enum"`
+//	}
+//
+// It behaves like oneof with the type's Values, so the allowed set is declared
+// once. Combine with omitempty for optional fields and dive for slices.
+const EnumTag = "enum"
+
+// Enum is implemented by string types with a fixed set of allowed values.
+type Enum interface {
+	Values() []string
+}
+
+// IsEnumValue reports whether value is one of its type's Values.
+func IsEnumValue(value Enum) bool {
+	current := reflect.ValueOf(value)
+	if current.Kind() != reflect.String {
+		return false
+	}
+	for _, allowed := range value.Values() {
+		if current.String() == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func registerEnum(v *gvalidator.Validate) error {
+	return v.RegisterValidation(EnumTag, validateEnum)
+}
+
+func validateEnum(fl gvalidator.FieldLevel) bool {
+	enum, ok := enumFor(fl.Field())
+	if !ok {
+		return false
+	}
+	value := fl.Field().String()
+	for _, allowed := range enum.Values() {
+		if value == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// enumFor returns the Enum implementation for field's type, whether Values is
+// declared on the value or the pointer receiver.
+func enumFor(field reflect.Value) (Enum, bool) {
+	if field.Kind() != reflect.String {
+		return nil, false
+	}
+	return enumForType(field.Type())
+}
+
+func enumForType(typ reflect.Type) (Enum, bool) {
+	if typ == nil {
+		return nil, false
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if enum, ok := reflect.Zero(typ).Interface().(Enum); ok {
+		return enum, true
+	}
+	if enum, ok := reflect.New(typ).Interface().(Enum); ok {
+		return enum, true
+	}
+	return nil, false
+}
+
+// enumMessage lists the allowed values in the field suggestion.
+func enumMessage(fe gvalidator.FieldError) string {
+	enum, ok := enumForType(fe.Type())
+	if !ok {
+		return fmt.Sprintf("field %s is not a supported enum type", fe.Field())
+	}
+	return fmt.Sprintf("%s must be one of: %s", fe.Field(), strings.Join(enum.Values(), ", "))
+}
@@ -0,0 +1,411 @@
+package validator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/milan604/core-lab/pkg/apperr"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	gvalidator "github.com/go-playground/validator/v10"
+)
+
+// Tags of failed FileRules. Their suggestions use the message keys
+// validation.<tag> with the data field, filename, param and value.
+const (
+	// TagFileRequired is reported when a required field has no file.
+	TagFileRequired = "file_required"
+	// TagFileMaxCount is reported when a field has more than MaxCount files;
+	// param is MaxCount.
+	TagFileMaxCount = "file_max_count"
+	// TagFileMaxSize is reported per file larger than MaxSize; param is
+	// MaxSize in bytes.
+	TagFileMaxSize = "file_max_size"
+	// TagFileType is reported per file whose sniffed type is not allowed;
+	// param lists AllowedTypes and value is the sniffed type.
+	TagFileType = "file_type"
+)
+
+// sniffLength is how much of a file http.DetectContentType looks at.
+const sniffLength = 512
+
+// FileRule constrains the files uploaded in one multipart form field.
+type FileRule struct {
+	// Field is the form field name, e.g. "avatar".
+	Field string
+	// Required rejects requests without a file in Field.
+	Required bool
+	// MaxCount caps the number of files in Field; zero allows any number.
+	MaxCount int
+	// MaxSize caps each file in bytes; zero allows any size.
+	MaxSize int64
+	// AllowedTypes restricts the MIME types, e.g. "image/png" or "image/*".
+	// Types are sniffed from the content with http.DetectContentType, not
+	// taken from the client's Content-Type, so formats it does not know,
+	// such as docx, are reported as application/zip or
+	// application/octet-stream. Empty allows any type.
+	AllowedTypes []string
+}
+
+// FileError is a failed FileRule.
+type FileError struct {
+	Field    string
+	Filename string
+	Tag      string
+	Param    string
+	Value    any
+	Message  string
+}
+
+// FileErrors are the failed FileRules of a request. ParseError turns them
+// into one suggestion per file.
+type FileErrors []FileError
+
+func (e FileErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Message
+	}
+	return "validator: " + strings.Join(messages, "; ")
+}
+
+// BindMultipart binds and validates a multipart/form-data request into T,
+// whose *multipart.FileHeader and []*multipart.FileHeader fields receive the
+// files, then checks the files against rules. Failed binding tags and rules
+// are reported together. Requests that are not multipart fail with
+// unsupported_media_type.
+//
+// The body is parsed with gin's MaxMultipartMemory, so larger files spill to
+// temporary files; use StreamFiles for uploads that should not be buffered.
+func BindMultipart[T any](vi ValidatorEngine, ctx *gin.Context, rules ...FileRule) (*T, *apperr.AppError) {
+	var req T
+	bindErr := ctx.ShouldBindWith(&req, binding.FormMultipart)
+	if errors.Is(bindErr, http.ErrNotMultipart) {
+		return nil, notMultipart()
+	}
+	var ves gvalidator.ValidationErrors
+	if bindErr != nil && !errors.As(bindErr, &ves) {
+		return nil, vi.ParseError(bindErr, requestLocale(ctx))
+	}
+
+	fileErr := ValidateFiles(ctx.Request.MultipartForm, rules...)
+	var fes FileErrors
+	if fileErr != nil && !errors.As(fileErr, &fes) {
+		return nil, apperr.New(apperr.ErrorCodeInternal).Wrap(fileErr)
+	}
+	switch {
+	case bindErr == nil && fileErr == nil:
+		return &req, nil
+	case bindErr == nil:
+		return nil, vi.ParseError(fileErr, requestLocale(ctx))
+	case fileErr == nil:
+		return nil, vi.ParseError(bindErr, requestLocale(ctx))
+	}
+	appErr := vi.ParseError(bindErr, requestLocale(ctx))
+	appErr.Suggestions = append(appErr.Suggestions, vi.ParseError(fileErr, requestLocale(ctx)).Suggestions...)
+	return nil, appErr
+}
+
+// ValidateFiles checks the files of form against rules and returns FileErrors
+// listing every failure, or the error of reading a file to sniff its type.
+// A nil form has no files.
+func ValidateFiles(form *multipart.Form, rules ...FileRule) error {
+	var errs FileErrors
+	for _, rule := range rules {
+		var files []*multipart.FileHeader
+		if form != nil {
+			files = form.File[rule.Field]
+		}
+		if len(files) == 0 {
+			if rule.Required {
+				errs = append(errs, rule.requiredError())
+			}
+			continue
+		}
+		if rule.MaxCount > 0 && len(files) > rule.MaxCount {
+			errs = append(errs, rule.countError())
+			continue
+		}
+		for _, fh := range files {
+			if rule.MaxSize > 0 && fh.Size > rule.MaxSize {
+				errs = append(errs, rule.sizeError(fh.Filename, fh.Size))
+				continue
+			}
+			if len(rule.AllowedTypes) == 0 {
+				continue
+			}
+			contentType, err := sniffFileHeader(fh)
+			if err != nil {
+				return fmt.Errorf("validator: read %s: %w", fh.Filename, err)
+			}
+			if !rule.allowsType(contentType) {
+				errs = append(errs, rule.typeError(fh.Filename, contentType))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Upload is a file of a multipart body read by StreamFiles. Reading it
+// past the rule's MaxSize fails with FileErrors.
+type Upload struct {
+	// Field is the form field the file was sent in.
+	Field string
+	// Filename is the base name the client sent, which callers should not
+	// trust as a path.
+	Filename string
+	// ContentType is the sniffed MIME type.
+	ContentType string
+
+	r        io.Reader
+	rule     FileRule
+	size     int64
+	exceeded bool
+}
+
+// Read reads the file content.
+func (u *Upload) Read(p []byte) (int, error) {
+	if u.exceeded {
+		return 0, FileErrors{u.rule.sizeError(u.Filename, 0)}
+	}
+	if u.rule.MaxSize > 0 {
+		// Read one byte beyond the limit to tell a file of exactly MaxSize
+		// from a larger one.
+		if remaining := u.rule.MaxSize - u.size + 1; int64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+	n, err := u.r.Read(p)
+	u.size += int64(n)
+	if u.rule.MaxSize > 0 && u.size > u.rule.MaxSize {
+		u.exceeded = true
+		return n - int(u.size-u.rule.MaxSize), FileErrors{u.rule.sizeError(u.Filename, 0)}
+	}
+	return n, err
+}
+
+// Size is the number of bytes read so far.
+func (u *Upload) Size() int64 { return u.size }
+
+// StreamFiles reads a multipart/form-data body part by part and calls save
+// with each file sent in a rule's field, so uploads of any size go to disk
+// or object storage without being buffered in memory or temporary files.
+// save must consume the Upload before returning, e.g. with SaveUpload or
+// io.Copy, and return its read error.
+//
+// Files are checked against the rules as they arrive: files of a
+// disallowed type or beyond MaxCount are skipped without calling save, and
+// an Upload fails once it exceeds MaxSize. Failures are reported like
+// BindMultipart's, after the rest of the body is read, so save may already
+// have stored other files; callers should remove them. Other errors of save
+// abort with an internal error wrapping them. Form values and files of
+// fields without a rule are skipped.
+func StreamFiles(vi ValidatorEngine, ctx *gin.Context, save func(*Upload) error, rules ...FileRule) *apperr.AppError {
+	reader, err := ctx.Request.MultipartReader()
+	if errors.Is(err, http.ErrNotMultipart) {
+		return notMultipart()
+	}
+	if err != nil {
+		return vi.ParseError(err, requestLocale(ctx))
+	}
+
+	byField := make(map[string]FileRule, len(rules))
+	for _, rule := range rules {
+		byField[rule.Field] = rule
+	}
+	counts := make(map[string]int, len(rules))
+	var errs FileErrors
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return vi.ParseError(err, requestLocale(ctx))
+		}
+		rule, ok := byField[part.FormName()]
+		if !ok || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		counts[rule.Field]++
+		if rule.MaxCount > 0 && counts[rule.Field] > rule.MaxCount {
+			if counts[rule.Field] == rule.MaxCount+1 {
+				errs = append(errs, rule.countError())
+			}
+			part.Close()
+			continue
+		}
+
+		buffered := bufio.NewReaderSize(part, sniffLength)
+		head, err := buffered.Peek(sniffLength)
+		if err != nil && !errors.Is(err, io.EOF) {
+			part.Close()
+			return vi.ParseError(err, requestLocale(ctx))
+		}
+		contentType := detectContentType(head)
+		if !rule.allowsType(contentType) {
+			errs = append(errs, rule.typeError(part.FileName(), contentType))
+			part.Close()
+			continue
+		}
+
+		upload := &Upload{
+			Field:       rule.Field,
+			Filename:    part.FileName(),
+			ContentType: contentType,
+			r:           buffered,
+			rule:        rule,
+		}
+		err = save(upload)
+		part.Close()
+		var fes FileErrors
+		switch {
+		case upload.exceeded:
+			errs = append(errs, rule.sizeError(upload.Filename, 0))
+		case errors.As(err, &fes):
+			errs = append(errs, fes...)
+		case err != nil:
+			return apperr.New(apperr.ErrorCodeInternal).Wrap(err)
+		}
+	}
+
+	for _, rule := range rules {
+		if rule.Required && counts[rule.Field] == 0 {
+			errs = append(errs, rule.requiredError())
+		}
+	}
+	if len(errs) > 0 {
+		return vi.ParseError(errs, requestLocale(ctx))
+	}
+	return nil
+}
+
+// SaveUpload writes u to path, through a temporary file in the same
+// directory that is renamed into place once complete, so a failed or
+// oversized upload never leaves a partial file behind. It returns u's read
+// error, e.g. FileErrors when u exceeds its MaxSize.
+func SaveUpload(u *Upload, path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, u); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func notMultipart() *apperr.AppError {
+	return apperr.New(apperr.ErrorCodeUnsupportedMediaType).
+		WithMessageKey(MessageKeyNotMultipart, "Content-Type must be multipart/form-data")
+}
+
+func sniffFileHeader(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	return detectContentType(head[:n]), nil
+}
+
+// detectContentType sniffs the MIME type of head without its parameters,
+// e.g. text/plain rather than text/plain; charset=utf-8.
+func detectContentType(head []byte) string {
+	contentType := http.DetectContentType(head)
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return contentType
+}
+
+func (r FileRule) allowsType(contentType string) bool {
+	if len(r.AllowedTypes) == 0 {
+		return true
+	}
+	for _, allowed := range r.AllowedTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == contentType || (strings.HasSuffix(allowed, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(allowed, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r FileRule) requiredError() FileError {
+	return FileError{
+		Field:   r.Field,
+		Tag:     TagFileRequired,
+		Message: fmt.Sprintf("%s is required", r.Field),
+	}
+}
+
+func (r FileRule) countError() FileError {
+	return FileError{
+		Field:   r.Field,
+		Tag:     TagFileMaxCount,
+		Param:   strconv.Itoa(r.MaxCount),
+		Message: fmt.Sprintf("%s accepts at most %d files", r.Field, r.MaxCount),
+	}
+}
+
+// sizeError reports filename as larger than MaxSize; size is 0 when the
+// file was streamed and its full size is unknown.
+func (r FileRule) sizeError(filename string, size int64) FileError {
+	return FileError{
+		Field:    r.Field,
+		Filename: filename,
+		Tag:      TagFileMaxSize,
+		Param:    strconv.FormatInt(r.MaxSize, 10),
+		Value:    size,
+		Message:  fmt.Sprintf("%s: %s is larger than the %s limit", r.Field, filename, formatBytes(r.MaxSize)),
+	}
+}
+
+func (r FileRule) typeError(filename, contentType string) FileError {
+	return FileError{
+		Field:    r.Field,
+		Filename: filename,
+		Tag:      TagFileType,
+		Param:    strings.Join(r.AllowedTypes, " "),
+		Value:    contentType,
+		Message:  fmt.Sprintf("%s: %s is %s, allowed types are %s", r.Field, filename, contentType, strings.Join(r.AllowedTypes, ", ")),
+	}
+}
+
+// formatBytes prints n in the largest binary unit it fills, e.g. 5 MiB or
+// 1.5 KiB.
+func formatBytes(n int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(n)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return strings.TrimSuffix(strconv.FormatFloat(value, 'f', 1, 64), ".0") + " " + units[unit]
+}
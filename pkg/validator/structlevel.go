@@ -0,0 +1,202 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/milan604/core-lab/pkg/apperr"
+
+	gvalidator "github.com/go-playground/validator/v10"
+)
+
+// Tags reported by the cross-field rules. Their suggestions name every field
+// involved, e.g. "ends_at must be after starts_at".
+const (
+	// TagDateRange is reported on the end field of DateRange.
+	TagDateRange = "date_range"
+	// TagEitherOr is reported on the first field of EitherOr.
+	TagEitherOr = "either_or"
+	// TagRequiredIf is reported on the required field of RequiredIf. The
+	// built-in required_if tag shares its message.
+	TagRequiredIf = "required_if"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// RegisterStructValidation registers fn as the struct-level validation of
+// types, run after their field tags. A type has one struct-level validation;
+// registering another replaces it, so combine rules with
+// RegisterStructRules.
+func (vi *Validator) RegisterStructValidation(fn gvalidator.StructLevelFunc, types ...any) {
+	vi.v.RegisterStructValidation(fn, types...)
+}
+
+// RegisterStructRules registers the cross-field rules of typ, e.g. DateRange
+// and RequiredIf, checked whenever a typ is bound or validated. Fields are
+// named by their Go names; suggestions use the json, form or uri names.
+//
+//	vi.RegisterStructRules(Booking{},
+//		validator.DateRange("StartsAt", "EndsAt"),
+//		validator.EitherOr("Email", "Phone"),
+//		validator.RequiredIf("VATID", "CustomerType", "business"),
+//	)
+func (vi *Validator) RegisterStructRules(typ any, rules ...gvalidator.StructLevelFunc) {
+	vi.RegisterStructValidation(func(sl gvalidator.StructLevel) {
+		for _, rule := range rules {
+			rule(sl)
+		}
+	}, typ)
+}
+
+// DateRange requires the time.Time (or *time.Time) field start to be before
+// end. It passes when either is unset; add required tags for that.
+func DateRange(start, end string) gvalidator.StructLevelFunc {
+	return func(sl gvalidator.StructLevel) {
+		startField, startValue := structField(sl, "DateRange", start)
+		endField, endValue := structField(sl, "DateRange", end)
+		if !isSet(startValue) || !isSet(endValue) {
+			return
+		}
+		startTime, ok := timeValue(startValue)
+		endTime, ok2 := timeValue(endValue)
+		if !ok || !ok2 {
+			panic(fmt.Sprintf("validator: DateRange: %s and %s must be time.Time fields", start, end))
+		}
+		if !startTime.Before(endTime) {
+			sl.ReportError(endValue.Interface(), fieldName(endField), endField.Name, TagDateRange, fieldName(startField))
+		}
+	}
+}
+
+// EitherOr requires at least one of fields to be set: a non-blank string,
+// a non-empty slice or map, a non-nil pointer or a non-zero value.
+func EitherOr(fields ...string) gvalidator.StructLevelFunc {
+	if len(fields) < 2 {
+		panic("validator: EitherOr needs at least two fields")
+	}
+	return func(sl gvalidator.StructLevel) {
+		names := make([]string, len(fields))
+		var first reflect.StructField
+		var firstValue reflect.Value
+		for i, name := range fields {
+			field, value := structField(sl, "EitherOr", name)
+			if isSet(value) {
+				return
+			}
+			if i == 0 {
+				first, firstValue = field, value
+			}
+			names[i] = fieldName(field)
+		}
+		sl.ReportError(firstValue.Interface(), names[0], first.Name, TagEitherOr, strings.Join(names[1:], " "))
+	}
+}
+
+// RequiredIf requires field to be set when other equals one of values,
+// compared by their printed form, so RequiredIf("VATID", "Type", "business")
+// works for string enums too.
+func RequiredIf(field, other string, values ...any) gvalidator.StructLevelFunc {
+	if len(values) == 0 {
+		panic("validator: RequiredIf needs at least one value")
+	}
+	return func(sl gvalidator.StructLevel) {
+		requiredField, requiredValue := structField(sl, "RequiredIf", field)
+		otherField, otherValue := structField(sl, "RequiredIf", other)
+		if isSet(requiredValue) {
+			return
+		}
+		current := ""
+		if v := deref(otherValue); v.Kind() != reflect.Pointer && v.Kind() != reflect.Interface {
+			current = fmt.Sprint(v.Interface())
+		}
+		for _, v := range values {
+			if current == fmt.Sprint(v) {
+				sl.ReportError(requiredValue.Interface(), fieldName(requiredField), requiredField.Name, TagRequiredIf, fieldName(otherField)+" "+current)
+				return
+			}
+		}
+	}
+}
+
+// ValidateStruct validates v, a struct or a pointer to one, against its
+// binding tags and struct rules outside of a Gin handler, e.g. for a queued
+// job's payload. Errors have the same shape as the Bind helpers', localized
+// into locale when the validator has a translator.
+func ValidateStruct[T any](vi *Validator, v T, locale ...string) *apperr.AppError {
+	if err := vi.v.Struct(v); err != nil {
+		return vi.ParseError(err, locale...)
+	}
+	return nil
+}
+
+// structField returns the field called name of the struct being validated,
+// panicking when there is none so a misspelled rule fails on first use.
+func structField(sl gvalidator.StructLevel, rule, name string) (reflect.StructField, reflect.Value) {
+	current := sl.Current()
+	field, ok := current.Type().FieldByName(name)
+	if !ok {
+		panic(fmt.Sprintf("validator: %s: %s has no field %s", rule, current.Type(), name))
+	}
+	return field, current.FieldByIndex(field.Index)
+}
+
+func deref(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// isSet reports whether v holds a value, with not_blank's notion of empty.
+func isSet(v reflect.Value) bool {
+	v = deref(v)
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return !v.IsNil()
+	case reflect.String:
+		return strings.TrimSpace(v.String()) != ""
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() > 0
+	default:
+		return !v.IsZero()
+	}
+}
+
+func timeValue(v reflect.Value) (time.Time, bool) {
+	v = deref(v)
+	if v.Type() != timeType {
+		return time.Time{}, false
+	}
+	return v.Interface().(time.Time), true
+}
+
+func dateRangeMessage(fe gvalidator.FieldError) string {
+	return fmt.Sprintf("%s must be after %s", fe.Field(), fe.Param())
+}
+
+func eitherOrMessage(fe gvalidator.FieldError) string {
+	names := append([]string{fe.Field()}, strings.Fields(fe.Param())...)
+	if len(names) == 2 {
+		return fmt.Sprintf("%s or %s is required", names[0], names[1])
+	}
+	return fmt.Sprintf("one of %s or %s is required", strings.Join(names[:len(names)-1], ", "), names[len(names)-1])
+}
+
+// requiredIfMessage also covers the built-in required_if tag, whose param
+// holds field/value pairs: "Type business".
+func requiredIfMessage(fe gvalidator.FieldError) string {
+	parts := strings.Fields(fe.Param())
+	if len(parts) < 2 || len(parts)%2 != 0 {
+		return fmt.Sprintf("%s is required", fe.Field())
+	}
+	conditions := make([]string, 0, len(parts)/2)
+	for i := 0; i < len(parts); i += 2 {
+		conditions = append(conditions, parts[i]+" is "+parts[i+1])
+	}
+	return fmt.Sprintf("%s is required when %s", fe.Field(), strings.Join(conditions, " and "))
+}
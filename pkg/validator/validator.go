@@ -2,12 +2,14 @@ package validator
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
 	"time"
 
 	"github.com/milan604/core-lab/pkg/apperr"
+	"github.com/milan604/core-lab/pkg/i18n"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -29,11 +31,37 @@ type TagErrorBuilder struct {
 	Builder func(fe gvalidator.FieldError) string
 }
 
+// Message keys of the errors ParseError builds. A failed validation tag uses
+// "validation.<tag>", e.g. validation.required, with the data field, tag,
+// param and value; the enum tag also gets values.
+const (
+	MessageKeyPrefix          = "validation."
+	MessageKeyInvalidType     = "validation.invalid_type"     // data: field, type
+	MessageKeyInvalidJSON     = "validation.invalid_json"     // no data
+	MessageKeyInvalidDatetime = "validation.invalid_datetime" // data: value
+	MessageKeyInvalidInput    = "validation.invalid_input"    // data: error
+	MessageKeyNotMultipart    = "validation.not_multipart"    // no data
+)
+
 // Validator is the wrapper around go-playground validator with extra features.
 type Validator struct {
 	v                *gvalidator.Validate
 	tagErrorBuilders map[string]TagErrorBuilder
 	fieldNameFn      func(reflect.StructField) string
+	translator       *i18n.Translator
+}
+
+// Option customizes a Validator on creation.
+type Option func(*Validator)
+
+// WithTranslator localizes the errors of ParseError into the locale it is
+// given, from the validation.* keys and the codes' message keys in tr.
+// Without it, ParseError still records the keys, so response.JSONError can
+// localize with the translator of the i18n middleware.
+func WithTranslator(tr *i18n.Translator) Option {
+	return func(vi *Validator) {
+		vi.translator = tr
+	}
 }
 
 // ValidatorEngine defines the interface for validation engines
@@ -42,31 +70,31 @@ type Validator struct {
 type ValidatorEngine interface {
 	RegisterValidation(tag string, fn gvalidator.Func) error
 	RegisterTagError(tag string, code *apperr.ErrorCode, builder func(gvalidator.FieldError) string)
-	ParseError(err error) *apperr.AppError
+	ParseError(err error, locale ...string) *apperr.AppError
 }
 
 // New creates a new Validator instance and wires up Gin's validator engine for tag->name resolution.
-func New() *Validator {
-	fieldNameFn := func(f reflect.StructField) string {
-		if name := getTagName(f, "json"); name != "" {
-			return name
-		}
-		if name := getTagName(f, "form"); name != "" {
-			return name
-		}
-		if name := getTagName(f, "uri"); name != "" {
-			return name
-		}
-		return f.Name
-	}
+// The enum tag (see EnumTag) is registered on the engine.
+func New(opts ...Option) *Validator {
+	fieldNameFn := fieldName
 
 	v := validatorEngine(fieldNameFn)
-
-	return &Validator{
-		v:                v,
-		tagErrorBuilders: make(map[string]TagErrorBuilder),
-		fieldNameFn:      fieldNameFn,
+	_ = registerEnum(v)
+
+	vi := &Validator{
+		v: v,
+		tagErrorBuilders: map[string]TagErrorBuilder{
+			EnumTag:       {Code: apperr.ErrorCodeValidationFail, Builder: enumMessage},
+			TagDateRange:  {Code: apperr.ErrorCodeValidationFail, Builder: dateRangeMessage},
+			TagEitherOr:   {Code: apperr.ErrorCodeValidationFail, Builder: eitherOrMessage},
+			TagRequiredIf: {Code: apperr.ErrorCodeValidationFail, Builder: requiredIfMessage},
+		},
+		fieldNameFn: fieldNameFn,
+	}
+	for _, opt := range opts {
+		opt(vi)
 	}
+	return vi
 }
 
 func validatorEngine(fieldNameFn func(reflect.StructField) string) *gvalidator.Validate {
@@ -85,6 +113,21 @@ func validatorEngine(fieldNameFn func(reflect.StructField) string) *gvalidator.V
 	return v
 }
 
+// fieldName is the name of f in messages: its json, form or uri name, or the
+// Go name.
+func fieldName(f reflect.StructField) string {
+	if name := getTagName(f, "json"); name != "" {
+		return name
+	}
+	if name := getTagName(f, "form"); name != "" {
+		return name
+	}
+	if name := getTagName(f, "uri"); name != "" {
+		return name
+	}
+	return f.Name
+}
+
 // helper to get tag name
 func getTagName(f reflect.StructField, tagName string) string {
 	tagValue := f.Tag.Get(tagName)
@@ -104,8 +147,19 @@ func (vi *Validator) RegisterTagError(tag string, code *apperr.ErrorCode, builde
 	vi.tagErrorBuilders[tag] = TagErrorBuilder{Code: code, Builder: builder}
 }
 
-// ParseError converts any binding/validator/json error into *apperr.AppError
-func (vi *Validator) ParseError(err error) *apperr.AppError {
+// ParseError converts any binding/validator/json error into *apperr.AppError.
+// Given a locale and a translator (WithTranslator), the message and field
+// suggestions are translated into it; untranslated keys keep the English
+// text.
+func (vi *Validator) ParseError(err error, locale ...string) *apperr.AppError {
+	appErr := vi.parseError(err)
+	if appErr == nil || vi.translator == nil || len(locale) == 0 || locale[0] == "" {
+		return appErr
+	}
+	return appErr.Localize(vi.translator.ForLocale(locale[0]))
+}
+
+func (vi *Validator) parseError(err error) *apperr.AppError {
 	if err == nil {
 		return nil
 	}
@@ -117,7 +171,21 @@ func (vi *Validator) ParseError(err error) *apperr.AppError {
 		for _, fe := range e {
 			field := fe.Field() // thanks to registered TagNameFunc this will be the json/form name
 			msg := vi.buildMessageForField(fe)
-			appErr.AddSuggestion(field, msg)
+			appErr.AddSuggestionKey(field, MessageKeyPrefix+fe.Tag(), messageData(fe), msg)
+		}
+		return appErr
+
+	case FileErrors:
+		appErr := apperr.New(apperr.ErrorCodeValidationFail)
+		for _, fe := range e {
+			data := map[string]any{
+				"field":    fe.Field,
+				"filename": fe.Filename,
+				"tag":      fe.Tag,
+				"param":    fe.Param,
+				"value":    fe.Value,
+			}
+			appErr.AddSuggestionKey(fe.Field, MessageKeyPrefix+fe.Tag, data, fe.Message)
 		}
 		return appErr
 
@@ -126,29 +194,83 @@ func (vi *Validator) ParseError(err error) *apperr.AppError {
 		f := e.Field
 		if f == "" {
 			// best-effort: sometimes field is empty for top-level decode errors
-			appErr.Message = "Invalid request body"
 			return appErr
 		}
 		msg := fmt.Sprintf("Invalid type for field %s: expected %s", f, e.Type.String())
-		appErr.AddSuggestion(f, msg)
+		appErr.AddSuggestionKey(f, MessageKeyInvalidType, map[string]any{"field": f, "type": e.Type.String()}, msg)
 		return appErr
 
 	case *json.SyntaxError:
-		appErr := apperr.New(apperr.ErrorCodeInvalidRequest)
-		appErr.Message = "Invalid JSON payload"
-		return appErr
+		return apperr.New(apperr.ErrorCodeInvalidRequest).
+			WithMessageKey(MessageKeyInvalidJSON, "Invalid JSON payload")
 
 	case *time.ParseError:
-		appErr := apperr.New(apperr.ErrorCodeInvalidInput)
-		appErr.Message = "Invalid datetime format"
-		return appErr
+		return apperr.New(apperr.ErrorCodeInvalidInput).
+			WithParams(map[string]any{"value": e.Value}).
+			WithMessageKey(MessageKeyInvalidDatetime, "Invalid datetime format")
 
 	default:
 		// generic error -> return InvalidInput but keep underlying message in server logs
-		appErr := apperr.New(apperr.ErrorCodeInvalidInput)
-		appErr.Message = fmt.Sprintf("Invalid input: %v", err.Error())
-		return appErr
+		return apperr.New(apperr.ErrorCodeInvalidInput).
+			WithParams(map[string]any{"error": err.Error()}).
+			WithMessageKey(MessageKeyInvalidInput, fmt.Sprintf("Invalid input: %v", err.Error()))
+	}
+}
+
+// messageData is the data of a validation.<tag> message.
+func messageData(fe gvalidator.FieldError) map[string]any {
+	data := map[string]any{
+		"field": fe.Field(),
+		"tag":   fe.Tag(),
+		"param": fe.Param(),
+		"value": fe.Value(),
+	}
+	if fe.Tag() == EnumTag {
+		if enum, ok := enumForType(fe.Type()); ok {
+			data["values"] = strings.Join(enum.Values(), ", ")
+		}
+	}
+	return data
+}
+
+// requestLocale returns the locale the i18n middleware detected for ctx.
+func requestLocale(ctx *gin.Context) string {
+	if ctx == nil || ctx.Request == nil {
+		return ""
+	}
+	return i18n.LocaleFromContext(ctx.Request.Context())
+}
+
+// StructErrors validates s against its binding tags, outside of a request,
+// and returns one FieldError per failed rule. Field is the path of Go field
+// names below the root type (e.g. "Database.Port", "Hosts[1]"), so callers can
+// map it to their own key names; Message comes from RegisterTagError builders
+// or the default wording. A non-validation error, such as s not being a
+// struct, is returned as is.
+func (vi *Validator) StructErrors(s interface{}) ([]FieldError, error) {
+	err := vi.v.Struct(s)
+	if err == nil {
+		return nil, nil
+	}
+	var ves gvalidator.ValidationErrors
+	if !errors.As(err, &ves) {
+		return nil, err
+	}
+	out := make([]FieldError, 0, len(ves))
+	for _, fe := range ves {
+		field := fe.StructNamespace()
+		if i := strings.IndexByte(field, '.'); i >= 0 {
+			field = field[i+1:]
+		}
+		out = append(out, FieldError{
+			Field:   field,
+			Message: vi.buildMessageForField(fe),
+			Tag:     fe.Tag(),
+			Param:   fe.Param(),
+			Value:   fe.Value(),
+		})
 	}
+	return out, nil
 }
 
 // buildMessageForField uses registered tag builders or defaults
@@ -170,6 +292,7 @@ func (vi *Validator) buildMessageForField(fe gvalidator.FieldError) string {
    - BindQuery
 	- BindURI
 	- BindHeader
+	- BindMultipart (see multipart.go)
 	 - Combined helpers
 		- BindJSONAndQuery
 		- BindJSONAndURI
@@ -183,7 +306,7 @@ func (vi *Validator) buildMessageForField(fe gvalidator.FieldError) string {
 func BindJSON[T any](vi ValidatorEngine, ctx *gin.Context) (*T, *apperr.AppError) {
 	var req T
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		return nil, vi.ParseError(err)
+		return nil, vi.ParseError(err, requestLocale(ctx))
 	}
 	return &req, nil
 }
@@ -192,7 +315,7 @@ func BindJSON[T any](vi ValidatorEngine, ctx *gin.Context) (*T, *apperr.AppError
 func BindQuery[T any](vi ValidatorEngine, ctx *gin.Context) (*T, *apperr.AppError) {
 	var req T
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		return nil, vi.ParseError(err)
+		return nil, vi.ParseError(err, requestLocale(ctx))
 	}
 	return &req, nil
 }
@@ -201,7 +324,7 @@ func BindQuery[T any](vi ValidatorEngine, ctx *gin.Context) (*T, *apperr.AppErro
 func BindURI[T any](vi ValidatorEngine, ctx *gin.Context) (*T, *apperr.AppError) {
 	var req T
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		return nil, vi.ParseError(err)
+		return nil, vi.ParseError(err, requestLocale(ctx))
 	}
 	return &req, nil
 }
@@ -210,7 +333,7 @@ func BindURI[T any](vi ValidatorEngine, ctx *gin.Context) (*T, *apperr.AppError)
 func BindHeader[T any](vi ValidatorEngine, ctx *gin.Context) (*T, *apperr.AppError) {
 	var req T
 	if err := ctx.ShouldBindHeader(&req); err != nil {
-		return nil, vi.ParseError(err)
+		return nil, vi.ParseError(err, requestLocale(ctx))
 	}
 	return &req, nil
 }
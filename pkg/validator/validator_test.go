@@ -1,14 +1,23 @@
 package validator
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	gvalidator "github.com/go-playground/validator/v10"
 	"github.com/milan604/core-lab/pkg/apperr"
+	"github.com/milan604/core-lab/pkg/i18n"
+	"github.com/milan604/core-lab/pkg/response"
 )
 
 func TestRegisterValidationAppliesToGinBinding(t *testing.T) {
@@ -64,3 +73,393 @@ func TestRegisterValidationAppliesToGinBinding(t *testing.T) {
 		t.Fatalf("expected message to mention %q, got %q", tag, got)
 	}
 }
+
+type testOrderStatus string
+
+func (testOrderStatus) Values() []string { return []string{"pending", "paid", "shipped"} }
+
+func TestEnumTagValidatesGoEnumTypes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	v := New()
+
+	type request struct {
+		Status   testOrderStatus   `json:"status" binding:"required,enum"`
+		Previous testOrderStatus   `json:"previous" binding:"omitempty,enum"`
+		History  []testOrderStatus `json:"history" binding:"dive,enum"`
+	}
+
+	bind := func(body string) (*request, *apperr.AppError) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		return BindJSON[request](v, ctx)
+	}
+
+	req, appErr := bind(`{"status":"paid","history":["pending"]}`)
+	if appErr != nil {
+		t.Fatalf("BindJSON() error = %v", appErr)
+	}
+	if req.Status != "paid" || !IsEnumValue(req.Status) {
+		t.Fatalf("Status = %q", req.Status)
+	}
+
+	_, appErr = bind(`{"status":"lost","history":["pending","returned"]}`)
+	if appErr == nil {
+		t.Fatal("expected validation error")
+	}
+	if len(appErr.Suggestions) != 2 {
+		t.Fatalf("expected 2 suggestions, got %+v", appErr.Suggestions)
+	}
+	if got := appErr.Suggestions[0]; got.Field != "status" || got.Message != "status must be one of: pending, paid, shipped" {
+		t.Fatalf("unexpected suggestion %+v", got)
+	}
+	if got := appErr.Suggestions[1].Field; got != "history[1]" {
+		t.Fatalf("expected field history[1], got %q", got)
+	}
+}
+
+func TestStructErrorsUseGoFieldPaths(t *testing.T) {
+	type database struct {
+		MaxConns int `json:"max_conns" binding:"gte=1"`
+	}
+	type settings struct {
+		Name     string   `binding:"required"`
+		Hosts    []string `binding:"dive,required"`
+		Database database
+	}
+
+	fieldErrs, err := New().StructErrors(settings{Hosts: []string{"a", ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields []string
+	for _, fe := range fieldErrs {
+		fields = append(fields, fe.Field+":"+fe.Tag)
+	}
+	if got := strings.Join(fields, ","); got != "Name:required,Hosts[1]:required,Database.MaxConns:gte" {
+		t.Fatalf("fields = %s", got)
+	}
+
+	if fieldErrs, err := New().StructErrors(settings{Name: "orders", Database: database{MaxConns: 5}}); err != nil || fieldErrs != nil {
+		t.Fatalf("StructErrors = %v, %v", fieldErrs, err)
+	}
+	if _, err := New().StructErrors("not a struct"); err == nil {
+		t.Fatal("expected an error for a non-struct")
+	}
+}
+
+func TestParseErrorLocalizesIntoRequestLocale(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tr := i18n.New(i18n.WithDefaultLocale("en"))
+	tr.AddBundle("default", "de", map[string]string{
+		"errors.validation_failed": "Validierung fehlgeschlagen",
+		"validation.required":      "{{field}} ist erforderlich",
+		"validation.min":           "{{field}} muss mindestens {{param}} Zeichen lang sein",
+	})
+	tr.Add("default", "en", "validation.required", "{{field}} is required")
+
+	type request struct {
+		Name  string `json:"name" binding:"required"`
+		Email string `json:"email" binding:"min=5"`
+		Phone string `json:"phone" binding:"max=3"`
+	}
+	for name, vi := range map[string]*Validator{"translator": New(WithTranslator(tr)), "response": New()} {
+		router := gin.New()
+		router.Use(tr.GinMiddleware())
+		router.POST("/", func(c *gin.Context) {
+			if _, appErr := BindJSON[request](vi, c); appErr != nil {
+				response.JSONError(c, appErr)
+			}
+		})
+		req := httptest.NewRequest(http.MethodPost, "/?lang=de", strings.NewReader(`{"email":"a@b","phone":"12345"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		var body response.APIResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: decode %s: %v", name, rec.Body.String(), err)
+		}
+		if body.Message != "Validierung fehlgeschlagen" || len(body.Errors) != 3 {
+			t.Fatalf("%s: body = %+v", name, body)
+		}
+		want := []string{"name ist erforderlich", "email muss mindestens 5 Zeichen lang sein", "field phone failed on 'max' validation (param=3)"}
+		for i, s := range body.Errors {
+			if s.Message != want[i] {
+				t.Errorf("%s: suggestion %d = %q, want %q", name, i, s.Message, want[i])
+			}
+		}
+	}
+
+	appErr := New(WithTranslator(tr)).ParseError(&json.SyntaxError{}, "de")
+	if appErr.Message != "Invalid JSON payload" {
+		t.Fatalf("untranslated key message = %q, want the English default", appErr.Message)
+	}
+}
+
+func TestRegisterDefaultsValidatesCommonTags(t *testing.T) {
+	vi := New()
+	if err := vi.RegisterDefaults(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tag     string
+		valid   []any
+		invalid []any
+	}{
+		{TagUUID4, []any{"0b3f7c1e-9d2a-4c5b-8e6f-1a2b3c4d5e6f", "0B3F7C1E-9D2A-4C5B-AE6F-1A2B3C4D5E6F"}, []any{"0b3f7c1e-9d2a-1c5b-8e6f-1a2b3c4d5e6f", "0b3f7c1e9d2a4c5b8e6f1a2b3c4d5e6f", 42}},
+		{TagULID, []any{"01ARZ3NDEKTSV4RRFFQ69G5FAV", "01arz3ndektsv4rrffq69g5fav"}, []any{"81ARZ3NDEKTSV4RRFFQ69G5FAV", "01ARZ3NDEKTSV4RRFFQ69G5FAI", "01ARZ3NDEK"}},
+		{TagSlug, []any{"summer-sale", "v2", "a-1-b"}, []any{"Summer-Sale", "summer--sale", "-sale", "sale-", "summer sale", ""}},
+		{TagPhone, []any{"+14155552671", "+442071838750"}, []any{"14155552671", "+04155552671", "+1415555267123456", "+1 415 555 2671"}},
+		{TagStrongPassword, []any{"Tr0ub4dor&3", "Ünïcødé-P4ss"}, []any{"Sh0rt!", "alllowercase1!", "ALLUPPERCASE1!", "NoDigitsHere!", "NoSymbols123"}},
+		{TagIBAN, []any{"GB82WEST12345698765432", "GB82 WEST 1234 5698 7654 32", "DE89370400440532013000", "NO9386011117947"}, []any{"GB82WEST12345698765433", "DE8937040044053201300", "gb82west12345698765432", "GB82"}},
+		{TagCountryCode, []any{"DE", "US", "NZ"}, []any{"de", "DEU", "276", "XX"}},
+		{TagTimezone, []any{"Europe/Berlin", "UTC", "America/Argentina/Buenos_Aires"}, []any{"", "Local", "Mars/Olympus", 3}},
+		{TagSemver, []any{"1.4.2", "v2.0.0-rc.1", "1.0.0+build.5"}, []any{"1.4", "01.4.2", "1.4.2-", "latest"}},
+		{TagNotBlank, []any{"x", " x ", []string{"a"}, map[string]int{"a": 1}, 1}, []any{"", "  \t", []string{}, map[string]int{}, 0}},
+	}
+	for _, tt := range tests {
+		for _, value := range tt.valid {
+			if err := vi.v.Var(value, tt.tag); err != nil {
+				t.Errorf("%s rejected %#v: %v", tt.tag, value, err)
+			}
+		}
+		for _, value := range tt.invalid {
+			if err := vi.v.Var(value, tt.tag); err == nil {
+				t.Errorf("%s accepted %#v", tt.tag, value)
+			}
+		}
+	}
+	if err := vi.v.Var("Abcdefg1!", TagStrongPassword+"=12"); err == nil {
+		t.Error("strong_password=12 accepted a 9 character password")
+	}
+
+	type body struct {
+		Country  string `json:"country" binding:"country_code"`
+		Password string `json:"password" binding:"strong_password=12"`
+		Name     string `json:"name" binding:"not_blank"`
+	}
+	fields, err := vi.StructErrors(body{Country: "de", Password: "short", Name: " "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FieldError{
+		{Field: "Country", Tag: TagCountryCode, Message: "country must be an ISO 3166-1 alpha-2 country code, e.g. DE"},
+		{Field: "Password", Tag: TagStrongPassword, Param: "12", Message: "password must be at least 12 characters with upper and lower case letters, a digit and a symbol"},
+		{Field: "Name", Tag: TagNotBlank, Message: "name must not be blank"},
+	}
+	if len(fields) != len(want) {
+		t.Fatalf("StructErrors = %+v", fields)
+	}
+	for i, w := range want {
+		got := fields[i]
+		if got.Field != w.Field || got.Tag != w.Tag || got.Param != w.Param || got.Message != w.Message {
+			t.Errorf("field %d = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestStructRulesReportBothFields(t *testing.T) {
+	type customerType string
+	type booking struct {
+		StartsAt     time.Time    `json:"starts_at"`
+		EndsAt       *time.Time   `json:"ends_at"`
+		Email        string       `json:"email"`
+		Phone        string       `json:"phone"`
+		CustomerType customerType `json:"customer_type"`
+		VATID        string       `json:"vat_id"`
+	}
+	vi := New()
+	vi.RegisterStructRules(booking{},
+		DateRange("StartsAt", "EndsAt"),
+		EitherOr("Email", "Phone"),
+		RequiredIf("VATID", "CustomerType", "business"),
+	)
+
+	start := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	before := start.Add(-time.Hour)
+	appErr := ValidateStruct(vi, booking{StartsAt: start, EndsAt: &before, Email: " ", CustomerType: "business"})
+	if appErr == nil {
+		t.Fatal("ValidateStruct accepted an invalid booking")
+	}
+	want := map[string]string{
+		"ends_at": "ends_at must be after starts_at",
+		"email":   "email or phone is required",
+		"vat_id":  "vat_id is required when customer_type is business",
+	}
+	if len(appErr.Suggestions) != len(want) {
+		t.Fatalf("suggestions = %+v", appErr.Suggestions)
+	}
+	for _, s := range appErr.Suggestions {
+		if want[s.Field] != s.Message {
+			t.Errorf("suggestion %s = %q, want %q", s.Field, s.Message, want[s.Field])
+		}
+	}
+
+	after := start.Add(time.Hour)
+	if appErr := ValidateStruct(vi, &booking{StartsAt: start, EndsAt: &after, Phone: "+14155552671", CustomerType: "person"}); appErr != nil {
+		t.Fatalf("ValidateStruct rejected a valid booking: %+v", appErr.Suggestions)
+	}
+	if appErr := ValidateStruct(vi, booking{Email: "a@b.c"}); appErr != nil {
+		t.Fatalf("ValidateStruct rejected unset dates: %+v", appErr.Suggestions)
+	}
+
+	type request struct {
+		Type  string `json:"type"`
+		VATID string `json:"vat_id" binding:"required_if=Type business"`
+	}
+	fields, err := vi.StructErrors(request{Type: "business"})
+	if err != nil || len(fields) != 1 || fields[0].Message != "vat_id is required when Type is business" {
+		t.Fatalf("built-in required_if = %+v, %v", fields, err)
+	}
+}
+
+// multipartRequest builds a multipart/form-data POST with the given form
+// values and files, keyed by field.
+func multipartRequest(t *testing.T, values map[string]string, files map[string][]string, contents map[string][]byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range values {
+		if err := w.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for field, names := range files {
+		for _, name := range names {
+			fw, err := w.CreateFormFile(field, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := fw.Write(contents[name]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestBindMultipartChecksFileRules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	type form struct {
+		Title       string                  `form:"title" binding:"required"`
+		Avatar      *multipart.FileHeader   `form:"avatar"`
+		Attachments []*multipart.FileHeader `form:"attachments"`
+	}
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+	contents := map[string][]byte{
+		"me.png":    png,
+		"me.exe":    []byte("MZ\x90\x00 not an image"),
+		"big.png":   append(png, make([]byte, 1024)...),
+		"notes.txt": []byte("hello"),
+	}
+	rules := []FileRule{
+		{Field: "avatar", Required: true, MaxCount: 1, MaxSize: 512, AllowedTypes: []string{"image/*"}},
+		{Field: "attachments", MaxCount: 2},
+		{Field: "contract", Required: true},
+	}
+	vi := New()
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = multipartRequest(t, nil, map[string][]string{
+		"avatar":      {"me.exe"},
+		"attachments": {"notes.txt", "notes.txt", "notes.txt"},
+	}, contents)
+	_, appErr := BindMultipart[form](vi, ctx, rules...)
+	if appErr == nil {
+		t.Fatal("BindMultipart accepted invalid files")
+	}
+	if appErr.Code != apperr.ErrorCodeValidationFail.Code() {
+		t.Fatalf("code = %s", appErr.Code)
+	}
+	want := map[string]string{
+		"title":       "field title failed on 'required' validation",
+		"avatar":      "avatar: me.exe is application/octet-stream, allowed types are image/*",
+		"attachments": "attachments accepts at most 2 files",
+		"contract":    "contract is required",
+	}
+	if len(appErr.Suggestions) != len(want) {
+		t.Fatalf("suggestions = %+v", appErr.Suggestions)
+	}
+	for _, s := range appErr.Suggestions {
+		if want[s.Field] != s.Message {
+			t.Errorf("suggestion %s = %q, want %q", s.Field, s.Message, want[s.Field])
+		}
+	}
+
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = multipartRequest(t, map[string]string{"title": "hi"}, map[string][]string{"avatar": {"big.png"}}, contents)
+	_, appErr = BindMultipart[form](vi, ctx, rules[0])
+	if appErr == nil || len(appErr.Suggestions) != 1 || appErr.Suggestions[0].Message != "avatar: big.png is larger than the 512 B limit" {
+		t.Fatalf("oversized avatar = %+v", appErr)
+	}
+
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = multipartRequest(t, map[string]string{"title": "hi"}, map[string][]string{
+		"avatar":      {"me.png"},
+		"attachments": {"notes.txt"},
+	}, contents)
+	got, appErr := BindMultipart[form](vi, ctx, rules[:2]...)
+	if appErr != nil {
+		t.Fatalf("BindMultipart() error = %+v", appErr.Suggestions)
+	}
+	if got.Title != "hi" || got.Avatar == nil || got.Avatar.Filename != "me.png" || len(got.Attachments) != 1 {
+		t.Fatalf("bound form = %+v", got)
+	}
+
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"title":"hi"}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	if _, appErr := BindMultipart[form](vi, ctx); appErr == nil || appErr.HTTPStatus != http.StatusUnsupportedMediaType {
+		t.Fatalf("JSON request = %+v", appErr)
+	}
+}
+
+func TestStreamFilesSavesWithinLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	contents := map[string][]byte{
+		"small.txt": []byte("hello"),
+		"large.txt": []byte(strings.Repeat("x", 2048)),
+	}
+	save := func(u *Upload) error {
+		return SaveUpload(u, filepath.Join(dir, u.Filename))
+	}
+	rule := FileRule{Field: "files", MaxSize: 1024, AllowedTypes: []string{"text/plain"}}
+	vi := New()
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = multipartRequest(t, map[string]string{"note": "ignored"}, map[string][]string{"files": {"small.txt", "large.txt"}}, contents)
+	appErr := StreamFiles(vi, ctx, save, rule)
+	if appErr == nil || len(appErr.Suggestions) != 1 || appErr.Suggestions[0].Message != "files: large.txt is larger than the 1 KiB limit" {
+		t.Fatalf("StreamFiles() = %+v", appErr)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "small.txt")); err != nil || string(got) != "hello" {
+		t.Fatalf("small.txt = %q, %v", got, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("saved files = %v, %v; want only small.txt", entries, err)
+	}
+
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = multipartRequest(t, nil, map[string][]string{"files": {"small.txt"}}, contents)
+	var saved int64
+	appErr = StreamFiles(vi, ctx, func(u *Upload) error {
+		if u.ContentType != "text/plain" {
+			t.Errorf("ContentType = %q", u.ContentType)
+		}
+		n, err := io.Copy(io.Discard, u)
+		saved = n
+		return err
+	}, rule)
+	if appErr != nil || saved != 5 {
+		t.Fatalf("StreamFiles() = %+v, saved %d bytes", appErr, saved)
+	}
+}